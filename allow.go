@@ -0,0 +1,186 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Types of the holders of delegated permissions
+const (
+	PermissionUser     = "user"
+	PermissionGroup    = "group"
+	PermissionEveryone = "everyone"
+)
+
+// AllowOptions are options you can specify to customize the allow and unallow commands
+type AllowOptions struct {
+	// Users and Groups are the users and groups to delegate the permissions to, or to remove them from
+	Users  []string
+	Groups []string
+	// Everyone delegates the permissions to, or removes them from, everyone
+	Everyone bool
+	// Permissions are the subcommands, properties and @sets of permissions
+	Permissions []string
+	// Recursive applies the permissions to the descendants of the dataset as well, otherwise only to the dataset
+	// itself. For unallow it removes the permissions from the descendants too.
+	Recursive bool
+}
+
+// Allow delegates the permissions to the users, groups or everyone
+func (d *Dataset) Allow(ctx context.Context, options AllowOptions) error {
+	var flags []string
+	if !options.Recursive {
+		flags = append(flags, "-l")
+	}
+	return d.runAllow(ctx, "allow", flags, options)
+}
+
+// Unallow removes the delegated permissions from the users, groups or everyone.
+// Without permissions all permissions of the users, groups or everyone are removed.
+func (d *Dataset) Unallow(ctx context.Context, options AllowOptions) error {
+	var flags []string
+	if options.Recursive {
+		flags = append(flags, "-r")
+	}
+	return d.runAllow(ctx, "unallow", flags, options)
+}
+
+// runAllow runs the allow or unallow command for the users, groups and everyone, which cannot be combined in one call
+func (d *Dataset) runAllow(ctx context.Context, cmd string, flags []string, options AllowOptions) error {
+	if len(options.Users) == 0 && len(options.Groups) == 0 && !options.Everyone {
+		return fmt.Errorf("%s on %s: no users, groups or everyone given", cmd, d.Name)
+	}
+
+	var whos [][]string
+	if len(options.Users) > 0 {
+		whos = append(whos, []string{"-u", strings.Join(options.Users, ",")})
+	}
+	if len(options.Groups) > 0 {
+		whos = append(whos, []string{"-g", strings.Join(options.Groups, ",")})
+	}
+	if options.Everyone {
+		whos = append(whos, []string{"-e"})
+	}
+
+	for _, who := range whos {
+		args := make([]string, 0, 8)
+		args = append(args, cmd)
+		args = append(args, flags...)
+		args = append(args, who...)
+		if len(options.Permissions) > 0 {
+			args = append(args, strings.Join(options.Permissions, ","))
+		}
+		args = append(args, d.Name)
+		err := zfs(ctx, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Permission is a set of delegated permissions of a user, group or everyone
+type Permission struct {
+	// Type is user, group or everyone
+	Type string `json:"Type"`
+	// Name is the user or group, empty for everyone
+	Name        string   `json:"Name"`
+	Permissions []string `json:"Permissions"`
+}
+
+// DatasetPermissions are the permissions delegated on a dataset, as shown by zfs allow
+type DatasetPermissions struct {
+	Dataset string `json:"Dataset"`
+	// Sets are the named permission sets, without their @ prefix
+	Sets map[string][]string `json:"Sets"`
+	// CreateTime are the permissions given to the creator of a descendant
+	CreateTime []string `json:"CreateTime"`
+	// Local apply to the dataset itself, Descendent to its descendants and LocalDescendent to both
+	Local           []Permission `json:"Local"`
+	Descendent      []Permission `json:"Descendent"`
+	LocalDescendent []Permission `json:"LocalDescendent"`
+}
+
+// Permissions returns the permissions delegated on the dataset, and those inherited from its ancestors
+func (d *Dataset) Permissions(ctx context.Context) ([]DatasetPermissions, error) {
+	var out bytes.Buffer
+	c := command{
+		cmd:    Binary,
+		ctx:    ctx,
+		stdout: &out,
+	}
+	_, err := c.Run("allow", d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return parsePermissions(out.Bytes())
+}
+
+func parsePermissions(output []byte) ([]DatasetPermissions, error) {
+	lines, err := outputLines(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []DatasetPermissions
+	var current *DatasetPermissions
+	section := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "---- Permissions on "):
+			name := strings.TrimPrefix(trimmed, "---- Permissions on ")
+			name, _, _ = strings.Cut(name, " ")
+			result = append(result, DatasetPermissions{Dataset: name, Sets: make(map[string][]string)})
+			current = &result[len(result)-1]
+			section = ""
+			continue
+		case current == nil:
+			return nil, fmt.Errorf("output of zfs allow contains permissions before a dataset: %s", trimmed)
+		case !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " ") && strings.HasSuffix(trimmed, ":"):
+			section = strings.TrimSuffix(trimmed, ":")
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		switch section {
+		case "Permission sets":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("output of zfs allow contains invalid permission set: %s", trimmed)
+			}
+			current.Sets[strings.TrimPrefix(fields[0], "@")] = strings.Split(fields[1], ",")
+		case "Create time permissions":
+			current.CreateTime = strings.Split(fields[0], ",")
+		case "Local permissions", "Descendent permissions", "Local+Descendent permissions":
+			perm, err := parsePermission(fields)
+			if err != nil {
+				return nil, fmt.Errorf("output of zfs allow contains invalid permissions: %s: %w", trimmed, err)
+			}
+			switch section {
+			case "Local permissions":
+				current.Local = append(current.Local, perm)
+			case "Descendent permissions":
+				current.Descendent = append(current.Descendent, perm)
+			default:
+				current.LocalDescendent = append(current.LocalDescendent, perm)
+			}
+		default:
+			return nil, fmt.Errorf("output of zfs allow contains unknown section %q", section)
+		}
+	}
+	return result, nil
+}
+
+func parsePermission(fields []string) (Permission, error) {
+	switch {
+	case len(fields) == 2 && fields[0] == PermissionEveryone:
+		return Permission{Type: PermissionEveryone, Permissions: strings.Split(fields[1], ",")}, nil
+	case len(fields) == 3 && (fields[0] == PermissionUser || fields[0] == PermissionGroup):
+		return Permission{Type: fields[0], Name: fields[1], Permissions: strings.Split(fields[2], ",")}, nil
+	}
+	return Permission{}, fmt.Errorf("expected user, group or everyone with permissions, got %d fields", len(fields))
+}
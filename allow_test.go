@@ -0,0 +1,75 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPermissions = `---- Permissions on tank/home/alice --------------------------------------
+Local+Descendent permissions:
+	user alice create,destroy,mount,snapshot
+---- Permissions on tank/home ----------------------------------------------
+Permission sets:
+	@backup hold,send,snapshot
+Create time permissions:
+	destroy,mount
+Local permissions:
+	group staff mount
+Descendent permissions:
+	user bob @backup
+Local+Descendent permissions:
+	everyone userprop
+`
+
+func Test_parsePermissions(t *testing.T) {
+	perms, err := ParsePermissions([]byte(testPermissions))
+	require.NoError(t, err)
+	require.Equal(t, []DatasetPermissions{
+		{
+			Dataset: "tank/home/alice",
+			Sets:    map[string][]string{},
+			LocalDescendent: []Permission{
+				{Type: PermissionUser, Name: "alice", Permissions: []string{"create", "destroy", "mount", "snapshot"}},
+			},
+		},
+		{
+			Dataset:    "tank/home",
+			Sets:       map[string][]string{"backup": {"hold", "send", "snapshot"}},
+			CreateTime: []string{"destroy", "mount"},
+			Local:      []Permission{{Type: PermissionGroup, Name: "staff", Permissions: []string{"mount"}}},
+			Descendent: []Permission{{Type: PermissionUser, Name: "bob", Permissions: []string{"@backup"}}},
+			LocalDescendent: []Permission{
+				{Type: PermissionEveryone, Permissions: []string{"userprop"}},
+			},
+		},
+	}, perms)
+
+	_, err = ParsePermissions([]byte("\tuser alice create\n"))
+	require.Error(t, err)
+}
+
+func Test_Allow(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "tank/home", Type: DatasetFilesystem}
+	require.NoError(t, ds.Allow(ctx, AllowOptions{
+		Users:       []string{"alice", "bob"},
+		Groups:      []string{"staff"},
+		Permissions: []string{"create", "mount"},
+	}))
+	require.NoError(t, ds.Unallow(ctx, AllowOptions{Everyone: true, Recursive: true}))
+	require.Equal(t, [][]string{
+		{"allow", "-l", "-u", "alice,bob", "create,mount", "tank/home"},
+		{"allow", "-l", "-g", "staff", "create,mount", "tank/home"},
+		{"unallow", "-r", "-e", "tank/home"},
+	}, executed)
+
+	require.Error(t, ds.Allow(ctx, AllowOptions{Permissions: []string{"mount"}}))
+}
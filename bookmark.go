@@ -0,0 +1,47 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// bookmarkFields are the fields of the Dataset struct that bookmarks have
+var bookmarkFields = []string{PropertyName, PropertyType, PropertyGUID, PropertyCreateTXG, PropertyCreation}
+
+// Bookmark creates a bookmark of the snapshot with the given name, without the dataset. A bookmark can be used as the
+// incremental base of a send after the snapshot it was created from is destroyed.
+func (d *Dataset) Bookmark(ctx context.Context, name string) (*Dataset, error) {
+	if d.Type != DatasetSnapshot {
+		return nil, ErrOnlySnapshotsSupported
+	}
+	dataset, _, _ := strings.Cut(d.Name, "@")
+	bookmark := fmt.Sprintf("%s#%s", dataset, name)
+
+	err := zfs(ctx, "bookmark", d.Name, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := ListDatasets(ctx, ListOptions{
+		ParentDataset: bookmark,
+		Fields:        bookmarkFields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ds) != 1 {
+		return nil, fmt.Errorf("expected 1 bookmark, got %d", len(ds))
+	}
+	return &ds[0], nil
+}
+
+// ListBookmarks returns a slice of ZFS bookmarks. When no fields are given, only the fields bookmarks have are
+// retrieved: the name, type, GUID, creation TXG and creation time.
+func ListBookmarks(ctx context.Context, options ListOptions) ([]Dataset, error) {
+	options.DatasetType = DatasetBookmark
+	options.Recursive = true
+	if len(options.Fields) == 0 {
+		options.Fields = bookmarkFields
+	}
+	return ListDatasets(ctx, options)
+}
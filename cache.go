@@ -0,0 +1,154 @@
+package zfs
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var lookupCache atomic.Pointer[cache]
+
+// SetCacheTTL enables caching of dataset and property lookups for the given duration, zero disables caching.
+// The cache is cleared whenever a command is executed that could change datasets or properties, but changes made
+// outside this package are only seen after the cached lookup expires.
+func SetCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		lookupCache.Store(nil)
+		return
+	}
+	lookupCache.Store(newCache(ttl))
+}
+
+// ClearCache removes all cached lookups, for instance after changing datasets outside this package
+func ClearCache() {
+	c := lookupCache.Load()
+	if c == nil {
+		return
+	}
+	c.clear()
+}
+
+// cachedLookup returns the cached result of the command with the given arguments, or runs lookup when it is not cached.
+// The clone function is used to copy results in and out of the cache, so callers cannot modify cached data.
+// Lookups with an executor or command config set on the context are not cached, as they may run on another host or
+// against another zfs binary or environment.
+func cachedLookup[T any](ctx context.Context, arg []string, clone func(T) T, lookup func() (T, error)) (T, error) {
+	c := lookupCache.Load()
+	if c == nil || ctx.Value(executorContextKey{}) != nil || ctx.Value(commandConfigContextKey{}) != nil {
+		return lookup()
+	}
+
+	key := strings.Join(arg, "\x00")
+	val, ok := c.get(key)
+	if ok {
+		return clone(val.(T)), nil
+	}
+
+	generation := c.generation()
+	result, err := lookup()
+	if err != nil {
+		return result, err
+	}
+	c.set(key, generation, clone(result))
+	return result, nil
+}
+
+// invalidateCache clears the cache after running a command that is not read-only
+func invalidateCache(arg []string) {
+	if commandCategory(arg) == CommandCategoryRead {
+		return
+	}
+	ClearCache()
+}
+
+func cloneDatasets(list []Dataset) []Dataset {
+	if list == nil {
+		return nil
+	}
+	cloned := make([]Dataset, len(list))
+	for i := range list {
+		cloned[i] = list[i]
+		cloned[i].ExtraProps = maps.Clone(list[i].ExtraProps)
+	}
+	return cloned
+}
+
+func cloneString(s string) string {
+	return s
+}
+
+type cache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	gen       uint64
+	lastPrune time.Time
+	entries   map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	cachedAt time.Time
+	value    any
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:       ttl,
+		lastPrune: time.Now(),
+		entries:   make(map[string]cacheEntry, 64),
+	}
+}
+
+func (c *cache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.cachedAt) >= c.ttl {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *cache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// set stores the value, unless the cache has been cleared since the given generation was retrieved
+func (c *cache) set(key string, generation uint64, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.gen {
+		return // Something changed while looking up, this value may be outdated
+	}
+
+	now := time.Now()
+	c.entries[key] = cacheEntry{
+		cachedAt: now,
+		value:    value,
+	}
+
+	if now.Sub(c.lastPrune) < c.ttl {
+		return
+	}
+	for k, entry := range c.entries {
+		if now.Sub(entry.cachedAt) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.lastPrune = now
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	clear(c.entries)
+}
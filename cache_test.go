@@ -0,0 +1,72 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cachedLookup(t *testing.T) {
+	SetCacheTTL(time.Minute)
+	defer SetCacheTTL(0)
+
+	lookups := 0
+	lookup := func() ([]Dataset, error) {
+		lookups++
+		return []Dataset{{Name: "pool/ds", ExtraProps: map[string]string{"prop": "val"}}}, nil
+	}
+	args := []string{"get", "-Hp", "pool/ds"}
+
+	ds, err := cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	ds[0].ExtraProps["prop"] = "changed"
+
+	ds, err = cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 1, lookups)
+	require.Equal(t, "val", ds[0].ExtraProps["prop"])
+
+	invalidateCache([]string{"list"})
+	_, err = cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 1, lookups)
+
+	invalidateCache([]string{"destroy", "pool/ds"})
+	_, err = cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 2, lookups)
+}
+
+func Test_cachedLookupContext(t *testing.T) {
+	SetCacheTTL(time.Minute)
+	defer SetCacheTTL(0)
+
+	lookups := 0
+	lookup := func() ([]Dataset, error) {
+		lookups++
+		return []Dataset{{Name: "pool/ds"}}, nil
+	}
+	args := []string{"get", "-Hp", "pool/ds"}
+
+	// Lookups with another executor or command config are neither cached nor served from the cache
+	_, err := cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	_, err = cachedLookup(WithCommandConfig(context.Background(), CommandConfig{ZFSPath: "/other/zfs"}), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	_, err = cachedLookup(WithExecutor(context.Background(), NewSudoExecutor()), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 3, lookups)
+
+	_, err = cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 3, lookups)
+
+	// Changing the package-wide config clears the cache
+	SetCommandConfig(&CommandConfig{ZFSPath: "/other/zfs"})
+	defer SetCommandConfig(nil)
+	_, err = cachedLookup(context.Background(), args, cloneDatasets, lookup)
+	require.NoError(t, err)
+	require.Equal(t, 4, lookups)
+}
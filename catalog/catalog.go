@@ -0,0 +1,175 @@
+// Package catalog produces an inventory of the datasets, snapshots and bookmarks below a parent dataset, which can be
+// stored as JSON to feed external backup catalogs, or compared with the inventory of a replication target.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// Catalog is the inventory of a parent dataset and its children
+type Catalog struct {
+	ParentDataset string    `json:"ParentDataset"`
+	GeneratedAt   time.Time `json:"GeneratedAt"`
+	Datasets      []Dataset `json:"Datasets"`
+}
+
+// Dataset is a filesystem or volume with its snapshots and bookmarks, ordered by creation
+type Dataset struct {
+	Name string `json:"Name"`
+	// RelativeName is the name below the parent dataset, which is the same on the source and its targets.
+	// It is empty for the parent dataset itself.
+	RelativeName string          `json:"RelativeName"`
+	Type         zfs.DatasetType `json:"Type"`
+	GUID         uint64          `json:"GUID"`
+	Creation     time.Time       `json:"Creation"`
+	Used         uint64          `json:"Used"`
+	Referenced   uint64          `json:"Referenced"`
+	LogicalUsed  uint64          `json:"LogicalUsed"`
+	Snapshots    []Snapshot      `json:"Snapshots"`
+	Bookmarks    []Bookmark      `json:"Bookmarks"`
+}
+
+// Snapshot is a snapshot of a dataset, a replicated snapshot has the same GUID as its source
+type Snapshot struct {
+	// Name is the part after the @
+	Name       string    `json:"Name"`
+	GUID       uint64    `json:"GUID"`
+	CreateTXG  uint64    `json:"CreateTXG"`
+	Creation   time.Time `json:"Creation"`
+	Used       uint64    `json:"Used"`
+	Referenced uint64    `json:"Referenced"`
+	Written    uint64    `json:"Written"`
+}
+
+// Bookmark is a bookmark of a dataset, which has the GUID of the snapshot it was created from
+type Bookmark struct {
+	// Name is the part after the #
+	Name      string    `json:"Name"`
+	GUID      uint64    `json:"GUID"`
+	CreateTXG uint64    `json:"CreateTXG"`
+	Creation  time.Time `json:"Creation"`
+}
+
+// Build creates the catalog of the parent dataset and all its children
+func Build(ctx context.Context, parentDataset string) (*Catalog, error) {
+	list, err := zfs.ListDatasets(ctx, zfs.ListOptions{
+		ParentDataset: parentDataset,
+		DatasetType:   zfs.DatasetFilesystem + "," + zfs.DatasetVolume + "," + zfs.DatasetSnapshot,
+		Recursive:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing datasets of %s: %w", parentDataset, err)
+	}
+	bookmarks, err := zfs.ListDatasets(ctx, zfs.ListOptions{
+		ParentDataset: parentDataset,
+		DatasetType:   zfs.DatasetBookmark,
+		Recursive:     true,
+		Fields:        []string{zfs.PropertyName, zfs.PropertyGUID, zfs.PropertyCreation, zfs.PropertyCreateTXG},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing bookmarks of %s: %w", parentDataset, err)
+	}
+
+	c := &Catalog{
+		ParentDataset: parentDataset,
+		GeneratedAt:   time.Now().UTC(),
+	}
+	index := make(map[string]int, len(list))
+	for _, ds := range list {
+		if ds.Type == zfs.DatasetSnapshot {
+			continue
+		}
+		index[ds.Name] = len(c.Datasets)
+		c.Datasets = append(c.Datasets, Dataset{
+			Name:         ds.Name,
+			RelativeName: strings.TrimPrefix(strings.TrimPrefix(ds.Name, parentDataset), "/"),
+			Type:         ds.Type,
+			GUID:         ds.GUID,
+			Creation:     ds.Creation.UTC(),
+			Used:         ds.Used,
+			Referenced:   ds.Referenced,
+			LogicalUsed:  ds.Logicalused,
+			Snapshots:    []Snapshot{},
+			Bookmarks:    []Bookmark{},
+		})
+	}
+
+	for _, snap := range list {
+		name, snapName, ok := strings.Cut(snap.Name, "@")
+		i, found := index[name]
+		if !ok || !found {
+			continue
+		}
+		c.Datasets[i].Snapshots = append(c.Datasets[i].Snapshots, Snapshot{
+			Name:       snapName,
+			GUID:       snap.GUID,
+			CreateTXG:  snap.CreateTXG,
+			Creation:   snap.Creation.UTC(),
+			Used:       snap.Used,
+			Referenced: snap.Referenced,
+			Written:    snap.Written,
+		})
+	}
+	for _, bookmark := range bookmarks {
+		name, bookmarkName, ok := strings.Cut(bookmark.Name, "#")
+		i, found := index[name]
+		if !ok || !found {
+			continue
+		}
+		c.Datasets[i].Bookmarks = append(c.Datasets[i].Bookmarks, Bookmark{
+			Name:      bookmarkName,
+			GUID:      bookmark.GUID,
+			CreateTXG: bookmark.CreateTXG,
+			Creation:  bookmark.Creation.UTC(),
+		})
+	}
+
+	slices.SortFunc(c.Datasets, func(a, b Dataset) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for i := range c.Datasets {
+		slices.SortStableFunc(c.Datasets[i].Snapshots, func(a, b Snapshot) int {
+			return compareUint(a.CreateTXG, b.CreateTXG)
+		})
+		slices.SortStableFunc(c.Datasets[i].Bookmarks, func(a, b Bookmark) int {
+			return compareUint(a.CreateTXG, b.CreateTXG)
+		})
+	}
+	return c, nil
+}
+
+// Dataset returns the dataset with the relative name, or nil
+func (c *Catalog) Dataset(relativeName string) *Dataset {
+	for i := range c.Datasets {
+		if c.Datasets[i].RelativeName == relativeName {
+			return &c.Datasets[i]
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the snapshot with the name, or nil
+func (d *Dataset) Snapshot(name string) *Snapshot {
+	for i := range d.Snapshots {
+		if d.Snapshots[i].Name == name {
+			return &d.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
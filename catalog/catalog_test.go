@@ -0,0 +1,70 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func TestBuild(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/src/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+	_, err = zfs.CreateVolume(ctx, "pool/src/vol", 1024*1024, zfs.CreateVolumeOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "snap1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "snap2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = zfs.CreateFilesystem(ctx, "pool/other", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+
+	c, err := Build(ctx, "pool/src")
+	require.NoError(t, err)
+	require.Equal(t, "pool/src", c.ParentDataset)
+	require.Len(t, c.Datasets, 3)
+
+	require.Equal(t, "", c.Datasets[0].RelativeName)
+	ds := c.Dataset("fs")
+	require.NotNil(t, ds)
+	require.Equal(t, "pool/src/fs", ds.Name)
+	require.Equal(t, zfs.DatasetFilesystem, ds.Type)
+	require.NotZero(t, ds.GUID)
+	require.False(t, ds.Creation.IsZero())
+	require.Len(t, ds.Snapshots, 2)
+	require.Equal(t, "snap1", ds.Snapshots[0].Name)
+	require.Equal(t, "snap2", ds.Snapshots[1].Name)
+	require.Less(t, ds.Snapshots[0].CreateTXG, ds.Snapshots[1].CreateTXG)
+	require.NotEqual(t, ds.Snapshots[0].GUID, ds.Snapshots[1].GUID)
+	require.Empty(t, ds.Bookmarks)
+
+	require.Equal(t, zfs.DatasetVolume, c.Dataset("vol").Type)
+	require.Nil(t, c.Dataset("other"))
+}
+
+func TestCompare(t *testing.T) {
+	source := &Catalog{Datasets: []Dataset{
+		{RelativeName: "", Snapshots: []Snapshot{{Name: "a", GUID: 1}}},
+		{RelativeName: "fs", Snapshots: []Snapshot{{Name: "a", GUID: 2}, {Name: "b", GUID: 3}, {Name: "c", GUID: 4}}},
+		{RelativeName: "new"},
+	}}
+	target := &Catalog{Datasets: []Dataset{
+		{RelativeName: "", Snapshots: []Snapshot{{Name: "a", GUID: 1}}},
+		{RelativeName: "fs", Snapshots: []Snapshot{{Name: "a", GUID: 2}, {Name: "b", GUID: 9}, {Name: "old", GUID: 5}}},
+		{RelativeName: "gone"},
+	}}
+
+	require.Equal(t, []Difference{
+		{Kind: SnapshotMismatch, Dataset: "fs", Snapshot: "b"},
+		{Kind: SnapshotMissing, Dataset: "fs", Snapshot: "c"},
+		{Kind: SnapshotExtra, Dataset: "fs", Snapshot: "old"},
+		{Kind: DatasetMissing, Dataset: "new"},
+		{Kind: DatasetExtra, Dataset: "gone"},
+	}, Compare(source, target))
+	require.Empty(t, Compare(source, source))
+}
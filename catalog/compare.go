@@ -0,0 +1,60 @@
+package catalog
+
+// DifferenceKind is the kind of difference between a source and target catalog
+type DifferenceKind string
+
+// Kinds of differences
+const (
+	// DatasetMissing is a dataset of the source that is not on the target
+	DatasetMissing DifferenceKind = "dataset-missing"
+	// DatasetExtra is a dataset on the target that is not on the source
+	DatasetExtra DifferenceKind = "dataset-extra"
+	// SnapshotMissing is a snapshot of the source that is not on the target
+	SnapshotMissing DifferenceKind = "snapshot-missing"
+	// SnapshotExtra is a snapshot on the target that is not on the source
+	SnapshotExtra DifferenceKind = "snapshot-extra"
+	// SnapshotMismatch is a snapshot with the same name, but another GUID, so it is not a replica of the source
+	SnapshotMismatch DifferenceKind = "snapshot-mismatch"
+)
+
+// Difference is a difference between a source and target catalog
+type Difference struct {
+	Kind DifferenceKind `json:"Kind"`
+	// Dataset is the relative name of the dataset
+	Dataset string `json:"Dataset"`
+	// Snapshot is the name of the snapshot, empty for dataset differences
+	Snapshot string `json:"Snapshot,omitempty"`
+}
+
+// Compare returns the differences between the datasets and snapshots of a source catalog and a target catalog it is
+// replicated to. Datasets are matched by their name relative to the parent dataset, snapshots by name and GUID.
+func Compare(source, target *Catalog) []Difference {
+	var diffs []Difference
+	for _, src := range source.Datasets {
+		dst := target.Dataset(src.RelativeName)
+		if dst == nil {
+			diffs = append(diffs, Difference{Kind: DatasetMissing, Dataset: src.RelativeName})
+			continue
+		}
+		for _, snap := range src.Snapshots {
+			dstSnap := dst.Snapshot(snap.Name)
+			switch {
+			case dstSnap == nil:
+				diffs = append(diffs, Difference{Kind: SnapshotMissing, Dataset: src.RelativeName, Snapshot: snap.Name})
+			case dstSnap.GUID != snap.GUID:
+				diffs = append(diffs, Difference{Kind: SnapshotMismatch, Dataset: src.RelativeName, Snapshot: snap.Name})
+			}
+		}
+		for _, snap := range dst.Snapshots {
+			if src.Snapshot(snap.Name) == nil {
+				diffs = append(diffs, Difference{Kind: SnapshotExtra, Dataset: src.RelativeName, Snapshot: snap.Name})
+			}
+		}
+	}
+	for _, dst := range target.Datasets {
+		if source.Dataset(dst.RelativeName) == nil {
+			diffs = append(diffs, Difference{Kind: DatasetExtra, Dataset: dst.RelativeName})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,99 @@
+package zfs
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Clones returns the datasets cloned from the snapshot
+func (d *Dataset) Clones(ctx context.Context) ([]Dataset, error) {
+	if d.Type != DatasetSnapshot {
+		return nil, ErrOnlySnapshotsSupported
+	}
+	out, err := zfsOutput(ctx, "get", "-Hp", "-o", "value", PropertyClones, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fields := range out {
+		for _, field := range fields {
+			names = append(names, splitClones(field)...)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return GetDatasets(ctx, names)
+}
+
+// splitClones splits the value of the clones property
+func splitClones(value string) []string {
+	value = setString(value)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// OriginGraph are the relations between clones and the snapshots they were cloned from
+type OriginGraph struct {
+	// Origins maps clones to the snapshot they were cloned from
+	Origins map[string]string `json:"Origins"`
+	// Clones maps snapshots to the clones of them, sorted by name
+	Clones map[string][]string `json:"Clones"`
+}
+
+// CloneTree returns the graph of the clones in the root dataset and its descendants, and the snapshots they were
+// cloned from. Clones outside the root are not part of the graph.
+func CloneTree(ctx context.Context, root string) (*OriginGraph, error) {
+	datasets, err := ListDatasets(ctx, ListOptions{
+		ParentDataset: root,
+		Recursive:     true,
+		Fields:        []string{PropertyName, PropertyType, PropertyOrigin},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &OriginGraph{
+		Origins: make(map[string]string),
+		Clones:  make(map[string][]string),
+	}
+	for _, ds := range datasets {
+		if ds.Origin == "" {
+			continue
+		}
+		graph.Origins[ds.Name] = ds.Origin
+		graph.Clones[ds.Origin] = append(graph.Clones[ds.Origin], ds.Name)
+	}
+	for _, clones := range graph.Clones {
+		slices.Sort(clones)
+	}
+	return graph, nil
+}
+
+// Dependents returns the clones that depend on the snapshot, directly or through snapshots of its clones or their
+// descendants, sorted by name. Destroying the snapshot requires destroying these first.
+func (g *OriginGraph) Dependents(snapshot string) []string {
+	var dependents []string
+	queue := []string{snapshot}
+	for len(queue) > 0 {
+		snap := queue[0]
+		queue = queue[1:]
+		for _, clone := range g.Clones[snap] {
+			if slices.Contains(dependents, clone) {
+				continue
+			}
+			dependents = append(dependents, clone)
+			for origin := range g.Clones {
+				fs, _, _ := strings.Cut(origin, "@")
+				if fs == clone || strings.HasPrefix(fs, clone+"/") {
+					queue = append(queue, origin)
+				}
+			}
+		}
+	}
+	slices.Sort(dependents)
+	return dependents
+}
@@ -0,0 +1,76 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CloneTree(t *testing.T) {
+	datasets := [][3]string{
+		{"pool/fs", "filesystem", "-"},
+		{"pool/fs@a", "snapshot", "-"},
+		{"pool/clone1", "filesystem", "pool/fs@a"},
+		{"pool/clone1/child", "filesystem", "-"},
+		{"pool/clone1/child@b", "snapshot", "-"},
+		{"pool/clone2", "filesystem", "pool/clone1/child@b"},
+		{"pool/clone3", "volume", "pool/fs@a"},
+		{"pool/other", "filesystem", "pool/fs@c"},
+	}
+	var output strings.Builder
+	for _, ds := range datasets {
+		output.WriteString(ds[0] + "\tname\t" + ds[0] + "\n")
+		output.WriteString(ds[0] + "\ttype\t" + ds[1] + "\n")
+		output.WriteString(ds[0] + "\torigin\t" + ds[2] + "\n")
+	}
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"get", "-Hp", "-o", "name,property,value", "-r", "name,type,origin", "pool"}, args)
+		_, err := io.WriteString(stdout, output.String())
+		return "", err
+	}))
+
+	graph, err := CloneTree(ctx, "pool")
+	require.NoError(t, err)
+	require.Equal(t, &OriginGraph{
+		Origins: map[string]string{
+			"pool/clone1": "pool/fs@a",
+			"pool/clone2": "pool/clone1/child@b",
+			"pool/clone3": "pool/fs@a",
+			"pool/other":  "pool/fs@c",
+		},
+		Clones: map[string][]string{
+			"pool/fs@a":           {"pool/clone1", "pool/clone3"},
+			"pool/clone1/child@b": {"pool/clone2"},
+			"pool/fs@c":           {"pool/other"},
+		},
+	}, graph)
+	require.Equal(t, []string{"pool/clone1", "pool/clone2", "pool/clone3"}, graph.Dependents("pool/fs@a"))
+	require.Equal(t, []string{"pool/clone2"}, graph.Dependents("pool/clone1/child@b"))
+	require.Empty(t, graph.Dependents("pool/fs@b"))
+}
+
+func Test_Clones(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[len(args)-1] == "pool/fs@b" {
+			_, err := io.WriteString(stdout, "\n")
+			return "", err
+		}
+		_, err := io.WriteString(stdout, "pool/clone1,pool/clone2\n")
+		return "", err
+	}))
+
+	snap := &Dataset{Name: "pool/fs@b", Type: DatasetSnapshot}
+	clones, err := snap.Clones(ctx)
+	require.NoError(t, err)
+	require.Empty(t, clones)
+	require.Equal(t, [][]string{{"get", "-Hp", "-o", "value", "clones", "pool/fs@b"}}, executed)
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	_, err = fs.Clones(ctx)
+	require.ErrorIs(t, err, ErrOnlySnapshotsSupported)
+}
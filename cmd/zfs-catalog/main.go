@@ -0,0 +1,90 @@
+// Command zfs-catalog writes a JSON inventory of the datasets, snapshots and bookmarks below a parent dataset.
+//
+// Usage:
+//
+//	zfs-catalog [-o file] [-compare file] dataset
+//
+// The catalog is written to stdout, or to the file given with -o. With -compare the catalog is compared with the
+// catalog in the given file, like one generated on a replication target, and the differences are written instead.
+// The command then exits with status 1 when there are any differences.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/vansante/go-zfsutils/catalog"
+)
+
+func main() {
+	output := flag.String("o", "", "path to write the output to instead of stdout")
+	compareFile := flag.String("compare", "", "path to a target catalog to compare with")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "zfs-catalog: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	diffs, err := run(ctx, w, flag.Arg(0), *compareFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zfs-catalog: %v\n", err)
+		os.Exit(2)
+	}
+	if diffs > 0 {
+		os.Exit(1)
+	}
+}
+
+// run writes the catalog of the dataset, or its differences with the target catalog, and returns the number of
+// differences
+func run(ctx context.Context, w io.Writer, dataset, compareFile string) (int, error) {
+	var target *catalog.Catalog
+	if compareFile != "" {
+		data, err := os.ReadFile(compareFile)
+		if err != nil {
+			return 0, err
+		}
+		target = &catalog.Catalog{}
+		err = json.Unmarshal(data, target)
+		if err != nil {
+			return 0, fmt.Errorf("error decoding catalog %s: %w", compareFile, err)
+		}
+	}
+
+	c, err := catalog.Build(ctx, dataset)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if target == nil {
+		return 0, enc.Encode(c)
+	}
+	diffs := catalog.Compare(c, target)
+	if diffs == nil {
+		diffs = []catalog.Difference{}
+	}
+	return len(diffs), enc.Encode(diffs)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/catalog"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func Test_run(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "snap1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	diffs, err := run(ctx, &buf, "pool/fs", "")
+	require.NoError(t, err)
+	require.Zero(t, diffs)
+
+	c := &catalog.Catalog{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), c))
+	require.Len(t, c.Datasets, 1)
+	require.Len(t, c.Datasets[0].Snapshots, 1)
+
+	file := filepath.Join(t.TempDir(), "target.json")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0o600))
+
+	buf.Reset()
+	diffs, err = run(ctx, &buf, "pool/fs", file)
+	require.NoError(t, err)
+	require.Zero(t, diffs)
+	require.JSONEq(t, "[]", buf.String())
+
+	_, err = fs.Snapshot(ctx, "snap2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	buf.Reset()
+	diffs, err = run(ctx, &buf, "pool/fs", file)
+	require.NoError(t, err)
+	require.Equal(t, 1, diffs)
+	require.JSONEq(t, `[{"Kind":"snapshot-missing","Dataset":"","Snapshot":"snap2"}]`, buf.String())
+}
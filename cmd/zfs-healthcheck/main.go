@@ -0,0 +1,88 @@
+// Command zfs-healthcheck checks that zfs is available, the pools are healthy, the datasets are accessible and their
+// snapshots are recent.
+//
+// Usage:
+//
+//	zfs-healthcheck [-pool name]... [-dataset name]... [-snapshot-age-warning duration] [-snapshot-age-critical duration] [-json]
+//
+// The command exits with the Nagios plugin status codes: 0 for OK, 1 for warning, 2 for critical and 3 for unknown.
+// This makes it usable both as a Nagios (or compatible) check and as a container health probe.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vansante/go-zfsutils/healthcheck"
+)
+
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	conf := healthcheck.Config{}
+	conf.ApplyDefaults()
+
+	var pools, datasets stringList
+	flag.Var(&pools, "pool", "pool that must be imported and healthy, can be repeated (default all imported pools)")
+	flag.Var(&datasets, "dataset", "dataset that must be accessible, can be repeated")
+	flag.Uint64Var(&conf.CapacityWarningPercent, "capacity-warning", conf.CapacityWarningPercent, "pool capacity percentage to warn at")
+	flag.Uint64Var(&conf.CapacityCriticalPercent, "capacity-critical", conf.CapacityCriticalPercent, "pool capacity percentage to fail at")
+	ageWarning := flag.Duration("snapshot-age-warning", 0, "age of the newest dataset snapshot to warn at")
+	ageCritical := flag.Duration("snapshot-age-critical", 0, "age of the newest dataset snapshot to fail at")
+	timeout := flag.Duration("timeout", 30*time.Second, "maximum duration of the checks")
+	asJSON := flag.Bool("json", false, "write the result as JSON")
+	flag.Parse()
+
+	conf.Pools = pools
+	conf.Datasets = datasets
+	conf.SnapshotAgeWarningMinutes = int64(ageWarning.Minutes())
+	conf.SnapshotAgeCriticalMinutes = int64(ageCritical.Minutes())
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	result := healthcheck.NewChecker(conf).Check(ctx)
+	err := write(os.Stdout, result, *asJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zfs-healthcheck: %v\n", err)
+		os.Exit(int(healthcheck.StatusUnknown))
+	}
+	os.Exit(result.ExitCode())
+}
+
+// write writes the result as JSON, or as the summary line followed by a line per check
+func write(w io.Writer, result *healthcheck.Result, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(result)
+	}
+
+	_, err := fmt.Fprintln(w, result.Summary())
+	if err != nil {
+		return err
+	}
+	for _, check := range result.Checks {
+		_, err = fmt.Fprintf(w, "%s %s: %s\n", check.Status, check.Name, check.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vansante/go-zfsutils/healthcheck"
+)
+
+func Test_write(t *testing.T) {
+	result := &healthcheck.Result{
+		Status: healthcheck.StatusWarning,
+		Checks: []healthcheck.CheckResult{
+			{Name: "zfs", Status: healthcheck.StatusOK, Message: "1 pools imported"},
+			{Name: "pool tank", Status: healthcheck.StatusWarning, Message: "pool is DEGRADED"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, write(&buf, result, false))
+	require.Equal(t, "ZFS WARNING - pool tank: pool is DEGRADED\n"+
+		"OK zfs: 1 pools imported\n"+
+		"WARNING pool tank: pool is DEGRADED\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, write(&buf, result, true))
+	require.Contains(t, buf.String(), `"Status": "WARNING"`)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	zfshttp "github.com/vansante/go-zfsutils/http"
+	"github.com/vansante/go-zfsutils/logging"
+)
+
+const (
+	defaultListen                 = ":7654"
+	defaultShutdownTimeoutSeconds = 30
+	envPrefix                     = "ZFS_HTTP_"
+)
+
+// Config is the configuration of the server, read from a YAML (or JSON) file and overridden by environment variables
+type Config struct {
+	// Listen is the address to listen on
+	Listen string `yaml:"Listen"`
+	// TLS enables HTTPS when a certificate and key are configured
+	TLS TLSConfig `yaml:"TLS"`
+	// Tokens are accepted as bearer tokens in the Authorization header. When empty, requests are not authenticated.
+	Tokens []string `yaml:"Tokens"`
+
+	// Config configures the level and format of the logs
+	logging.Config `yaml:",inline"`
+
+	// ShutdownTimeoutSeconds is the time running requests get to finish when the server is stopped
+	ShutdownTimeoutSeconds int `yaml:"ShutdownTimeoutSeconds"`
+
+	// HTTP is the configuration of the zfs http handlers
+	HTTP zfshttp.Config `yaml:"HTTP"`
+	// Roots serves multiple parent datasets, each under its own path prefix, using the HTTP configuration for the rest.
+	// When empty, only the parent dataset of the HTTP configuration is served.
+	Roots []Root `yaml:"Roots"`
+}
+
+// TLSConfig specifies the certificate for serving HTTPS
+type TLSConfig struct {
+	CertFile string `yaml:"CertFile"`
+	KeyFile  string `yaml:"KeyFile"`
+}
+
+// Root is a parent dataset served under a path prefix
+type Root struct {
+	HTTPPathPrefix string `yaml:"HTTPPathPrefix"`
+	ParentDataset  string `yaml:"ParentDataset"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.Listen = defaultListen
+	c.Config.ApplyDefaults()
+	c.ShutdownTimeoutSeconds = defaultShutdownTimeoutSeconds
+	c.HTTP.ApplyDefaults()
+}
+
+// loadConfig reads the configuration file if one is given, and applies the environment on top of it
+func loadConfig(file string, lookupEnv func(string) (string, bool)) (Config, error) {
+	conf := Config{}
+	conf.ApplyDefaults()
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return conf, fmt.Errorf("error reading config file: %w", err)
+		}
+		err = yaml.Unmarshal(data, &conf)
+		if err != nil {
+			return conf, fmt.Errorf("error parsing config file %s: %w", file, err)
+		}
+	}
+
+	err := conf.applyEnv(lookupEnv)
+	if err != nil {
+		return conf, err
+	}
+	return conf, conf.validate()
+}
+
+// applyEnv overrides the configuration with the ZFS_HTTP_ environment variables that are set
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"LISTEN":         &c.Listen,
+		"TLS_CERT_FILE":  &c.TLS.CertFile,
+		"TLS_KEY_FILE":   &c.TLS.KeyFile,
+		"LOG_LEVEL":      &c.LogLevel,
+		"LOG_FORMAT":     &c.LogFormat,
+		"PATH_PREFIX":    &c.HTTP.HTTPPathPrefix,
+		"PARENT_DATASET": &c.HTTP.ParentDataset,
+	}
+	for name, dst := range strs {
+		value, ok := lookupEnv(envPrefix + name)
+		if !ok {
+			continue
+		}
+		*dst = value
+	}
+
+	if value, ok := lookupEnv(envPrefix + "TOKENS"); ok {
+		c.Tokens = nil
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				c.Tokens = append(c.Tokens, token)
+			}
+		}
+	}
+
+	if value, ok := lookupEnv(envPrefix + "SPEED_LIMIT_BPS"); ok {
+		speed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %sSPEED_LIMIT_BPS: %w", envPrefix, err)
+		}
+		c.HTTP.SpeedBytesPerSecond = speed
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("both a TLS certificate and key file are required for TLS")
+	}
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	prefixes := make(map[string]bool, len(c.Roots))
+	for i, root := range c.Roots {
+		if root.ParentDataset == "" {
+			return fmt.Errorf("root %d has no parent dataset", i)
+		}
+		prefix := strings.TrimSuffix(root.HTTPPathPrefix, "/")
+		if prefixes[prefix] {
+			return fmt.Errorf("root %d uses path prefix %q again", i, root.HTTPPathPrefix)
+		}
+		prefixes[prefix] = true
+	}
+	return nil
+}
+
+// httpConfigs returns the configuration of the zfs http handlers for every root
+func (c *Config) httpConfigs() []zfshttp.Config {
+	if len(c.Roots) == 0 {
+		return []zfshttp.Config{c.HTTP}
+	}
+	configs := make([]zfshttp.Config, len(c.Roots))
+	for i, root := range c.Roots {
+		configs[i] = c.HTTP
+		configs[i].HTTPPathPrefix = root.HTTPPathPrefix
+		configs[i].ParentDataset = root.ParentDataset
+	}
+	return configs
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+Listen: "127.0.0.1:9000"
+Tokens: [secret]
+LogLevel: debug
+HTTP:
+  SpeedBytesPerSecond: 1000
+  Permissions:
+    AllowDestroySnapshots: true
+Roots:
+  - HTTPPathPrefix: /a
+    ParentDataset: pool/a
+  - HTTPPathPrefix: /b
+    ParentDataset: pool/b
+`
+
+func Test_loadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(testConfig), 0o600))
+
+	env := map[string]string{
+		"ZFS_HTTP_LISTEN": ":9001",
+		"ZFS_HTTP_TOKENS": "one, two",
+	}
+	conf, err := loadConfig(file, func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	require.NoError(t, err)
+	require.Equal(t, ":9001", conf.Listen)
+	require.Equal(t, []string{"one", "two"}, conf.Tokens)
+	require.Equal(t, "debug", conf.LogLevel)
+	require.Equal(t, "text", conf.LogFormat)
+	require.EqualValues(t, 1000, conf.HTTP.SpeedBytesPerSecond)
+	require.Equal(t, 3, conf.HTTP.MaximumConcurrentReceives)
+	require.True(t, conf.HTTP.Permissions.AllowDestroySnapshots)
+
+	configs := conf.httpConfigs()
+	require.Len(t, configs, 2)
+	require.Equal(t, "/b", configs[1].HTTPPathPrefix)
+	require.Equal(t, "pool/b", configs[1].ParentDataset)
+	require.EqualValues(t, 1000, configs[1].SpeedBytesPerSecond)
+
+	env["ZFS_HTTP_TLS_CERT_FILE"] = "cert.pem"
+	_, err = loadConfig(file, func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	require.Error(t, err)
+}
+
+func Test_requireToken(t *testing.T) {
+	handler := requireToken(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), []string{"one", "two"})
+
+	for token, status := range map[string]int{
+		"":           http.StatusUnauthorized,
+		"Bearer":     http.StatusUnauthorized,
+		"Bearer on":  http.StatusUnauthorized,
+		"two":        http.StatusUnauthorized,
+		"Bearer two": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/filesystems", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, status, rec.Code, token)
+	}
+}
@@ -0,0 +1,151 @@
+// Command zfs-http-server serves ZFS datasets over HTTP using the http package, so snapshots can be sent to and
+// received from this machine.
+//
+// Usage:
+//
+//	zfs-http-server [-config file]
+//
+// The configuration is read from a YAML (or JSON) file, environment variables prefixed with ZFS_HTTP_ override it:
+// LISTEN, TLS_CERT_FILE, TLS_KEY_FILE, TOKENS (comma separated), LOG_LEVEL, LOG_FORMAT, PATH_PREFIX, PARENT_DATASET
+// and SPEED_LIMIT_BPS. The config file can be given with ZFS_HTTP_CONFIG as well.
+//
+// When run as a systemd service with Type=notify, the server reports it is ready once it listens, and pings the
+// watchdog when WatchdogSec is set.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	zfshttp "github.com/vansante/go-zfsutils/http"
+	"github.com/vansante/go-zfsutils/sdnotify"
+)
+
+func main() {
+	configFile := flag.String("config", os.Getenv(envPrefix+"CONFIG"), "path to the YAML or JSON config file")
+	flag.Parse()
+
+	conf, err := loadConfig(*configFile, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zfs-http-server: %v\n", err)
+		os.Exit(2)
+	}
+
+	logger, _ := conf.NewLogger(os.Stderr) // Validated when loading
+	err = run(conf, logger)
+	if err != nil {
+		logger.Error("zfs-http-server: Stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves until SIGINT or SIGTERM is received, then gives running requests time to finish
+func run(conf Config, logger *slog.Logger) error {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Streams keep running on their own context while shutting down, it is only cancelled after the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := &http.Server{
+		Addr:              conf.Listen,
+		Handler:           newHandler(ctx, conf, logger),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", conf.Listen)
+	if err != nil {
+		return err
+	}
+	logger.Info("zfs-http-server: Listening", "address", listener.Addr(), "tls", conf.TLS.CertFile != "")
+
+	errs := make(chan error, 1)
+	go func() {
+		if conf.TLS.CertFile != "" {
+			errs <- server.ServeTLS(listener, conf.TLS.CertFile, conf.TLS.KeyFile)
+			return
+		}
+		errs <- server.Serve(listener)
+	}()
+
+	notifier := sdnotify.New()
+	err = notifier.Notify(sdnotify.StateReady)
+	if err != nil {
+		logger.Warn("zfs-http-server: Error notifying systemd", "error", err)
+	}
+	go notifier.RunWatchdog(signalCtx, nil, logger)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-signalCtx.Done():
+	}
+
+	logger.Info("zfs-http-server: Shutting down", "timeoutSeconds", conf.ShutdownTimeoutSeconds)
+	err = notifier.Notify(sdnotify.StateStopping)
+	if err != nil {
+		logger.Warn("zfs-http-server: Error notifying systemd", "error", err)
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(conf.ShutdownTimeoutSeconds)*time.Second)
+	defer shutdownCancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Warn("zfs-http-server: Requests still running after shutdown timeout", "error", err)
+		cancel()
+		_ = server.Close()
+	}
+
+	err = <-errs
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// newHandler creates the zfs http handlers for all roots, requiring a token when tokens are configured
+func newHandler(ctx context.Context, conf Config, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	for _, httpConf := range conf.httpConfigs() {
+		logger.Info("zfs-http-server: Serving dataset", "parentDataset", httpConf.ParentDataset, "pathPrefix", httpConf.HTTPPathPrefix)
+		mux.Handle(strings.TrimSuffix(httpConf.HTTPPathPrefix, "/")+"/", zfshttp.NewHTTP(ctx, httpConf, logger))
+	}
+	if len(conf.Tokens) == 0 {
+		return mux
+	}
+	return requireToken(mux, conf.Tokens)
+}
+
+// requireToken only lets requests through that have one of the tokens as bearer token
+func requireToken(next http.Handler, tokens []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if ok && validToken(token, tokens) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
+
+func validToken(token string, tokens []string) bool {
+	valid := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vansante/go-zfsutils/job"
+	"github.com/vansante/go-zfsutils/logging"
+)
+
+const defaultWatchdogStallSeconds = 2 * 60 * 60
+
+// Config is the configuration of the replication daemon, read from a YAML (or JSON) file
+type Config struct {
+	// Config configures the level and format of the logs
+	logging.Config `yaml:",inline"`
+
+	// Job is the configuration of the job runner
+	Job job.Config `yaml:"Job"`
+	// Datasets are the datasets to replicate, which must be below the parent dataset of the job configuration
+	Datasets []Dataset `yaml:"Datasets"`
+
+	// WatchdogStallSeconds is the time without any job completing after which the systemd watchdog is no longer
+	// pinged, so systemd restarts the daemon. Zero pings the watchdog as long as the daemon runs.
+	WatchdogStallSeconds int64 `yaml:"WatchdogStallSeconds"`
+}
+
+// Dataset configures the snapshot schedule, retention and target of a dataset
+type Dataset struct {
+	Name                string `yaml:"Name"`
+	job.DatasetSchedule `yaml:",inline"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.Config.ApplyDefaults()
+	c.WatchdogStallSeconds = defaultWatchdogStallSeconds
+	c.Job.ApplyDefaults()
+}
+
+func loadConfig(file string) (Config, error) {
+	conf := Config{}
+	conf.ApplyDefaults()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return conf, fmt.Errorf("error reading config file: %w", err)
+	}
+	err = yaml.Unmarshal(data, &conf)
+	if err != nil {
+		return conf, fmt.Errorf("error parsing config file %s: %w", file, err)
+	}
+	return conf, conf.validate()
+}
+
+func (c *Config) validate() error {
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	if c.WatchdogStallSeconds < 0 {
+		return fmt.Errorf("invalid watchdog stall seconds %d", c.WatchdogStallSeconds)
+	}
+	if c.Job.ParentDataset == "" {
+		return fmt.Errorf("no parent dataset configured for the jobs")
+	}
+
+	names := make(map[string]bool, len(c.Datasets))
+	for i, ds := range c.Datasets {
+		if !strings.HasPrefix(ds.Name, strings.TrimRight(c.Job.ParentDataset, "/")+"/") {
+			return fmt.Errorf("dataset %d (%s) is not below parent dataset %s", i, ds.Name, c.Job.ParentDataset)
+		}
+		if names[ds.Name] {
+			return fmt.Errorf("dataset %d (%s) is configured twice", i, ds.Name)
+		}
+		names[ds.Name] = true
+	}
+	return nil
+}
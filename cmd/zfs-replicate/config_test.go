@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+const testConfig = `
+LogFormat: json
+Job:
+  ParentDataset: pool/data
+  SendRoutines: 1
+  Properties:
+    Namespace: nl.test
+Datasets:
+  - Name: pool/data/fs
+    SnapshotIntervalMinutes: 15
+    SnapshotRetentionCount: 96
+    SendTo: https://backup:7654
+`
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(config), 0o600))
+	return file
+}
+
+func Test_loadConfig(t *testing.T) {
+	conf, err := loadConfig(writeConfig(t, testConfig))
+	require.NoError(t, err)
+	require.Equal(t, "json", conf.LogFormat)
+	require.EqualValues(t, defaultWatchdogStallSeconds, conf.WatchdogStallSeconds)
+	require.Equal(t, "pool/data", conf.Job.ParentDataset)
+	require.Equal(t, 1, conf.Job.SendRoutines)
+	require.True(t, conf.Job.EnableSnapshotCreate)
+	require.Equal(t, "nl.test", conf.Job.Properties.Namespace)
+	require.Equal(t, "snapshot-send-to", conf.Job.Properties.SnapshotSendTo)
+	require.Len(t, conf.Datasets, 1)
+	require.EqualValues(t, 96, conf.Datasets[0].SnapshotRetentionCount)
+	require.Equal(t, "https://backup:7654", conf.Datasets[0].SendTo)
+
+	_, err = loadConfig(writeConfig(t, testConfig+"  - Name: pool/other\n"))
+	require.Error(t, err)
+}
+
+func Test_applySchedules(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	_, err := zfs.CreateFilesystem(ctx, "pool/data/fs", zfs.CreateFilesystemOptions{
+		CreateParents: true,
+		Properties:    map[string]string{"nl.test:snapshot-retention-minutes": "60"},
+	})
+	require.NoError(t, err)
+
+	conf, err := loadConfig(writeConfig(t, testConfig))
+	require.NoError(t, err)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	props := []string{"nl.test:snapshot-interval-minutes", "nl.test:snapshot-retention-minutes"}
+	require.NoError(t, applySchedules(ctx, conf, logger, true))
+	ds, err := zfs.GetDataset(ctx, "pool/data/fs", props...)
+	require.NoError(t, err)
+	require.Equal(t, zfs.Properties{props[0]: "", props[1]: "60"}, ds.ExtraProps)
+
+	require.NoError(t, applySchedules(ctx, conf, logger, false))
+	ds, err = zfs.GetDataset(ctx, "pool/data/fs", props...)
+	require.NoError(t, err)
+	require.Equal(t, zfs.Properties{props[0]: "15", props[1]: ""}, ds.ExtraProps)
+}
+
+func Test_dryRunPrune(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/data/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "old", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.NoError(t, snap.SetProperty(ctx, "nl.test:delete-at", time.Now().Add(-time.Hour).Format(time.RFC3339)))
+
+	conf, err := loadConfig(writeConfig(t, testConfig))
+	require.NoError(t, err)
+	require.NoError(t, dryRunPrune(ctx, conf, slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	// Nothing is destroyed, nor created
+	snaps, err := fs.Snapshots(ctx, zfs.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	require.Equal(t, "pool/data/fs@old", snaps[0].Name)
+}
+
+func Test_stallCheck(t *testing.T) {
+	require.Nil(t, stallCheck(time.Now, 0))
+
+	check := stallCheck(func() time.Time { return time.Now().Add(-time.Minute) }, time.Hour)
+	require.NoError(t, check())
+	check = stallCheck(func() time.Time { return time.Now().Add(-2 * time.Hour) }, time.Hour)
+	require.Error(t, check())
+}
@@ -0,0 +1,195 @@
+// Command zfs-replicate runs the jobs of the job package as a daemon: it creates snapshots of datasets on a schedule,
+// sends them to zfs http servers and prunes them according to their retention.
+//
+// Usage:
+//
+//	zfs-replicate -config file [-dry-run] [-once]
+//
+// The datasets in the YAML (or JSON) config file get their schedule, retention and target set as properties, after
+// which the jobs pick them up. With -dry-run the property changes are only logged, and the prune jobs run once with
+// zfs.WithDryRun, logging what they would destroy without destroying anything.
+// With -once every job runs a single time, instead of periodically until SIGINT or SIGTERM is received.
+//
+// When run as a systemd service with Type=notify, the daemon reports it is ready after the first job completed
+// successfully, and pings the watchdog when WatchdogSec is set. As the first jobs only run minutes after starting,
+// the unit needs a long start timeout, like TimeoutStartSec=20min.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/job"
+	"github.com/vansante/go-zfsutils/sdnotify"
+)
+
+func main() {
+	configFile := flag.String("config", "", "path to the YAML or JSON config file")
+	dryRun := flag.Bool("dry-run", false, "only log the dataset property changes and what the prune jobs would destroy")
+	once := flag.Bool("once", false, "run every job once and exit")
+	flag.Parse()
+
+	if *configFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zfs-replicate: %v\n", err)
+		os.Exit(2)
+	}
+
+	logger, _ := conf.NewLogger(os.Stderr) // Validated when loading
+	err = run(conf, logger, *dryRun, *once)
+	if err != nil {
+		logger.Error("zfs-replicate: Stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(conf Config, logger *slog.Logger, dryRun, once bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := applySchedules(ctx, conf, logger, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return dryRunPrune(ctx, conf, logger)
+	}
+
+	runner := job.NewRunner(ctx, conf.Job, logger)
+	if once {
+		return runner.RunOnce()
+	}
+
+	notifier := sdnotify.New()
+	lastCompleted := notifySystemd(notifier, runner, logger)
+	go notifier.RunWatchdog(ctx, stallCheck(lastCompleted, time.Duration(conf.WatchdogStallSeconds)*time.Second), logger)
+
+	runner.Run()
+	logger.Info("zfs-replicate: Running", "parentDataset", conf.Job.ParentDataset, "datasets", len(conf.Datasets))
+	<-ctx.Done()
+	logger.Info("zfs-replicate: Stopping")
+	err = notifier.Notify(sdnotify.StateStopping)
+	if err != nil {
+		logger.Warn("zfs-replicate: Error notifying systemd", "error", err)
+	}
+	return nil
+}
+
+// dryRunPrune runs the enabled prune jobs once in dry-run mode, so nothing is destroyed. Jobs changing datasets
+// otherwise, like creating, sending and marking snapshots, do not run.
+func dryRunPrune(ctx context.Context, conf Config, logger *slog.Logger) error {
+	jobConf := conf.Job
+	jobConf.EnableSnapshotCreate = false
+	jobConf.EnableSnapshotSend = false
+	jobConf.EnableSnapshotMark = false
+	jobConf.EnableSnapshotMarkRemote = false
+	jobConf.EnablePoolScrub = false
+
+	ctx = zfs.WithDryRun(ctx)
+	err := job.NewRunner(ctx, jobConf, logger).RunOnce()
+
+	var reclaim uint64
+	commands := zfs.DryRunCommands(ctx)
+	for _, cmd := range commands {
+		if cmd.Destroy != nil {
+			reclaim += cmd.Destroy.Reclaim
+		}
+	}
+	logger.Info("zfs-replicate: Dry run completed", "commands", len(commands), "reclaim", reclaim)
+	return err
+}
+
+// notifySystemd reports the daemon ready once a job completed, and returns a function returning the time
+// the last job completed, which is the start time until then
+func notifySystemd(notifier *sdnotify.Notifier, runner *job.Runner, logger *slog.Logger) func() time.Time {
+	var lastCompleted atomic.Int64
+	lastCompleted.Store(time.Now().UnixNano())
+	var ready sync.Once
+
+	runner.AddListener(job.JobCompletedEvent, func(arguments ...any) {
+		lastCompleted.Store(time.Now().UnixNano())
+		ready.Do(func() {
+			if !notifier.Enabled() {
+				return
+			}
+			err := notifier.Notify(sdnotify.StateReady, fmt.Sprintf("STATUS=Completed %s job", arguments[0]))
+			if err != nil {
+				logger.Warn("zfs-replicate: Error notifying systemd", "error", err)
+				return
+			}
+			logger.Info("zfs-replicate: Notified systemd of readiness")
+		})
+	})
+	return func() time.Time {
+		return time.Unix(0, lastCompleted.Load())
+	}
+}
+
+// stallCheck returns a watchdog check failing when no job completed for the stall duration, zero disables it
+func stallCheck(lastCompleted func() time.Time, stall time.Duration) func() error {
+	if stall <= 0 {
+		return nil
+	}
+	return func() error {
+		since := time.Since(lastCompleted())
+		if since > stall {
+			return fmt.Errorf("no job completed for %s", since.Round(time.Second))
+		}
+		return nil
+	}
+}
+
+// applySchedules sets the schedule of every configured dataset as its properties, on a dry run it only logs the changes
+func applySchedules(ctx context.Context, conf Config, logger *slog.Logger, dryRun bool) error {
+	for _, dsConf := range conf.Datasets {
+		props := conf.Job.ScheduleProperties(dsConf.DatasetSchedule)
+		names := make([]string, 0, len(props))
+		for prop := range props {
+			names = append(names, prop)
+		}
+		slices.Sort(names)
+
+		ds, err := zfs.GetDataset(ctx, dsConf.Name, names...)
+		if err != nil {
+			return fmt.Errorf("error retrieving dataset %s: %w", dsConf.Name, err)
+		}
+
+		for _, prop := range names {
+			value := props[prop]
+			current := ds.ExtraProps[prop]
+			if value == current {
+				continue
+			}
+
+			logger := logger.With("dataset", ds.Name, "property", prop, "value", value, "current", current)
+			if dryRun {
+				logger.Info("zfs-replicate: Would change dataset property")
+				continue
+			}
+			if value == "" {
+				err = ds.InheritProperty(ctx, prop)
+			} else {
+				err = ds.SetProperty(ctx, prop, value)
+			}
+			if err != nil {
+				return fmt.Errorf("error changing property %s of dataset %s: %w", prop, ds.Name, err)
+			}
+			logger.Info("zfs-replicate: Changed dataset property")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package zfs
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"sync/atomic"
+)
+
+// CommandConfig configures how the zfs and zpool processes are started, for installs outside the PATH, chroots or
+// shims in tests. It applies to the commands run by this package and by an ExecExecutor.
+type CommandConfig struct {
+	// ZFSPath and ZpoolPath are the paths of the zfs and zpool binaries, empty to look them up in the PATH.
+	// With an ExecExecutor running them over ssh, these are the paths on the remote host.
+	ZFSPath   string `json:"ZFSPath" yaml:"ZFSPath"`
+	ZpoolPath string `json:"ZpoolPath" yaml:"ZpoolPath"`
+	// Env are extra environment variables of the processes in the form key=value, on top of the environment of
+	// this process
+	Env []string `json:"Env" yaml:"Env"`
+	// WorkDir is the working directory of the processes, empty for the working directory of this process
+	WorkDir string `json:"WorkDir" yaml:"WorkDir"`
+}
+
+var commandConfig atomic.Pointer[CommandConfig]
+
+// SetCommandConfig sets the package-wide config of the zfs and zpool processes, nil restores the default.
+// Cached lookups are cleared, as they may have been made against another zfs binary or environment.
+func SetCommandConfig(config *CommandConfig) {
+	defer ClearCache()
+	if config == nil {
+		commandConfig.Store(nil)
+		return
+	}
+	c := *config
+	c.Env = slices.Clone(c.Env)
+	commandConfig.Store(&c)
+}
+
+type commandConfigContextKey struct{}
+
+// WithCommandConfig returns a context that makes the commands run with it use the given config, instead of the one
+// set with SetCommandConfig. Lookups of these commands are not cached, see SetCacheTTL.
+func WithCommandConfig(ctx context.Context, config CommandConfig) context.Context {
+	return context.WithValue(ctx, commandConfigContextKey{}, config)
+}
+
+// loadCommandConfig returns the config of the context, or the one set with SetCommandConfig
+func loadCommandConfig(ctx context.Context) CommandConfig {
+	if config, ok := ctx.Value(commandConfigContextKey{}).(CommandConfig); ok {
+		return config
+	}
+	config := commandConfig.Load()
+	if config == nil {
+		return CommandConfig{}
+	}
+	return *config
+}
+
+// path returns the path to run the zfs or zpool command from
+func (c CommandConfig) path(cmd string) string {
+	switch {
+	case cmd == Binary && c.ZFSPath != "":
+		return c.ZFSPath
+	case cmd == PoolBinary && c.ZpoolPath != "":
+		return c.ZpoolPath
+	}
+	return cmd
+}
+
+// apply sets the environment and working directory of the process
+func (c CommandConfig) apply(cmd *exec.Cmd) {
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	if c.WorkDir != "" {
+		cmd.Dir = c.WorkDir
+	}
+}
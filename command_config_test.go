@@ -0,0 +1,45 @@
+package zfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CommandConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shim is a shell script")
+	}
+	dir := t.TempDir()
+	shim := filepath.Join(dir, "zfs-shim")
+	err := os.WriteFile(shim, []byte("#!/bin/sh\nprintf '%s\\t%s\\t%s\\n' \"$PWD\" \"$SHIM_VAR\" \"$*\"\n"), 0o755)
+	require.NoError(t, err)
+
+	config := CommandConfig{
+		ZFSPath: shim,
+		Env:     []string{"SHIM_VAR=value"},
+		WorkDir: dir,
+	}
+	expected := [][]string{{dir, "value", "list -H pool"}}
+
+	ctx := WithCommandConfig(context.Background(), config)
+	out, err := zfsOutput(ctx, "list", "-H", "pool")
+	require.NoError(t, err)
+	require.Equal(t, expected, out)
+
+	SetCommandConfig(&config)
+	defer SetCommandConfig(nil)
+	out, err = zfsOutput(context.Background(), "list", "-H", "pool")
+	require.NoError(t, err)
+	require.Equal(t, expected, out)
+
+	out, err = zfsOutput(WithExecutor(context.Background(), &ExecExecutor{}), "list", "-H", "pool")
+	require.NoError(t, err)
+	require.Equal(t, expected, out)
+
+	require.Equal(t, PoolBinary, config.path(PoolBinary))
+}
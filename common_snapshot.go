@@ -0,0 +1,132 @@
+package zfs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// commonSnapshotFields are the fields needed to match snapshots by GUID and order them
+var commonSnapshotFields = []string{PropertyName, PropertyType, PropertyGUID, PropertyCreateTXG}
+
+// MostRecentCommonSnapshot returns the most recent snapshot of the source dataset that the target dataset has too,
+// which is the base for an incremental send from the source to the target. Snapshots are matched by GUID, so
+// renamed snapshots match, and snapshots with the same name but other contents do not.
+// It returns nil when the datasets have no snapshot in common.
+func MostRecentCommonSnapshot(ctx context.Context, source, target string) (*Dataset, error) {
+	targetSnaps, err := ListSnapshots(ctx, ListOptions{ParentDataset: target, Depth: 1, Fields: commonSnapshotFields})
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots of %s: %w", target, err)
+	}
+	return FindCommonSnapshot(ctx, source, targetSnaps)
+}
+
+// FindCommonSnapshot returns the most recent snapshot of the source filesystem that is in the target snapshots too,
+// matched by GUID, so the target snapshots can come from another host. It returns nil when there is none.
+func FindCommonSnapshot(ctx context.Context, sourceFilesystem string, targetSnapshots []Dataset) (*Dataset, error) {
+	sourceSnaps, err := sourceSnapshots(ctx, sourceFilesystem)
+	if err != nil {
+		return nil, err
+	}
+	return CommonSnapshot(sourceSnaps, targetSnapshots), nil
+}
+
+// sourceSnapshots lists the snapshots of the filesystem with the fields to match them, ordered by creation TXG
+func sourceSnapshots(ctx context.Context, filesystem string) ([]Dataset, error) {
+	snaps, err := ListSnapshots(ctx, ListOptions{ParentDataset: filesystem, Depth: 1, Fields: commonSnapshotFields})
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots of %s: %w", filesystem, err)
+	}
+	slices.SortFunc(snaps, func(a, b Dataset) int {
+		return cmp.Compare(a.CreateTXG, b.CreateTXG)
+	})
+	return snaps, nil
+}
+
+// CommonSnapshot returns the most recent snapshot of the source list that is in the target list too, matched by GUID,
+// or nil. The source snapshots must have their GUID and CreateTXG set, the target snapshots their GUID.
+// The returned snapshot points into the source list.
+func CommonSnapshot(source, target []Dataset) *Dataset {
+	guids := make(map[uint64]struct{}, len(target))
+	for _, snap := range target {
+		guids[snap.GUID] = struct{}{}
+	}
+
+	var common *Dataset
+	for i := range source {
+		snap := &source[i]
+		if _, ok := guids[snap.GUID]; !ok {
+			continue
+		}
+		if common == nil || snap.CreateTXG > common.CreateTXG {
+			common = snap
+		}
+	}
+	return common
+}
+
+// WriterFactory returns the writer the stream of the snapshot is sent to, which is incremental from the base when it
+// is not nil. Closing the writer must complete the receive of the stream, its error is returned by ReplicateDataset.
+type WriterFactory func(ctx context.Context, snapshot, base *Dataset) (io.WriteCloser, error)
+
+// ReplicateOptions are options you can specify to customize a replication
+type ReplicateOptions struct {
+	// SendOptions are used for sending every snapshot, their incremental base is set by the replication
+	SendOptions
+	// TargetSnapshots are the snapshots the target has, empty when the target does not exist yet.
+	// Only their GUIDs are used, so they can come from another host.
+	TargetSnapshots []Dataset
+}
+
+// ReplicateDataset sends the snapshots of the source filesystem that the target does not have yet, oldest first, each
+// to a writer of the factory. When the target has no snapshots, the first snapshot is sent in full and the others
+// incrementally, otherwise the snapshots after the most recent common snapshot are sent incrementally.
+// It returns the snapshots that were sent. When the target has snapshots but none in common with the source,
+// ErrNoCommonSnapshot is returned.
+func ReplicateDataset(ctx context.Context, source string, newWriter WriterFactory, options ReplicateOptions) ([]Dataset, error) {
+	snaps, err := sourceSnapshots(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("%s: %w", source, ErrNoSnapshots)
+	}
+
+	toSend := snaps
+	base := CommonSnapshot(snaps, options.TargetSnapshots)
+	switch {
+	case base != nil:
+		toSend = snaps[slices.IndexFunc(snaps, func(snap Dataset) bool { return snap.GUID == base.GUID })+1:]
+	case len(options.TargetSnapshots) > 0:
+		return nil, fmt.Errorf("%s: %w", source, ErrNoCommonSnapshot)
+	}
+
+	sent := make([]Dataset, 0, len(toSend))
+	for i := range toSend {
+		snap := &toSend[i]
+		err = replicateSnapshot(ctx, snap, base, newWriter, options.SendOptions)
+		if err != nil {
+			return sent, fmt.Errorf("error sending %s: %w", snap.Name, err)
+		}
+		sent = append(sent, *snap)
+		base = snap
+	}
+	return sent, nil
+}
+
+func replicateSnapshot(ctx context.Context, snap, base *Dataset, newWriter WriterFactory, options SendOptions) error {
+	output, err := newWriter(ctx, snap, base)
+	if err != nil {
+		return err
+	}
+	options.IncrementalBase = base
+	options.IncludeIntermediarySnapshots = false
+	_, err = snap.SendSnapshot(ctx, output, options)
+	closeErr := output.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,104 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CommonSnapshot(t *testing.T) {
+	source := []Dataset{
+		{Name: "pool/src@a", GUID: 1, CreateTXG: 10},
+		{Name: "pool/src@c", GUID: 3, CreateTXG: 30},
+		{Name: "pool/src@b", GUID: 2, CreateTXG: 20},
+		{Name: "pool/src@d", GUID: 4, CreateTXG: 40},
+	}
+	target := []Dataset{
+		{Name: "pool/dst@a", GUID: 1},
+		{Name: "pool/dst@b", GUID: 2},
+		{Name: "pool/dst@renamed", GUID: 3},
+		{Name: "pool/dst@d", GUID: 99},
+	}
+
+	common := CommonSnapshot(source, target)
+	require.NotNil(t, common)
+	require.Equal(t, "pool/src@c", common.Name)
+
+	require.Nil(t, CommonSnapshot(source, target[3:]))
+	require.Nil(t, CommonSnapshot(nil, target))
+}
+
+type replicateWriter struct {
+	bytes.Buffer
+	name   string
+	closed *[]string
+}
+
+func (w *replicateWriter) Close() error {
+	*w.closed = append(*w.closed, w.name+": "+w.String())
+	return nil
+}
+
+func Test_ReplicateDataset(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		switch args[0] {
+		case "get":
+			for _, snap := range []struct{ name, guid, txg string }{
+				{"pool/src@b", "2", "20"},
+				{"pool/src@a", "1", "10"},
+				{"pool/src@c", "3", "30"},
+			} {
+				_, err := fmt.Fprintf(stdout, "%[1]s\tname\t%[1]s\n%[1]s\ttype\tsnapshot\n%[1]s\tguid\t%[2]s\n%[1]s\tcreatetxg\t%[3]s\n",
+					snap.name, snap.guid, snap.txg)
+				if err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		case "send":
+			_, err := io.WriteString(stdout, strings.Join(args, " "))
+			return "", err
+		}
+		return "", fmt.Errorf("unexpected command %v", args)
+	}))
+
+	var closed []string
+	newWriter := func(_ context.Context, snap, base *Dataset) (io.WriteCloser, error) {
+		name := snap.Name
+		if base != nil {
+			name = base.Name + ".." + name
+		}
+		return &replicateWriter{name: name, closed: &closed}, nil
+	}
+
+	sent, err := ReplicateDataset(ctx, "pool/src", newWriter, ReplicateOptions{})
+	require.NoError(t, err)
+	require.Len(t, sent, 3)
+	require.Equal(t, []string{
+		"pool/src@a: send pool/src@a",
+		"pool/src@a..pool/src@b: send -i pool/src@a pool/src@b",
+		"pool/src@b..pool/src@c: send -i pool/src@b pool/src@c",
+	}, closed)
+
+	closed = nil
+	sent, err = ReplicateDataset(ctx, "pool/src", newWriter, ReplicateOptions{
+		TargetSnapshots: []Dataset{{Name: "pool/dst@renamed", GUID: 2}},
+	})
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"pool/src@b..pool/src@c: send -i pool/src@b pool/src@c"}, closed)
+
+	_, err = ReplicateDataset(ctx, "pool/src", newWriter, ReplicateOptions{
+		TargetSnapshots: []Dataset{{Name: "pool/dst@other", GUID: 99}},
+	})
+	require.ErrorIs(t, err, ErrNoCommonSnapshot)
+
+	common, err := FindCommonSnapshot(ctx, "pool/src", []Dataset{{GUID: 1}, {GUID: 2}})
+	require.NoError(t, err)
+	require.Equal(t, "pool/src@b", common.Name)
+}
@@ -0,0 +1,66 @@
+package csiutil
+
+import "fmt"
+
+const (
+	defaultCapacityBytes       = 1024 * 1024 * 1024 // 1 GiB
+	defaultCapacityAlignment   = 1024 * 1024        // 1 MiB, a multiple of every volblocksize
+	defaultCloneSnapshotPrefix = "csi-clone-"
+)
+
+// Config configures the provisioner
+type Config struct {
+	// ParentDataset is the dataset the volumes are created in
+	ParentDataset string `json:"ParentDataset" yaml:"ParentDataset"`
+	// VolumeProperties are set on every new volume
+	VolumeProperties map[string]string `json:"VolumeProperties" yaml:"VolumeProperties"`
+	// Sparse creates volumes without reserving their capacity
+	Sparse bool `json:"Sparse" yaml:"Sparse"`
+	// DefaultCapacityBytes is the capacity of volumes when no capacity is requested
+	DefaultCapacityBytes uint64 `json:"DefaultCapacityBytes" yaml:"DefaultCapacityBytes"`
+	// CapacityAlignment rounds capacities up to a multiple of it, so it must be a multiple of the volblocksize
+	CapacityAlignment uint64 `json:"CapacityAlignment" yaml:"CapacityAlignment"`
+	// CloneSnapshotPrefix prefixes the snapshots taken to clone a volume from another volume
+	CloneSnapshotPrefix string `json:"CloneSnapshotPrefix" yaml:"CloneSnapshotPrefix"`
+
+	Properties Properties `json:"Properties" yaml:"Properties"`
+}
+
+// ApplyDefaults applies all the default values to the configuration
+func (c *Config) ApplyDefaults() {
+	c.DefaultCapacityBytes = defaultCapacityBytes
+	c.CapacityAlignment = defaultCapacityAlignment
+	c.CloneSnapshotPrefix = defaultCloneSnapshotPrefix
+	c.Properties.ApplyDefaults()
+}
+
+// Properties sets the names of the custom ZFS properties to use
+type Properties struct {
+	Namespace string `json:"Namespace" yaml:"Namespace"`
+
+	Managed  string `json:"Managed" yaml:"Managed"`
+	Capacity string `json:"Capacity" yaml:"Capacity"`
+}
+
+const (
+	defaultNamespace = "com.github.vansante"
+
+	defaultManagedProperty  = "csi-managed"
+	defaultCapacityProperty = "csi-capacity"
+)
+
+// ApplyDefaults applies all the default values to the Properties
+func (p *Properties) ApplyDefaults() {
+	p.Namespace = defaultNamespace
+
+	p.Managed = defaultManagedProperty
+	p.Capacity = defaultCapacityProperty
+}
+
+func (p *Properties) managed() string {
+	return fmt.Sprintf("%s:%s", p.Namespace, p.Managed)
+}
+
+func (p *Properties) capacity() string {
+	return fmt.Sprintf("%s:%s", p.Namespace, p.Capacity)
+}
@@ -0,0 +1,137 @@
+// Package csiutil provides the provisioning primitives a Kubernetes CSI driver needs, built on the zfs package.
+// Volumes are filesystems or zvols directly below a parent dataset and their ID is the dataset name. The ID of a
+// snapshot is the snapshot name, like: parent/volume@snapshot. All operations are idempotent, so a CSI driver can
+// retry them safely, and only datasets created by the provisioner are ever changed or destroyed. Filesystems are
+// created with canmount=noauto, so they are only mounted by the node using them.
+package csiutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const managedValue = "true"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,200}$`)
+
+var (
+	// ErrInvalidName is returned for volume or snapshot names that cannot be used in a dataset name
+	ErrInvalidName = errors.New("invalid name")
+	// ErrInvalidID is returned for IDs that do not refer to a volume or snapshot of the provisioner
+	ErrInvalidID = errors.New("invalid id")
+	// ErrNotManaged is returned for datasets that were not created by the provisioner
+	ErrNotManaged = errors.New("dataset is not managed by the provisioner")
+	// ErrInvalidCapacity is returned when a capacity range cannot be satisfied
+	ErrInvalidCapacity = errors.New("invalid capacity range")
+	// ErrVolumeExists is returned when a volume with the same name exists, but it does not match the request
+	ErrVolumeExists = errors.New("volume exists with different parameters")
+	// ErrSnapshotExists is returned when a snapshot with the same name exists for another volume
+	ErrSnapshotExists = errors.New("snapshot exists for another volume")
+	// ErrIncompatibleSource is returned when a volume cannot be created from the requested source
+	ErrIncompatibleSource = errors.New("incompatible volume source")
+	// ErrVolumeInUse is returned when deleting a filesystem that is still mounted
+	ErrVolumeInUse = errors.New("volume is in use")
+	// ErrVolumeHasSnapshots is returned when deleting a volume that still has snapshots
+	ErrVolumeHasSnapshots = errors.New("volume has snapshots")
+	// ErrVolumeHasClones is returned when deleting a volume that other volumes were cloned from
+	ErrVolumeHasClones = errors.New("volume has dependent clones")
+)
+
+// Provisioner creates, expands, snapshots, clones and deletes volumes below the parent dataset
+type Provisioner struct {
+	config Config
+	logger *slog.Logger
+}
+
+// NewProvisioner creates a new provisioner
+func NewProvisioner(conf Config, logger *slog.Logger) *Provisioner {
+	return &Provisioner{
+		config: conf,
+		logger: logger,
+	}
+}
+
+func (p *Provisioner) parent() string {
+	return strings.TrimRight(p.config.ParentDataset, "/")
+}
+
+// datasetName returns the dataset name for a volume name
+func (p *Provisioner) datasetName(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return fmt.Sprintf("%s/%s", p.parent(), name), nil
+}
+
+// checkVolumeID checks whether the ID is that of a volume directly below the parent dataset
+func (p *Provisioner) checkVolumeID(id string) error {
+	name, ok := strings.CutPrefix(id, p.parent()+"/")
+	if !ok || !validName.MatchString(name) {
+		return fmt.Errorf("%w: %q is not a volume", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// splitSnapshotID returns the volume ID and snapshot name of a snapshot ID
+func (p *Provisioner) splitSnapshotID(id string) (volumeID, name string, err error) {
+	volumeID, name, ok := strings.Cut(id, "@")
+	if !ok || !validName.MatchString(name) || p.checkVolumeID(volumeID) != nil {
+		return "", "", fmt.Errorf("%w: %q is not a snapshot", ErrInvalidID, id)
+	}
+	return volumeID, name, nil
+}
+
+// getManaged retrieves a dataset, and checks whether it was created by the provisioner
+func (p *Provisioner) getManaged(ctx context.Context, name string) (*zfs.Dataset, error) {
+	ds, err := zfs.GetDataset(ctx, name, p.config.Properties.managed(), p.config.Properties.capacity())
+	if err != nil {
+		return nil, err
+	}
+	if ds.ExtraProps[p.config.Properties.managed()] != managedValue {
+		return nil, fmt.Errorf("%w: %s", ErrNotManaged, name)
+	}
+	return ds, nil
+}
+
+// capacity determines the capacity for a capacity range, a zero limit means no limit
+func (p *Provisioner) capacity(requiredBytes, limitBytes uint64) (uint64, error) {
+	capacity := requiredBytes
+	if capacity == 0 {
+		capacity = p.config.DefaultCapacityBytes
+		if limitBytes > 0 && capacity > limitBytes {
+			capacity = limitBytes
+		}
+	}
+	if align := p.config.CapacityAlignment; align > 0 && capacity%align != 0 {
+		capacity += align - capacity%align
+	}
+	if capacity == 0 || (limitBytes > 0 && capacity > limitBytes) {
+		return 0, fmt.Errorf("%w: required %d bytes, limit %d bytes, aligned to %d bytes",
+			ErrInvalidCapacity, requiredBytes, limitBytes, p.config.CapacityAlignment)
+	}
+	return capacity, nil
+}
+
+// datasetCapacity returns the capacity of a volume dataset
+func datasetCapacity(ds *zfs.Dataset) uint64 {
+	if ds.Type == zfs.DatasetVolume {
+		return ds.Volsize
+	}
+	return ds.Refquota
+}
+
+// snapshotCapacity returns the capacity a snapshot was taken with
+func (p *Provisioner) snapshotCapacity(snap *zfs.Dataset) uint64 {
+	capacity, err := strconv.ParseUint(snap.ExtraProps[p.config.Properties.capacity()], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return capacity
+}
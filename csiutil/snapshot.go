@@ -0,0 +1,168 @@
+package csiutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// Snapshot is a snapshot of a volume
+type Snapshot struct {
+	ID             string
+	SourceVolumeID string
+	// SizeBytes is the capacity of the volume when the snapshot was taken, the minimum capacity to restore it to
+	SizeBytes uint64
+	CreatedAt time.Time
+}
+
+// CreateSnapshot takes a snapshot of a volume. Snapshot names are unique for all volumes, so when a snapshot with
+// the name exists for the volume it is returned, and when it exists for another volume ErrSnapshotExists is returned.
+func (p *Provisioner) CreateSnapshot(ctx context.Context, volumeID, name string) (*Snapshot, error) {
+	err := p.checkVolumeID(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if !validName.MatchString(name) || strings.HasPrefix(name, p.config.CloneSnapshotPrefix) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	snaps, err := p.listSnapshots(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range snaps {
+		if !strings.HasSuffix(snaps[i].ID, "@"+name) {
+			continue
+		}
+		if snaps[i].SourceVolumeID != volumeID {
+			return nil, fmt.Errorf("%w: %s", ErrSnapshotExists, snaps[i].ID)
+		}
+		return &snaps[i], nil
+	}
+
+	ds, err := p.getManaged(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := ds.Snapshot(ctx, name, zfs.SnapshotOptions{
+		Properties: map[string]string{
+			p.config.Properties.managed():  managedValue,
+			p.config.Properties.capacity(): strconv.FormatUint(datasetCapacity(ds), 10),
+		},
+		SkipRefetch: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error snapshotting volume %s: %w", volumeID, err)
+	}
+	p.logger.Info("zfs.csiutil.Provisioner.CreateSnapshot: Snapshot created", "snapshot", snap.Name)
+	return p.GetSnapshot(ctx, snap.Name)
+}
+
+// DeleteSnapshot destroys a snapshot, deleting a snapshot that does not exist succeeds.
+// Snapshots that volumes were restored from are never destroyed, zfs.ErrSnapshotHasDependentClones is returned then.
+func (p *Provisioner) DeleteSnapshot(ctx context.Context, id string) error {
+	_, _, err := p.splitSnapshotID(id)
+	if err != nil {
+		return err
+	}
+	snap, err := p.getManaged(ctx, id)
+	if errors.Is(err, zfs.ErrDatasetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = snap.Destroy(ctx, zfs.DestroyOptions{})
+	if err != nil {
+		return fmt.Errorf("error destroying snapshot %s: %w", id, err)
+	}
+	p.logger.Info("zfs.csiutil.Provisioner.DeleteSnapshot: Snapshot deleted", "snapshot", id)
+	return nil
+}
+
+// RollbackSnapshot restores a volume in place to its latest snapshot. Rolling back to an earlier snapshot fails,
+// as that would destroy the more recent snapshots.
+func (p *Provisioner) RollbackSnapshot(ctx context.Context, id string) error {
+	_, _, err := p.splitSnapshotID(id)
+	if err != nil {
+		return err
+	}
+	snap, err := p.getManaged(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = snap.Rollback(ctx, zfs.RollbackOptions{})
+	if err != nil {
+		return fmt.Errorf("error rolling back to snapshot %s: %w", id, err)
+	}
+	p.logger.Info("zfs.csiutil.Provisioner.RollbackSnapshot: Volume rolled back", "snapshot", id)
+	return nil
+}
+
+// GetSnapshot returns a snapshot
+func (p *Provisioner) GetSnapshot(ctx context.Context, id string) (*Snapshot, error) {
+	_, _, err := p.splitSnapshotID(id)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := zfs.GetDataset(ctx, id, p.config.Properties.managed(), p.config.Properties.capacity())
+	if err != nil {
+		return nil, err
+	}
+	if ds.ExtraProps[p.config.Properties.managed()] != managedValue {
+		return nil, fmt.Errorf("%w: %s", ErrNotManaged, id)
+	}
+	return p.snapshot(ds), nil
+}
+
+// ListSnapshots returns the snapshots created by the provisioner, for a single volume or for all volumes when the
+// volume ID is empty. The snapshots taken to clone volumes are left out.
+func (p *Provisioner) ListSnapshots(ctx context.Context, volumeID string) ([]Snapshot, error) {
+	if volumeID != "" {
+		err := p.checkVolumeID(volumeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.listSnapshots(ctx, volumeID)
+}
+
+func (p *Provisioner) listSnapshots(ctx context.Context, volumeID string) ([]Snapshot, error) {
+	options := zfs.ListOptions{
+		ParentDataset:   p.parent(),
+		Depth:           2,
+		ExtraProperties: []string{p.config.Properties.managed(), p.config.Properties.capacity()},
+	}
+	if volumeID != "" {
+		options.ParentDataset = volumeID
+		options.Depth = 1
+	}
+	datasets, err := zfs.ListSnapshots(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := make([]Snapshot, 0, len(datasets))
+	for i := range datasets {
+		ds := &datasets[i]
+		if ds.ExtraProps[p.config.Properties.managed()] != managedValue || p.isCloneSnapshot(ds.Name) {
+			continue
+		}
+		snaps = append(snaps, *p.snapshot(ds))
+	}
+	return snaps, nil
+}
+
+func (p *Provisioner) snapshot(ds *zfs.Dataset) *Snapshot {
+	return &Snapshot{
+		ID:             ds.Name,
+		SourceVolumeID: ds.Name[:strings.IndexByte(ds.Name, '@')],
+		SizeBytes:      p.snapshotCapacity(ds),
+		CreatedAt:      ds.Creation,
+	}
+}
@@ -0,0 +1,63 @@
+package csiutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+func TestProvisioner_Snapshots(t *testing.T) {
+	p := newTestProvisioner(t)
+	ctx := context.Background()
+
+	vol, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-1", RequiredBytes: 10 * mib})
+	require.NoError(t, err)
+	other, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-2"})
+	require.NoError(t, err)
+
+	snap, err := p.CreateSnapshot(ctx, vol.ID, "snap-1")
+	require.NoError(t, err)
+	require.Equal(t, vol.ID+"@snap-1", snap.ID)
+	require.Equal(t, vol.ID, snap.SourceVolumeID)
+	require.EqualValues(t, 10*mib, snap.SizeBytes)
+	require.False(t, snap.CreatedAt.IsZero())
+
+	again, err := p.CreateSnapshot(ctx, vol.ID, "snap-1")
+	require.NoError(t, err)
+	require.Equal(t, snap, again)
+	_, err = p.CreateSnapshot(ctx, other.ID, "snap-1")
+	require.ErrorIs(t, err, ErrSnapshotExists)
+	_, err = p.CreateSnapshot(ctx, other.ID, "csi-clone-x")
+	require.ErrorIs(t, err, ErrInvalidName)
+
+	// The volume grows, but restoring needs only the capacity at the time of the snapshot
+	_, err = p.ExpandVolume(ctx, vol.ID, 50*mib, 0)
+	require.NoError(t, err)
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "small", RequiredBytes: 5 * mib, SourceSnapshotID: snap.ID})
+	require.ErrorIs(t, err, ErrInvalidCapacity)
+	restored, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "restored", RequiredBytes: 10 * mib, SourceSnapshotID: snap.ID})
+	require.NoError(t, err)
+	require.Equal(t, snap.ID, restored.SourceSnapshotID)
+	require.EqualValues(t, 10*mib, restored.CapacityBytes)
+
+	_, err = p.CreateSnapshot(ctx, vol.ID, "snap-2")
+	require.NoError(t, err)
+	list, err := p.ListSnapshots(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	require.Error(t, p.RollbackSnapshot(ctx, snap.ID))
+	require.NoError(t, p.RollbackSnapshot(ctx, vol.ID+"@snap-2"))
+
+	require.ErrorIs(t, p.DeleteSnapshot(ctx, snap.ID), zfs.ErrSnapshotHasDependentClones)
+	require.NoError(t, p.DeleteVolume(ctx, restored.ID))
+	require.NoError(t, p.DeleteSnapshot(ctx, snap.ID))
+	require.NoError(t, p.DeleteSnapshot(ctx, snap.ID))
+
+	_, err = p.GetSnapshot(ctx, snap.ID)
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+	require.ErrorIs(t, p.DeleteSnapshot(ctx, "pool/csi@snap"), ErrInvalidID)
+}
@@ -0,0 +1,392 @@
+package csiutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// VolumeMode is the access type of a volume
+type VolumeMode string
+
+const (
+	// ModeFilesystem volumes are filesystems, their capacity is enforced with the refquota property
+	ModeFilesystem VolumeMode = "filesystem"
+	// ModeBlock volumes are zvols
+	ModeBlock VolumeMode = "block"
+)
+
+// Volume is a provisioned volume
+type Volume struct {
+	ID            string
+	Mode          VolumeMode
+	CapacityBytes uint64
+	// SourceSnapshotID is the snapshot the volume was restored or cloned from, if any
+	SourceSnapshotID string
+}
+
+// CreateVolumeRequest describes the volume to create
+type CreateVolumeRequest struct {
+	// Name is the name of the volume, which becomes the name of its dataset below the parent dataset
+	Name string
+	// Mode is the access type of the volume, filesystem when empty
+	Mode VolumeMode
+	// RequiredBytes is the minimum capacity of the volume, when zero the default capacity is used
+	RequiredBytes uint64
+	// LimitBytes is the maximum capacity of the volume, when zero there is no maximum
+	LimitBytes uint64
+	// Properties are set on the volume, in addition to the configured volume properties
+	Properties map[string]string
+	// SourceSnapshotID restores the volume from a snapshot, by cloning it
+	SourceSnapshotID string
+	// SourceVolumeID clones the volume from another volume, through a snapshot taken on demand
+	SourceVolumeID string
+}
+
+// CreateVolume creates a volume. When the volume already exists and it matches the request it is returned,
+// otherwise ErrVolumeExists is returned.
+func (p *Provisioner) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*Volume, error) {
+	name, err := p.datasetName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = ModeFilesystem
+	}
+	if req.Mode != ModeFilesystem && req.Mode != ModeBlock {
+		return nil, fmt.Errorf("invalid volume mode %q", req.Mode)
+	}
+	if req.SourceSnapshotID != "" && req.SourceVolumeID != "" {
+		return nil, fmt.Errorf("%w: both a source snapshot and volume given", ErrIncompatibleSource)
+	}
+	capacity, err := p.capacity(req.RequiredBytes, req.LimitBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := p.getManaged(ctx, name)
+	switch {
+	case err == nil:
+		return p.existingVolume(ds, req)
+	case !errors.Is(err, zfs.ErrDatasetNotFound):
+		return nil, err
+	}
+
+	props := make(map[string]string, len(p.config.VolumeProperties)+len(req.Properties)+4)
+	if req.Mode == ModeFilesystem {
+		props[zfs.PropertyCanMount] = zfs.CanMountNoAuto // Mounted by the node using it
+	}
+	for k, v := range p.config.VolumeProperties {
+		props[k] = v
+	}
+	for k, v := range req.Properties {
+		props[k] = v
+	}
+	props[p.config.Properties.managed()] = managedValue
+
+	switch {
+	case req.SourceSnapshotID != "":
+		ds, err = p.restoreSnapshot(ctx, name, req.SourceSnapshotID, req.Mode, capacity, props)
+	case req.SourceVolumeID != "":
+		ds, err = p.cloneVolume(ctx, name, req.SourceVolumeID, req.Mode, capacity, props)
+	case req.Mode == ModeBlock:
+		ds, err = zfs.CreateVolume(ctx, name, capacity, zfs.CreateVolumeOptions{
+			Properties: props,
+			Sparse:     p.config.Sparse,
+		})
+	default:
+		p.setFilesystemCapacity(props, capacity)
+		ds, err = zfs.CreateFilesystem(ctx, name, zfs.CreateFilesystemOptions{
+			Properties: props,
+			NoMount:    true,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating volume %s: %w", name, err)
+	}
+
+	p.logger.Info("zfs.csiutil.Provisioner.CreateVolume: Volume created",
+		"volume", name,
+		"mode", req.Mode,
+		"capacity", capacity,
+		"origin", ds.Origin,
+	)
+	return p.volume(ds), nil
+}
+
+// existingVolume checks whether an existing volume matches the create request
+func (p *Provisioner) existingVolume(ds *zfs.Dataset, req CreateVolumeRequest) (*Volume, error) {
+	vol := p.volume(ds)
+
+	expectedSource := req.SourceSnapshotID
+	if req.SourceVolumeID != "" {
+		expectedSource = fmt.Sprintf("%s@%s%s", req.SourceVolumeID, p.config.CloneSnapshotPrefix, req.Name)
+	}
+	switch {
+	case vol.Mode != req.Mode:
+		return nil, fmt.Errorf("%w: volume %s has mode %s", ErrVolumeExists, ds.Name, vol.Mode)
+	case vol.CapacityBytes < req.RequiredBytes, req.LimitBytes > 0 && vol.CapacityBytes > req.LimitBytes:
+		return nil, fmt.Errorf("%w: volume %s has a capacity of %d bytes", ErrVolumeExists, ds.Name, vol.CapacityBytes)
+	case vol.SourceSnapshotID != expectedSource:
+		return nil, fmt.Errorf("%w: volume %s has source %q", ErrVolumeExists, ds.Name, vol.SourceSnapshotID)
+	}
+	return vol, nil
+}
+
+// restoreSnapshot creates the volume as a clone of a snapshot created by the provisioner
+func (p *Provisioner) restoreSnapshot(ctx context.Context, name, snapshotID string, mode VolumeMode,
+	capacity uint64, props map[string]string) (*zfs.Dataset, error) {
+	volumeID, _, err := p.splitSnapshotID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := p.getManaged(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := p.getManaged(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	return p.clone(ctx, name, snap, source, mode, capacity, props)
+}
+
+// cloneVolume creates the volume as a clone of another volume, through a snapshot taken on demand
+func (p *Provisioner) cloneVolume(ctx context.Context, name, volumeID string, mode VolumeMode,
+	capacity uint64, props map[string]string) (*zfs.Dataset, error) {
+	err := p.checkVolumeID(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := p.getManaged(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapName := p.config.CloneSnapshotPrefix + name[strings.LastIndexByte(name, '/')+1:]
+	snap, err := p.getManaged(ctx, fmt.Sprintf("%s@%s", volumeID, snapName))
+	if errors.Is(err, zfs.ErrDatasetNotFound) {
+		// Not taken by an earlier attempt yet
+		snap, err = source.Snapshot(ctx, snapName, zfs.SnapshotOptions{
+			Properties: map[string]string{
+				p.config.Properties.managed():  managedValue,
+				p.config.Properties.capacity(): strconv.FormatUint(datasetCapacity(source), 10),
+			},
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error snapshotting source volume %s: %w", volumeID, err)
+	}
+	return p.clone(ctx, name, snap, source, mode, capacity, props)
+}
+
+// clone clones the snapshot of the source volume into the new volume, which must be at least as large as the source
+func (p *Provisioner) clone(ctx context.Context, name string, snap, source *zfs.Dataset, mode VolumeMode,
+	capacity uint64, props map[string]string) (*zfs.Dataset, error) {
+	if p.mode(source) != mode {
+		return nil, fmt.Errorf("%w: cannot create a %s volume from %s %s", ErrIncompatibleSource, mode, p.mode(source), snap.Name)
+	}
+	sourceCapacity := p.snapshotCapacity(snap)
+	if sourceCapacity == 0 {
+		sourceCapacity = datasetCapacity(source)
+	}
+	if capacity < sourceCapacity {
+		return nil, fmt.Errorf("%w: %s has a capacity of %d bytes, more than %d bytes",
+			ErrInvalidCapacity, snap.Name, sourceCapacity, capacity)
+	}
+
+	if mode == ModeFilesystem {
+		p.setFilesystemCapacity(props, capacity)
+	} else if !p.config.Sparse {
+		props[zfs.PropertyRefReservation] = "auto" // Clones do not inherit the reservation
+	}
+	clone, err := snap.Clone(ctx, name, zfs.CloneOptions{Properties: props})
+	if err != nil {
+		return nil, err
+	}
+	if mode == ModeFilesystem || clone.Volsize >= capacity {
+		return clone, nil
+	}
+
+	err = clone.SetProperty(ctx, zfs.PropertyVolSize, strconv.FormatUint(capacity, 10))
+	if err != nil {
+		// Remove the clone, so a retry does not find a volume that is too small
+		destroyErr := clone.Destroy(ctx, zfs.DestroyOptions{})
+		return nil, errors.Join(fmt.Errorf("error expanding clone %s: %w", name, err), destroyErr)
+	}
+	return p.getManaged(ctx, name)
+}
+
+// setFilesystemCapacity sets the properties limiting (and unless sparse, reserving) the capacity of a filesystem
+func (p *Provisioner) setFilesystemCapacity(props map[string]string, capacity uint64) {
+	props[zfs.PropertyRefQuota] = strconv.FormatUint(capacity, 10)
+	if !p.config.Sparse {
+		props[zfs.PropertyRefReservation] = strconv.FormatUint(capacity, 10)
+	}
+}
+
+// ExpandVolume grows a volume to at least the required capacity, and returns its new capacity.
+// Volumes are never shrunk, so when the volume is already large enough its capacity is returned.
+func (p *Provisioner) ExpandVolume(ctx context.Context, id string, requiredBytes, limitBytes uint64) (uint64, error) {
+	err := p.checkVolumeID(id)
+	if err != nil {
+		return 0, err
+	}
+	ds, err := p.getManaged(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	current := datasetCapacity(ds)
+	if current >= requiredBytes {
+		return current, nil
+	}
+	capacity, err := p.capacity(requiredBytes, limitBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	size := strconv.FormatUint(capacity, 10)
+	if ds.Type == zfs.DatasetVolume {
+		err = ds.SetProperty(ctx, zfs.PropertyVolSize, size)
+	} else {
+		err = ds.SetProperty(ctx, zfs.PropertyRefQuota, size)
+		if err == nil && !p.config.Sparse {
+			err = ds.SetProperty(ctx, zfs.PropertyRefReservation, size)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error expanding volume %s: %w", id, err)
+	}
+
+	p.logger.Info("zfs.csiutil.Provisioner.ExpandVolume: Volume expanded",
+		"volume", id,
+		"previousCapacity", current,
+		"capacity", capacity,
+	)
+	return capacity, nil
+}
+
+// DeleteVolume destroys a volume, deleting a volume that does not exist succeeds. Filesystems that are mounted,
+// volumes with snapshots and volumes that other volumes were cloned from are never destroyed. When the volume was
+// cloned from another volume, the snapshot taken for it is destroyed as well.
+func (p *Provisioner) DeleteVolume(ctx context.Context, id string) error {
+	err := p.checkVolumeID(id)
+	if err != nil {
+		return err
+	}
+	ds, err := p.getManaged(ctx, id)
+	if errors.Is(err, zfs.ErrDatasetNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ds.Mounted {
+		return fmt.Errorf("%w: %s is mounted at %s", ErrVolumeInUse, id, ds.Mountpoint)
+	}
+
+	snaps, err := ds.Snapshots(ctx, zfs.ListOptions{Depth: 1})
+	if err != nil {
+		return fmt.Errorf("error listing snapshots of %s: %w", id, err)
+	}
+	for _, snap := range snaps {
+		if !p.isCloneSnapshot(snap.Name) {
+			return fmt.Errorf("%w: %s", ErrVolumeHasSnapshots, snap.Name)
+		}
+	}
+	for _, snap := range snaps {
+		err = snap.Destroy(ctx, zfs.DestroyOptions{})
+		if errors.Is(err, zfs.ErrSnapshotHasDependentClones) {
+			return fmt.Errorf("%w: %s: %w", ErrVolumeHasClones, snap.Name, err)
+		}
+		if err != nil {
+			return fmt.Errorf("error destroying snapshot %s: %w", snap.Name, err)
+		}
+	}
+
+	err = ds.Destroy(ctx, zfs.DestroyOptions{})
+	if err != nil {
+		return fmt.Errorf("error destroying volume %s: %w", id, err)
+	}
+	p.logger.Info("zfs.csiutil.Provisioner.DeleteVolume: Volume deleted", "volume", id)
+
+	if !p.isCloneSnapshot(ds.Origin) {
+		return nil
+	}
+	// The snapshot taken to clone this volume is no longer needed
+	origin, err := p.getManaged(ctx, ds.Origin)
+	if err == nil {
+		err = origin.Destroy(ctx, zfs.DestroyOptions{})
+	}
+	if err != nil && !errors.Is(err, zfs.ErrDatasetNotFound) {
+		return fmt.Errorf("error destroying origin snapshot %s: %w", ds.Origin, err)
+	}
+	return nil
+}
+
+// isCloneSnapshot returns whether the snapshot was taken to clone a volume from another volume
+func (p *Provisioner) isCloneSnapshot(snapshotID string) bool {
+	_, name, err := p.splitSnapshotID(snapshotID)
+	return err == nil && strings.HasPrefix(name, p.config.CloneSnapshotPrefix)
+}
+
+// GetVolume returns a volume
+func (p *Provisioner) GetVolume(ctx context.Context, id string) (*Volume, error) {
+	err := p.checkVolumeID(id)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := p.getManaged(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.volume(ds), nil
+}
+
+// ListVolumes returns all volumes created by the provisioner
+func (p *Provisioner) ListVolumes(ctx context.Context) ([]Volume, error) {
+	options := zfs.ListOptions{
+		ParentDataset:   p.parent(),
+		Depth:           1,
+		FilterSelf:      true,
+		ExtraProperties: []string{p.config.Properties.managed()},
+	}
+	filesystems, err := zfs.ListFilesystems(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := zfs.ListVolumes(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Volume, 0, len(filesystems)+len(volumes))
+	for _, datasets := range [][]zfs.Dataset{filesystems, volumes} {
+		for i := range datasets {
+			if datasets[i].ExtraProps[p.config.Properties.managed()] == managedValue {
+				list = append(list, *p.volume(&datasets[i]))
+			}
+		}
+	}
+	return list, nil
+}
+
+func (p *Provisioner) mode(ds *zfs.Dataset) VolumeMode {
+	if ds.Type == zfs.DatasetVolume {
+		return ModeBlock
+	}
+	return ModeFilesystem
+}
+
+func (p *Provisioner) volume(ds *zfs.Dataset) *Volume {
+	return &Volume{
+		ID:               ds.Name,
+		Mode:             p.mode(ds),
+		CapacityBytes:    datasetCapacity(ds),
+		SourceSnapshotID: ds.Origin,
+	}
+}
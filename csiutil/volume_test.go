@@ -0,0 +1,184 @@
+package csiutil
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+const (
+	testParent = "pool/csi"
+	mib        = 1024 * 1024
+)
+
+func newTestProvisioner(t *testing.T) *Provisioner {
+	t.Helper()
+	zfsfake.Install(t, "pool")
+	_, err := zfs.CreateFilesystem(context.Background(), testParent, zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+
+	conf := Config{ParentDataset: testParent}
+	conf.ApplyDefaults()
+	return NewProvisioner(conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestProvisioner_capacity(t *testing.T) {
+	p := &Provisioner{}
+	p.config.ApplyDefaults()
+
+	capacity, err := p.capacity(0, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, defaultCapacityBytes, capacity)
+
+	capacity, err = p.capacity(0, 10*mib)
+	require.NoError(t, err)
+	require.EqualValues(t, 10*mib, capacity)
+
+	capacity, err = p.capacity(mib+1, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, 2*mib, capacity)
+
+	_, err = p.capacity(mib+1, mib+100)
+	require.ErrorIs(t, err, ErrInvalidCapacity)
+}
+
+func TestProvisioner_CreateVolume(t *testing.T) {
+	p := newTestProvisioner(t)
+	ctx := context.Background()
+
+	_, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "in/valid"})
+	require.ErrorIs(t, err, ErrInvalidName)
+
+	req := CreateVolumeRequest{Name: "pvc-1", RequiredBytes: 10 * mib}
+	vol, err := p.CreateVolume(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, &Volume{ID: testParent + "/pvc-1", Mode: ModeFilesystem, CapacityBytes: 10 * mib}, vol)
+
+	ds, err := zfs.GetDataset(ctx, vol.ID, zfs.PropertyRefReservation)
+	require.NoError(t, err)
+	require.EqualValues(t, 10*mib, ds.Refquota)
+	require.Equal(t, "10485760", ds.ExtraProps[zfs.PropertyRefReservation])
+
+	// Idempotent
+	again, err := p.CreateVolume(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, vol, again)
+
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-1", RequiredBytes: 20 * mib})
+	require.ErrorIs(t, err, ErrVolumeExists)
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-1", Mode: ModeBlock, RequiredBytes: 10 * mib})
+	require.ErrorIs(t, err, ErrVolumeExists)
+
+	block, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-2", Mode: ModeBlock, RequiredBytes: 5 * mib})
+	require.NoError(t, err)
+	require.Equal(t, &Volume{ID: testParent + "/pvc-2", Mode: ModeBlock, CapacityBytes: 5 * mib}, block)
+
+	// Datasets not created by the provisioner are left alone
+	_, err = zfs.CreateFilesystem(ctx, testParent+"/other", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "other"})
+	require.ErrorIs(t, err, ErrNotManaged)
+	require.ErrorIs(t, p.DeleteVolume(ctx, testParent+"/other"), ErrNotManaged)
+
+	list, err := p.ListVolumes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Volume{*vol, *block}, list)
+}
+
+func TestProvisioner_ExpandVolume(t *testing.T) {
+	p := newTestProvisioner(t)
+	ctx := context.Background()
+
+	for _, mode := range []VolumeMode{ModeFilesystem, ModeBlock} {
+		vol, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc-" + string(mode), Mode: mode, RequiredBytes: 10 * mib})
+		require.NoError(t, err)
+
+		capacity, err := p.ExpandVolume(ctx, vol.ID, 5*mib, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 10*mib, capacity)
+
+		capacity, err = p.ExpandVolume(ctx, vol.ID, 15*mib, 0)
+		require.NoError(t, err)
+		require.EqualValues(t, 15*mib, capacity)
+
+		vol, err = p.GetVolume(ctx, vol.ID)
+		require.NoError(t, err)
+		require.EqualValues(t, 15*mib, vol.CapacityBytes)
+
+		_, err = p.ExpandVolume(ctx, vol.ID, 30*mib, 20*mib)
+		require.ErrorIs(t, err, ErrInvalidCapacity)
+	}
+
+	_, err := p.ExpandVolume(ctx, "pool/elsewhere", 30*mib, 0)
+	require.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestProvisioner_CloneVolume(t *testing.T) {
+	p := newTestProvisioner(t)
+	ctx := context.Background()
+
+	source, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "source", Mode: ModeBlock, RequiredBytes: 10 * mib})
+	require.NoError(t, err)
+
+	req := CreateVolumeRequest{Name: "clone", Mode: ModeBlock, RequiredBytes: 20 * mib, SourceVolumeID: source.ID}
+	clone, err := p.CreateVolume(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, &Volume{
+		ID:               testParent + "/clone",
+		Mode:             ModeBlock,
+		CapacityBytes:    20 * mib,
+		SourceSnapshotID: source.ID + "@csi-clone-clone",
+	}, clone)
+
+	again, err := p.CreateVolume(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, clone, again)
+
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "small", Mode: ModeBlock, RequiredBytes: mib, SourceVolumeID: source.ID})
+	require.ErrorIs(t, err, ErrInvalidCapacity)
+	_, err = p.CreateVolume(ctx, CreateVolumeRequest{Name: "fs", RequiredBytes: 20 * mib, SourceVolumeID: source.ID})
+	require.ErrorIs(t, err, ErrIncompatibleSource)
+
+	// The clone snapshot is not a user snapshot, but the source cannot go while the clone exists
+	snaps, err := p.ListSnapshots(ctx, source.ID)
+	require.NoError(t, err)
+	require.Empty(t, snaps)
+	require.ErrorIs(t, p.DeleteVolume(ctx, source.ID), ErrVolumeHasClones)
+
+	require.NoError(t, p.DeleteVolume(ctx, clone.ID))
+	require.NoError(t, p.DeleteVolume(ctx, clone.ID))
+	_, err = zfs.GetDataset(ctx, clone.SourceSnapshotID)
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+	require.NoError(t, p.DeleteVolume(ctx, source.ID))
+}
+
+func TestProvisioner_DeleteVolume(t *testing.T) {
+	p := newTestProvisioner(t)
+	ctx := context.Background()
+
+	vol, err := p.CreateVolume(ctx, CreateVolumeRequest{Name: "pvc"})
+	require.NoError(t, err)
+	ds, err := zfs.GetDataset(ctx, vol.ID, zfs.PropertyCanMount)
+	require.NoError(t, err)
+	require.False(t, ds.Mounted)
+	require.Equal(t, zfs.CanMountNoAuto, ds.ExtraProps[zfs.PropertyCanMount])
+
+	require.NoError(t, ds.Mount(ctx, zfs.MountOptions{}))
+	require.ErrorIs(t, p.DeleteVolume(ctx, vol.ID), ErrVolumeInUse)
+	require.NoError(t, ds.Unmount(ctx, zfs.UnmountOptions{}))
+
+	_, err = p.CreateSnapshot(ctx, vol.ID, "snap")
+	require.NoError(t, err)
+	require.ErrorIs(t, p.DeleteVolume(ctx, vol.ID), ErrVolumeHasSnapshots)
+
+	require.NoError(t, p.DeleteSnapshot(ctx, vol.ID+"@snap"))
+	require.NoError(t, p.DeleteVolume(ctx, vol.ID))
+	_, err = p.GetVolume(ctx, vol.ID)
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+}
@@ -2,8 +2,10 @@ package zfs
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // DatasetType is the zfs dataset type
@@ -15,6 +17,7 @@ const (
 	DatasetFilesystem DatasetType = "filesystem"
 	DatasetSnapshot   DatasetType = "snapshot"
 	DatasetVolume     DatasetType = "volume"
+	DatasetBookmark   DatasetType = "bookmark"
 )
 
 // Dataset is a ZFS dataset.  A dataset could be a clone, filesystem, snapshot, or volume.
@@ -23,22 +26,47 @@ const (
 // The field definitions can be found in the ZFS manual:
 // https://openzfs.github.io/openzfs-docs/man/7/zfsprops.7.html.
 type Dataset struct {
-	Name          string            `json:"Name"`
-	Type          DatasetType       `json:"Type"`
-	Origin        string            `json:"Origin"`
-	Used          uint64            `json:"Used"`
-	Available     uint64            `json:"Available"`
-	Mounted       bool              `json:"Mounted"`
-	Mountpoint    string            `json:"Mountpoint"`
-	Compression   string            `json:"Compression"`
-	Written       uint64            `json:"Written"`
-	Volsize       uint64            `json:"Volsize"`
-	Logicalused   uint64            `json:"Logicalused"`
-	Usedbydataset uint64            `json:"Usedbydataset"`
-	Quota         uint64            `json:"Quota"`
-	Refquota      uint64            `json:"Refquota"`
-	Referenced    uint64            `json:"Referenced"`
-	ExtraProps    map[string]string `json:"ExtraProps"`
+	Name          string      `json:"Name"`
+	Type          DatasetType `json:"Type"`
+	Origin        string      `json:"Origin"`
+	Used          uint64      `json:"Used"`
+	Available     uint64      `json:"Available"`
+	Mounted       bool        `json:"Mounted"`
+	Mountpoint    string      `json:"Mountpoint"`
+	Compression   string      `json:"Compression"`
+	Written       uint64      `json:"Written"`
+	Volsize       uint64      `json:"Volsize"`
+	Logicalused   uint64      `json:"Logicalused"`
+	Usedbydataset uint64      `json:"Usedbydataset"`
+	Quota         uint64      `json:"Quota"`
+	Refquota      uint64      `json:"Refquota"`
+	Referenced    uint64      `json:"Referenced"`
+	GUID          uint64      `json:"GUID"`
+	Creation      time.Time   `json:"Creation"`
+	CreateTXG     uint64      `json:"CreateTXG"`
+	// The encryption fields are only set when the EncryptionProperties were requested
+	Encryption     string     `json:"Encryption,omitempty"`
+	KeyStatus      string     `json:"KeyStatus,omitempty"`
+	KeyFormat      string     `json:"KeyFormat,omitempty"`
+	EncryptionRoot string     `json:"EncryptionRoot,omitempty"`
+	ExtraProps     Properties `json:"ExtraProps"`
+}
+
+// IsEncrypted returns whether the dataset is encrypted, the Encryption field must have been retrieved
+func (d *Dataset) IsEncrypted() bool {
+	return d.Encryption != "" && d.Encryption != ValueOff
+}
+
+// IsEncryptionRoot returns whether the dataset is the encryption root, whose key its encrypted children inherit.
+// The EncryptionRoot field must have been retrieved.
+func (d *Dataset) IsEncryptionRoot() bool {
+	return d.EncryptionRoot != "" && d.EncryptionRoot == d.Name
+}
+
+// KeyLoaded returns whether the encryption key of the dataset is loaded, so its data can be accessed.
+// Unencrypted datasets have no key, the KeyStatus field must have been retrieved.
+func (d *Dataset) KeyLoaded() bool {
+	return d.KeyStatus == KeyStatusAvailable
 }
 
 const (
@@ -47,81 +75,192 @@ const (
 	valueField
 )
 
-func readDatasets(output [][]string, extraProps []string) ([]Dataset, error) {
-	multiple := len(dsPropList) + len(extraProps)
-	if len(output)%multiple != 0 {
-		return nil, fmt.Errorf("output invalid: %d lines where a multiple of %d was expected: %s",
-			len(output), multiple, strings.Join(output[0], " "),
-		)
-	}
-
-	count := len(output) / multiple
-	curDataset := 0
-	datasets := make([]Dataset, count)
-	for i, fields := range output {
-		if len(fields) != 3 {
-			return nil, fmt.Errorf("output contains line with %d fields: %s", len(fields), strings.Join(fields, " "))
+// propertyList returns the fields followed by the extra properties that are not a field already,
+// so every property is retrieved once
+func propertyList(fields, extraProps []string) []string {
+	list := slices.Clip(fields)
+	for _, prop := range extraProps {
+		if !slices.Contains(fields, prop) {
+			list = append(list, prop)
 		}
+	}
+	return list
+}
 
-		if i > 0 && fields[nameField] != datasets[curDataset].Name {
-			curDataset++
+func readDatasets(output [][]string, extraProps []string) ([]Dataset, error) {
+	parser := newDatasetParser(dsPropList, extraProps)
+	parser.grow(len(output))
+	for _, fields := range output {
+		err := parser.parseLine(fields)
+		if err != nil {
+			return nil, err
 		}
+	}
+	return parser.datasets()
+}
 
-		ds := &datasets[curDataset]
-		ds.Name = fields[nameField]
-		// Init extra props if needed
-		if ds.ExtraProps == nil {
-			ds.ExtraProps = make(map[string]string, len(extraProps))
-		}
+// datasetParser incrementally builds datasets from `zfs get` output lines
+type datasetParser struct {
+	extraProps []string
+	multiple   int
+	lines      int
+	list       []Dataset
+}
+
+func newDatasetParser(fields, extraProps []string) *datasetParser {
+	return &datasetParser{
+		extraProps: extraProps,
+		multiple:   len(propertyList(fields, extraProps)),
+		list:       make([]Dataset, 0, 16),
+	}
+}
 
-		prop := fields[propertyField]
-		val := fields[valueField]
-
-		var setError error
-		switch prop {
-		case PropertyName:
-			ds.Name = val
-		case PropertyType:
-			ds.Type = DatasetType(val)
-		case PropertyOrigin:
-			ds.Origin = setString(val)
-		case PropertyUsed:
-			ds.Used, setError = setUint(val)
-		case PropertyAvailable:
-			ds.Available, setError = setUint(val)
-		case PropertyMounted:
-			ds.Mounted = setBool(val)
-		case PropertyMountPoint:
-			ds.Mountpoint = setString(val)
-		case PropertyCompression:
-			ds.Compression = setString(val)
-		case PropertyWritten:
-			ds.Written, setError = setUint(val)
-		case PropertyVolSize:
-			ds.Volsize, setError = setUint(val)
-		case PropertyLogicalUsed:
-			ds.Logicalused, setError = setUint(val)
-		case PropertyUsedByDataset:
-			ds.Usedbydataset, setError = setUint(val)
-		case PropertyQuota:
-			ds.Quota, setError = setUint(val)
-		case PropertyRefQuota:
-			ds.Refquota, setError = setUint(val)
-		case PropertyReferenced:
-			ds.Referenced, setError = setUint(val)
-		default:
-			if val == ValueUnset {
-				ds.ExtraProps[prop] = ""
-				continue
+// grow pre-sizes the dataset list for the given amount of output lines
+func (p *datasetParser) grow(lines int) {
+	p.list = slices.Grow(p.list, lines/p.multiple)
+}
+
+func (p *datasetParser) parseLine(fields []string) error {
+	if len(fields) != 3 {
+		return fmt.Errorf("output contains line with %d fields: %s", len(fields), strings.Join(fields, " "))
+	}
+	p.lines++
+
+	if len(p.list) == 0 || fields[nameField] != p.list[len(p.list)-1].Name {
+		p.list = append(p.list, Dataset{
+			Name:       fields[nameField],
+			ExtraProps: make(map[string]string, len(p.extraProps)),
+		})
+	}
+
+	curDataset := len(p.list) - 1
+	ds := &p.list[curDataset]
+
+	prop := fields[propertyField]
+	val := fields[valueField]
+
+	var setError error
+	switch prop {
+	case PropertyName:
+		ds.Name = val
+	case PropertyType:
+		ds.Type = DatasetType(val)
+	case PropertyOrigin:
+		ds.Origin = setString(val)
+	case PropertyUsed:
+		ds.Used, setError = setUint(val)
+	case PropertyAvailable:
+		ds.Available, setError = setUint(val)
+	case PropertyMounted:
+		ds.Mounted = setBool(val)
+	case PropertyMountPoint:
+		ds.Mountpoint = setString(val)
+	case PropertyCompression:
+		ds.Compression = setString(val)
+	case PropertyWritten:
+		ds.Written, setError = setUint(val)
+	case PropertyVolSize:
+		ds.Volsize, setError = setUint(val)
+	case PropertyLogicalUsed:
+		ds.Logicalused, setError = setUint(val)
+	case PropertyUsedByDataset:
+		ds.Usedbydataset, setError = setUint(val)
+	case PropertyQuota:
+		ds.Quota, setError = setUint(val)
+	case PropertyRefQuota:
+		ds.Refquota, setError = setUint(val)
+	case PropertyReferenced:
+		ds.Referenced, setError = setUint(val)
+	case PropertyGUID:
+		ds.GUID, setError = setUint(val)
+	case PropertyCreation:
+		ds.Creation, setError = setTime(val)
+	case PropertyCreateTXG:
+		ds.CreateTXG, setError = setUint(val)
+	case PropertyEncryption:
+		ds.Encryption = setString(val)
+	case PropertyKeyStatus:
+		ds.KeyStatus = setString(val)
+	case PropertyKeyFormat:
+		ds.KeyFormat = setString(val)
+	case PropertyEncryptionRoot:
+		ds.EncryptionRoot = setString(val)
+	default:
+		ds.ExtraProps[prop] = setString(val)
+		return nil
+	}
+	if setError != nil {
+		return fmt.Errorf("error in dataset %d (%s) field %s [%s]: %w", curDataset, ds.Name, prop, val, setError)
+	}
+	// Properties with a field are kept in the extra properties too, when they were requested as such
+	if slices.Contains(p.extraProps, prop) {
+		ds.ExtraProps[prop] = setString(val)
+	}
+	return nil
+}
+
+// stream returns a line function that calls fn for every dataset once all its properties have been received,
+// keeping only the dataset being parsed in memory. The last dataset must be passed with flush when the output ends.
+func (p *datasetParser) stream(fn func(Dataset) error) lineFunc {
+	return func(fields []string) error {
+		if len(p.list) > 0 && len(fields) == 3 && fields[nameField] != p.list[0].Name {
+			err := p.flush(fn)
+			if err != nil {
+				return err
 			}
-			ds.ExtraProps[prop] = val
 		}
-		if setError != nil {
-			return nil, fmt.Errorf("error in dataset %d (%s) field %s [%s]: %w", curDataset, ds.Name, prop, val, setError)
+		return p.parseLine(fields)
+	}
+}
+
+// listColumns returns the columns of zfs list output for the properties, the name comes first
+func listColumns(props []string) []string {
+	if len(props) > 0 && props[0] == PropertyName {
+		return props
+	}
+	return append([]string{PropertyName}, props...)
+}
+
+// rows returns a line function parsing the rows of zfs list output with the listColumns of the properties,
+// which calls fn for the dataset of every row
+func (p *datasetParser) rows(props []string, fn func(Dataset) error) lineFunc {
+	columns := listColumns(props)
+	offset := len(columns) - len(props)
+	return func(fields []string) error {
+		if len(fields) != len(columns) {
+			return fmt.Errorf("output contains row with %d columns where %d were expected: %s",
+				len(fields), len(columns), strings.Join(fields, " "))
 		}
+		for i, prop := range props {
+			err := p.parseLine([]string{fields[0], prop, fields[offset+i]})
+			if err != nil {
+				return err
+			}
+		}
+		return p.flush(fn)
 	}
+}
 
-	return datasets, nil
+// flush passes the dataset being parsed to fn, after checking all its properties were received
+func (p *datasetParser) flush(fn func(Dataset) error) error {
+	if len(p.list) == 0 {
+		return nil
+	}
+	if p.lines != p.multiple {
+		return fmt.Errorf("output invalid: %d lines for dataset %s where %d were expected", p.lines, p.list[0].Name, p.multiple)
+	}
+	ds := p.list[0]
+	p.list = p.list[:0]
+	p.lines = 0
+	return fn(ds)
+}
+
+// datasets returns the parsed datasets, after checking all expected properties were received
+func (p *datasetParser) datasets() ([]Dataset, error) {
+	if p.lines%p.multiple != 0 {
+		return nil, fmt.Errorf("output invalid: %d lines where a multiple of %d was expected", p.lines, p.multiple)
+	}
+	return p.list, nil
 }
 
 func setString(val string) string {
@@ -143,6 +282,18 @@ func setUint(val string) (uint64, error) {
 	return v, nil
 }
 
+func setTime(val string) (time.Time, error) {
+	if val == ValueUnset {
+		return time.Time{}, nil
+	}
+
+	v, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(v, 0), nil
+}
+
 func setBool(val string) bool {
 	return strings.EqualFold(val, ValueYes) || strings.EqualFold(val, ValueOn)
 }
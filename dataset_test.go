@@ -1,13 +1,16 @@
 package zfs
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 func Test_readDatasets(t *testing.T) {
-	in := splitOutput(testInput)
+	in := splitOutput(testInput, 3)
 
 	const prop1 = "nl.test:hiephoi"
 	const prop2 = "nl.test:eigenschap"
@@ -27,11 +30,123 @@ func Test_readDatasets(t *testing.T) {
 		require.NotZero(t, ds[i].Referenced)
 		require.NotZero(t, ds[i].Used)
 		require.NotZero(t, ds[i].Available)
+		require.EqualValues(t, 1234+i, ds[i].GUID)
+		require.Equal(t, time.Unix(1700000000, 0), ds[i].Creation)
+		require.EqualValues(t, 10+i, ds[i].CreateTXG)
 		require.Equal(t, "42", ds[i].ExtraProps[prop1])
 		require.Equal(t, "ja", ds[i].ExtraProps[prop2])
 	}
 }
 
+func Test_readDatasetsWhitespace(t *testing.T) {
+	const prop = "nl.test:description"
+	in := strings.Join([]string{
+		"testpool/my data\tname\ttestpool/my data",
+		"testpool/my data\ttype\tfilesystem",
+		"testpool/my data\torigin\t-",
+		"testpool/my data\tused\t196416",
+		"testpool/my data\tavailable\t186368146928528",
+		"testpool/my data\tmounted\tyes",
+		"testpool/my data\tmountpoint\t/mnt/my data/\tweird  ",
+		"testpool/my data\tcompression\toff",
+		"testpool/my data\tvolsize\t-",
+		"testpool/my data\tquota\t0",
+		"testpool/my data\trefquota\t0",
+		"testpool/my data\treferenced\t196416",
+		"testpool/my data\twritten\t196416",
+		"testpool/my data\tlogicalused\t43520",
+		"testpool/my data\tusedbydataset\t196416",
+		"testpool/my data\tguid\t1234",
+		"testpool/my data\tcreation\t1700000000",
+		"testpool/my data\tcreatetxg\t-",
+		"testpool/my data\t" + prop + "\t a value\twith\ttabs ",
+	}, "\n")
+
+	ds, err := readDatasets(splitOutput(in, 3), []string{prop})
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	require.Equal(t, "testpool/my data", ds[0].Name)
+	require.Equal(t, "/mnt/my data/\tweird  ", ds[0].Mountpoint)
+	require.Equal(t, " a value\twith\ttabs ", ds[0].ExtraProps[prop])
+	require.EqualValues(t, 196416, ds[0].Referenced)
+}
+
+func Test_datasetParserFields(t *testing.T) {
+	in := "testpool/ds0\ttype\tfilesystem\n" +
+		"testpool/ds0\tguid\t123\n" +
+		"testpool/ds0@snap\ttype\tsnapshot\n" +
+		"testpool/ds0@snap\tguid\t456\n"
+
+	parser := newDatasetParser([]string{PropertyType}, []string{PropertyGUID})
+	require.NoError(t, scanLines(strings.NewReader(in), 3, parser.parseLine))
+	ds, err := parser.datasets()
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+	require.Equal(t, "testpool/ds0", ds[0].Name)
+	require.Equal(t, DatasetFilesystem, ds[0].Type)
+	require.Equal(t, "123", ds[0].ExtraProps[PropertyGUID])
+	require.Equal(t, "testpool/ds0@snap", ds[1].Name)
+	require.Equal(t, DatasetSnapshot, ds[1].Type)
+	require.Equal(t, "456", ds[1].ExtraProps[PropertyGUID])
+	require.EqualValues(t, 456, ds[1].GUID)
+	require.Zero(t, ds[1].Used)
+}
+
+func Test_datasetEncryption(t *testing.T) {
+	in := "testpool/enc\tencryption\taes-256-gcm\n" +
+		"testpool/enc\tkeystatus\tavailable\n" +
+		"testpool/enc\tkeyformat\tpassphrase\n" +
+		"testpool/enc\tencryptionroot\ttestpool/enc\n" +
+		"testpool/enc/child\tencryption\taes-256-gcm\n" +
+		"testpool/enc/child\tkeystatus\tunavailable\n" +
+		"testpool/enc/child\tkeyformat\tpassphrase\n" +
+		"testpool/enc/child\tencryptionroot\ttestpool/enc\n" +
+		"testpool/plain\tencryption\toff\n" +
+		"testpool/plain\tkeystatus\t-\n" +
+		"testpool/plain\tkeyformat\tnone\n" +
+		"testpool/plain\tencryptionroot\t-\n"
+
+	parser := newDatasetParser(EncryptionProperties, nil)
+	require.NoError(t, scanLines(strings.NewReader(in), 3, parser.parseLine))
+	ds, err := parser.datasets()
+	require.NoError(t, err)
+	require.Len(t, ds, 3)
+
+	require.Equal(t, EncryptionAES256GCM, ds[0].Encryption)
+	require.Equal(t, KeyFormatPassphrase, ds[0].KeyFormat)
+	require.True(t, ds[0].IsEncrypted())
+	require.True(t, ds[0].IsEncryptionRoot())
+	require.True(t, ds[0].KeyLoaded())
+
+	require.True(t, ds[1].IsEncrypted())
+	require.False(t, ds[1].IsEncryptionRoot())
+	require.False(t, ds[1].KeyLoaded())
+
+	require.False(t, ds[2].IsEncrypted())
+	require.False(t, ds[2].IsEncryptionRoot())
+	require.False(t, ds[2].KeyLoaded())
+	require.Empty(t, ds[2].EncryptionRoot)
+	require.Empty(t, ds[2].ExtraProps)
+}
+
+func Test_propertyList(t *testing.T) {
+	fields := []string{PropertyName, PropertyGUID}
+	require.Equal(t, []string{PropertyName, PropertyGUID, "nl.test:prop"},
+		propertyList(fields, []string{PropertyGUID, "nl.test:prop"}))
+	require.Equal(t, []string{PropertyName, PropertyGUID}, fields)
+
+	// A property that is a field and an extra property is only retrieved once
+	in := "testpool/ds0\tname\ttestpool/ds0\n" +
+		"testpool/ds0\tguid\t123\n"
+	parser := newDatasetParser(fields, []string{PropertyGUID})
+	require.NoError(t, scanLines(strings.NewReader(in), 3, parser.parseLine))
+	ds, err := parser.datasets()
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	require.EqualValues(t, 123, ds[0].GUID)
+	require.Equal(t, "123", ds[0].ExtraProps[PropertyGUID])
+}
+
 const testInput = `testpool/ds0	name	testpool/ds0
 testpool/ds0	type	filesystem
 testpool/ds0	origin	-
@@ -47,6 +162,9 @@ testpool/ds0	referenced	196416
 testpool/ds0	written	196416
 testpool/ds0	logicalused	43520
 testpool/ds0	usedbydataset	196416
+testpool/ds0	guid	1234
+testpool/ds0	creation	1700000000
+testpool/ds0	createtxg	10
 testpool/ds0	nl.test:hiephoi	42
 testpool/ds0	nl.test:eigenschap	ja
 testpool/ds1	name	testpool/ds1
@@ -64,6 +182,9 @@ testpool/ds1	referenced	196416
 testpool/ds1	written	196416
 testpool/ds1	logicalused	43520
 testpool/ds1	usedbydataset	196416
+testpool/ds1	guid	1235
+testpool/ds1	creation	1700000000
+testpool/ds1	createtxg	11
 testpool/ds1	nl.test:hiephoi	42
 testpool/ds1	nl.test:eigenschap	ja
 testpool/ds10	name	testpool/ds10
@@ -81,6 +202,92 @@ testpool/ds10	referenced	196416
 testpool/ds10	written	196416
 testpool/ds10	logicalused	43520
 testpool/ds10	usedbydataset	196416
+testpool/ds10	guid	1236
+testpool/ds10	creation	1700000000
+testpool/ds10	createtxg	12
 testpool/ds10	nl.test:hiephoi	42
 testpool/ds10	nl.test:eigenschap	ja
 `
+
+func benchmarkListOutput(datasets int) string {
+	var sb strings.Builder
+	for i := 0; i < datasets; i++ {
+		name := fmt.Sprintf("testpool/ds%d@snap%d", i/100, i)
+		for _, prop := range dsPropList {
+			val := "196416"
+			switch prop {
+			case PropertyName:
+				val = name
+			case PropertyType:
+				val = string(DatasetSnapshot)
+			case PropertyOrigin, PropertyMountPoint, PropertyVolSize, PropertyCompression, PropertyAvailable:
+				val = ValueUnset
+			case PropertyMounted:
+				val = ValueNo
+			}
+			sb.WriteString(name + fieldSeparator + prop + fieldSeparator + val + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func Benchmark_listParsing(b *testing.B) {
+	out := benchmarkListOutput(100_000)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parser := newDatasetParser(dsPropList, nil)
+		err := scanLines(strings.NewReader(out), 3, parser.parseLine)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ds, err := parser.datasets()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(ds) != 100_000 {
+			b.Fatalf("unexpected dataset count %d", len(ds))
+		}
+	}
+}
+
+func Test_Properties(t *testing.T) {
+	props := Properties{
+		PropertyQuota:         ValueNone,
+		PropertyUsed:          "4096",
+		PropertyCompressRatio: "1.50x",
+		PropertyReadOnly:      ValueOn,
+		PropertyCreation:      "1700000000",
+		PropertyOrigin:        ValueUnset,
+		PropertyMountPoint:    ValueNone,
+		PropertyCompression:   "zstd",
+	}
+
+	quota, err := props.Uint(PropertyQuota)
+	require.NoError(t, err)
+	require.Zero(t, quota)
+	used, err := props.Uint(PropertyUsed)
+	require.NoError(t, err)
+	require.EqualValues(t, 4096, used)
+	_, err = props.Uint(PropertyWritten)
+	require.Error(t, err)
+	_, err = props.Uint(PropertyCompression)
+	require.Error(t, err)
+
+	ratio, err := props.Float(PropertyCompressRatio)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, ratio)
+
+	require.True(t, props.Bool(PropertyReadOnly))
+	require.False(t, props.Bool(PropertyMounted))
+
+	creation, err := props.Time(PropertyCreation)
+	require.NoError(t, err)
+	require.True(t, creation.Equal(time.Unix(1700000000, 0)))
+
+	require.Empty(t, props.String(PropertyOrigin))
+	require.Empty(t, props.String(PropertyMountPoint))
+	require.Equal(t, "zstd", props.String(PropertyCompression))
+}
@@ -0,0 +1,136 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiffChange is the type of change of a path between a snapshot and a later snapshot or the filesystem
+type DiffChange string
+
+// Changes of zfs diff
+const (
+	DiffRemoved  DiffChange = "-"
+	DiffCreated  DiffChange = "+"
+	DiffModified DiffChange = "M"
+	DiffRenamed  DiffChange = "R"
+)
+
+// DiffFileType is the type of the file of a changed path
+type DiffFileType string
+
+// File types of zfs diff -F
+const (
+	DiffBlockDevice     DiffFileType = "B"
+	DiffCharacterDevice DiffFileType = "C"
+	DiffDirectory       DiffFileType = "/"
+	DiffDoor            DiffFileType = ">"
+	DiffPipe            DiffFileType = "|"
+	DiffSymlink         DiffFileType = "@"
+	DiffEventPort       DiffFileType = "P"
+	DiffSocket          DiffFileType = "="
+	DiffFile            DiffFileType = "F"
+)
+
+// DiffEntry is a path that changed between a snapshot and a later snapshot or the filesystem
+type DiffEntry struct {
+	Change   DiffChange   `json:"Change"`
+	FileType DiffFileType `json:"FileType"`
+	// Time is the inode change time of the path
+	Time time.Time `json:"Time"`
+	Path string    `json:"Path"`
+	// NewPath is the path a renamed path was renamed to, empty for other changes
+	NewPath string `json:"NewPath"`
+}
+
+// Diff returns the paths that changed between the snapshot and the other snapshot or filesystem, which can be given
+// as @snap for a snapshot of the same filesystem. An empty other compares with the current state of the filesystem.
+func (d *Dataset) Diff(ctx context.Context, other string) ([]DiffEntry, error) {
+	if d.Type != DatasetSnapshot {
+		return nil, ErrOnlySnapshotsSupported
+	}
+	args := []string{"diff", "-FHt", d.Name}
+	if other != "" {
+		args = append(args, other)
+	}
+
+	var entries []DiffEntry
+	c := command{
+		cmd: Binary,
+		ctx: ctx,
+	}
+	err := c.Stream(func(fields []string) error {
+		entry, err := parseDiffEntry(fields)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseDiffEntry parses a line of zfs diff -FHt: time, change, file type, path and, for renames, the new path
+func parseDiffEntry(fields []string) (DiffEntry, error) {
+	if len(fields) != 4 && len(fields) != 5 {
+		return DiffEntry{}, fmt.Errorf("output of zfs diff contains line with %d fields: %v", len(fields), fields)
+	}
+	changed, err := parseDiffTime(fields[0])
+	if err != nil {
+		return DiffEntry{}, fmt.Errorf("error parsing time of %s: %w", fields[3], err)
+	}
+	entry := DiffEntry{
+		Change:   DiffChange(fields[1]),
+		FileType: DiffFileType(fields[2]),
+		Time:     changed,
+		Path:     unescapeDiffPath(fields[3]),
+	}
+	if len(fields) == 5 {
+		entry.NewPath = unescapeDiffPath(fields[4])
+	}
+	return entry, nil
+}
+
+// parseDiffTime parses the seconds.nanoseconds inode change time of zfs diff -t
+func parseDiffTime(value string) (time.Time, error) {
+	secs, nsecs, _ := strings.Cut(value, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var ns int64
+	if nsecs != "" {
+		ns, err = strconv.ParseInt(nsecs, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(s, ns), nil
+}
+
+// unescapeDiffPath decodes the backslash and octal escapes zfs diff writes for whitespace and unprintable bytes,
+// like \0040 for a space
+func unescapeDiffPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+5], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 4
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Diff(t *testing.T) {
+	var executed []string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		_, err := io.WriteString(stdout, "1700000000.000000001\tM\t/\t/pool/fs\n"+
+			"1700000001.500000000\t+\tF\t/pool/fs/new\\0040file\n"+
+			"1700000002.000000000\tR\tF\t/pool/fs/old\t/pool/fs/renamed\n"+
+			"1700000003.000000000\t-\t@\t/pool/fs/link\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	entries, err := snap.Diff(ctx, "@b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"diff", "-FHt", "pool/fs@a", "@b"}, executed)
+	require.Equal(t, []DiffEntry{
+		{Change: DiffModified, FileType: DiffDirectory, Time: time.Unix(1700000000, 1), Path: "/pool/fs"},
+		{Change: DiffCreated, FileType: DiffFile, Time: time.Unix(1700000001, 5e8), Path: "/pool/fs/new file"},
+		{Change: DiffRenamed, FileType: DiffFile, Time: time.Unix(1700000002, 0), Path: "/pool/fs/old", NewPath: "/pool/fs/renamed"},
+		{Change: DiffRemoved, FileType: DiffSymlink, Time: time.Unix(1700000003, 0), Path: "/pool/fs/link"},
+	}, entries)
+
+	_, err = snap.Diff(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"diff", "-FHt", "pool/fs@a"}, executed)
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	_, err = fs.Diff(ctx, "pool/fs@b")
+	require.ErrorIs(t, err, ErrOnlySnapshotsSupported)
+}
+
+func Test_unescapeDiffPath(t *testing.T) {
+	require.Equal(t, "/a b", unescapeDiffPath(`/a\0040b`))
+	require.Equal(t, "/tab\there", unescapeDiffPath(`/tab\0011here`))
+	require.Equal(t, `/back\slash`, unescapeDiffPath(`/back\0134slash`))
+	require.Equal(t, `/trailing\`, unescapeDiffPath(`/trailing\`))
+}
@@ -0,0 +1,285 @@
+// Package dockervolume implements the Docker volume plugin API, storing every volume as a ZFS filesystem.
+// Serve the Driver on the plugin socket, for instance /run/docker/plugins/zfs.sock, to use it as a volume driver.
+package dockervolume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const (
+	// OptionFrom creates the volume as a clone of another volume. The value is either a volume, of which a snapshot
+	// is taken on demand, or a snapshot of a volume, like: volume@snapshot
+	OptionFrom = "from"
+	// OptionSize limits the size of the volume, in bytes
+	OptionSize = "size"
+
+	onDemandSnapshotPrefix = "clone-"
+)
+
+var (
+	// ErrInvalidName is returned for volume names that cannot be used as filesystem name
+	ErrInvalidName = errors.New("invalid volume name")
+	// ErrVolumeInUse is returned when removing a volume that is still mounted by containers
+	ErrVolumeInUse = errors.New("volume is in use")
+)
+
+// Config configures the volume driver
+type Config struct {
+	// ParentDataset is the filesystem the volumes are created in
+	ParentDataset string `json:"ParentDataset" yaml:"ParentDataset"`
+	// Properties are set on every new volume, the volume options are set as properties as well
+	Properties map[string]string `json:"Properties" yaml:"Properties"`
+}
+
+// Volume is a volume as reported to Docker
+type Volume struct {
+	Name       string            `json:"Name"`
+	Mountpoint string            `json:"Mountpoint,omitempty"`
+	Status     map[string]string `json:"Status,omitempty"`
+}
+
+// Driver manages the volumes as filesystems below the parent dataset. Volumes are only mounted while containers use
+// them. The containers using a volume are tracked in memory, so after a restart they are forgotten.
+type Driver struct {
+	config Config
+	logger *slog.Logger
+	ctx    context.Context
+
+	mounts    map[string][]string // Mount IDs of the containers using a volume, indexed by volume
+	mountLock sync.Mutex
+}
+
+// NewDriver creates a new volume driver
+func NewDriver(ctx context.Context, conf Config, logger *slog.Logger) *Driver {
+	return &Driver{
+		config: conf,
+		logger: logger,
+		ctx:    ctx,
+		mounts: make(map[string][]string),
+	}
+}
+
+func (d *Driver) datasetName(volume string) (string, error) {
+	if volume == "" || strings.ContainsAny(volume, "/@# ") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, volume)
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(d.config.ParentDataset, "/"), volume), nil
+}
+
+func (d *Driver) dataset(volume string) (*zfs.Dataset, error) {
+	name, err := d.datasetName(volume)
+	if err != nil {
+		return nil, err
+	}
+	return zfs.GetDataset(d.ctx, name)
+}
+
+// Create creates a volume. The size and from options are handled by the driver, other options are set as properties.
+func (d *Driver) Create(volume string, options map[string]string) error {
+	name, err := d.datasetName(volume)
+	if err != nil {
+		return err
+	}
+
+	props := make(map[string]string, len(d.config.Properties)+len(options)+1)
+	for k, v := range d.config.Properties {
+		props[k] = v
+	}
+	props[zfs.PropertyCanMount] = zfs.CanMountNoAuto // Mounted when used
+	var from string
+	for k, v := range options {
+		switch k {
+		case OptionFrom:
+			from = v
+		case OptionSize:
+			size, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid size %q: %w", v, err)
+			}
+			props[zfs.PropertyRefQuota] = strconv.FormatUint(size, 10)
+		default:
+			props[k] = v
+		}
+	}
+
+	if from != "" {
+		return d.createClone(name, from, props)
+	}
+	_, err = zfs.CreateFilesystem(d.ctx, name, zfs.CreateFilesystemOptions{
+		Properties:  props,
+		NoMount:     true,
+		SkipRefetch: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating volume %s: %w", volume, err)
+	}
+	d.logger.Info("zfs.dockervolume.Driver.Create: Volume created", "volume", volume, "dataset", name)
+	return nil
+}
+
+// createClone creates the volume as a clone of a snapshot of another volume, taking the snapshot when not given
+func (d *Driver) createClone(name, from string, props map[string]string) error {
+	source, snapName, _ := strings.Cut(from, "@")
+	sourceDS, err := d.dataset(source)
+	if err != nil {
+		return fmt.Errorf("error retrieving source volume %s: %w", source, err)
+	}
+
+	var snap *zfs.Dataset
+	if snapName == "" {
+		snap, err = sourceDS.Snapshot(d.ctx, onDemandSnapshotPrefix+name[strings.LastIndex(name, "/")+1:], zfs.SnapshotOptions{
+			SkipRefetch: true,
+		})
+	} else {
+		snap, err = zfs.GetDataset(d.ctx, fmt.Sprintf("%s@%s", sourceDS.Name, snapName))
+	}
+	if err != nil {
+		return fmt.Errorf("error getting snapshot of source volume %s: %w", source, err)
+	}
+
+	_, err = snap.Clone(d.ctx, name, zfs.CloneOptions{
+		Properties:  props,
+		SkipRefetch: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error cloning %s: %w", snap.Name, err)
+	}
+	d.logger.Info("zfs.dockervolume.Driver.createClone: Volume cloned", "dataset", name, "origin", snap.Name)
+	return nil
+}
+
+// Snapshot takes a snapshot of a volume, which can be used to create new volumes from
+func (d *Driver) Snapshot(volume, snapshot string) error {
+	ds, err := d.dataset(volume)
+	if err != nil {
+		return err
+	}
+	_, err = ds.Snapshot(d.ctx, snapshot, zfs.SnapshotOptions{SkipRefetch: true})
+	return err
+}
+
+// Remove destroys a volume along with its snapshots. Volumes cloned from it must be removed first.
+func (d *Driver) Remove(volume string) error {
+	d.mountLock.Lock()
+	defer d.mountLock.Unlock()
+
+	if len(d.mounts[volume]) > 0 {
+		return fmt.Errorf("%w: %s", ErrVolumeInUse, volume)
+	}
+	ds, err := d.dataset(volume)
+	if err != nil {
+		return err
+	}
+	if ds.Mounted {
+		err = ds.Unmount(d.ctx, zfs.UnmountOptions{})
+		if err != nil {
+			return fmt.Errorf("error unmounting volume %s: %w", volume, err)
+		}
+	}
+	err = ds.Destroy(d.ctx, zfs.DestroyOptions{Recursive: true})
+	if err != nil {
+		return fmt.Errorf("error destroying volume %s: %w", volume, err)
+	}
+	d.logger.Info("zfs.dockervolume.Driver.Remove: Volume removed", "volume", volume)
+	return nil
+}
+
+// Mount mounts the volume for a container, and returns its mountpoint
+func (d *Driver) Mount(volume, id string) (string, error) {
+	d.mountLock.Lock()
+	defer d.mountLock.Unlock()
+
+	ds, err := d.dataset(volume)
+	if err != nil {
+		return "", err
+	}
+	if !ds.Mounted {
+		err = ds.Mount(d.ctx, zfs.MountOptions{})
+		if err != nil && !errors.Is(err, zfs.ErrFilesystemAlreadyMounted) {
+			return "", fmt.Errorf("error mounting volume %s: %w", volume, err)
+		}
+	}
+	if !slices.Contains(d.mounts[volume], id) {
+		d.mounts[volume] = append(d.mounts[volume], id)
+	}
+	return ds.Mountpoint, nil
+}
+
+// Unmount releases the volume for a container, it is unmounted when no containers use it anymore
+func (d *Driver) Unmount(volume, id string) error {
+	d.mountLock.Lock()
+	defer d.mountLock.Unlock()
+
+	d.mounts[volume] = slices.DeleteFunc(d.mounts[volume], func(mountID string) bool {
+		return mountID == id
+	})
+	if len(d.mounts[volume]) > 0 {
+		return nil
+	}
+	delete(d.mounts, volume)
+
+	ds, err := d.dataset(volume)
+	if err != nil {
+		return err
+	}
+	if !ds.Mounted {
+		return nil
+	}
+	err = ds.Unmount(d.ctx, zfs.UnmountOptions{})
+	if err != nil {
+		return fmt.Errorf("error unmounting volume %s: %w", volume, err)
+	}
+	return nil
+}
+
+// Get returns a volume
+func (d *Driver) Get(volume string) (Volume, error) {
+	ds, err := d.dataset(volume)
+	if err != nil {
+		return Volume{}, err
+	}
+	return d.volume(ds), nil
+}
+
+// List returns all volumes
+func (d *Driver) List() ([]Volume, error) {
+	list, err := zfs.ListFilesystems(d.ctx, zfs.ListOptions{
+		ParentDataset: d.config.ParentDataset,
+		Depth:         1,
+		FilterSelf:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	volumes := make([]Volume, len(list))
+	for i := range list {
+		volumes[i] = d.volume(&list[i])
+	}
+	return volumes, nil
+}
+
+func (d *Driver) volume(ds *zfs.Dataset) Volume {
+	vol := Volume{
+		Name: ds.Name[strings.LastIndex(ds.Name, "/")+1:],
+		Status: map[string]string{
+			"dataset":    ds.Name,
+			"referenced": strconv.FormatUint(ds.Referenced, 10),
+		},
+	}
+	if ds.Origin != "" {
+		vol.Status["origin"] = ds.Origin
+	}
+	if ds.Mounted {
+		vol.Mountpoint = ds.Mountpoint
+	}
+	return vol
+}
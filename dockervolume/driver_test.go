@@ -0,0 +1,120 @@
+package dockervolume
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func newTestDriver(t *testing.T) *Driver {
+	t.Helper()
+	zfsfake.Install(t, "pool")
+	_, err := zfs.CreateFilesystem(context.Background(), "pool/volumes", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+
+	return NewDriver(context.Background(), Config{
+		ParentDataset: "pool/volumes",
+		Properties:    map[string]string{zfs.PropertyCompression: "zstd"},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDriver(t *testing.T) {
+	d := newTestDriver(t)
+
+	require.ErrorIs(t, d.Create("in/valid", nil), ErrInvalidName)
+	require.NoError(t, d.Create("data", map[string]string{OptionSize: "1048576", "atime": "off"}))
+
+	ds, err := zfs.GetDataset(context.Background(), "pool/volumes/data", "atime")
+	require.NoError(t, err)
+	require.False(t, ds.Mounted)
+	require.EqualValues(t, 1048576, ds.Refquota)
+	require.Equal(t, "zstd", ds.Compression)
+	require.Equal(t, "off", ds.ExtraProps["atime"])
+
+	mountpoint, err := d.Mount("data", "container1")
+	require.NoError(t, err)
+	require.Equal(t, "/pool/volumes/data", mountpoint)
+	_, err = d.Mount("data", "container2")
+	require.NoError(t, err)
+
+	require.NoError(t, d.Unmount("data", "container1"))
+	vol, err := d.Get("data")
+	require.NoError(t, err)
+	require.Equal(t, "/pool/volumes/data", vol.Mountpoint)
+	require.ErrorIs(t, d.Remove("data"), ErrVolumeInUse)
+
+	require.NoError(t, d.Unmount("data", "container2"))
+	vol, err = d.Get("data")
+	require.NoError(t, err)
+	require.Equal(t, "", vol.Mountpoint)
+
+	require.NoError(t, d.Create("copy", map[string]string{OptionFrom: "data"}))
+	vol, err = d.Get("copy")
+	require.NoError(t, err)
+	require.Equal(t, "pool/volumes/data@clone-copy", vol.Status["origin"])
+
+	require.NoError(t, d.Snapshot("data", "manual"))
+	require.NoError(t, d.Create("copy2", map[string]string{OptionFrom: "data@manual"}))
+
+	volumes, err := d.List()
+	require.NoError(t, err)
+	require.Len(t, volumes, 3)
+
+	require.ErrorIs(t, d.Remove("data"), zfs.ErrSnapshotHasDependentClones)
+	require.NoError(t, d.Remove("copy"))
+	require.NoError(t, d.Remove("copy2"))
+	require.NoError(t, d.Remove("data"))
+	_, err = d.Get("data")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+}
+
+func TestDriver_Handler(t *testing.T) {
+	d := newTestDriver(t)
+	server := httptest.NewServer(d.Handler())
+	defer server.Close()
+
+	call := func(path string, req request) (int, response) {
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		resp, err := http.Post(server.URL+path, contentType, bytes.NewReader(data))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	status, resp := call("/Plugin.Activate", request{})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"VolumeDriver"}, resp.Implements)
+
+	status, _ = call("/VolumeDriver.Create", request{Name: "vol"})
+	require.Equal(t, http.StatusOK, status)
+
+	status, resp = call("/VolumeDriver.Mount", request{Name: "vol", ID: "abc"})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "/pool/volumes/vol", resp.Mountpoint)
+
+	status, resp = call("/VolumeDriver.List", request{})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []Volume{{
+		Name:       "vol",
+		Mountpoint: "/pool/volumes/vol",
+		Status:     map[string]string{"dataset": "pool/volumes/vol", "referenced": "24576"},
+	}}, resp.Volumes)
+
+	status, resp = call("/VolumeDriver.Get", request{Name: "missing"})
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.NotEmpty(t, resp.Err)
+}
@@ -0,0 +1,101 @@
+package dockervolume
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// contentType is the content type of the Docker plugin protocol
+const contentType = "application/vnd.docker.plugins.v1.2+json"
+
+type request struct {
+	Name string            `json:"Name"`
+	ID   string            `json:"ID"`
+	Opts map[string]string `json:"Opts"`
+}
+
+type response struct {
+	Mountpoint   string        `json:"Mountpoint,omitempty"`
+	Volume       *Volume       `json:"Volume,omitempty"`
+	Volumes      []Volume      `json:"Volumes,omitempty"`
+	Capabilities *capabilities `json:"Capabilities,omitempty"`
+	Implements   []string      `json:"Implements,omitempty"`
+	Err          string        `json:"Err"`
+}
+
+type capabilities struct {
+	Scope string `json:"Scope"`
+}
+
+type handle func(req request) (response, error)
+
+// Handler returns the HTTP handler implementing the Docker volume plugin protocol for the driver
+func (d *Driver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	d.registerRoute(mux, "/Plugin.Activate", func(request) (response, error) {
+		return response{Implements: []string{"VolumeDriver"}}, nil
+	})
+	d.registerRoute(mux, "/VolumeDriver.Capabilities", func(request) (response, error) {
+		return response{Capabilities: &capabilities{Scope: "local"}}, nil
+	})
+	d.registerRoute(mux, "/VolumeDriver.Create", func(req request) (response, error) {
+		return response{}, d.Create(req.Name, req.Opts)
+	})
+	d.registerRoute(mux, "/VolumeDriver.Remove", func(req request) (response, error) {
+		return response{}, d.Remove(req.Name)
+	})
+	d.registerRoute(mux, "/VolumeDriver.Mount", func(req request) (response, error) {
+		mountpoint, err := d.Mount(req.Name, req.ID)
+		return response{Mountpoint: mountpoint}, err
+	})
+	d.registerRoute(mux, "/VolumeDriver.Unmount", func(req request) (response, error) {
+		return response{}, d.Unmount(req.Name, req.ID)
+	})
+	d.registerRoute(mux, "/VolumeDriver.Path", func(req request) (response, error) {
+		vol, err := d.Get(req.Name)
+		return response{Mountpoint: vol.Mountpoint}, err
+	})
+	d.registerRoute(mux, "/VolumeDriver.Get", func(req request) (response, error) {
+		vol, err := d.Get(req.Name)
+		return response{Volume: &vol}, err
+	})
+	d.registerRoute(mux, "/VolumeDriver.List", func(request) (response, error) {
+		volumes, err := d.List()
+		return response{Volumes: volumes}, err
+	})
+	return mux
+}
+
+func (d *Driver) registerRoute(mux *http.ServeMux, path string, handler handle) {
+	mux.HandleFunc(http.MethodPost+" "+path, func(w http.ResponseWriter, r *http.Request) {
+		logger := d.logger.With("path", path)
+
+		var req request
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				logger.Info("zfs.dockervolume.Driver.Handler: Invalid request", "error", err)
+				writeResponse(w, http.StatusBadRequest, response{Err: err.Error()}, logger)
+				return
+			}
+		}
+
+		resp, err := handler(req)
+		if err != nil {
+			logger.Warn("zfs.dockervolume.Driver.Handler: Request failed", "error", err, "volume", req.Name)
+			writeResponse(w, http.StatusInternalServerError, response{Err: err.Error()}, logger)
+			return
+		}
+		writeResponse(w, http.StatusOK, resp, logger)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response, logger *slog.Logger) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		logger.Error("zfs.dockervolume.writeResponse: Error writing response", "error", err)
+	}
+}
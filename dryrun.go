@@ -0,0 +1,104 @@
+package zfs
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// dryRunCommands are the destructive subcommands that are not run in dry-run mode
+var dryRunCommands = []string{"destroy", "rollback", "rename", "inherit"}
+
+// DryRunCommand is a destructive command that was validated but not run in dry-run mode
+type DryRunCommand struct {
+	// Args are the arguments of the zfs command that would have run
+	Args []string `json:"Args"`
+	// Destroy is what destroying would have done, for destroy commands
+	Destroy *DestroyEstimate `json:"Destroy,omitempty"`
+}
+
+type dryRun struct {
+	mu       sync.Mutex
+	commands []DryRunCommand
+}
+
+type dryRunContextKey struct{}
+
+// WithDryRun returns a context in which the destroy, rollback, rename and inherit commands are validated but not run.
+// Destroys run as a zfs destroy dry run, the others check whether the dataset exists. The commands that would have
+// run are logged with the default slog logger and returned by DryRunCommands. Other commands, like creating snapshots
+// or setting properties, do run.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, &dryRun{})
+}
+
+// IsDryRun returns whether destructive commands run with the context are only validated, see WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	return loadDryRun(ctx) != nil
+}
+
+// DryRunCommands returns the destructive commands that were not run with the dry-run context, see WithDryRun
+func DryRunCommands(ctx context.Context) []DryRunCommand {
+	d := loadDryRun(ctx)
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.commands)
+}
+
+func loadDryRun(ctx context.Context) *dryRun {
+	d, _ := ctx.Value(dryRunContextKey{}).(*dryRun)
+	return d
+}
+
+// skipDryRun returns whether the command is not run because the context is in dry-run mode. The command is validated
+// and logged instead, the error is that of the validation.
+func (c *command) skipDryRun(arg []string) (bool, error) {
+	d := loadDryRun(c.ctx)
+	if d == nil || c.cmd != Binary || len(arg) < 2 || !slices.Contains(dryRunCommands, arg[0]) {
+		return false, nil
+	}
+	if arg[0] == "destroy" && slices.Contains(arg, "-n") {
+		return false, nil // A dry run already
+	}
+
+	// Validate without the dry-run mode
+	ctx := context.WithValue(c.ctx, dryRunContextKey{}, (*dryRun)(nil))
+	record := DryRunCommand{Args: slices.Clone(arg)}
+	switch arg[0] {
+	case "destroy":
+		validate := append([]string{"destroy", "-n", "-p", "-v"}, arg[1:]...)
+		out, err := zfsOutput(ctx, validate...)
+		if err != nil {
+			return true, err
+		}
+		record.Destroy, err = parseDestroyEstimate(out, arg[len(arg)-1])
+		if err != nil {
+			return true, err
+		}
+	default:
+		dataset := arg[len(arg)-1]
+		if arg[0] == "rename" {
+			dataset = arg[len(arg)-2]
+		}
+		_, err := zfsOutput(ctx, "list", "-H", "-o", PropertyName, dataset)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	if record.Destroy != nil {
+		slog.Info("zfs.command.skipDryRun: Not running command in dry-run mode", "args", arg,
+			"datasets", record.Destroy.Datasets, "reclaim", record.Destroy.Reclaim)
+	} else {
+		slog.Info("zfs.command.skipDryRun: Not running command in dry-run mode", "args", arg)
+	}
+
+	d.mu.Lock()
+	d.commands = append(d.commands, record)
+	d.mu.Unlock()
+	return true, nil
+}
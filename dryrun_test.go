@@ -0,0 +1,57 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithDryRun(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		switch {
+		case args[0] == "destroy":
+			_, err := io.WriteString(stdout, "destroy\tpool/fs@a\nreclaim\t4096\n")
+			return "", err
+		case args[len(args)-1] == "pool/missing":
+			return "cannot open 'pool/missing': dataset does not exist", errors.New("exit status 1")
+		}
+		_, err := io.WriteString(stdout, args[len(args)-1]+"\n")
+		return "", err
+	}))
+	require.False(t, IsDryRun(ctx))
+	ctx = WithDryRun(ctx)
+	require.True(t, IsDryRun(ctx))
+
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	missing := &Dataset{Name: "pool/missing", Type: DatasetFilesystem}
+	require.NoError(t, snap.Destroy(ctx, DestroyOptions{Recursive: true}))
+	require.NoError(t, snap.Rollback(ctx, RollbackOptions{}))
+	require.NoError(t, fs.Rename(ctx, "pool/fs2", RenameOptions{}))
+	require.NoError(t, fs.InheritProperty(ctx, PropertyMountPoint))
+	require.ErrorIs(t, missing.InheritProperty(ctx, PropertyMountPoint), ErrDatasetNotFound)
+	require.NoError(t, fs.SetProperty(ctx, PropertyMountPoint, "/srv"))
+
+	require.Equal(t, [][]string{
+		{"destroy", "-n", "-p", "-v", "-r", "pool/fs@a"},
+		{"list", "-H", "-o", "name", "pool/fs@a"},
+		{"list", "-H", "-o", "name", "pool/fs"},
+		{"list", "-H", "-o", "name", "pool/fs"},
+		{"list", "-H", "-o", "name", "pool/missing"},
+		{"set", "mountpoint=/srv", "pool/fs"},
+	}, executed)
+	require.Equal(t, []DryRunCommand{
+		{
+			Args:    []string{"destroy", "-r", "pool/fs@a"},
+			Destroy: &DestroyEstimate{Datasets: []string{"pool/fs@a"}, Reclaim: 4096},
+		},
+		{Args: []string{"rollback", "pool/fs@a"}},
+		{Args: []string{"rename", "pool/fs", "pool/fs2"}},
+		{Args: []string{"inherit", "mountpoint", "pool/fs"}},
+	}, DryRunCommands(ctx))
+}
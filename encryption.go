@@ -0,0 +1,114 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ChangeKeyOptions are options you can specify to customize the change-key command
+type ChangeKeyOptions struct {
+	// KeyFormat sets a new format of the key, raw, hex or passphrase
+	KeyFormat string
+
+	// KeyLocation sets a new location of the key, prompt or a file:// URL
+	KeyLocation string
+
+	// LoadKey loads the current key before changing it, when it was not loaded yet
+	LoadKey bool
+
+	// Inherit makes the dataset inherit the key of its parent, so it is no longer an encryption root
+	Inherit bool
+
+	// Provide a reader to read the current key from stdin when loading it, and the new key when its location is prompt
+	KeyReader io.Reader
+}
+
+// ChangeKey changes the user's key of the encryption root, which wraps the key the data is encrypted with.
+// The data does not have to be rewritten, so rotating the key is fast.
+// See: https://openzfs.github.io/openzfs-docs/man/8/zfs-change-key.8.html
+func (d *Dataset) ChangeKey(ctx context.Context, options ChangeKeyOptions) error {
+	if options.Inherit && (options.KeyFormat != "" || options.KeyLocation != "") {
+		return fmt.Errorf("change-key on %s: cannot inherit the key and set a key format or location", d.Name)
+	}
+
+	args := make([]string, 1, 8)
+	args[0] = "change-key"
+	if options.LoadKey {
+		args = append(args, "-l")
+	}
+	if options.Inherit {
+		args = append(args, "-i")
+	}
+	if options.KeyFormat != "" {
+		args = append(args, "-o", fmt.Sprintf("%s=%s", PropertyKeyFormat, options.KeyFormat))
+	}
+	if options.KeyLocation != "" {
+		args = append(args, "-o", fmt.Sprintf("%s=%s", PropertyKeyLocation, options.KeyLocation))
+	}
+	args = append(args, d.Name)
+
+	c := command{
+		cmd:   Binary,
+		ctx:   ctx,
+		stdin: options.KeyReader,
+	}
+	_, err := c.Run(args...)
+	return err
+}
+
+// KeyInfo is the encryption state of a dataset
+type KeyInfo struct {
+	// Encryption is the encryption algorithm, off when the dataset is not encrypted
+	Encryption string `json:"Encryption"`
+	// EncryptionRoot is the dataset whose key this dataset inherits, empty when the dataset is not encrypted
+	EncryptionRoot string `json:"EncryptionRoot"`
+	// KeyFormat and KeyLocation are empty when the dataset is not encrypted
+	KeyFormat   string `json:"KeyFormat"`
+	KeyLocation string `json:"KeyLocation"`
+	// Loaded is whether the key is loaded, so the data can be accessed
+	Loaded bool `json:"Loaded"`
+}
+
+// Encrypted returns whether the dataset is encrypted
+func (k *KeyInfo) Encrypted() bool {
+	return k.Encryption != "" && k.Encryption != ValueOff
+}
+
+// KeyInfo returns the encryption state of the dataset, regardless of the fields retrieved for the dataset
+func (d *Dataset) KeyInfo(ctx context.Context) (*KeyInfo, error) {
+	out, err := zfsOutput(ctx, "get", "-Hp", "-o", "property,value", fmt.Sprintf("%s,%s,%s,%s,%s",
+		PropertyEncryption, PropertyEncryptionRoot, PropertyKeyFormat, PropertyKeyLocation, PropertyKeyStatus,
+	), d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &KeyInfo{}
+	for _, fields := range out {
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("output contains line with %d fields: %v", len(fields), fields)
+		}
+		switch fields[0] {
+		case PropertyEncryption:
+			info.Encryption = fields[1]
+		case PropertyEncryptionRoot:
+			info.EncryptionRoot = setString(fields[1])
+		case PropertyKeyFormat:
+			info.KeyFormat = keyValue(fields[1])
+		case PropertyKeyLocation:
+			info.KeyLocation = keyValue(fields[1])
+		case PropertyKeyStatus:
+			info.Loaded = fields[1] == KeyStatusAvailable
+		}
+	}
+	return info, nil
+}
+
+// keyValue returns the value of a key property, which is none for unencrypted datasets
+func keyValue(val string) string {
+	if val == ValueNone {
+		return ""
+	}
+	return setString(val)
+}
@@ -0,0 +1,102 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ChangeKey(t *testing.T) {
+	var executed [][]string
+	var stdins []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, stdin io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		if stdin != nil {
+			input, err := io.ReadAll(stdin)
+			require.NoError(t, err)
+			stdins = append(stdins, string(input))
+		}
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "tank/secret", Type: DatasetFilesystem}
+	require.NoError(t, ds.ChangeKey(ctx, ChangeKeyOptions{
+		KeyFormat:   KeyFormatPassphrase,
+		KeyLocation: KeyLocationPrompt,
+		LoadKey:     true,
+		KeyReader:   strings.NewReader("old-passphrase\nnew-passphrase\n"),
+	}))
+	require.NoError(t, ds.ChangeKey(ctx, ChangeKeyOptions{Inherit: true}))
+	require.Error(t, ds.ChangeKey(ctx, ChangeKeyOptions{Inherit: true, KeyFormat: KeyFormatHex}))
+
+	require.Equal(t, [][]string{
+		{"change-key", "-l", "-o", "keyformat=passphrase", "-o", "keylocation=prompt", "tank/secret"},
+		{"change-key", "-i", "tank/secret"},
+	}, executed)
+	require.Equal(t, []string{"old-passphrase\nnew-passphrase\n"}, stdins)
+}
+
+func Test_KeyInfo(t *testing.T) {
+	output := "encryption\taes-256-gcm\nencryptionroot\ttank/secret\nkeyformat\tpassphrase\nkeylocation\tprompt\nkeystatus\tavailable\n"
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"get", "-Hp", "-o", "property,value", "encryption,encryptionroot,keyformat,keylocation,keystatus", "tank/secret/child"}, args)
+		_, err := io.WriteString(stdout, output)
+		return "", err
+	}))
+
+	ds := &Dataset{Name: "tank/secret/child", Type: DatasetFilesystem}
+	info, err := ds.KeyInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &KeyInfo{
+		Encryption:     EncryptionAES256GCM,
+		EncryptionRoot: "tank/secret",
+		KeyFormat:      KeyFormatPassphrase,
+		KeyLocation:    KeyLocationPrompt,
+		Loaded:         true,
+	}, info)
+	require.True(t, info.Encrypted())
+
+	output = "encryption\toff\nencryptionroot\t-\nkeyformat\tnone\nkeylocation\tnone\nkeystatus\t-\n"
+	info, err = ds.KeyInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &KeyInfo{Encryption: ValueOff}, info)
+	require.False(t, info.Encrypted())
+}
+
+func Test_CreateFilesystemEncrypted(t *testing.T) {
+	var executed []string
+	var stdin string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, input io.Reader, _ io.Writer) (string, error) {
+		executed = args
+		data, err := io.ReadAll(input)
+		require.NoError(t, err)
+		stdin = string(data)
+		return "", nil
+	}))
+
+	_, err := CreateFilesystem(ctx, "tank/secret", CreateFilesystemOptions{
+		Properties:       map[string]string{PropertyCanMount: CanMountNoAuto},
+		KeyFormat:        KeyFormatPassphrase,
+		PassphraseReader: strings.NewReader("passphrase\n"),
+		SkipRefetch:      true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "create", executed[0])
+	require.Equal(t, "tank/secret", executed[len(executed)-1])
+	require.ElementsMatch(t, []string{
+		"-o", "canmount=noauto", "-o", "encryption=on", "-o", "keyformat=passphrase", "-o", "keylocation=prompt",
+	}, executed[1:len(executed)-1])
+	require.Equal(t, "passphrase\n", stdin)
+
+	for _, options := range []CreateFilesystemOptions{
+		{PassphraseReader: strings.NewReader("passphrase"), Stdin: strings.NewReader("passphrase")},
+		{PassphraseReader: strings.NewReader("passphrase")},
+		{KeyFormat: KeyFormatHex, KeyLocation: "file:///key", PassphraseReader: strings.NewReader("passphrase")},
+	} {
+		_, err = CreateFilesystem(ctx, "tank/secret", options)
+		require.Error(t, err)
+	}
+}
@@ -20,6 +20,12 @@ const (
 	datasetExistsMessage         = "dataset already exists"
 	destinationExistsMessage1    = "destination '"
 	destinationExistsMessage2    = "' exists"
+	poolScrubbingMessage         = "currently scrubbing"
+	poolNotFoundMessage          = "no such pool"
+	noActiveScrubMessage         = "there is no active scrub"
+	holdExistsMessage            = "tag already exists on this dataset"
+	holdNotFoundMessage          = "no such tag on this dataset"
+	permissionDeniedMessage      = "permission denied"
 )
 
 var (
@@ -52,14 +58,57 @@ var (
 
 	// ErrFilesystemAlreadyMounted is returned when mounting an already mounted filesystem
 	ErrFilesystemAlreadyMounted = errors.New("filesystem already mounted")
+
+	// ErrCommandCancelled is returned when a command was terminated because its context is done
+	ErrCommandCancelled = errors.New("command cancelled")
+
+	// ErrNotSupported is returned when an option is not supported by the zfs commands of the platform, see Platform
+	ErrNotSupported = errors.New("not supported on this platform")
+
+	// ErrPoolNotFound is returned when the pool is not imported
+	ErrPoolNotFound = errors.New("pool not found")
+
+	// ErrPoolScrubbing is returned when starting a scrub of a pool that is being scrubbed already
+	ErrPoolScrubbing = errors.New("pool is currently scrubbing")
+
+	// ErrNoActiveScrub is returned when stopping or pausing the scrub of a pool that is not being scrubbed
+	ErrNoActiveScrub = errors.New("no active scrub")
+
+	// ErrHoldExists is returned when adding a hold with a tag the snapshot has a hold with already
+	ErrHoldExists = errors.New("hold already exists")
+
+	// ErrHoldNotFound is returned when releasing a hold the snapshot does not have
+	ErrHoldNotFound = errors.New("hold not found")
+
+	// ErrPermissionDenied is returned when the user is not allowed to run the command, see zfs allow
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrUnknownField is returned when a field is requested that is not part of the Dataset struct
+	ErrUnknownField = errors.New("unknown dataset field")
+
+	// ErrNoSnapshots is returned when replicating a dataset without snapshots
+	ErrNoSnapshots = errors.New("dataset has no snapshots")
+
+	// ErrNoCommonSnapshot is returned when replicating to a target that has snapshots, but none in common with the source
+	ErrNoCommonSnapshot = errors.New("no common snapshot with target")
+
+	// ErrInvalidSnapshotRange is returned when a snapshot range is unbounded or spans other datasets
+	ErrInvalidSnapshotRange = errors.New("invalid snapshot range")
 )
 
 // CommandError is an error which is returned when the `zfs` or `zpool` shell
-// commands return with a non-zero exit code.
+// commands return with a non-zero exit code. When the stderr is recognised, the error
+// also matches the sentinel error of its Kind, like ErrDatasetNotFound, with errors.Is.
 type CommandError struct {
 	Err    error
 	Debug  string
 	Stderr string
+	// Args are the arguments of the command, starting with the command itself
+	Args []string
+	// ExitCode is the exit code of the command, -1 when it is unknown
+	ExitCode int
+	// Kind is the sentinel error the stderr was recognised as, nil when it was not recognised
+	Kind error
 }
 
 // ResumableStreamError is returned when a zfs send is interrupted and contains the token
@@ -70,54 +119,91 @@ type ResumableStreamError struct {
 	ReceiveResumeToken string
 }
 
-func createError(cmd *exec.Cmd, stderr string, err error) error {
-	switch {
-	case strings.Contains(stderr, datasetNotFoundMessage):
-		return ErrDatasetNotFound
-	case strings.Contains(stderr, datasetBusyMessage):
-		idx := strings.LastIndex(stderr, ":")
-		if idx > 0 {
-			stderr = stderr[:idx]
+// errorKinds are the sentinel errors of command errors, by a message in the stderr. The first match is used.
+var errorKinds = []struct {
+	match func(stderr string) bool
+	kind  error
+}{
+	{contains(datasetNotFoundMessage), ErrDatasetNotFound},
+	{contains(datasetBusyMessage), ErrPoolOrDatasetBusy},
+	{contains(poolIOSuspendedMessage), ErrPoolIOSuspended},
+	{contains(datasetNoLongerExistsMessage), ErrDatasetNotFound},
+	{contains(datasetExistsMessage), ErrDatasetExists},
+	{func(stderr string) bool {
+		return strings.Contains(stderr, destinationExistsMessage1) && strings.Contains(stderr, destinationExistsMessage2)
+	}, ErrDatasetExists},
+	{contains(snapshotHasDependentsMessage), ErrSnapshotHasDependentClones},
+	{contains(keyAlreadyLoadedMessage), ErrKeyAlreadyLoaded},
+	{contains(keyAlreadyUnloadedMessage), ErrKeyAlreadyUnloaded},
+	{contains(filesystemAlreadyMounted), ErrFilesystemAlreadyMounted},
+	{contains(poolNotFoundMessage), ErrPoolNotFound},
+	{contains(holdExistsMessage), ErrHoldExists},
+	{contains(holdNotFoundMessage), ErrHoldNotFound},
+	{contains(noActiveScrubMessage), ErrNoActiveScrub},
+	{contains(poolScrubbingMessage), ErrPoolScrubbing},
+	{func(stderr string) bool {
+		return strings.Contains(strings.ToLower(stderr), permissionDeniedMessage)
+	}, ErrPermissionDenied},
+}
+
+func contains(message string) func(stderr string) bool {
+	return func(stderr string) bool {
+		return strings.Contains(stderr, message)
+	}
+}
+
+// errorKind returns the sentinel error the stderr is recognised as, or nil
+func errorKind(stderr string) error {
+	for _, kind := range errorKinds {
+		if kind.match(stderr) {
+			return kind.kind
 		}
-		return fmt.Errorf("%s: %w", stderr, ErrPoolOrDatasetBusy)
-	case strings.Contains(stderr, poolIOSuspendedMessage):
-		return fmt.Errorf("%s: %w", stderr, ErrPoolIOSuspended)
-	case strings.Contains(stderr, datasetNoLongerExistsMessage):
-		return fmt.Errorf("%s: %w", stderr, ErrDatasetNotFound)
-	case strings.Contains(stderr, datasetExistsMessage),
-		strings.Contains(stderr, destinationExistsMessage1) && strings.Contains(stderr, destinationExistsMessage2):
-		return fmt.Errorf("%s: %w", stderr, ErrDatasetExists)
-	case strings.Contains(stderr, snapshotHasDependentsMessage):
-		return fmt.Errorf("%s: %w", stderr, ErrSnapshotHasDependentClones)
-	case strings.Contains(stderr, keyAlreadyLoadedMessage):
-		return fmt.Errorf("%s: %w", stderr, ErrKeyAlreadyLoaded)
-	case strings.Contains(stderr, keyAlreadyUnloadedMessage):
-		return fmt.Errorf("%s: %w", stderr, ErrKeyAlreadyUnloaded)
-	case strings.Contains(stderr, filesystemAlreadyMounted):
-		return fmt.Errorf("%s: %w", stderr, ErrFilesystemAlreadyMounted)
-	case strings.Contains(stderr, resumableErrorMessage):
+	}
+	return nil
+}
+
+func createError(cmd *exec.Cmd, stderr string, err error) error {
+	cmdErr := CommandError{
+		Err:      err,
+		Debug:    strings.Join(append([]string{cmd.Path}, cmd.Args...), " "),
+		Stderr:   stderr,
+		Args:     cmd.Args,
+		ExitCode: -1,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	}
+
+	cmdErr.Kind = errorKind(stderr)
+	if cmdErr.Kind != nil {
+		return &cmdErr
+	}
+	if strings.Contains(stderr, resumableErrorMessage) {
 		return &ResumableStreamError{
-			CommandError: CommandError{
-				Err:    err,
-				Debug:  strings.Join(append([]string{cmd.Path}, cmd.Args...), " "),
-				Stderr: stderr,
-			},
+			CommandError:       cmdErr,
 			ReceiveResumeToken: extractStderrResumeToken(stderr),
 		}
 	}
-
-	return &CommandError{
-		Err:    err,
-		Debug:  strings.Join(append([]string{cmd.Path}, cmd.Args...), " "),
-		Stderr: stderr,
-	}
+	return &cmdErr
 }
 
 // CommandError returns the string representation of an CommandError.
 func (e CommandError) Error() string {
+	if e.Kind != nil {
+		return fmt.Sprintf("%s: %s", strings.TrimSpace(e.Stderr), e.Kind)
+	}
 	return fmt.Sprintf("%s: %q => %s", e.Err, e.Debug, e.Stderr)
 }
 
+// Unwrap returns the error of running the command, and the sentinel error of its kind
+func (e CommandError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
 // ResumeToken returns the resume token for this send
 func (e ResumableStreamError) ResumeToken() string {
 	return e.ReceiveResumeToken
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os/exec"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestError(t *testing.T) {
@@ -49,3 +51,38 @@ func Test_createError(t *testing.T) {
 		t.Fatalf("unexpected error type: %v", err)
 	}
 }
+
+func Test_createErrorKinds(t *testing.T) {
+	tests := []struct {
+		stderr string
+		kind   error
+	}{
+		{"cannot open 'pool/fs': dataset does not exist", ErrDatasetNotFound},
+		{"cannot create 'pool/fs': dataset already exists", ErrDatasetExists},
+		{"cannot create 'pool/fs': permission denied", ErrPermissionDenied},
+		{"Permission denied the ZFS utilities must be run as root.", ErrPermissionDenied},
+		{"cannot open 'pool': pool I/O is currently suspended", ErrPoolIOSuspended},
+		{"cannot release hold from snapshot 'pool/fs@snap': no such tag on this dataset", ErrHoldNotFound},
+		{"something unexpected", nil},
+	}
+
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	cmd := &exec.Cmd{Path: "/sbin/zfs", Args: []string{"zfs", "create", "pool/fs"}}
+	for _, test := range tests {
+		err := createError(cmd, test.stderr, exitErr)
+
+		var cmdErr *CommandError
+		require.ErrorAs(t, err, &cmdErr)
+		require.Equal(t, test.kind, cmdErr.Kind)
+		require.Equal(t, []string{"zfs", "create", "pool/fs"}, cmdErr.Args)
+		require.Equal(t, 3, cmdErr.ExitCode)
+		require.Equal(t, test.stderr, cmdErr.Stderr)
+		require.ErrorIs(t, err, exitErr)
+		if test.kind != nil {
+			require.ErrorIs(t, err, test.kind)
+		}
+	}
+
+	err := createError(cmd, "cannot create 'pool/fs': dataset already exists", errors.New("exit status 1"))
+	require.Equal(t, -1, err.(*CommandError).ExitCode)
+}
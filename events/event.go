@@ -0,0 +1,157 @@
+// Package events streams the kernel ZFS events of zpool events as typed structs on a channel, and can forward them
+// to an event emitter, such as the one of the job runner. The snapshot watcher does the same for snapshots that are
+// created, destroyed or renamed outside this package.
+package events
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event classes that are commonly acted upon, see zpool-events(8) for all of them
+const (
+	ClassScrubStart     = "sysevent.fs.zfs.scrub_start"
+	ClassScrubFinish    = "sysevent.fs.zfs.scrub_finish"
+	ClassScrubAbort     = "sysevent.fs.zfs.scrub_abort"
+	ClassResilverStart  = "sysevent.fs.zfs.resilver_start"
+	ClassResilverFinish = "sysevent.fs.zfs.resilver_finish"
+	ClassTrimFinish     = "sysevent.fs.zfs.trim_finish"
+	ClassPoolImport     = "sysevent.fs.zfs.pool_import"
+	ClassHistory        = "sysevent.fs.zfs.history_event"
+	ClassStateChange    = "resource.fs.zfs.statechange"
+	ClassChecksumError  = "ereport.fs.zfs.checksum"
+	ClassIOError        = "ereport.fs.zfs.io"
+	ClassDataError      = "ereport.fs.zfs.data"
+	ClassDeadmanError   = "ereport.fs.zfs.deadman"
+	ClassErrorPrefix    = "ereport.fs.zfs."
+	ClassSysEventPrefix = "sysevent.fs.zfs."
+)
+
+const (
+	headerTimeLayout     = "Jan 2 2006 15:04:05.000000000"
+	embeddedNVListMarker = "(embedded nvlist)"
+)
+
+// Event is a kernel ZFS event
+type Event struct {
+	Time  time.Time
+	Class string
+	// EID is the event ID, which increases for every event until the ZFS module is reloaded
+	EID      uint64
+	Pool     string
+	PoolGUID uint64
+	// VdevPath is the path of the vdev the event is about, if any
+	VdevPath string
+	// Fields are all fields of the event. Strings are unquoted, numbers are in hexadecimal like 0x1f, and the
+	// fields of embedded lists are prefixed with the name of the list and a dot.
+	Fields map[string]string
+}
+
+// Subclass returns the last part of the class, like scrub_finish
+func (e *Event) Subclass() string {
+	return e.Class[strings.LastIndexByte(e.Class, '.')+1:]
+}
+
+// IsError returns whether the event is an error report
+func (e *Event) IsError() bool {
+	return strings.HasPrefix(e.Class, ClassErrorPrefix)
+}
+
+// Uint returns a numeric field, and whether it was present and numeric
+func (e *Event) Uint(field string) (uint64, bool) {
+	value, ok := e.Fields[field]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value, 0, 64)
+	return n, err == nil
+}
+
+// parser assembles events from the lines of zpool events -vH
+type parser struct {
+	event  *Event
+	nested []string
+	emit   func(Event) error
+}
+
+func (p *parser) line(line string) error {
+	switch {
+	case strings.TrimSpace(line) == "":
+		return p.flush()
+	case line[0] != ' ' && line[0] != '\t':
+		err := p.flush()
+		if err != nil {
+			return err
+		}
+		p.header(line)
+		return nil
+	case p.event != nil:
+		p.field(strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// header starts a new event from its time and class
+func (p *parser) header(line string) {
+	p.event = &Event{Fields: make(map[string]string, 16)}
+	p.nested = p.nested[:0]
+
+	tm, class, ok := strings.Cut(line, "\t")
+	if !ok {
+		// Without scripted mode the time and class are separated by spaces, the time has four fields
+		fields := strings.Fields(line)
+		tm = strings.Join(fields[:min(4, len(fields))], " ")
+		class = strings.Join(fields[min(4, len(fields)):], " ")
+	}
+	p.event.Class = strings.TrimSpace(class)
+	p.event.Time, _ = time.ParseInLocation(headerTimeLayout, strings.TrimSpace(tm), time.Local)
+}
+
+func (p *parser) field(line string) {
+	if name, ok := strings.CutPrefix(line, "(end "); ok {
+		if len(p.nested) > 0 && strings.TrimSuffix(name, ")") == p.nested[len(p.nested)-1] {
+			p.nested = p.nested[:len(p.nested)-1]
+		}
+		return
+	}
+	name, value, ok := strings.Cut(line, " = ")
+	if !ok {
+		return
+	}
+	if value == embeddedNVListMarker {
+		p.nested = append(p.nested, name)
+		return
+	}
+	if len(p.nested) > 0 {
+		name = strings.Join(p.nested, ".") + "." + name
+	}
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	p.event.Fields[name] = value
+}
+
+// flush emits the event being assembled, if any
+func (p *parser) flush() error {
+	event := p.event
+	if event == nil {
+		return nil
+	}
+	p.event = nil
+
+	event.EID, _ = event.Uint("eid")
+	event.Pool = event.Fields["pool"]
+	event.PoolGUID, _ = event.Uint("pool_guid")
+	event.VdevPath = event.Fields["vdev_path"]
+	// The time field holds the seconds and nanoseconds, which is more precise than the header and timezone-free
+	if seconds, nanos, ok := strings.Cut(event.Fields["time"], " "); ok {
+		s, errS := strconv.ParseInt(seconds, 0, 64)
+		ns, errNS := strconv.ParseInt(strings.TrimSpace(nanos), 0, 64)
+		if errS == nil && errNS == nil {
+			event.Time = time.Unix(s, ns)
+		}
+	}
+	return p.emit(*event)
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testEvents = `Oct 15 2026 10:00:00.123456789	sysevent.fs.zfs.scrub_finish
+	version = 0x0
+	class = "sysevent.fs.zfs.scrub_finish"
+	pool = "tank"
+	pool_guid = 0x1f2e3d4c5b6a7988
+	pool_state = 0x0
+	pool_context = 0x0
+	time = 0x6a0f3a10 0x75bcd15
+	eid = 0x2a
+
+Oct 15 2026 10:05:00.000000000	ereport.fs.zfs.checksum
+	class = "ereport.fs.zfs.checksum"
+	pool = "tank"
+	pool_guid = 0x1f2e3d4c5b6a7988
+	vdev_path = "/dev/sda1"
+	vdev_guid = 0x10
+	zio_err = 0x34
+	bad_ranges = (embedded nvlist)
+		offset = 0x200
+	(end bad_ranges)
+	time = 0x6a0f3b3c 0x0
+	eid = 0x2b
+
+`
+
+func parseTestEvents(t *testing.T, output string) []Event {
+	var events []Event
+	p := &parser{emit: func(event Event) error {
+		events = append(events, event)
+		return nil
+	}}
+	for _, line := range strings.Split(output, "\n") {
+		require.NoError(t, p.line(line))
+	}
+	return events
+}
+
+func TestParser(t *testing.T) {
+	events := parseTestEvents(t, testEvents)
+	require.Len(t, events, 2)
+
+	scrub := events[0]
+	require.Equal(t, ClassScrubFinish, scrub.Class)
+	require.Equal(t, "scrub_finish", scrub.Subclass())
+	require.False(t, scrub.IsError())
+	require.EqualValues(t, 42, scrub.EID)
+	require.Equal(t, "tank", scrub.Pool)
+	require.EqualValues(t, uint64(0x1f2e3d4c5b6a7988), scrub.PoolGUID)
+	require.Equal(t, time.Unix(0x6a0f3a10, 123456789), scrub.Time)
+	state, ok := scrub.Uint("pool_state")
+	require.True(t, ok)
+	require.Zero(t, state)
+
+	checksum := events[1]
+	require.Equal(t, ClassChecksumError, checksum.Class)
+	require.True(t, checksum.IsError())
+	require.EqualValues(t, 43, checksum.EID)
+	require.Equal(t, "/dev/sda1", checksum.VdevPath)
+	require.Equal(t, "0x200", checksum.Fields["bad_ranges.offset"])
+	require.Equal(t, "0x6a0f3b3c 0x0", checksum.Fields["time"])
+	_, ok = checksum.Uint("missing")
+	require.False(t, ok)
+}
+
+func TestParser_Header(t *testing.T) {
+	events := parseTestEvents(t, "Oct 15 2026 10:00:00.500000000 sysevent.fs.zfs.history_event\n\tpool = \"tank\"\n\n")
+	require.Len(t, events, 1)
+	require.Equal(t, ClassHistory, events[0].Class)
+	require.Equal(t, time.Date(2026, time.October, 15, 10, 0, 0, 500000000, time.Local), events[0].Time)
+	require.Zero(t, events[0].EID)
+}
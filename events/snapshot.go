@@ -0,0 +1,242 @@
+package events
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	eventemitter "github.com/vansante/go-event-emitter"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const defaultSnapshotIntervalSeconds = 60
+
+// Snapshot event types
+const (
+	SnapshotCreated   = "created"
+	SnapshotDestroyed = "destroyed"
+	SnapshotRenamed   = "renamed"
+)
+
+// Event types for forwarding snapshot events to an emitter, the SnapshotEvent is the only argument
+const (
+	SnapshotCreatedEvent   eventemitter.EventType = "zfs-snapshot-created"
+	SnapshotDestroyedEvent eventemitter.EventType = "zfs-snapshot-destroyed"
+	SnapshotRenamedEvent   eventemitter.EventType = "zfs-snapshot-renamed"
+)
+
+// SnapshotEvent is a change of the snapshots of a watched dataset
+type SnapshotEvent struct {
+	// Type is created, destroyed or renamed
+	Type string
+	// Dataset is the dataset of the snapshot
+	Dataset string
+	// Snapshot is the full name of the snapshot, the new name when it was renamed
+	Snapshot string
+	// PreviousName is the full name of a renamed snapshot before it was renamed
+	PreviousName string
+	GUID         uint64
+	Creation     time.Time
+}
+
+// SnapshotConfig configures the snapshot watcher
+type SnapshotConfig struct {
+	// Datasets are the datasets whose snapshots are watched
+	Datasets []string `json:"Datasets" yaml:"Datasets"`
+	// Recursive watches the snapshots of the descendants of the datasets too
+	Recursive bool `json:"Recursive" yaml:"Recursive"`
+	// IgnoreExisting skips the created events of the snapshots that exist when the watcher starts
+	IgnoreExisting bool `json:"IgnoreExisting" yaml:"IgnoreExisting"`
+	// IntervalSeconds is the interval of listing the snapshots
+	IntervalSeconds int64 `json:"IntervalSeconds" yaml:"IntervalSeconds"`
+	// BufferSize is the amount of events buffered in the channel
+	BufferSize int `json:"BufferSize" yaml:"BufferSize"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *SnapshotConfig) ApplyDefaults() {
+	c.IntervalSeconds = defaultSnapshotIntervalSeconds
+	c.BufferSize = defaultBufferSize
+}
+
+type snapshotForward struct {
+	emitter   Emitter
+	eventType eventemitter.EventType
+	types     []string
+}
+
+// watchedSnapshot is a snapshot seen by the last listing
+type watchedSnapshot struct {
+	name     string
+	creation time.Time
+}
+
+// SnapshotWatcher detects snapshots created, destroyed or renamed by others, like cron jobs or other hosts receiving
+// into the pool, by periodically listing the snapshots of the datasets and comparing their GUIDs
+type SnapshotWatcher struct {
+	config   SnapshotConfig
+	logger   *slog.Logger
+	events   chan SnapshotEvent
+	forwards []snapshotForward
+	known    map[string]map[uint64]watchedSnapshot // Snapshots by GUID, indexed by watched dataset
+}
+
+// NewSnapshotWatcher creates a new snapshot watcher
+func NewSnapshotWatcher(conf SnapshotConfig, logger *slog.Logger) *SnapshotWatcher {
+	return &SnapshotWatcher{
+		config: conf,
+		logger: logger,
+		known:  make(map[string]map[uint64]watchedSnapshot, len(conf.Datasets)),
+	}
+}
+
+// Events returns the channel the events are sent on, which is closed when Run returns.
+// It must be called before Run, otherwise the events are only forwarded.
+func (w *SnapshotWatcher) Events() <-chan SnapshotEvent {
+	if w.events == nil {
+		w.events = make(chan SnapshotEvent, w.config.BufferSize)
+	}
+	return w.events
+}
+
+// Forward emits the events of the types, or of all types when none are given, on the emitter as the event type,
+// with the SnapshotEvent as argument. It must be called before Run.
+func (w *SnapshotWatcher) Forward(emitter Emitter, eventType eventemitter.EventType, types ...string) {
+	w.forwards = append(w.forwards, snapshotForward{
+		emitter:   emitter,
+		eventType: eventType,
+		types:     types,
+	})
+}
+
+// Run lists the snapshots every interval until the context is cancelled. Changes are only seen once a listing is
+// not served from the lookup cache set with zfs.SetCacheTTL anymore.
+func (w *SnapshotWatcher) Run(ctx context.Context) error {
+	if w.events != nil {
+		defer close(w.events)
+	}
+
+	ticker := time.NewTicker(time.Duration(w.config.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		err := w.poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			w.logger.Error("zfs.events.SnapshotWatcher.Run: Error listing snapshots", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll lists the snapshots of every dataset and delivers the differences with the last listing
+func (w *SnapshotWatcher) poll(ctx context.Context) error {
+	var errs []error
+	for _, dataset := range w.config.Datasets {
+		err := w.pollDataset(ctx, dataset)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *SnapshotWatcher) pollDataset(ctx context.Context, dataset string) error {
+	options := zfs.ListOptions{
+		ParentDataset: dataset,
+		Fields:        []string{zfs.PropertyName, zfs.PropertyGUID, zfs.PropertyCreation},
+	}
+	if !w.config.Recursive {
+		options.Depth = 1
+	}
+	snaps, err := zfs.ListSnapshots(ctx, options)
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		// Keep the snapshots, so a dataset that is recreated by a receive does not report all of them as new
+		w.logger.Warn("zfs.events.SnapshotWatcher.pollDataset: Dataset not found", "dataset", dataset)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	previous, seen := w.known[dataset]
+	current := make(map[uint64]watchedSnapshot, len(snaps))
+	for _, snap := range snaps {
+		current[snap.GUID] = watchedSnapshot{name: snap.Name, creation: snap.Creation}
+	}
+	w.known[dataset] = current
+	if !seen && w.config.IgnoreExisting {
+		return nil
+	}
+
+	var changes []SnapshotEvent
+	for _, snap := range snaps {
+		prev, ok := previous[snap.GUID]
+		switch {
+		case !ok:
+			changes = append(changes, snapshotEvent(SnapshotCreated, snap.Name, snap.GUID, snap.Creation))
+		case prev.name != snap.Name:
+			event := snapshotEvent(SnapshotRenamed, snap.Name, snap.GUID, snap.Creation)
+			event.PreviousName = prev.name
+			changes = append(changes, event)
+		}
+	}
+	for guid, prev := range previous {
+		if _, ok := current[guid]; !ok {
+			changes = append(changes, snapshotEvent(SnapshotDestroyed, prev.name, guid, prev.creation))
+		}
+	}
+	slices.SortFunc(changes, func(a, b SnapshotEvent) int {
+		return cmp.Or(a.Creation.Compare(b.Creation), strings.Compare(a.Snapshot, b.Snapshot))
+	})
+
+	for _, event := range changes {
+		err = w.deliver(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func snapshotEvent(typ, snapshot string, guid uint64, creation time.Time) SnapshotEvent {
+	dataset, _, _ := strings.Cut(snapshot, "@")
+	return SnapshotEvent{
+		Type:     typ,
+		Dataset:  dataset,
+		Snapshot: snapshot,
+		GUID:     guid,
+		Creation: creation,
+	}
+}
+
+func (w *SnapshotWatcher) deliver(ctx context.Context, event SnapshotEvent) error {
+	w.logger.Debug("zfs.events.SnapshotWatcher.deliver: Event", "type", event.Type, "snapshot", event.Snapshot)
+
+	for _, fwd := range w.forwards {
+		if len(fwd.types) == 0 || slices.Contains(fwd.types, event.Type) {
+			fwd.emitter.EmitEvent(fwd.eventType, event)
+		}
+	}
+	if w.events == nil {
+		return nil
+	}
+	select {
+	case w.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
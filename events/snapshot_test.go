@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	eventemitter "github.com/vansante/go-event-emitter"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func testSnapshotWatcher(t *testing.T, conf SnapshotConfig) (*SnapshotWatcher, *zfs.Dataset) {
+	zfsfake.Install(t, "pool")
+	fs, err := zfs.CreateFilesystem(context.Background(), "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(context.Background(), "snap1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	conf.IntervalSeconds = defaultSnapshotIntervalSeconds
+	conf.BufferSize = defaultBufferSize
+	conf.Datasets = []string{fs.Name}
+	return NewSnapshotWatcher(conf, slog.New(slog.NewTextHandler(io.Discard, nil))), fs
+}
+
+func pollEvents(t *testing.T, w *SnapshotWatcher, events <-chan SnapshotEvent) []SnapshotEvent {
+	require.NoError(t, w.poll(context.Background()))
+	var list []SnapshotEvent
+	for len(events) > 0 {
+		list = append(list, <-events)
+	}
+	return list
+}
+
+func TestSnapshotWatcher_poll(t *testing.T) {
+	w, fs := testSnapshotWatcher(t, SnapshotConfig{})
+	events := w.Events()
+	ctx := context.Background()
+
+	list := pollEvents(t, w, events)
+	require.Len(t, list, 1)
+	require.Equal(t, SnapshotCreated, list[0].Type)
+	require.Equal(t, "pool/fs", list[0].Dataset)
+	require.Equal(t, "pool/fs@snap1", list[0].Snapshot)
+	require.NotZero(t, list[0].GUID)
+	require.Empty(t, pollEvents(t, w, events))
+
+	snap2, err := fs.Snapshot(ctx, "snap2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	snap1, err := zfs.GetDataset(ctx, "pool/fs@snap1")
+	require.NoError(t, err)
+	require.NoError(t, snap1.Rename(ctx, "pool/fs@renamed", zfs.RenameOptions{}))
+	list = pollEvents(t, w, events)
+	require.Len(t, list, 2)
+	require.Equal(t, SnapshotRenamed, list[0].Type)
+	require.Equal(t, "pool/fs@renamed", list[0].Snapshot)
+	require.Equal(t, "pool/fs@snap1", list[0].PreviousName)
+	require.Equal(t, SnapshotCreated, list[1].Type)
+	require.Equal(t, snap2.GUID, list[1].GUID)
+
+	require.NoError(t, snap2.Destroy(ctx, zfs.DestroyOptions{}))
+	list = pollEvents(t, w, events)
+	require.Len(t, list, 1)
+	require.Equal(t, SnapshotDestroyed, list[0].Type)
+	require.Equal(t, "pool/fs@snap2", list[0].Snapshot)
+}
+
+func TestSnapshotWatcher_IgnoreExisting(t *testing.T) {
+	w, fs := testSnapshotWatcher(t, SnapshotConfig{IgnoreExisting: true})
+	events := w.Events()
+	require.Empty(t, pollEvents(t, w, events))
+
+	_, err := fs.Snapshot(context.Background(), "snap2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	list := pollEvents(t, w, events)
+	require.Len(t, list, 1)
+	require.Equal(t, "pool/fs@snap2", list[0].Snapshot)
+}
+
+func TestSnapshotWatcher_Forward(t *testing.T) {
+	w, _ := testSnapshotWatcher(t, SnapshotConfig{})
+	emitter := eventemitter.NewEmitter(false)
+	forwarded := make(chan SnapshotEvent, 1)
+	emitter.AddListener(SnapshotCreatedEvent, func(arguments ...any) {
+		forwarded <- arguments[0].(SnapshotEvent)
+	})
+	w.Forward(emitter, SnapshotCreatedEvent, SnapshotCreated)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	event := <-forwarded
+	require.Equal(t, "pool/fs@snap1", event.Snapshot)
+}
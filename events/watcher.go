@@ -0,0 +1,165 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	eventemitter "github.com/vansante/go-event-emitter"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const (
+	defaultBufferSize          = 64
+	defaultRestartDelaySeconds = 5
+)
+
+// Event types for forwarding events to an emitter, the event is the only argument
+const (
+	ScrubFinishedEvent    eventemitter.EventType = "zfs-scrub-finished"
+	ResilverFinishedEvent eventemitter.EventType = "zfs-resilver-finished"
+	PoolErrorEvent        eventemitter.EventType = "zfs-pool-error"
+)
+
+// Config configures the watcher
+type Config struct {
+	// Pool limits the events to those of this pool, empty for all pools
+	Pool string `json:"Pool" yaml:"Pool"`
+	// Classes limits the events sent on the channel to these classes, a class ending with a dot matches all
+	// classes starting with it. Empty sends all events.
+	Classes []string `json:"Classes" yaml:"Classes"`
+	// IgnoreExisting skips the events that happened before the watcher started
+	IgnoreExisting bool `json:"IgnoreExisting" yaml:"IgnoreExisting"`
+	// BufferSize is the amount of events buffered in the channel
+	BufferSize int `json:"BufferSize" yaml:"BufferSize"`
+	// RestartDelaySeconds is the delay before running zpool events again when it stops
+	RestartDelaySeconds int64 `json:"RestartDelaySeconds" yaml:"RestartDelaySeconds"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.BufferSize = defaultBufferSize
+	c.RestartDelaySeconds = defaultRestartDelaySeconds
+}
+
+// Emitter is an event emitter events can be forwarded to, like the job runner
+type Emitter interface {
+	EmitEvent(event eventemitter.EventType, arguments ...any)
+}
+
+type forward struct {
+	emitter   Emitter
+	eventType eventemitter.EventType
+	classes   []string
+}
+
+// Watcher follows the output of zpool events, restarting it when it stops
+type Watcher struct {
+	config   Config
+	logger   *slog.Logger
+	events   chan Event
+	forwards []forward
+	lastEID  uint64
+}
+
+// NewWatcher creates a new watcher
+func NewWatcher(conf Config, logger *slog.Logger) *Watcher {
+	return &Watcher{
+		config: conf,
+		logger: logger,
+	}
+}
+
+// Events returns the channel the events are sent on, which is closed when Run returns.
+// It must be called before Run, otherwise the events are only forwarded.
+func (w *Watcher) Events() <-chan Event {
+	if w.events == nil {
+		w.events = make(chan Event, w.config.BufferSize)
+	}
+	return w.events
+}
+
+// Forward emits the events of the classes on the emitter as the event type, with the Event as argument.
+// A class ending with a dot matches all classes starting with it. It must be called before Run.
+func (w *Watcher) Forward(emitter Emitter, eventType eventemitter.EventType, classes ...string) {
+	w.forwards = append(w.forwards, forward{
+		emitter:   emitter,
+		eventType: eventType,
+		classes:   classes,
+	})
+}
+
+// Run follows the events until the context is cancelled. When zpool events is restarted it replays the earlier
+// events, these are skipped by their event ID.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.events != nil {
+		defer close(w.events)
+	}
+
+	if w.config.IgnoreExisting {
+		err := zfs.StreamPoolEvents(ctx, zfs.PoolEventsOptions{Pool: w.config.Pool}, w.parser(func(event Event) error {
+			w.lastEID = max(w.lastEID, event.EID)
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		p := w.parser(func(event Event) error {
+			return w.deliver(ctx, event)
+		})
+		err := zfs.StreamPoolEvents(ctx, zfs.PoolEventsOptions{Pool: w.config.Pool, Follow: true}, p)
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.logger.Warn("zfs.events.Watcher.Run: zpool events stopped, restarting", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(w.config.RestartDelaySeconds) * time.Second):
+		}
+	}
+}
+
+// parser returns a line function assembling the events
+func (w *Watcher) parser(emit func(Event) error) func(string) error {
+	p := &parser{emit: emit}
+	return p.line
+}
+
+func (w *Watcher) deliver(ctx context.Context, event Event) error {
+	if event.EID != 0 && event.EID <= w.lastEID {
+		return nil // Replayed
+	}
+	w.lastEID = max(w.lastEID, event.EID)
+	w.logger.Debug("zfs.events.Watcher.deliver: Event", "class", event.Class, "pool", event.Pool, "eid", event.EID)
+
+	for _, fwd := range w.forwards {
+		if matchClass(fwd.classes, event.Class) {
+			fwd.emitter.EmitEvent(fwd.eventType, event)
+		}
+	}
+	if w.events == nil || (len(w.config.Classes) > 0 && !matchClass(w.config.Classes, event.Class)) {
+		return nil
+	}
+	select {
+	case w.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func matchClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class || (strings.HasSuffix(c, ".") && strings.HasPrefix(class, c)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	eventemitter "github.com/vansante/go-event-emitter"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+type executorFunc func(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error)
+
+func (fn executorFunc) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	return fn(ctx, cmd, args, stdin, stdout)
+}
+
+func testWatcher(t *testing.T, conf Config, existing, follow string) *Watcher {
+	zfs.SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, zfs.PoolBinary, cmd)
+		output := existing
+		if slices.Contains(args, "-f") {
+			output = follow
+		}
+		_, err := io.WriteString(stdout, output)
+		return "", err
+	}))
+	t.Cleanup(func() {
+		zfs.SetExecutor(nil)
+	})
+
+	conf.BufferSize = defaultBufferSize
+	return NewWatcher(conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func runWatcher(t *testing.T, w *Watcher) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		require.NoError(t, <-done)
+	}
+}
+
+func receive(t *testing.T, events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+		return Event{}
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	w := testWatcher(t, Config{Pool: "tank", Classes: []string{ClassErrorPrefix}}, "", testEvents)
+	events := w.Events()
+	stop := runWatcher(t, w)
+
+	// Restarts replay the scrub event, which is filtered, and the checksum event, which is skipped by its ID
+	event := receive(t, events)
+	require.Equal(t, ClassChecksumError, event.Class)
+	select {
+	case event = <-events:
+		t.Fatalf("unexpected event %s", event.Class)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stop()
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestWatcher_IgnoreExisting(t *testing.T) {
+	existing, _, _ := strings.Cut(testEvents, "\n\n")
+	w := testWatcher(t, Config{IgnoreExisting: true}, existing+"\n\n", testEvents)
+	events := w.Events()
+	stop := runWatcher(t, w)
+	defer stop()
+
+	event := receive(t, events)
+	require.EqualValues(t, 43, event.EID)
+}
+
+func TestWatcher_Forward(t *testing.T) {
+	w := testWatcher(t, Config{}, "", testEvents)
+	emitter := eventemitter.NewEmitter(false)
+	forwarded := make(chan Event, 2)
+	emitter.AddListener(ScrubFinishedEvent, func(arguments ...any) {
+		forwarded <- arguments[0].(Event)
+	})
+	w.Forward(emitter, ScrubFinishedEvent, ClassScrubFinish)
+	stop := runWatcher(t, w)
+	defer stop()
+
+	event := receive(t, forwarded)
+	require.Equal(t, ClassScrubFinish, event.Class)
+	require.Equal(t, "tank", event.Pool)
+}
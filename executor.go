@@ -0,0 +1,172 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Executor executes zfs commands in place of the zfs binary, for instance to run them through sudo or on another host
+// over SSH with an ExecExecutor, or to fake ZFS in unit tests
+type Executor interface {
+	// Execute runs the command with the given arguments, reading any input from stdin and writing its output to stdout.
+	// When the command fails, it returns an error along with the output the command would have written to stderr.
+	// That output determines the error returned to the caller, like ErrDatasetNotFound.
+	Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (stderr string, err error)
+}
+
+type executorHolder struct {
+	executor Executor
+}
+
+var commandExecutor atomic.Pointer[executorHolder]
+
+// SetExecutor makes all commands run through the given executor instead of the zfs binary, nil restores the default.
+func SetExecutor(executor Executor) {
+	if executor == nil {
+		commandExecutor.Store(nil)
+		return
+	}
+	commandExecutor.Store(&executorHolder{executor: executor})
+}
+
+type executorContextKey struct{}
+
+// WithExecutor returns a context that makes the commands run with it use the given executor, instead of the one set
+// with SetExecutor or the zfs binary. This is useful to run some commands on another host, like the send of a pull
+// replication. Lookups of these commands are not cached, see SetCacheTTL.
+func WithExecutor(ctx context.Context, executor Executor) context.Context {
+	return context.WithValue(ctx, executorContextKey{}, executor)
+}
+
+// loadExecutor returns the executor of the context, or the one set with SetExecutor
+func loadExecutor(ctx context.Context) Executor {
+	if executor, ok := ctx.Value(executorContextKey{}).(Executor); ok && executor != nil {
+		return executor
+	}
+	holder := commandExecutor.Load()
+	if holder == nil {
+		return nil
+	}
+	return holder.executor
+}
+
+// ExecExecutor runs the commands as processes, optionally through another command like sudo or ssh.
+// The processes are started with the CommandConfig of the context.
+type ExecExecutor struct {
+	// Prefix is the command and arguments the command is run through, like sudo -n, empty to run it directly
+	Prefix []string
+	// Quote passes the command with its arguments to the prefix as a single shell quoted argument, for prefixes that
+	// run it through a shell, like ssh does
+	Quote bool
+}
+
+// NewSudoExecutor returns an executor running the commands through sudo, which must not ask for a password
+func NewSudoExecutor() *ExecExecutor {
+	return &ExecExecutor{Prefix: []string{"sudo", "-n"}}
+}
+
+// NewSSHExecutor returns an executor running the commands on the destination over SSH, with the extra options for
+// ssh, like -i identity_file. Use BatchMode so ssh fails instead of asking for a password.
+func NewSSHExecutor(destination string, options ...string) *ExecExecutor {
+	prefix := append([]string{"ssh"}, options...)
+	return &ExecExecutor{
+		Prefix: append(prefix, "--", destination),
+		Quote:  true,
+	}
+}
+
+// Execute runs the command, see Executor
+func (e *ExecExecutor) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	config := loadCommandConfig(ctx)
+	command := append([]string{config.path(cmd)}, args...)
+	if e.Quote {
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = shellQuote(arg)
+		}
+		command = []string{strings.Join(quoted, " ")}
+	}
+	command = append(slices.Clip(e.Prefix), command...)
+
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	config.apply(c)
+	c.SysProcAttr = procAttributes()
+	waited := setTermination(c)
+
+	var stderr bytes.Buffer
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = &stderr
+	err := c.Run()
+	waited()
+	return stderr.String(), err
+}
+
+// shellQuote quotes the argument for a POSIX shell
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// execute runs the command using the executor, with the same limits, caching and output handling as other commands.
+// Output lines are parsed while the executor writes them, so the complete output never has to be held in memory.
+func (c *command) execute(executor Executor, fn lineFunc, arg ...string) error {
+	release, err := acquireCommandSlot(c.ctx, arg)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer invalidateCache(arg)
+
+	if fn == nil {
+		stdout := c.stdout
+		if stdout == nil {
+			stdout = io.Discard
+		}
+		stderr, err := executor.Execute(c.ctx, c.cmd, arg, c.stdin, stdout)
+		return c.executeError(stderr, err, arg)
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	scanned := make(chan error, 1)
+	go func() {
+		err := scanLines(pr, c.fields, fn)
+		if err != nil {
+			// We are no longer interested in the output, so stop the command and fail its writes
+			cancel()
+			_ = pr.CloseWithError(err)
+		}
+		scanned <- err
+	}()
+
+	stderr, err := executor.Execute(ctx, c.cmd, arg, c.stdin, pw)
+	_ = pw.Close()
+	outputErr := <-scanned
+	if outputErr != nil && c.ctx.Err() != nil {
+		return fmt.Errorf("%w (%w): %w", ErrCommandCancelled, c.ctx.Err(), outputErr)
+	}
+	if outputErr != nil {
+		return outputErr
+	}
+	return c.executeError(stderr, err, arg)
+}
+
+// executeError returns the error for a command the executor failed to run
+func (c *command) executeError(stderr string, err error, arg []string) error {
+	if err != nil && c.ctx.Err() != nil {
+		return fmt.Errorf("%w (%w): %w", ErrCommandCancelled, c.ctx.Err(), err)
+	}
+	if err != nil {
+		cmd := &exec.Cmd{Path: c.cmd, Args: append([]string{c.cmd}, arg...)}
+		return createError(cmd, stderr, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type executorFunc func(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error)
+
+func (fn executorFunc) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	return fn(ctx, cmd, args, stdin, stdout)
+}
+
+func Test_SetExecutor(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, Binary, cmd)
+		executed = append(executed, args)
+		if args[0] == "destroy" {
+			return "cannot open 'pool/fs': dataset does not exist", errors.New("exit status 1")
+		}
+		_, err := io.WriteString(stdout, "pool/fs\tvalue with\ttab\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	props, err := ListWithProperty(context.Background(), "nl.test:prop", ListWithPropertyOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"pool/fs": "value with\ttab"}, props)
+
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	err = ds.Destroy(context.Background(), DestroyOptions{})
+	require.ErrorIs(t, err, ErrDatasetNotFound)
+	require.Len(t, executed, 2)
+	require.Equal(t, []string{"destroy", "pool/fs"}, executed[1])
+
+	SetExecutor(nil)
+	require.Nil(t, loadExecutor(context.Background()))
+}
+
+func Test_WithExecutor(t *testing.T) {
+	SetExecutor(executorFunc(func(context.Context, string, []string, io.Reader, io.Writer) (string, error) {
+		return "global executor used", errors.New("exit status 1")
+	}))
+	defer SetExecutor(nil)
+
+	var executed []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = args
+		return "", nil
+	}))
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, ds.SetProperty(ctx, "nl.test:prop", "value"))
+	require.Equal(t, []string{"set", "nl.test:prop=value", "pool/fs"}, executed)
+
+	require.Error(t, ds.SetProperty(context.Background(), "nl.test:prop", "value"))
+}
+
+func Test_ExecutorStreaming(t *testing.T) {
+	// The executor only finishes once the first line was handled, which hangs when the output is buffered
+	handled := make(chan struct{})
+	ctx := WithExecutor(context.Background(), executorFunc(func(ctx context.Context, _ string, _ []string, _ io.Reader, stdout io.Writer) (string, error) {
+		_, _ = io.WriteString(stdout, "first\n")
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			return "output not streamed", errors.New("timeout")
+		}
+		_, err := io.WriteString(stdout, "second\n")
+		return "", err
+	}))
+
+	var lines []string
+	c := command{ctx: ctx, cmd: Binary}
+	err := c.Stream(func(fields []string) error {
+		if len(lines) == 0 {
+			close(handled)
+		}
+		lines = append(lines, fields[0])
+		return nil
+	}, "list")
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, lines)
+
+	// Stopping the stream stops the executor as well
+	stop := errors.New("stop")
+	ctx = WithExecutor(context.Background(), executorFunc(func(ctx context.Context, _ string, _ []string, _ io.Reader, stdout io.Writer) (string, error) {
+		for {
+			_, err := io.WriteString(stdout, "line\n")
+			if err != nil {
+				return "", err
+			}
+		}
+	}))
+	c = command{ctx: ctx, cmd: Binary}
+	err = c.Stream(func([]string) error {
+		return stop
+	}, "list")
+	require.ErrorIs(t, err, stop)
+}
+
+func Test_ExecExecutor(t *testing.T) {
+	ctx := context.Background()
+	var stdout strings.Builder
+	executor := &ExecExecutor{Prefix: []string{"env"}}
+	stderr, err := executor.Execute(ctx, "cat", nil, strings.NewReader("input"), &stdout)
+	require.NoError(t, err)
+	require.Empty(t, stderr)
+	require.Equal(t, "input", stdout.String())
+
+	stdout.Reset()
+	executor = &ExecExecutor{Prefix: []string{"sh", "-c"}, Quote: true}
+	_, err = executor.Execute(ctx, "echo", []string{"a  b", "it's", "$HOME"}, nil, &stdout)
+	require.NoError(t, err)
+	require.Equal(t, "a  b it's $HOME\n", stdout.String())
+
+	stderr, err = executor.Execute(ctx, "sh", []string{"-c", "echo failed >&2; exit 1"}, nil, io.Discard)
+	require.Error(t, err)
+	require.Equal(t, "failed\n", stderr)
+
+	require.Equal(t, []string{"ssh", "-o", "BatchMode=yes", "--", "backup@host"},
+		NewSSHExecutor("backup@host", "-o", "BatchMode=yes").Prefix)
+	require.Equal(t, []string{"sudo", "-n"}, NewSudoExecutor().Prefix)
+}
+
+func Test_ExecExecutorStreaming(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shim is a shell script")
+	}
+	// The process only exits once the first line was handled, and fails when that takes too long
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "handled")
+	shim := filepath.Join(dir, "zfs-shim")
+	script := "#!/bin/sh\necho first\ni=0\nwhile [ ! -e " + shellQuote(marker) + " ]; do\n" +
+		"  i=$((i+1)); [ $i -gt 500 ] && { echo 'output not streamed' >&2; exit 1; }\n  sleep 0.01\ndone\necho second\n"
+	require.NoError(t, os.WriteFile(shim, []byte(script), 0o755))
+
+	ctx := WithCommandConfig(context.Background(), CommandConfig{ZFSPath: shim})
+	ctx = WithExecutor(ctx, &ExecExecutor{Prefix: []string{"sh", "-c"}, Quote: true})
+
+	var lines []string
+	c := command{ctx: ctx, cmd: Binary}
+	err := c.Stream(func(fields []string) error {
+		if len(lines) == 0 {
+			require.NoError(t, os.WriteFile(marker, nil, 0o600))
+		}
+		lines = append(lines, fields[0])
+		return nil
+	}, "list")
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, lines)
+}
@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const propertyCompressRatio = "compressratio"
+
+// DatasetCollector collects the usage, quotas and compression ratio of filesystems and volumes
+type DatasetCollector struct {
+	// ParentDataset limits the datasets to this dataset and its children, empty for all datasets
+	ParentDataset string
+}
+
+// Collect lists the datasets and adds their metrics
+func (c *DatasetCollector) Collect(ctx context.Context, m *Metrics) error {
+	datasets, err := zfs.ListDatasets(ctx, zfs.ListOptions{
+		ParentDataset:   c.ParentDataset,
+		DatasetType:     zfs.DatasetFilesystem + "," + zfs.DatasetVolume,
+		Recursive:       true,
+		ExtraProperties: []string{propertyCompressRatio},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ds := range datasets {
+		labels := []Label{{"dataset", ds.Name}, {"type", string(ds.Type)}}
+		m.Gauge("dataset_used_bytes", "Space used by the dataset and its children", float64(ds.Used), labels...)
+		m.Gauge("dataset_available_bytes", "Space available to the dataset", float64(ds.Available), labels...)
+		m.Gauge("dataset_referenced_bytes", "Space referenced by the dataset", float64(ds.Referenced), labels...)
+		m.Gauge("dataset_logical_used_bytes", "Space used by the dataset before compression", float64(ds.Logicalused), labels...)
+		m.Gauge("dataset_quota_bytes", "Quota of the dataset and its children, zero when not set", float64(ds.Quota), labels...)
+		m.Gauge("dataset_refquota_bytes", "Quota of the dataset itself, zero when not set", float64(ds.Refquota), labels...)
+		if ds.Type == zfs.DatasetVolume {
+			m.Gauge("dataset_volume_size_bytes", "Size of the volume", float64(ds.Volsize), labels...)
+		}
+		ratio, err := strconv.ParseFloat(strings.TrimSuffix(ds.ExtraProps[propertyCompressRatio], "x"), 64)
+		if err == nil {
+			m.Gauge("dataset_compression_ratio", "Compression ratio of the data of the dataset", ratio, labels...)
+		}
+	}
+	return nil
+}
+
+// PoolCollector collects the capacity, health and errors of the pools
+type PoolCollector struct{}
+
+// Collect lists the pools, retrieves their errors and adds their metrics
+func (c *PoolCollector) Collect(ctx context.Context, m *Metrics) error {
+	pools, err := zfs.ListPools(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		label := Label{"pool", pool.Name}
+		m.Gauge("pool_size_bytes", "Size of the pool", float64(pool.Size), label)
+		m.Gauge("pool_allocated_bytes", "Allocated space of the pool", float64(pool.Allocated), label)
+		m.Gauge("pool_free_bytes", "Free space of the pool", float64(pool.Free), label)
+		m.Gauge("pool_capacity_ratio", "Allocated part of the pool", float64(pool.Capacity)/100, label)
+		m.Gauge("pool_fragmentation_ratio", "Fragmentation of the free space of the pool", float64(pool.Fragmentation)/100, label)
+		m.Gauge("pool_dedup_ratio", "Deduplication ratio of the pool", pool.DedupRatio, label)
+		for _, state := range zfs.PoolHealthStates {
+			value := 0.0
+			if pool.Health == state {
+				value = 1
+			}
+			m.Gauge("pool_health", "Health of the pool, 1 for its current state", value, label, Label{"state", strings.ToLower(state)})
+		}
+
+		errs, err := zfs.GetPoolErrors(ctx, pool.Name)
+		if err != nil {
+			return err
+		}
+		m.Gauge("pool_device_errors", "Errors of the devices of the pool since they were last cleared",
+			float64(errs.Read), label, Label{"type", "read"})
+		m.Gauge("pool_device_errors", "", float64(errs.Write), label, Label{"type", "write"})
+		m.Gauge("pool_device_errors", "", float64(errs.Checksum), label, Label{"type", "checksum"})
+		m.Gauge("pool_data_errors", "Files or metadata of the pool with permanent errors", float64(errs.Data), label)
+	}
+	return nil
+}
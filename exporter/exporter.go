@@ -0,0 +1,139 @@
+// Package exporter exposes metrics of the datasets and pools in the Prometheus text format. The collectors run
+// periodically instead of on every scrape, so scrapes are cheap and never pile up zfs commands.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNamespace       = "zfs"
+	defaultIntervalSeconds = 60
+	contentType            = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Config configures the exporter
+type Config struct {
+	// Namespace prefixes the names of all metrics
+	Namespace string `json:"Namespace" yaml:"Namespace"`
+	// IntervalSeconds is the interval between collections
+	IntervalSeconds int64 `json:"IntervalSeconds" yaml:"IntervalSeconds"`
+	// ParentDataset limits the dataset metrics to this dataset and its children, empty for all datasets
+	ParentDataset string `json:"ParentDataset" yaml:"ParentDataset"`
+	// DisableDatasets disables the dataset metrics
+	DisableDatasets bool `json:"DisableDatasets" yaml:"DisableDatasets"`
+	// DisablePools disables the pool metrics
+	DisablePools bool `json:"DisablePools" yaml:"DisablePools"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.Namespace = defaultNamespace
+	c.IntervalSeconds = defaultIntervalSeconds
+}
+
+// Collector collects a set of metrics
+type Collector interface {
+	Collect(ctx context.Context, m *Metrics) error
+}
+
+type namedCollector struct {
+	name      string
+	collector Collector
+}
+
+// Exporter runs its collectors periodically, and serves the metrics of the last collection over HTTP
+type Exporter struct {
+	config     Config
+	logger     *slog.Logger
+	collectors []namedCollector
+
+	mu      sync.RWMutex
+	metrics []byte
+}
+
+// NewExporter creates a new exporter with the dataset and pool collectors registered, unless they are disabled
+func NewExporter(conf Config, logger *slog.Logger) *Exporter {
+	e := &Exporter{
+		config: conf,
+		logger: logger,
+	}
+	if !conf.DisableDatasets {
+		e.Register("datasets", &DatasetCollector{ParentDataset: conf.ParentDataset})
+	}
+	if !conf.DisablePools {
+		e.Register("pools", &PoolCollector{})
+	}
+	return e
+}
+
+// Register adds a collector, it must be called before Run
+func (e *Exporter) Register(name string, collector Collector) {
+	e.collectors = append(e.collectors, namedCollector{name: name, collector: collector})
+}
+
+// Run collects the metrics every interval until the context is cancelled
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(e.config.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	e.logger.Info("zfs.exporter.Exporter.Run: Running", "interval", e.config.IntervalSeconds)
+	defer e.logger.Info("zfs.exporter.Exporter.Run: Stopped")
+
+	for {
+		e.Collect(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Collect runs all collectors once, and stores their metrics for serving. A collector that fails is reported
+// in the collector_success metric, the metrics of the others are still stored.
+func (e *Exporter) Collect(ctx context.Context) {
+	m := newMetrics(e.config.Namespace)
+	for _, c := range e.collectors {
+		start := time.Now()
+		err := c.collector.Collect(ctx, m)
+		label := Label{"collector", c.name}
+		m.Gauge("exporter_collector_duration_seconds", "Duration of the last collection", time.Since(start).Seconds(), label)
+
+		success := 1.0
+		if err != nil {
+			e.logger.Warn("zfs.exporter.Exporter.Collect: Error collecting metrics", "collector", c.name, "error", err)
+			success = 0
+		}
+		m.Gauge("exporter_collector_success", "Whether the last collection succeeded", success, label)
+	}
+	m.Gauge("exporter_last_collection_timestamp_seconds", "Time of the last collection", float64(time.Now().Unix()))
+
+	var buf bytes.Buffer
+	_, _ = m.WriteTo(&buf)
+	e.mu.Lock()
+	e.metrics = buf.Bytes()
+	e.mu.Unlock()
+}
+
+// ServeHTTP serves the metrics of the last collection
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	e.mu.RLock()
+	metrics := e.metrics
+	e.mu.RUnlock()
+
+	if metrics == nil {
+		http.Error(w, "metrics not collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, err := w.Write(metrics)
+	if err != nil {
+		e.logger.Debug("zfs.exporter.Exporter.ServeHTTP: Error writing metrics", "error", err)
+	}
+}
@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+const testPoolStatus = `  pool: pool
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	pool        ONLINE       0     0     0
+	  sda       ONLINE       0     0     5
+
+errors: No known data errors
+`
+
+// poolExecutor runs the zpool commands itself, and the zfs commands using the fake
+type poolExecutor struct {
+	fake *zfsfake.Fake
+}
+
+func (e poolExecutor) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	if cmd != zfs.PoolBinary {
+		return e.fake.Execute(ctx, cmd, args, stdin, stdout)
+	}
+	output := "pool\tONLINE\t1000\t250\t750\t3\t25\t1.00\n"
+	if args[0] == "status" {
+		output = testPoolStatus
+	}
+	_, err := io.WriteString(stdout, output)
+	return "", err
+}
+
+type failingCollector struct{}
+
+func (failingCollector) Collect(context.Context, *Metrics) error {
+	return errors.New("broken")
+}
+
+func TestExporter(t *testing.T) {
+	fake := zfsfake.Install(t, "pool")
+	zfs.SetExecutor(poolExecutor{fake: fake})
+
+	ctx := context.Background()
+	_, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = zfs.CreateVolume(ctx, "pool/vol", 1024*1024, zfs.CreateVolumeOptions{})
+	require.NoError(t, err)
+
+	conf := Config{}
+	conf.ApplyDefaults()
+	e := NewExporter(conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.Register("broken", failingCollector{})
+
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	e.Collect(ctx)
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, contentType, resp.Header().Get("Content-Type"))
+
+	body := resp.Body.String()
+	for _, line := range []string{
+		"# TYPE zfs_dataset_used_bytes gauge",
+		`zfs_dataset_referenced_bytes{dataset="pool/fs",type="filesystem"} 24576`,
+		`zfs_dataset_volume_size_bytes{dataset="pool/vol",type="volume"} 1.048576e+06`,
+		`zfs_dataset_compression_ratio{dataset="pool/fs",type="filesystem"} 1`,
+		`zfs_pool_size_bytes{pool="pool"} 1000`,
+		`zfs_pool_capacity_ratio{pool="pool"} 0.25`,
+		`zfs_pool_health{pool="pool",state="online"} 1`,
+		`zfs_pool_health{pool="pool",state="degraded"} 0`,
+		`zfs_pool_device_errors{pool="pool",type="checksum"} 5`,
+		`zfs_pool_data_errors{pool="pool"} 0`,
+		`zfs_exporter_collector_success{collector="datasets"} 1`,
+		`zfs_exporter_collector_success{collector="pools"} 1`,
+		`zfs_exporter_collector_success{collector="broken"} 0`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+	require.NotContains(t, body, `dataset="pool/fs@`)
+}
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := newMetrics("")
+	m.Gauge("test", "Help with \\ and\nnewline", 1.5, Label{"name", "quote \" and \\"})
+	m.Gauge("test", "", 2)
+
+	var b strings.Builder
+	_, err := m.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, "# HELP test Help with \\\\ and\\nnewline\n# TYPE test gauge\n"+
+		"test{name=\"quote \\\" and \\\\\"} 1.5\ntest 2\n", b.String())
+}
@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Label is a label of a metric sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+type sample struct {
+	labels []Label
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+// Metrics collects the gauges of one collection, in the Prometheus text exposition format
+type Metrics struct {
+	namespace string
+	families  map[string]*family
+	order     []string
+}
+
+func newMetrics(namespace string) *Metrics {
+	return &Metrics{
+		namespace: namespace,
+		families:  make(map[string]*family, 32),
+	}
+}
+
+// Gauge adds a sample of a gauge, the name is prefixed with the namespace.
+// The help text of the first sample of a gauge is used.
+func (m *Metrics) Gauge(name, help string, value float64, labels ...Label) {
+	if m.namespace != "" {
+		name = m.namespace + "_" + name
+	}
+	f, ok := m.families[name]
+	if !ok {
+		f = &family{name: name, help: help}
+		m.families[name] = f
+		m.order = append(m.order, name)
+	}
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, name := range m.order {
+		f := m.families[name]
+		b.WriteString("# HELP ")
+		b.WriteString(f.name)
+		b.WriteByte(' ')
+		b.WriteString(helpReplacer.Replace(f.help))
+		b.WriteString("\n# TYPE ")
+		b.WriteString(f.name)
+		b.WriteString(" gauge\n")
+
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i, label := range s.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(label.Name)
+					b.WriteString(`="`)
+					b.WriteString(labelReplacer.Replace(label.Value))
+					b.WriteByte('"')
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
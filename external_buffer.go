@@ -0,0 +1,145 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// External buffer programs
+const (
+	ExternalBufferMbuffer = "mbuffer"
+	ExternalBufferPV      = "pv"
+)
+
+// ErrUnknownExternalBuffer is returned for an external buffer with an unsupported program
+var ErrUnknownExternalBuffer = errors.New("unknown external buffer program")
+
+// ExternalBuffer configures an external program buffering the stream between zfs and the rest of the pipeline.
+// Unlike the in-memory buffer it runs in its own process, so it keeps zfs going when the reading side stalls
+// for a while, like on high-latency connections.
+type ExternalBuffer struct {
+	// Program is the buffer program, ExternalBufferMbuffer or ExternalBufferPV
+	Program string `json:"Program" yaml:"Program"`
+	// Path is the path of the program binary, when empty the program is looked up in the PATH
+	Path string `json:"Path" yaml:"Path"`
+	// SizeBytes is the size of the buffer, zero for the default of the program
+	SizeBytes int64 `json:"SizeBytes" yaml:"SizeBytes"`
+	// BytesPerSecond limits the rate of the stream, zero for no limit
+	BytesPerSecond int64 `json:"BytesPerSecond" yaml:"BytesPerSecond"`
+	// ExtraArgs are added to the arguments of the program
+	ExtraArgs []string `json:"ExtraArgs" yaml:"ExtraArgs"`
+}
+
+func (b *ExternalBuffer) command() (string, []string, error) {
+	path := b.Path
+	if path == "" {
+		path = b.Program
+	}
+
+	args := make([]string, 0, 5+len(b.ExtraArgs))
+	switch b.Program {
+	case ExternalBufferMbuffer:
+		args = append(args, "-q")
+		if b.SizeBytes > 0 {
+			args = append(args, "-m", strconv.FormatInt(max(b.SizeBytes/1024, 1), 10)+"k")
+		}
+		if b.BytesPerSecond > 0 {
+			args = append(args, "-r", strconv.FormatInt(max(b.BytesPerSecond/1024, 1), 10)+"k")
+		}
+	case ExternalBufferPV:
+		args = append(args, "-q")
+		if b.SizeBytes > 0 {
+			args = append(args, "-B", strconv.FormatInt(b.SizeBytes, 10))
+		}
+		if b.BytesPerSecond > 0 {
+			args = append(args, "-L", strconv.FormatInt(b.BytesPerSecond, 10))
+		}
+	default:
+		return "", nil, fmt.Errorf("%w: %q", ErrUnknownExternalBuffer, b.Program)
+	}
+	return path, append(args, b.ExtraArgs...), nil
+}
+
+// startExternalBuffer starts the buffer program copying stdin to stdout. The returned function waits for it to exit.
+func startExternalBuffer(ctx context.Context, buffer *ExternalBuffer, stdin io.Reader, stdout io.Writer) (wait func() error, err error) {
+	path, args, err := buffer.command()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.SysProcAttr = procAttributes()
+	waited := setTermination(cmd)
+	var stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, createError(cmd, stderr.String(), err)
+	}
+	return func() error {
+		err := cmd.Wait()
+		waited()
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("%w (%w): %w", ErrCommandCancelled, ctx.Err(), createError(cmd, stderr.String(), err))
+		}
+		if err != nil {
+			return createError(cmd, stderr.String(), err)
+		}
+		return nil
+	}, nil
+}
+
+// externalBufferWriter returns the writer zfs should write to, which is buffered by the external program before
+// reaching the output. The returned function must be called when zfs has exited.
+func externalBufferWriter(ctx context.Context, output io.Writer, buffer *ExternalBuffer) (io.Writer, func() error, error) {
+	if buffer == nil {
+		return output, func() error { return nil }, nil
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating external buffer pipe: %w", err)
+	}
+	wait, err := startExternalBuffer(ctx, buffer, pr, output)
+	_ = pr.Close() // The program has its own copy now
+	if err != nil {
+		_ = pw.Close()
+		return nil, nil, err
+	}
+	return pw, func() error {
+		_ = pw.Close() // Ends the input of the program
+		return wait()
+	}, nil
+}
+
+// externalBufferReader returns the reader zfs should read from, which is the input buffered by the external program.
+// The returned function must be called when zfs has exited.
+func externalBufferReader(ctx context.Context, input io.Reader, buffer *ExternalBuffer) (io.Reader, func() error, error) {
+	if buffer == nil {
+		return input, func() error { return nil }, nil
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating external buffer pipe: %w", err)
+	}
+	wait, err := startExternalBuffer(ctx, buffer, input, pw)
+	_ = pw.Close() // The program has its own copy now
+	if err != nil {
+		_ = pr.Close()
+		return nil, nil, err
+	}
+	return pr, func() error {
+		_ = pr.Close() // Stops the program when zfs did not read everything
+		return wait()
+	}, nil
+}
@@ -0,0 +1,98 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBufferProgram writes a script that records its arguments and copies its input, or fails when exitCode is set
+func testBufferProgram(t *testing.T, exitCode int) (path, argsFile string) {
+	dir := t.TempDir()
+	path = filepath.Join(dir, "buffer")
+	argsFile = filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\nexec cat\n"
+	if exitCode != 0 {
+		script = "#!/bin/sh\necho 'buffer broke' >&2\nexit 3\n"
+	}
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o700))
+	return path, argsFile
+}
+
+func Test_ExternalBuffer_command(t *testing.T) {
+	path, args, err := (&ExternalBuffer{Program: ExternalBufferMbuffer, SizeBytes: 1024 * 1024 * 1024, BytesPerSecond: 10 * 1024 * 1024}).command()
+	require.NoError(t, err)
+	require.Equal(t, "mbuffer", path)
+	require.Equal(t, []string{"-q", "-m", "1048576k", "-r", "10240k"}, args)
+
+	path, args, err = (&ExternalBuffer{Program: ExternalBufferPV, Path: "/opt/pv", SizeBytes: 4096, ExtraArgs: []string{"-C"}}).command()
+	require.NoError(t, err)
+	require.Equal(t, "/opt/pv", path)
+	require.Equal(t, []string{"-q", "-B", "4096", "-C"}, args)
+
+	_, _, err = (&ExternalBuffer{Program: "dd"}).command()
+	require.ErrorIs(t, err, ErrUnknownExternalBuffer)
+}
+
+func Test_SendSnapshot_ExternalBuffer(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, _ string, _ []string, _ io.Reader, stdout io.Writer) (string, error) {
+		_, err := io.WriteString(stdout, "stream data")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	path, argsFile := testBufferProgram(t, 0)
+	snap := &Dataset{Name: "pool/fs@snap", Type: DatasetSnapshot}
+	var output bytes.Buffer
+	_, err := snap.SendSnapshot(context.Background(), &output, SendOptions{
+		ExternalBuffer: &ExternalBuffer{Program: ExternalBufferPV, Path: path, SizeBytes: 1024},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "stream data", output.String())
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	require.Equal(t, "-q -B 1024\n", string(args))
+
+	path, _ = testBufferProgram(t, 3)
+	_, err = snap.SendSnapshot(context.Background(), io.Discard, SendOptions{
+		ExternalBuffer: &ExternalBuffer{Program: ExternalBufferPV, Path: path},
+	})
+	require.ErrorContains(t, err, "external buffer")
+	require.ErrorContains(t, err, "buffer broke")
+}
+
+func Test_ReceiveSnapshot_ExternalBuffer(t *testing.T) {
+	var received string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, stdin io.Reader, _ io.Writer) (string, error) {
+		if args[0] == "receive" && args[1] == "pool/exists@snap" {
+			return "cannot receive new filesystem stream: destination 'pool/exists' exists", errors.New("exit status 1")
+		}
+		data, err := io.ReadAll(stdin)
+		received = string(data)
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	path, _ := testBufferProgram(t, 0)
+	buffer := &ExternalBuffer{Program: ExternalBufferMbuffer, Path: path}
+	_, _, err := ReceiveSnapshot(context.Background(), strings.NewReader("stream data"), "pool/fs@snap", ReceiveOptions{
+		ExternalBuffer: buffer,
+		SkipRefetch:    true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "stream data", received)
+
+	// The error of zfs is returned, not that of the buffer program it stopped reading from
+	_, _, err = ReceiveSnapshot(context.Background(), strings.NewReader("stream data"), "pool/exists@snap", ReceiveOptions{
+		ExternalBuffer: buffer,
+		SkipRefetch:    true,
+	})
+	require.ErrorIs(t, err, ErrDatasetExists)
+}
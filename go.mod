@@ -7,6 +7,7 @@ require (
 	github.com/klauspost/compress v1.17.11
 	github.com/stretchr/testify v1.9.0
 	github.com/vansante/go-event-emitter v1.0.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -14,5 +15,4 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
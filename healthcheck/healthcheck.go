@@ -0,0 +1,249 @@
+// Package healthcheck verifies that zfs is available, the pools are healthy, the datasets are accessible and their
+// snapshots are recent. The results have the Nagios plugin status codes, so they can be used by monitoring systems
+// and container health probes directly.
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const (
+	defaultCapacityWarningPercent  = 80
+	defaultCapacityCriticalPercent = 90
+)
+
+// Status is the status of a check, its value is the Nagios plugin exit code
+type Status int
+
+// Check statuses, in increasing severity
+const (
+	StatusOK       Status = 0
+	StatusWarning  Status = 1
+	StatusCritical Status = 2
+	StatusUnknown  Status = 3
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "OK"
+	case StatusWarning:
+		return "WARNING"
+	case StatusCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// MarshalText encodes the status as its name
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// worse returns the most severe of the statuses, unknown is less severe than critical
+func worse(a, b Status) Status {
+	severity := func(s Status) int {
+		switch s {
+		case StatusUnknown:
+			return 2
+		case StatusCritical:
+			return 3
+		}
+		return int(s)
+	}
+	if severity(b) > severity(a) {
+		return b
+	}
+	return a
+}
+
+// Config configures the checks
+type Config struct {
+	// Pools are the pools that must be imported and healthy, empty checks all imported pools
+	Pools []string `json:"Pools" yaml:"Pools"`
+	// Datasets are the datasets that must be accessible
+	Datasets []string `json:"Datasets" yaml:"Datasets"`
+
+	// CapacityWarningPercent and CapacityCriticalPercent are the thresholds for the allocated space of the pools
+	CapacityWarningPercent  uint64 `json:"CapacityWarningPercent" yaml:"CapacityWarningPercent"`
+	CapacityCriticalPercent uint64 `json:"CapacityCriticalPercent" yaml:"CapacityCriticalPercent"`
+
+	// SnapshotAgeWarningMinutes and SnapshotAgeCriticalMinutes are the thresholds for the age of the newest snapshot
+	// of the datasets, like the replication lag on a backup server. Zero disables the check.
+	SnapshotAgeWarningMinutes  int64 `json:"SnapshotAgeWarningMinutes" yaml:"SnapshotAgeWarningMinutes"`
+	SnapshotAgeCriticalMinutes int64 `json:"SnapshotAgeCriticalMinutes" yaml:"SnapshotAgeCriticalMinutes"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.CapacityWarningPercent = defaultCapacityWarningPercent
+	c.CapacityCriticalPercent = defaultCapacityCriticalPercent
+}
+
+// CheckResult is the result of a single check
+type CheckResult struct {
+	Name    string `json:"Name"`
+	Status  Status `json:"Status"`
+	Message string `json:"Message"`
+}
+
+// Result is the result of all checks, its status is that of the most severe check
+type Result struct {
+	Status Status        `json:"Status"`
+	Checks []CheckResult `json:"Checks"`
+}
+
+func (r *Result) add(name string, status Status, format string, args ...any) {
+	r.Checks = append(r.Checks, CheckResult{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+	r.Status = worse(r.Status, status)
+}
+
+// ExitCode returns the Nagios plugin exit code for the result
+func (r *Result) ExitCode() int {
+	return int(r.Status)
+}
+
+// Summary returns a single line describing the result, listing the checks that are not OK
+func (r *Result) Summary() string {
+	var problems []string
+	for _, check := range r.Checks {
+		if check.Status != StatusOK {
+			problems = append(problems, fmt.Sprintf("%s: %s", check.Name, check.Message))
+		}
+	}
+	if len(problems) == 0 {
+		return fmt.Sprintf("ZFS %s - %d checks passed", r.Status, len(r.Checks))
+	}
+	return fmt.Sprintf("ZFS %s - %s", r.Status, strings.Join(problems, "; "))
+}
+
+// Checker runs the checks
+type Checker struct {
+	config Config
+	now    func() time.Time
+}
+
+// NewChecker creates a new checker
+func NewChecker(conf Config) *Checker {
+	return &Checker{
+		config: conf,
+		now:    time.Now,
+	}
+}
+
+// Check runs all checks. Failing checks are part of the result, so it never returns an error.
+func (c *Checker) Check(ctx context.Context) *Result {
+	result := &Result{Status: StatusOK}
+
+	pools, err := zfs.ListPools(ctx)
+	if err != nil {
+		result.add("zfs", StatusCritical, "zfs is not available: %s", err)
+		return result
+	}
+	result.add("zfs", StatusOK, "%d pools imported", len(pools))
+
+	c.checkPools(ctx, result, pools)
+	for _, dataset := range c.config.Datasets {
+		c.checkDataset(ctx, result, dataset)
+	}
+	return result
+}
+
+func (c *Checker) checkPools(ctx context.Context, result *Result, pools []zfs.Pool) {
+	for _, name := range c.config.Pools {
+		if !slices.ContainsFunc(pools, func(pool zfs.Pool) bool { return pool.Name == name }) {
+			result.add("pool "+name, StatusCritical, "pool is not imported")
+		}
+	}
+
+	for _, pool := range pools {
+		if len(c.config.Pools) > 0 && !slices.Contains(c.config.Pools, pool.Name) {
+			continue
+		}
+		name := "pool " + pool.Name
+		switch pool.Health {
+		case zfs.PoolOnline:
+		case zfs.PoolDegraded:
+			result.add(name, StatusWarning, "pool is %s", pool.Health)
+			continue
+		default:
+			result.add(name, StatusCritical, "pool is %s", pool.Health)
+			continue
+		}
+
+		errs, err := zfs.GetPoolErrors(ctx, pool.Name)
+		switch {
+		case err != nil:
+			result.add(name, StatusUnknown, "error retrieving errors: %s", err)
+		case errs.Data > 0:
+			result.add(name, StatusCritical, "pool has %d permanent data errors", errs.Data)
+		case errs.Read+errs.Write+errs.Checksum > 0:
+			result.add(name, StatusWarning, "devices have %d read, %d write and %d checksum errors",
+				errs.Read, errs.Write, errs.Checksum)
+		case c.config.CapacityCriticalPercent > 0 && pool.Capacity >= c.config.CapacityCriticalPercent:
+			result.add(name, StatusCritical, "pool is %d%% full", pool.Capacity)
+		case c.config.CapacityWarningPercent > 0 && pool.Capacity >= c.config.CapacityWarningPercent:
+			result.add(name, StatusWarning, "pool is %d%% full", pool.Capacity)
+		default:
+			result.add(name, StatusOK, "pool is %s and %d%% full", pool.Health, pool.Capacity)
+		}
+	}
+}
+
+func (c *Checker) checkDataset(ctx context.Context, result *Result, dataset string) {
+	name := "dataset " + dataset
+	exists, err := zfs.DatasetExists(ctx, dataset, "")
+	switch {
+	case err != nil:
+		result.add(name, StatusCritical, "dataset is not accessible: %s", err)
+		return
+	case !exists:
+		result.add(name, StatusCritical, "dataset does not exist")
+		return
+	}
+	if c.config.SnapshotAgeWarningMinutes <= 0 && c.config.SnapshotAgeCriticalMinutes <= 0 {
+		result.add(name, StatusOK, "dataset is accessible")
+		return
+	}
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{
+		ParentDataset: dataset,
+		Depth:         1,
+		Fields:        []string{zfs.PropertyName, zfs.PropertyCreation},
+	})
+	if err != nil {
+		result.add(name, StatusUnknown, "error listing snapshots: %s", err)
+		return
+	}
+	if len(snaps) == 0 {
+		result.add(name, StatusCritical, "dataset has no snapshots")
+		return
+	}
+	var newest time.Time
+	for _, snap := range snaps {
+		if snap.Creation.After(newest) {
+			newest = snap.Creation
+		}
+	}
+
+	age := c.now().Sub(newest).Truncate(time.Second)
+	switch {
+	case c.config.SnapshotAgeCriticalMinutes > 0 && age >= time.Duration(c.config.SnapshotAgeCriticalMinutes)*time.Minute:
+		result.add(name, StatusCritical, "newest snapshot is %s old", age)
+	case c.config.SnapshotAgeWarningMinutes > 0 && age >= time.Duration(c.config.SnapshotAgeWarningMinutes)*time.Minute:
+		result.add(name, StatusWarning, "newest snapshot is %s old", age)
+	default:
+		result.add(name, StatusOK, "newest snapshot is %s old", age)
+	}
+}
@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+const testPoolStatus = `  pool: pool
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	pool        ONLINE       0     0     0
+	  sda       ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+// poolExecutor runs the zpool commands itself, and the zfs commands using the fake
+type poolExecutor struct {
+	fake *zfsfake.Fake
+	list string
+	err  error
+}
+
+func (e *poolExecutor) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	if cmd != zfs.PoolBinary {
+		return e.fake.Execute(ctx, cmd, args, stdin, stdout)
+	}
+	if e.err != nil {
+		return "", e.err
+	}
+	output := e.list
+	if args[0] == "status" {
+		output = testPoolStatus
+	}
+	_, err := io.WriteString(stdout, output)
+	return "", err
+}
+
+func TestChecker(t *testing.T) {
+	exec := &poolExecutor{
+		fake: zfsfake.Install(t, "pool"),
+		list: "pool\tONLINE\t1000\t250\t750\t3\t25\t1.00\n",
+	}
+	zfs.SetExecutor(exec)
+
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "snap", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	conf := Config{
+		Pools:                      []string{"pool"},
+		Datasets:                   []string{"pool/fs"},
+		SnapshotAgeWarningMinutes:  60,
+		SnapshotAgeCriticalMinutes: 120,
+	}
+	conf.ApplyDefaults()
+	c := NewChecker(conf)
+
+	result := c.Check(ctx)
+	require.Equal(t, StatusOK, result.Status, result.Summary())
+	require.Equal(t, 0, result.ExitCode())
+	require.Len(t, result.Checks, 3)
+	require.Equal(t, "ZFS OK - 3 checks passed", result.Summary())
+
+	c.now = func() time.Time { return time.Now().Add(90 * time.Minute) }
+	result = c.Check(ctx)
+	require.Equal(t, StatusWarning, result.Status)
+	c.now = func() time.Time { return time.Now().Add(3 * time.Hour) }
+	result = c.Check(ctx)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Contains(t, result.Summary(), "dataset pool/fs: newest snapshot is 3h0m")
+	c.now = time.Now
+
+	exec.list = "pool\tDEGRADED\t1000\t850\t150\t3\t85\t1.00\n"
+	result = c.Check(ctx)
+	require.Equal(t, StatusWarning, result.Status)
+	require.Equal(t, "ZFS WARNING - pool pool: pool is DEGRADED", result.Summary())
+
+	exec.list = "pool\tONLINE\t1000\t950\t50\t3\t95\t1.00\n"
+	result = c.Check(ctx)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Equal(t, "ZFS CRITICAL - pool pool: pool is 95% full", result.Summary())
+
+	exec.list = "other\tONLINE\t1000\t250\t750\t3\t25\t1.00\n"
+	c.config.Datasets = []string{"pool/missing"}
+	result = c.Check(ctx)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Len(t, result.Checks, 3)
+	require.Equal(t, "pool is not imported", result.Checks[1].Message)
+	require.Equal(t, "dataset does not exist", result.Checks[2].Message)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Status":"CRITICAL"`)
+
+	exec.err = errors.New("zpool: not found")
+	result = c.Check(ctx)
+	require.Equal(t, StatusCritical, result.Status)
+	require.Len(t, result.Checks, 1)
+	require.Equal(t, 2, result.ExitCode())
+}
+
+func Test_worse(t *testing.T) {
+	require.Equal(t, StatusWarning, worse(StatusOK, StatusWarning))
+	require.Equal(t, StatusUnknown, worse(StatusWarning, StatusUnknown))
+	require.Equal(t, StatusCritical, worse(StatusUnknown, StatusCritical))
+	require.Equal(t, StatusCritical, worse(StatusCritical, StatusUnknown))
+}
@@ -0,0 +1,70 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const holdTimeLayout = "Mon Jan _2 15:04 2006"
+
+// Hold is a user hold on a snapshot, a snapshot with holds cannot be destroyed until they are released
+type Hold struct {
+	Snapshot string    `json:"Snapshot"`
+	Tag      string    `json:"Tag"`
+	Created  time.Time `json:"Created"`
+}
+
+// Hold adds a hold with the tag to the snapshot, it returns ErrHoldExists when the snapshot has a hold with the tag
+func (d *Dataset) Hold(ctx context.Context, tag string) error {
+	if d.Type != DatasetSnapshot {
+		return ErrOnlySnapshotsSupported
+	}
+	return zfs(ctx, "hold", tag, d.Name)
+}
+
+// Release removes the hold with the tag from the snapshot, it returns ErrHoldNotFound when there is no such hold
+func (d *Dataset) Release(ctx context.Context, tag string) error {
+	if d.Type != DatasetSnapshot {
+		return ErrOnlySnapshotsSupported
+	}
+	return zfs(ctx, "release", tag, d.Name)
+}
+
+// Holds returns the holds on the snapshot
+func (d *Dataset) Holds(ctx context.Context) ([]Hold, error) {
+	if d.Type != DatasetSnapshot {
+		return nil, ErrOnlySnapshotsSupported
+	}
+	args := []string{"holds", "-H", d.Name}
+	if CurrentPlatform().HoldsParsable {
+		args = []string{"holds", "-H", "-p", d.Name}
+	}
+	out, err := zfsOutput(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	holds := make([]Hold, 0, len(out))
+	for _, fields := range out {
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("output of zfs holds contains line with %d fields: %v", len(fields), fields)
+		}
+		created, err := parseHoldTime(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing time of hold %s on %s: %w", fields[1], fields[0], err)
+		}
+		holds = append(holds, Hold{Snapshot: fields[0], Tag: fields[1], Created: created})
+	}
+	return holds, nil
+}
+
+// parseHoldTime parses the timestamp of zfs holds, which is in seconds with -p
+func parseHoldTime(value string) (time.Time, error) {
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.ParseInLocation(holdTimeLayout, value, time.Local)
+}
@@ -0,0 +1,49 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Holds(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, Binary, cmd)
+		executed = append(executed, args)
+		switch args[0] {
+		case "holds":
+			_, err := io.WriteString(stdout, "pool/fs@snap\tkeep\t1700000000\npool/fs@snap\tsend\tThu Oct 15 10:00 2026\n")
+			return "", err
+		case "release":
+			return "cannot release hold from snapshot 'pool/fs@snap': no such tag on this dataset", errors.New("exit status 1")
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+	SetPlatform(&PlatformLinux)
+	defer SetPlatform(nil)
+
+	ctx := context.Background()
+	snap := &Dataset{Name: "pool/fs@snap", Type: DatasetSnapshot}
+	require.NoError(t, snap.Hold(ctx, "keep"))
+	holds, err := snap.Holds(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Hold{
+		{Snapshot: "pool/fs@snap", Tag: "keep", Created: time.Unix(1700000000, 0)},
+		{Snapshot: "pool/fs@snap", Tag: "send", Created: time.Date(2026, time.October, 15, 10, 0, 0, 0, time.Local)},
+	}, holds)
+	require.ErrorIs(t, snap.Release(ctx, "other"), ErrHoldNotFound)
+	require.Equal(t, [][]string{
+		{"hold", "keep", "pool/fs@snap"},
+		{"holds", "-H", "-p", "pool/fs@snap"},
+		{"release", "other", "pool/fs@snap"},
+	}, executed)
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.ErrorIs(t, fs.Hold(ctx, "keep"), ErrOnlySnapshotsSupported)
+}
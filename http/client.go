@@ -73,15 +73,31 @@ func (c *Client) request(ctx context.Context, method, url string, body io.Reader
 	for hdr := range c.headers {
 		req.Header.Set(hdr, c.headers[hdr])
 	}
+	if propagator, ok := zfs.CurrentTracer().(TracePropagator); ok {
+		propagator.Inject(ctx, req.Header)
+	}
 	return req, nil
 }
 
 // DatasetSnapshots requests the snapshots for a remote dataset
 func (c *Client) DatasetSnapshots(ctx context.Context, dataset string, extraProps []string) ([]zfs.Dataset, error) {
-	req, err := c.request(ctx, http.MethodGet, fmt.Sprintf("filesystems/%s/snapshots?%s=%s",
+	return c.DatasetSnapshotsPage(ctx, dataset, extraProps, 0, 0)
+}
+
+// DatasetSnapshotsPage retrieves a page of the snapshots of a remote dataset, skipping offset snapshots and returning
+// at most limit snapshots, zero for no limit
+func (c *Client) DatasetSnapshotsPage(ctx context.Context, dataset string, extraProps []string, offset, limit int) ([]zfs.Dataset, error) {
+	path := fmt.Sprintf("filesystems/%s/snapshots?%s=%s",
 		dataset,
 		GETParamExtraProperties, strings.Join(extraProps, ","),
-	), nil)
+	)
+	if offset > 0 {
+		path += fmt.Sprintf("&%s=%d", GETParamOffset, offset)
+	}
+	if limit > 0 {
+		path += fmt.Sprintf("&%s=%d", GETParamLimit, limit)
+	}
+	req, err := c.request(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -106,6 +122,33 @@ func (c *Client) DatasetSnapshots(ctx context.Context, dataset string, extraProp
 	return datasets, err
 }
 
+// SnapshotSpaceMap requests the space of every snapshot of a remote dataset, oldest first
+func (c *Client) SnapshotSpaceMap(ctx context.Context, dataset string) ([]zfs.SnapshotSpace, error) {
+	req, err := c.request(ctx, http.MethodGet, fmt.Sprintf("filesystems/%s/snapshot-space", dataset), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting snapshot space: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Continue
+	case http.StatusNotFound:
+		return nil, zfs.ErrDatasetNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status %d requesting snapshot space", resp.StatusCode)
+	}
+
+	var spaces []zfs.SnapshotSpace
+	err = json.NewDecoder(resp.Body).Decode(&spaces)
+	return spaces, err
+}
+
 // ResumableSendToken requests the resume token for a remote dataset, if there is one
 func (c *Client) ResumableSendToken(ctx context.Context, dataset string) (token string, curBytes uint64, err error) {
 	req, err := c.request(ctx, http.MethodGet, fmt.Sprintf("filesystems/%s/resume-token",
@@ -150,11 +193,11 @@ func (c *Client) ResumeSend(ctx context.Context, dataset, resumeToken string, op
 
 	sendCtx, cancelSend := context.WithCancel(ctx)
 	go func() {
-		err := zfs.ResumeSend(sendCtx, pipeWrtr, resumeToken, options.ResumeSendOptions)
+		_, err := zfs.ResumeSend(sendCtx, pipeWrtr, resumeToken, options.ResumeSendOptions)
 		if err != nil {
 			c.logger.Error("zfs.http.Client.ResumeSend: Error sending resume stream",
 				"error", err,
-				"server", c.server,
+				"target", c.server,
 				"dataset", dataset,
 				"resumeToken", resumeToken,
 			)
@@ -163,7 +206,7 @@ func (c *Client) ResumeSend(ctx context.Context, dataset, resumeToken string, op
 		if err != nil {
 			c.logger.Error("zfs.http.Client.sendWithBase: Error closing snapshot pipe",
 				"error", err,
-				"server", c.server,
+				"target", c.server,
 				"dataset", dataset,
 				"resumeToken", resumeToken,
 			)
@@ -223,17 +266,22 @@ type SendResult struct {
 	TimeTaken time.Duration
 }
 
+// BytesPerSecond returns the average throughput of the send
+func (r SendResult) BytesPerSecond() float64 {
+	return zfs.StreamStats{Bytes: r.BytesSent, Duration: r.TimeTaken}.BytesPerSecond()
+}
+
 // Send sends the snapshot job to the remote server
 func (c *Client) Send(ctx context.Context, send SnapshotSendOptions) (SendResult, error) {
 	pipeRdr, pipeWrtr := io.Pipe()
 
 	sendCtx, cancelSend := context.WithCancel(ctx)
 	go func() {
-		err := send.Snapshot.SendSnapshot(sendCtx, pipeWrtr, send.SendOptions)
+		_, err := send.Snapshot.SendSnapshot(sendCtx, pipeWrtr, send.SendOptions)
 		if err != nil {
 			c.logger.Error("zfs.http.Client.sendWithBase: Error sending incremental snapshot stream",
 				"error", err,
-				"server", c.server,
+				"target", c.server,
 				"snapshot", send.Snapshot.Name,
 				"baseSnapshot", send.IncrementalBase,
 			)
@@ -242,7 +290,7 @@ func (c *Client) Send(ctx context.Context, send SnapshotSendOptions) (SendResult
 		if err != nil {
 			c.logger.Error("zfs.http.Client.sendWithBase: Error closing snapshot pipe",
 				"error", err,
-				"server", c.server,
+				"target", c.server,
 				"snapshot", send.Snapshot.Name,
 				"baseSnapshot", send.IncrementalBase,
 			)
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func TestClient_SnapshotSpaceMap(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/backup/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		_, err = fs.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conf := Config{ParentDataset: "pool/backup"}
+	conf.ApplyDefaults()
+	server := httptest.NewServer(NewHTTP(ctx, conf, logger))
+	defer server.Close()
+	client := NewClient(server.URL, logger)
+
+	spaces, err := client.SnapshotSpaceMap(ctx, "fs")
+	require.NoError(t, err)
+	require.Len(t, spaces, 2)
+	require.Equal(t, "pool/backup/fs@a", spaces[0].Snapshot)
+	require.Equal(t, "pool/backup/fs@b", spaces[1].Snapshot)
+
+	_, err = client.SnapshotSpaceMap(ctx, "missing")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+}
+
+func TestClient_DatasetSnapshotsPage(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/backup/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err = fs.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conf := Config{ParentDataset: "pool/backup"}
+	conf.ApplyDefaults()
+	server := httptest.NewServer(NewHTTP(ctx, conf, logger))
+	defer server.Close()
+	client := NewClient(server.URL, logger)
+
+	snaps, err := client.DatasetSnapshotsPage(ctx, "fs", nil, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	require.Equal(t, "pool/backup/fs@b", snaps[0].Name)
+
+	snaps, err = client.DatasetSnapshots(ctx, "fs", nil)
+	require.NoError(t, err)
+	require.Len(t, snaps, 3)
+
+	resp, err := http.Get(server.URL + "/filesystems/fs/snapshots?limit=-1")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+var (
+	// ErrNoSnapshots is returned when syncing a dataset without snapshots
+	ErrNoSnapshots = errors.New("dataset has no snapshots")
+	// ErrNoCommonSnapshot is returned when the remote filesystem has snapshots, but none in common with the local dataset
+	ErrNoCommonSnapshot = errors.New("no common snapshot with remote filesystem")
+	// ErrSyncNotVerified is returned when the remote filesystem does not have the last snapshot after syncing
+	ErrSyncNotVerified = errors.New("remote filesystem does not have the synced snapshot")
+)
+
+// SyncOptions are options you can specify to customize a dataset sync
+type SyncOptions struct {
+	// SendOptions are used for sending every snapshot, its incremental base is set by the sync
+	zfs.SendOptions
+
+	// ResumeAttempts is the amount of times an interrupted send is resumed, zero disables resuming.
+	// When set, the snapshots are received resumable.
+	ResumeAttempts int
+	// ReceiveForceRollback sets whether the remote filesystem is rolled back to the received snapshot
+	ReceiveForceRollback bool
+	// Properties are set on the remote filesystem when it is created
+	Properties ReceiveProperties
+
+	// ProgressFn: Set a callback function to receive updates about the progress of every snapshot.
+	// When resuming an earlier interrupted receive, the remote filesystem name is given instead.
+	ProgressFn func(snapshot string, bytes int64)
+	// ProgressEvery determines progress update interval
+	ProgressEvery time.Duration
+}
+
+// SyncResult contains the statistics of a dataset sync
+type SyncResult struct {
+	// Snapshots are the names of the snapshots sent
+	Snapshots []string
+	// Resumes is the amount of times an interrupted send was resumed
+	Resumes   int
+	BytesSent int64
+	TimeTaken time.Duration
+}
+
+// SyncDataset replicates the snapshots of the local dataset to the remote filesystem. It resumes a previously
+// interrupted receive first, then looks up the most recent common snapshot by GUID, and sends the snapshots after it
+// incrementally, or all snapshots when the remote filesystem does not exist yet. Finally, it verifies the remote
+// filesystem has the last snapshot.
+func (c *Client) SyncDataset(ctx context.Context, localDataset, remoteFilesystem string,
+	options SyncOptions) (result SyncResult, err error) {
+	start := time.Now()
+	defer func() {
+		result.TimeTaken = time.Since(start)
+	}()
+
+	local, err := zfs.ListSnapshots(ctx, zfs.ListOptions{
+		ParentDataset: localDataset,
+		Depth:         1,
+		Fields:        []string{zfs.PropertyName, zfs.PropertyType, zfs.PropertyGUID, zfs.PropertyCreateTXG},
+	})
+	if err != nil {
+		return result, fmt.Errorf("error listing local snapshots of %s: %w", localDataset, err)
+	}
+	if len(local) == 0 {
+		return result, fmt.Errorf("%s: %w", localDataset, ErrNoSnapshots)
+	}
+	slices.SortFunc(local, func(a, b zfs.Dataset) int {
+		return cmp.Compare(a.CreateTXG, b.CreateTXG)
+	})
+
+	if options.ResumeAttempts > 0 {
+		// The interrupted snapshot may be the base for the next ones, so finish it first
+		_, err = c.resumeSync(ctx, remoteFilesystem, remoteFilesystem, options, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	remote, err := c.remoteSnapshots(ctx, remoteFilesystem)
+	if err != nil {
+		return result, err
+	}
+
+	toSend := local
+	base := zfs.CommonSnapshot(local, remote)
+	switch {
+	case base != nil:
+		toSend = local[slices.IndexFunc(local, func(snap zfs.Dataset) bool { return snap.GUID == base.GUID })+1:]
+	case len(remote) > 0:
+		return result, fmt.Errorf("%s: %w", remoteFilesystem, ErrNoCommonSnapshot)
+	}
+
+	for i := range toSend {
+		snap := &toSend[i]
+		err = c.syncSnapshot(ctx, snap, base, remoteFilesystem, options, &result)
+		if err != nil {
+			return result, err
+		}
+		result.Snapshots = append(result.Snapshots, snap.Name)
+		base = snap
+	}
+
+	remote, err = c.remoteSnapshots(ctx, remoteFilesystem)
+	if err != nil {
+		return result, err
+	}
+	last := local[len(local)-1]
+	if !slices.ContainsFunc(remote, func(snap zfs.Dataset) bool { return snap.GUID == last.GUID }) {
+		return result, fmt.Errorf("%s: %w", last.Name, ErrSyncNotVerified)
+	}
+	return result, nil
+}
+
+// remoteSnapshots lists the snapshots of the remote filesystem, none when it does not exist
+func (c *Client) remoteSnapshots(ctx context.Context, filesystem string) ([]zfs.Dataset, error) {
+	snaps, err := c.DatasetSnapshots(ctx, filesystem, nil)
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("error listing remote snapshots of %s: %w", filesystem, err)
+	}
+	return snaps, nil
+}
+
+func (c *Client) syncSnapshot(ctx context.Context, snap, base *zfs.Dataset, remoteFilesystem string,
+	options SyncOptions, result *SyncResult) error {
+	send := SnapshotSendOptions{
+		SendOptions:          options.SendOptions,
+		DatasetName:          remoteFilesystem,
+		SnapshotName:         snap.Name[strings.IndexByte(snap.Name, '@')+1:],
+		Snapshot:             snap,
+		Resumable:            options.ResumeAttempts > 0,
+		ReceiveForceRollback: options.ReceiveForceRollback,
+		ProgressEvery:        options.ProgressEvery,
+	}
+	send.IncrementalBase = base
+	if base == nil {
+		send.Properties = options.Properties
+	}
+	if options.ProgressFn != nil {
+		send.ProgressFn = func(bytes int64) {
+			options.ProgressFn(snap.Name, bytes)
+		}
+	}
+
+	sent, err := c.Send(ctx, send)
+	result.BytesSent += sent.BytesSent
+	for attempt := 0; err != nil && attempt < options.ResumeAttempts && ctx.Err() == nil; attempt++ {
+		c.logger.Warn("zfs.http.Client.syncSnapshot: Send failed, trying to resume",
+			"error", err,
+			"target", c.server,
+			"snapshot", snap.Name,
+			"attempt", attempt+1,
+		)
+		resumed, resumeErr := c.resumeSync(ctx, remoteFilesystem, snap.Name, options, result)
+		if !resumed {
+			break // Nothing was received, so the send cannot be resumed
+		}
+		err = resumeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error sending %s: %w", snap.Name, err)
+	}
+	return nil
+}
+
+// resumeSync resumes an interrupted receive of the remote filesystem, it returns whether there was one.
+// The progress is reported for the given snapshot name.
+func (c *Client) resumeSync(ctx context.Context, remoteFilesystem, snapshot string, options SyncOptions,
+	result *SyncResult) (bool, error) {
+	token, _, err := c.ResumableSendToken(ctx, remoteFilesystem)
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("error retrieving resume token of %s: %w", remoteFilesystem, err)
+	case token == "":
+		return false, nil
+	}
+
+	resume := ResumeSendOptions{
+		ResumeSendOptions: zfs.ResumeSendOptions{
+			BytesPerSecond:   options.BytesPerSecond,
+			CompressionLevel: options.CompressionLevel,
+			BufferSize:       options.BufferSize,
+			ExternalBuffer:   options.ExternalBuffer,
+		},
+		ProgressEvery: options.ProgressEvery,
+	}
+	if options.ProgressFn != nil {
+		resume.ProgressFn = func(bytes int64) {
+			options.ProgressFn(snapshot, bytes)
+		}
+	}
+	sent, err := c.ResumeSend(ctx, remoteFilesystem, token, resume)
+	result.BytesSent += sent.BytesSent
+	result.Resumes++
+	if err != nil {
+		return true, fmt.Errorf("error resuming send to %s: %w", remoteFilesystem, err)
+	}
+	return true, nil
+}
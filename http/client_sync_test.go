@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func TestClient_SyncDataset(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	src, err := zfs.CreateFilesystem(ctx, "pool/src", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = zfs.CreateFilesystem(ctx, "pool/backup", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		_, err = src.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conf := Config{ParentDataset: "pool/backup"}
+	conf.ApplyDefaults()
+	server := httptest.NewServer(NewHTTP(ctx, conf, logger))
+	defer server.Close()
+	client := NewClient(server.URL, logger)
+
+	var progress []string
+	result, err := client.SyncDataset(ctx, "pool/src", "fs", SyncOptions{
+		ResumeAttempts: 2,
+		ProgressEvery:  time.Nanosecond,
+		Properties:     ReceiveProperties{zfs.PropertyCanMount: zfs.ValueOff},
+		ProgressFn: func(snapshot string, _ int64) {
+			progress = append(progress, snapshot)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/src@a", "pool/src@b"}, result.Snapshots)
+	require.NotZero(t, result.BytesSent)
+	require.Zero(t, result.Resumes)
+	require.Contains(t, progress, "pool/src@b")
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "pool/backup/fs"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+
+	_, err = src.Snapshot(ctx, "c", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	result, err = client.SyncDataset(ctx, "pool/src", "fs", SyncOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/src@c"}, result.Snapshots)
+
+	result, err = client.SyncDataset(ctx, "pool/src", "fs", SyncOptions{})
+	require.NoError(t, err)
+	require.Empty(t, result.Snapshots)
+
+	other, err := zfs.CreateFilesystem(ctx, "pool/backup/other", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = other.Snapshot(ctx, "x", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = client.SyncDataset(ctx, "pool/src", "other", SyncOptions{})
+	require.ErrorIs(t, err, ErrNoCommonSnapshot)
+
+	_, err = zfs.CreateFilesystem(ctx, "pool/empty", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = client.SyncDataset(ctx, "pool/empty", "empty", SyncOptions{})
+	require.ErrorIs(t, err, ErrNoSnapshots)
+}
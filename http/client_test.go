@@ -8,13 +8,14 @@ import (
 	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfstest"
 
 	"github.com/stretchr/testify/require"
 )
 
 func clientTest(t *testing.T, fn func(client *Client)) {
 	t.Helper()
-	TestHTTPZPool(testZPool, testPrefix, testFilesystem, func(server *httptest.Server) {
+	TestHTTPServer(t, testZPool, testFilesystemFixture(), func(server *httptest.Server, _ *zfstest.Pool) {
 		c := NewClient(server.URL+testPrefix, slog.Default())
 		fn(c)
 	})
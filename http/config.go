@@ -1,8 +1,11 @@
 package http
 
+import zfs "github.com/vansante/go-zfsutils"
+
 const (
 	defaultBytesPerSecond            = 100 * 1024 * 1024
 	defaultMaximumConcurrentReceives = 3
+	defaultStreamBufferSize          = 4 * 1024 * 1024
 )
 
 // Config specifies the configuration for the zfs http server
@@ -14,6 +17,12 @@ type Config struct {
 	// MaximumConcurrentReceives limits the concurrent amount of ZFS receives, set to zero to disable limits
 	MaximumConcurrentReceives int `json:"MaximumConcurrentReceives" yaml:"MaximumConcurrentReceives"`
 
+	// StreamBufferSize sets the amount of bytes buffered in memory for send and receive streams, zero to disable
+	StreamBufferSize int `json:"StreamBufferSize" yaml:"StreamBufferSize"`
+	// ExternalBuffer runs an external program like mbuffer between zfs and the connection of send and receive streams,
+	// nil for none
+	ExternalBuffer *zfs.ExternalBuffer `json:"ExternalBuffer" yaml:"ExternalBuffer"`
+
 	Permissions Permissions `json:"Permissions" yaml:"Permissions"`
 }
 
@@ -30,4 +39,5 @@ type Permissions struct {
 func (c *Config) ApplyDefaults() {
 	c.SpeedBytesPerSecond = defaultBytesPerSecond
 	c.MaximumConcurrentReceives = defaultMaximumConcurrentReceives
+	c.StreamBufferSize = defaultStreamBufferSize
 }
@@ -2,6 +2,8 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -9,6 +11,9 @@ import (
 	"sync"
 
 	"github.com/klauspost/compress/zstd"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/logging"
 )
 
 // HTTP is the main object for serving the ZFS HTTP server
@@ -50,6 +55,7 @@ func (h *HTTP) registerRoutes() {
 
 	h.registerRoute(http.MethodGet, "/filesystems/{filesystem}/snapshots", h.handleListSnapshots)
 	h.registerRoute(http.MethodGet, "/filesystems/{filesystem}/resume-token", h.handleGetResumeToken)
+	h.registerRoute(http.MethodGet, "/filesystems/{filesystem}/snapshot-space", h.handleSnapshotSpace)
 
 	h.registerRoute(http.MethodGet, "/filesystems/{filesystem}/snapshots/{snapshot}", h.handleGetSnapshot)
 	h.registerRoute(http.MethodGet, "/filesystems/{filesystem}/snapshots/{snapshot}/incremental/{basesnapshot}", h.handleGetSnapshotIncremental)
@@ -63,13 +69,20 @@ func (h *HTTP) registerRoutes() {
 }
 
 func (h *HTTP) registerRoute(method, url string, handler handle) {
-	h.router.HandleFunc(fmt.Sprintf("%s %s%s", method, h.config.HTTPPathPrefix, url), h.middleware(handler))
+	pattern := fmt.Sprintf("%s %s%s", method, h.config.HTTPPathPrefix, url)
+	h.router.HandleFunc(pattern, h.middleware(pattern, handler))
 }
 
 // middleware is an HTTP handler wrapper
-func (h *HTTP) middleware(handle handle) http.HandlerFunc {
+func (h *HTTP) middleware(pattern string, handle handle) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		logger := h.logger.With(slog.Group("req",
+		requestID := req.Header.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		logger := h.logger.With(logging.KeyRequestID, requestID, slog.Group("req",
 			"URL", req.URL.String(),
 			"method", req.Method),
 			"remoteAddr", req.RemoteAddr,
@@ -77,10 +90,33 @@ func (h *HTTP) middleware(handle handle) http.HandlerFunc {
 		)
 		logger.Info("zfs.http.middleware: Handling")
 
-		handle(w, req, logger)
+		ctx := req.Context()
+		if propagator, ok := zfs.CurrentTracer().(TracePropagator); ok {
+			ctx = propagator.Extract(ctx, req.Header)
+		}
+		ctx, span := zfs.StartSpan(ctx, "zfs.http "+pattern,
+			zfs.Attribute{Key: "http.request.method", Value: req.Method},
+			zfs.Attribute{Key: "http.route", Value: pattern},
+			zfs.Attribute{Key: "url.path", Value: req.URL.Path},
+		)
+		defer span.End(nil)
+
+		handle(w, req.WithContext(ctx), logger)
 	}
 }
 
+// validRequestID returns whether a request ID given by the client is safe to log and return
+func validRequestID(id string) bool {
+	return validRequestIDRegexp.MatchString(id)
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
 func (h *HTTP) getSpeed(req *http.Request) int64 {
 	speed := h.config.SpeedBytesPerSecond
 	if !h.config.Permissions.AllowSpeedOverride {
@@ -24,12 +24,15 @@ const (
 	GETParamBytesPerSecond      = "bytesPerSecond"
 	GETParamEnableDecompression = "enableDecompression"
 	GETParamCompressionLevel    = "compressionLevel"
+	GETParamOffset              = "offset"
+	GETParamLimit               = "limit"
 )
 
 const (
 	HeaderResumeReceiveToken  = "X-Receive-Resume-Token"
 	HeaderResumeReceivedBytes = "X-Received-Bytes"
 	HeaderError               = "X-Error"
+	HeaderRequestID           = "X-Request-Id"
 )
 
 type ReceiveProperties map[string]string
@@ -60,6 +63,7 @@ type SetProperties struct {
 var (
 	validIdentifierRegexp  = regexp.MustCompile(`^[a-zA-Z0-9_]{1,100}$`)
 	validResumeTokenRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{100,500}$`)
+	validRequestIDRegexp   = regexp.MustCompile(`^[a-zA-Z0-9_.:-]{1,64}$`)
 )
 
 func validIdentifier(name string) bool {
@@ -191,9 +195,18 @@ func (h *HTTP) handleListSnapshots(w http.ResponseWriter, req *http.Request, log
 		return
 	}
 
+	offset, limit, err := pagination(req)
+	if err != nil {
+		logger.Info("zfs.http.handleListSnapshots: Invalid pagination", "error", err, "filesystem", filesystem)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	list, err := zfs.ListSnapshots(req.Context(), zfs.ListOptions{
 		ParentDataset:   fmt.Sprintf("%s/%s", h.config.ParentDataset, filesystem),
 		ExtraProperties: zfsExtraProperties(req),
+		Offset:          offset,
+		Limit:           limit,
 	})
 	switch {
 	case errors.Is(err, zfs.ErrDatasetNotFound):
@@ -214,6 +227,50 @@ func (h *HTTP) handleListSnapshots(w http.ResponseWriter, req *http.Request, log
 	}
 }
 
+// pagination returns the offset and limit GET parameters, which are zero when absent
+func pagination(req *http.Request) (offset, limit int, err error) {
+	for param, dst := range map[string]*int{GETParamOffset: &offset, GETParamLimit: &limit} {
+		str := req.URL.Query().Get(param)
+		if str == "" {
+			continue
+		}
+		*dst, err = strconv.Atoi(str)
+		if err != nil || *dst < 0 {
+			return 0, 0, fmt.Errorf("invalid %s parameter: %s", param, str)
+		}
+	}
+	return offset, limit, nil
+}
+
+func (h *HTTP) handleSnapshotSpace(w http.ResponseWriter, req *http.Request, logger *slog.Logger) {
+	filesystem := req.PathValue("filesystem")
+	if !validIdentifier(filesystem) {
+		logger.Info("zfs.http.handleSnapshotSpace: Invalid identifier", "filesystem", filesystem)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ds := &zfs.Dataset{Name: fmt.Sprintf("%s/%s", h.config.ParentDataset, filesystem)}
+	spaces, err := ds.SnapshotSpaceMap(req.Context())
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		logger.Info("zfs.http.handleSnapshotSpace: Filesystem not found", "error", err, "filesystem", filesystem)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		logger.Error("zfs.http.handleSnapshotSpace: Error getting snapshot space", "error", err, "filesystem", filesystem)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(spaces)
+	if err != nil {
+		logger.Error("zfs.http.handleSnapshotSpace: Error encoding json", "error", err, "filesystem", filesystem)
+		return
+	}
+}
+
 func (h *HTTP) handleGetResumeToken(w http.ResponseWriter, req *http.Request, logger *slog.Logger) {
 	filesystem := req.PathValue("filesystem")
 	if !validIdentifier(filesystem) {
@@ -329,11 +386,13 @@ func (h *HTTP) handleReceiveSnapshot(w http.ResponseWriter, req *http.Request, l
 		}()
 	}
 
-	ds, err := zfs.ReceiveSnapshot(req.Context(), req.Body, receiveDataset, zfs.ReceiveOptions{
+	ds, stats, err := zfs.ReceiveSnapshot(req.Context(), req.Body, receiveDataset, zfs.ReceiveOptions{
 		EnableDecompression: h.getEnableDecompression(req),
 		ForceRollback:       h.getReceiveForceRollback(req),
 		Resumable:           resumable,
 		Properties:          props,
+		BufferSize:          h.config.StreamBufferSize,
+		ExternalBuffer:      h.config.ExternalBuffer,
 	})
 	switch {
 	case errors.Is(err, zfs.ErrDatasetExists):
@@ -350,6 +409,7 @@ func (h *HTTP) handleReceiveSnapshot(w http.ResponseWriter, req *http.Request, l
 
 	logger.Info("zfs.http.handleReceiveSnapshot: Received snapshot",
 		"dataset", receiveDataset, "properties", props,
+		"bytes", stats.Bytes, "bytesPerSecond", int64(stats.BytesPerSecond()),
 	)
 
 	w.WriteHeader(http.StatusCreated)
@@ -423,11 +483,13 @@ func (h *HTTP) handleGetSnapshot(w http.ResponseWriter, req *http.Request, logge
 		return
 	}
 
-	err = ds.SendSnapshot(req.Context(), w, zfs.SendOptions{
+	_, err = ds.SendSnapshot(req.Context(), w, zfs.SendOptions{
 		BytesPerSecond:    h.getSpeed(req),
 		IncludeProperties: h.getIncludeProperties(req),
 		Raw:               h.getRaw(req),
 		CompressionLevel:  h.getCompressionLevel(req),
+		BufferSize:        h.config.StreamBufferSize,
+		ExternalBuffer:    h.config.ExternalBuffer,
 	})
 	if err != nil {
 		logger.Error("zfs.http.handleGetSnapshot: Error sending snapshot", "error", err)
@@ -442,7 +504,7 @@ func (h *HTTP) handleGetSnapshotIncremental(w http.ResponseWriter, req *http.Req
 	logger = logger.With(
 		"filesystem", filesystem,
 		"snapshot", snapshot,
-		"basesnapshot", basesnapshot,
+		"baseSnapshot", basesnapshot,
 	)
 
 	if !validIdentifier(filesystem) || !validIdentifier(basesnapshot) || !validIdentifier(snapshot) {
@@ -483,12 +545,14 @@ func (h *HTTP) handleGetSnapshotIncremental(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	err = snap.SendSnapshot(req.Context(), w, zfs.SendOptions{
+	_, err = snap.SendSnapshot(req.Context(), w, zfs.SendOptions{
 		BytesPerSecond:    h.getSpeed(req),
 		IncludeProperties: h.getIncludeProperties(req),
 		Raw:               h.getRaw(req),
 		IncrementalBase:   base,
 		CompressionLevel:  h.getCompressionLevel(req),
+		BufferSize:        h.config.StreamBufferSize,
+		ExternalBuffer:    h.config.ExternalBuffer,
 	})
 	if err != nil {
 		logger.Error("zfs.http.handleGetSnapshotIncremental: Error sending incremental snapshot", "error", err)
@@ -504,9 +568,11 @@ func (h *HTTP) handleResumeGetSnapshot(w http.ResponseWriter, req *http.Request,
 		return
 	}
 
-	err := zfs.ResumeSend(req.Context(), w, token, zfs.ResumeSendOptions{
+	_, err := zfs.ResumeSend(req.Context(), w, token, zfs.ResumeSendOptions{
 		BytesPerSecond:   h.getSpeed(req),
 		CompressionLevel: h.getCompressionLevel(req),
+		BufferSize:       h.config.StreamBufferSize,
+		ExternalBuffer:   h.config.ExternalBuffer,
 	})
 	if err != nil {
 		logger.Error("zfs.http.handleResumeGetSnapshot: Error sending snapshot", "error", err, "token", token)
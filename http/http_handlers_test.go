@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -28,11 +30,21 @@ const (
 
 func httpHandlerTest(t *testing.T, fn func(url string)) {
 	t.Helper()
-	TestHTTPZPool(testZPool, testPrefix, testFilesystem, func(server *httptest.Server) {
+	TestHTTPServer(t, testZPool, testFilesystemFixture(), func(server *httptest.Server, _ *zfstest.Pool) {
 		fn(server.URL + testPrefix)
 	})
 }
 
+// testFilesystemFixture is a test pool with the unmounted test filesystem
+func testFilesystemFixture() TestFixture {
+	fixture := TestFixture{Prefix: testPrefix}
+	fixture.Filesystems = []zfstest.Filesystem{{
+		Name:       testFilesystemName,
+		Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
+	}}
+	return fixture
+}
+
 func TestHTTP_handleListFilesystems(t *testing.T) {
 	httpHandlerTest(t, func(url string) {
 		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/filesystems", url), nil)
@@ -134,7 +146,7 @@ func TestHTTP_handleGetSnapshot(t *testing.T) {
 		require.EqualValues(t, http.StatusOK, resp.StatusCode)
 
 		testName := fmt.Sprintf("%s/%s", testZPool, "receive")
-		ds, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, testName, zfs.ReceiveOptions{
+		ds, _, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, testName, zfs.ReceiveOptions{
 			Resumable:  false,
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
@@ -168,13 +180,13 @@ func TestHTTP_handleGetSnapshotIncremental(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			_, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, newFilesys, zfs.ReceiveOptions{
+			_, _, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, newFilesys, zfs.ReceiveOptions{
 				Resumable:  false,
 				Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 			})
 			require.NoError(t, err)
 		}()
-		err = snap1.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true})
+		_, err = snap1.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true})
 		require.NoError(t, err)
 		require.NoError(t, pipeWrtr.Close())
 		wg.Wait()
@@ -191,7 +203,7 @@ func TestHTTP_handleGetSnapshotIncremental(t *testing.T) {
 		defer resp.Body.Close()
 		require.EqualValues(t, http.StatusOK, resp.StatusCode)
 
-		ds, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, newFilesys, zfs.ReceiveOptions{
+		ds, _, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, newFilesys, zfs.ReceiveOptions{
 			Resumable:  false,
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
@@ -227,7 +239,7 @@ func TestHTTP_handleResumeGetSnapshot(t *testing.T) {
 		require.EqualValues(t, http.StatusOK, resp.StatusCode)
 
 		testName := fmt.Sprintf("%s/%s", testZPool, "receive")
-		ds, err = zfs.ReceiveSnapshot(context.Background(), io.LimitReader(resp.Body, 29_636), testName, zfs.ReceiveOptions{
+		ds, _, err = zfs.ReceiveSnapshot(context.Background(), io.LimitReader(resp.Body, 29_636), testName, zfs.ReceiveOptions{
 			Resumable:  true,
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
@@ -256,7 +268,7 @@ func TestHTTP_handleResumeGetSnapshot(t *testing.T) {
 		defer resp.Body.Close()
 		require.EqualValues(t, http.StatusOK, resp.StatusCode)
 
-		ds, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, testName, zfs.ReceiveOptions{
+		ds, _, err = zfs.ReceiveSnapshot(context.Background(), resp.Body, testName, zfs.ReceiveOptions{
 			Resumable:  true,
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
@@ -322,7 +334,7 @@ func TestHTTP_handleReceiveSnapshot(t *testing.T) {
 
 		ds, err = ds.Snapshot(context.Background(), snapName, zfs.SnapshotOptions{})
 		require.NoError(t, err)
-		err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true, IncludeProperties: true})
+		_, err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true, IncludeProperties: true})
 		require.NoError(t, err)
 		require.NoError(t, pipeWrtr.Close())
 
@@ -370,7 +382,7 @@ func TestHTTP_handleReceiveSnapshotNoExplicitName(t *testing.T) {
 		require.NoError(t, err)
 		ds, err = ds.Snapshot(context.Background(), snapName, zfs.SnapshotOptions{})
 		require.NoError(t, err)
-		err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true, IncludeProperties: true})
+		_, err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{Raw: true, IncludeProperties: true})
 		require.NoError(t, err)
 		require.NoError(t, pipeWrtr.Close())
 
@@ -398,7 +410,7 @@ func TestHTTP_handleReceiveSnapshotResume(t *testing.T) {
 
 		go func() {
 			defer wg.Done()
-			_, err := zfs.ReceiveSnapshot(context.Background(), io.LimitReader(pipeRdr, 28_725), newFullSnap, zfs.ReceiveOptions{
+			_, _, err := zfs.ReceiveSnapshot(context.Background(), io.LimitReader(pipeRdr, 28_725), newFullSnap, zfs.ReceiveOptions{
 				Resumable:  true,
 				Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 			},
@@ -413,7 +425,7 @@ func TestHTTP_handleReceiveSnapshotResume(t *testing.T) {
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		err = toBeSent.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{
+		_, err = toBeSent.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{
 			Raw:               true,
 			IncludeProperties: true,
 		})
@@ -476,7 +488,7 @@ func TestHTTP_handleReceiveSnapshotResume(t *testing.T) {
 			require.Equal(t, name, snaps[0].Name)
 		}()
 
-		err = zfs.ResumeSend(context.Background(), pipeWrtr, token, zfs.ResumeSendOptions{})
+		_, err = zfs.ResumeSend(context.Background(), pipeWrtr, token, zfs.ResumeSendOptions{})
 		require.NoError(t, err)
 		require.NoError(t, pipeWrtr.Close())
 
@@ -550,3 +562,22 @@ func TestHTTP_handleReceiveSnapshotMaxConcurrent(t *testing.T) {
 		require.GreaterOrEqual(t, countError, int32(2), "CountError is not at least 2")
 	})
 }
+
+func TestHTTP_handleSnapshotSpace(t *testing.T) {
+	fixture := TestFixture{Prefix: testPrefix}
+	fixture.Files = 1
+	fixture.FileSize = 128 * 1024 * 1024
+	fixture.Filesystems = []zfstest.Filesystem{{
+		Name:       testFilesystemName,
+		Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
+		Snapshots:  []string{"snap1", "snap2"},
+	}}
+	TestHTTPServer(t, testZPool, fixture, func(server *httptest.Server, _ *zfstest.Pool) {
+		client := NewClient(server.URL+testPrefix, slog.Default())
+		spaces, err := client.SnapshotSpaceMap(context.Background(), testFilesystemName)
+		require.NoError(t, err)
+		require.Len(t, spaces, 2)
+		require.Equal(t, testFilesystem+"@snap1", spaces[0].Snapshot)
+		require.Equal(t, testFilesystem+"@snap2", spaces[1].Snapshot)
+	})
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/logging"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func Test_requestID(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	_, err := zfs.CreateFilesystem(context.Background(), "pool/parent/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	conf := Config{ParentDataset: "pool/parent"}
+	conf.ApplyDefaults()
+	handler := NewHTTP(context.Background(), conf, slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	for id, expected := range map[string]string{
+		"abc-123":             "abc-123",
+		"":                    "",
+		"invalid\nrequest id": "",
+	} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/filesystems", nil)
+		req.Header.Set(HeaderRequestID, id)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		requestID := w.Header().Get(HeaderRequestID)
+		if expected != "" {
+			require.Equal(t, expected, requestID)
+		} else {
+			require.Len(t, requestID, 16)
+		}
+
+		entry := map[string]any{}
+		line, _, _ := strings.Cut(buf.String(), "\n")
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		require.Equal(t, requestID, entry[logging.KeyRequestID])
+	}
+}
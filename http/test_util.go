@@ -4,37 +4,72 @@ import (
 	"context"
 	"log/slog"
 	"net/http/httptest"
+	"strings"
 
 	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfstest"
 )
 
+// TestFixture configures the test pool and the server created by TestHTTPServer
+type TestFixture struct {
+	// Fixture configures the pool size and the datasets to create in the pool
+	zfstest.Fixture
+	// Prefix is the HTTP path prefix of the server
+	Prefix string
+	// Config is the server config, nil for a config that allows everything. Its parent dataset is set to the pool.
+	Config *Config
+	// Logger is the logger of the server, nil for the default logger
+	Logger *slog.Logger
+}
+
+// TestHTTPServer creates a test pool populated as described by the fixture for the duration of the test, and runs fn
+// with a server for the pool. The test is skipped when ZFS is not available.
+func TestHTTPServer(t zfstest.TB, testZPool string, fixture TestFixture, fn func(server *httptest.Server, pool *zfstest.Pool)) {
+	t.Helper()
+	zfstest.RunFixture(t, testZPool, fixture.Fixture, func(pool *zfstest.Pool) {
+		fn(newTestServer(testZPool, fixture), pool)
+	})
+}
+
+// TestHTTPZPool creates a test pool with a server for it, and the unmounted test filesystem when given.
+// It panics when the pool cannot be created.
+//
+// Deprecated: Use TestHTTPServer instead, which skips the test when ZFS is not available.
 func TestHTTPZPool(testZPool, prefix, testFs string, fn func(server *httptest.Server)) {
-	zfs.TestZPool(testZPool, func() {
-		h := NewHTTP(context.Background(), Config{
-			ParentDataset:  testZPool,
-			HTTPPathPrefix: prefix,
-
-			MaximumConcurrentReceives: 2,
-
-			Permissions: Permissions{
-				AllowSpeedOverride:      true,
-				AllowNonRaw:             true,
-				AllowIncludeProperties:  true,
-				AllowDestroyFilesystems: true,
-				AllowDestroySnapshots:   true,
-			},
-		}, slog.Default())
-
-		if testFs != "" {
-			_, err := zfs.CreateFilesystem(context.Background(), testFs, zfs.CreateFilesystemOptions{
-				Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
-			})
-			if err != nil {
-				panic(err)
-			}
-		}
-
-		server := httptest.NewServer(h)
-		fn(server)
+	fixture := TestFixture{Prefix: prefix}
+	if testFs != "" {
+		fixture.Filesystems = []zfstest.Filesystem{{
+			Name:       strings.TrimPrefix(testFs, testZPool+"/"),
+			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
+		}}
+	}
+	zfstest.WithFixture(testZPool, fixture.Fixture, func(*zfstest.Pool) {
+		fn(newTestServer(testZPool, fixture))
 	})
 }
+
+// newTestServer creates a server for the test pool configured by the fixture
+func newTestServer(testZPool string, fixture TestFixture) *httptest.Server {
+	conf := Config{
+		MaximumConcurrentReceives: 2,
+
+		Permissions: Permissions{
+			AllowSpeedOverride:      true,
+			AllowNonRaw:             true,
+			AllowIncludeProperties:  true,
+			AllowDestroyFilesystems: true,
+			AllowDestroySnapshots:   true,
+		},
+	}
+	if fixture.Config != nil {
+		conf = *fixture.Config
+	}
+	conf.ParentDataset = testZPool
+	conf.HTTPPathPrefix = fixture.Prefix
+
+	logger := fixture.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return httptest.NewServer(NewHTTP(context.Background(), conf, logger))
+}
@@ -0,0 +1,13 @@
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// TracePropagator can be implemented by the tracer set with zfs.SetTracer to continue traces across servers.
+// The client injects the trace into the headers of its requests, and the server extracts it for its spans.
+type TracePropagator interface {
+	Inject(ctx context.Context, header http.Header)
+	Extract(ctx context.Context, header http.Header) context.Context
+}
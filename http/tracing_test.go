@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+type traceKey struct{}
+
+type testSpan struct{}
+
+func (testSpan) SetAttributes(...zfs.Attribute) {}
+func (testSpan) End(error)                      {}
+
+// testPropagator records the span names with the trace they belong to
+type testPropagator struct {
+	mu    sync.Mutex
+	spans map[string]string
+}
+
+func (p *testPropagator) Start(ctx context.Context, name string, _ ...zfs.Attribute) (context.Context, zfs.Span) {
+	trace, _ := ctx.Value(traceKey{}).(string)
+	p.mu.Lock()
+	p.spans[name] = trace
+	p.mu.Unlock()
+	return ctx, testSpan{}
+}
+
+func (p *testPropagator) Inject(ctx context.Context, header http.Header) {
+	trace, _ := ctx.Value(traceKey{}).(string)
+	header.Set("X-Test-Trace", trace)
+}
+
+func (p *testPropagator) Extract(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, traceKey{}, header.Get("X-Test-Trace"))
+}
+
+func TestTracePropagator(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	_, err := zfs.CreateFilesystem(context.Background(), "pool/parent/fs", zfs.CreateFilesystemOptions{CreateParents: true})
+	require.NoError(t, err)
+
+	tracer := &testPropagator{spans: make(map[string]string)}
+	zfs.SetTracer(tracer)
+	defer zfs.SetTracer(nil)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conf := Config{ParentDataset: "pool/parent"}
+	conf.ApplyDefaults()
+	server := httptest.NewServer(NewHTTP(context.Background(), conf, logger))
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	_, err = NewClient(server.URL, logger).DatasetSnapshots(ctx, "fs", nil)
+	require.NoError(t, err)
+
+	require.Equal(t, "trace-1", tracer.spans["zfs.http GET /filesystems/{filesystem}/snapshots"])
+	require.Equal(t, "trace-1", tracer.spans["zfs get"], "command span is a child of the request span")
+}
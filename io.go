@@ -1,9 +1,11 @@
 package zfs
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -38,48 +40,366 @@ func zstdWriter(writer io.Writer, level zstd.EncoderLevel) (io.Writer, func(), e
 // ProgressCallback is a callback function that lets you monitor progress
 type ProgressCallback func(bytes int64)
 
+// StreamProgressCallback is a callback function that lets you monitor the amount of bytes of a send or receive stream
+type StreamProgressCallback func(bytes uint64)
+
+// StreamStats contains the byte count and duration of a stream
+type StreamStats struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+// BytesPerSecond returns the average throughput of the stream
+func (s StreamStats) BytesPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
 // NewCountReader creates a new CountReader
 func NewCountReader(reader io.Reader) *CountReader {
 	return &CountReader{
-		Reader: reader,
+		Reader:  reader,
+		counter: newCounter(),
 	}
 }
 
 // CountReader counts the bytes it has read
 type CountReader struct {
 	io.Reader
-	n int64
+	counter
+}
 
-	every      time.Duration
-	progressFn ProgressCallback
-	last       time.Time
+func (r *CountReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.add(n)
+	return n, err
 }
 
-// SetProgressCallback sets a new progress handler every duration
-func (r *CountReader) SetProgressCallback(every time.Duration, progressFn ProgressCallback) {
-	r.progressFn = progressFn
-	r.every = every
+// WriteTo lets io.Copy use the fast paths of the wrapped reader and the writer, like splice into a pipe.
+// Without a progress callback the bytes are only counted once copying is done.
+func (r *CountReader) WriteTo(w io.Writer) (int64, error) {
+	if r.periodic() {
+		return io.Copy(w, struct{ io.Reader }{r}) // Count while copying
+	}
+	n, err := io.Copy(w, r.Reader)
+	atomic.AddInt64(&r.n, n)
+	return n, err
 }
 
-func (r *CountReader) Read(p []byte) (int, error) {
-	n, err := r.Reader.Read(p)
-	atomic.AddInt64(&r.n, int64(n))
-	r.progress()
+// NewCountWriter creates a new CountWriter
+func NewCountWriter(writer io.Writer) *CountWriter {
+	return &CountWriter{
+		Writer:  writer,
+		counter: newCounter(),
+	}
+}
+
+// CountWriter counts the bytes it has written
+type CountWriter struct {
+	io.Writer
+	counter
+}
+
+func (w *CountWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.add(n)
+	return n, err
+}
+
+// ReadFrom lets io.Copy use the fast paths of the reader and the wrapped writer, like sendfile or splice from a pipe.
+// Without a progress callback the bytes are only counted once copying is done.
+func (w *CountWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.periodic() {
+		return io.Copy(struct{ io.Writer }{w}, r) // Count while copying
+	}
+
+	var n int64
+	var err error
+	if rf, ok := w.Writer.(io.ReaderFrom); ok {
+		// Before io.Copy tries the reader, as a file only splices into sockets and otherwise hides itself
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.Writer, r)
+	}
+	atomic.AddInt64(&w.n, n)
 	return n, err
 }
 
-func (r *CountReader) progress() {
-	if r.progressFn == nil || r.every <= 0 {
-		return
+type counter struct {
+	n       int64
+	started time.Time
+
+	progressFn    ProgressCallback
+	progressEvery time.Duration
+	progressLast  time.Time
+}
+
+func newCounter() counter {
+	return counter{
+		started: time.Now(),
 	}
-	if time.Since(r.last) < r.every {
-		return
+}
+
+// SetProgressCallback sets a new progress handler every duration
+func (c *counter) SetProgressCallback(every time.Duration, progressFn ProgressCallback) {
+	c.progressFn = progressFn
+	c.progressEvery = every
+}
+
+func (c *counter) add(n int) {
+	atomic.AddInt64(&c.n, int64(n))
+	c.progress()
+}
+
+// periodic returns whether the progress callback is called while counting
+func (c *counter) periodic() bool {
+	return c.progressFn != nil && c.progressEvery > 0
+}
+
+func (c *counter) progress() {
+	if c.periodic() && time.Since(c.progressLast) >= c.progressEvery {
+		c.progressFn(atomic.LoadInt64(&c.n))
+		c.progressLast = time.Now()
+	}
+}
+
+// Count returns the amount of bytes counted
+func (c *counter) Count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// Stats returns the amount of bytes counted and the time passed since counting started
+func (c *counter) Stats() StreamStats {
+	return StreamStats{
+		Bytes:    atomic.LoadInt64(&c.n),
+		Duration: time.Since(c.started),
+	}
+}
+
+// done returns the final stats, and passes the total to the progress callback
+func (c *counter) done() StreamStats {
+	stats := c.Stats()
+	if c.progressFn != nil {
+		c.progressFn(stats.Bytes)
+	}
+	return stats
+}
+
+// streamCounter returns a counter calling the progress callback of a send or receive stream every interval
+func streamCounter(every time.Duration, progressFn StreamProgressCallback) counter {
+	c := newCounter()
+	if progressFn != nil {
+		c.SetProgressCallback(every, func(bytes int64) {
+			progressFn(uint64(bytes))
+		})
+	}
+	return c
+}
+
+// countOutput wraps the writer to count the bytes written, calling the progress callback every interval when given.
+// The returned done function returns the totals, and calls the callback a final time with them.
+func countOutput(writer io.Writer, every time.Duration, progressFn StreamProgressCallback) (io.Writer, func() StreamStats) {
+	counter := &CountWriter{Writer: writer, counter: streamCounter(every, progressFn)}
+	return counter, counter.done
+}
+
+// countInput wraps the reader to count the bytes read, calling the progress callback every interval when given.
+// The returned done function returns the totals, and calls the callback a final time with them.
+func countInput(reader io.Reader, every time.Duration, progressFn StreamProgressCallback) (io.Reader, func() StreamStats) {
+	counter := &CountReader{Reader: reader, counter: streamCounter(every, progressFn)}
+	return counter, counter.done
+}
+
+// bufferChunkSize is the size of the chunks the stream buffers are divided in
+const bufferChunkSize = 128 * 1024
+
+// bufferWriter returns a writer that buffers up to roughly size bytes in memory, while a separate goroutine writes
+// them to the given writer. This decouples a bursty producer from the consumer.
+// The returned flush function must always be called, it waits for all buffered data to be written.
+func bufferWriter(writer io.Writer, size int) (io.Writer, func() error) {
+	if size <= 0 {
+		return writer, func() error { return nil }
+	}
+
+	w := newAsyncWriter(writer, size)
+	return w, w.flush
+}
+
+func newAsyncWriter(writer io.Writer, size int) *asyncWriter {
+	w := &asyncWriter{
+		writer: writer,
+		chunks: make(chan []byte, max(size/bufferChunkSize, 1)),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+type asyncWriter struct {
+	writer io.Writer
+	chunks chan []byte
+	done   chan struct{}
+	err    atomic.Pointer[error]
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for chunk := range w.chunks {
+		if w.err.Load() != nil {
+			continue // Drain the remaining chunks
+		}
+		_, err := w.writer.Write(chunk)
+		if err != nil {
+			w.err.Store(&err)
+		}
+	}
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if err := w.err.Load(); err != nil {
+			return written, *err
+		}
+
+		n := min(len(p), bufferChunkSize)
+		chunk := make([]byte, n)
+		copy(chunk, p[:n])
+		select {
+		case w.chunks <- chunk:
+		case <-w.done:
+			return written, io.ErrClosedPipe
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (w *asyncWriter) flush() error {
+	close(w.chunks)
+	<-w.done
+	if err := w.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// fanoutWriter writes everything to multiple writers concurrently, each with their own buffer.
+// A writer that fails is dropped without affecting the others, writing only fails when all writers have failed.
+type fanoutWriter struct {
+	writers []*asyncWriter
+	errs    []error
+}
+
+func newFanoutWriter(writers []io.Writer, size int) *fanoutWriter {
+	f := &fanoutWriter{
+		writers: make([]*asyncWriter, len(writers)),
+		errs:    make([]error, len(writers)),
+	}
+	for i := range writers {
+		f.writers[i] = newAsyncWriter(writers[i], size)
+	}
+	return f
+}
+
+func (f *fanoutWriter) Write(p []byte) (int, error) {
+	active := 0
+	for i, w := range f.writers {
+		if f.errs[i] != nil {
+			continue
+		}
+		_, err := w.Write(p)
+		if err != nil {
+			f.errs[i] = err
+			continue
+		}
+		active++
+	}
+	if active == 0 {
+		return 0, fmt.Errorf("all %d outputs failed: %w", len(f.writers), errors.Join(f.errs...))
+	}
+	return len(p), nil
+}
+
+// flush waits for all writers to finish and returns the error for every writer
+func (f *fanoutWriter) flush() []error {
+	for i, w := range f.writers {
+		err := w.flush()
+		if f.errs[i] == nil {
+			f.errs[i] = err
+		}
+	}
+	return f.errs
+}
+
+// bufferReader returns a reader that reads ahead up to roughly size bytes from the given reader in a separate
+// goroutine. This decouples a bursty producer from the consumer.
+// The returned stop function must always be called, it stops reading ahead.
+func bufferReader(reader io.Reader, size int) (io.Reader, func()) {
+	if size <= 0 {
+		return reader, func() {}
+	}
+
+	r := &asyncReader{
+		reader: reader,
+		chunks: make(chan []byte, max(size/bufferChunkSize, 1)),
+		stop:   make(chan struct{}),
+	}
+	go r.run()
+	return r, r.close
+}
+
+type asyncReader struct {
+	reader  io.Reader
+	chunks  chan []byte
+	stop    chan struct{}
+	once    sync.Once
+	err     error
+	current []byte
+}
+
+func (r *asyncReader) run() {
+	defer close(r.chunks)
+	for {
+		chunk := make([]byte, bufferChunkSize)
+		n, err := r.reader.Read(chunk)
+		if n > 0 {
+			select {
+			case r.chunks <- chunk[:n]:
+			case <-r.stop:
+				return
+			}
+		}
+		if err != nil {
+			// Only read after the chunks channel is closed, so this is safe
+			r.err = err
+			return
+		}
+	}
+}
+
+func (r *asyncReader) Read(p []byte) (int, error) {
+	if len(r.current) == 0 {
+		chunk, ok := <-r.chunks
+		if !ok && r.err == nil {
+			return 0, io.ErrClosedPipe // Stopped reading ahead
+		}
+		if !ok {
+			return 0, r.err
+		}
+		r.current = chunk
 	}
 
-	r.progressFn(atomic.LoadInt64(&r.n))
-	r.last = time.Now()
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+	return n, nil
 }
 
-func (r *CountReader) Count() int64 {
-	return atomic.LoadInt64(&r.n)
+func (r *asyncReader) close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
 }
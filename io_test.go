@@ -0,0 +1,145 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bufferWriter(t *testing.T) {
+	data := make([]byte, 3*bufferChunkSize+123)
+	_, _ = rand.Read(data)
+
+	var out bytes.Buffer
+	w, flush := bufferWriter(&out, 2*bufferChunkSize)
+	n, err := io.Copy(w, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.EqualValues(t, len(data), n)
+	require.NoError(t, flush())
+	require.Equal(t, data, out.Bytes())
+}
+
+func Test_bufferReader(t *testing.T) {
+	data := make([]byte, 3*bufferChunkSize+123)
+	_, _ = rand.Read(data)
+
+	r, stop := bufferReader(bytes.NewReader(data), 2*bufferChunkSize)
+	defer stop()
+	read, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, read)
+}
+
+func Test_countOutput(t *testing.T) {
+	var progress []uint64
+	w, done := countOutput(io.Discard, time.Hour, func(bytes uint64) {
+		progress = append(progress, bytes)
+	})
+
+	_, err := w.Write(make([]byte, 100))
+	require.NoError(t, err)
+	_, err = w.Write(make([]byte, 50))
+	require.NoError(t, err)
+	total := done()
+
+	require.Equal(t, []uint64{100, 150}, progress)
+	require.EqualValues(t, 150, total.Bytes)
+	require.Positive(t, total.Duration)
+	require.Positive(t, total.BytesPerSecond())
+
+	// Without a callback the bytes are still counted
+	w, done = countOutput(io.Discard, 0, nil)
+	_, err = w.Write(make([]byte, 10))
+	require.NoError(t, err)
+	require.EqualValues(t, 10, done().Bytes)
+}
+
+func Test_StreamStats(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+		switch args[0] {
+		case "send":
+			_, err := stdout.Write(make([]byte, 300))
+			return "", err
+		case "receive":
+			_, err := io.Copy(io.Discard, stdin)
+			return "", err
+		}
+		return "", nil
+	}))
+
+	snap := &Dataset{Name: "pool/fs@snap", Type: DatasetSnapshot}
+	stats, err := snap.SendSnapshot(ctx, io.Discard, SendOptions{})
+	require.NoError(t, err)
+	require.EqualValues(t, 300, stats.Bytes)
+	require.Positive(t, stats.Duration)
+
+	stats, err = ResumeSend(ctx, io.Discard, "token", ResumeSendOptions{})
+	require.NoError(t, err)
+	require.EqualValues(t, 300, stats.Bytes)
+
+	var received uint64
+	ds, stats, err := ReceiveSnapshot(ctx, bytes.NewReader(make([]byte, 200)), "pool/fs@snap", ReceiveOptions{
+		SkipRefetch: true,
+		ProgressFn: func(bytes uint64) {
+			received = bytes
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pool/fs@snap", ds.Name)
+	require.EqualValues(t, 200, stats.Bytes)
+	require.EqualValues(t, 200, received)
+}
+
+func Test_countInputProgress(t *testing.T) {
+	var progress []uint64
+	r, done := countInput(bytes.NewReader(make([]byte, 150)), time.Hour, func(bytes uint64) {
+		progress = append(progress, bytes)
+	})
+
+	_, err := io.ReadAll(r)
+	require.NoError(t, err)
+	stats := done()
+
+	require.Len(t, progress, 2)
+	require.EqualValues(t, 150, progress[1])
+	require.EqualValues(t, 150, stats.Bytes)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func Test_fanoutWriter(t *testing.T) {
+	data := make([]byte, 3*bufferChunkSize+123)
+	_, _ = rand.Read(data)
+
+	var out1, out2 bytes.Buffer
+	f := newFanoutWriter([]io.Writer{&out1, failingWriter{}, &out2}, bufferChunkSize)
+	n, err := io.Copy(f, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.EqualValues(t, len(data), n)
+
+	errs := f.flush()
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], io.ErrShortWrite)
+	require.NoError(t, errs[2])
+	require.Equal(t, data, out1.Bytes())
+	require.Equal(t, data, out2.Bytes())
+
+	// The failure is noticed asynchronously, so keep writing until it surfaces
+	f = newFanoutWriter([]io.Writer{failingWriter{}}, bufferChunkSize)
+	require.Eventually(t, func() bool {
+		_, err = f.Write(data)
+		return err != nil
+	}, time.Second, time.Millisecond)
+	require.ErrorIs(t, err, io.ErrShortWrite)
+	require.ErrorIs(t, f.flush()[0], io.ErrShortWrite)
+}
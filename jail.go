@@ -0,0 +1,59 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoJail is returned when no jail was given to attach a dataset to or detach it from
+var ErrNoJail = errors.New("no jail given")
+
+// Jail attaches the filesystem and its children to a FreeBSD jail, given by its ID or name, so they can be managed
+// from within the jail. The jailed property is set first, as zfs requires it. Jails are only available on FreeBSD.
+func (d *Dataset) Jail(ctx context.Context, jailID string) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	if jailID == "" {
+		return ErrNoJail
+	}
+	err := d.SetJailed(ctx, true)
+	if err != nil {
+		return err
+	}
+	return zfs(ctx, "jail", jailID, d.Name)
+}
+
+// Unjail detaches the filesystem and its children from a FreeBSD jail, given by its ID or name.
+// The jailed property stays set, see SetJailed.
+func (d *Dataset) Unjail(ctx context.Context, jailID string) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	if jailID == "" {
+		return ErrNoJail
+	}
+	return zfs(ctx, "unjail", jailID, d.Name)
+}
+
+// SetJailed sets or clears the jailed property. A jailed filesystem cannot be mounted on the host, as the jail may
+// have changed its mountpoint. Check the mountpoint before clearing the property of a filesystem that was jailed.
+func (d *Dataset) SetJailed(ctx context.Context, jailed bool) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	value := "off"
+	if jailed {
+		value = "on"
+	}
+	return d.SetProperty(ctx, PropertyJailed, value)
+}
+
+// Jailed returns whether the jailed property is set
+func (d *Dataset) Jailed(ctx context.Context) (bool, error) {
+	value, err := d.GetProperty(ctx, PropertyJailed)
+	if err != nil {
+		return false, err
+	}
+	return value == "on", nil
+}
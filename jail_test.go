@@ -0,0 +1,40 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Jail(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[0] == "get" {
+			_, err := io.WriteString(stdout, "on\n")
+			return "", err
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	ds := &Dataset{Name: "pool/jails/www", Type: DatasetFilesystem}
+	require.NoError(t, ds.Jail(ctx, "www"))
+	require.NoError(t, ds.Unjail(ctx, "12"))
+	require.Equal(t, [][]string{
+		{"set", "jailed=on", "pool/jails/www"},
+		{"jail", "www", "pool/jails/www"},
+		{"unjail", "12", "pool/jails/www"},
+	}, executed)
+
+	jailed, err := ds.Jailed(ctx)
+	require.NoError(t, err)
+	require.True(t, jailed)
+
+	require.ErrorIs(t, ds.Jail(ctx, ""), ErrNoJail)
+	snap := &Dataset{Name: "pool/jails/www@snap", Type: DatasetSnapshot}
+	require.ErrorIs(t, snap.Jail(ctx, "www"), ErrSnapshotsNotSupported)
+}
@@ -0,0 +1,182 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/objectstore"
+)
+
+// archivePrefix marks a send to property value as a file archive directory instead of a server URL
+const archivePrefix = "file://"
+
+// archiveDir returns the directory of a file archive send target, like file:///mnt/backup
+func archiveDir(sendTo string) (string, bool) {
+	return strings.CutPrefix(sendTo, archivePrefix)
+}
+
+func (r *Runner) archiveTarget(dir string) (*objectstore.Target, error) {
+	store, err := objectstore.NewDirStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive directory %s: %w", dir, err)
+	}
+
+	conf := objectstore.TargetConfig{}
+	conf.ApplyDefaults()
+	conf.ChunkSize = r.config.ArchiveChunkSize
+	conf.EncryptionKey = r.config.ArchiveEncryptionKey
+	return objectstore.NewTarget(store, conf, r.logger)
+}
+
+// archiveDatasetSnapshots writes the snapshots newer than the last archived one to the file archive, incrementally
+// upon the previous snapshot until ArchiveFullEvery incremental streams are written. When the last archived snapshot
+// no longer exists locally, a new chain is started with a full stream of the newest snapshot.
+func (r *Runner) archiveDatasetSnapshots(ds *zfs.Dataset, sendTo string, localSnaps []zfs.Dataset) error {
+	if len(localSnaps) == 0 {
+		return nil // Nothing to do
+	}
+
+	dir, _ := archiveDir(sendTo)
+	target, err := r.archiveTarget(dir)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := target.Manifest(r.ctx, ds.Name)
+	if err != nil {
+		return err
+	}
+
+	toArchive := localSnaps
+	var base *zfs.Dataset
+	incrementals := 0
+	if latest := manifest.Latest(); latest != nil {
+		idx := slices.IndexFunc(localSnaps, func(snap zfs.Dataset) bool {
+			return snapshotName(snap.Name) == latest.Name
+		})
+		if idx >= 0 {
+			chain, err := manifest.Chain(latest.Name)
+			if err != nil {
+				return err
+			}
+			toArchive = localSnaps[idx+1:]
+			base = &localSnaps[idx]
+			incrementals = len(chain) - 1
+		} else {
+			toArchive = localSnaps[len(localSnaps)-1:]
+		}
+	}
+
+	sentProp := r.config.Properties.snapshotSentAt()
+	for i := range toArchive {
+		if r.ctx.Err() != nil {
+			return nil // context expired, no problem
+		}
+		snap := &toArchive[i]
+
+		if base != nil && r.config.ArchiveFullEvery > 0 && incrementals >= r.config.ArchiveFullEvery {
+			base = nil
+		}
+		err = r.archiveSnapshot(target, snap, base, sendTo)
+		if err != nil {
+			return err
+		}
+		if base == nil {
+			incrementals = 0
+		} else {
+			incrementals++
+		}
+		base = snap
+
+		err = snap.SetProperty(r.ctx, sentProp, time.Now().Format(dateTimeFormat))
+		switch {
+		case errors.Is(err, zfs.ErrDatasetNotFound):
+			r.logger.Warn("zfs.job.Runner.archiveDatasetSnapshots: Dataset not found, did not set sent property",
+				"snapshot", snap.Name, "property", sentProp,
+			)
+		case err != nil:
+			return fmt.Errorf("error setting %s property on %s after archiving: %w", sentProp, snap.Name, err)
+		}
+	}
+
+	if r.config.ArchiveKeepChains <= 0 {
+		return nil
+	}
+	deleted, err := target.Rotate(r.ctx, ds.Name, r.config.ArchiveKeepChains)
+	if err != nil {
+		return fmt.Errorf("error rotating archive of %s: %w", ds.Name, err)
+	}
+	if len(deleted) > 0 {
+		r.logger.Info("zfs.job.Runner.archiveDatasetSnapshots: Archive rotated",
+			"dataset", ds.Name,
+			"target", sendTo,
+			"deleted", deleted,
+		)
+	}
+	return nil
+}
+
+func (r *Runner) archiveSnapshot(target *objectstore.Target, snap, base *zfs.Dataset, sendTo string) error {
+	r.logger.Debug("zfs.job.Runner.archiveSnapshot: Archiving snapshot",
+		"snapshot", snap.Name,
+		"target", sendTo,
+		"incremental", base != nil,
+	)
+
+	now := time.Now()
+	ctx, span := zfs.StartSpan(r.ctx, "zfs.job archive snapshot",
+		zfs.Attribute{Key: "zfs.snapshot", Value: snap.Name},
+		zfs.Attribute{Key: "zfs.server", Value: sendTo},
+	)
+	ctx, cancel := context.WithTimeout(ctx, r.config.maximumSendTime())
+	sending := &zfsSend{
+		dataset: snap.Name,
+		server:  sendTo,
+		updated: now,
+		started: now,
+		cancel:  cancel,
+	}
+
+	r.setSendingState(sending)
+	defer func() {
+		r.clearSendingState(sending)
+	}()
+
+	r.EmitEvent(StartSendingSnapshotEvent, snap.Name, sendTo)
+
+	stored, err := target.Send(ctx, snap, zfs.SendOptions{
+		Raw:               r.config.SendRaw,
+		IncludeProperties: r.config.SendIncludeProperties,
+		IncrementalBase:   base,
+		BytesPerSecond:    r.config.SendSpeedBytesPerSecond,
+		CompressionLevel:  r.config.SendCompressionLevel,
+		BufferSize:        r.config.SendBufferSize,
+		ExternalBuffer:    r.config.SendExternalBuffer,
+		ProgressEvery:     r.config.sendProgressInterval(),
+		ProgressFn: func(bytes uint64) {
+			r.EmitEvent(SnapshotSendingProgressEvent, snap.Name, sendTo, int64(bytes))
+		},
+	})
+	cancel()
+	span.End(err)
+	if err != nil {
+		r.EmitEvent(SendSnapshotErrorEvent, snap.Name, sendTo, err)
+		return fmt.Errorf("error archiving %s: %w", snap.Name, err)
+	}
+	timeTaken := time.Since(now)
+
+	r.logger.Debug("zfs.job.Runner.archiveSnapshot: Snapshot archived",
+		"snapshot", snap.Name,
+		"target", sendTo,
+		"bytesSent", stored.Size,
+		"timeTaken", timeTaken.String(),
+	)
+
+	r.EmitEvent(SentSnapshotEvent, snap.Name, sendTo, stored.Size, timeTaken)
+	return nil
+}
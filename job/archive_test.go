@@ -0,0 +1,61 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+func Test_archiveDir(t *testing.T) {
+	dir, ok := archiveDir("file:///mnt/backup")
+	require.True(t, ok)
+	require.Equal(t, "/mnt/backup", dir)
+
+	_, ok = archiveDir("http://localhost:1337/zfs")
+	require.False(t, ok)
+}
+
+func TestRunner_archiveSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	sendTest(t, func(_ string, runner *Runner) {
+		runner.config.ArchiveFullEvery = 2
+		runner.config.ArchiveKeepChains = 1
+
+		sendToProp := runner.config.Properties.snapshotSendTo()
+		ds, err := zfs.GetDataset(context.Background(), testFilesystem)
+		require.NoError(t, err)
+		err = ds.SetProperty(context.Background(), sendToProp, archivePrefix+dir)
+		require.NoError(t, err)
+		ds, err = zfs.GetDataset(context.Background(), testFilesystem, sendToProp)
+		require.NoError(t, err)
+
+		err = runner.sendDatasetSnapshots(ds)
+		require.NoError(t, err)
+
+		target, err := runner.archiveTarget(dir)
+		require.NoError(t, err)
+		manifest, err := target.Manifest(context.Background(), testFilesystem)
+		require.NoError(t, err)
+
+		// Chains snap1-snap3 and snap4-snap5, of which the first is rotated
+		require.Len(t, manifest.Snapshots, 2)
+		require.Equal(t, sendSnaps[3], manifest.Snapshots[0].Name)
+		require.Empty(t, manifest.Snapshots[0].Base)
+		require.Equal(t, sendSnaps[4], manifest.Snapshots[1].Name)
+		require.Equal(t, sendSnaps[3], manifest.Snapshots[1].Base)
+
+		chain, err := manifest.Chain(sendSnaps[4])
+		require.NoError(t, err)
+		require.Len(t, chain, 2)
+
+		// Another run has nothing new to archive
+		err = runner.sendDatasetSnapshots(ds)
+		require.NoError(t, err)
+		manifest, err = target.Manifest(context.Background(), testFilesystem)
+		require.NoError(t, err)
+		require.Len(t, manifest.Snapshots, 2)
+	})
+}
@@ -16,6 +16,10 @@ const (
 	defaultSendRoutines                         = 3
 	defaultSendProgressEventIntervalSeconds     = 5 * 60  // 5 minutes
 	defaultMaximumRemoteSnapshotCacheAgeSeconds = 30 * 60 // 30 minutes
+	defaultMaximumLocalSnapshotCacheAgeSeconds  = 4 * 60  // 4 minutes, less than the snapshot create interval
+	defaultSendBufferSize                       = 4 * 1024 * 1024
+	defaultArchiveChunkSize                     = 1024 * 1024 * 1024 // 1 GiB
+	defaultScrubIntervalHours                   = 30 * 24            // 30 days
 )
 
 // Config configures the runner
@@ -25,12 +29,23 @@ type Config struct {
 	HTTPHeaders          map[string]string `json:"HTTPHeaders" yaml:"HTTPHeaders"`
 	SnapshotNameTemplate string            `json:"SnapshotNameTemplate" yaml:"SnapshotNameTemplate"`
 
+	// SanoidCompatible creates snapshots with sanoid style names, like autosnap_2024-01-02_15:04:05_hourly, instead of
+	// using the name template. Snapshots without the created property get their creation time from their sanoid name,
+	// so the retention of datasets previously managed by sanoid applies to their existing snapshots as well.
+	SanoidCompatible bool `json:"SanoidCompatible" yaml:"SanoidCompatible"`
+
 	EnableSnapshotCreate     bool `json:"EnableSnapshotCreate" yaml:"EnableSnapshotCreate"`
 	EnableSnapshotSend       bool `json:"EnableSnapshotSend" yaml:"EnableSnapshotSend"`
 	EnableSnapshotMark       bool `json:"EnableSnapshotMark" yaml:"EnableSnapshotMark"`
 	EnableSnapshotMarkRemote bool `json:"EnableSnapshotMarkRemote" yaml:"EnableSnapshotMarkRemote"`
 	EnableSnapshotPrune      bool `json:"EnableSnapshotPrune" yaml:"EnableSnapshotPrune"`
 	EnableFilesystemPrune    bool `json:"EnableFilesystemPrune" yaml:"EnableFilesystemPrune"`
+	EnablePoolScrub          bool `json:"EnablePoolScrub" yaml:"EnablePoolScrub"`
+
+	// ScrubPools are the pools to scrub, empty for the pool of the parent dataset
+	ScrubPools []string `json:"ScrubPools" yaml:"ScrubPools"`
+	// ScrubIntervalHours is the time between the end of a scrub and the start of the next one
+	ScrubIntervalHours int64 `json:"ScrubIntervalHours" yaml:"ScrubIntervalHours"`
 
 	SendRoutines          int  `json:"SendRoutines" yaml:"SendRoutines"`
 	SendResumable         bool `json:"SendResumable" yaml:"SendResumable"`
@@ -48,10 +63,26 @@ type Config struct {
 
 	SendCompressionLevel                 zstd.EncoderLevel `json:"SendCompressionLevel" yaml:"SendCompressionLevel"`
 	SendSpeedBytesPerSecond              int64             `json:"SendSpeedBytesPerSecond" yaml:"SendSpeedBytesPerSecond"`
+	SendBufferSize                       int               `json:"SendBufferSize" yaml:"SendBufferSize"`
 	SendProgressEventIntervalSeconds     int64             `json:"SendProgressEventIntervalSeconds" yaml:"SendProgressEventIntervalSeconds"`
 	SendReceiveForceRollback             bool              `json:"SendReceiveForceRollback" yaml:"SendReceiveForceRollback"`
 	MaximumSendTimeSeconds               int64             `json:"MaximumSendTimeSeconds" yaml:"MaximumSendTimeSeconds"`
 	MaximumRemoteSnapshotCacheAgeSeconds int64             `json:"MaximumRemoteSnapshotCacheAgeSeconds" yaml:"MaximumRemoteSnapshotCacheAgeSeconds"`
+	MaximumLocalSnapshotCacheAgeSeconds  int64             `json:"MaximumLocalSnapshotCacheAgeSeconds" yaml:"MaximumLocalSnapshotCacheAgeSeconds"`
+
+	// SendExternalBuffer runs an external program like mbuffer between zfs send and the connection, nil for none
+	SendExternalBuffer *zfs.ExternalBuffer `json:"SendExternalBuffer" yaml:"SendExternalBuffer"`
+
+	// ArchiveFullEvery sets after how many incremental streams a new full stream is written to a file archive,
+	// zero to only write a full stream when nothing is archived yet
+	ArchiveFullEvery int `json:"ArchiveFullEvery" yaml:"ArchiveFullEvery"`
+	// ArchiveKeepChains is the amount of full streams with their incremental streams kept in a file archive,
+	// zero to keep all of them
+	ArchiveKeepChains int `json:"ArchiveKeepChains" yaml:"ArchiveKeepChains"`
+	// ArchiveChunkSize is the maximum size of the files a stream is split into
+	ArchiveChunkSize int64 `json:"ArchiveChunkSize" yaml:"ArchiveChunkSize"`
+	// ArchiveEncryptionKey is the hex encoded AES-256 key to encrypt the archived streams with, empty to not encrypt them
+	ArchiveEncryptionKey string `json:"ArchiveEncryptionKey" yaml:"ArchiveEncryptionKey"`
 
 	Properties Properties `json:"Properties" yaml:"Properties"`
 }
@@ -63,19 +94,25 @@ func (c *Config) ApplyDefaults() {
 	c.MaximumSendTimeSeconds = defaultMaximumSendTimeSeconds
 	c.SendProgressEventIntervalSeconds = defaultSendProgressEventIntervalSeconds
 	c.MaximumRemoteSnapshotCacheAgeSeconds = defaultMaximumRemoteSnapshotCacheAgeSeconds
+	c.MaximumLocalSnapshotCacheAgeSeconds = defaultMaximumLocalSnapshotCacheAgeSeconds
 
 	c.EnableSnapshotCreate = true
 	c.EnableSnapshotSend = true
 	c.EnableSnapshotMark = true
 	c.EnableSnapshotPrune = true
 	c.EnableFilesystemPrune = false
+	c.EnablePoolScrub = false
+	c.ScrubIntervalHours = defaultScrubIntervalHours
 
 	c.SnapshotRetentionCountIgnoreWithoutCreated = true
 
 	c.SendRoutines = defaultSendRoutines
+	c.SendBufferSize = defaultSendBufferSize
 	c.SendRaw = true
 	c.SendIncludeProperties = false
 
+	c.ArchiveChunkSize = defaultArchiveChunkSize
+
 	c.Properties.ApplyDefaults()
 
 	c.SendCopySnapshotProperties = []string{
@@ -95,6 +132,21 @@ func (c *Config) maximumRemoteSnapshotCacheAge() time.Duration {
 	return time.Duration(c.MaximumRemoteSnapshotCacheAgeSeconds) * time.Second
 }
 
+func (c *Config) maximumLocalSnapshotCacheAge() time.Duration {
+	return time.Duration(c.MaximumLocalSnapshotCacheAgeSeconds) * time.Second
+}
+
+func (c *Config) scrubInterval() time.Duration {
+	return time.Duration(c.ScrubIntervalHours) * time.Hour
+}
+
+func (c *Config) scrubPools() []string {
+	if len(c.ScrubPools) > 0 {
+		return c.ScrubPools
+	}
+	return []string{poolName(c.ParentDataset)}
+}
+
 func (c *Config) sendSetProperties() map[string]string {
 	props := make(map[string]string, len(c.SendSetProperties)+len(c.SendCopyProperties))
 	for k, v := range c.SendSetProperties {
@@ -12,4 +12,20 @@ const (
 	MarkSnapshotDeletionEvent    eventemitter.EventType = "mark-snapshot-deletion"
 	DeletedSnapshotEvent         eventemitter.EventType = "deleted-snapshot"
 	DeletedFilesystemEvent       eventemitter.EventType = "deleted-filesystem"
+	StartedScrubEvent            eventemitter.EventType = "started-scrub"
+	ScrubProgressEvent           eventemitter.EventType = "scrub-progress"
+	CompletedScrubEvent          eventemitter.EventType = "completed-scrub"
+	ScrubErrorEvent              eventemitter.EventType = "scrub-error"
+	// JobCompletedEvent is emitted every time a job ran without errors, with the job name as argument
+	JobCompletedEvent eventemitter.EventType = "job-completed"
+)
+
+// Job names, passed as argument of the JobCompletedEvent
+const (
+	JobCreateSnapshots  = "create snapshots"
+	JobSendSnapshots    = "send snapshots"
+	JobMarkSnapshots    = "mark snapshots"
+	JobPruneSnapshots   = "prune snapshots"
+	JobPruneFilesystems = "prune filesystems"
+	JobScrubPools       = "scrub pools"
 )
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
 )
@@ -24,18 +23,24 @@ func (r *Runner) pruneFilesystems() error {
 		return fmt.Errorf("error finding prunable old filesystems: %w", err)
 	}
 
-	for filesystem := range filesystems {
+	datasets, err := r.getDatasets(datasetNames(filesystems), deleteProp)
+	if err != nil {
+		return fmt.Errorf("error retrieving prunable old filesystems: %w", err)
+	}
+
+	for i := range datasets {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		err = r.pruneAgedFilesystem(filesystem)
+		fs := &datasets[i]
+		err = r.pruneAgedFilesystem(fs)
 		switch {
 		case isContextError(err):
-			r.logger.Info("zfs.job.Runner.pruneFilesystems: Prune filesystem job interrupted", "error", err, "dataset", filesystem)
+			r.logger.Info("zfs.job.Runner.pruneFilesystems: Prune filesystem job interrupted", "error", err, "dataset", fs.Name)
 			return nil // Return no error
 		case err != nil:
-			r.logger.Error("zfs.job.Runner.pruneFilesystems: Error pruning aged filesystems", "error", err, "dataset", filesystem)
+			r.logger.Error("zfs.job.Runner.pruneFilesystems: Error pruning aged filesystems", "error", err, "dataset", fs.Name)
 			continue // on to the next dataset :-/
 		}
 	}
@@ -53,18 +58,24 @@ func (r *Runner) pruneFilesystems() error {
 		return fmt.Errorf("error finding prunable filesystems without snapshots: %w", err)
 	}
 
-	for filesystem := range filesystems {
+	datasets, err = r.getDatasets(datasetNames(filesystems), deleteWithoutSnaps)
+	if err != nil {
+		return fmt.Errorf("error retrieving prunable filesystems without snapshots: %w", err)
+	}
+
+	for i := range datasets {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		err = r.pruneFilesystemWithoutSnapshots(filesystem)
+		fs := &datasets[i]
+		err = r.pruneFilesystemWithoutSnapshots(fs)
 		switch {
 		case isContextError(err):
-			r.logger.Info("zfs.job.Runner.pruneFilesystems: Prune filesystem job interrupted", "error", err, "dataset", filesystem)
+			r.logger.Info("zfs.job.Runner.pruneFilesystems: Prune filesystem job interrupted", "error", err, "dataset", fs.Name)
 			return nil // Return no error
 		case err != nil:
-			r.logger.Error("zfs.job.Runner.pruneFilesystems: Error pruning filesystems without snapshots", "error", err, "dataset", filesystem)
+			r.logger.Error("zfs.job.Runner.pruneFilesystems: Error pruning filesystems without snapshots", "error", err, "dataset", fs.Name)
 			continue // on to the next dataset :-/
 		}
 	}
@@ -72,7 +83,16 @@ func (r *Runner) pruneFilesystems() error {
 	return nil
 }
 
-func (r *Runner) pruneAgedFilesystem(filesystem string) error {
+func (r *Runner) pruneAgedFilesystem(fs *zfs.Dataset) error {
+	if fs.Type != zfs.DatasetFilesystem {
+		return fmt.Errorf("unexpected dataset type %s for %s", fs.Type, fs.Name)
+	}
+	due, _, err := r.deleteDue(fs)
+	if err != nil || !due {
+		return err
+	}
+
+	filesystem := fs.Name
 	locked, unlock := r.lockDataset(filesystem)
 	if !locked {
 		return nil // Some other goroutine is doing something with this dataset already, continue to next.
@@ -82,28 +102,17 @@ func (r *Runner) pruneAgedFilesystem(filesystem string) error {
 		unlock()
 	}()
 
-	deleteProp := r.config.Properties.deleteAt()
-
-	fs, err := zfs.GetDataset(r.ctx, filesystem, deleteProp)
-	if err != nil {
-		return fmt.Errorf("error getting filesystem %s: %w", filesystem, err)
-	}
-
-	if fs.Type != zfs.DatasetFilesystem {
-		return fmt.Errorf("unexpected dataset type %s for %s: %w", fs.Type, filesystem, err)
-	}
-
-	if !propertyIsSet(fs.ExtraProps[deleteProp]) {
-		return nil
-	}
-
-	deleteAt, err := parseDatasetTimeProperty(fs, deleteProp)
-	if err != nil {
-		return fmt.Errorf("error parsing %s on %s: %w", deleteProp, filesystem, err)
+	// Check the property again now the dataset is locked, it could have changed since the batched lookup
+	fs, err = zfs.GetDataset(r.ctx, filesystem, r.config.Properties.deleteAt())
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		return nil // Filesystem was removed meanwhile
+	case err != nil:
+		return fmt.Errorf("error retrieving %s: %w", filesystem, err)
 	}
-
-	if deleteAt.After(time.Now()) {
-		return nil // Not due for removal yet
+	due, deleteAt, err := r.deleteDue(fs)
+	if err != nil || !due {
+		return err
 	}
 
 	children, err := fs.Children(r.ctx, zfs.ListOptions{})
@@ -131,7 +140,15 @@ func (r *Runner) pruneAgedFilesystem(filesystem string) error {
 	return nil
 }
 
-func (r *Runner) pruneFilesystemWithoutSnapshots(filesystem string) error {
+func (r *Runner) pruneFilesystemWithoutSnapshots(fs *zfs.Dataset) error {
+	if fs.Type != zfs.DatasetFilesystem {
+		return fmt.Errorf("unexpected dataset type %s for %s", fs.Type, fs.Name)
+	}
+	if !r.deleteWithoutSnapshots(fs) {
+		return nil
+	}
+
+	filesystem := fs.Name
 	locked, unlock := r.lockDataset(filesystem)
 	if !locked {
 		return nil // Some other goroutine is doing something with this dataset already, continue to next.
@@ -141,23 +158,15 @@ func (r *Runner) pruneFilesystemWithoutSnapshots(filesystem string) error {
 		unlock()
 	}()
 
-	deleteWithoutSnaps := r.config.Properties.deleteWithoutSnapshots()
-
-	fs, err := zfs.GetDataset(r.ctx, filesystem, deleteWithoutSnaps)
-	if err != nil {
-		return fmt.Errorf("error getting filesystem %s: %w", filesystem, err)
-	}
-
-	if fs.Type != zfs.DatasetFilesystem {
-		return fmt.Errorf("unexpected dataset type %s for %s: %w", fs.Type, filesystem, err)
-	}
-
-	if !propertyIsSet(fs.ExtraProps[deleteWithoutSnaps]) {
-		return nil
+	// Check the property again now the dataset is locked, it could have changed since the batched lookup
+	fs, err := zfs.GetDataset(r.ctx, filesystem, r.config.Properties.deleteWithoutSnapshots())
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		return nil // Filesystem was removed meanwhile
+	case err != nil:
+		return fmt.Errorf("error retrieving %s: %w", filesystem, err)
 	}
-
-	shouldDelete, _ := strconv.ParseBool(fs.ExtraProps[deleteWithoutSnaps])
-	if !shouldDelete {
+	if !r.deleteWithoutSnapshots(fs) {
 		return nil
 	}
 
@@ -182,3 +191,9 @@ func (r *Runner) pruneFilesystemWithoutSnapshots(filesystem string) error {
 
 	return nil
 }
+
+// deleteWithoutSnapshots returns whether the delete without snapshots property of the filesystem is set to true
+func (r *Runner) deleteWithoutSnapshots(fs *zfs.Dataset) bool {
+	shouldDelete, _ := strconv.ParseBool(fs.ExtraProps[r.config.Properties.deleteWithoutSnapshots()])
+	return shouldDelete
+}
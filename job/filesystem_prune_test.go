@@ -3,10 +3,13 @@ package job
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
 
 	"github.com/stretchr/testify/require"
 )
@@ -80,3 +83,48 @@ func TestRunner_pruneFilesystems(t *testing.T) {
 		require.Equal(t, fmt.Sprintf("%s/%s", testZPool, deleteLater), datasets[4].Name)
 	})
 }
+
+func TestRunner_pruneFilesystemsStale(t *testing.T) {
+	zfsfake.Install(t, "tank")
+	ctx := context.Background()
+
+	conf := Config{}
+	conf.ApplyDefaults()
+	conf.ParentDataset = "tank"
+	r := NewRunner(ctx, conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	deleteProp := r.config.Properties.deleteAt()
+	withoutSnapsProp := r.config.Properties.deleteWithoutSnapshots()
+
+	past := time.Now().Add(-time.Minute).Format(dateTimeFormat)
+	names := []string{"tank/fs1", "tank/fs2", "tank/fs3"}
+	for _, name := range names {
+		_, err := zfs.CreateFilesystem(ctx, name, zfs.CreateFilesystemOptions{Properties: map[string]string{
+			deleteProp:       past,
+			withoutSnapsProp: "true",
+		}})
+		require.NoError(t, err)
+	}
+	aged, err := zfs.GetDatasets(ctx, names, deleteProp)
+	require.NoError(t, err)
+	withoutSnaps, err := zfs.GetDatasets(ctx, names, withoutSnapsProp)
+	require.NoError(t, err)
+
+	// The retrieved properties are stale, they are checked again once the dataset is locked
+	require.NoError(t, aged[1].SetProperty(ctx, deleteProp, time.Now().Add(time.Hour).Format(dateTimeFormat)))
+	require.NoError(t, aged[1].SetProperty(ctx, withoutSnapsProp, "false"))
+	require.NoError(t, aged[2].Destroy(ctx, zfs.DestroyOptions{}))
+
+	for i := range aged {
+		require.NoError(t, r.pruneAgedFilesystem(&aged[i]))
+	}
+	exists, err := zfs.DatasetExists(ctx, "tank/fs1", zfs.DatasetFilesystem)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	for i := range withoutSnaps {
+		require.NoError(t, r.pruneFilesystemWithoutSnapshots(&withoutSnaps[i]))
+	}
+	exists, err = zfs.DatasetExists(ctx, "tank/fs2", zfs.DatasetFilesystem)
+	require.NoError(t, err)
+	require.True(t, exists)
+}
@@ -0,0 +1,118 @@
+package job
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// localSnapshotProperties returns the properties retrieved for local snapshots, which are those needed by all jobs
+func (r *Runner) localSnapshotProperties() []string {
+	return []string{
+		r.config.Properties.snapshotCreatedAt(),
+		r.config.Properties.snapshotIgnoreCreate(),
+		r.config.Properties.snapshotSendTo(),
+		r.config.Properties.snapshotIgnoreSend(),
+		r.config.Properties.snapshotIgnoreCountPrune(),
+		r.config.Properties.snapshotIgnoreMinutesPrune(),
+		r.config.Properties.deleteAt(),
+	}
+}
+
+// localDatasetSnapshots retrieves the snapshots of a local dataset. The list is shared between the jobs and kept for
+// a time, the jobs update it with the changes they make themselves. The caller gets its own copy of the list.
+func (r *Runner) localDatasetSnapshots(dataset string) ([]zfs.Dataset, error) {
+	r.localCacheLock.Lock()
+	dsCache, ok := r.localCache[dataset]
+	if ok && time.Since(dsCache.cachedAt) < r.config.maximumLocalSnapshotCacheAge() {
+		snaps := cloneSnapshots(dsCache.snapshots)
+		r.localCacheLock.Unlock()
+		return snaps, nil
+	}
+	r.localCacheLock.Unlock()
+
+	snaps, err := zfs.ListSnapshots(r.ctx, zfs.ListOptions{
+		ParentDataset:   dataset,
+		ExtraProperties: r.localSnapshotProperties(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.localCacheLock.Lock()
+	r.localCache[dataset] = &datasetCache{
+		cachedAt:  time.Now(),
+		snapshots: cloneSnapshots(snaps),
+	}
+	r.localCacheLock.Unlock()
+	return snaps, nil
+}
+
+// addLocalSnapshot adds a newly created snapshot to the cached snapshots of its dataset
+func (r *Runner) addLocalSnapshot(snap zfs.Dataset) {
+	r.localCacheLock.Lock()
+	defer r.localCacheLock.Unlock()
+
+	dsCache, ok := r.localCache[stripDatasetSnapshot(snap.Name)]
+	if !ok {
+		return // Not cached, will be retrieved next time
+	}
+	snap.ExtraProps = maps.Clone(snap.ExtraProps)
+	dsCache.snapshots = append(dsCache.snapshots, snap)
+}
+
+// setLocalSnapshotProperty updates a property of a snapshot in the cached snapshots of its dataset
+func (r *Runner) setLocalSnapshotProperty(snapName, prop, value string) {
+	r.localCacheLock.Lock()
+	defer r.localCacheLock.Unlock()
+
+	dsCache, ok := r.localCache[stripDatasetSnapshot(snapName)]
+	if !ok {
+		return
+	}
+	idx := slices.IndexFunc(dsCache.snapshots, func(snap zfs.Dataset) bool {
+		return snap.Name == snapName
+	})
+	if idx < 0 {
+		return
+	}
+	if dsCache.snapshots[idx].ExtraProps == nil {
+		dsCache.snapshots[idx].ExtraProps = make(map[string]string, 1)
+	}
+	dsCache.snapshots[idx].ExtraProps[prop] = value
+}
+
+// removeLocalSnapshot removes a destroyed snapshot from the cached snapshots of its dataset
+func (r *Runner) removeLocalSnapshot(snapName string) {
+	r.localCacheLock.Lock()
+	defer r.localCacheLock.Unlock()
+
+	dsCache, ok := r.localCache[stripDatasetSnapshot(snapName)]
+	if !ok {
+		return
+	}
+	dsCache.snapshots = slices.DeleteFunc(dsCache.snapshots, func(snap zfs.Dataset) bool {
+		return snap.Name == snapName
+	})
+}
+
+func (r *Runner) pruneLocalSnapshotCache() {
+	r.localCacheLock.Lock()
+	defer r.localCacheLock.Unlock()
+
+	for dataset, dsCache := range r.localCache {
+		if time.Since(dsCache.cachedAt) >= r.config.maximumLocalSnapshotCacheAge() {
+			delete(r.localCache, dataset)
+		}
+	}
+}
+
+func cloneSnapshots(list []zfs.Dataset) []zfs.Dataset {
+	cloned := slices.Clone(list)
+	for i := range cloned {
+		cloned[i].ExtraProps = maps.Clone(list[i].ExtraProps)
+	}
+	return cloned
+}
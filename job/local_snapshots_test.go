@@ -0,0 +1,48 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+func TestRunner_localDatasetSnapshots(t *testing.T) {
+	runnerTest(t, func(url string, runner *Runner) {
+		runner.config.MaximumLocalSnapshotCacheAgeSeconds = 60
+		deleteProp := runner.config.Properties.deleteAt()
+
+		ds, err := zfs.GetDataset(context.Background(), testFilesystem)
+		require.NoError(t, err)
+		snap1, err := ds.Snapshot(context.Background(), "snap1", zfs.SnapshotOptions{})
+		require.NoError(t, err)
+
+		snaps, err := runner.localDatasetSnapshots(testFilesystem)
+		require.NoError(t, err)
+		require.Len(t, snaps, 1)
+		require.Equal(t, snap1.Name, snaps[0].Name)
+
+		// Snapshots made outside the runner are not seen until the list expires
+		snap2, err := ds.Snapshot(context.Background(), "snap2", zfs.SnapshotOptions{})
+		require.NoError(t, err)
+		snaps, err = runner.localDatasetSnapshots(testFilesystem)
+		require.NoError(t, err)
+		require.Len(t, snaps, 1)
+
+		runner.addLocalSnapshot(*snap2)
+		runner.setLocalSnapshotProperty(snap1.Name, deleteProp, "soon")
+		snaps, err = runner.localDatasetSnapshots(testFilesystem)
+		require.NoError(t, err)
+		require.Len(t, snaps, 2)
+		require.Equal(t, "soon", snaps[0].ExtraProps[deleteProp])
+		require.Equal(t, snap2.Name, snaps[1].Name)
+
+		runner.removeLocalSnapshot(snap1.Name)
+		snaps, err = runner.localDatasetSnapshots(testFilesystem)
+		require.NoError(t, err)
+		require.Len(t, snaps, 1)
+		require.Equal(t, snap2.Name, snaps[0].Name)
+	})
+}
@@ -0,0 +1,97 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// ErrScrubCanceled is emitted with the ScrubErrorEvent when a scrub started by the runner was canceled
+var ErrScrubCanceled = errors.New("scrub was canceled")
+
+func (r *Runner) scrubPools() error {
+	var errs []error
+	for _, pool := range r.config.scrubPools() {
+		if r.ctx.Err() != nil {
+			return nil // context expired, no problem
+		}
+
+		err := r.scrubPool(pool)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// scrubPool reports the progress and outcome of a scrub the runner started, and starts a scrub when the last one
+// finished longer than the scrub interval ago
+func (r *Runner) scrubPool(pool string) error {
+	scan, err := zfs.GetPoolScan(r.ctx, pool)
+	if err != nil {
+		return fmt.Errorf("error retrieving scan state of pool %s: %w", pool, err)
+	}
+
+	r.scrubLock.Lock()
+	_, started := r.scrubs[pool]
+	r.scrubLock.Unlock()
+
+	switch {
+	case scan.State == zfs.ScanScanning && scan.Function == zfs.ScanScrub:
+		if started {
+			r.EmitEvent(ScrubProgressEvent, pool, scan.Progress)
+		}
+		return nil
+	case scan.State == zfs.ScanScanning, scan.State == zfs.ScanPaused:
+		return nil // Resilvering or paused, do not interfere
+	}
+
+	if started && scan.Function == zfs.ScanScrub {
+		r.scrubLock.Lock()
+		delete(r.scrubs, pool)
+		r.scrubLock.Unlock()
+
+		switch {
+		case scan.State == zfs.ScanCanceled:
+			r.EmitEvent(ScrubErrorEvent, pool, ErrScrubCanceled)
+		case scan.Errors > 0:
+			r.EmitEvent(ScrubErrorEvent, pool, fmt.Errorf("scrub of pool %s found %d errors", pool, scan.Errors))
+		default:
+			r.EmitEvent(CompletedScrubEvent, pool, scan.EndedAt)
+		}
+	}
+
+	var lastScrub time.Time
+	if scan.Function == zfs.ScanScrub && scan.State == zfs.ScanFinished {
+		lastScrub = scan.EndedAt
+	}
+	if time.Since(lastScrub) < r.config.scrubInterval() {
+		return nil
+	}
+
+	err = zfs.ScrubPool(r.ctx, pool)
+	switch {
+	case errors.Is(err, zfs.ErrPoolScrubbing):
+		r.logger.Debug("zfs.job.Runner.scrubPool: Pool is scrubbing already", "pool", pool)
+		return nil
+	case err != nil:
+		return fmt.Errorf("error starting scrub of pool %s: %w", pool, err)
+	}
+
+	r.scrubLock.Lock()
+	r.scrubs[pool] = struct{}{}
+	r.scrubLock.Unlock()
+
+	r.logger.Info("zfs.job.Runner.scrubPool: Scrub started", "pool", pool, "lastScrub", lastScrub)
+	r.EmitEvent(StartedScrubEvent, pool)
+	return nil
+}
+
+// poolName returns the name of the pool of the dataset
+func poolName(dataset string) string {
+	pool, _, _ := strings.Cut(dataset, "/")
+	return pool
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	eventemitter "github.com/vansante/go-event-emitter"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// scrubExecutor emulates zpool status and scrub for a single pool
+type scrubExecutor struct {
+	scan     string
+	scrubbed int
+}
+
+func (e *scrubExecutor) Execute(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+	if cmd != zfs.PoolBinary {
+		return "unexpected command", errors.New("exit status 1")
+	}
+	switch args[0] {
+	case "status":
+		_, err := io.WriteString(stdout, "  pool: tank\n state: ONLINE\n"+e.scan+"config:\n")
+		return "", err
+	case "scrub":
+		e.scrubbed++
+		e.scan = "  scan: scrub in progress since Thu Oct 15 10:00:00 2026\n\t0B repaired, 42.50% done, 00:03:10 to go\n"
+		return "", nil
+	}
+	return "unexpected command", errors.New("exit status 1")
+}
+
+func TestRunner_scrubPools(t *testing.T) {
+	exec := &scrubExecutor{scan: "  scan: scrub repaired 0B in 00:00:01 with 0 errors on " + scanTime(-48*time.Hour) + "\n"}
+	zfs.SetExecutor(exec)
+	defer zfs.SetExecutor(nil)
+
+	conf := Config{}
+	conf.ApplyDefaults()
+	conf.ParentDataset = "tank/backups"
+	conf.ScrubIntervalHours = 24
+	r := NewRunner(context.Background(), conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var events []eventemitter.EventType
+	for _, event := range []eventemitter.EventType{StartedScrubEvent, ScrubProgressEvent, CompletedScrubEvent, ScrubErrorEvent} {
+		r.AddListener(event, func(args ...any) {
+			require.Equal(t, "tank", args[0])
+			events = append(events, event)
+		})
+	}
+
+	require.NoError(t, r.scrubPools())
+	require.Equal(t, 1, exec.scrubbed)
+	require.NoError(t, r.scrubPools())
+	require.Equal(t, 1, exec.scrubbed)
+
+	exec.scan = "  scan: scrub repaired 0B in 00:00:01 with 3 errors on " + scanTime(-time.Hour) + "\n"
+	require.NoError(t, r.scrubPools())
+	require.Equal(t, 1, exec.scrubbed, "scrubbed less than the interval ago")
+	require.Equal(t, []eventemitter.EventType{StartedScrubEvent, ScrubProgressEvent, ScrubErrorEvent}, events)
+}
+
+func scanTime(ago time.Duration) string {
+	return time.Now().Add(ago).Format("Mon Jan _2 15:04:05 2006")
+}
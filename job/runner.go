@@ -12,6 +12,7 @@ import (
 	eventemitter "github.com/vansante/go-event-emitter"
 	zfs "github.com/vansante/go-zfsutils"
 	zfshttp "github.com/vansante/go-zfsutils/http"
+	"github.com/vansante/go-zfsutils/logging"
 )
 
 const (
@@ -19,12 +20,13 @@ const (
 
 	requestTimeout = time.Second * 20
 
-	createSnapshotInterval   = 5 * time.Minute
-	sendSnapshotInterval     = 15 * time.Minute // Effectively divided by the amount of send routines configured (default 3)
-	pruneRemoteCacheInterval = 5 * time.Minute
-	markSnapshotInterval     = 10 * time.Minute
-	pruneSnapshotInterval    = 10 * time.Minute
-	pruneFilesystemInterval  = 10 * time.Minute
+	createSnapshotInterval  = 5 * time.Minute
+	sendSnapshotInterval    = 15 * time.Minute // Effectively divided by the amount of send routines configured (default 3)
+	pruneCacheInterval      = 5 * time.Minute
+	markSnapshotInterval    = 10 * time.Minute
+	pruneSnapshotInterval   = 10 * time.Minute
+	pruneFilesystemInterval = 10 * time.Minute
+	scrubPoolsInterval      = 10 * time.Minute
 )
 
 // NewRunner creates a new job runner
@@ -34,6 +36,8 @@ func NewRunner(ctx context.Context, conf Config, logger *slog.Logger) *Runner {
 		config:      conf,
 		datasetLock: make(map[string]struct{}),
 		remoteCache: make(map[string]map[string]*datasetCache),
+		localCache:  make(map[string]*datasetCache),
+		scrubs:      make(map[string]struct{}),
 		sendChan:    make(chan string),
 		logger:      logger,
 		ctx:         ctx,
@@ -54,6 +58,12 @@ type Runner struct {
 	remoteCache map[string]map[string]*datasetCache // Snapshots indexed by server, then dataset name
 	cacheLock   sync.RWMutex
 
+	localCache     map[string]*datasetCache // Local snapshots indexed by dataset name, shared by the jobs
+	localCacheLock sync.Mutex
+
+	scrubs    map[string]struct{} // Pools being scrubbed by the runner
+	scrubLock sync.Mutex
+
 	sendChan chan string
 	sends    []*zfsSend
 	sendLock sync.RWMutex
@@ -95,7 +105,7 @@ func (r *Runner) onSendStart(snapName string) {
 	dsName := r.fullDatasetName(datasetName(snapName, true))
 	ds, err := zfs.GetDataset(r.ctx, dsName)
 	if err != nil {
-		r.logger.Error("zfs.job.runner.onSendStart: Error retrieving dataset", "error", err, "snapName", snapName)
+		r.logger.Error("zfs.job.runner.onSendStart: Error retrieving dataset", "error", err, "snapshot", snapName)
 		return
 	}
 	err = ds.SetProperty(r.ctx, r.config.Properties.snapshotSending(), snapshotName(snapName))
@@ -112,7 +122,7 @@ func (r *Runner) onSendComplete(snapName string) {
 	dsName := r.fullDatasetName(datasetName(snapName, true))
 	ds, err := zfs.GetDataset(r.ctx, dsName)
 	if err != nil {
-		r.logger.Error("zfs.job.runner.onSendComplete: Error retrieving dataset", "error", err, "snapName", snapName)
+		r.logger.Error("zfs.job.runner.onSendComplete: Error retrieving dataset", "error", err, "snapshot", snapName)
 		return
 	}
 	err = ds.InheritProperty(r.ctx, r.config.Properties.snapshotSending())
@@ -179,10 +189,10 @@ func (r *Runner) Run() {
 		for i := 1; i <= r.config.SendRoutines; i++ {
 			go r.runSendSnapshotRoutine(i)
 		}
-
-		go r.runPruneRemoteCache()
 	}
 
+	go r.runPruneCaches()
+
 	if r.config.EnableSnapshotMark {
 		go r.runMarkSnapshots(time.Minute)
 	}
@@ -194,6 +204,53 @@ func (r *Runner) Run() {
 	if r.config.EnableFilesystemPrune {
 		go r.runPruneFilesystems(time.Minute * 3)
 	}
+
+	if r.config.EnablePoolScrub {
+		go r.runScrubPools(time.Minute * 4)
+	}
+}
+
+// RunOnce runs every enabled job once, one after the other, instead of periodically like Run does.
+// It returns the errors of the jobs that failed, the other jobs still run.
+func (r *Runner) RunOnce() error {
+	jobs := []struct {
+		name    string
+		enabled bool
+		run     func() error
+	}{
+		{JobCreateSnapshots, r.config.EnableSnapshotCreate, r.createSnapshots},
+		{JobSendSnapshots, r.config.EnableSnapshotSend, func() error { return r.sendSnapshots(1) }},
+		{JobMarkSnapshots, r.config.EnableSnapshotMark, r.markPrunableSnapshots},
+		{JobPruneSnapshots, r.config.EnableSnapshotPrune, r.pruneSnapshots},
+		{JobPruneFilesystems, r.config.EnableFilesystemPrune, r.pruneFilesystems},
+		{JobScrubPools, r.config.EnablePoolScrub, r.scrubPools},
+	}
+
+	var errs []error
+	for _, job := range jobs {
+		if !job.enabled {
+			continue
+		}
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		err := r.runJob(job.name, job.run)
+		switch {
+		case err == nil:
+			r.EmitEvent(JobCompletedEvent, job.name)
+		case !isContextError(err):
+			errs = append(errs, fmt.Errorf("error running %s job: %w", job.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runJob runs a job in a span of the tracer set with zfs.SetTracer
+func (r *Runner) runJob(name string, run func() error) error {
+	_, span := zfs.StartSpan(r.ctx, "zfs.job "+name, zfs.Attribute{Key: "zfs.job", Value: name})
+	err := run()
+	span.End(err)
+	return err
 }
 
 // ListCurrentSends returns a list of current ZFS sends in progress
@@ -220,20 +277,23 @@ func (r *Runner) runCreateSnapshots() {
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
-	r.logger.Info("zfs.job.Runner.runCreateSnapshots: Running", "interval", dur)
-	defer r.logger.Info("zfs.job.Runner.runCreateSnapshots: Stopped")
+	logger := r.logger.With(logging.KeyJob, JobCreateSnapshots)
+	logger.Info("zfs.job.Runner.runCreateSnapshots: Running", "interval", dur)
+	defer logger.Info("zfs.job.Runner.runCreateSnapshots: Stopped")
 
 	for {
 		select {
 		case <-ticker.C:
-			err := r.createSnapshots()
+			err := r.runJob(JobCreateSnapshots, r.createSnapshots)
 			switch {
 			case isContextError(err):
-				r.logger.Info("zfs.job.Runner.runCreateSnapshots: Job interrupted", "error", err)
+				logger.Info("zfs.job.Runner.runCreateSnapshots: Job interrupted", "error", err)
 			case errors.Is(err, zfs.ErrPoolIOSuspended), errors.Is(err, zfs.ErrDatasetNotFound):
-				r.logger.Warn("zfs.job.Runner.runCreateSnapshots: Cannot query datasets", "error", err)
+				logger.Warn("zfs.job.Runner.runCreateSnapshots: Cannot query datasets", "error", err)
 			case err != nil:
-				r.logger.Error("zfs.job.Runner.runCreateSnapshots: Error making snapshots", "error", err)
+				logger.Error("zfs.job.Runner.runCreateSnapshots: Error making snapshots", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobCreateSnapshots)
 			}
 		case <-r.ctx.Done():
 			return
@@ -250,20 +310,23 @@ func (r *Runner) runSendSnapshotRoutine(id int) {
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
-	r.logger.Info("zfs.job.Runner.runSendSnapshotRoutine: Running", "interval", dur, "routineID", id)
-	defer r.logger.Info("zfs.job.Runner.runSendSnapshotRoutine: Stopped", "interval", dur, "routineID", id)
+	logger := r.logger.With(logging.KeyJob, JobSendSnapshots)
+	logger.Info("zfs.job.Runner.runSendSnapshotRoutine: Running", "interval", dur, "routineID", id)
+	defer logger.Info("zfs.job.Runner.runSendSnapshotRoutine: Stopped", "interval", dur, "routineID", id)
 
 	for {
 		select {
 		case <-ticker.C:
-			err := r.sendSnapshots(id)
+			err := r.runJob(JobSendSnapshots, func() error { return r.sendSnapshots(id) })
 			switch {
 			case isContextError(err):
-				r.logger.Info("zfs.job.Runner.runSendSnapshots: Job interrupted", "error", err)
+				logger.Info("zfs.job.Runner.runSendSnapshots: Job interrupted", "error", err)
 			case errors.Is(err, zfs.ErrPoolIOSuspended), errors.Is(err, zfs.ErrDatasetNotFound):
-				r.logger.Warn("zfs.job.Runner.runSendSnapshots: Cannot query datasets", "error", err)
+				logger.Warn("zfs.job.Runner.runSendSnapshots: Cannot query datasets", "error", err)
 			case err != nil:
-				r.logger.Error("zfs.job.Runner.runSendSnapshots: Error sending snapshots", "error", err)
+				logger.Error("zfs.job.Runner.runSendSnapshots: Error sending snapshots", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobSendSnapshots)
 			}
 		case dataset := <-r.sendChan:
 			// Errors are already logged
@@ -274,8 +337,8 @@ func (r *Runner) runSendSnapshotRoutine(id int) {
 	}
 }
 
-func (r *Runner) runPruneRemoteCache() {
-	dur := randomizeDuration(pruneRemoteCacheInterval)
+func (r *Runner) runPruneCaches() {
+	dur := randomizeDuration(pruneCacheInterval)
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
@@ -283,6 +346,7 @@ func (r *Runner) runPruneRemoteCache() {
 		select {
 		case <-ticker.C:
 			r.pruneRemoteDatasetCache()
+			r.pruneLocalSnapshotCache()
 		case <-r.ctx.Done():
 			return
 		}
@@ -296,20 +360,23 @@ func (r *Runner) runMarkSnapshots(initDelay time.Duration) {
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
-	r.logger.Info("zfs.job.Runner.runMarkSnapshots: Running", "interval", dur)
-	defer r.logger.Info("zfs.job.Runner.runMarkSnapshots: Stopped")
+	logger := r.logger.With(logging.KeyJob, JobMarkSnapshots)
+	logger.Info("zfs.job.Runner.runMarkSnapshots: Running", "interval", dur)
+	defer logger.Info("zfs.job.Runner.runMarkSnapshots: Stopped")
 
 	for {
 		select {
 		case <-ticker.C:
-			err := r.markPrunableSnapshots()
+			err := r.runJob(JobMarkSnapshots, r.markPrunableSnapshots)
 			switch {
 			case isContextError(err):
-				r.logger.Info("zfs.job.Runner.runMarkSnapshots: Job interrupted", "error", err)
+				logger.Info("zfs.job.Runner.runMarkSnapshots: Job interrupted", "error", err)
 			case errors.Is(err, zfs.ErrPoolIOSuspended), errors.Is(err, zfs.ErrDatasetNotFound):
-				r.logger.Warn("zfs.job.Runner.runCreateSnapshots: Cannot query datasets", "error", err)
+				logger.Warn("zfs.job.Runner.runCreateSnapshots: Cannot query datasets", "error", err)
 			case err != nil:
-				r.logger.Error("zfs.job.Runner.runMarkSnapshots: Error marking snapshots", "error", err)
+				logger.Error("zfs.job.Runner.runMarkSnapshots: Error marking snapshots", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobMarkSnapshots)
 			}
 		case <-r.ctx.Done():
 			return
@@ -324,20 +391,23 @@ func (r *Runner) runPruneSnapshots(initDelay time.Duration) {
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
-	r.logger.Info("zfs.job.Runner.runPruneSnapshots: Running", "interval", dur)
-	defer r.logger.Info("zfs.job.Runner.runPruneSnapshots: Stopped")
+	logger := r.logger.With(logging.KeyJob, JobPruneSnapshots)
+	logger.Info("zfs.job.Runner.runPruneSnapshots: Running", "interval", dur)
+	defer logger.Info("zfs.job.Runner.runPruneSnapshots: Stopped")
 
 	for {
 		select {
 		case <-ticker.C:
-			err := r.pruneSnapshots()
+			err := r.runJob(JobPruneSnapshots, r.pruneSnapshots)
 			switch {
 			case isContextError(err):
-				r.logger.Info("zfs.job.Runner.runPruneSnapshots: Job interrupted", "error", err)
+				logger.Info("zfs.job.Runner.runPruneSnapshots: Job interrupted", "error", err)
 			case errors.Is(err, zfs.ErrPoolIOSuspended), errors.Is(err, zfs.ErrDatasetNotFound):
-				r.logger.Warn("zfs.job.Runner.runPruneSnapshots: Cannot query datasets", "error", err)
+				logger.Warn("zfs.job.Runner.runPruneSnapshots: Cannot query datasets", "error", err)
 			case err != nil:
-				r.logger.Error("zfs.job.Runner.runPruneSnapshots: Error pruning snapshots", "error", err)
+				logger.Error("zfs.job.Runner.runPruneSnapshots: Error pruning snapshots", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobPruneSnapshots)
 			}
 		case <-r.ctx.Done():
 			return
@@ -352,20 +422,52 @@ func (r *Runner) runPruneFilesystems(initDelay time.Duration) {
 	ticker := time.NewTicker(dur)
 	defer ticker.Stop()
 
-	r.logger.Info("zfs.job.Runner.runPruneFilesystems: Running", "interval", dur)
-	defer r.logger.Info("zfs.job.Runner.runPruneFilesystems: Stopped")
+	logger := r.logger.With(logging.KeyJob, JobPruneFilesystems)
+	logger.Info("zfs.job.Runner.runPruneFilesystems: Running", "interval", dur)
+	defer logger.Info("zfs.job.Runner.runPruneFilesystems: Stopped")
 
 	for {
 		select {
 		case <-ticker.C:
-			err := r.pruneFilesystems()
+			err := r.runJob(JobPruneFilesystems, r.pruneFilesystems)
 			switch {
 			case isContextError(err):
-				r.logger.Info("zfs.job.Runner.runPruneFilesystems: Job interrupted", "error", err)
+				logger.Info("zfs.job.Runner.runPruneFilesystems: Job interrupted", "error", err)
 			case errors.Is(err, zfs.ErrPoolIOSuspended), errors.Is(err, zfs.ErrDatasetNotFound):
-				r.logger.Warn("zfs.job.Runner.runPruneFilesystems: Cannot query datasets", "error", err)
+				logger.Warn("zfs.job.Runner.runPruneFilesystems: Cannot query datasets", "error", err)
+			case err != nil:
+				logger.Error("zfs.job.Runner.runPruneFilesystems: Error pruning filesystems", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobPruneFilesystems)
+			}
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Runner) runScrubPools(initDelay time.Duration) {
+	time.Sleep(initDelay)
+
+	dur := randomizeDuration(scrubPoolsInterval)
+	ticker := time.NewTicker(dur)
+	defer ticker.Stop()
+
+	logger := r.logger.With(logging.KeyJob, JobScrubPools)
+	logger.Info("zfs.job.Runner.runScrubPools: Running", "interval", dur)
+	defer logger.Info("zfs.job.Runner.runScrubPools: Stopped")
+
+	for {
+		select {
+		case <-ticker.C:
+			err := r.runJob(JobScrubPools, r.scrubPools)
+			switch {
+			case isContextError(err):
+				logger.Info("zfs.job.Runner.runScrubPools: Job interrupted", "error", err)
 			case err != nil:
-				r.logger.Error("zfs.job.Runner.runPruneFilesystems: Error pruning filesystems", "error", err)
+				logger.Error("zfs.job.Runner.runScrubPools: Error scrubbing pools", "error", err)
+			default:
+				r.EmitEvent(JobCompletedEvent, JobScrubPools)
 			}
 		case <-r.ctx.Done():
 			return
@@ -10,6 +10,7 @@ import (
 
 	zfs "github.com/vansante/go-zfsutils"
 	zfshttp "github.com/vansante/go-zfsutils/http"
+	"github.com/vansante/go-zfsutils/zfstest"
 )
 
 const (
@@ -22,13 +23,15 @@ const (
 func runnerTest(t *testing.T, fn func(url string, runner *Runner)) {
 	t.Helper()
 
-	zfshttp.TestHTTPZPool(testHTTPZPool, testPrefix, "", func(server *httptest.Server) {
+	zfshttp.TestHTTPServer(t, testHTTPZPool, zfshttp.TestFixture{Prefix: testPrefix}, func(server *httptest.Server, _ *zfstest.Pool) {
 		// Create another zpool as 'source':
-		zfs.TestZPool(testZPool, func() {
+		zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 			r := &Runner{
 				Emitter:     eventemitter.NewEmitter(false),
 				datasetLock: make(map[string]struct{}),
 				remoteCache: make(map[string]map[string]*datasetCache),
+				localCache:  make(map[string]*datasetCache),
+				scrubs:      make(map[string]struct{}),
 				sendChan:    make(chan string),
 				config: Config{
 					ParentDataset: testZPool,
@@ -41,6 +44,8 @@ func runnerTest(t *testing.T, fn func(url string, runner *Runner)) {
 
 			r.config.ApplyDefaults()
 			r.config.MaximumSendTimeSeconds = 30
+			// The tests change snapshots outside the runner, so do not keep the local snapshots around
+			r.config.MaximumLocalSnapshotCacheAgeSeconds = 0
 			r.config.SendSetProperties = map[string]string{
 				zfs.PropertyCanMount: zfs.ValueOff,
 			}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const (
+	sanoidPrefix     = "autosnap_"
+	sanoidTimeFormat = "2006-01-02_15:04:05"
+)
+
+var sanoidName = regexp.MustCompile(`^autosnap_(\d{4}-\d{2}-\d{2}_\d{2}:\d{2}:\d{2})_([a-z]+)$`)
+
+// sanoidLabel returns the sanoid period label that matches the snapshot interval best
+func sanoidLabel(interval time.Duration) string {
+	switch {
+	case interval < time.Hour:
+		return "frequently"
+	case interval < 24*time.Hour:
+		return "hourly"
+	case interval < 7*24*time.Hour:
+		return "daily"
+	case interval < 28*24*time.Hour:
+		return "weekly"
+	case interval < 365*24*time.Hour:
+		return "monthly"
+	default:
+		return "yearly"
+	}
+}
+
+// sanoidSnapshotName returns a sanoid style snapshot name, like autosnap_2024-01-02_15:04:05_hourly
+func sanoidSnapshotName(tm time.Time, interval time.Duration) string {
+	return sanoidPrefix + tm.Local().Format(sanoidTimeFormat) + "_" + sanoidLabel(interval)
+}
+
+// parseSanoidSnapshotName returns the creation time in a sanoid style snapshot name, sanoid uses the local time
+func parseSanoidSnapshotName(name string) (time.Time, bool) {
+	match := sanoidName.FindStringSubmatch(snapshotName(name))
+	if match == nil {
+		return time.Time{}, false
+	}
+	tm, err := time.ParseInLocation(sanoidTimeFormat, match[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return tm, true
+}
+
+// snapshotCreatedAt returns when a snapshot was created according to its created property. In sanoid compatibility
+// mode the time in its name is used for snapshots without the property. It returns false when the time is unknown.
+func (r *Runner) snapshotCreatedAt(snap *zfs.Dataset) (time.Time, bool, error) {
+	createdProp := r.config.Properties.snapshotCreatedAt()
+	if propertyIsSet(snap.ExtraProps[createdProp]) {
+		created, err := parseDatasetTimeProperty(snap, createdProp)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("error parsing %s property on %s: %w", createdProp, snap.Name, err)
+		}
+		return created, true, nil
+	}
+	if !r.config.SanoidCompatible {
+		return time.Time{}, false, nil
+	}
+	created, ok := parseSanoidSnapshotName(snap.Name)
+	return created, ok, nil
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+func Test_sanoidSnapshotName(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_frequently", sanoidSnapshotName(tm, 15*time.Minute))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_hourly", sanoidSnapshotName(tm, time.Hour))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_daily", sanoidSnapshotName(tm, 24*time.Hour))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_weekly", sanoidSnapshotName(tm, 7*24*time.Hour))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_monthly", sanoidSnapshotName(tm, 30*24*time.Hour))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_yearly", sanoidSnapshotName(tm, 365*24*time.Hour))
+
+	parsed, ok := parseSanoidSnapshotName("pool/fs@" + sanoidSnapshotName(tm, time.Hour))
+	require.True(t, ok)
+	require.True(t, tm.Equal(parsed))
+
+	for _, name := range []string{"pool/fs@backup_1700000000", "pool/fs@autosnap_2024-13-02_15:04:05_hourly",
+		"pool/fs@autosnap_2024-01-02_15:04:05", "pool/fs"} {
+		_, ok = parseSanoidSnapshotName(name)
+		require.False(t, ok, name)
+	}
+}
+
+func TestRunner_snapshotCreatedAt(t *testing.T) {
+	r := &Runner{}
+	r.config.ApplyDefaults()
+	createdProp := r.config.Properties.snapshotCreatedAt()
+
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	snap := &zfs.Dataset{
+		Name:       "pool/fs@backup_1704207845",
+		ExtraProps: map[string]string{createdProp: created.Format(dateTimeFormat)},
+	}
+	tm, ok, err := r.snapshotCreatedAt(snap)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, created.Equal(tm))
+
+	sanoidSnap := &zfs.Dataset{
+		Name:       "pool/fs@autosnap_2024-01-02_15:04:05_hourly",
+		ExtraProps: map[string]string{createdProp: zfs.ValueUnset},
+	}
+	_, ok, err = r.snapshotCreatedAt(sanoidSnap)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	r.config.SanoidCompatible = true
+	tm, ok, err = r.snapshotCreatedAt(sanoidSnap)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local).Equal(tm))
+	require.Equal(t, "autosnap_2024-01-02_15:04:05_hourly", r.snapshotName(tm, time.Hour))
+
+	snap.ExtraProps[createdProp] = "invalid"
+	_, _, err = r.snapshotCreatedAt(snap)
+	require.Error(t, err)
+}
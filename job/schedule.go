@@ -0,0 +1,34 @@
+package job
+
+import "strconv"
+
+// DatasetSchedule configures the jobs for a single dataset. The runner reads the schedule from the dataset
+// properties, so it takes effect once the properties from ScheduleProperties are set on the dataset.
+type DatasetSchedule struct {
+	// SnapshotIntervalMinutes is the interval at which snapshots are created, zero for none
+	SnapshotIntervalMinutes int64 `json:"SnapshotIntervalMinutes" yaml:"SnapshotIntervalMinutes"`
+	// SnapshotRetentionCount is the amount of snapshots to keep, zero to keep all
+	SnapshotRetentionCount int64 `json:"SnapshotRetentionCount" yaml:"SnapshotRetentionCount"`
+	// SnapshotRetentionMinutes is how long snapshots are kept, zero to keep them forever
+	SnapshotRetentionMinutes int64 `json:"SnapshotRetentionMinutes" yaml:"SnapshotRetentionMinutes"`
+	// SendTo is the URL of the zfs http server to send the snapshots to, empty to not send them
+	SendTo string `json:"SendTo" yaml:"SendTo"`
+}
+
+// ScheduleProperties returns the dataset properties for the schedule. The properties the schedule leaves unset have
+// an empty value, these should be inherited so they are no longer set on the dataset.
+func (c *Config) ScheduleProperties(schedule DatasetSchedule) map[string]string {
+	return map[string]string{
+		c.Properties.snapshotIntervalMinutes():  formatPositive(schedule.SnapshotIntervalMinutes),
+		c.Properties.snapshotRetentionCount():   formatPositive(schedule.SnapshotRetentionCount),
+		c.Properties.snapshotRetentionMinutes(): formatPositive(schedule.SnapshotRetentionMinutes),
+		c.Properties.snapshotSendTo():           schedule.SendTo,
+	}
+}
+
+func formatPositive(i int64) string {
+	if i <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(i, 10)
+}
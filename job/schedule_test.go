@@ -0,0 +1,25 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ScheduleProperties(t *testing.T) {
+	conf := Config{}
+	conf.ApplyDefaults()
+	conf.Properties.Namespace = "nl.test"
+
+	props := conf.ScheduleProperties(DatasetSchedule{
+		SnapshotIntervalMinutes: 15,
+		SnapshotRetentionCount:  96,
+		SendTo:                  "https://backup:7654",
+	})
+	require.Equal(t, map[string]string{
+		"nl.test:snapshot-interval-minutes":  "15",
+		"nl.test:snapshot-retention-count":   "96",
+		"nl.test:snapshot-retention-minutes": "",
+		"nl.test:snapshot-send-to":           "https://backup:7654",
+	}, props)
+}
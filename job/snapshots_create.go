@@ -28,26 +28,24 @@ func (r *Runner) createSnapshots() error {
 		return fmt.Errorf("error finding snapshottable datasets: %w", err)
 	}
 
-	for dataset := range datasets {
+	list, err := r.getDatasets(datasetNames(datasets), intervalProp, deleteProp)
+	if err != nil {
+		return fmt.Errorf("error retrieving snapshottable datasets: %w", err)
+	}
+
+	for i := range list {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		ds, err := zfs.GetDataset(r.ctx, dataset, intervalProp, deleteProp)
-		switch {
-		case errors.Is(err, zfs.ErrDatasetNotFound):
-			continue // Dataset was removed meanwhile, continue with the next one
-		case err != nil:
-			return fmt.Errorf("error retrieving snapshottable dataset %s: %w", dataset, err)
-		}
-
+		ds := &list[i]
 		err = r.createDatasetSnapshot(ds)
 		switch {
 		case isContextError(err):
-			r.logger.Info("zfs.job.Runner.createSnapshots: Create snapshot job interrupted", "error", err, "dataset", dataset)
+			r.logger.Info("zfs.job.Runner.createSnapshots: Create snapshot job interrupted", "error", err, "dataset", ds.Name)
 			return nil // Return no error
 		case err != nil:
-			r.logger.Error("zfs.job.Runner.createSnapshots: Error creating snapshot", "error", err, "dataset", dataset)
+			r.logger.Error("zfs.job.Runner.createSnapshots: Error creating snapshot", "error", err, "dataset", ds.Name)
 			continue // on to the next dataset :-/
 		}
 	}
@@ -55,7 +53,10 @@ func (r *Runner) createSnapshots() error {
 	return nil
 }
 
-func (r *Runner) snapshotName(tm time.Time) string {
+func (r *Runner) snapshotName(tm time.Time, interval time.Duration) string {
+	if r.config.SanoidCompatible {
+		return sanoidSnapshotName(tm, interval)
+	}
 	name := r.config.SnapshotNameTemplate
 	name = strings.ReplaceAll(name, "%UNIXTIME%", strconv.FormatInt(tm.Unix(), 10))
 	name = strings.ReplaceAll(name, "%RFC3339%", tm.Format(time.RFC3339))
@@ -91,9 +92,7 @@ func (r *Runner) createDatasetSnapshot(ds *zfs.Dataset) error {
 	createdProp := r.config.Properties.snapshotCreatedAt()
 	ignoreProp := r.config.Properties.snapshotIgnoreCreate()
 
-	snapshots, err := ds.Snapshots(r.ctx, zfs.ListOptions{
-		ExtraProperties: []string{createdProp, ignoreProp},
-	})
+	snapshots, err := r.localDatasetSnapshots(ds.Name)
 	if err != nil {
 		return fmt.Errorf("error listing existing snapshots on %s: %w", ds.Name, err)
 	}
@@ -104,13 +103,12 @@ func (r *Runner) createDatasetSnapshot(ds *zfs.Dataset) error {
 		if propertyIsSet(snap.ExtraProps[ignoreProp]) {
 			continue // Ignored
 		}
-		if !propertyIsSet(snap.ExtraProps[createdProp]) {
-			continue // Cannot determine age, so skip anyway
-		}
-
-		created, err := parseDatasetTimeProperty(snap, createdProp)
+		created, ok, err := r.snapshotCreatedAt(snap)
 		if err != nil {
-			return fmt.Errorf("error parsing %s on snapshot %s: %w", createdProp, snap.Name, err)
+			return err
+		}
+		if !ok {
+			continue // Cannot determine age, so skip anyway
 		}
 		if created.After(latestSnap) {
 			latestSnap = created
@@ -132,15 +130,19 @@ func (r *Runner) createDatasetSnapshot(ds *zfs.Dataset) error {
 	}
 
 	tm := time.Now()
-	name := r.snapshotName(tm)
+	name := r.snapshotName(tm, interval)
+	props := map[string]string{
+		createdProp: tm.Format(dateTimeFormat),
+	}
 	snap, err := ds.Snapshot(r.ctx, name, zfs.SnapshotOptions{
-		Properties: map[string]string{
-			createdProp: tm.Format(dateTimeFormat),
-		},
+		Properties:  props,
+		SkipRefetch: true,
 	})
 	if err != nil {
 		return fmt.Errorf("error creating snapshot %s for %s: %w", name, ds.Name, err)
 	}
+	snap.ExtraProps = props
+	r.addLocalSnapshot(*snap)
 
 	r.logger.Debug("zfs.job.Runner.createDatasetSnapshot: Snapshot created",
 		"snapshot", snap.Name,
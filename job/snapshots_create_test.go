@@ -32,8 +32,8 @@ func TestRunner_createSnapshots(t *testing.T) {
 			require.Equal(t, testZPool+"/"+fsName, arguments[0])
 
 			tm := time.Now()
-			name := runner.snapshotName(tm)
-			require.Equal(t, runner.snapshotName(time.Now()), arguments[1])
+			name := runner.snapshotName(tm, time.Minute)
+			require.Equal(t, runner.snapshotName(time.Now(), time.Minute), arguments[1])
 			createTm := arguments[2].(time.Time)
 			require.WithinDuration(t, tm, createTm, time.Second)
 
@@ -36,18 +36,18 @@ func (r *Runner) markPrunableExcessSnapshots() error {
 		return fmt.Errorf("error finding retention count datasets: %w", err)
 	}
 
-	for dataset := range datasets {
+	list, err := r.getDatasets(datasetNames(datasets), countProp)
+	if err != nil {
+		return fmt.Errorf("error retrieving count retention datasets: %w", err)
+	}
+
+	for i := range list {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		ds, err := zfs.GetDataset(r.ctx, dataset, countProp)
-		switch {
-		case errors.Is(err, zfs.ErrDatasetNotFound):
-			continue // Dataset was removed meanwhile, continue with next one
-		case err != nil:
-			return fmt.Errorf("error retrieving count retention dataset %s: %w", dataset, err)
-		}
+		ds := &list[i]
+		dataset := ds.Name
 
 		if !propertyIsSet(ds.ExtraProps[countProp]) {
 			continue // Not set (anymore), skip
@@ -86,14 +86,11 @@ func (r *Runner) markExcessDatasetSnapshots(ds *zfs.Dataset, maxCount int64) err
 		unlock()
 	}()
 
-	createdProp := r.config.Properties.snapshotCreatedAt()
 	deleteProp := r.config.Properties.deleteAt()
 	serverProp := r.config.Properties.snapshotSendTo()
 	ignoreProp := r.config.Properties.snapshotIgnoreCountPrune()
 
-	snaps, err := ds.Snapshots(r.ctx, zfs.ListOptions{
-		ExtraProperties: []string{createdProp, deleteProp, ignoreProp},
-	})
+	snaps, err := r.localDatasetSnapshots(ds.Name)
 	if err != nil {
 		return fmt.Errorf("error retrieving snapshots for %s: %w", ds.Name, err)
 	}
@@ -109,8 +106,14 @@ func (r *Runner) markExcessDatasetSnapshots(ds *zfs.Dataset, maxCount int64) err
 		}
 		snap := &snaps[i]
 
-		if r.config.SnapshotRetentionCountIgnoreWithoutCreated && !propertyIsSet(snap.ExtraProps[createdProp]) {
-			continue // Ignore without created property
+		if r.config.SnapshotRetentionCountIgnoreWithoutCreated {
+			_, ok, err := r.snapshotCreatedAt(snap)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue // Ignore without created property
+			}
 		}
 		if propertyIsSet(snap.ExtraProps[ignoreProp]) {
 			continue // Ignored by property
@@ -129,6 +132,7 @@ func (r *Runner) markExcessDatasetSnapshots(ds *zfs.Dataset, maxCount int64) err
 		if err != nil {
 			return fmt.Errorf("error setting %s property for %s: %w", deleteProp, snap.Name, err)
 		}
+		r.setLocalSnapshotProperty(snap.Name, deleteProp, deleteAt.Format(dateTimeFormat))
 
 		err = r.markRemoteDatasetSnapshot(snap, snap.ExtraProps[serverProp], deleteProp, deleteAt)
 		if err != nil {
@@ -137,7 +141,7 @@ func (r *Runner) markExcessDatasetSnapshots(ds *zfs.Dataset, maxCount int64) err
 				"snapshot", snap.Name,
 				"deleteAt", deleteAt.Format(dateTimeFormat),
 				"maxCount", maxCount,
-				"server", snap.ExtraProps[serverProp],
+				"target", snap.ExtraProps[serverProp],
 			)
 		}
 
@@ -147,7 +151,7 @@ func (r *Runner) markExcessDatasetSnapshots(ds *zfs.Dataset, maxCount int64) err
 			"deleteAt", deleteAt.Format(dateTimeFormat),
 			"maxCount", maxCount,
 			"remoteMarked", r.config.EnableSnapshotMarkRemote,
-			"server", snap.ExtraProps[serverProp],
+			"target", snap.ExtraProps[serverProp],
 		)
 
 		r.EmitEvent(MarkSnapshotDeletionEvent, snap.Name, datasetName(snap.Name, true), snapshotName(snap.Name))
@@ -171,18 +175,18 @@ func (r *Runner) markPrunableSnapshotsByAge() error {
 		return fmt.Errorf("error finding retention time datasets: %w", err)
 	}
 
-	for dataset := range datasets {
+	list, err := r.getDatasets(datasetNames(datasets), retentionProp)
+	if err != nil {
+		return fmt.Errorf("error retrieving time retention datasets: %w", err)
+	}
+
+	for i := range list {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		ds, err := zfs.GetDataset(r.ctx, dataset, retentionProp)
-		switch {
-		case errors.Is(err, zfs.ErrDatasetNotFound):
-			continue // Dataset was removed meanwhile, continue with next one
-		case err != nil:
-			return fmt.Errorf("error retrieving time retention dataset %s: %w", dataset, err)
-		}
+		ds := &list[i]
+		dataset := ds.Name
 
 		if !propertyIsSet(ds.ExtraProps[retentionProp]) {
 			continue // Not set (anymore), skip
@@ -216,14 +220,11 @@ func (r *Runner) markAgingDatasetSnapshots(ds *zfs.Dataset, duration time.Durati
 		unlock()
 	}()
 
-	createdProp := r.config.Properties.snapshotCreatedAt()
 	deleteProp := r.config.Properties.deleteAt()
 	serverProp := r.config.Properties.snapshotSendTo()
 	ignoreProp := r.config.Properties.snapshotIgnoreMinutesPrune()
 
-	snaps, err := ds.Snapshots(r.ctx, zfs.ListOptions{
-		ExtraProperties: []string{createdProp, deleteProp, serverProp, ignoreProp},
-	})
+	snaps, err := r.localDatasetSnapshots(ds.Name)
 	if err != nil {
 		return fmt.Errorf("error retrieving snapshots for %s: %w", ds.Name, err)
 	}
@@ -239,18 +240,17 @@ func (r *Runner) markAgingDatasetSnapshots(ds *zfs.Dataset, duration time.Durati
 		if propertyIsSet(snap.ExtraProps[ignoreProp]) {
 			continue // Ignored by property
 		}
-		if !propertyIsSet(snap.ExtraProps[createdProp]) {
+		createdAt, ok, err := r.snapshotCreatedAt(snap)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			continue // Cannot determine age
 		}
 		if propertyIsSet(snap.ExtraProps[deleteProp]) && propertyIsBefore(snap.ExtraProps[deleteProp], deleteAt) {
 			continue // Already being deleted, sooner than we would
 		}
 
-		createdAt, err := parseDatasetTimeProperty(snap, createdProp)
-		if err != nil {
-			return fmt.Errorf("error parsing %s property on %s: %w", createdProp, snap.Name, err)
-		}
-
 		if createdAt.Add(duration).After(now) {
 			continue // Retention period has not passed yet.
 		}
@@ -259,6 +259,7 @@ func (r *Runner) markAgingDatasetSnapshots(ds *zfs.Dataset, duration time.Durati
 		if err != nil {
 			return fmt.Errorf("error setting %s property on %s: %w", deleteProp, snap.Name, err)
 		}
+		r.setLocalSnapshotProperty(snap.Name, deleteProp, deleteAt.Format(dateTimeFormat))
 
 		err = r.markRemoteDatasetSnapshot(snap, snap.ExtraProps[serverProp], deleteProp, deleteAt)
 		if err != nil {
@@ -268,7 +269,7 @@ func (r *Runner) markAgingDatasetSnapshots(ds *zfs.Dataset, duration time.Durati
 				"createdAt", createdAt,
 				"deleteAt", deleteAt.Format(dateTimeFormat),
 				"deleteAfter", duration,
-				"server", snap.ExtraProps[serverProp],
+				"target", snap.ExtraProps[serverProp],
 			)
 		}
 
@@ -278,7 +279,7 @@ func (r *Runner) markAgingDatasetSnapshots(ds *zfs.Dataset, duration time.Durati
 			"deleteAt", deleteAt.Format(dateTimeFormat),
 			"deleteAfter", duration,
 			"remoteMarked", r.config.EnableSnapshotMarkRemote,
-			"server", snap.ExtraProps[serverProp],
+			"target", snap.ExtraProps[serverProp],
 		)
 
 		r.EmitEvent(MarkSnapshotDeletionEvent, snap.Name, datasetName(snap.Name, true), snapshotName(snap.Name))
@@ -290,6 +291,9 @@ func (r *Runner) markRemoteDatasetSnapshot(localSnap *zfs.Dataset, server, delet
 	if !r.config.EnableSnapshotMarkRemote || !propertyIsSet(server) {
 		return nil
 	}
+	if _, ok := archiveDir(server); ok {
+		return nil // Archived streams are rotated by the send job
+	}
 
 	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Minute)
 	defer cancel()
@@ -3,6 +3,8 @@ package job
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
@@ -24,35 +26,48 @@ func (r *Runner) pruneSnapshots() error {
 		return fmt.Errorf("error finding prunable datasets: %w", err)
 	}
 
-	for snapshot := range snapshots {
+	// Retrieve all snapshots at once to find the ones due, instead of running a command per snapshot
+	datasets, err := r.getDatasets(datasetNames(snapshots), deleteProp)
+	if err != nil {
+		return fmt.Errorf("error retrieving prunable snapshots: %w", err)
+	}
+
+	// Prune per filesystem, so the due snapshots of a filesystem can be destroyed together as ranges
+	var filesystems []string
+	for i := range datasets {
+		due, _, err := r.deleteDue(&datasets[i])
+		if err != nil {
+			r.logger.Error("zfs.job.Runner.pruneSnapshots: Error checking snapshot",
+				"error", err,
+				"dataset", datasetName(datasets[i].Name, true),
+				"snapshot", snapshotName(datasets[i].Name),
+				"full", datasets[i].Name,
+			)
+			continue
+		}
+		filesystem := stripDatasetSnapshot(datasets[i].Name)
+		if due && !slices.Contains(filesystems, filesystem) {
+			filesystems = append(filesystems, filesystem)
+		}
+	}
+
+	for _, filesystem := range filesystems {
 		if r.ctx.Err() != nil {
 			return nil // context expired, no problem
 		}
 
-		err = r.pruneMarkedSnapshot(snapshot)
+		err = r.pruneFilesystemSnapshots(filesystem)
 		switch {
 		case isContextError(err):
 			r.logger.Info("zfs.job.Runner.pruneSnapshots: Prune snapshot job interrupted",
 				"error", err,
-				"dataset", datasetName(snapshot, true),
-				"snapshot", snapshotName(snapshot),
-				"full", snapshot,
+				"dataset", filesystem,
 			)
 			return nil // Return no error
-		case errors.Is(err, zfs.ErrSnapshotHasDependentClones):
-			r.logger.Warn("zfs.job.Runner.pruneSnapshots: Snapshot in use",
-				"error", err,
-				"dataset", datasetName(snapshot, true),
-				"snapshot", snapshotName(snapshot),
-				"full", snapshot,
-			)
-			continue // Nothing to do here, next
 		case err != nil:
-			r.logger.Error("zfs.job.Runner.pruneSnapshots: Error pruning snapshot",
+			r.logger.Error("zfs.job.Runner.pruneSnapshots: Error pruning snapshots",
 				"error", err,
-				"dataset", datasetName(snapshot, true),
-				"snapshot", snapshotName(snapshot),
-				"full", snapshot,
+				"dataset", filesystem,
 			)
 			continue // on to the next dataset :-/
 		}
@@ -61,8 +76,10 @@ func (r *Runner) pruneSnapshots() error {
 	return nil
 }
 
-func (r *Runner) pruneMarkedSnapshot(snapshot string) error {
-	locked, unlock := r.lockDataset(stripDatasetSnapshot(snapshot))
+// pruneFilesystemSnapshots destroys the due snapshots of the filesystem, every run of consecutive due snapshots
+// with a single range destroy
+func (r *Runner) pruneFilesystemSnapshots(filesystem string) error {
+	locked, unlock := r.lockDataset(filesystem)
 	if !locked {
 		return nil // Some other goroutine is doing something with this dataset already, continue to next.
 	}
@@ -71,45 +88,119 @@ func (r *Runner) pruneMarkedSnapshot(snapshot string) error {
 		unlock()
 	}()
 
-	deleteProp := r.config.Properties.deleteAt()
-
-	snap, err := zfs.GetDataset(r.ctx, snapshot, deleteProp)
+	// Check the properties again now the dataset is locked, they could have changed since the batched lookup
+	snapshots, err := zfs.ListDatasets(r.ctx, zfs.ListOptions{
+		ParentDataset:   filesystem,
+		DatasetType:     zfs.DatasetSnapshot,
+		Depth:           1,
+		Fields:          []string{zfs.PropertyName},
+		ExtraProperties: []string{r.config.Properties.deleteAt(), zfs.PropertyClones},
+		SortBy:          zfs.PropertyCreateTXG,
+	})
 	switch {
 	case errors.Is(err, zfs.ErrDatasetNotFound):
-		return nil // Dataset was removed meanwhile, return early
+		return nil // Filesystem was removed meanwhile
 	case err != nil:
-		return fmt.Errorf("error getting snapshot %s: %w", snapshot, err)
+		return fmt.Errorf("error listing snapshots of %s: %w", filesystem, err)
 	}
 
-	if snap.Type != zfs.DatasetSnapshot {
-		return fmt.Errorf("unexpected dataset type %s for %s", snap.Type, snap.Name)
-	}
-
-	if !propertyIsSet(snap.ExtraProps[deleteProp]) {
+	runs := r.dueSnapshotRuns(snapshots)
+	if len(runs) == 0 {
 		return nil
 	}
 
-	deleteAt, err := parseDatasetTimeProperty(snap, deleteProp)
+	// Estimate the space freed by all runs together with a single dry run
+	ranges := make([]string, 0, len(runs))
+	count := 0
+	for _, run := range runs {
+		snapRange := snapshotName(run[0].Name)
+		if len(run) > 1 {
+			snapRange += "%" + snapshotName(run[len(run)-1].Name)
+		}
+		ranges = append(ranges, snapRange)
+		count += len(run)
+	}
+	all := &zfs.Dataset{Name: filesystem + "@" + strings.Join(ranges, ","), Type: zfs.DatasetSnapshot}
+	estimate, err := all.DestroyDryRun(r.ctx, zfs.DestroyOptions{})
 	if err != nil {
-		return fmt.Errorf("error parsing %s on %s: %w", deleteProp, snap.Name, err)
+		return fmt.Errorf("error estimating destroy of %s: %w", all.Name, err)
 	}
+	r.logger.Info("zfs.job.Runner.pruneFilesystemSnapshots: Pruning snapshots",
+		"dataset", filesystem,
+		"snapshots", count,
+		"reclaim", estimate.Reclaim,
+	)
 
-	if deleteAt.After(time.Now()) {
-		return nil // Not due for removal yet
-	}
+	for _, run := range runs {
+		err = zfs.DestroySnapshotRange(r.ctx, filesystem, run[0].Name, run[len(run)-1].Name, zfs.DestroyOptions{})
+		if err != nil {
+			return fmt.Errorf("error destroying snapshots %s to %s: %w", run[0].Name, run[len(run)-1].Name, err)
+		}
 
-	// TODO: FIXME: Do we want deferred destroy?
-	err = snap.Destroy(r.ctx, zfs.DestroyOptions{})
-	if err != nil {
-		return fmt.Errorf("error destroying %s: %w", snap.Name, err)
+		for _, snap := range run {
+			r.removeLocalSnapshot(snap.Name)
+
+			r.logger.Debug("zfs.job.Runner.pruneFilesystemSnapshots: Snapshot pruned",
+				"snapshot", snap.Name,
+				"deleteAt", snap.ExtraProps[r.config.Properties.deleteAt()],
+			)
+
+			r.EmitEvent(DeletedSnapshotEvent, snap.Name, datasetName(snap.Name, true), snapshotName(snap.Name))
+		}
 	}
+	return nil
+}
 
-	r.logger.Debug("zfs.job.Runner.pruneMarkedSnapshot: Snapshot pruned",
-		"snapshot", snap.Name,
-		"deleteAt", deleteAt.Format(dateTimeFormat),
-	)
+// dueSnapshotRuns returns the runs of consecutive due snapshots in the ordered snapshots. Snapshots with clones
+// are never destroyed, so they end a run as well.
+func (r *Runner) dueSnapshotRuns(snapshots []zfs.Dataset) [][]zfs.Dataset {
+	var runs [][]zfs.Dataset
+	var run []zfs.Dataset
+	for i := range snapshots {
+		snap := &snapshots[i]
+		due, _, err := r.deleteDue(snap)
+		clones := snap.ExtraProps[zfs.PropertyClones]
+		switch {
+		case err != nil:
+			r.logger.Error("zfs.job.Runner.dueSnapshotRuns: Error checking snapshot",
+				"error", err,
+				"dataset", datasetName(snap.Name, true),
+				"snapshot", snapshotName(snap.Name),
+				"full", snap.Name,
+			)
+		case due && propertyIsSet(clones):
+			// Refuse to destroy the clones along with it
+			r.logger.Warn("zfs.job.Runner.dueSnapshotRuns: Snapshot in use",
+				"error", fmt.Errorf("%w: %s", zfs.ErrSnapshotHasDependentClones, clones),
+				"dataset", datasetName(snap.Name, true),
+				"snapshot", snapshotName(snap.Name),
+				"full", snap.Name,
+			)
+		case due:
+			run = append(run, *snap)
+			continue
+		}
+		if len(run) > 0 {
+			runs = append(runs, run)
+			run = nil
+		}
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
 
-	r.EmitEvent(DeletedSnapshotEvent, snap.Name, datasetName(snap.Name, true), snapshotName(snap.Name))
+// deleteDue returns whether the delete at property of the snapshot or filesystem is set and has passed
+func (r *Runner) deleteDue(ds *zfs.Dataset) (bool, time.Time, error) {
+	deleteProp := r.config.Properties.deleteAt()
+	if !propertyIsSet(ds.ExtraProps[deleteProp]) {
+		return false, time.Time{}, nil
+	}
 
-	return nil
+	deleteAt, err := parseDatasetTimeProperty(ds, deleteProp)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("error parsing %s on %s: %w", deleteProp, ds.Name, err)
+	}
+	return !deleteAt.After(time.Now()), deleteAt, nil
 }
@@ -3,10 +3,13 @@ package job
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
 	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
 
 	"github.com/stretchr/testify/require"
 )
@@ -67,3 +70,62 @@ func TestRunner_pruneSnapshots(t *testing.T) {
 		require.Equal(t, snaps[1].Name, fmt.Sprintf("%s@%s", testFilesystem, snap4))
 	})
 }
+
+func TestRunner_pruneFilesystemSnapshots(t *testing.T) {
+	fake := zfsfake.Install(t, "tank")
+	ctx := context.Background()
+
+	conf := Config{}
+	conf.ApplyDefaults()
+	conf.ParentDataset = "tank"
+	r := NewRunner(ctx, conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	deleteProp := r.config.Properties.deleteAt()
+
+	fs, err := zfs.CreateFilesystem(ctx, "tank/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Minute).Format(dateTimeFormat)
+	for _, name := range []string{"s1", "s2", "s3", "s4", "s5", "s6"} {
+		_, err = fs.Snapshot(ctx, name, zfs.SnapshotOptions{Properties: map[string]string{deleteProp: past}})
+		require.NoError(t, err)
+	}
+	snapshots, err := zfs.GetDatasets(ctx, []string{"tank/fs@s1", "tank/fs@s2", "tank/fs@s3"}, deleteProp)
+	require.NoError(t, err)
+
+	// The properties are checked again once the dataset is locked
+	require.NoError(t, snapshots[2].SetProperty(ctx, deleteProp, time.Now().Add(time.Hour).Format(dateTimeFormat)))
+	clone, err := zfs.GetDataset(ctx, "tank/fs@s5")
+	require.NoError(t, err)
+	_, err = clone.Clone(ctx, "tank/clone", zfs.CloneOptions{})
+	require.NoError(t, err)
+
+	var deleted []string
+	r.AddListener(DeletedSnapshotEvent, func(arguments ...interface{}) {
+		deleted = append(deleted, arguments[0].(string))
+	})
+
+	require.NoError(t, r.pruneFilesystemSnapshots("tank/fs"))
+	require.Equal(t, []string{"tank/fs@s1", "tank/fs@s2", "tank/fs@s4", "tank/fs@s6"}, deleted)
+
+	// A single dry run estimates all runs, then every run is destroyed in one command
+	var destroyed [][]string
+	for _, cmd := range fake.Commands() {
+		if cmd[0] == "destroy" {
+			destroyed = append(destroyed, cmd)
+		}
+	}
+	require.Equal(t, [][]string{
+		{"destroy", "-n", "-p", "-v", "tank/fs@s1%s2,s4,s6"},
+		{"destroy", "tank/fs@s1%s2"},
+		{"destroy", "tank/fs@s4"},
+		{"destroy", "tank/fs@s6"},
+	}, destroyed)
+
+	list, err := fs.Snapshots(ctx, zfs.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "tank/fs@s3", list[0].Name)
+	require.Equal(t, "tank/fs@s5", list[1].Name)
+
+	// A removed filesystem has nothing left to prune
+	require.NoError(t, r.pruneFilesystemSnapshots("tank/missing"))
+}
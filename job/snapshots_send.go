@@ -108,16 +108,12 @@ func (r *Runner) sendDatasetSnapshots(ds *zfs.Dataset) error {
 		unlock()
 	}()
 
-	createdProp := r.config.Properties.snapshotCreatedAt()
 	sendToProp := r.config.Properties.snapshotSendTo()
 	sendingProp := r.config.Properties.snapshotSending()
 	sentProp := r.config.Properties.snapshotSentAt()
 	ignoreProp := r.config.Properties.snapshotIgnoreSend()
 
-	localSnaps, err := zfs.ListSnapshots(r.ctx, zfs.ListOptions{
-		ParentDataset:   ds.Name,
-		ExtraProperties: []string{createdProp, ignoreProp},
-	})
+	localSnaps, err := r.localDatasetSnapshots(ds.Name)
 	if err != nil {
 		return fmt.Errorf("error listing local %s snapshots: %w", ds.Name, err)
 	}
@@ -128,6 +124,9 @@ func (r *Runner) sendDatasetSnapshots(ds *zfs.Dataset) error {
 	}
 
 	server := ds.ExtraProps[sendToProp]
+	if _, ok := archiveDir(server); ok {
+		return r.archiveDatasetSnapshots(ds, server, filterSnapshotsWithProp(localSnaps, ignoreProp))
+	}
 	client := r.getServerClient(server)
 	remoteDataset := datasetName(ds.Name, true)
 
@@ -212,14 +211,17 @@ func (r *Runner) resumeSendSnapshot(client *zfshttp.Client, ds *zfs.Dataset, rem
 
 	r.logger.Debug("zfs.job.Runner.resumeSendSnapshot: Resuming sending snapshot",
 		"dataset", ds.Name,
-		"server", client.Server(),
-		"snapshotName", sendingSnapName,
+		"target", client.Server(),
 		"snapshot", fullSnapName,
 		"curBytes", curBytes,
 	)
 
 	now := time.Now()
-	ctx, cancel = context.WithTimeout(r.ctx, r.config.maximumSendTime())
+	ctx, span := zfs.StartSpan(r.ctx, "zfs.job resume send snapshot",
+		zfs.Attribute{Key: "zfs.snapshot", Value: fullSnapName},
+		zfs.Attribute{Key: "zfs.server", Value: client.Server()},
+	)
+	ctx, cancel = context.WithTimeout(ctx, r.config.maximumSendTime())
 	sending := &zfsSend{
 		dataset: fullSnapName,
 		server:  client.Server(),
@@ -239,6 +241,8 @@ func (r *Runner) resumeSendSnapshot(client *zfshttp.Client, ds *zfs.Dataset, rem
 		ResumeSendOptions: zfs.ResumeSendOptions{
 			BytesPerSecond:   r.config.SendSpeedBytesPerSecond,
 			CompressionLevel: r.config.SendCompressionLevel,
+			BufferSize:       r.config.SendBufferSize,
+			ExternalBuffer:   r.config.SendExternalBuffer,
 		},
 		ProgressEvery: r.config.sendProgressInterval(),
 		ProgressFn: func(bytes int64) {
@@ -246,14 +250,14 @@ func (r *Runner) resumeSendSnapshot(client *zfshttp.Client, ds *zfs.Dataset, rem
 		},
 	})
 	cancel()
+	span.End(err)
 	result.BytesSent += int64(curBytes)
 	switch {
 	case errors.Is(err, zfshttp.ErrTooManyRequests):
 		r.logger.Info("zfs.job.Runner.resumeSendSnapshot: Too many receives, delaying",
 			"error", err,
-			"snapshot", ds.Name,
-			"server", client.Server(),
-			"snapshotName", sendingSnapName,
+			"dataset", ds.Name,
+			"target", client.Server(),
 			"snapshot", fullSnapName,
 		)
 		return true, nil
@@ -271,12 +275,12 @@ func (r *Runner) resumeSendSnapshot(client *zfshttp.Client, ds *zfs.Dataset, rem
 	}
 
 	r.logger.Debug("zfs.job.Runner.resumeSendSnapshot: Sent snapshot",
-		"snapshot", ds.Name,
-		"server", client.Server(),
-		"snapshotName", sendingSnapName,
+		"dataset", ds.Name,
+		"target", client.Server(),
 		"snapshot", fullSnapName,
 		"bytesSent", result.BytesSent,
 		"timeTaken", result.TimeTaken.String(),
+		"bytesPerSecond", int64(result.BytesPerSecond()),
 	)
 
 	r.EmitEvent(SentSnapshotEvent, fullSnapName, client.Server(), result.BytesSent, result.TimeTaken)
@@ -286,12 +290,16 @@ func (r *Runner) resumeSendSnapshot(client *zfshttp.Client, ds *zfs.Dataset, rem
 func (r *Runner) sendSnapshot(client *zfshttp.Client, send zfshttp.SnapshotSendOptions) error {
 	r.logger.Debug("zfs.job.Runner.sendDatasetSnapshots: Sending snapshot",
 		"snapshot", send.Snapshot.Name,
-		"server", client.Server(),
-		"sendSnapshotName", send.SnapshotName,
+		"target", client.Server(),
+		"targetSnapshot", send.SnapshotName,
 	)
 
 	now := time.Now()
-	ctx, cancel := context.WithTimeout(r.ctx, r.config.maximumSendTime())
+	ctx, span := zfs.StartSpan(r.ctx, "zfs.job send snapshot",
+		zfs.Attribute{Key: "zfs.snapshot", Value: send.Snapshot.Name},
+		zfs.Attribute{Key: "zfs.server", Value: client.Server()},
+	)
+	ctx, cancel := context.WithTimeout(ctx, r.config.maximumSendTime())
 	sending := &zfsSend{
 		dataset: send.Snapshot.Name,
 		server:  client.Server(),
@@ -309,13 +317,14 @@ func (r *Runner) sendSnapshot(client *zfshttp.Client, send zfshttp.SnapshotSendO
 
 	result, err := client.Send(ctx, send)
 	cancel()
+	span.End(err)
 	switch {
 	case errors.Is(err, zfs.ErrDatasetExists):
 		r.logger.Warn("zfs.job.Runner.sendDatasetSnapshots: Dataset exists",
 			"error", err,
 			"snapshot", send.Snapshot.Name,
-			"server", client.Server(),
-			"sendSnapshotName", send.SnapshotName,
+			"target", client.Server(),
+			"targetSnapshot", send.SnapshotName,
 		)
 		r.clearRemoteDatasetCache(client.Server(), datasetName(send.Snapshot.Name, true))
 		return nil
@@ -323,8 +332,8 @@ func (r *Runner) sendSnapshot(client *zfshttp.Client, send zfshttp.SnapshotSendO
 		r.logger.Info("zfs.job.Runner.sendDatasetSnapshots: Too many receives, delaying",
 			"error", err,
 			"snapshot", send.Snapshot.Name,
-			"server", client.Server(),
-			"sendSnapshotName", send.SnapshotName,
+			"target", client.Server(),
+			"targetSnapshot", send.SnapshotName,
 		)
 		return nil
 	case err != nil:
@@ -337,10 +346,11 @@ func (r *Runner) sendSnapshot(client *zfshttp.Client, send zfshttp.SnapshotSendO
 
 	r.logger.Debug("zfs.job.Runner.sendDatasetSnapshots: Snapshot sent",
 		"snapshot", send.Snapshot.Name,
-		"server", client.Server(),
-		"sendSnapshotName", send.SnapshotName,
+		"target", client.Server(),
+		"targetSnapshot", send.SnapshotName,
 		"bytesSent", result.BytesSent,
 		"timeTaken", result.TimeTaken.String(),
+		"bytesPerSecond", int64(result.BytesPerSecond()),
 	)
 
 	r.EmitEvent(SentSnapshotEvent, send.Snapshot.Name, client.Server(), result.BytesSent, result.TimeTaken)
@@ -396,6 +406,8 @@ func (r *Runner) reconcileSnapshots(local, remote []zfs.Dataset, server string)
 			SendOptions: zfs.SendOptions{
 				CompressionLevel:  r.config.SendCompressionLevel,
 				BytesPerSecond:    r.config.SendSpeedBytesPerSecond,
+				BufferSize:        r.config.SendBufferSize,
+				ExternalBuffer:    r.config.SendExternalBuffer,
 				Raw:               r.config.SendRaw,
 				IncludeProperties: r.config.SendIncludeProperties,
 				IncrementalBase:   prevRemoteSnap,
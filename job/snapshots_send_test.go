@@ -198,12 +198,12 @@ func TestRunner_sendPartialSnapshots(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
+			_, err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, false), zfs.ReceiveOptions{
+		_, _, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, false), zfs.ReceiveOptions{
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
 		require.NoError(t, err)
@@ -261,12 +261,12 @@ func TestRunner_sendResumeSnapshot(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err := snap.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{})
+			_, err := snap.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = zfs.ReceiveSnapshot(
+		_, _, err = zfs.ReceiveSnapshot(
 			context.Background(),
 			io.LimitReader(pipeRdr, 10*1024),
 			testHTTPZPool+"/"+datasetName(snap.Name, false),
@@ -353,12 +353,12 @@ func TestRunner_sendWithMissingSnapshots(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
+			_, err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, false), zfs.ReceiveOptions{
+		_, _, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, false), zfs.ReceiveOptions{
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
 		require.NoError(t, err)
@@ -413,12 +413,12 @@ func TestRunner_sendNoCommonSnapshots(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
+			_, err = ds.SendSnapshot(context.Background(), pipeWrtr, zfs.SendOptions{IncludeProperties: true})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		ds, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, true), zfs.ReceiveOptions{
+		ds, _, err = zfs.ReceiveSnapshot(context.Background(), pipeRdr, testHTTPZPool+"/"+datasetName(ds.Name, true), zfs.ReceiveOptions{
 			Properties: map[string]string{zfs.PropertyCanMount: zfs.ValueOff},
 		})
 		require.NoError(t, err)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -70,6 +71,39 @@ func snapshotName(name string) string {
 	return name[idx+1:]
 }
 
+// datasetNames returns the dataset names from a map as returned by zfs.ListWithProperty
+func datasetNames(datasets map[string]string) []string {
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// getDatasets retrieves the datasets with the extra properties at once. When some of them were removed meanwhile,
+// it retrieves them one by one instead, leaving out the removed ones, so the others are still handled this time.
+func (r *Runner) getDatasets(names []string, extraProperties ...string) ([]zfs.Dataset, error) {
+	datasets, err := zfs.GetDatasets(r.ctx, names, extraProperties...)
+	if !errors.Is(err, zfs.ErrDatasetNotFound) {
+		return datasets, err
+	}
+
+	names = slices.Clone(names)
+	slices.Sort(names)
+	datasets = make([]zfs.Dataset, 0, len(names))
+	for _, name := range names {
+		ds, err := zfs.GetDataset(r.ctx, name, extraProperties...)
+		switch {
+		case errors.Is(err, zfs.ErrDatasetNotFound):
+			continue // Removed meanwhile, skip it
+		case err != nil:
+			return nil, err
+		}
+		datasets = append(datasets, *ds)
+	}
+	return datasets, nil
+}
+
 func filterSnapshotsWithProp(list []zfs.Dataset, prop string) []zfs.Dataset {
 	nwList := make([]zfs.Dataset, 0, len(list))
 	for _, snap := range list {
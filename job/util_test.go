@@ -1,8 +1,14 @@
 package job
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
 )
 
 func Test_datasetName(t *testing.T) {
@@ -73,3 +79,23 @@ func Test_randomizeDuration(t *testing.T) {
 		// t.Logf("randomizeDuration() = %v", dur)
 	}
 }
+
+func TestRunner_getDatasets(t *testing.T) {
+	zfsfake.Install(t, "tank")
+	ctx := context.Background()
+	for _, name := range []string{"tank/a", "tank/b"} {
+		_, err := zfs.CreateFilesystem(ctx, name, zfs.CreateFilesystemOptions{
+			Properties: map[string]string{"nl.test:prop": "1"},
+		})
+		require.NoError(t, err)
+	}
+	r := &Runner{ctx: ctx}
+
+	// A dataset removed meanwhile is left out, instead of failing the lookup of all of them
+	datasets, err := r.getDatasets([]string{"tank/b", "tank/gone", "tank/a"}, "nl.test:prop")
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	require.Equal(t, "tank/a", datasets[0].Name)
+	require.Equal(t, "tank/b", datasets[1].Name)
+	require.Equal(t, "1", datasets[1].ExtraProps["nl.test:prop"])
+}
@@ -0,0 +1,305 @@
+package zfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The JSON output of the -j flag of OpenZFS 2.3 and newer, used when the platform has JSONOutput set.
+// Objects are decoded in order, as the order of datasets and vdevs is significant.
+
+// jsonString is a value of the JSON output, which is a string, or a number with --json-int
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		err := json.Unmarshal(data, &value)
+		*s = jsonString(value)
+		return err
+	}
+	*s = jsonString(data)
+	return nil
+}
+
+func (s jsonString) uint() uint64 {
+	n, _ := strconv.ParseUint(string(s), 10, 64)
+	return n
+}
+
+// decodeObject calls fn for every member of the JSON object in order
+func decodeObject(data []byte, fn func(key string, value json.RawMessage) error) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		err = dec.Decode(&value)
+		if err != nil {
+			return err
+		}
+		err = fn(tok.(string), value)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+type jsonDataset struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+type jsonProperty struct {
+	Value jsonString `json:"value"`
+}
+
+// parseDatasetsJSON parses the output of `zfs get -j -p` for the given fields and extra properties
+func parseDatasetsJSON(output []byte, fields, extraProps []string) ([]Dataset, error) {
+	var out struct {
+		Datasets json.RawMessage `json:"datasets"`
+	}
+	err := json.Unmarshal(output, &out)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON output: %w", err)
+	}
+
+	props := propertyList(fields, extraProps)
+	parser := newDatasetParser(fields, extraProps)
+	err = decodeObject(out.Datasets, func(name string, data json.RawMessage) error {
+		var ds jsonDataset
+		err := json.Unmarshal(data, &ds)
+		if err != nil {
+			return fmt.Errorf("error parsing JSON output of dataset %s: %w", name, err)
+		}
+		values := make(map[string]jsonProperty, len(props))
+		err = json.Unmarshal(ds.Properties, &values)
+		if err != nil {
+			return fmt.Errorf("error parsing JSON output of properties of dataset %s: %w", name, err)
+		}
+
+		for _, prop := range props {
+			value, ok := values[prop]
+			switch {
+			case ok:
+			case prop == PropertyName:
+				value.Value = jsonString(name)
+			case prop == PropertyType:
+				value.Value = jsonString(strings.ToLower(ds.Type))
+			default:
+				value.Value = ValueUnset
+			}
+			err = parser.parseLine([]string{name, prop, string(value.Value)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser.datasets()
+}
+
+type jsonPool struct {
+	Name       string          `json:"name"`
+	State      string          `json:"state"`
+	Status     string          `json:"status"`
+	Action     string          `json:"action"`
+	ErrorCount jsonString      `json:"error_count"`
+	ScanStats  *jsonScanStats  `json:"scan_stats"`
+	Vdevs      json.RawMessage `json:"vdevs"`
+	Special    json.RawMessage `json:"special"`
+	Dedup      json.RawMessage `json:"dedup"`
+	Logs       json.RawMessage `json:"logs"`
+	L2Cache    json.RawMessage `json:"l2cache"`
+	Spares     json.RawMessage `json:"spares"`
+}
+
+type jsonVdev struct {
+	Name           string          `json:"name"`
+	State          string          `json:"state"`
+	ReadErrors     jsonString      `json:"read_errors"`
+	WriteErrors    jsonString      `json:"write_errors"`
+	ChecksumErrors jsonString      `json:"checksum_errors"`
+	Vdevs          json.RawMessage `json:"vdevs"`
+}
+
+type jsonScanStats struct {
+	Function  string     `json:"function"`
+	State     string     `json:"state"`
+	StartTime jsonString `json:"start_time"`
+	EndTime   jsonString `json:"end_time"`
+	ToExamine jsonString `json:"to_examine"`
+	Skipped   jsonString `json:"skipped"`
+	Issued    jsonString `json:"issued"`
+	Errors    jsonString `json:"errors"`
+	PauseTime jsonString `json:"scrub_pause"`
+}
+
+// parsePoolStatusJSON parses the output of `zpool status -j -p` for the pool
+func parsePoolStatusJSON(output []byte, pool string) (*PoolStatus, error) {
+	var out struct {
+		Pools json.RawMessage `json:"pools"`
+	}
+	err := json.Unmarshal(output, &out)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON output: %w", err)
+	}
+
+	var status *PoolStatus
+	err = decodeObject(out.Pools, func(name string, data json.RawMessage) error {
+		if status != nil || (pool != "" && name != pool) {
+			return nil
+		}
+		var p jsonPool
+		err := json.Unmarshal(data, &p)
+		if err != nil {
+			return fmt.Errorf("error parsing JSON output of pool %s: %w", name, err)
+		}
+		status, err = p.status()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, fmt.Errorf("no status of pool %s in JSON output: %w", pool, ErrPoolNotFound)
+	}
+	return status, nil
+}
+
+func (p *jsonPool) status() (*PoolStatus, error) {
+	status := &PoolStatus{
+		Name:   p.Name,
+		State:  p.State,
+		Status: p.Status,
+		Action: p.Action,
+		Scan:   p.ScanStats.scan(),
+	}
+	roots, err := jsonVdevs(p.Vdevs)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) > 0 {
+		status.Root = roots[0]
+	}
+	for _, section := range []struct {
+		data json.RawMessage
+		dst  *[]VdevStatus
+	}{
+		{p.Special, &status.Special},
+		{p.Dedup, &status.Dedup},
+		{p.Logs, &status.Logs},
+		{p.L2Cache, &status.Cache},
+		{p.Spares, &status.Spares},
+	} {
+		*section.dst, err = jsonVdevs(section.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Like the text output, only the leaf devices are added up, leaving out the spares
+	var addLeaves func(vdevs []VdevStatus)
+	addLeaves = func(vdevs []VdevStatus) {
+		for _, vdev := range vdevs {
+			if len(vdev.Children) > 0 {
+				addLeaves(vdev.Children)
+				continue
+			}
+			status.Errors.Read += vdev.Read
+			status.Errors.Write += vdev.Write
+			status.Errors.Checksum += vdev.Checksum
+		}
+	}
+	addLeaves(status.Root.Children)
+	for _, section := range [][]VdevStatus{status.Special, status.Dedup, status.Logs, status.Cache} {
+		addLeaves(section)
+	}
+	status.Errors.Data = p.ErrorCount.uint()
+	return status, nil
+}
+
+// jsonVdevs returns the vdevs of the JSON object of vdevs by name
+func jsonVdevs(data json.RawMessage) ([]VdevStatus, error) {
+	var vdevs []VdevStatus
+	err := decodeObject(data, func(name string, data json.RawMessage) error {
+		var v jsonVdev
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			return fmt.Errorf("error parsing JSON output of vdev %s: %w", name, err)
+		}
+		vdev := VdevStatus{
+			Name:     name,
+			State:    v.State,
+			Read:     v.ReadErrors.uint(),
+			Write:    v.WriteErrors.uint(),
+			Checksum: v.ChecksumErrors.uint(),
+		}
+		vdev.Children, err = jsonVdevs(v.Vdevs)
+		if err != nil {
+			return err
+		}
+		vdevs = append(vdevs, vdev)
+		return nil
+	})
+	return vdevs, err
+}
+
+// scan returns the scan of the scan stats, which are left out when no scan was requested
+func (s *jsonScanStats) scan() *PoolScan {
+	scan := &PoolScan{State: ScanNone}
+	if s == nil || s.Function == "" || strings.EqualFold(s.Function, ScanNone) {
+		return scan
+	}
+	scan.Function = strings.ToLower(s.Function)
+	scan.Errors = s.Errors.uint()
+	switch strings.ToUpper(s.State) {
+	case "SCANNING":
+		scan.State = ScanScanning
+		if s.PauseTime != "" && s.PauseTime != ValueUnset && s.PauseTime != "0" {
+			scan.State = ScanPaused
+		}
+		scan.StartedAt = parseJSONTime(s.StartTime)
+		if total := s.ToExamine.uint() - min(s.Skipped.uint(), s.ToExamine.uint()); total > 0 {
+			scan.Progress = float64(s.Issued.uint()) / float64(total) * 100
+		}
+	case "FINISHED":
+		scan.State = ScanFinished
+		scan.EndedAt = parseJSONTime(s.EndTime)
+	case "CANCELED":
+		scan.State = ScanCanceled
+		scan.EndedAt = parseJSONTime(s.EndTime)
+	}
+	return scan
+}
+
+// parseJSONTime parses a time of the JSON output, which is in seconds with -p
+func parseJSONTime(value jsonString) time.Time {
+	if secs, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+		return time.Unix(secs, 0)
+	}
+	tm, _ := time.ParseInLocation(scanTimeLayout, string(value), time.Local)
+	return tm
+}
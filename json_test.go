@@ -0,0 +1,165 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDatasetsJSON = `{
+  "output_version": {"command": "zfs get", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/b": {
+      "name": "tank/b",
+      "type": "FILESYSTEM",
+      "pool": "tank",
+      "createtxg": "12",
+      "properties": {
+        "used": {"value": "4096", "source": {"type": "NONE", "data": "-"}},
+        "guid": {"value": "1234", "source": {"type": "NONE", "data": "-"}},
+        "nl.test:prop": {"value": "value with\ttab", "source": {"type": "LOCAL", "data": "-"}}
+      }
+    },
+    "tank/a": {
+      "name": "tank/a",
+      "type": "VOLUME",
+      "pool": "tank",
+      "createtxg": "10",
+      "properties": {
+        "used": {"value": 8192, "source": {"type": "NONE", "data": "-"}},
+        "guid": {"value": 5678, "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}`
+
+func Test_parseDatasetsJSON(t *testing.T) {
+	fields := []string{PropertyName, PropertyType, PropertyUsed, PropertyGUID}
+	ds, err := ParseDatasetsJSON([]byte(testDatasetsJSON), fields, []string{"nl.test:prop"})
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+
+	require.Equal(t, "tank/b", ds[0].Name)
+	require.Equal(t, DatasetFilesystem, ds[0].Type)
+	require.EqualValues(t, 4096, ds[0].Used)
+	require.EqualValues(t, 1234, ds[0].GUID)
+	require.Equal(t, "value with\ttab", ds[0].ExtraProps["nl.test:prop"])
+
+	require.Equal(t, "tank/a", ds[1].Name)
+	require.Equal(t, DatasetVolume, ds[1].Type)
+	require.EqualValues(t, 8192, ds[1].Used)
+	require.EqualValues(t, 5678, ds[1].GUID)
+	require.Empty(t, ds[1].ExtraProps["nl.test:prop"])
+}
+
+func Test_ListDatasetsJSON(t *testing.T) {
+	platform := CurrentPlatform()
+	platform.JSONOutput = true
+	SetPlatform(&platform)
+	defer SetPlatform(nil)
+
+	var executed []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		_, err := io.WriteString(stdout, testDatasetsJSON)
+		return "", err
+	}))
+	ds, err := ListDatasets(ctx, ListOptions{
+		ParentDataset: "tank",
+		Fields:        []string{PropertyName, PropertyUsed},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"get", "-j", "-p", "name,used", "tank"}, executed)
+	require.Len(t, ds, 2)
+	require.Equal(t, "tank/b", ds[0].Name)
+	require.EqualValues(t, 8192, ds[1].Used)
+}
+
+const testPoolStatusJSON = `{
+  "output_version": {"command": "zpool status", "vers_major": 0, "vers_minor": 1},
+  "pools": {
+    "tank": {
+      "name": "tank",
+      "state": "DEGRADED",
+      "pool_guid": "123",
+      "status": "One or more devices has experienced an unrecoverable error.",
+      "action": "Determine if the device needs to be replaced.",
+      "error_count": "4",
+      "scan_stats": {
+        "function": "SCRUB",
+        "state": "FINISHED",
+        "start_time": "1700000000",
+        "end_time": "1700003600",
+        "to_examine": "1000",
+        "skipped": "0",
+        "issued": "1000",
+        "errors": "0"
+      },
+      "vdevs": {
+        "tank": {
+          "name": "tank",
+          "vdev_type": "root",
+          "state": "DEGRADED",
+          "read_errors": "0",
+          "write_errors": "0",
+          "checksum_errors": "0",
+          "vdevs": {
+            "mirror-0": {
+              "name": "mirror-0",
+              "vdev_type": "mirror",
+              "state": "DEGRADED",
+              "read_errors": "0",
+              "write_errors": "0",
+              "checksum_errors": "2",
+              "vdevs": {
+                "sda": {"name": "sda", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"},
+                "sdb": {"name": "sdb", "vdev_type": "disk", "state": "DEGRADED", "read_errors": "1", "write_errors": "0", "checksum_errors": "2"}
+              }
+            },
+            "sdc": {"name": "sdc", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "3", "checksum_errors": "0"}
+          }
+        }
+      },
+      "logs": {
+        "sdd": {"name": "sdd", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"}
+      },
+      "spares": {
+        "sde": {"name": "sde", "vdev_type": "disk", "state": "AVAIL"}
+      }
+    }
+  }
+}`
+
+func Test_parsePoolStatusJSON(t *testing.T) {
+	status, err := ParsePoolStatusJSON([]byte(testPoolStatusJSON), "tank")
+	require.NoError(t, err)
+	require.Equal(t, "tank", status.Name)
+	require.Equal(t, PoolDegraded, status.State)
+	require.Equal(t, "One or more devices has experienced an unrecoverable error.", status.Status)
+	require.Equal(t, PoolErrors{Read: 1, Write: 3, Checksum: 2, Data: 4}, status.Errors)
+
+	require.Equal(t, "scrub", status.Scan.Function)
+	require.Equal(t, ScanFinished, status.Scan.State)
+	require.True(t, status.Scan.EndedAt.Equal(time.Unix(1700003600, 0)))
+
+	require.Equal(t, VdevStatus{
+		Name:  "tank",
+		State: PoolDegraded,
+		Children: []VdevStatus{
+			{Name: "mirror-0", State: PoolDegraded, Checksum: 2, Children: []VdevStatus{
+				{Name: "sda", State: PoolOnline},
+				{Name: "sdb", State: PoolDegraded, Read: 1, Checksum: 2},
+			}},
+			{Name: "sdc", State: PoolOnline, Write: 3},
+		},
+	}, status.Root)
+	require.Equal(t, []VdevStatus{{Name: "sdd", State: PoolOnline}}, status.Logs)
+	require.Equal(t, []VdevStatus{{Name: "sde", State: DeviceAvail}}, status.Spares)
+	require.Empty(t, status.Cache)
+
+	_, err = ParsePoolStatusJSON([]byte(testPoolStatusJSON), "other")
+	require.ErrorIs(t, err, ErrPoolNotFound)
+}
@@ -0,0 +1,185 @@
+package zfs
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// CommandCategory is the category of a zfs or zpool command, used to weigh it when limiting concurrency
+type CommandCategory string
+
+// Command categories, which determine the weight of a command when concurrency is limited.
+const (
+	// CommandCategoryRead are commands that only read state, such as list and get
+	CommandCategoryRead CommandCategory = "read"
+	// CommandCategoryWrite are commands that change state, such as snapshot, set or destroy
+	CommandCategoryWrite CommandCategory = "write"
+	// CommandCategoryStream are long-running commands that stream data, such as send and receive
+	CommandCategoryStream CommandCategory = "stream"
+)
+
+var commandCategories = map[string]CommandCategory{
+	"list":         CommandCategoryRead,
+	"get":          CommandCategoryRead,
+	"holds":        CommandCategoryRead,
+	"diff":         CommandCategoryRead,
+	"status":       CommandCategoryRead,
+	"events":       CommandCategoryRead,
+	"version":      CommandCategoryRead,
+	"userspace":    CommandCategoryRead,
+	"groupspace":   CommandCategoryRead,
+	"projectspace": CommandCategoryRead,
+	"wait":         CommandCategoryRead,
+	"send":         CommandCategoryStream,
+	"recv":         CommandCategoryStream,
+	"receive":      CommandCategoryStream,
+}
+
+// commandCategory returns the category for a command by its subcommand argument
+func commandCategory(arg []string) CommandCategory {
+	if len(arg) == 0 {
+		return CommandCategoryWrite
+	}
+	category, ok := commandCategories[arg[0]]
+	if !ok {
+		return CommandCategoryWrite
+	}
+	return category
+}
+
+// CommandLimits configures the package-wide limit on concurrently running zfs and zpool processes.
+// Every running command claims its category's weight from the maximum weight, commands that do not fit wait
+// until enough weight has been released.
+type CommandLimits struct {
+	// MaximumWeight is the total weight of commands allowed to run concurrently, zero disables the limit
+	MaximumWeight int64
+	// Weights sets the weight per command category, categories without a weight have a weight of one
+	Weights map[CommandCategory]int64
+}
+
+var commandLimiter atomic.Pointer[limiter]
+
+// SetCommandLimits sets the package-wide limits on concurrently running commands.
+// Commands that are already running or waiting are not affected by the new limits.
+func SetCommandLimits(limits CommandLimits) {
+	if limits.MaximumWeight <= 0 {
+		commandLimiter.Store(nil)
+		return
+	}
+
+	weights := make(map[CommandCategory]int64, len(limits.Weights))
+	for category, weight := range limits.Weights {
+		weights[category] = weight
+	}
+	commandLimiter.Store(newLimiter(limits.MaximumWeight, weights))
+}
+
+// acquireCommandSlot waits until the command is allowed to run, the returned function releases the slot again
+func acquireCommandSlot(ctx context.Context, arg []string) (release func(), err error) {
+	l := commandLimiter.Load()
+	if l == nil {
+		return func() {}, nil
+	}
+
+	weight := l.weight(commandCategory(arg))
+	err = l.acquire(ctx, weight)
+	if err != nil {
+		return func() {}, err
+	}
+	return func() {
+		l.release(weight)
+	}, nil
+}
+
+// limiter is a weighted semaphore, which serves its waiters in order of arrival
+type limiter struct {
+	size    int64
+	weights map[CommandCategory]int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List
+}
+
+type limiterWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newLimiter(size int64, weights map[CommandCategory]int64) *limiter {
+	return &limiter{
+		size:    size,
+		weights: weights,
+	}
+}
+
+func (l *limiter) weight(category CommandCategory) int64 {
+	weight, ok := l.weights[category]
+	if !ok || weight <= 0 {
+		weight = 1
+	}
+	// Never claim more than the total size, or the command could never run
+	return min(weight, l.size)
+}
+
+func (l *limiter) acquire(ctx context.Context, n int64) error {
+	l.mu.Lock()
+	if l.size-l.cur >= n && l.waiters.Len() == 0 {
+		l.cur += n
+		l.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(limiterWaiter{n: n, ready: ready})
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-ready:
+			// Acquired just after the context was done, give it back
+			l.cur -= n
+			l.notifyWaiters()
+		default:
+			isFront := l.waiters.Front() == elem
+			l.waiters.Remove(elem)
+			if isFront && l.size > l.cur {
+				l.notifyWaiters()
+			}
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release(n int64) {
+	l.mu.Lock()
+	l.cur -= n
+	l.notifyWaiters()
+	l.mu.Unlock()
+}
+
+func (l *limiter) notifyWaiters() {
+	for {
+		next := l.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(limiterWaiter)
+		if l.size-l.cur < w.n {
+			// Not enough room for the next waiter, keep the order of arrival
+			return
+		}
+
+		l.cur += w.n
+		l.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,53 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_commandCategory(t *testing.T) {
+	require.Equal(t, CommandCategoryRead, commandCategory([]string{"get", "-Hp"}))
+	require.Equal(t, CommandCategoryStream, commandCategory([]string{"send", "-w"}))
+	require.Equal(t, CommandCategoryWrite, commandCategory([]string{"destroy"}))
+	require.Equal(t, CommandCategoryWrite, commandCategory(nil))
+}
+
+func Test_limiter(t *testing.T) {
+	l := newLimiter(3, map[CommandCategory]int64{
+		CommandCategoryStream: 2,
+		CommandCategoryWrite:  10,
+	})
+	require.EqualValues(t, 1, l.weight(CommandCategoryRead))
+	require.EqualValues(t, 2, l.weight(CommandCategoryStream))
+	require.EqualValues(t, 3, l.weight(CommandCategoryWrite))
+
+	require.NoError(t, l.acquire(context.Background(), 2))
+	require.NoError(t, l.acquire(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.acquire(ctx, 1), context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		require.NoError(t, l.acquire(context.Background(), 2))
+		close(acquired)
+	}()
+
+	l.release(1)
+	select {
+	case <-acquired:
+		t.Fatal("acquired without enough room")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.release(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("not acquired after release")
+	}
+}
@@ -0,0 +1,80 @@
+// Package logging creates the slog loggers of the commands, and defines the attribute keys all packages log with,
+// so the logs of the subsystems can be ingested and queried the same way.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Attribute keys shared by the packages
+const (
+	// KeyError is the error that occurred
+	KeyError = "error"
+	// KeyDataset is the full name of a filesystem or volume
+	KeyDataset = "dataset"
+	// KeySnapshot is the full name of a snapshot, including its dataset
+	KeySnapshot = "snapshot"
+	// KeyJob is the name of a job of the job runner
+	KeyJob = "job"
+	// KeyRequestID identifies an HTTP request
+	KeyRequestID = "request_id"
+	// KeyTarget is the server or object store snapshots are sent to
+	KeyTarget = "target"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config configures a logger
+type Config struct {
+	// LogLevel is the minimum level to log: debug, info, warn or error
+	LogLevel string `json:"LogLevel" yaml:"LogLevel"`
+	// LogFormat is either text or json
+	LogFormat string `json:"LogFormat" yaml:"LogFormat"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *Config) ApplyDefaults() {
+	c.LogLevel = slog.LevelInfo.String()
+	c.LogFormat = FormatText
+}
+
+// Validate returns an error when the level or format is invalid
+func (c *Config) Validate() error {
+	_, err := c.level()
+	if err != nil {
+		return err
+	}
+	if c.LogFormat != FormatText && c.LogFormat != FormatJSON {
+		return fmt.Errorf("invalid log format %q, expected %s or %s", c.LogFormat, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+func (c *Config) level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	if err != nil {
+		return level, fmt.Errorf("invalid log level %q: %w", c.LogLevel, err)
+	}
+	return level, nil
+}
+
+// NewLogger creates a logger writing to w in the configured format
+func (c *Config) NewLogger(w io.Writer) (*slog.Logger, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	level, _ := c.level()
+	options := &slog.HandlerOptions{Level: level}
+	if c.LogFormat == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, options)), nil
+}
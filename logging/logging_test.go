@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_NewLogger(t *testing.T) {
+	conf := Config{}
+	conf.ApplyDefaults()
+	conf.LogFormat = FormatJSON
+
+	var buf bytes.Buffer
+	logger, err := conf.NewLogger(&buf)
+	require.NoError(t, err)
+	logger.Debug("Hidden")
+	logger.Info("Message", KeyDataset, "pool/fs", KeyRequestID, "abc")
+
+	entry := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "Message", entry["msg"])
+	require.Equal(t, "pool/fs", entry["dataset"])
+	require.Equal(t, "abc", entry["request_id"])
+
+	conf.LogLevel = "debug"
+	conf.LogFormat = FormatText
+	buf.Reset()
+	logger, err = conf.NewLogger(&buf)
+	require.NoError(t, err)
+	logger.Debug("Shown")
+	require.Contains(t, buf.String(), "level=DEBUG msg=Shown")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	conf := Config{}
+	conf.ApplyDefaults()
+	require.NoError(t, conf.Validate())
+
+	conf.LogLevel = "loud"
+	require.ErrorContains(t, conf.Validate(), "invalid log level")
+
+	conf.ApplyDefaults()
+	conf.LogFormat = "xml"
+	require.ErrorContains(t, conf.Validate(), "invalid log format")
+}
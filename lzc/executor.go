@@ -0,0 +1,243 @@
+// Package lzc runs the most frequent zfs commands through libzfs_core instead of forking the zfs binary, which
+// dominates the time spent when managing tens of thousands of datasets. The Executor handles creating, destroying,
+// holding, releasing and bookmarking snapshots and sending snapshots, all other commands are passed to a fallback
+// executor, the zfs binary by default:
+//
+//	zfs.SetExecutor(lzc.New())
+//
+// libzfs_core is only used when built with cgo and the libzfs_core build tag on Linux, which requires the libzfs_core
+// and libnvpair headers and pkg-config file, like those of the libzfslinux-dev package. Otherwise, or when /dev/zfs
+// cannot be opened, all commands are passed to the fallback.
+package lzc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// ErrUnavailable is returned by Init when libzfs_core cannot be used
+var ErrUnavailable = errors.New("libzfs_core is unavailable")
+
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// Init opens libzfs_core, it returns ErrUnavailable when the package is built without it.
+// The Executor calls it on first use, and passes all commands to its fallback when it fails.
+func Init() error {
+	initOnce.Do(func() {
+		initErr = initLibrary()
+	})
+	return initErr
+}
+
+// Executor runs the zfs commands libzfs_core supports through it, and all other commands through its fallback
+type Executor struct {
+	// Fallback executes the commands libzfs_core does not support
+	Fallback zfs.Executor
+}
+
+// New creates an executor that runs the other commands with the zfs binary
+func New() *Executor {
+	return &Executor{Fallback: &zfs.ExecExecutor{}}
+}
+
+// Execute runs the command, see zfs.Executor
+func (e *Executor) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	op, ok := parseOperation(cmd, args)
+	if !ok || Init() != nil {
+		return e.Fallback.Execute(ctx, cmd, args, stdin, stdout)
+	}
+	err := op.run(ctx, stdout)
+	if err != nil {
+		return op.stderr(err), err
+	}
+	return "", nil
+}
+
+type operationKind int
+
+const (
+	opSnapshot operationKind = iota
+	opDestroy
+	opHold
+	opRelease
+	opBookmark
+	opSend
+)
+
+// operation is a zfs command libzfs_core can run
+type operation struct {
+	kind operationKind
+	// name is the snapshot, or the bookmark to create
+	name string
+	// source is the snapshot to bookmark, or the incremental base to send from
+	source string
+	tag    string
+	// props are the user properties of new snapshots
+	props map[string]string
+	// deferred marks snapshots for deferred destruction
+	deferred bool
+	raw      bool
+}
+
+// parseOperation returns the operation for the arguments of the zfs command, when libzfs_core supports it with the
+// given flags. Recursive operations are not supported, as the command lists the descendants itself.
+func parseOperation(cmd string, args []string) (*operation, bool) {
+	if cmd != zfs.Binary || len(args) == 0 {
+		return nil, false
+	}
+	flags, operands, ok := splitArgs(args[1:])
+	if !ok {
+		return nil, false
+	}
+
+	switch args[0] {
+	case "snapshot":
+		op := &operation{kind: opSnapshot, props: make(map[string]string)}
+		for _, flag := range flags {
+			prop, value, ok := strings.Cut(flag.value, "=")
+			if flag.name != 'o' || !ok || !strings.Contains(prop, ":") {
+				return nil, false // Only user properties can be set with lzc_snapshot
+			}
+			op.props[prop] = value
+		}
+		if len(operands) != 1 || !strings.Contains(operands[0], "@") {
+			return nil, false
+		}
+		op.name = operands[0]
+		return op, true
+
+	case "destroy":
+		op := &operation{kind: opDestroy}
+		for _, flag := range flags {
+			if flag.name != 'd' {
+				return nil, false
+			}
+			op.deferred = true
+		}
+		if len(operands) != 1 || !strings.Contains(operands[0], "@") || strings.ContainsAny(operands[0], "%,") {
+			return nil, false
+		}
+		op.name = operands[0]
+		return op, true
+
+	case "hold", "release":
+		if len(flags) > 0 || len(operands) != 2 {
+			return nil, false
+		}
+		op := &operation{kind: opHold, tag: operands[0], name: operands[1]}
+		if args[0] == "release" {
+			op.kind = opRelease
+		}
+		return op, true
+
+	case "bookmark":
+		if len(flags) > 0 || len(operands) != 2 || !strings.Contains(operands[0], "@") {
+			return nil, false
+		}
+		return &operation{kind: opBookmark, source: operands[0], name: operands[1]}, true
+
+	case "send":
+		op := &operation{kind: opSend}
+		for _, flag := range flags {
+			switch flag.name {
+			case 'w':
+				op.raw = true
+			case 'i':
+				op.source = flag.value
+			default:
+				return nil, false // Like -p for properties, which lzc_send does not send
+			}
+		}
+		if len(operands) != 1 {
+			return nil, false
+		}
+		op.name = operands[0]
+		if strings.HasPrefix(op.source, "@") || strings.HasPrefix(op.source, "#") {
+			dataset, _, _ := strings.Cut(op.name, "@")
+			op.source = dataset + op.source
+		}
+		return op, true
+	}
+	return nil, false
+}
+
+type flag struct {
+	name  byte
+	value string
+}
+
+// valueFlags are the flags of the supported commands that take a value
+const valueFlags = "oi"
+
+// splitArgs splits the arguments in flags and operands, flags can be combined like -Lec
+func splitArgs(args []string) ([]flag, []string, bool) {
+	var flags []flag
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			return flags, args[i:], true
+		}
+		for j := 1; j < len(arg); j++ {
+			if !strings.ContainsRune(valueFlags, rune(arg[j])) {
+				flags = append(flags, flag{name: arg[j]})
+				continue
+			}
+			value := arg[j+1:]
+			if value == "" {
+				i++
+				if i == len(args) {
+					return nil, nil, false
+				}
+				value = args[i]
+			}
+			flags = append(flags, flag{name: arg[j], value: value})
+			break
+		}
+	}
+	return flags, nil, true
+}
+
+// stderr returns the message the zfs command prints for the error, so the zfs package returns the same errors
+func (op *operation) stderr(err error) string {
+	var action string
+	switch op.kind {
+	case opSnapshot:
+		action = fmt.Sprintf("cannot create snapshot '%s'", op.name)
+	case opDestroy:
+		action = fmt.Sprintf("cannot destroy snapshot %s", op.name)
+	case opHold:
+		action = fmt.Sprintf("cannot hold snapshot '%s'", op.name)
+	case opRelease:
+		action = fmt.Sprintf("cannot release hold from snapshot '%s'", op.name)
+	case opBookmark:
+		action = fmt.Sprintf("cannot create bookmark '%s'", op.name)
+	case opSend:
+		action = fmt.Sprintf("cannot send '%s'", op.name)
+	}
+
+	switch {
+	case op.kind == opHold && errors.Is(err, syscall.EEXIST):
+		return action + ": tag already exists on this dataset"
+	case op.kind == opRelease && (errors.Is(err, syscall.ESRCH) || errors.Is(err, syscall.ENOENT)):
+		return action + ": no such tag on this dataset"
+	case errors.Is(err, syscall.ENOENT):
+		return action + ": dataset does not exist"
+	case errors.Is(err, syscall.EEXIST):
+		return action + ": dataset already exists"
+	case errors.Is(err, syscall.EBUSY):
+		return action + ": pool or dataset is busy"
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return action + ": permission denied"
+	}
+	return fmt.Sprintf("%s: %s", action, err)
+}
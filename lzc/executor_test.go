@@ -0,0 +1,85 @@
+package lzc
+
+import (
+	"context"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+func Test_parseOperation(t *testing.T) {
+	tests := []struct {
+		args []string
+		op   *operation
+	}{
+		{[]string{"snapshot", "pool/fs@snap"}, &operation{kind: opSnapshot, name: "pool/fs@snap", props: map[string]string{}}},
+		{[]string{"snapshot", "-o", "nl.test:prop=value", "pool/fs@snap"},
+			&operation{kind: opSnapshot, name: "pool/fs@snap", props: map[string]string{"nl.test:prop": "value"}}},
+		{[]string{"snapshot", "-o", "compression=lz4", "pool/fs@snap"}, nil},
+		{[]string{"snapshot", "-r", "pool/fs@snap"}, nil},
+		{[]string{"destroy", "pool/fs@snap"}, &operation{kind: opDestroy, name: "pool/fs@snap"}},
+		{[]string{"destroy", "-d", "pool/fs@snap"}, &operation{kind: opDestroy, name: "pool/fs@snap", deferred: true}},
+		{[]string{"destroy", "pool/fs"}, nil},
+		{[]string{"destroy", "pool/fs@a%b"}, nil},
+		{[]string{"destroy", "-r", "pool/fs@snap"}, nil},
+		{[]string{"hold", "keep", "pool/fs@snap"}, &operation{kind: opHold, name: "pool/fs@snap", tag: "keep"}},
+		{[]string{"release", "keep", "pool/fs@snap"}, &operation{kind: opRelease, name: "pool/fs@snap", tag: "keep"}},
+		{[]string{"hold", "-r", "keep", "pool/fs@snap"}, nil},
+		{[]string{"bookmark", "pool/fs@snap", "pool/fs#bm"},
+			&operation{kind: opBookmark, name: "pool/fs#bm", source: "pool/fs@snap"}},
+		{[]string{"send", "-w", "-i", "#bm", "pool/fs@snap"},
+			&operation{kind: opSend, name: "pool/fs@snap", source: "pool/fs#bm", raw: true}},
+		{[]string{"send", "-ipool/fs@a", "pool/fs@b"}, &operation{kind: opSend, name: "pool/fs@b", source: "pool/fs@a"}},
+		{[]string{"send", "-p", "pool/fs@snap"}, nil},
+		{[]string{"send", "-I", "@a", "pool/fs@snap"}, nil},
+		{[]string{"send", "-t", "token"}, nil},
+		{[]string{"get", "-Hp", "name", "pool/fs"}, nil},
+	}
+	for _, test := range tests {
+		op, ok := parseOperation(zfs.Binary, test.args)
+		require.Equal(t, test.op != nil, ok, test.args)
+		require.Equal(t, test.op, op, test.args)
+	}
+
+	_, ok := parseOperation(zfs.PoolBinary, []string{"destroy", "pool"})
+	require.False(t, ok)
+}
+
+func Test_operationStderr(t *testing.T) {
+	hold := &operation{kind: opHold, name: "pool/fs@snap", tag: "keep"}
+	require.Equal(t, "cannot hold snapshot 'pool/fs@snap': tag already exists on this dataset", hold.stderr(syscall.EEXIST))
+	release := &operation{kind: opRelease, name: "pool/fs@snap", tag: "keep"}
+	require.Equal(t, "cannot release hold from snapshot 'pool/fs@snap': no such tag on this dataset",
+		release.stderr(syscall.ESRCH))
+	snapshot := &operation{kind: opSnapshot, name: "pool/fs@snap"}
+	require.Equal(t, "cannot create snapshot 'pool/fs@snap': dataset already exists", snapshot.stderr(syscall.EEXIST))
+	require.Equal(t, "cannot create snapshot 'pool/fs@snap': dataset does not exist", snapshot.stderr(syscall.ENOENT))
+	destroy := &operation{kind: opDestroy, name: "pool/fs@snap"}
+	require.Equal(t, "cannot destroy snapshot pool/fs@snap: pool or dataset is busy", destroy.stderr(syscall.EBUSY))
+}
+
+type executorFunc func(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error)
+
+func (fn executorFunc) Execute(ctx context.Context, cmd string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	return fn(ctx, cmd, args, stdin, stdout)
+}
+
+func TestExecutor_Fallback(t *testing.T) {
+	if Init() == nil {
+		t.Skip("libzfs_core is available")
+	}
+
+	var executed [][]string
+	executor := &Executor{Fallback: executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	})}
+	_, err := executor.Execute(context.Background(), zfs.Binary, []string{"snapshot", "pool/fs@snap"}, nil, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"snapshot", "pool/fs@snap"}}, executed)
+	require.ErrorIs(t, Init(), ErrUnavailable)
+}
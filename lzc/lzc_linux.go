@@ -0,0 +1,209 @@
+//go:build linux && cgo && libzfs_core
+
+package lzc
+
+/*
+#cgo pkg-config: libzfs_core
+#cgo LDFLAGS: -lnvpair
+#include <stdlib.h>
+#include <libzfs_core.h>
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func initLibrary() error {
+	if errno := C.libzfs_core_init(); errno != 0 {
+		return fmt.Errorf("%w: %w", ErrUnavailable, syscall.Errno(errno))
+	}
+	return nil
+}
+
+func (op *operation) run(ctx context.Context, stdout io.Writer) error {
+	switch op.kind {
+	case opSnapshot:
+		return op.snapshot()
+	case opDestroy:
+		return op.destroy()
+	case opHold:
+		return op.hold()
+	case opRelease:
+		return op.release()
+	case opBookmark:
+		return op.bookmark()
+	case opSend:
+		return op.send(ctx, stdout)
+	}
+	return fmt.Errorf("unknown operation %d", op.kind)
+}
+
+func (op *operation) snapshot() error {
+	snaps := newNvlist()
+	defer snaps.free()
+	snaps.addBoolean(op.name)
+	props := newNvlist()
+	defer props.free()
+	for prop, value := range op.props {
+		props.addString(prop, value)
+	}
+
+	var errlist *C.nvlist_t
+	errno := C.lzc_snapshot(snaps.nvl, props.nvl, &errlist)
+	freeErrlist(errlist)
+	return errnoError(errno)
+}
+
+func (op *operation) destroy() error {
+	snaps := newNvlist()
+	defer snaps.free()
+	snaps.addBoolean(op.name)
+
+	deferred := C.boolean_t(C.B_FALSE)
+	if op.deferred {
+		deferred = C.B_TRUE
+	}
+	var errlist *C.nvlist_t
+	errno := C.lzc_destroy_snaps(snaps.nvl, deferred, &errlist)
+	freeErrlist(errlist)
+	return errnoError(errno)
+}
+
+func (op *operation) hold() error {
+	holds := newNvlist()
+	defer holds.free()
+	holds.addString(op.name, op.tag)
+
+	var errlist *C.nvlist_t
+	errno := C.lzc_hold(holds.nvl, -1, &errlist)
+	freeErrlist(errlist)
+	return errnoError(errno)
+}
+
+func (op *operation) release() error {
+	tags := newNvlist()
+	defer tags.free()
+	tags.addBoolean(op.tag)
+	holds := newNvlist()
+	defer holds.free()
+	holds.addNvlist(op.name, tags)
+
+	var errlist *C.nvlist_t
+	errno := C.lzc_release(holds.nvl, &errlist)
+	freeErrlist(errlist)
+	return errnoError(errno)
+}
+
+func (op *operation) bookmark() error {
+	bookmarks := newNvlist()
+	defer bookmarks.free()
+	bookmarks.addString(op.name, op.source)
+
+	var errlist *C.nvlist_t
+	errno := C.lzc_bookmark(bookmarks.nvl, &errlist)
+	freeErrlist(errlist)
+	return errnoError(errno)
+}
+
+// send writes the stream to a pipe, which is copied to the output. Closing the read end of the pipe when the
+// context is done or the output fails makes lzc_send fail, as it cannot be cancelled otherwise.
+func (op *operation) send(ctx context.Context, stdout io.Writer) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(stdout, r)
+		if err != nil {
+			_ = r.Close()
+		}
+		copied <- err
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		_ = r.Close()
+	})
+	defer stop()
+
+	name := C.CString(op.name)
+	defer C.free(unsafe.Pointer(name))
+	var from *C.char
+	if op.source != "" {
+		from = C.CString(op.source)
+		defer C.free(unsafe.Pointer(from))
+	}
+	var flags C.enum_lzc_send_flags
+	if op.raw {
+		flags |= C.LZC_SEND_FLAG_RAW
+	}
+
+	errno := C.lzc_send(name, from, C.int(w.Fd()), flags)
+	closeErr := w.Close()
+	copyErr := <-copied
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case copyErr != nil:
+		return copyErr
+	case errno != 0:
+		return errnoError(errno)
+	}
+	return closeErr
+}
+
+// nvlist is a name value list passed to libzfs_core
+type nvlist struct {
+	nvl *C.nvlist_t
+}
+
+func newNvlist() nvlist {
+	return nvlist{nvl: C.fnvlist_alloc()}
+}
+
+func (l nvlist) free() {
+	C.fnvlist_free(l.nvl)
+}
+
+func (l nvlist) addBoolean(name string) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	C.fnvlist_add_boolean(l.nvl, cName)
+}
+
+func (l nvlist) addString(name, value string) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+	C.fnvlist_add_string(l.nvl, cName, cValue)
+}
+
+// addNvlist adds a copy of the list
+func (l nvlist) addNvlist(name string, value nvlist) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	C.fnvlist_add_nvlist(l.nvl, cName, value.nvl)
+}
+
+func freeErrlist(errlist *C.nvlist_t) {
+	if errlist != nil {
+		C.fnvlist_free(errlist)
+	}
+}
+
+func errnoError(errno C.int) error {
+	if errno == 0 {
+		return nil
+	}
+	return syscall.Errno(errno)
+}
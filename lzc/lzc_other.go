@@ -0,0 +1,16 @@
+//go:build !(linux && cgo && libzfs_core)
+
+package lzc
+
+import (
+	"context"
+	"io"
+)
+
+func initLibrary() error {
+	return ErrUnavailable
+}
+
+func (op *operation) run(context.Context, io.Writer) error {
+	return ErrUnavailable
+}
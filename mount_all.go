@@ -0,0 +1,96 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MountAllOptions are options you can specify to customize mounting all filesystems
+type MountAllOptions struct {
+	// Load keys for encrypted filesystems as they are being mounted, see MountOptions
+	LoadKeys bool
+
+	// Perform overlay mounts. Allows mounting in non-empty mountpoints.
+	OverlayMount bool
+}
+
+// MountError is the error of mounting or unmounting one filesystem with MountAll or UnmountAll
+type MountError struct {
+	// Dataset is the filesystem, or its mountpoint when zfs reports that instead
+	Dataset string
+	// Err is the CommandError of the command, with only the stderr about the filesystem
+	Err error
+}
+
+// Error returns the error of the filesystem
+func (e *MountError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Dataset, e.Err)
+}
+
+// Unwrap returns the CommandError of the filesystem
+func (e *MountError) Unwrap() error {
+	return e.Err
+}
+
+// MountAll mounts all filesystems that have canmount=on, like on boot. zfs mounts them in parallel.
+// When filesystems fail to mount, the others are still mounted and a MountError is returned for every failed
+// filesystem, joined with errors.Join.
+func MountAll(ctx context.Context, options MountAllOptions) error {
+	args := make([]string, 1, 4)
+	args[0] = "mount"
+	if options.OverlayMount {
+		args = append(args, "-O")
+	}
+	if options.LoadKeys {
+		if p := CurrentPlatform(); !p.MountLoadKeys {
+			return p.notSupported("mount -l")
+		}
+		args = append(args, "-l")
+	}
+	args = append(args, "-a")
+
+	return mountErrors(zfs(ctx, args...))
+}
+
+// UnmountAll unmounts all mounted filesystems, forcefully when force is set.
+// When filesystems fail to unmount, the others are still unmounted and a MountError is returned for every failed
+// filesystem, joined with errors.Join.
+func UnmountAll(ctx context.Context, force bool) error {
+	args := make([]string, 1, 3)
+	args[0] = "umount"
+	if force {
+		args = append(args, "-f")
+	}
+	args = append(args, "-a")
+
+	return mountErrors(zfs(ctx, args...))
+}
+
+var mountErrorLine = regexp.MustCompile(`^cannot (?:mount|unmount) '([^']+)': (.+)$`)
+
+// mountErrors splits the error of mounting or unmounting all filesystems into a MountError per filesystem
+func mountErrors(err error) error {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return err
+	}
+
+	var errs []error
+	for _, line := range strings.Split(cmdErr.Stderr, "\n") {
+		match := mountErrorLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		lineErr := *cmdErr
+		lineErr.Stderr = match[0]
+		lineErr.Kind = errorKind(match[0])
+		errs = append(errs, &MountError{Dataset: match[1], Err: &lineErr})
+	}
+	if len(errs) == 0 {
+		return err
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,44 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MountAll(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[0] == "umount" {
+			return "", nil
+		}
+		return "cannot mount 'pool/a': filesystem already mounted\n" +
+			"cannot mount '/srv/b': directory is not empty\n", errors.New("exit status 1")
+	}))
+
+	err := MountAll(ctx, MountAllOptions{LoadKeys: true})
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrFilesystemAlreadyMounted)
+
+	var mountErrs []*MountError
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var mountErr *MountError
+		require.ErrorAs(t, err, &mountErr)
+		mountErrs = append(mountErrs, mountErr)
+	}
+	require.Len(t, mountErrs, 2)
+	require.Equal(t, "pool/a", mountErrs[0].Dataset)
+	require.ErrorIs(t, mountErrs[0], ErrFilesystemAlreadyMounted)
+	require.Equal(t, "/srv/b", mountErrs[1].Dataset)
+	require.NotErrorIs(t, mountErrs[1], ErrFilesystemAlreadyMounted)
+
+	require.NoError(t, UnmountAll(ctx, true))
+	require.Equal(t, [][]string{
+		{"mount", "-l", "-a"},
+		{"umount", "-f", "-a"},
+	}, executed)
+}
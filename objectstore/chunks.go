@@ -0,0 +1,189 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrChecksumMismatch is returned when a chunk read back does not match the manifest
+var ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+
+// chunkWriter splits the stream written to it into chunk objects, uploading every chunk in parts
+type chunkWriter struct {
+	ctx       context.Context
+	store     ObjectStore
+	keyPrefix string
+	chunkSize int64
+	partSize  int
+
+	chunks []Chunk
+
+	// The chunk being uploaded, the hash is nil in between chunks
+	key      string
+	size     int64
+	hash     hash.Hash
+	uploadID string
+	parts    []CompletedPart
+	buf      []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.hash == nil {
+			w.key = fmt.Sprintf("%s/%06d", w.keyPrefix, len(w.chunks))
+			w.size = 0
+			w.hash = sha256.New()
+		}
+
+		n := min(len(p), int(w.chunkSize-w.size), w.partSize-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		w.hash.Write(p[:n])
+		w.size += int64(n)
+		p = p[n:]
+		written += n
+
+		var err error
+		switch {
+		case w.size == w.chunkSize:
+			err = w.finishChunk()
+		case len(w.buf) == w.partSize:
+			err = w.uploadPart()
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (w *chunkWriter) uploadPart() error {
+	if w.uploadID == "" {
+		uploadID, err := w.store.CreateMultipartUpload(w.ctx, w.key)
+		if err != nil {
+			return fmt.Errorf("error creating multipart upload of %s: %w", w.key, err)
+		}
+		w.uploadID = uploadID
+	}
+
+	partNumber := len(w.parts) + 1
+	etag, err := w.store.UploadPart(w.ctx, w.key, w.uploadID, partNumber, w.buf)
+	if err != nil {
+		return fmt.Errorf("error uploading part %d of %s: %w", partNumber, w.key, err)
+	}
+	w.parts = append(w.parts, CompletedPart{PartNumber: partNumber, ETag: etag})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// finishChunk uploads the rest of the chunk, a chunk that fits in a single part is stored without multipart upload
+func (w *chunkWriter) finishChunk() error {
+	if w.uploadID == "" {
+		err := w.store.PutObject(w.ctx, w.key, w.buf)
+		if err != nil {
+			return fmt.Errorf("error storing %s: %w", w.key, err)
+		}
+	} else {
+		err := w.uploadPart()
+		if err != nil {
+			return err
+		}
+		err = w.store.CompleteMultipartUpload(w.ctx, w.key, w.uploadID, w.parts)
+		if err != nil {
+			return fmt.Errorf("error completing multipart upload of %s: %w", w.key, err)
+		}
+	}
+
+	w.chunks = append(w.chunks, Chunk{
+		Key:    w.key,
+		Size:   w.size,
+		SHA256: hex.EncodeToString(w.hash.Sum(nil)),
+	})
+	w.hash = nil
+	w.uploadID = ""
+	w.parts = w.parts[:0]
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close uploads the last chunk
+func (w *chunkWriter) Close() error {
+	if w.hash == nil {
+		return nil
+	}
+	return w.finishChunk()
+}
+
+// abort aborts the multipart upload in progress, if any
+func (w *chunkWriter) abort() error {
+	if w.uploadID == "" {
+		return nil
+	}
+	return w.store.AbortMultipartUpload(context.WithoutCancel(w.ctx), w.key, w.uploadID)
+}
+
+// chunkReader reads the chunks in order, verifying them against their size and checksum
+type chunkReader struct {
+	ctx    context.Context
+	store  ObjectStore
+	chunks []Chunk
+
+	current io.ReadCloser
+	size    int64
+	hash    hash.Hash
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			obj, err := r.store.GetObject(r.ctx, r.chunks[0].Key)
+			if err != nil {
+				return 0, fmt.Errorf("error retrieving %s: %w", r.chunks[0].Key, err)
+			}
+			r.current = obj
+			r.size = 0
+			r.hash = sha256.New()
+		}
+
+		n, err := r.current.Read(p)
+		r.size += int64(n)
+		r.hash.Write(p[:n])
+		if errors.Is(err, io.EOF) {
+			err = r.finishChunk()
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// finishChunk verifies the chunk that was read completely, and moves on to the next one
+func (r *chunkReader) finishChunk() error {
+	chunk := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	err := r.current.Close()
+	r.current = nil
+	if err != nil {
+		return err
+	}
+	if r.size != chunk.Size || hex.EncodeToString(r.hash.Sum(nil)) != chunk.SHA256 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, chunk.Key)
+	}
+	return nil
+}
+
+// Close closes the chunk being read
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
@@ -0,0 +1,219 @@
+package objectstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted streams start with the header and a random salt, followed by segments. Every segment is the length of
+// its plaintext followed by the sealed plaintext. Every stream is sealed with its own key, derived from the configured
+// key and the salt with HKDF-SHA256, so the segment number can be the nonce without ever repeating it for a key.
+// The last segment is sealed with different additional data, so truncated streams are detected.
+//
+// Only primitives of the standard library are used, AES-GCM and HMAC-SHA256. HKDF is not in the standard library
+// of Go 1.22 and is small enough to implement here, rather than adding golang.org/x/crypto for it.
+const (
+	encryptionHeader      = "zfsenc2\n"
+	encryptionKeySize     = 32 // AES-256
+	encryptionSaltSize    = 32
+	encryptionNonceSize   = 12
+	encryptionSegmentSize = 64 * 1024
+	encryptionKeyInfo     = "zfsutils objectstore stream key"
+)
+
+var (
+	segmentData     = []byte{0}
+	lastSegmentData = []byte{1}
+)
+
+// ErrDecrypt is returned when an encrypted stream is invalid, truncated or encrypted with another key
+var ErrDecrypt = errors.New("error decrypting stream")
+
+// parseEncryptionKey parses a hex encoded AES-256 key
+func parseEncryptionKey(key string) ([]byte, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil || len(raw) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d hex encoded bytes", encryptionKeySize)
+	}
+	return raw, nil
+}
+
+// newAEAD creates the AES-256-GCM cipher for the key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveStreamKey derives the key of a stream from the configured key and the salt of the stream with HKDF-SHA256
+// (RFC 5869). A single block of the expand step is the full key.
+func deriveStreamKey(key, salt []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(key)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(encryptionKeyInfo))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:encryptionKeySize]
+}
+
+type encryptWriter struct {
+	writer  io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	segment uint64
+	buf     []byte
+	out     []byte
+}
+
+// newEncryptWriter encrypts everything written to it into the writer with a new key derived from the key,
+// Close must be called to write the last segment
+func newEncryptWriter(writer io.Writer, key []byte) (*encryptWriter, error) {
+	salt := make([]byte, encryptionSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(deriveStreamKey(key, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &encryptWriter{
+		writer: writer,
+		aead:   aead,
+		nonce:  make([]byte, encryptionNonceSize),
+		buf:    make([]byte, 0, encryptionSegmentSize),
+	}
+	_, err = writer.Write(append([]byte(encryptionHeader), salt...))
+	return w, err
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data follows, as it could be the last one
+		if len(w.buf) == encryptionSegmentSize {
+			err := w.seal(segmentData)
+			if err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):encryptionSegmentSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the last segment
+func (w *encryptWriter) Close() error {
+	return w.seal(lastSegmentData)
+}
+
+func (w *encryptWriter) seal(additionalData []byte) error {
+	binary.BigEndian.PutUint64(w.nonce[encryptionNonceSize-8:], w.segment)
+	w.segment++
+
+	w.out = binary.BigEndian.AppendUint32(w.out[:0], uint32(len(w.buf)))
+	w.out = w.aead.Seal(w.out, w.nonce, w.buf, additionalData)
+	w.buf = w.buf[:0]
+	_, err := w.writer.Write(w.out)
+	return err
+}
+
+type decryptReader struct {
+	reader   io.Reader
+	aead     cipher.AEAD
+	nonce    []byte
+	segment  uint64
+	buf      []byte
+	plainBuf []byte
+	plain    []byte
+	last     bool
+}
+
+// newDecryptReader decrypts a stream written by an encryptWriter with the key
+func newDecryptReader(reader io.Reader, key []byte) (*decryptReader, error) {
+	header := make([]byte, len(encryptionHeader))
+	_, err := io.ReadFull(reader, header)
+	if err != nil || string(header) != encryptionHeader {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecrypt)
+	}
+	salt := make([]byte, encryptionSaltSize)
+	_, err = io.ReadFull(reader, salt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecrypt)
+	}
+
+	r := &decryptReader{
+		reader: reader,
+		nonce:  make([]byte, encryptionNonceSize),
+	}
+	key = deriveStreamKey(key, salt)
+
+	r.aead, err = newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.last {
+			return 0, io.EOF
+		}
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment
+func (r *decryptReader) open() error {
+	var length [4]byte
+	_, err := io.ReadFull(r.reader, length[:])
+	if err != nil {
+		return fmt.Errorf("%w: truncated stream: %w", ErrDecrypt, err)
+	}
+	size := int(binary.BigEndian.Uint32(length[:]))
+	if size > encryptionSegmentSize {
+		return fmt.Errorf("%w: invalid segment size %d", ErrDecrypt, size)
+	}
+	if cap(r.buf) < size+r.aead.Overhead() {
+		r.buf = make([]byte, size+r.aead.Overhead())
+		r.plainBuf = make([]byte, 0, size)
+	}
+	r.buf = r.buf[:size+r.aead.Overhead()]
+	_, err = io.ReadFull(r.reader, r.buf)
+	if err != nil {
+		return fmt.Errorf("%w: truncated stream: %w", ErrDecrypt, err)
+	}
+
+	binary.BigEndian.PutUint64(r.nonce[encryptionNonceSize-8:], r.segment)
+	r.segment++
+	r.plain, err = r.aead.Open(r.plainBuf[:0], r.nonce, r.buf, segmentData)
+	if err != nil {
+		r.plain, err = r.aead.Open(r.plainBuf[:0], r.nonce, r.buf, lastSegmentData)
+		r.last = err == nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return nil
+}
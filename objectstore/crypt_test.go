@@ -0,0 +1,74 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_encryptWriter(t *testing.T) {
+	key, err := parseEncryptionKey(hex.EncodeToString(bytes.Repeat([]byte{7}, encryptionKeySize)))
+	require.NoError(t, err)
+	_, err = parseEncryptionKey("abcd")
+	require.Error(t, err)
+
+	for _, size := range []int{0, 100, encryptionSegmentSize, 3*encryptionSegmentSize + 5} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		w, err := newEncryptWriter(&encrypted, key)
+		require.NoError(t, err)
+		_, err = w.Write(plain)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := newDecryptReader(bytes.NewReader(encrypted.Bytes()), key)
+		require.NoError(t, err)
+		decrypted, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, plain, decrypted)
+
+		// Cutting off the last segment is detected
+		if size > encryptionSegmentSize {
+			truncated := encrypted.Bytes()[:encrypted.Len()-5-4-16]
+			r, err = newDecryptReader(bytes.NewReader(truncated), key)
+			require.NoError(t, err)
+			_, err = io.ReadAll(r)
+			require.ErrorIs(t, err, ErrDecrypt)
+		}
+	}
+}
+
+func Test_encryptWriterStreamKeys(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, encryptionKeySize)
+	plain := bytes.Repeat([]byte("zfs"), 1000)
+
+	// Every stream has its own salt and key, so the same plaintext never encrypts the same
+	encrypt := func() []byte {
+		var encrypted bytes.Buffer
+		w, err := newEncryptWriter(&encrypted, key)
+		require.NoError(t, err)
+		_, err = w.Write(plain)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return encrypted.Bytes()
+	}
+	first, second := encrypt(), encrypt()
+	require.NotEqual(t, first[:len(encryptionHeader)+encryptionSaltSize], second[:len(encryptionHeader)+encryptionSaltSize])
+	require.NotEqual(t, first[len(encryptionHeader)+encryptionSaltSize:], second[len(encryptionHeader)+encryptionSaltSize:])
+
+	// Another key fails to decrypt
+	r, err := newDecryptReader(bytes.NewReader(first), bytes.Repeat([]byte{8}, encryptionKeySize))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	// Streams of the insecure first version are not decrypted
+	_, err = newDecryptReader(bytes.NewReader(append([]byte("zfsenc1\n"), first[len(encryptionHeader):]...)), key)
+	require.ErrorIs(t, err, ErrDecrypt)
+}
@@ -0,0 +1,195 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const uploadsDir = ".uploads"
+
+// DirStore stores the objects as files in a local directory, for instance on removable media for air-gapped
+// backups. The keys are the paths of the files, the parts of multipart uploads are kept in the .uploads directory
+// until they are assembled.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a store in the directory, which must exist
+func NewDirStore(dir string) (*DirStore, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *DirStore) uploadPath(uploadID string, elem ...string) (string, error) {
+	if !filepath.IsLocal(uploadID) {
+		return "", fmt.Errorf("invalid upload ID %q", uploadID)
+	}
+	return filepath.Join(append([]string{s.dir, uploadsDir, uploadID}, elem...)...), nil
+}
+
+// PutObject stores an object, replacing the file atomically
+func (s *DirStore) PutObject(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+// CreateMultipartUpload starts a multipart upload of an object, and returns its upload ID
+func (s *DirStore) CreateMultipartUpload(_ context.Context, key string) (string, error) {
+	_, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	uploadID := hex.EncodeToString(id)
+
+	path, err := s.uploadPath(uploadID)
+	if err != nil {
+		return "", err
+	}
+	return uploadID, os.MkdirAll(path, 0o700)
+}
+
+// UploadPart stores a part of a multipart upload, and returns its SHA-256 checksum as ETag
+func (s *DirStore) UploadPart(_ context.Context, _, uploadID string, partNumber int, data []byte) (string, error) {
+	path, err := s.uploadPath(uploadID, strconv.Itoa(partNumber))
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CompleteMultipartUpload assembles the object from the parts, in the order given
+func (s *DirStore) CompleteMultipartUpload(_ context.Context, key, uploadID string, parts []CompletedPart) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = writeFile(path, func(f *os.File) error {
+		for _, part := range parts {
+			partPath, err := s.uploadPath(uploadID, strconv.Itoa(part.PartNumber))
+			if err != nil {
+				return err
+			}
+			err = appendFile(f, partPath)
+			if err != nil {
+				return fmt.Errorf("error appending part %d of %s: %w", part.PartNumber, key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.AbortMultipartUpload(context.Background(), key, uploadID)
+}
+
+// AbortMultipartUpload removes the uploaded parts
+func (s *DirStore) AbortMultipartUpload(_ context.Context, _, uploadID string) error {
+	path, err := s.uploadPath(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// GetObject opens the file of an object, or returns ErrObjectNotFound
+func (s *DirStore) GetObject(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return f, err
+}
+
+// DeleteObject removes the file of an object and its directories once empty.
+// A missing file is not an error, like with S3.
+func (s *DirStore) DeleteObject(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(path); dir != filepath.Clean(s.dir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // Not empty
+		}
+	}
+	return nil
+}
+
+// writeFile writes a file using a temporary file, which is synced and renamed to the path once fn succeeds
+func writeFile(path string, fn func(f *os.File) error) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Fails once renamed
+
+	err = fn(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func appendFile(f *os.File, path string) error {
+	part, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+	_, err = io.Copy(f, part)
+	return err
+}
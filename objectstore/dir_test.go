@@ -0,0 +1,139 @@
+package objectstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func TestTarget_DirStore(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewDirStore(dir)
+	require.NoError(t, err)
+
+	conf := TargetConfig{}
+	conf.ApplyDefaults()
+	conf.ChunkSize = 100
+	conf.PartSize = 40
+	target, err := NewTarget(store, conf, testLogger)
+	require.NoError(t, err)
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	var snaps []*zfs.Dataset
+	for _, name := range []string{"snap1", "snap2", "snap3", "snap4"} {
+		snap, err := fs.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+		snaps = append(snaps, snap)
+	}
+
+	_, err = target.Send(ctx, snaps[0], zfs.SendOptions{})
+	require.NoError(t, err)
+	_, err = target.Send(ctx, snaps[1], zfs.SendOptions{IncrementalBase: snaps[0]})
+	require.NoError(t, err)
+	_, err = target.Send(ctx, snaps[2], zfs.SendOptions{})
+	require.NoError(t, err)
+	_, err = target.Send(ctx, snaps[3], zfs.SendOptions{IncrementalBase: snaps[2]})
+	require.NoError(t, err)
+
+	require.FileExists(t, filepath.Join(dir, "pool", "fs", "manifest.json"))
+	require.FileExists(t, filepath.Join(dir, "pool", "fs", "snap1", "000000"))
+	uploads, err := os.ReadDir(filepath.Join(dir, uploadsDir))
+	require.NoError(t, err)
+	require.Empty(t, uploads)
+
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap2", "pool/restored", RestoreOptions{}))
+
+	deleted, err := target.Rotate(ctx, "pool/fs", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"snap2", "snap1"}, deleted)
+	require.NoDirExists(t, filepath.Join(dir, "pool", "fs", "snap1"))
+
+	manifest, err := target.Manifest(ctx, "pool/fs")
+	require.NoError(t, err)
+	require.Len(t, manifest.Snapshots, 2)
+	require.Equal(t, "snap4", manifest.Latest().Name)
+
+	deleted, err = target.Rotate(ctx, "pool/fs", 1)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+
+	_, err = target.Rotate(ctx, "pool/fs", -1)
+	require.Error(t, err)
+}
+
+func TestTarget_DirStoreKeys(t *testing.T) {
+	store, err := NewDirStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.Error(t, store.PutObject(ctx, "../escape", nil))
+	_, err = store.GetObject(ctx, "missing")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	require.NoError(t, store.DeleteObject(ctx, "missing"))
+
+	_, err = NewDirStore(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestTarget_RestoreResume(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	ctx := context.Background()
+	store, err := NewDirStore(t.TempDir())
+	require.NoError(t, err)
+
+	conf := TargetConfig{}
+	conf.ApplyDefaults()
+	target, err := NewTarget(store, conf, testLogger)
+	require.NoError(t, err)
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	var base *zfs.Dataset
+	for _, name := range []string{"snap1", "snap2", "snap3"} {
+		snap, err := fs.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+		stored, err := target.Send(ctx, snap, zfs.SendOptions{IncrementalBase: base})
+		require.NoError(t, err)
+		require.Equal(t, snap.GUID, stored.GUID)
+		base = snap
+	}
+
+	// An interrupted restore only received the first snapshots
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap2", "pool/restored", RestoreOptions{}))
+	require.Error(t, target.Restore(ctx, "pool/fs", "snap3", "pool/restored", RestoreOptions{}))
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap3", "pool/restored", RestoreOptions{Resume: true}))
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "pool/restored"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 3)
+	require.Equal(t, base.GUID, snaps[2].GUID)
+
+	// Nothing is left to receive
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap3", "pool/restored", RestoreOptions{Resume: true}))
+}
+
+func TestValidateChain(t *testing.T) {
+	chain := []StoredSnapshot{
+		{Name: "snap1", GUID: 1},
+		{Name: "snap2", Base: "snap1", GUID: 2, BaseGUID: 1},
+		{Name: "snap3", Base: "snap2"}, // Stored before GUIDs were recorded
+	}
+	require.NoError(t, ValidateChain(chain))
+	require.ErrorIs(t, ValidateChain(chain[1:]), ErrInvalidChain)
+
+	chain[1].BaseGUID = 3
+	require.ErrorIs(t, ValidateChain(chain), ErrInvalidChain)
+
+	chain[1].BaseGUID = 1
+	chain[2].Base = "snap1"
+	require.ErrorIs(t, ValidateChain(chain), ErrInvalidChain)
+}
@@ -0,0 +1,97 @@
+package objectstore
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Manifest describes the snapshots stored for a dataset
+type Manifest struct {
+	Dataset   string           `json:"Dataset"`
+	Snapshots []StoredSnapshot `json:"Snapshots"`
+}
+
+// StoredSnapshot is the send stream of a snapshot, stored as chunk objects
+type StoredSnapshot struct {
+	// Name is the name of the snapshot, without the dataset
+	Name string `json:"Name"`
+	// Base is the name of the incremental base snapshot, empty for a full stream
+	Base string `json:"Base,omitempty"`
+	// GUID is the GUID of the snapshot, which is kept when it is received
+	GUID uint64 `json:"GUID,omitempty"`
+	// BaseGUID is the GUID of the incremental base snapshot
+	BaseGUID   uint64    `json:"BaseGUID,omitempty"`
+	Raw        bool      `json:"Raw"`
+	Compressed bool      `json:"Compressed"`
+	Encrypted  bool      `json:"Encrypted"`
+	Size       int64     `json:"Size"`
+	Chunks     []Chunk   `json:"Chunks"`
+	StoredAt   time.Time `json:"StoredAt"`
+}
+
+// Chunk is an object holding a part of a stream
+type Chunk struct {
+	Key    string `json:"Key"`
+	Size   int64  `json:"Size"`
+	SHA256 string `json:"SHA256"`
+}
+
+// Snapshot returns the stored snapshot with the name, or nil
+func (m *Manifest) Snapshot(name string) *StoredSnapshot {
+	for i := range m.Snapshots {
+		if m.Snapshots[i].Name == name {
+			return &m.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// Chain returns the stored snapshots to receive in order to restore the snapshot: a full stream followed by
+// the incremental streams up to and including the snapshot
+func (m *Manifest) Chain(name string) ([]StoredSnapshot, error) {
+	var chain []StoredSnapshot
+	for name != "" {
+		snap := m.Snapshot(name)
+		if snap == nil {
+			return nil, fmt.Errorf("%w: %s@%s", ErrSnapshotNotStored, m.Dataset, name)
+		}
+		if len(chain) > len(m.Snapshots) {
+			return nil, fmt.Errorf("incremental chain of %s@%s has a cycle", m.Dataset, name)
+		}
+		chain = append(chain, *snap)
+		name = snap.Base
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+// ValidateChain checks the chain starts with a full stream, and every incremental stream is based on the one before
+// it, by name and by GUID when known
+func ValidateChain(chain []StoredSnapshot) error {
+	for i, stored := range chain {
+		switch {
+		case i == 0 && stored.Base != "":
+			return fmt.Errorf("%w: %s is not a full stream", ErrInvalidChain, stored.Name)
+		case i == 0:
+			continue
+		}
+		prev := chain[i-1]
+		if stored.Base != prev.Name {
+			return fmt.Errorf("%w: %s is based on %s instead of %s", ErrInvalidChain, stored.Name, stored.Base, prev.Name)
+		}
+		if stored.BaseGUID != 0 && prev.GUID != 0 && stored.BaseGUID != prev.GUID {
+			return fmt.Errorf("%w: GUID %d of %s does not match the base GUID %d of %s",
+				ErrInvalidChain, prev.GUID, prev.Name, stored.BaseGUID, stored.Name)
+		}
+	}
+	return nil
+}
+
+// Latest returns the snapshot stored last, or nil
+func (m *Manifest) Latest() *StoredSnapshot {
+	if len(m.Snapshots) == 0 {
+		return nil
+	}
+	return &m.Snapshots[len(m.Snapshots)-1]
+}
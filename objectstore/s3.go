@@ -0,0 +1,243 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	emptyPayloadHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	maximumErrorBytes = 64 * 1024
+)
+
+// S3Config configures the S3 client
+type S3Config struct {
+	// Endpoint is the URL of the S3 service, like https://s3.eu-west-1.amazonaws.com
+	Endpoint string `json:"Endpoint" yaml:"Endpoint"`
+	Region   string `json:"Region" yaml:"Region"`
+	Bucket   string `json:"Bucket" yaml:"Bucket"`
+	// PathStyle puts the bucket in the path instead of the host name, which most S3-compatible services require
+	PathStyle bool `json:"PathStyle" yaml:"PathStyle"`
+
+	AccessKeyID     string `json:"AccessKeyID" yaml:"AccessKeyID"`
+	SecretAccessKey string `json:"SecretAccessKey" yaml:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken" yaml:"SessionToken"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *S3Config) ApplyDefaults() {
+	c.Region = defaultS3Region
+}
+
+// S3Error is an error response of the S3 service
+type S3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("s3 error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is makes errors.Is(err, ErrObjectNotFound) work for missing objects
+func (e *S3Error) Is(target error) bool {
+	return target == ErrObjectNotFound && (e.Code == "NoSuchKey" || e.StatusCode == http.StatusNotFound && e.Code == "")
+}
+
+// S3Client stores objects in an S3 bucket, signing its requests with AWS signature version 4
+type S3Client struct {
+	config S3Config
+	logger *slog.Logger
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Client creates a new S3 client
+func NewS3Client(conf S3Config, logger *slog.Logger) (*S3Client, error) {
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", conf.Endpoint)
+	}
+	if conf.Bucket == "" {
+		return nil, errors.New("no s3 bucket configured")
+	}
+	if conf.Region == "" {
+		conf.Region = defaultS3Region
+	}
+	return &S3Client{
+		config: conf,
+		logger: logger,
+		client: http.DefaultClient,
+		now:    time.Now,
+	}, nil
+}
+
+// SetClient configures a custom http client for doing requests
+func (c *S3Client) SetClient(client *http.Client) {
+	c.client = client
+}
+
+func (c *S3Client) objectURL(key string, query url.Values) *url.URL {
+	u, _ := url.Parse(c.config.Endpoint) // Validated when creating the client
+	path := strings.TrimRight(u.Path, "/")
+	if c.config.PathStyle {
+		path += "/" + c.config.Bucket
+	} else {
+		u.Host = c.config.Bucket + "." + u.Host
+	}
+	u.Path = path + "/" + key
+	u.RawPath = uriEncode(u.Path, false) // Sent exactly as signed
+	u.RawQuery = query.Encode()
+	return u
+}
+
+// do signs and sends a request, and returns the response when it is successful
+func (c *S3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	c.sign(req, payloadHash, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	err = readS3Error(resp)
+	c.logger.Debug("zfs.objectstore.S3Client.do: Request failed", "method", method, "key", key, "error", err)
+	return nil, err
+}
+
+func readS3Error(resp *http.Response) error {
+	s3Err := &S3Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maximumErrorBytes))
+	if len(data) > 0 && xml.Unmarshal(data, s3Err) != nil {
+		s3Err.Message = string(data)
+	}
+	if s3Err.Message == "" {
+		s3Err.Message = http.StatusText(resp.StatusCode)
+	}
+	return s3Err
+}
+
+// PutObject stores an object in a single request
+func (c *S3Client) PutObject(ctx context.Context, key string, data []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// CreateMultipartUpload starts a multipart upload of an object, and returns its upload ID
+func (c *S3Client) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", fmt.Errorf("error decoding create multipart upload response: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", errors.New("no upload id in create multipart upload response")
+	}
+	return result.UploadID, nil
+}
+
+// UploadPart uploads a part of a multipart upload, and returns its ETag
+func (c *S3Client) UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, query, data)
+	if err != nil {
+		return "", err
+	}
+	etag := resp.Header.Get("ETag")
+	return etag, resp.Body.Close()
+}
+
+// CompleteMultipartUpload assembles the object from the uploaded parts
+func (c *S3Client) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Completing can fail after the 200 OK status has been sent, the error is in the body then
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maximumErrorBytes))
+	if err != nil {
+		return fmt.Errorf("error reading complete multipart upload response: %w", err)
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		s3Err := &S3Error{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(data, s3Err)
+		return s3Err
+	}
+	return nil
+}
+
+// AbortMultipartUpload aborts a multipart upload, removing the uploaded parts
+func (c *S3Client) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// GetObject returns the contents of an object, or ErrObjectNotFound
+func (c *S3Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteObject deletes an object
+func (c *S3Client) DeleteObject(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,37 @@
+// Package objectstore stores zfs send streams in S3-compatible object storage, and restores them with zfs receive.
+// Streams are split into chunk objects, which are uploaded in parts with multipart uploads. They can be compressed
+// and encrypted. A manifest per dataset describes the stored snapshots and their incremental chain.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrObjectNotFound is returned when an object does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// CompletedPart is an uploaded part of a multipart upload
+type CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// ObjectStore is the object storage the streams are stored in, S3Client implements it for S3-compatible storage
+type ObjectStore interface {
+	// PutObject stores an object in a single request
+	PutObject(ctx context.Context, key string, data []byte) error
+	// CreateMultipartUpload starts a multipart upload of an object, and returns its upload ID
+	CreateMultipartUpload(ctx context.Context, key string) (string, error)
+	// UploadPart uploads a part of a multipart upload, and returns its ETag
+	UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error)
+	// CompleteMultipartUpload assembles the object from the uploaded parts
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error
+	// AbortMultipartUpload aborts a multipart upload, removing the uploaded parts
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+	// GetObject returns the contents of an object, or ErrObjectNotFound
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	// DeleteObject deletes an object
+	DeleteObject(ctx context.Context, key string) error
+}
@@ -0,0 +1,416 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const (
+	defaultChunkSize = 1024 * 1024 * 1024 // 1 GiB
+	defaultPartSize  = 64 * 1024 * 1024   // 64 MiB
+	manifestName     = "manifest.json"
+)
+
+var (
+	// ErrSnapshotStored is returned when sending a snapshot that is stored already
+	ErrSnapshotStored = errors.New("snapshot is stored already")
+	// ErrSnapshotNotStored is returned for snapshots that are not in the manifest
+	ErrSnapshotNotStored = errors.New("snapshot is not stored")
+	// ErrSnapshotHasDependents is returned when deleting a snapshot that incremental streams are based on
+	ErrSnapshotHasDependents = errors.New("snapshot has dependent incremental snapshots")
+	// ErrInvalidChain is returned when the stored incremental streams do not form a chain
+	ErrInvalidChain = errors.New("invalid incremental chain")
+	// ErrNoEncryptionKey is returned when restoring an encrypted stream without an encryption key
+	ErrNoEncryptionKey = errors.New("no encryption key configured")
+)
+
+// TargetConfig configures the target
+type TargetConfig struct {
+	// Prefix is prepended to the keys of all objects
+	Prefix string `json:"Prefix" yaml:"Prefix"`
+	// ChunkSize is the maximum size of a chunk object
+	ChunkSize int64 `json:"ChunkSize" yaml:"ChunkSize"`
+	// PartSize is the size of the parts of the multipart uploads, S3 requires at least 5 MiB.
+	// Every send buffers a part in memory.
+	PartSize int `json:"PartSize" yaml:"PartSize"`
+	// EncryptionKey is the hex encoded AES-256 key to encrypt the streams with, empty to not encrypt them
+	EncryptionKey string `json:"EncryptionKey" yaml:"EncryptionKey"`
+}
+
+// ApplyDefaults sets all config values to their defaults (if they have one)
+func (c *TargetConfig) ApplyDefaults() {
+	c.ChunkSize = defaultChunkSize
+	c.PartSize = defaultPartSize
+}
+
+// Target stores send streams in an object store, and restores them. The manifest of a dataset is rewritten for
+// every change, so only one target should change the snapshots of a dataset at a time.
+type Target struct {
+	store  ObjectStore
+	config TargetConfig
+	encKey []byte
+	logger *slog.Logger
+}
+
+// NewTarget creates a new target storing the streams in the store
+func NewTarget(store ObjectStore, conf TargetConfig, logger *slog.Logger) (*Target, error) {
+	if conf.ChunkSize <= 0 || conf.PartSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d or part size %d", conf.ChunkSize, conf.PartSize)
+	}
+	t := &Target{
+		store:  store,
+		config: conf,
+		logger: logger,
+	}
+	if conf.EncryptionKey != "" {
+		var err error
+		t.encKey, err = parseEncryptionKey(conf.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *Target) key(dataset string, elem ...string) string {
+	key := strings.Join(append([]string{dataset}, elem...), "/")
+	if t.config.Prefix == "" {
+		return key
+	}
+	return strings.TrimRight(t.config.Prefix, "/") + "/" + key
+}
+
+// Manifest returns the manifest of a dataset, which is empty when nothing is stored for it
+func (t *Target) Manifest(ctx context.Context, dataset string) (*Manifest, error) {
+	obj, err := t.store.GetObject(ctx, t.key(dataset, manifestName))
+	if errors.Is(err, ErrObjectNotFound) {
+		return &Manifest{Dataset: dataset}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving manifest of %s: %w", dataset, err)
+	}
+	defer obj.Close()
+
+	manifest := &Manifest{}
+	err = json.NewDecoder(obj).Decode(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding manifest of %s: %w", dataset, err)
+	}
+	return manifest, nil
+}
+
+func (t *Target) putManifest(ctx context.Context, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = t.store.PutObject(ctx, t.key(manifest.Dataset, manifestName), data)
+	if err != nil {
+		return fmt.Errorf("error storing manifest of %s: %w", manifest.Dataset, err)
+	}
+	return nil
+}
+
+// Send stores the send stream of a snapshot. For an incremental stream the base snapshot must be stored already.
+// The snapshot is added to the manifest once all its chunks are stored, a failed send removes the stored chunks.
+func (t *Target) Send(ctx context.Context, snapshot *zfs.Dataset, options zfs.SendOptions) (*StoredSnapshot, error) {
+	dataset, name, ok := strings.Cut(snapshot.Name, "@")
+	if !ok {
+		return nil, zfs.ErrOnlySnapshotsSupported
+	}
+	manifest, err := t.Manifest(ctx, dataset)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Snapshot(name) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotStored, snapshot.Name)
+	}
+
+	stored := StoredSnapshot{
+		Name:       name,
+		GUID:       snapshot.GUID,
+		Raw:        options.Raw,
+		Compressed: options.CompressionLevel != 0,
+		Encrypted:  t.encKey != nil,
+	}
+	if options.IncrementalBase != nil {
+		baseDataset, baseName, _ := strings.Cut(options.IncrementalBase.Name, "@")
+		if baseDataset != dataset || manifest.Snapshot(baseName) == nil {
+			return nil, fmt.Errorf("%w: incremental base %s", ErrSnapshotNotStored, options.IncrementalBase.Name)
+		}
+		stored.Base = baseName
+		stored.BaseGUID = options.IncrementalBase.GUID
+	}
+
+	chunks := &chunkWriter{
+		ctx:       ctx,
+		store:     t.store,
+		keyPrefix: t.key(dataset, name),
+		chunkSize: t.config.ChunkSize,
+		partSize:  t.config.PartSize,
+		buf:       make([]byte, 0, t.config.PartSize),
+	}
+	err = t.send(ctx, snapshot, chunks, options)
+	if err != nil {
+		abortErr := chunks.abort()
+		deleteErr := t.deleteChunks(context.WithoutCancel(ctx), chunks.chunks)
+		return nil, errors.Join(fmt.Errorf("error storing %s: %w", snapshot.Name, err), abortErr, deleteErr)
+	}
+
+	stored.Chunks = chunks.chunks
+	for _, chunk := range stored.Chunks {
+		stored.Size += chunk.Size
+	}
+	stored.StoredAt = time.Now()
+	manifest.Snapshots = append(manifest.Snapshots, stored)
+	err = t.putManifest(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	t.logger.Info("zfs.objectstore.Target.Send: Snapshot stored",
+		"snapshot", snapshot.Name,
+		"base", stored.Base,
+		"size", stored.Size,
+		"chunks", len(stored.Chunks),
+	)
+	return &stored, nil
+}
+
+func (t *Target) send(ctx context.Context, snapshot *zfs.Dataset, chunks *chunkWriter, options zfs.SendOptions) error {
+	var output io.Writer = chunks
+	var encrypter *encryptWriter
+	if t.encKey != nil {
+		var err error
+		encrypter, err = newEncryptWriter(chunks, t.encKey)
+		if err != nil {
+			return err
+		}
+		output = encrypter
+	}
+
+	_, err := snapshot.SendSnapshot(ctx, output, options)
+	if err != nil {
+		return err
+	}
+	if encrypter != nil {
+		err = encrypter.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return chunks.Close()
+}
+
+// RestoreOptions are options you can specify to customize restoring a snapshot
+type RestoreOptions struct {
+	// ExistingBase is a snapshot in the incremental chain that exists in the target dataset already,
+	// so only the snapshots after it are received
+	ExistingBase string
+	// Resume continues an interrupted restore, the snapshots of the chain that exist in the target dataset already
+	// are matched by GUID, and only the snapshots after the last of them are received
+	Resume bool
+	// ReceiveOptions are used for receiving every stream, decompression is enabled for the compressed streams
+	ReceiveOptions zfs.ReceiveOptions
+}
+
+// Chain returns the validated incremental chain to restore a stored snapshot
+func (t *Target) Chain(ctx context.Context, dataset, snapshot string) ([]StoredSnapshot, error) {
+	manifest, err := t.Manifest(ctx, dataset)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := manifest.Chain(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateChain(chain)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// Restore receives a stored snapshot into the target dataset, receiving its incremental chain in order
+func (t *Target) Restore(ctx context.Context, dataset, snapshot, target string, options RestoreOptions) error {
+	chain, err := t.Chain(ctx, dataset, snapshot)
+	if err != nil {
+		return err
+	}
+	if options.ExistingBase != "" {
+		idx := slices.IndexFunc(chain, func(s StoredSnapshot) bool {
+			return s.Name == options.ExistingBase
+		})
+		if idx < 0 {
+			return fmt.Errorf("%w: %s@%s is not in the incremental chain of %s",
+				ErrSnapshotNotStored, dataset, options.ExistingBase, snapshot)
+		}
+		chain = chain[idx+1:]
+	}
+	if options.Resume {
+		received, err := restoredSnapshots(ctx, chain, target)
+		if err != nil {
+			return err
+		}
+		chain = chain[received:]
+	}
+
+	for _, stored := range chain {
+		err = t.receive(ctx, stored, fmt.Sprintf("%s@%s", target, stored.Name), options.ReceiveOptions)
+		if err != nil {
+			return fmt.Errorf("error restoring %s@%s: %w", dataset, stored.Name, err)
+		}
+		t.logger.Info("zfs.objectstore.Target.Restore: Snapshot restored",
+			"snapshot", stored.Name,
+			"dataset", dataset,
+			"target", target,
+		)
+	}
+	return nil
+}
+
+// restoredSnapshots returns the amount of snapshots at the start of the chain the target dataset has already,
+// which is up to the last snapshot of the chain with a GUID that is found in the target dataset
+func restoredSnapshots(ctx context.Context, chain []StoredSnapshot, target string) (int, error) {
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{
+		ParentDataset: target,
+		Depth:         1,
+		Fields:        []string{zfs.PropertyName, zfs.PropertyType, zfs.PropertyGUID},
+	})
+	switch {
+	case errors.Is(err, zfs.ErrDatasetNotFound):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("error listing snapshots of %s: %w", target, err)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		guid := chain[i].GUID
+		if guid != 0 && slices.ContainsFunc(snaps, func(snap zfs.Dataset) bool { return snap.GUID == guid }) {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (t *Target) receive(ctx context.Context, stored StoredSnapshot, name string, options zfs.ReceiveOptions) error {
+	chunks := &chunkReader{
+		ctx:    ctx,
+		store:  t.store,
+		chunks: stored.Chunks,
+	}
+	defer chunks.Close()
+
+	var input io.Reader = chunks
+	if stored.Encrypted {
+		if t.encKey == nil {
+			return ErrNoEncryptionKey
+		}
+		var err error
+		input, err = newDecryptReader(chunks, t.encKey)
+		if err != nil {
+			return err
+		}
+	}
+	options.EnableDecompression = stored.Compressed
+	options.SkipRefetch = true
+	_, _, err := zfs.ReceiveSnapshot(ctx, input, name, options)
+	return err
+}
+
+// Delete removes a stored snapshot, which must not be the base of other stored snapshots
+func (t *Target) Delete(ctx context.Context, dataset, snapshot string) error {
+	manifest, err := t.Manifest(ctx, dataset)
+	if err != nil {
+		return err
+	}
+	stored := manifest.Snapshot(snapshot)
+	if stored == nil {
+		return fmt.Errorf("%w: %s@%s", ErrSnapshotNotStored, dataset, snapshot)
+	}
+	for _, other := range manifest.Snapshots {
+		if other.Base == snapshot {
+			return fmt.Errorf("%w: %s@%s is the base of %s", ErrSnapshotHasDependents, dataset, snapshot, other.Name)
+		}
+	}
+	chunks := stored.Chunks
+
+	// The manifest is stored first, so it never refers to deleted chunks
+	manifest.Snapshots = slices.DeleteFunc(manifest.Snapshots, func(s StoredSnapshot) bool {
+		return s.Name == snapshot
+	})
+	err = t.putManifest(ctx, manifest)
+	if err != nil {
+		return err
+	}
+	err = t.deleteChunks(ctx, chunks)
+	if err != nil {
+		return err
+	}
+	t.logger.Info("zfs.objectstore.Target.Delete: Snapshot deleted", "dataset", dataset, "snapshot", snapshot)
+	return nil
+}
+
+func (t *Target) deleteChunks(ctx context.Context, chunks []Chunk) error {
+	var errs []error
+	for _, chunk := range chunks {
+		err := t.store.DeleteObject(ctx, chunk.Key)
+		if err != nil && !errors.Is(err, ErrObjectNotFound) {
+			errs = append(errs, fmt.Errorf("error deleting %s: %w", chunk.Key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rotate deletes the oldest chains of stored snapshots of a dataset, each being a full stream with the incremental
+// streams based on it, so only the newest chains are kept. It returns the names of the deleted snapshots.
+func (t *Target) Rotate(ctx context.Context, dataset string, keepChains int) ([]string, error) {
+	if keepChains < 0 {
+		return nil, fmt.Errorf("invalid amount of chains to keep: %d", keepChains)
+	}
+	manifest, err := t.Manifest(ctx, dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make(map[string]string, len(manifest.Snapshots))
+	var fulls []string
+	for _, stored := range manifest.Snapshots {
+		chain, err := manifest.Chain(stored.Name)
+		if err != nil {
+			return nil, err
+		}
+		roots[stored.Name] = chain[0].Name
+		if stored.Base == "" {
+			fulls = append(fulls, stored.Name)
+		}
+	}
+	if len(fulls) <= keepChains {
+		return nil, nil
+	}
+	rotated := fulls[:len(fulls)-keepChains]
+
+	// Incremental streams are stored after their base, so deleting in reverse order deletes the dependents first
+	var deleted []string
+	for i := len(manifest.Snapshots) - 1; i >= 0; i-- {
+		name := manifest.Snapshots[i].Name
+		if !slices.Contains(rotated, roots[name]) {
+			continue
+		}
+		err = t.Delete(ctx, dataset, name)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, name)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,103 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+	"github.com/vansante/go-zfsutils/zfsfake"
+)
+
+func TestTarget(t *testing.T) {
+	zfsfake.Install(t, "pool")
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+
+	conf := TargetConfig{Prefix: "backups/"}
+	conf.ApplyDefaults()
+	conf.ChunkSize = 100
+	conf.PartSize = 40
+	conf.EncryptionKey = hex.EncodeToString([]byte(strings.Repeat("k", encryptionKeySize)))
+	target, err := NewTarget(client, conf, testLogger)
+	require.NoError(t, err)
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "snap1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	snap2, err := fs.Snapshot(ctx, "snap2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	_, err = target.Send(ctx, snap2, zfs.SendOptions{IncrementalBase: snap1})
+	require.ErrorIs(t, err, ErrSnapshotNotStored)
+
+	stored, err := target.Send(ctx, snap1, zfs.SendOptions{CompressionLevel: zstd.SpeedFastest})
+	require.NoError(t, err)
+	require.True(t, stored.Compressed)
+	require.True(t, stored.Encrypted)
+	require.Greater(t, len(stored.Chunks), 1)
+	require.Equal(t, "backups/pool/fs/snap1/000000", stored.Chunks[0].Key)
+	require.Contains(t, fake.requests, "UploadPart backups/pool/fs/snap1/000000")
+
+	_, err = target.Send(ctx, snap1, zfs.SendOptions{})
+	require.ErrorIs(t, err, ErrSnapshotStored)
+	stored, err = target.Send(ctx, snap2, zfs.SendOptions{IncrementalBase: snap1})
+	require.NoError(t, err)
+	require.Equal(t, "snap1", stored.Base)
+	require.False(t, stored.Compressed)
+
+	manifest, err := target.Manifest(ctx, "pool/fs")
+	require.NoError(t, err)
+	chain, err := manifest.Chain("snap2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"snap1", "snap2"}, []string{chain[0].Name, chain[1].Name})
+
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap2", "pool/restored", RestoreOptions{}))
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "pool/restored"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, "pool/restored@snap2", snaps[1].Name)
+
+	// Restoring on top of an existing base only receives the later snapshots
+	require.NoError(t, snaps[1].Destroy(ctx, zfs.DestroyOptions{}))
+	require.NoError(t, target.Restore(ctx, "pool/fs", "snap2", "pool/restored", RestoreOptions{ExistingBase: "snap1"}))
+
+	// Corrupted chunks are detected
+	fake.objects[chain[1].Chunks[0].Key][20]++
+	err = target.Restore(ctx, "pool/fs", "snap2", "pool/other", RestoreOptions{})
+	require.Error(t, err)
+
+	require.ErrorIs(t, target.Delete(ctx, "pool/fs", "snap1"), ErrSnapshotHasDependents)
+	require.NoError(t, target.Delete(ctx, "pool/fs", "snap2"))
+	require.NoError(t, target.Delete(ctx, "pool/fs", "snap1"))
+	require.Len(t, fake.objects, 1) // Only the empty manifest is left
+	require.Empty(t, fake.uploads)
+}
+
+func TestTarget_SendFailure(t *testing.T) {
+	fakeZFS := zfsfake.Install(t, "pool")
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+
+	conf := TargetConfig{}
+	conf.ApplyDefaults()
+	conf.PartSize = 10
+	target, err := NewTarget(client, conf, testLogger)
+	require.NoError(t, err)
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "snap", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	fakeZFS.FailNext("send", "cannot send: I/O error")
+	_, err = target.Send(ctx, snap, zfs.SendOptions{})
+	require.Error(t, err)
+	require.Empty(t, fake.objects)
+	require.Empty(t, fake.uploads)
+}
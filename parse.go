@@ -0,0 +1,156 @@
+package zfs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The parsers in this file operate on captured command output, so output can be parsed without running the commands
+
+// ParseDatasets parses the output of `zfs get -Hp -o name,property,value` for the given fields and extra properties,
+// as ListDatasets runs it. When no fields are given, the default fields of ListDatasets are assumed.
+func ParseDatasets(output []byte, fields, extraProps []string) ([]Dataset, error) {
+	if len(fields) == 0 {
+		fields = dsPropList
+	}
+	parser := newDatasetParser(fields, extraProps)
+	err := scanLines(bytes.NewReader(output), 3, parser.parseLine)
+	if err != nil {
+		return nil, err
+	}
+	return parser.datasets()
+}
+
+// ParseDatasetsJSON parses the JSON output of `zfs get -j -p` for the given fields and extra properties, as
+// ListDatasets runs it when the platform has JSONOutput set. When no fields are given, the default fields of
+// ListDatasets are assumed.
+func ParseDatasetsJSON(output []byte, fields, extraProps []string) ([]Dataset, error) {
+	if len(fields) == 0 {
+		fields = dsPropList
+	}
+	return parseDatasetsJSON(output, fields, extraProps)
+}
+
+// ParsePropertyValues parses the output of `zfs get -Hp -o name,value` into a map of dataset names mapped to the
+// property value, as ListWithProperty runs it
+func ParsePropertyValues(output []byte) (map[string]string, error) {
+	result := make(map[string]string, 16)
+	err := scanLines(bytes.NewReader(output), 2, func(fields []string) error {
+		addPropertyValue(result, fields)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseResumeToken returns the resume token from the stderr output of an interrupted resumable receive,
+// or an empty string when there is none
+func ParseResumeToken(stderr []byte) string {
+	return extractStderrResumeToken(string(stderr))
+}
+
+// ParsePoolErrors parses the error counters from the output of `zpool status`
+func ParsePoolErrors(status []byte) (*PoolErrors, error) {
+	lines, err := outputLines(status)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolErrors(lines)
+}
+
+// ParsePoolScan parses the state of the last scrub or resilver from the output of `zpool status`
+func ParsePoolScan(status []byte) (*PoolScan, error) {
+	lines, err := outputLines(status)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolScan(lines)
+}
+
+// ParsePoolStatus parses the output of `zpool status` into the status of the pool with its vdev tree
+func ParsePoolStatus(status []byte) (*PoolStatus, error) {
+	lines, err := outputLines(status)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolStatus(lines)
+}
+
+// ParsePoolStatusJSON parses the JSON output of `zpool status -j` into the status of the pool with its vdev tree.
+// An empty pool name parses the first pool of the output.
+func ParsePoolStatusJSON(status []byte, pool string) (*PoolStatus, error) {
+	return parsePoolStatusJSON(status, pool)
+}
+
+// ParsePermissions parses the output of `zfs allow` into the permissions delegated on the dataset and its ancestors
+func ParsePermissions(output []byte) ([]DatasetPermissions, error) {
+	return parsePermissions(output)
+}
+
+// SendProgress is a progress update printed by `zfs send -v -P` while sending
+type SendProgress struct {
+	// Time is the time of day of the update, formatted as HH:MM:SS
+	Time string `json:"Time"`
+	// Bytes is the amount of bytes sent of the snapshot
+	Bytes uint64 `json:"Bytes"`
+	// Snapshot is the snapshot being sent
+	Snapshot string `json:"Snapshot"`
+}
+
+const sendProgressTimeLayout = "15:04:05"
+
+// ParseSendProgress parses the progress updates from the stderr output of `zfs send -v -P`.
+// The estimates printed before the stream starts are skipped.
+func ParseSendProgress(stderr []byte) ([]SendProgress, error) {
+	var updates []SendProgress
+	err := scanLines(bytes.NewReader(stderr), 3, func(fields []string) error {
+		if len(fields) != 3 {
+			return nil // Estimate or summary line
+		}
+		if _, err := time.Parse(sendProgressTimeLayout, fields[0]); err != nil {
+			return nil // Estimate, like: full	pool/fs@snap	1234
+		}
+		sent, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing sent bytes of progress line %q: %w", strings.Join(fields, fieldSeparator), err)
+		}
+		updates = append(updates, SendProgress{
+			Time:     fields[0],
+			Bytes:    sent,
+			Snapshot: fields[2],
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// addPropertyValue adds a name and value line of `zfs get` output to the result
+func addPropertyValue(result map[string]string, fields []string) {
+	switch len(fields) {
+	case 2:
+		result[fields[0]] = fields[1]
+	case 1:
+		result[fields[0]] = ""
+	}
+}
+
+// outputLines splits the output in lines
+func outputLines(output []byte) ([]string, error) {
+	lines := make([]string, 0, bytes.Count(output, []byte("\n"))+1)
+	err := scanLines(bytes.NewReader(output), 1, func(fields []string) error {
+		lines = append(lines, fields[0])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
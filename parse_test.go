@@ -0,0 +1,105 @@
+package zfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSendProgress = "full\ttestpool/ds0@snap1\t4213584\n" +
+	"size\t4213584\n" +
+	"10:00:00\t1048576\ttestpool/ds0@snap1\n" +
+	"10:00:01\t4213584\ttestpool/ds0@snap1\n"
+
+func TestParseDatasets(t *testing.T) {
+	ds, err := ParseDatasets([]byte(testInput), nil, []string{"nl.test:hiephoi", "nl.test:eigenschap"})
+	require.NoError(t, err)
+	require.Len(t, ds, 3)
+	require.Equal(t, "testpool/ds0", ds[0].Name)
+	require.Equal(t, "42", ds[2].ExtraProps["nl.test:hiephoi"])
+
+	_, err = ParseDatasets([]byte("testpool/ds0\tname\n"), nil, nil)
+	require.Error(t, err)
+}
+
+func TestParsePropertyValues(t *testing.T) {
+	values, err := ParsePropertyValues([]byte("testpool/ds0\tyes\ntestpool/ds1\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"testpool/ds0": "yes", "testpool/ds1": ""}, values)
+}
+
+func TestParseResumeToken(t *testing.T) {
+	token := ParseResumeToken([]byte("cannot receive: checksum mismatch\n" +
+		"A resuming stream can be generated on the sending system by running:\n" +
+		"    zfs send -t 1-1234-abcd\n"))
+	require.Equal(t, "1-1234-abcd", token)
+	require.Empty(t, ParseResumeToken([]byte("cannot open 'testpool/ds0': dataset does not exist")))
+}
+
+func TestParsePoolStatus(t *testing.T) {
+	errs, err := ParsePoolErrors([]byte(testPoolStatus))
+	require.NoError(t, err)
+	expected, err := parsePoolErrors(strings.Split(testPoolStatus, "\n"))
+	require.NoError(t, err)
+	require.Equal(t, expected, errs)
+
+	scan, err := ParsePoolScan([]byte(testPoolStatus))
+	require.NoError(t, err)
+	require.Equal(t, ScanFinished, scan.State)
+}
+
+func TestParseSendProgress(t *testing.T) {
+	updates, err := ParseSendProgress([]byte(testSendProgress))
+	require.NoError(t, err)
+	require.Equal(t, []SendProgress{
+		{Time: "10:00:00", Bytes: 1048576, Snapshot: "testpool/ds0@snap1"},
+		{Time: "10:00:01", Bytes: 4213584, Snapshot: "testpool/ds0@snap1"},
+	}, updates)
+
+	_, err = ParseSendProgress([]byte("10:00:00\t1.2M\ttestpool/ds0@snap1\n"))
+	require.Error(t, err)
+}
+
+func FuzzParseDatasets(f *testing.F) {
+	f.Add([]byte(testInput))
+	f.Add([]byte("testpool/ds0\tname\ttestpool/ds0\ntestpool/ds0\tused\t-\n"))
+	f.Fuzz(func(t *testing.T, output []byte) {
+		ds, err := ParseDatasets(output, []string{PropertyName, PropertyUsed}, []string{"nl.test:prop"})
+		if err != nil {
+			require.Nil(t, ds)
+		}
+	})
+}
+
+func FuzzParsePropertyValues(f *testing.F) {
+	f.Add([]byte("testpool/ds0\tyes\ntestpool/ds1\n"))
+	f.Fuzz(func(t *testing.T, output []byte) {
+		_, _ = ParsePropertyValues(output)
+	})
+}
+
+func FuzzParseResumeToken(f *testing.F) {
+	f.Add([]byte("zfs send -t 1-1234-abcd\n"))
+	f.Fuzz(func(t *testing.T, stderr []byte) {
+		_ = ParseResumeToken(stderr)
+	})
+}
+
+func FuzzParsePoolStatus(f *testing.F) {
+	f.Add([]byte(testPoolStatus))
+	f.Add([]byte("  scan: resilver in progress since Thu Oct 15 10:00:00 2026\n\t10.00% done\nconfig:\n"))
+	f.Add([]byte("config:\n\tNAME STATE READ WRITE CKSUM\n\ttank ONLINE 1.5K 0 0\nerrors: 3 data errors\n"))
+	f.Fuzz(func(t *testing.T, status []byte) {
+		_, _ = ParsePoolErrors(status)
+		_, _ = ParsePoolScan(status)
+		_, _ = ParsePoolStatus(status)
+	})
+}
+
+func FuzzParseSendProgress(f *testing.F) {
+	f.Add([]byte(testSendProgress))
+	f.Fuzz(func(t *testing.T, stderr []byte) {
+		_, _ = ParseSendProgress(stderr)
+	})
+}
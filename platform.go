@@ -0,0 +1,116 @@
+package zfs
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Platform describes the flags supported by the zfs and zpool commands of a platform, as these differ between
+// OpenZFS on Linux and FreeBSD, and the illumos ZFS. Options that need an unsupported flag return ErrNotSupported,
+// instead of a usage error of the command, or the flag being left out silently.
+type Platform struct {
+	// Name identifies the platform in errors
+	Name string
+
+	// MountLoadKeys is whether zfs mount supports -l to load keys, see MountOptions
+	MountLoadKeys bool
+	// TemporaryMountpoint is whether zfs mount supports the mountpoint temporary mount option, see WithTemporaryMount
+	TemporaryMountpoint bool
+	// UnmountUnloadKeys is whether zfs unmount supports -u to unload keys, see UnmountOptions
+	UnmountUnloadKeys bool
+	// CreateNoMount is whether zfs create supports -u to not mount the filesystem, see CreateFilesystemOptions
+	CreateNoMount bool
+	// RenameNoMount is whether zfs rename supports -u to not remount filesystems, see RenameOptions
+	RenameNoMount bool
+	// SetNoMount is whether zfs set supports -u to not remount filesystems, see SetPropertyOptions
+	SetNoMount bool
+	// SendSavedState is whether zfs send supports -S to send a partially received state, see SendSavedState
+	SendSavedState bool
+	// PoolStatusParsable is whether zpool status supports -p to print exact error counters, see GetPoolErrors
+	PoolStatusParsable bool
+	// PoolWait is whether zpool wait and zfs wait are available, see WaitPoolScrub and Dataset.Wait
+	PoolWait bool
+	// HoldsParsable is whether zfs holds supports -p to print the time of holds in seconds, see Dataset.Holds
+	HoldsParsable bool
+	// RawSend is whether zfs send supports -w to send encrypted datasets raw, see SendOptions
+	RawSend bool
+	// ResumableReceive is whether zfs receive supports -s to save a partially received state, see ReceiveOptions
+	ResumableReceive bool
+	// ProjectIDs is whether zfs project can set the project of directories, which only Linux can, see SetProjectID
+	ProjectIDs bool
+	// JSONOutput is whether zfs get and zpool status support -j to print JSON, as OpenZFS 2.3 and newer do. It is off
+	// by default, as it depends on the version, see DetectPlatform. Dataset lists and pool statuses are then parsed
+	// from JSON, which is unaffected by property values containing tabs or newlines.
+	JSONOutput bool
+}
+
+var (
+	// PlatformLinux is OpenZFS on Linux
+	PlatformLinux = Platform{
+		Name:                "linux",
+		MountLoadKeys:       true,
+		TemporaryMountpoint: true,
+		UnmountUnloadKeys:   true,
+		CreateNoMount:       true,
+		RenameNoMount:       true,
+		SetNoMount:          true,
+		SendSavedState:      true,
+		PoolStatusParsable:  true,
+		PoolWait:            true,
+		HoldsParsable:       true,
+		RawSend:             true,
+		ResumableReceive:    true,
+		ProjectIDs:          true,
+	}
+
+	// PlatformFreeBSD is OpenZFS on FreeBSD 13 and newer
+	PlatformFreeBSD = Platform{
+		Name:               "freebsd",
+		MountLoadKeys:      true,
+		UnmountUnloadKeys:  true,
+		CreateNoMount:      true,
+		RenameNoMount:      true,
+		SetNoMount:         true,
+		SendSavedState:     true,
+		PoolStatusParsable: true,
+		PoolWait:           true,
+		HoldsParsable:      true,
+		RawSend:            true,
+		ResumableReceive:   true,
+	}
+
+	// PlatformIllumos is the ZFS of illumos distributions like OmniOS and SmartOS
+	PlatformIllumos = Platform{
+		Name:             "illumos",
+		MountLoadKeys:    true,
+		RawSend:          true,
+		ResumableReceive: true,
+	}
+)
+
+var currentPlatform atomic.Pointer[Platform]
+
+// SetPlatform sets the platform the commands are adapted to, for instance when they are run on another machine
+// through an Executor. Nil restores the default, which is the platform this program was built for.
+func SetPlatform(platform *Platform) {
+	if platform == nil {
+		currentPlatform.Store(nil)
+		return
+	}
+	p := *platform
+	currentPlatform.Store(&p)
+}
+
+// CurrentPlatform returns the platform the commands are adapted to
+func CurrentPlatform() Platform {
+	p := currentPlatform.Load()
+	if p == nil {
+		return defaultPlatform
+	}
+	return *p
+}
+
+// notSupported returns the error for a flag the platform does not support
+func (p Platform) notSupported(flag string) error {
+	return fmt.Errorf("%s on %s: %w", flag, p.Name, ErrNotSupported)
+}
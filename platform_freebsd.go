@@ -0,0 +1,6 @@
+//go:build freebsd
+// +build freebsd
+
+package zfs
+
+var defaultPlatform = PlatformFreeBSD
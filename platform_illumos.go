@@ -0,0 +1,6 @@
+//go:build illumos || solaris
+// +build illumos solaris
+
+package zfs
+
+var defaultPlatform = PlatformIllumos
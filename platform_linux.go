@@ -0,0 +1,6 @@
+//go:build linux
+// +build linux
+
+package zfs
+
+var defaultPlatform = PlatformLinux
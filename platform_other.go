@@ -0,0 +1,16 @@
+//go:build !freebsd && !linux && !illumos && !solaris
+// +build !freebsd,!linux,!illumos,!solaris
+
+package zfs
+
+// defaultPlatform is OpenZFS on other platforms, like macOS and Windows
+var defaultPlatform = Platform{
+	Name:               "openzfs",
+	MountLoadKeys:      true,
+	UnmountUnloadKeys:  true,
+	CreateNoMount:      true,
+	RenameNoMount:      true,
+	SetNoMount:         true,
+	SendSavedState:     true,
+	PoolStatusParsable: true,
+}
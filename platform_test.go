@@ -0,0 +1,81 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetPlatform(t *testing.T) {
+	SetPlatform(&PlatformIllumos)
+	require.Equal(t, PlatformIllumos, CurrentPlatform())
+	SetPlatform(nil)
+	require.Equal(t, defaultPlatform, CurrentPlatform())
+}
+
+func Test_platformNotSupported(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[0] == "status" {
+			_, err := io.WriteString(stdout, testPoolStatus)
+			return "", err
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+	SetPlatform(&PlatformIllumos)
+	defer SetPlatform(nil)
+
+	ctx := context.Background()
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	for _, err := range []error{
+		ds.Unmount(ctx, UnmountOptions{UnloadKeys: true}),
+		ds.Rename(ctx, "pool/fs2", RenameOptions{NoMount: true}),
+		ds.SetProperties(ctx, map[string]string{PropertyMountPoint: "/srv"}, SetPropertyOptions{NoMount: true}),
+		ds.WithTemporaryMount(ctx, TemporaryMountOptions{}, func(string) error { return nil }),
+		func() error {
+			_, err := SendSavedState(ctx, io.Discard, "pool/fs", ResumeSendOptions{})
+			return err
+		}(),
+		ds.Wait(ctx),
+		SetProjectID(ctx, "/pool/fs", 1, ProjectIDOptions{}),
+		func() error {
+			_, err := WaitPoolScrub(ctx, "pool")
+			return err
+		}(),
+		func() error {
+			_, err := CreateFilesystem(ctx, "pool/fs2", CreateFilesystemOptions{NoMount: true})
+			return err
+		}(),
+	} {
+		require.ErrorIs(t, err, ErrNotSupported)
+	}
+	require.Empty(t, executed)
+	require.NoError(t, ds.Mount(ctx, MountOptions{LoadKeys: true}))
+
+	executed = nil
+	errs, err := GetPoolErrors(ctx, "tank")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"status", "tank"}}, executed)
+	require.Equal(t, &PoolErrors{Read: 1, Write: 3, Checksum: 2, Data: 4}, errs)
+}
+
+func Test_parseErrorCounter(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"0":    0,
+		"12":   12,
+		"1.5K": 1536,
+		"2M":   2 << 20,
+	} {
+		n, err := parseErrorCounter(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, n, value)
+	}
+	for _, value := range []string{"", "x", "1.2X"} {
+		_, err := parseErrorCounter(value)
+		require.Error(t, err, value)
+	}
+}
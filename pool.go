@@ -0,0 +1,214 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Pool health states
+const (
+	PoolOnline    = "ONLINE"
+	PoolDegraded  = "DEGRADED"
+	PoolFaulted   = "FAULTED"
+	PoolOffline   = "OFFLINE"
+	PoolUnavail   = "UNAVAIL"
+	PoolRemoved   = "REMOVED"
+	PoolSuspended = "SUSPENDED"
+)
+
+// PoolHealthStates are all health states of a pool
+var PoolHealthStates = []string{PoolOnline, PoolDegraded, PoolFaulted, PoolOffline, PoolUnavail, PoolRemoved, PoolSuspended}
+
+var poolPropList = []string{"name", "health", "size", "allocated", "free", "fragmentation", "capacity", "dedupratio"}
+
+// Pool is a ZFS storage pool as listed by zpool list
+type Pool struct {
+	Name      string `json:"Name"`
+	Health    string `json:"Health"`
+	Size      uint64 `json:"Size"`
+	Allocated uint64 `json:"Allocated"`
+	Free      uint64 `json:"Free"`
+	// Fragmentation is the fragmentation of the free space in percent
+	Fragmentation uint64 `json:"Fragmentation"`
+	// Capacity is the allocated space in percent
+	Capacity   uint64  `json:"Capacity"`
+	DedupRatio float64 `json:"DedupRatio"`
+}
+
+// ListPools lists all imported pools
+func ListPools(ctx context.Context) ([]Pool, error) {
+	return listPools(ctx)
+}
+
+// GetPool returns the imported pool with the given name, or ErrPoolNotFound
+func GetPool(ctx context.Context, name string) (*Pool, error) {
+	pools, err := listPools(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(pools) != 1 {
+		return nil, fmt.Errorf("expected 1 pool, got %d: %w", len(pools), ErrPoolNotFound)
+	}
+	return &pools[0], nil
+}
+
+func listPools(ctx context.Context, names ...string) ([]Pool, error) {
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	out, err := c.Run(append([]string{"list", "-Hp", "-o", strings.Join(poolPropList, ",")}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make([]Pool, 0, len(out))
+	for _, fields := range out {
+		if len(fields) != len(poolPropList) {
+			return nil, fmt.Errorf("output of zpool list does not match properties: %v", fields)
+		}
+		pool := Pool{
+			Name:   fields[0],
+			Health: fields[1],
+		}
+		for i, field := range []*uint64{&pool.Size, &pool.Allocated, &pool.Free, &pool.Fragmentation, &pool.Capacity} {
+			*field, err = parsePoolNumber(fields[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing %s of pool %s: %w", poolPropList[i+2], pool.Name, err)
+			}
+		}
+		pool.DedupRatio, err = strconv.ParseFloat(strings.TrimSuffix(fields[7], "x"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing dedupratio of pool %s: %w", pool.Name, err)
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func parsePoolNumber(value string) (uint64, error) {
+	if value == ValueUnset {
+		return 0, nil
+	}
+	return strconv.ParseUint(strings.TrimSuffix(value, "%"), 10, 64)
+}
+
+// PoolErrors are the error counters of a pool, as shown by zpool status
+type PoolErrors struct {
+	// Read, Write and Checksum are the errors of the devices of the pool added up
+	Read     uint64 `json:"Read"`
+	Write    uint64 `json:"Write"`
+	Checksum uint64 `json:"Checksum"`
+	// Data is the amount of files or metadata with permanent errors
+	Data uint64 `json:"Data"`
+}
+
+// GetPoolErrors returns the error counters of a pool
+func GetPoolErrors(ctx context.Context, pool string) (*PoolErrors, error) {
+	if CurrentPlatform().JSONOutput {
+		status, err := GetPoolStatus(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		return &status.Errors, nil
+	}
+	lines, err := poolStatusLines(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolErrors(lines)
+}
+
+// poolStatusLines returns the output lines of zpool status, with exact numbers when the platform supports it
+func poolStatusLines(ctx context.Context, pool string) ([]string, error) {
+	c := command{
+		cmd:    PoolBinary,
+		ctx:    ctx,
+		fields: 1,
+	}
+	args := []string{"status", pool}
+	if CurrentPlatform().PoolStatusParsable {
+		args = []string{"status", "-p", pool}
+	}
+	var lines []string
+	err := c.Stream(func(fields []string) error {
+		lines = append(lines, fields[0])
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// deviceRow is a row of the config section of zpool status
+type deviceRow struct {
+	indent   int
+	counters [3]uint64
+}
+
+func parsePoolErrors(lines []string) (*PoolErrors, error) {
+	errs := &PoolErrors{}
+	var rows []deviceRow
+	inConfig := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "config:"):
+			inConfig = true
+			continue
+		case strings.HasPrefix(trimmed, "errors:"):
+			inConfig = false
+			count, _, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(trimmed, "errors:")), " ")
+			errs.Data, _ = strconv.ParseUint(count, 10, 64) // No known data errors
+			continue
+		case !inConfig || trimmed == "":
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if fields[0] == "NAME" || len(fields) < 5 {
+			continue // Header, or a section like logs or spares
+		}
+		row := deviceRow{indent: len(strings.TrimLeft(line, "\t")) - len(strings.TrimLeft(line, "\t "))}
+		for i := range row.counters {
+			n, err := parseErrorCounter(fields[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing error counters of device %s: %w", fields[0], err)
+			}
+			row.counters[i] = n
+		}
+		rows = append(rows, row)
+	}
+
+	// Only the leaf devices are added up, the counters of the vdevs above them are left out
+	for i, row := range rows {
+		if i+1 < len(rows) && rows[i+1].indent > row.indent {
+			continue
+		}
+		errs.Read += row.counters[0]
+		errs.Write += row.counters[1]
+		errs.Checksum += row.counters[2]
+	}
+	return errs, nil
+}
+
+// parseErrorCounter parses an error counter of zpool status, which is abbreviated like 1.2K without the -p flag
+func parseErrorCounter(value string) (uint64, error) {
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err == nil || value == "" {
+		return n, err
+	}
+	multiplier := strings.IndexByte("KMGTPE", value[len(value)-1])
+	if multiplier < 0 {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value[:len(value)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(f * math.Pow(1024, float64(multiplier+1))), nil
+}
@@ -0,0 +1,180 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Vdev types
+const (
+	VdevStripe = ""
+	VdevMirror = "mirror"
+	VdevRaidz1 = "raidz1"
+	VdevRaidz2 = "raidz2"
+	VdevRaidz3 = "raidz3"
+)
+
+// ErrInvalidTopology is returned when creating a pool with a topology zpool create would reject
+var ErrInvalidTopology = errors.New("invalid pool topology")
+
+// minimumVdevDevices is the minimum amount of devices of every vdev type
+var minimumVdevDevices = map[string]int{
+	VdevStripe: 1,
+	VdevMirror: 2,
+	VdevRaidz1: 2,
+	VdevRaidz2: 3,
+	VdevRaidz3: 4,
+}
+
+// Vdev is a virtual device of a pool
+type Vdev struct {
+	// Type is mirror or raidz1, 2 or 3, or empty to stripe the data over the devices
+	Type string `json:"Type"`
+	// Devices are the disks, partitions or files of the vdev
+	Devices []string `json:"Devices"`
+}
+
+// PoolTopology is the layout of the vdevs of a pool
+type PoolTopology struct {
+	// Data are the vdevs storing the data
+	Data []Vdev `json:"Data"`
+	// Special are the vdevs dedicated to metadata and small blocks
+	Special []Vdev `json:"Special"`
+	// Log are the separate intent log vdevs
+	Log []Vdev `json:"Log"`
+	// Cache are the devices of the level 2 read cache, which cannot be mirrored
+	Cache []string `json:"Cache"`
+	// Spares are the hot spare devices
+	Spares []string `json:"Spares"`
+}
+
+// Validate checks that the topology has data vdevs, and that every vdev has enough devices for its type
+func (t PoolTopology) Validate() error {
+	if len(t.Data) == 0 {
+		return fmt.Errorf("%w: no data vdevs", ErrInvalidTopology)
+	}
+	for _, vdevs := range [][]Vdev{t.Data, t.Special, t.Log} {
+		for _, vdev := range vdevs {
+			minimum, ok := minimumVdevDevices[vdev.Type]
+			if !ok {
+				return fmt.Errorf("%w: unknown vdev type %s", ErrInvalidTopology, vdev.Type)
+			}
+			if len(vdev.Devices) < minimum {
+				return fmt.Errorf("%w: %s vdev needs at least %d devices, got %d",
+					ErrInvalidTopology, vdevType(vdev.Type), minimum, len(vdev.Devices),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// args returns the vdev arguments of zpool create
+func (t PoolTopology) args() []string {
+	args := vdevArgs(nil, t.Data)
+	if len(t.Special) > 0 {
+		args = vdevArgs(append(args, "special"), t.Special)
+	}
+	if len(t.Log) > 0 {
+		args = vdevArgs(append(args, "log"), t.Log)
+	}
+	if len(t.Cache) > 0 {
+		args = append(append(args, "cache"), t.Cache...)
+	}
+	if len(t.Spares) > 0 {
+		args = append(append(args, "spare"), t.Spares...)
+	}
+	return args
+}
+
+func vdevArgs(args []string, vdevs []Vdev) []string {
+	for _, vdev := range vdevs {
+		if vdev.Type != VdevStripe {
+			args = append(args, vdev.Type)
+		}
+		args = append(args, vdev.Devices...)
+	}
+	return args
+}
+
+func vdevType(typ string) string {
+	if typ == VdevStripe {
+		return "stripe"
+	}
+	return typ
+}
+
+// CreatePoolOptions are options you can specify to customize the create pool command
+type CreatePoolOptions struct {
+	// Topology is the layout of the vdevs of the pool
+	Topology PoolTopology
+
+	// Properties are the pool properties to set, like ashift
+	Properties map[string]string
+
+	// FilesystemProperties are the properties to set on the root filesystem of the pool, like compression
+	FilesystemProperties map[string]string
+
+	// Mountpoint is the mountpoint of the root filesystem, empty for the default /pool
+	Mountpoint string
+
+	// Force uses the devices even when they appear in use, or when the vdevs have differing redundancy
+	Force bool
+}
+
+// CreatePool creates a new pool with the given topology, it returns ErrInvalidTopology when the topology is invalid
+func CreatePool(ctx context.Context, name string, options CreatePoolOptions) (*Pool, error) {
+	err := options.Topology.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 1, 16)
+	args[0] = "create"
+	if options.Force {
+		args = append(args, "-f")
+	}
+	if options.Mountpoint != "" {
+		args = append(args, "-m", options.Mountpoint)
+	}
+	args = append(args, propsSlice(options.Properties)...)
+	for k, v := range options.FilesystemProperties {
+		args = append(args, "-O", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, name)
+	args = append(args, options.Topology.args()...)
+
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err = c.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return GetPool(ctx, name)
+}
+
+// DestroyPoolOptions are options you can specify to customize the destroy pool command
+type DestroyPoolOptions struct {
+	// Forcibly unmount all active datasets
+	Force bool
+}
+
+// DestroyPool destroys the pool and all data on it
+func DestroyPool(ctx context.Context, name string, options DestroyPoolOptions) error {
+	args := make([]string, 1, 3)
+	args[0] = "destroy"
+	if options.Force {
+		args = append(args, "-f")
+	}
+	args = append(args, name)
+
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run(args...)
+	return err
+}
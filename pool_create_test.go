@@ -0,0 +1,65 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolTopology_Validate(t *testing.T) {
+	require.ErrorIs(t, PoolTopology{}.Validate(), ErrInvalidTopology)
+	require.ErrorIs(t, PoolTopology{Data: []Vdev{{Type: VdevMirror, Devices: []string{"sda"}}}}.Validate(), ErrInvalidTopology)
+	require.ErrorIs(t, PoolTopology{Data: []Vdev{{Type: "raidz9", Devices: []string{"sda", "sdb"}}}}.Validate(), ErrInvalidTopology)
+	require.ErrorIs(t, PoolTopology{
+		Data: []Vdev{{Type: VdevRaidz2, Devices: []string{"sda", "sdb", "sdc"}}},
+		Log:  []Vdev{{Type: VdevRaidz3, Devices: []string{"sdd"}}},
+	}.Validate(), ErrInvalidTopology)
+	require.NoError(t, PoolTopology{Data: []Vdev{{Devices: []string{"sda"}}}}.Validate())
+}
+
+func Test_CreatePool(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		executed = append(executed, args)
+		if args[0] == "list" {
+			_, err := io.WriteString(stdout, "tank\tONLINE\t1000\t0\t1000\t0\t0\t1.00x\n")
+			return "", err
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	pool, err := CreatePool(context.Background(), "tank", CreatePoolOptions{
+		Topology: PoolTopology{
+			Data: []Vdev{
+				{Type: VdevMirror, Devices: []string{"sda", "sdb"}},
+				{Type: VdevMirror, Devices: []string{"sdc", "sdd"}},
+			},
+			Special: []Vdev{{Type: VdevMirror, Devices: []string{"nvme0", "nvme1"}}},
+			Log:     []Vdev{{Devices: []string{"nvme2"}}},
+			Cache:   []string{"nvme3", "nvme4"},
+			Spares:  []string{"sde"},
+		},
+		Properties:           map[string]string{"ashift": "12"},
+		FilesystemProperties: map[string]string{PropertyCompression: "lz4"},
+		Mountpoint:           ValueNone,
+		Force:                true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tank", pool.Name)
+	require.Equal(t, []string{
+		"create", "-f", "-m", "none", "-o", "ashift=12", "-O", "compression=lz4", "tank",
+		"mirror", "sda", "sdb", "mirror", "sdc", "sdd",
+		"special", "mirror", "nvme0", "nvme1",
+		"log", "nvme2",
+		"cache", "nvme3", "nvme4",
+		"spare", "sde",
+	}, executed[0])
+
+	err = DestroyPool(context.Background(), "tank", DestroyPoolOptions{Force: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"destroy", "-f", "tank"}, executed[len(executed)-1])
+}
@@ -0,0 +1,35 @@
+package zfs
+
+import "context"
+
+// PoolEventsOptions are options you can specify to customize the zpool events command
+type PoolEventsOptions struct {
+	// Pool limits the events to those of this pool, empty for all pools
+	Pool string
+	// Follow keeps the command running, streaming new events as they happen until the context is cancelled.
+	// While it runs, the command holds a read slot of the command limits.
+	Follow bool
+}
+
+// StreamPoolEvents runs zpool events with verbose output, and calls fn for every line of output as soon as it is read.
+// Every event starts with an unindented line with its time and class, followed by its indented fields and an empty line.
+func StreamPoolEvents(ctx context.Context, options PoolEventsOptions, fn func(line string) error) error {
+	args := make([]string, 2, 4)
+	args[0] = "events"
+	args[1] = "-vH"
+	if options.Follow {
+		args = append(args, "-f")
+	}
+	if options.Pool != "" {
+		args = append(args, options.Pool)
+	}
+
+	c := command{
+		cmd:    PoolBinary,
+		ctx:    ctx,
+		fields: 1,
+	}
+	return c.Stream(func(fields []string) error {
+		return fn(fields[0])
+	}, args...)
+}
@@ -0,0 +1,27 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StreamPoolEvents(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		require.Equal(t, []string{"events", "-vH", "-f", "tank"}, args)
+		_, err := io.WriteString(stdout, "Oct 15 2026 10:00:00.000000000\tsysevent.fs.zfs.scrub_start\n\tpool = \"tank\"\n\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	var lines []string
+	err := StreamPoolEvents(context.Background(), PoolEventsOptions{Pool: "tank", Follow: true}, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Oct 15 2026 10:00:00.000000000\tsysevent.fs.zfs.scrub_start", "\tpool = \"tank\"", ""}, lines)
+}
@@ -0,0 +1,88 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ImportPoolOptions are options you can specify to customize the import pool command
+type ImportPoolOptions struct {
+	// Name is the name of the pool to import. When GUID is set as well, the pool with that GUID is imported under
+	// this name, which renames it when it had another name.
+	Name string
+
+	// GUID selects the pool by its GUID, for instance when several exported pools have the same name
+	GUID uint64
+
+	// SearchDirs are the directories searched for the devices of the pool, like /dev/disk/by-id, instead of /dev
+	SearchDirs []string
+
+	// AltRoot imports the pool with an alternate root, which is prefixed to the mountpoints of its filesystems
+	AltRoot string
+
+	// Force imports the pool even when it appears to be in use by another system
+	Force bool
+
+	// ReadOnly imports the pool read only
+	ReadOnly bool
+}
+
+// ImportPool imports an exported pool, it returns ErrPoolNotFound when no pool can be found to import
+func ImportPool(ctx context.Context, options ImportPoolOptions) (*Pool, error) {
+	if options.Name == "" {
+		return nil, errors.New("no pool name given to import")
+	}
+
+	args := make([]string, 1, 12)
+	args[0] = "import"
+	for _, dir := range options.SearchDirs {
+		args = append(args, "-d", dir)
+	}
+	if options.AltRoot != "" {
+		args = append(args, "-R", options.AltRoot)
+	}
+	if options.Force {
+		args = append(args, "-f")
+	}
+	if options.ReadOnly {
+		args = append(args, "-o", "readonly=on")
+	}
+	if options.GUID != 0 {
+		args = append(args, strconv.FormatUint(options.GUID, 10))
+	}
+	args = append(args, options.Name)
+
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return GetPool(ctx, options.Name)
+}
+
+// ExportPoolOptions are options you can specify to customize the export pool command
+type ExportPoolOptions struct {
+	// Forcibly unmount all datasets
+	Force bool
+}
+
+// ExportPool exports the pool, so it can be imported on another system
+func ExportPool(ctx context.Context, name string, options ExportPoolOptions) error {
+	args := make([]string, 1, 3)
+	args[0] = "export"
+	if options.Force {
+		args = append(args, "-f")
+	}
+	args = append(args, name)
+
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run(args...)
+	return err
+}
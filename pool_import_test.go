@@ -0,0 +1,50 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ImportPool(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		executed = append(executed, args)
+		switch {
+		case args[0] == "list":
+			_, err := io.WriteString(stdout, "backup\tONLINE\t1000\t400\t600\t12\t40\t1.00x\n")
+			return "", err
+		case args[len(args)-1] == "missing":
+			return "cannot import 'missing': no such pool available", errors.New("exit status 1")
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	pool, err := ImportPool(ctx, ImportPoolOptions{
+		Name:       "backup",
+		GUID:       1234,
+		SearchDirs: []string{"/dev/disk/by-id"},
+		AltRoot:    "/mnt",
+		Force:      true,
+		ReadOnly:   true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "backup", pool.Name)
+	require.Equal(t, []string{
+		"import", "-d", "/dev/disk/by-id", "-R", "/mnt", "-f", "-o", "readonly=on", "1234", "backup",
+	}, executed[0])
+
+	_, err = ImportPool(ctx, ImportPoolOptions{Name: "missing"})
+	require.ErrorIs(t, err, ErrPoolNotFound)
+	_, err = ImportPool(ctx, ImportPoolOptions{GUID: 1234})
+	require.Error(t, err)
+
+	require.NoError(t, ExportPool(ctx, "backup", ExportPoolOptions{Force: true}))
+	require.Equal(t, []string{"export", "-f", "backup"}, executed[len(executed)-1])
+}
@@ -0,0 +1,107 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pool properties, see zpoolprops(7) for all of them
+const (
+	PoolPropertyAllocated     = "allocated"
+	PoolPropertyAltRoot       = "altroot"
+	PoolPropertyAshift        = "ashift"
+	PoolPropertyAutoExpand    = "autoexpand"
+	PoolPropertyAutoTrim      = "autotrim"
+	PoolPropertyCapacity      = "capacity"
+	PoolPropertyComment       = "comment"
+	PoolPropertyDedupRatio    = "dedupratio"
+	PoolPropertyFailMode      = "failmode"
+	PoolPropertyFragmentation = "fragmentation"
+	PoolPropertyFree          = "free"
+	PoolPropertyGUID          = "guid"
+	PoolPropertyHealth        = "health"
+	PoolPropertyReadOnly      = "readonly"
+	PoolPropertySize          = "size"
+)
+
+// PoolProperties are property values of a pool indexed by property name, with helpers to parse typed values
+type PoolProperties map[string]string
+
+// Uint parses a numeric property like ashift, capacity or size. Percentages are parsed without their percent sign,
+// unset values as zero.
+func (p PoolProperties) Uint(key string) (uint64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("pool property %s not retrieved", key)
+	}
+	n, err := parsePoolNumber(value)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing pool property %s [%s]: %w", key, value, err)
+	}
+	return n, nil
+}
+
+// Float parses a ratio property like dedupratio, without its x suffix
+func (p PoolProperties) Float(key string) (float64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("pool property %s not retrieved", key)
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing pool property %s [%s]: %w", key, value, err)
+	}
+	return f, nil
+}
+
+// Bool returns whether an on/off property like autotrim is on
+func (p PoolProperties) Bool(key string) bool {
+	return setBool(p[key])
+}
+
+// GetProperties returns the values of the given properties of the pool, or of all properties when none are given
+func (p *Pool) GetProperties(ctx context.Context, keys ...string) (PoolProperties, error) {
+	list := "all"
+	if len(keys) > 0 {
+		list = strings.Join(keys, ",")
+	}
+	c := command{
+		cmd:    PoolBinary,
+		ctx:    ctx,
+		fields: 2,
+	}
+	props := make(PoolProperties, max(len(keys), 16))
+	err := c.Stream(func(fields []string) error {
+		addPropertyValue(props, fields)
+		return nil
+	}, "get", "-Hp", "-o", "property,value", list, p.Name)
+	if err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// GetProperty returns the current value of a property of the pool
+func (p *Pool) GetProperty(ctx context.Context, key string) (string, error) {
+	props, err := p.GetProperties(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	value, ok := props[key]
+	if !ok {
+		return "", fmt.Errorf("pool property %s not returned", key)
+	}
+	return value, nil
+}
+
+// SetProperty sets a property of the pool
+func (p *Pool) SetProperty(ctx context.Context, key, val string) error {
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run("set", fmt.Sprintf("%s=%s", key, val), p.Name)
+	return err
+}
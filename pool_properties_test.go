@@ -0,0 +1,50 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PoolProperties(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		executed = append(executed, args)
+		if args[0] == "get" {
+			_, err := io.WriteString(stdout, "ashift\t12\ncapacity\t40\ndedupratio\t1.50x\nautotrim\ton\ncomment\t\n")
+			return "", err
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	pool := &Pool{Name: "tank"}
+	props, err := pool.GetProperties(ctx, PoolPropertyAshift, PoolPropertyCapacity, PoolPropertyDedupRatio, PoolPropertyAutoTrim, PoolPropertyComment)
+	require.NoError(t, err)
+	require.Equal(t, []string{"get", "-Hp", "-o", "property,value", "ashift,capacity,dedupratio,autotrim,comment", "tank"}, executed[0])
+
+	ashift, err := props.Uint(PoolPropertyAshift)
+	require.NoError(t, err)
+	require.EqualValues(t, 12, ashift)
+	capacity, err := props.Uint(PoolPropertyCapacity)
+	require.NoError(t, err)
+	require.EqualValues(t, 40, capacity)
+	ratio, err := props.Float(PoolPropertyDedupRatio)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, ratio)
+	require.True(t, props.Bool(PoolPropertyAutoTrim))
+	require.Empty(t, props[PoolPropertyComment])
+	_, err = props.Uint(PoolPropertySize)
+	require.Error(t, err)
+
+	value, err := pool.GetProperty(ctx, PoolPropertyAshift)
+	require.NoError(t, err)
+	require.Equal(t, "12", value)
+
+	require.NoError(t, pool.SetProperty(ctx, PoolPropertyAutoTrim, ValueOn))
+	require.Equal(t, []string{"set", "autotrim=on", "tank"}, executed[len(executed)-1])
+}
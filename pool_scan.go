@@ -0,0 +1,221 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scan states of a pool, as shown by zpool status
+const (
+	ScanNone     = "none"
+	ScanScanning = "scanning"
+	ScanPaused   = "paused"
+	ScanFinished = "finished"
+	ScanCanceled = "canceled"
+)
+
+// Scan functions of a pool
+const (
+	ScanScrub    = "scrub"
+	ScanResilver = "resilver"
+)
+
+const scanTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// PoolScan is the state of the last scrub or resilver of a pool
+type PoolScan struct {
+	// Function is scrub or resilver, empty when no scan was requested
+	Function string `json:"Function"`
+	State    string `json:"State"`
+	// Progress is the percentage done of a scan in progress
+	Progress float64 `json:"Progress"`
+	// Remaining is the estimated time until a scan in progress completes, zero when there is no estimate
+	Remaining time.Duration `json:"Remaining"`
+	// Errors is the amount of errors a finished scan found
+	Errors uint64 `json:"Errors"`
+	// StartedAt is when the scan in progress started
+	StartedAt time.Time `json:"StartedAt"`
+	// EndedAt is when the scan finished or was canceled
+	EndedAt time.Time `json:"EndedAt"`
+}
+
+// ScrubPool starts a scrub of the pool, it returns ErrPoolScrubbing when the pool is being scrubbed already.
+// A paused scrub is resumed.
+func ScrubPool(ctx context.Context, pool string) error {
+	return scrub(ctx, pool)
+}
+
+// StopScrub cancels the scrub of the pool, it returns ErrNoActiveScrub when the pool is not being scrubbed
+func StopScrub(ctx context.Context, pool string) error {
+	return scrub(ctx, pool, "-s")
+}
+
+// PauseScrub pauses the scrub of the pool, ScrubPool resumes it again
+func PauseScrub(ctx context.Context, pool string) error {
+	return scrub(ctx, pool, "-p")
+}
+
+func scrub(ctx context.Context, pool string, flags ...string) error {
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run(append(append([]string{"scrub"}, flags...), pool)...)
+	return err
+}
+
+// WaitPoolScrub waits until the scrub of the pool is finished, canceled or paused, and returns its state
+func WaitPoolScrub(ctx context.Context, pool string) (*PoolScan, error) {
+	p := CurrentPlatform()
+	if !p.PoolWait {
+		return nil, p.notSupported("zpool wait")
+	}
+	c := command{
+		cmd: PoolBinary,
+		ctx: ctx,
+	}
+	_, err := c.Run("wait", "-t", "scrub", pool)
+	if err != nil {
+		return nil, err
+	}
+	return GetPoolScan(ctx, pool)
+}
+
+// GetPoolScan returns the state of the last scrub or resilver of the pool
+func GetPoolScan(ctx context.Context, pool string) (*PoolScan, error) {
+	if CurrentPlatform().JSONOutput {
+		status, err := GetPoolStatus(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		return status.Scan, nil
+	}
+	lines, err := poolStatusLines(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolScan(lines)
+}
+
+func parsePoolScan(lines []string) (*PoolScan, error) {
+	scan := &PoolScan{State: ScanNone}
+	idx := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "scan:") {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return scan, nil
+	}
+	status := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[idx]), "scan:"))
+
+	var err error
+	switch {
+	case status == "none requested":
+		return scan, nil
+	case strings.HasPrefix(status, "scrub repaired"), strings.HasPrefix(status, "resilvered"):
+		scan.Function = scanFunction(status)
+		scan.State = ScanFinished
+		_, errs, _ := strings.Cut(status, " with ")
+		errs, _, _ = strings.Cut(errs, " ")
+		scan.Errors, err = strconv.ParseUint(errs, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing scan errors of %q: %w", status, err)
+		}
+		scan.EndedAt, err = parseScanTime(status, " on ")
+	case strings.Contains(status, " in progress since "):
+		scan.Function = scanFunction(status)
+		scan.State = ScanScanning
+		scan.StartedAt, err = parseScanTime(status, " since ")
+		if err == nil {
+			err = parseScanProgress(scan, lines[idx+1:])
+		}
+	case strings.Contains(status, " paused since "):
+		scan.Function = scanFunction(status)
+		scan.State = ScanPaused
+		err = parseScanProgress(scan, lines[idx+1:])
+	case strings.Contains(status, " canceled on "):
+		scan.Function = scanFunction(status)
+		scan.State = ScanCanceled
+		scan.EndedAt, err = parseScanTime(status, " on ")
+	default:
+		return nil, fmt.Errorf("unknown scan status %q", status)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
+func scanFunction(status string) string {
+	if strings.HasPrefix(status, "resilver") {
+		return ScanResilver
+	}
+	return ScanScrub
+}
+
+// parseScanTime parses the time after the separator in the scan status
+func parseScanTime(status, separator string) (time.Time, error) {
+	idx := strings.LastIndex(status, separator)
+	if idx < 0 {
+		return time.Time{}, fmt.Errorf("no time in scan status %q", status)
+	}
+	tm, err := time.ParseInLocation(scanTimeLayout, status[idx+len(separator):], time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing time of scan status %q: %w", status, err)
+	}
+	return tm, nil
+}
+
+// parseScanProgress parses the percentage done and the estimated time to go from the lines following the scan status
+func parseScanProgress(scan *PoolScan, lines []string) error {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, ":") {
+			break // Next section
+		}
+		for _, part := range strings.Split(line, ", ") {
+			var err error
+			if done, ok := strings.CutSuffix(part, "% done"); ok {
+				scan.Progress, err = strconv.ParseFloat(done, 64)
+			}
+			if remaining, ok := strings.CutSuffix(part, " to go"); ok {
+				scan.Remaining, err = parseScanDuration(remaining)
+			}
+			if err != nil {
+				return fmt.Errorf("error parsing scan progress %q: %w", part, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseScanDuration parses a duration of zpool status, like 00:03:10 or 1 days 02:00:00
+func parseScanDuration(value string) (time.Duration, error) {
+	var dur time.Duration
+	if days, clock, ok := strings.Cut(value, " days "); ok {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		dur = time.Duration(n) * 24 * time.Hour
+		value = clock
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.ParseUint(parts[i], 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		dur += time.Duration(n) * unit
+	}
+	return dur, nil
+}
@@ -0,0 +1,102 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePoolScan(t *testing.T) {
+	scan, err := parsePoolScan(strings.Split(testPoolStatus, "\n"))
+	require.NoError(t, err)
+	require.Equal(t, ScanScrub, scan.Function)
+	require.Equal(t, ScanFinished, scan.State)
+	require.Zero(t, scan.Errors)
+	require.Equal(t, time.Date(2026, time.October, 15, 10, 0, 0, 0, time.Local), scan.EndedAt)
+
+	scan, err = parsePoolScan([]string{
+		"  pool: tank",
+		"  scan: scrub in progress since Thu Oct 15 10:00:00 2026",
+		"\t1.23G scanned at 100M/s, 500M issued at 50M/s, 10G total",
+		"\t0B repaired, 5.00% done, 00:03:10 to go",
+		"config:",
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScanScanning, scan.State)
+	require.Equal(t, 5.0, scan.Progress)
+	require.Equal(t, 3*time.Minute+10*time.Second, scan.Remaining)
+	require.Equal(t, time.Date(2026, time.October, 15, 10, 0, 0, 0, time.Local), scan.StartedAt)
+
+	scan, err = parsePoolScan([]string{
+		"  scan: scrub paused since Thu Oct 15 10:00:00 2026",
+		"\t0B repaired, 45.50% done, 1 days 02:00:00 to go",
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScanPaused, scan.State)
+	require.Equal(t, 45.5, scan.Progress)
+	require.Equal(t, 26*time.Hour, scan.Remaining)
+
+	scan, err = parsePoolScan([]string{"  scan: resilvered 1.20G in 00:10:00 with 2 errors on Thu Oct 15 10:00:00 2026"})
+	require.NoError(t, err)
+	require.Equal(t, ScanResilver, scan.Function)
+	require.EqualValues(t, 2, scan.Errors)
+
+	scan, err = parsePoolScan([]string{"  scan: scrub canceled on Thu Oct 15 10:00:00 2026"})
+	require.NoError(t, err)
+	require.Equal(t, ScanCanceled, scan.State)
+
+	scan, err = parsePoolScan([]string{"  scan: none requested"})
+	require.NoError(t, err)
+	require.Equal(t, &PoolScan{State: ScanNone}, scan)
+
+	_, err = parsePoolScan([]string{"  scan: something else"})
+	require.Error(t, err)
+}
+
+func Test_ScrubPool(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		require.Equal(t, []string{"scrub", "tank"}, args)
+		return "cannot scrub tank: currently scrubbing; use 'zpool scrub -s' to cancel current scrub", errors.New("exit status 1")
+	}))
+	defer SetExecutor(nil)
+
+	require.ErrorIs(t, ScrubPool(context.Background(), "tank"), ErrPoolScrubbing)
+}
+
+func Test_StopScrub(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		executed = append(executed, args)
+		switch args[0] {
+		case "status":
+			_, err := io.WriteString(stdout, testPoolStatus)
+			return "", err
+		case "scrub":
+			return "cannot cancel scrubbing tank: there is no active scrub", errors.New("exit status 1")
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+	SetPlatform(&PlatformLinux)
+	defer SetPlatform(nil)
+
+	ctx := context.Background()
+	require.ErrorIs(t, StopScrub(ctx, "tank"), ErrNoActiveScrub)
+	require.ErrorIs(t, PauseScrub(ctx, "tank"), ErrNoActiveScrub)
+	scan, err := WaitPoolScrub(ctx, "tank")
+	require.NoError(t, err)
+	require.Equal(t, ScanFinished, scan.State)
+	require.Equal(t, [][]string{
+		{"scrub", "-s", "tank"},
+		{"scrub", "-p", "tank"},
+		{"wait", "-t", "scrub", "tank"},
+		{"status", "-p", "tank"},
+	}, executed)
+}
@@ -0,0 +1,215 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Sections of the config of zpool status, besides the data vdevs
+const (
+	sectionSpecial = "special"
+	sectionDedup   = "dedup"
+	sectionLogs    = "logs"
+	sectionCache   = "cache"
+	sectionSpares  = "spares"
+)
+
+// Device states of zpool status that are not pool health states
+const (
+	DeviceAvail = "AVAIL"
+	DeviceInUse = "INUSE"
+)
+
+// VdevStatus is a vdev or device in the config of zpool status
+type VdevStatus struct {
+	Name  string `json:"Name"`
+	State string `json:"State"`
+	// Read, Write and Checksum are the error counters of the vdev or device, spares have none
+	Read     uint64 `json:"Read"`
+	Write    uint64 `json:"Write"`
+	Checksum uint64 `json:"Checksum"`
+	// Message is the note after the counters, like (resilvering) or was /dev/sdb
+	Message  string       `json:"Message"`
+	Children []VdevStatus `json:"Children"`
+}
+
+// Healthy returns whether the vdev or device is online, or an available or used spare, without errors
+func (v *VdevStatus) Healthy() bool {
+	switch v.State {
+	case PoolOnline, DeviceAvail, DeviceInUse:
+		return v.Read == 0 && v.Write == 0 && v.Checksum == 0
+	}
+	return false
+}
+
+// PoolStatus is the status of a pool as shown by zpool status, with the vdev tree of its config
+type PoolStatus struct {
+	Name  string `json:"Name"`
+	State string `json:"State"`
+	// Status and Action explain a problem with the pool and how to resolve it, empty when there is none
+	Status string `json:"Status"`
+	Action string `json:"Action"`
+	// Scan is the last scrub or resilver, including the progress of a resilver in progress
+	Scan *PoolScan `json:"Scan"`
+	// Root is the pool itself, its children are the data vdevs
+	Root VdevStatus `json:"Root"`
+	// Special, Dedup, Logs and Cache are the vdevs or devices of the allocation classes and the cache
+	Special []VdevStatus `json:"Special"`
+	Dedup   []VdevStatus `json:"Dedup"`
+	Logs    []VdevStatus `json:"Logs"`
+	Cache   []VdevStatus `json:"Cache"`
+	Spares  []VdevStatus `json:"Spares"`
+	// Errors are the error counters of the devices added up, and the amount of data errors
+	Errors PoolErrors `json:"Errors"`
+}
+
+// UnhealthyDevices returns all vdevs and devices that are not healthy, parents before their children
+func (s *PoolStatus) UnhealthyDevices() []VdevStatus {
+	var unhealthy []VdevStatus
+	var walk func(vdevs []VdevStatus)
+	walk = func(vdevs []VdevStatus) {
+		for _, vdev := range vdevs {
+			if !vdev.Healthy() {
+				unhealthy = append(unhealthy, vdev)
+			}
+			walk(vdev.Children)
+		}
+	}
+	walk(s.Root.Children)
+	for _, section := range [][]VdevStatus{s.Special, s.Dedup, s.Logs, s.Cache, s.Spares} {
+		walk(section)
+	}
+	return unhealthy
+}
+
+// GetPoolStatus returns the status of a pool with its vdev tree
+func GetPoolStatus(ctx context.Context, pool string) (*PoolStatus, error) {
+	if CurrentPlatform().JSONOutput {
+		var out bytes.Buffer
+		c := command{
+			cmd:    PoolBinary,
+			ctx:    ctx,
+			stdout: &out,
+		}
+		_, err := c.Run("status", "-j", "-p", pool)
+		if err != nil {
+			return nil, err
+		}
+		return parsePoolStatusJSON(out.Bytes(), pool)
+	}
+
+	lines, err := poolStatusLines(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolStatus(lines)
+}
+
+// vdevRow is a row of the config section of zpool status
+type vdevRow struct {
+	indent int
+	vdev   VdevStatus
+}
+
+func parsePoolStatus(lines []string) (*PoolStatus, error) {
+	status := &PoolStatus{}
+	var err error
+	status.Scan, err = parsePoolScan(lines)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := parsePoolErrors(lines)
+	if err != nil {
+		return nil, err
+	}
+	status.Errors = *errs
+
+	sections := make(map[string][]vdevRow)
+	section := ""
+	key := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if name, value, ok := strings.Cut(trimmed, ":"); ok && !strings.HasPrefix(line, "\t") && !strings.Contains(name, " ") {
+			key = name
+			value = strings.TrimSpace(value)
+			switch key {
+			case "pool":
+				status.Name = value
+			case "state":
+				status.State = value
+			case "status":
+				status.Status = value
+			case "action":
+				status.Action = value
+			}
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			continue
+		case key == "status":
+			status.Status = strings.TrimSpace(status.Status + " " + trimmed)
+			continue
+		case key == "action":
+			status.Action = strings.TrimSpace(status.Action + " " + trimmed)
+			continue
+		case key != "config":
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		indent := len(strings.TrimLeft(line, "\t")) - len(strings.TrimLeft(line, "\t "))
+		switch {
+		case fields[0] == "NAME":
+			continue // Header
+		case len(fields) == 1 && indent == 0:
+			section = fields[0]
+			continue
+		case len(fields) == 1:
+			continue
+		}
+
+		row := vdevRow{indent: indent, vdev: VdevStatus{Name: fields[0], State: fields[1]}}
+		message := fields[2:]
+		if len(fields) >= 5 {
+			for i, counter := range []*uint64{&row.vdev.Read, &row.vdev.Write, &row.vdev.Checksum} {
+				*counter, err = parseErrorCounter(fields[i+2])
+				if err != nil {
+					return nil, fmt.Errorf("error parsing error counters of device %s: %w", fields[0], err)
+				}
+			}
+			message = fields[5:]
+		}
+		row.vdev.Message = strings.Join(message, " ")
+		sections[section] = append(sections[section], row)
+	}
+
+	if roots := buildVdevTree(sections[""]); len(roots) > 0 {
+		status.Root = roots[0]
+	}
+	status.Special = buildVdevTree(sections[sectionSpecial])
+	status.Dedup = buildVdevTree(sections[sectionDedup])
+	status.Logs = buildVdevTree(sections[sectionLogs])
+	status.Cache = buildVdevTree(sections[sectionCache])
+	status.Spares = buildVdevTree(sections[sectionSpares])
+	return status, nil
+}
+
+// buildVdevTree nests the rows below the preceding row with a smaller indent
+func buildVdevTree(rows []vdevRow) []VdevStatus {
+	var vdevs []VdevStatus
+	for i := 0; i < len(rows); {
+		end := i + 1
+		for end < len(rows) && rows[end].indent > rows[i].indent {
+			end++
+		}
+		vdev := rows[i].vdev
+		vdev.Children = buildVdevTree(rows[i+1 : end])
+		vdevs = append(vdevs, vdev)
+		i = end
+	}
+	return vdevs
+}
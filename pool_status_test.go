@@ -0,0 +1,82 @@
+package zfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePoolStatus(t *testing.T) {
+	status, err := parsePoolStatus(strings.Split(testPoolStatus, "\n"))
+	require.NoError(t, err)
+	require.Equal(t, "tank", status.Name)
+	require.Equal(t, PoolDegraded, status.State)
+	require.Equal(t, "One or more devices has experienced an unrecoverable error.", status.Status)
+	require.Equal(t, ScanFinished, status.Scan.State)
+	require.Equal(t, PoolErrors{Read: 1, Write: 3, Checksum: 2, Data: 4}, status.Errors)
+
+	require.Equal(t, VdevStatus{
+		Name:  "tank",
+		State: PoolDegraded,
+		Children: []VdevStatus{
+			{Name: "mirror-0", State: PoolDegraded, Checksum: 2, Children: []VdevStatus{
+				{Name: "sda", State: PoolOnline},
+				{Name: "sdb", State: PoolDegraded, Read: 1, Checksum: 2},
+			}},
+			{Name: "sdc", State: PoolOnline, Write: 3},
+		},
+	}, status.Root)
+	require.Equal(t, []VdevStatus{{Name: "sdd", State: PoolOnline}}, status.Logs)
+	require.Equal(t, []VdevStatus{{Name: "sde", State: DeviceAvail}}, status.Spares)
+	require.Empty(t, status.Cache)
+
+	var unhealthy []string
+	for _, vdev := range status.UnhealthyDevices() {
+		unhealthy = append(unhealthy, vdev.Name)
+	}
+	require.Equal(t, []string{"mirror-0", "sdb", "sdc"}, unhealthy)
+}
+
+func Test_parsePoolStatusResilver(t *testing.T) {
+	status, err := parsePoolStatus([]string{
+		"  pool: tank",
+		" state: DEGRADED",
+		"status: One or more devices is currently being resilvered.  The pool will",
+		"\tcontinue to function, possibly in a degraded state.",
+		"action: Wait for the resilver to complete.",
+		"  scan: resilver in progress since Thu Oct 15 10:00:00 2026",
+		"\t1.23G scanned at 100M/s, 500M issued at 50M/s, 10G total",
+		"\t500M resilvered, 5.00% done, 00:03:10 to go",
+		"config:",
+		"",
+		"\tNAME             STATE     READ WRITE CKSUM",
+		"\ttank             DEGRADED     0     0     0",
+		"\t  mirror-0       DEGRADED     0     0     0",
+		"\t    sda          ONLINE       0     0     0",
+		"\t    replacing-1  DEGRADED     0     0     0",
+		"\t      sdb        UNAVAIL      0     0     0  was /dev/sdb1",
+		"\t      sdc        ONLINE       0     0     0  (resilvering)",
+		"\tspecial",
+		"\t  nvme0n1        ONLINE       0     0     0",
+		"\tcache",
+		"\t  nvme1n1        ONLINE       0     0  1.5K",
+		"",
+		"errors: No known data errors",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "One or more devices is currently being resilvered.  The pool will "+
+		"continue to function, possibly in a degraded state.", status.Status)
+	require.Equal(t, "Wait for the resilver to complete.", status.Action)
+	require.Equal(t, ScanResilver, status.Scan.Function)
+	require.Equal(t, 5.0, status.Scan.Progress)
+
+	replacing := status.Root.Children[0].Children[1]
+	require.Equal(t, "replacing-1", replacing.Name)
+	require.Len(t, replacing.Children, 2)
+	require.Equal(t, "was /dev/sdb1", replacing.Children[0].Message)
+	require.Equal(t, "(resilvering)", replacing.Children[1].Message)
+	require.Equal(t, []VdevStatus{{Name: "nvme0n1", State: PoolOnline}}, status.Special)
+	require.EqualValues(t, 1536, status.Cache[0].Checksum)
+	require.Zero(t, status.Errors.Data)
+}
@@ -0,0 +1,87 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPoolStatus = `  pool: tank
+ state: DEGRADED
+status: One or more devices has experienced an unrecoverable error.
+  scan: scrub repaired 0B in 00:00:01 with 0 errors on Thu Oct 15 10:00:00 2026
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  mirror-0  DEGRADED     0     0     2
+	    sda     ONLINE       0     0     0
+	    sdb     DEGRADED     1     0     2
+	  sdc       ONLINE       0     3     0
+	logs
+	  sdd       ONLINE       0     0     0
+	spares
+	  sde       AVAIL
+
+errors: 4 data errors, use '-v' for a list
+`
+
+func Test_ListPools(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		require.Equal(t, []string{"list", "-Hp", "-o", "name,health,size,allocated,free,fragmentation,capacity,dedupratio"}, args)
+		_, err := io.WriteString(stdout, "tank\tONLINE\t1000\t400\t600\t12\t40\t1.50\nboot\tDEGRADED\t100\t10\t90\t-\t10\t1.00x\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	pools, err := ListPools(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []Pool{
+		{Name: "tank", Health: PoolOnline, Size: 1000, Allocated: 400, Free: 600, Fragmentation: 12, Capacity: 40, DedupRatio: 1.5},
+		{Name: "boot", Health: PoolDegraded, Size: 100, Allocated: 10, Free: 90, Capacity: 10, DedupRatio: 1},
+	}, pools)
+}
+
+func Test_GetPool(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		if args[len(args)-1] == "missing" {
+			return "cannot open 'missing': no such pool", errors.New("exit status 1")
+		}
+		require.Equal(t, []string{"list", "-Hp", "-o", "name,health,size,allocated,free,fragmentation,capacity,dedupratio", "tank"}, args)
+		_, err := io.WriteString(stdout, "tank\tONLINE\t1000\t400\t600\t12\t40\t1.50\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	pool, err := GetPool(context.Background(), "tank")
+	require.NoError(t, err)
+	require.Equal(t, &Pool{Name: "tank", Health: PoolOnline, Size: 1000, Allocated: 400, Free: 600, Fragmentation: 12, Capacity: 40, DedupRatio: 1.5}, pool)
+
+	_, err = GetPool(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrPoolNotFound)
+}
+
+func Test_GetPoolErrors(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, cmd string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, PoolBinary, cmd)
+		require.Equal(t, []string{"status", "-p", "tank"}, args)
+		_, err := io.WriteString(stdout, testPoolStatus)
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	errs, err := GetPoolErrors(context.Background(), "tank")
+	require.NoError(t, err)
+	require.Equal(t, &PoolErrors{Read: 1, Write: 3, Checksum: 2, Data: 4}, errs)
+}
+
+func Test_parsePoolErrors(t *testing.T) {
+	errs, err := parsePoolErrors([]string{"config:", "", "\tNAME STATE READ WRITE CKSUM", "\ttank ONLINE 0 0 0", "\t  sda ONLINE 0 0 0", "", "errors: No known data errors"})
+	require.NoError(t, err)
+	require.Equal(t, &PoolErrors{}, errs)
+}
@@ -4,11 +4,18 @@
 package zfs
 
 import (
+	"os/exec"
 	"syscall"
 )
 
 func procAttributes() *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{
 		Pdeathsig: syscall.SIGINT,
+		Setpgid:   true,
 	}
 }
+
+// signalProcess sends the signal to the process group of the command, so its children receive it as well
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
@@ -4,9 +4,15 @@
 package zfs
 
 import (
+	"os/exec"
 	"syscall"
 )
 
 func procAttributes() *syscall.SysProcAttr {
 	return nil
 }
+
+// signalProcess sends the signal to the process of the command
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Signal(sig)
+}
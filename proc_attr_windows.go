@@ -4,6 +4,7 @@
 package zfs
 
 import (
+	"os/exec"
 	"syscall"
 )
 
@@ -12,3 +13,8 @@ func procAttributes() *syscall.SysProcAttr {
 		HideWindow: true,
 	}
 }
+
+// signalProcess kills the process of the command, as windows does not support sending other signals
+func signalProcess(cmd *exec.Cmd, _ syscall.Signal) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,59 @@
+package zfs
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultTerminationGracePeriod = 5 * time.Second
+
+var terminationGracePeriod atomic.Int64
+
+func init() {
+	terminationGracePeriod.Store(int64(defaultTerminationGracePeriod))
+}
+
+// SetTerminationGracePeriod sets how long a command gets to exit after it was asked to terminate because its
+// context is done. When the grace period has passed, the command and its children are killed and its pipes closed.
+func SetTerminationGracePeriod(period time.Duration) {
+	terminationGracePeriod.Store(int64(period))
+}
+
+// withCommandTimeout returns the context with the timeout of the options of a command, when it is positive.
+// When the timeout passes, the command is terminated like when its context is done.
+func withCommandTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// setTermination makes the command terminate its whole process group when its context is done.
+// The returned function must be called after the command has been waited for.
+func setTermination(cmd *exec.Cmd) (waited func()) {
+	grace := time.Duration(terminationGracePeriod.Load())
+
+	var killTimer atomic.Pointer[time.Timer]
+	cmd.Cancel = func() error {
+		err := signalProcess(cmd, syscall.SIGTERM)
+		killTimer.Store(time.AfterFunc(grace, func() {
+			_ = signalProcess(cmd, syscall.SIGKILL)
+		}))
+		return err
+	}
+	// After the grace period, exec stops waiting for the pipes to be closed
+	cmd.WaitDelay = grace
+
+	return func() {
+		timer := killTimer.Load()
+		if timer == nil {
+			return // Not cancelled
+		}
+		timer.Stop()
+		// Make sure no children of the command are left behind holding datasets busy
+		_ = signalProcess(cmd, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,133 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_commandTermination(t *testing.T) {
+	SetTerminationGracePeriod(500 * time.Millisecond)
+	defer SetTerminationGracePeriod(defaultTerminationGracePeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := command{
+		cmd: "sh",
+		ctx: ctx,
+	}
+
+	start := time.Now()
+	// The shell ignores the termination signal, so it has to be killed after the grace period
+	_, err := c.Run("-c", "trap '' TERM; sleep 10 & wait")
+	require.ErrorIs(t, err, ErrCommandCancelled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func Test_commandReaderFromOutput(t *testing.T) {
+	var out bytes.Buffer // Implements io.ReaderFrom, so the output is read from the pipe directly
+	c := command{
+		cmd:    "sh",
+		ctx:    context.Background(),
+		stdout: &out,
+	}
+
+	_, err := c.Run("-c", "printf 'hello world'")
+	require.NoError(t, err)
+	require.Equal(t, "hello world", out.String())
+}
+
+// readerFromRecorder records the reader its ReadFrom fast path was called with
+type readerFromRecorder struct {
+	bytes.Buffer
+	src io.Reader
+}
+
+func (r *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.src = src
+	return r.Buffer.ReadFrom(src)
+}
+
+// writerToRecorder records the writer its WriteTo fast path was called with
+type writerToRecorder struct {
+	*bytes.Reader
+	dst io.Writer
+}
+
+func (r *writerToRecorder) WriteTo(dst io.Writer) (int64, error) {
+	r.dst = dst
+	return r.Reader.WriteTo(dst)
+}
+
+func Test_streamFastPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shim is a shell script")
+	}
+	dir := t.TempDir()
+	shim := filepath.Join(dir, "zfs-shim")
+	script := "#!/bin/sh\ncase \"$1\" in\nsend) printf stream ;;\nreceive) cat > received ;;\nesac\n"
+	require.NoError(t, os.WriteFile(shim, []byte(script), 0o755))
+	ctx := WithCommandConfig(context.Background(), CommandConfig{ZFSPath: shim, WorkDir: dir})
+
+	// The counted output is handed the zfs stdout pipe itself, so the writer can sendfile or splice from it
+	var out readerFromRecorder
+	stats, err := ResumeSend(ctx, &out, "token", ResumeSendOptions{})
+	require.NoError(t, err)
+	require.IsType(t, &os.File{}, out.src)
+	require.Equal(t, "stream", out.String())
+	require.EqualValues(t, 6, stats.Bytes)
+
+	// The counted input is copied into the zfs stdin pipe by the reader itself
+	in := &writerToRecorder{Reader: bytes.NewReader([]byte("stream"))}
+	_, stats, err = ReceiveSnapshot(ctx, in, "pool/fs@snap", ReceiveOptions{SkipRefetch: true})
+	require.NoError(t, err)
+	require.IsType(t, &os.File{}, in.dst)
+	require.EqualValues(t, 6, stats.Bytes)
+	received, err := os.ReadFile(filepath.Join(dir, "received"))
+	require.NoError(t, err)
+	require.Equal(t, "stream", string(received))
+
+	// Progress has to be counted while copying, so the copy goes through the counter instead
+	out = readerFromRecorder{}
+	var progress uint64
+	stats, err = ResumeSend(ctx, &out, "token", ResumeSendOptions{
+		ProgressEvery: time.Millisecond,
+		ProgressFn: func(bytes uint64) {
+			progress = bytes
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, out.src)
+	require.Equal(t, "stream", out.String())
+	require.EqualValues(t, 6, stats.Bytes)
+	require.EqualValues(t, 6, progress)
+}
+
+func Test_CommandTimeout(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(ctx context.Context, _ string, _ []string, _ io.Reader, _ io.Writer) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+
+	start := time.Now()
+	_, err := ListDatasets(ctx, ListOptions{ParentDataset: "pool", CommandTimeout: 50 * time.Millisecond})
+	require.ErrorIs(t, err, ErrCommandCancelled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	_, err = snap.SendSnapshot(ctx, io.Discard, SendOptions{CommandTimeout: 50 * time.Millisecond})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, _, err = ReceiveSnapshot(ctx, bytes.NewReader(nil), "pool/fs", ReceiveOptions{CommandTimeout: 50 * time.Millisecond})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,68 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProgramOptions are options you can specify to customize the zfs program command
+type ProgramOptions struct {
+	// Args are passed to the script as the argv table
+	Args []string
+	// ReadOnly runs the script without making changes, so it cannot call the zfs.sync functions
+	ReadOnly bool
+	// InstructionLimit is the maximum amount of Lua instructions, zero keeps the default of 10 million
+	InstructionLimit uint64
+	// MemoryLimit is the maximum amount of memory in bytes, zero keeps the default of 10 MiB
+	MemoryLimit uint64
+}
+
+// RunChannelProgram runs the Lua script as a channel program on the pool, which executes atomically in the kernel.
+// It returns the output of the script, the value it returns is under the return key. Numbers are json.Number values,
+// so GUIDs and sizes keep their precision.
+func RunChannelProgram(ctx context.Context, pool, script string, options ProgramOptions) (map[string]any, error) {
+	args := make([]string, 0, 8+len(options.Args))
+	args = append(args, "program", "-j")
+	if options.ReadOnly {
+		args = append(args, "-n")
+	}
+	if options.InstructionLimit > 0 {
+		args = append(args, "-t", strconv.FormatUint(options.InstructionLimit, 10))
+	}
+	if options.MemoryLimit > 0 {
+		args = append(args, "-m", strconv.FormatUint(options.MemoryLimit, 10))
+	}
+	args = append(args, pool, "-")
+	args = append(args, options.Args...)
+
+	var out bytes.Buffer
+	c := command{
+		cmd:    Binary,
+		ctx:    ctx,
+		stdin:  strings.NewReader(script),
+		stdout: &out,
+	}
+	_, err := c.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseProgramOutput(out.Bytes())
+}
+
+func parseProgramOutput(output []byte) (map[string]any, error) {
+	result := make(map[string]any)
+	if len(bytes.TrimSpace(output)) == 0 {
+		return result, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(output))
+	dec.UseNumber()
+	err := dec.Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing channel program output: %w", err)
+	}
+	return result, nil
+}
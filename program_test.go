@@ -0,0 +1,46 @@
+package zfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunChannelProgram(t *testing.T) {
+	const script = `return {snapshot = argv[1]}`
+	var executed []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		input, err := io.ReadAll(stdin)
+		require.NoError(t, err)
+		require.Equal(t, script, string(input))
+		_, err = io.WriteString(stdout, `{"return": {"snapshot": "pool/fs@snap", "guid": 18446744073709551615}}`)
+		return "", err
+	}))
+
+	result, err := RunChannelProgram(ctx, "pool", script, ProgramOptions{
+		Args:             []string{"pool/fs@snap"},
+		ReadOnly:         true,
+		InstructionLimit: 1000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"program", "-j", "-n", "-t", "1000", "pool", "-", "pool/fs@snap"}, executed)
+	require.Equal(t, map[string]any{
+		"return": map[string]any{
+			"snapshot": "pool/fs@snap",
+			"guid":     json.Number("18446744073709551615"),
+		},
+	}, result)
+}
+
+func Test_parseProgramOutput(t *testing.T) {
+	result, err := parseProgramOutput(nil)
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	_, err = parseProgramOutput([]byte("Channel program fully executed with no return value."))
+	require.Error(t, err)
+}
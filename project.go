@@ -0,0 +1,148 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Project quota properties, which are suffixed with @ and the project id
+const (
+	PropertyProjectUsed        = "projectused"
+	PropertyProjectQuota       = "projectquota"
+	PropertyProjectObjectUsed  = "projectobjused"
+	PropertyProjectObjectQuota = "projectobjquota"
+)
+
+// SetProjectQuota sets the quota in bytes of the project in the filesystem, zero removes the quota
+func (d *Dataset) SetProjectQuota(ctx context.Context, project, quota uint64) error {
+	return d.setProjectQuota(ctx, PropertyProjectQuota, project, quota)
+}
+
+// SetProjectObjectQuota sets the quota of the amount of objects of the project in the filesystem, zero removes the quota
+func (d *Dataset) SetProjectObjectQuota(ctx context.Context, project, quota uint64) error {
+	return d.setProjectQuota(ctx, PropertyProjectObjectQuota, project, quota)
+}
+
+func (d *Dataset) setProjectQuota(ctx context.Context, prop string, project, quota uint64) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	value := ValueNone
+	if quota > 0 {
+		value = strconv.FormatUint(quota, 10)
+	}
+	return d.SetProperty(ctx, projectProperty(prop, project), value)
+}
+
+// ProjectQuota returns the space and objects used by the project in the filesystem or snapshot, and its quotas.
+// Unlike ProjectSpace this also returns projects that use no space yet.
+func (d *Dataset) ProjectQuota(ctx context.Context, project uint64) (*SpaceUsage, error) {
+	props := []string{PropertyProjectUsed, PropertyProjectQuota, PropertyProjectObjectUsed, PropertyProjectObjectQuota}
+	for i, prop := range props {
+		props[i] = projectProperty(prop, project)
+	}
+	out, err := zfsOutput(ctx, "get", "-Hp", "-o", "property,value", strings.Join(props, ","), d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &SpaceUsage{Type: SpaceProject, Name: strconv.FormatUint(project, 10)}
+	values := map[string]*uint64{
+		props[0]: &usage.Used,
+		props[1]: &usage.Quota,
+		props[2]: &usage.ObjectsUsed,
+		props[3]: &usage.ObjectQuota,
+	}
+	for _, fields := range out {
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("output contains line with %d fields: %v", len(fields), fields)
+		}
+		dst, ok := values[fields[0]]
+		if !ok || fields[1] == ValueNone {
+			continue
+		}
+		*dst, err = setUint(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s of %s: %w", fields[0], d.Name, err)
+		}
+	}
+	return usage, nil
+}
+
+func projectProperty(prop string, project uint64) string {
+	return fmt.Sprintf("%s@%d", prop, project)
+}
+
+// ProjectIDOptions are options you can specify to customize setting the project of a directory
+type ProjectIDOptions struct {
+	// Recursive also sets the project of all files and directories in the directory
+	Recursive bool
+	// Inherit sets the inherit flag, so new files and directories in the directory get the same project
+	Inherit bool
+}
+
+// SetProjectID sets the project of the file or directory in a mounted filesystem, so its space counts towards the
+// quota of the project. This requires the ProjectIDs platform flag.
+func SetProjectID(ctx context.Context, path string, project uint64, options ProjectIDOptions) error {
+	if p := CurrentPlatform(); !p.ProjectIDs {
+		return p.notSupported("zfs project")
+	}
+	args := make([]string, 1, 6)
+	args[0] = "project"
+	if options.Recursive {
+		args = append(args, "-r")
+	}
+	if options.Inherit {
+		args = append(args, "-s")
+	}
+	args = append(args, "-p", strconv.FormatUint(project, 10), path)
+	return zfs(ctx, args...)
+}
+
+// ProjectID is the project of a file or directory
+type ProjectID struct {
+	Project uint64 `json:"Project"`
+	// Inherit is whether new files and directories in the directory get the same project
+	Inherit bool   `json:"Inherit"`
+	Path    string `json:"Path"`
+}
+
+// GetProjectID returns the project of the file or directory in a mounted filesystem.
+// This requires the ProjectIDs platform flag.
+func GetProjectID(ctx context.Context, path string) (*ProjectID, error) {
+	if p := CurrentPlatform(); !p.ProjectIDs {
+		return nil, p.notSupported("zfs project")
+	}
+	var out bytes.Buffer
+	c := command{
+		cmd:    Binary,
+		ctx:    ctx,
+		stdout: &out,
+	}
+	_, err := c.Run("project", "-d", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseProjectID(out.String())
+}
+
+// parseProjectID parses a line of zfs project, the project, the inherit flag P or - and the path
+func parseProjectID(output string) (*ProjectID, error) {
+	line := strings.TrimSpace(output)
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid zfs project output %q", line)
+	}
+	project, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project in zfs project output %q: %w", line, err)
+	}
+	flag, path, ok := strings.Cut(strings.TrimLeft(fields[1], " "), " ")
+	if !ok || (flag != "P" && flag != "-") {
+		return nil, fmt.Errorf("invalid zfs project output %q", line)
+	}
+	return &ProjectID{Project: project, Inherit: flag == "P", Path: path}, nil
+}
@@ -0,0 +1,51 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ProjectQuota(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		var err error
+		switch args[0] {
+		case "get":
+			_, err = io.WriteString(stdout, "projectused@7\t1024\nprojectquota@7\t1048576\nprojectobjused@7\t3\nprojectobjquota@7\tnone\n")
+		case "project":
+			if args[1] == "-d" {
+				_, err = io.WriteString(stdout, "     7 P /pool/fs/dir with space\n")
+			}
+		}
+		return "", err
+	}))
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, fs.SetProjectQuota(ctx, 7, 1<<20))
+	require.NoError(t, fs.SetProjectObjectQuota(ctx, 7, 0))
+	usage, err := fs.ProjectQuota(ctx, 7)
+	require.NoError(t, err)
+	require.Equal(t, &SpaceUsage{Type: SpaceProject, Name: "7", Used: 1024, Quota: 1 << 20, ObjectsUsed: 3}, usage)
+
+	require.NoError(t, SetProjectID(ctx, "/pool/fs/dir with space", 7, ProjectIDOptions{Recursive: true, Inherit: true}))
+	id, err := GetProjectID(ctx, "/pool/fs/dir with space")
+	require.NoError(t, err)
+	require.Equal(t, &ProjectID{Project: 7, Inherit: true, Path: "/pool/fs/dir with space"}, id)
+
+	require.Equal(t, [][]string{
+		{"set", "projectquota@7=1048576", "pool/fs"},
+		{"set", "projectobjquota@7=none", "pool/fs"},
+		{"get", "-Hp", "-o", "property,value", "projectused@7,projectquota@7,projectobjused@7,projectobjquota@7", "pool/fs"},
+		{"project", "-r", "-s", "-p", "7", "/pool/fs/dir with space"},
+		{"project", "-d", "/pool/fs/dir with space"},
+	}, executed)
+
+	_, err = parseProjectID("0 - /pool/fs")
+	require.NoError(t, err)
+	_, err = parseProjectID("x P /pool/fs")
+	require.Error(t, err)
+}
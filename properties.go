@@ -1,5 +1,12 @@
 package zfs
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type PropertySource string
 
 type PropertySources []PropertySource
@@ -18,33 +25,84 @@ const (
 	PropertySourceTemporary PropertySource = "temporary"
 	PropertySourceReceived  PropertySource = "received"
 	PropertySourceDefault   PropertySource = "default"
+	PropertySourceNone      PropertySource = "none"
 )
 
+// Dataset properties, see zfsprops(7) for all of them
 const (
-	PropertyAvailable          = "available"
-	PropertyCanMount           = "canmount"
-	PropertyCompression        = "compression"
-	PropertyEncryption         = "encryption"
-	PropertyEncryptionRoot     = "encryptionroot"
-	PropertyFilesystemCount    = "filesystem_count"
-	PropertyKeyFormat          = "keyformat"
-	PropertyKeyStatus          = "keystatus"
-	PropertyKeyLocation        = "keylocation"
-	PropertyLogicalUsed        = "logicalused"
-	PropertyMounted            = "mounted"
-	PropertyMountPoint         = "mountpoint"
-	PropertyName               = "name"
-	PropertyOrigin             = "origin"
-	PropertyQuota              = "quota"
-	PropertyReferenced         = "referenced"
-	PropertyRefQuota           = "refquota"
-	PropertyReadOnly           = "readonly"
-	PropertyReceiveResumeToken = "receive_resume_token"
-	PropertyType               = "type"
-	PropertyUsed               = "used"
-	PropertyUsedByDataset      = "usedbydataset"
-	PropertyVolSize            = "volsize"
-	PropertyWritten            = "written"
+	PropertyACLInherit           = "aclinherit"
+	PropertyACLMode              = "aclmode"
+	PropertyACLType              = "acltype"
+	PropertyATime                = "atime"
+	PropertyAvailable            = "available"
+	PropertyCanMount             = "canmount"
+	PropertyCaseSensitivity      = "casesensitivity"
+	PropertyChecksum             = "checksum"
+	PropertyClones               = "clones"
+	PropertyCompression          = "compression"
+	PropertyCompressRatio        = "compressratio"
+	PropertyCopies               = "copies"
+	PropertyCreation             = "creation"
+	PropertyCreateTXG            = "createtxg"
+	PropertyDedup                = "dedup"
+	PropertyDeferDestroy         = "defer_destroy"
+	PropertyDevices              = "devices"
+	PropertyDnodeSize            = "dnodesize"
+	PropertyEncryption           = "encryption"
+	PropertyEncryptionRoot       = "encryptionroot"
+	PropertyExec                 = "exec"
+	PropertyFilesystemCount      = "filesystem_count"
+	PropertyFilesystemLimit      = "filesystem_limit"
+	PropertyGUID                 = "guid"
+	PropertyJailed               = "jailed"
+	PropertyKeyFormat            = "keyformat"
+	PropertyKeyStatus            = "keystatus"
+	PropertyKeyLocation          = "keylocation"
+	PropertyLogBias              = "logbias"
+	PropertyLogicalReferenced    = "logicalreferenced"
+	PropertyLogicalUsed          = "logicalused"
+	PropertyMounted              = "mounted"
+	PropertyMountPoint           = "mountpoint"
+	PropertyName                 = "name"
+	PropertyNormalization        = "normalization"
+	PropertyObjsetID             = "objsetid"
+	PropertyOrigin               = "origin"
+	PropertyPrimaryCache         = "primarycache"
+	PropertyQuota                = "quota"
+	PropertyReadOnly             = "readonly"
+	PropertyReceiveResumeToken   = "receive_resume_token"
+	PropertyRecordSize           = "recordsize"
+	PropertyRedundantMetadata    = "redundant_metadata"
+	PropertyRefCompressRatio     = "refcompressratio"
+	PropertyReferenced           = "referenced"
+	PropertyRefQuota             = "refquota"
+	PropertyRefReservation       = "refreservation"
+	PropertyRelATime             = "relatime"
+	PropertyReservation          = "reservation"
+	PropertySecondaryCache       = "secondarycache"
+	PropertySetUID               = "setuid"
+	PropertyShareNFS             = "sharenfs"
+	PropertyShareSMB             = "sharesmb"
+	PropertySnapDev              = "snapdev"
+	PropertySnapDir              = "snapdir"
+	PropertySnapshotCount        = "snapshot_count"
+	PropertySnapshotLimit        = "snapshot_limit"
+	PropertySpecialSmallBlocks   = "special_small_blocks"
+	PropertySync                 = "sync"
+	PropertyType                 = "type"
+	PropertyUsed                 = "used"
+	PropertyUsedByChildren       = "usedbychildren"
+	PropertyUsedByDataset        = "usedbydataset"
+	PropertyUsedByRefReservation = "usedbyrefreservation"
+	PropertyUsedBySnapshots      = "usedbysnapshots"
+	PropertyUserRefs             = "userrefs"
+	PropertyUTF8Only             = "utf8only"
+	PropertyVolBlockSize         = "volblocksize"
+	PropertyVolMode              = "volmode"
+	PropertyVolSize              = "volsize"
+	PropertyWritten              = "written"
+	PropertyXattr                = "xattr"
+	PropertyZoned                = "zoned"
 )
 
 const (
@@ -72,8 +130,69 @@ const (
 )
 
 const (
-	KeyLocationPrompt  = "prompt"
-	KeyStatusAvailable = "available"
+	KeyLocationPrompt    = "prompt"
+	KeyStatusAvailable   = "available"
+	KeyStatusUnavailable = "unavailable"
 )
 
 const CanMountNoAuto = "noauto"
+
+// Properties are property values of a dataset indexed by property name, as retrieved with the -p flag, with helpers
+// to parse typed values
+type Properties map[string]string
+
+// String returns the value of the property, with unset values and none as an empty string
+func (p Properties) String(key string) string {
+	value := p[key]
+	if value == ValueNone {
+		return ""
+	}
+	return setString(value)
+}
+
+// Uint parses a numeric property like used or quota, unset values and none are parsed as zero
+func (p Properties) Uint(key string) (uint64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("property %s not retrieved", key)
+	}
+	if value == ValueNone {
+		return 0, nil
+	}
+	n, err := setUint(value)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing property %s [%s]: %w", key, value, err)
+	}
+	return n, nil
+}
+
+// Float parses a ratio property like compressratio, without its x suffix
+func (p Properties) Float(key string) (float64, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, fmt.Errorf("property %s not retrieved", key)
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing property %s [%s]: %w", key, value, err)
+	}
+	return f, nil
+}
+
+// Bool returns whether an on/off or yes/no property like readonly or mounted is on
+func (p Properties) Bool(key string) bool {
+	return setBool(p[key])
+}
+
+// Time parses a time property like creation, which is in seconds, unset values are parsed as the zero time
+func (p Properties) Time(key string) (time.Time, error) {
+	value, ok := p[key]
+	if !ok {
+		return time.Time{}, fmt.Errorf("property %s not retrieved", key)
+	}
+	tm, err := setTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing property %s [%s]: %w", key, value, err)
+	}
+	return tm, nil
+}
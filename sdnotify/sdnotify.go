@@ -0,0 +1,119 @@
+// Package sdnotify implements the systemd service notification protocol, so services can use Type=notify and
+// WatchdogSec. When the service is not started by systemd, NOTIFY_SOCKET is not set and notifying does nothing.
+package sdnotify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States that can be sent to systemd, see sd_notify(3)
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Notifier sends notifications to the service manager
+type Notifier struct {
+	socket   string
+	watchdog time.Duration
+}
+
+// New creates a notifier from the NOTIFY_SOCKET and WATCHDOG_USEC environment variables systemd sets
+func New() *Notifier {
+	return newNotifier(os.Getenv, os.Getpid())
+}
+
+func newNotifier(getenv func(string) string, pid int) *Notifier {
+	n := &Notifier{socket: getenv("NOTIFY_SOCKET")}
+
+	usec, err := strconv.ParseInt(getenv("WATCHDOG_USEC"), 10, 64)
+	watchdogPID := getenv("WATCHDOG_PID")
+	if err == nil && usec > 0 && (watchdogPID == "" || watchdogPID == strconv.Itoa(pid)) {
+		n.watchdog = time.Duration(usec) * time.Microsecond
+	}
+	return n
+}
+
+// Enabled returns whether the service was started with a notification socket
+func (n *Notifier) Enabled() bool {
+	return n.socket != ""
+}
+
+// WatchdogInterval returns the watchdog timeout of the service, or zero when the watchdog is not enabled
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if !n.Enabled() {
+		return 0
+	}
+	return n.watchdog
+}
+
+// Notify sends the states, like StateReady, to the service manager. It does nothing when the notifier is not enabled.
+func (n *Notifier) Notify(states ...string) error {
+	if !n.Enabled() || len(states) == 0 {
+		return nil
+	}
+
+	socket := n.socket
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // Abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("error connecting to notify socket %s: %w", n.socket, err)
+	}
+	defer conn.Close()
+
+	var msg []byte
+	for _, state := range states {
+		msg = append(msg, state...)
+		msg = append(msg, '\n')
+	}
+	_, err = conn.Write(msg)
+	if err != nil {
+		return fmt.Errorf("error writing to notify socket %s: %w", n.socket, err)
+	}
+	return nil
+}
+
+// Status sends a free-form status text, which systemctl status shows
+func (n *Notifier) Status(status string) error {
+	return n.Notify("STATUS=" + status)
+}
+
+// RunWatchdog pings the watchdog at half its interval until the context is cancelled. When check is not nil,
+// the watchdog is only pinged while check returns no error, so systemd restarts the service when it hangs.
+// It returns immediately when the watchdog is not enabled.
+func (n *Notifier) RunWatchdog(ctx context.Context, check func() error, logger *slog.Logger) {
+	interval := n.WatchdogInterval() / 2
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("zfs.sdnotify.Notifier.RunWatchdog: Running", "interval", interval)
+	for {
+		select {
+		case <-ticker.C:
+			if check != nil {
+				if err := check(); err != nil {
+					logger.Error("zfs.sdnotify.Notifier.RunWatchdog: Check failed, not pinging watchdog", "error", err)
+					continue
+				}
+			}
+			err := n.Notify(StateWatchdog)
+			if err != nil {
+				logger.Warn("zfs.sdnotify.Notifier.RunWatchdog: Error pinging watchdog", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package sdnotify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn, socket
+}
+
+func read(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	conn, socket := listen(t)
+	n := newNotifier(env(map[string]string{"NOTIFY_SOCKET": socket}), 1)
+	require.True(t, n.Enabled())
+	require.Zero(t, n.WatchdogInterval())
+
+	require.NoError(t, n.Notify(StateReady, "STATUS=Serving"))
+	require.Equal(t, "READY=1\nSTATUS=Serving\n", read(t, conn))
+	require.NoError(t, n.Status("Stopping"))
+	require.Equal(t, "STATUS=Stopping\n", read(t, conn))
+}
+
+func TestNotifier_Disabled(t *testing.T) {
+	n := newNotifier(env(map[string]string{"WATCHDOG_USEC": "1000000"}), 1)
+	require.False(t, n.Enabled())
+	require.Zero(t, n.WatchdogInterval())
+	require.NoError(t, n.Notify(StateReady))
+}
+
+func TestNotifier_WatchdogInterval(t *testing.T) {
+	n := newNotifier(env(map[string]string{"NOTIFY_SOCKET": "/run/notify", "WATCHDOG_USEC": "30000000"}), 1)
+	require.Equal(t, 30*time.Second, n.WatchdogInterval())
+
+	n = newNotifier(env(map[string]string{"NOTIFY_SOCKET": "/run/notify", "WATCHDOG_USEC": "30000000", "WATCHDOG_PID": "2"}), 1)
+	require.Zero(t, n.WatchdogInterval(), "watchdog of another process")
+}
+
+func TestNotifier_RunWatchdog(t *testing.T) {
+	conn, socket := listen(t)
+	n := newNotifier(env(map[string]string{"NOTIFY_SOCKET": socket, "WATCHDOG_USEC": "20000"}), 1)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checks := 0
+	go n.RunWatchdog(ctx, func() error {
+		checks++
+		if checks == 1 {
+			return errors.New("stalled")
+		}
+		return nil
+	}, logger)
+
+	require.Equal(t, "WATCHDOG=1\n", read(t, conn))
+}
@@ -0,0 +1,73 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Share shares the filesystem over NFS and SMB, as configured by its sharenfs and sharesmb properties
+func (d *Dataset) Share(ctx context.Context) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	return zfs(ctx, "share", d.Name)
+}
+
+// Unshare stops sharing the filesystem over NFS and SMB
+func (d *Dataset) Unshare(ctx context.Context) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	return zfs(ctx, "unshare", d.Name)
+}
+
+// ShareAll shares all filesystems that have sharenfs or sharesmb set, like on boot
+func ShareAll(ctx context.Context) error {
+	return zfs(ctx, "share", "-a")
+}
+
+// SetShareNFS sets the sharenfs property of the filesystem, which shares it over NFS when it is mounted.
+// The value is on, off, or the export options, like rw=@10.0.0.0/8,no_root_squash on Linux.
+func (d *Dataset) SetShareNFS(ctx context.Context, value string) error {
+	return d.setShare(ctx, PropertyShareNFS, value)
+}
+
+// SetShareSMB sets the sharesmb property of the filesystem, which shares it over SMB when it is mounted.
+// The value is on, off, or the share options, like name=data.
+func (d *Dataset) SetShareSMB(ctx context.Context, value string) error {
+	return d.setShare(ctx, PropertyShareSMB, value)
+}
+
+func (d *Dataset) setShare(ctx context.Context, prop, value string) error {
+	if d.Type == DatasetSnapshot || d.Type == DatasetVolume {
+		return fmt.Errorf("setting %s on %s: only filesystems can be shared", prop, d.Name)
+	}
+	err := validateShareOptions(value)
+	if err != nil {
+		return fmt.Errorf("setting %s on %s: %w", prop, d.Name, err)
+	}
+	return d.SetProperty(ctx, prop, value)
+}
+
+// validateShareOptions checks the value of sharenfs or sharesmb is on, off, or a list of comma separated options,
+// which must not contain whitespace as that would break the generated exports
+func validateShareOptions(value string) error {
+	if value == ValueOn || value == ValueOff {
+		return nil
+	}
+	if value == "" {
+		return fmt.Errorf("empty share options")
+	}
+	if strings.IndexFunc(value, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("share options %q contain whitespace", value)
+	}
+	for _, option := range strings.Split(value, ",") {
+		key, _, _ := strings.Cut(option, "=")
+		if key == "" {
+			return fmt.Errorf("share options %q contain an empty option", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Share(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, fs.SetShareNFS(ctx, "rw=@10.0.0.0/8,no_root_squash"))
+	require.NoError(t, fs.SetShareSMB(ctx, ValueOn))
+	require.NoError(t, fs.Share(ctx))
+	require.NoError(t, fs.Unshare(ctx))
+	require.NoError(t, ShareAll(ctx))
+	require.Equal(t, [][]string{
+		{"set", "sharenfs=rw=@10.0.0.0/8,no_root_squash", "pool/fs"},
+		{"set", "sharesmb=on", "pool/fs"},
+		{"share", "pool/fs"},
+		{"unshare", "pool/fs"},
+		{"share", "-a"},
+	}, executed)
+
+	executed = nil
+	require.Error(t, fs.SetShareNFS(ctx, ""))
+	require.Error(t, fs.SetShareNFS(ctx, "rw, ro"))
+	require.Error(t, fs.SetShareNFS(ctx, "rw,,ro"))
+	require.Error(t, fs.SetShareNFS(ctx, "rw\nro"))
+	vol := &Dataset{Name: "pool/vol", Type: DatasetVolume}
+	require.Error(t, vol.SetShareNFS(ctx, ValueOn))
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	require.ErrorIs(t, snap.Share(ctx), ErrSnapshotsNotSupported)
+	require.Empty(t, executed)
+}
@@ -0,0 +1,80 @@
+package zfs
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+// SnapshotSpace describes the space used by a snapshot, to decide which snapshots are worth pruning
+type SnapshotSpace struct {
+	// Snapshot is the full name of the snapshot
+	Snapshot string `json:"Snapshot"`
+	// Used is the space that is freed by destroying only this snapshot
+	Used uint64 `json:"Used"`
+	// Referenced is the space of all data the snapshot refers to
+	Referenced uint64 `json:"Referenced"`
+	// Written is the space written between the previous snapshot and this one
+	Written uint64 `json:"Written"`
+	// ReclaimWithOlder is the space that is freed by destroying this snapshot together with all older snapshots,
+	// which includes the space shared by several of them that the used values do not account for
+	ReclaimWithOlder uint64 `json:"ReclaimWithOlder"`
+}
+
+// SnapshotSpaceMap reports the space of every snapshot of the dataset, oldest first. The space freed by destroying
+// the snapshots up to every snapshot is estimated with a dry run of destroying the range of snapshots.
+func (d *Dataset) SnapshotSpaceMap(ctx context.Context) ([]SnapshotSpace, error) {
+	snaps, err := ListSnapshots(ctx, ListOptions{
+		ParentDataset: d.Name,
+		Depth:         1,
+		Fields: []string{
+			PropertyName, PropertyType, PropertyUsed, PropertyReferenced, PropertyWritten, PropertyCreateTXG,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(snaps, func(a, b Dataset) int {
+		return cmp.Compare(a.CreateTXG, b.CreateTXG)
+	})
+
+	spaces := make([]SnapshotSpace, 0, len(snaps))
+	var first string
+	for _, snap := range snaps {
+		_, name, _ := strings.Cut(snap.Name, "@")
+		if first == "" {
+			first = name
+		}
+		reclaim, err := d.ReclaimableSpace(ctx, first, name)
+		if err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, SnapshotSpace{
+			Snapshot:         snap.Name,
+			Used:             snap.Used,
+			Referenced:       snap.Referenced,
+			Written:          snap.Written,
+			ReclaimWithOlder: reclaim,
+		})
+	}
+	return spaces, nil
+}
+
+// ReclaimableSpace estimates the space that is freed by destroying the range of snapshots of the dataset from the
+// first up to and including the last snapshot, without destroying them. The snapshots are given without the dataset.
+func (d *Dataset) ReclaimableSpace(ctx context.Context, firstSnapshot, lastSnapshot string) (uint64, error) {
+	snapRange, err := snapshotRange(d.Name, firstSnapshot, lastSnapshot)
+	if err != nil {
+		return 0, err
+	}
+	out, err := zfsOutput(ctx, "destroy", "-n", "-p", "-v", snapRange)
+	if err != nil {
+		return 0, err
+	}
+	estimate, err := parseDestroyEstimate(out, snapRange)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.Reclaim, nil
+}
@@ -0,0 +1,29 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReclaimableSpace(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		_, err := io.WriteString(stdout, "destroy\tpool/fs@snap1\ndestroy\tpool/fs@snap2\nreclaim\t1048576\n")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	reclaim, err := ds.ReclaimableSpace(context.Background(), "snap1", "snap2")
+	require.NoError(t, err)
+	require.EqualValues(t, 1048576, reclaim)
+	require.Equal(t, []string{"destroy", "-n", "-p", "-v", "pool/fs@snap1%snap2"}, executed[0])
+
+	_, err = ds.ReclaimableSpace(context.Background(), "snap1", "snap1")
+	require.NoError(t, err)
+	require.Equal(t, "pool/fs@snap1", executed[1][4])
+}
@@ -0,0 +1,70 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TemporaryMountOptions are options you can specify to customize a temporary mount
+type TemporaryMountOptions struct {
+	// Mountpoint is the directory to mount the filesystem at, which must exist.
+	// When empty, a temporary directory is created and removed afterwards.
+	Mountpoint string
+
+	// ReadOnly mounts the filesystem read-only, so the operation cannot change it
+	ReadOnly bool
+
+	// Load the key of an encrypted filesystem as it is being mounted, see MountOptions
+	LoadKeys bool
+}
+
+// WithTemporaryMount mounts the filesystem, which must not be mounted, at a temporary mountpoint for the duration
+// of fn, for instance to verify a received backup. The mountpoint property of the filesystem is not changed.
+// The filesystem is always unmounted again afterwards, errors of fn and the unmount are both returned.
+func (d *Dataset) WithTemporaryMount(ctx context.Context, options TemporaryMountOptions,
+	fn func(mountpoint string) error) (err error) {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	if p := CurrentPlatform(); !p.TemporaryMountpoint {
+		return p.notSupported("mount -o mountpoint")
+	}
+
+	mountpoint := options.Mountpoint
+	if mountpoint == "" {
+		mountpoint, err = os.MkdirTemp("", "zfs-mount-")
+		if err != nil {
+			return fmt.Errorf("error creating temporary mountpoint: %w", err)
+		}
+		defer func() {
+			// Remove only removes the directory when it is empty, so never the contents of a mounted filesystem
+			removeErr := os.Remove(mountpoint)
+			if removeErr != nil {
+				err = errors.Join(err, fmt.Errorf("error removing temporary mountpoint: %w", removeErr))
+			}
+		}()
+	}
+
+	mountOptions := []string{"mountpoint=" + mountpoint}
+	if options.ReadOnly {
+		mountOptions = append(mountOptions, "ro")
+	}
+	err = d.Mount(ctx, MountOptions{
+		Options:  mountOptions,
+		LoadKeys: options.LoadKeys,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Unmount with a fresh context, so the filesystem is unmounted even when the context was cancelled
+		unmountErr := d.Unmount(context.WithoutCancel(ctx), UnmountOptions{})
+		if unmountErr != nil {
+			err = errors.Join(err, fmt.Errorf("error unmounting %s: %w", d.Name, unmountErr))
+		}
+	}()
+
+	return fn(mountpoint)
+}
@@ -0,0 +1,76 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithTemporaryMount(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	ds := &Dataset{Name: "pool/backup", Type: DatasetFilesystem}
+
+	var tempMountpoint string
+	errVerify := errors.New("verification failed")
+	err := ds.WithTemporaryMount(ctx, TemporaryMountOptions{ReadOnly: true}, func(mountpoint string) error {
+		tempMountpoint = mountpoint
+		require.DirExists(t, mountpoint)
+		return errVerify
+	})
+	require.ErrorIs(t, err, errVerify)
+	require.NoDirExists(t, tempMountpoint)
+	require.Equal(t, [][]string{
+		{"mount", "-o", "mountpoint=" + tempMountpoint + ",ro", "pool/backup"},
+		{"umount", "pool/backup"},
+	}, executed)
+
+	executed = nil
+	mountpoint := t.TempDir()
+	err = ds.WithTemporaryMount(ctx, TemporaryMountOptions{Mountpoint: mountpoint, LoadKeys: true}, func(string) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.DirExists(t, mountpoint)
+	require.Equal(t, [][]string{
+		{"mount", "-l", "-o", "mountpoint=" + mountpoint, "pool/backup"},
+		{"umount", "pool/backup"},
+	}, executed)
+
+	snap := &Dataset{Name: "pool/backup@snap", Type: DatasetSnapshot}
+	require.ErrorIs(t, snap.WithTemporaryMount(ctx, TemporaryMountOptions{}, func(string) error {
+		return nil
+	}), ErrSnapshotsNotSupported)
+}
+
+func Test_WithTemporaryMountFailure(t *testing.T) {
+	SetExecutor(executorFunc(func(_ context.Context, _ string, _ []string, _ io.Reader, _ io.Writer) (string, error) {
+		return "cannot mount 'pool/backup': filesystem already mounted", errors.New("exit status 1")
+	}))
+	defer SetExecutor(nil)
+
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	ds := &Dataset{Name: "pool/backup", Type: DatasetFilesystem}
+	err := ds.WithTemporaryMount(context.Background(), TemporaryMountOptions{}, func(string) error {
+		t.Fatal("called without being mounted")
+		return nil
+	})
+	require.ErrorIs(t, err, ErrFilesystemAlreadyMounted)
+
+	// The temporary mountpoint was removed again
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,118 @@
+package zfs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Tracer starts spans around the zfs and zpool commands, and around the requests and job runs of the http and job
+// packages, for instance to trace them with OpenTelemetry. A tracer that also implements the TracePropagator of the
+// http package has its traces continued on the servers it sends snapshots to.
+type Tracer interface {
+	// Start starts a span as child of the span in the context, if any, and returns the context holding the new span
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	// SetAttributes adds attributes to the span
+	SetAttributes(attributes ...Attribute)
+	// End ends the span, with the error of the traced operation if it failed
+	End(err error)
+}
+
+// Attribute is a key and value describing a span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var commandTracer atomic.Pointer[tracerHolder]
+
+// SetTracer makes all commands, HTTP requests and job runs start spans using the tracer, nil disables tracing
+func SetTracer(tracer Tracer) {
+	if tracer == nil {
+		commandTracer.Store(nil)
+		return
+	}
+	commandTracer.Store(&tracerHolder{tracer: tracer})
+}
+
+// CurrentTracer returns the tracer set with SetTracer, or nil
+func CurrentTracer() Tracer {
+	holder := commandTracer.Load()
+	if holder == nil {
+		return nil
+	}
+	return holder.tracer
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End(error)                  {}
+
+// StartSpan starts a span using the tracer set with SetTracer, when no tracer is set the span does nothing
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	tracer := CurrentTracer()
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name, attributes...)
+}
+
+// CommandHook is called after every zfs and zpool command, with the command and its arguments, how long it ran and its
+// error, if any. It is called with the context of the command, holding its span when a tracer is set.
+type CommandHook func(ctx context.Context, argv []string, duration time.Duration, err error)
+
+type hookHolder struct {
+	hook CommandHook
+}
+
+var commandHook atomic.Pointer[hookHolder]
+
+// SetCommandHook makes all commands call the hook when they are done, for instance to log or time them, nil removes
+// the hook. The hook is called synchronously, so it should be fast.
+func SetCommandHook(hook CommandHook) {
+	if hook == nil {
+		commandHook.Store(nil)
+		return
+	}
+	commandHook.Store(&hookHolder{hook: hook})
+}
+
+// startCommandSpan starts a span for a command, the returned function ends it with the error of the command and calls
+// the command hook
+func startCommandSpan(ctx context.Context, cmd string, arg []string) (end func(err error)) {
+	tracer := CurrentTracer()
+	hook := commandHook.Load()
+	if tracer == nil && hook == nil {
+		return func(error) {}
+	}
+
+	var span Span = noopSpan{}
+	if tracer != nil {
+		name := cmd
+		if len(arg) > 0 {
+			name += " " + arg[0]
+		}
+		ctx, span = tracer.Start(ctx, name,
+			Attribute{Key: "zfs.command", Value: cmd},
+			Attribute{Key: "zfs.args", Value: arg},
+		)
+	}
+	start := time.Now()
+	return func(err error) {
+		duration := time.Since(start)
+		span.SetAttributes(Attribute{Key: "zfs.duration_seconds", Value: duration.Seconds()})
+		span.End(err)
+		if hook != nil {
+			hook.hook(ctx, append([]string{cmd}, arg...), duration, err)
+		}
+	}
+}
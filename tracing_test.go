@@ -0,0 +1,108 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSpan struct {
+	name       string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttributes(attributes ...Attribute) {
+	for _, attr := range attributes {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.ended = true
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	span := &testSpan{name: name, attributes: make(map[string]any)}
+	span.SetAttributes(attributes...)
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return ctx, span
+}
+
+func Test_SetTracer(t *testing.T) {
+	tracer := &testTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+	require.Equal(t, tracer, CurrentTracer())
+
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		if args[0] == "destroy" {
+			return "cannot open 'pool/fs': dataset does not exist", errors.New("exit status 1")
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, ds.SetProperty(context.Background(), "nl.test:prop", "value"))
+	require.ErrorIs(t, ds.Destroy(context.Background(), DestroyOptions{}), ErrDatasetNotFound)
+
+	require.Len(t, tracer.spans, 2)
+	span := tracer.spans[0]
+	require.Equal(t, "zfs set", span.name)
+	require.Equal(t, Binary, span.attributes["zfs.command"])
+	require.Equal(t, []string{"set", "nl.test:prop=value", "pool/fs"}, span.attributes["zfs.args"])
+	require.Contains(t, span.attributes, "zfs.duration_seconds")
+	require.True(t, span.ended)
+	require.NoError(t, span.err)
+	require.ErrorIs(t, tracer.spans[1].err, ErrDatasetNotFound)
+
+	SetTracer(nil)
+	require.Nil(t, CurrentTracer())
+	_, noop := StartSpan(context.Background(), "test")
+	noop.End(nil)
+}
+
+func Test_SetCommandHook(t *testing.T) {
+	type call struct {
+		argv []string
+		err  error
+	}
+	var calls []call
+	SetCommandHook(func(_ context.Context, argv []string, duration time.Duration, err error) {
+		require.GreaterOrEqual(t, duration, time.Duration(0))
+		calls = append(calls, call{argv: argv, err: err})
+	})
+	defer SetCommandHook(nil)
+
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		if args[0] == "destroy" {
+			return "cannot open 'pool/fs': dataset does not exist", errors.New("exit status 1")
+		}
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, ds.SetProperty(ctx, "nl.test:prop", "value"))
+	require.ErrorIs(t, ds.Destroy(ctx, DestroyOptions{}), ErrDatasetNotFound)
+
+	require.Len(t, calls, 2)
+	require.Equal(t, []string{"zfs", "set", "nl.test:prop=value", "pool/fs"}, calls[0].argv)
+	require.NoError(t, calls[0].err)
+	require.Equal(t, []string{"zfs", "destroy", "pool/fs"}, calls[1].argv)
+	require.ErrorIs(t, calls[1].err, ErrDatasetNotFound)
+}
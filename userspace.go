@@ -0,0 +1,84 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+)
+
+// Types of space usage of zfs userspace, groupspace and projectspace
+const (
+	SpacePOSIXUser  = "POSIX User"
+	SpacePOSIXGroup = "POSIX Group"
+	SpaceSMBUser    = "SMB User"
+	SpaceSMBGroup   = "SMB Group"
+	SpaceProject    = "Project"
+)
+
+// SpaceUsage is the space and objects used by a user, group or project in a dataset, and its quotas
+type SpaceUsage struct {
+	// Type is POSIX User or Group, SMB User or Group, or Project
+	Type string `json:"Type"`
+	// Name is the user, group or project, or its numeric id when it has no name
+	Name string `json:"Name"`
+	Used uint64 `json:"Used"`
+	// Quota is zero when there is no quota
+	Quota uint64 `json:"Quota"`
+	// ObjectsUsed and ObjectQuota are zero when the pool does not account objects
+	ObjectsUsed uint64 `json:"ObjectsUsed"`
+	ObjectQuota uint64 `json:"ObjectQuota"`
+}
+
+// UserSpace returns the space used by every user in the filesystem or snapshot, with their quotas
+func (d *Dataset) UserSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return d.spaceUsage(ctx, "userspace")
+}
+
+// GroupSpace returns the space used by every group in the filesystem or snapshot, with their quotas
+func (d *Dataset) GroupSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return d.spaceUsage(ctx, "groupspace")
+}
+
+// ProjectSpace returns the space used by every project in the filesystem or snapshot, with their quotas
+func (d *Dataset) ProjectSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return d.spaceUsage(ctx, "projectspace")
+}
+
+func (d *Dataset) spaceUsage(ctx context.Context, cmd string) ([]SpaceUsage, error) {
+	// The type column is not supported by projectspace
+	fields := "type,name,used,quota,objused,objquota"
+	if cmd == "projectspace" {
+		fields = "name,used,quota,objused,objquota"
+	}
+	out, err := zfsOutput(ctx, cmd, "-Hp", "-o", fields, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpaceUsage(out, cmd == "projectspace")
+}
+
+func parseSpaceUsage(out [][]string, project bool) ([]SpaceUsage, error) {
+	usage := make([]SpaceUsage, 0, len(out))
+	for _, fields := range out {
+		if project {
+			fields = append([]string{SpaceProject}, fields...)
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("output contains line with %d fields: %v", len(fields), fields)
+		}
+
+		u := SpaceUsage{Type: fields[0], Name: fields[1]}
+		for i, dst := range []*uint64{&u.Used, &u.Quota, &u.ObjectsUsed, &u.ObjectQuota} {
+			val := fields[i+2]
+			if val == ValueNone {
+				continue
+			}
+			var err error
+			*dst, err = setUint(val)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing space usage of %s %s: %w", u.Type, u.Name, err)
+			}
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
@@ -0,0 +1,41 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UserSpace(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		output := "POSIX User\talice\t1048576\t10737418240\t12\tnone\nPOSIX User\t1001\t4096\tnone\t-\t-\n"
+		if args[0] == "projectspace" {
+			output = "42\t8192\t1073741824\t3\t100\n"
+		}
+		_, err := io.WriteString(stdout, output)
+		return "", err
+	}))
+
+	ds := &Dataset{Name: "tank/home", Type: DatasetFilesystem}
+	usage, err := ds.UserSpace(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []SpaceUsage{
+		{Type: SpacePOSIXUser, Name: "alice", Used: 1048576, Quota: 10737418240, ObjectsUsed: 12},
+		{Type: SpacePOSIXUser, Name: "1001", Used: 4096},
+	}, usage)
+
+	usage, err = ds.ProjectSpace(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []SpaceUsage{
+		{Type: SpaceProject, Name: "42", Used: 8192, Quota: 1073741824, ObjectsUsed: 3, ObjectQuota: 100},
+	}, usage)
+
+	require.Equal(t, [][]string{
+		{"userspace", "-Hp", "-o", "type,name,used,quota,objused,objquota", "tank/home"},
+		{"projectspace", "-Hp", "-o", "name,used,quota,objused,objquota", "tank/home"},
+	}, executed)
+}
@@ -1,11 +1,14 @@
 package zfs
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"slices"
 	"strings"
 )
 
@@ -26,10 +29,23 @@ var dsPropList = []string{
 	PropertyWritten,
 	PropertyLogicalUsed,
 	PropertyUsedByDataset,
+	PropertyGUID,
+	PropertyCreation,
+	PropertyCreateTXG,
+}
+
+// EncryptionProperties are the encryption properties with a field in the Dataset struct. They are not retrieved by
+// default, request them as fields or extra properties.
+var EncryptionProperties = []string{
+	PropertyEncryption,
+	PropertyKeyStatus,
+	PropertyKeyFormat,
+	PropertyEncryptionRoot,
 }
 
 const (
 	fieldSeparator = "\t"
+	maxLineLength  = 4 * 1024 * 1024
 )
 
 // zfs is a helper function to wrap typical calls to zfs that ignores stdout.
@@ -52,44 +68,180 @@ type command struct {
 	cmd    string
 	stdin  io.Reader
 	stdout io.Writer
+	// fields limits the amount of fields output lines are split in, the last field holds the rest of the line.
+	// This keeps property values containing tabs intact. Zero splits on every separator.
+	fields int
 }
 
+// lineFunc is called for every line of command output, with the line split into its fields.
+// The fields slice is reused for the next line, so it must not be retained, the strings in it can be.
+type lineFunc func(fields []string) error
+
+// Run runs the command and returns all of its output split into lines and fields
 func (c *command) Run(arg ...string) ([][]string, error) {
-	cmd := exec.CommandContext(c.ctx, c.cmd, arg...)
+	// assume if you passed in something for stdout, that you know what to do with it
+	if c.stdout != nil {
+		return nil, c.run(nil, arg...)
+	}
+
+	output := make([][]string, 0, 16)
+	err := c.run(func(fields []string) error {
+		output = append(output, slices.Clone(fields))
+		return nil
+	}, arg...)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// Stream runs the command and calls fn for every line of output as soon as it is read,
+// so the complete output never has to be held in memory.
+// When fn returns an error, the command is stopped and the error is returned.
+func (c *command) Stream(fn lineFunc, arg ...string) error {
+	return c.run(fn, arg...)
+}
+
+func (c *command) run(fn lineFunc, arg ...string) (err error) {
+	if skip, err := c.skipDryRun(arg); skip {
+		return err
+	}
+
+	endSpan := startCommandSpan(c.ctx, c.cmd, arg)
+	defer func() {
+		endSpan(err)
+	}()
+
+	if executor := loadExecutor(c.ctx); executor != nil {
+		return c.execute(executor, fn, arg...)
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+
+	config := loadCommandConfig(c.ctx)
+	cmd := exec.CommandContext(ctx, config.path(c.cmd), arg...)
+	config.apply(cmd)
 	cmd.SysProcAttr = procAttributes()
+	waited := setTermination(cmd)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = c.stdout
+	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
-	if c.stdout == nil {
-		cmd.Stdout = &stdout
+
+	// Wait for a slot before creating the pipes, so waiting commands do not hold file descriptors
+	release, err := acquireCommandSlot(ctx, arg)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer invalidateCache(arg)
+
+	// When the output can read from a file directly, we hand it the pipe ourselves so it can use its
+	// zero-copy fast path (sendfile/splice) instead of exec copying through an intermediate buffer.
+	var stdoutPipe *os.File
+	readerFrom, directOutput := c.stdout.(io.ReaderFrom)
+	if _, isFile := c.stdout.(*os.File); isFile {
+		directOutput = false
+	}
+	switch {
+	case directOutput:
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("error creating stdout pipe: %w", err)
+		}
+		defer pr.Close()
+		defer pw.Close()
+		stdoutPipe = pr
+		cmd.Stdout = pw
+	case c.stdout != nil:
+		cmd.Stdout = c.stdout
 	}
 	if c.stdin != nil {
 		cmd.Stdin = c.stdin
 	}
 
-	err := cmd.Run()
+	var stdout io.Reader
+	if fn != nil {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("error creating stdout pipe: %w", err)
+		}
+		stdout = pipe
+	}
+
+	err = cmd.Start()
 	if err != nil {
-		return nil, createError(cmd, stderr.String(), err)
+		return createError(cmd, stderr.String(), err)
 	}
 
-	// assume if you passed in something for stdout, that you know what to do with it
-	if c.stdout != nil {
-		return nil, nil
+	var outputErr error
+	switch {
+	case fn != nil:
+		outputErr = scanLines(stdout, c.fields, fn)
+	case stdoutPipe != nil:
+		// Close our copy of the write end, so we get EOF when the command exits
+		_ = cmd.Stdout.(*os.File).Close()
+		_, outputErr = readerFrom.ReadFrom(stdoutPipe)
+	}
+	if outputErr != nil {
+		// We are no longer interested in the output, so stop the command
+		cancel()
 	}
 
-	return splitOutput(stdout.String()), nil
+	err = cmd.Wait()
+	waited()
+	if outputErr != nil && c.ctx.Err() != nil {
+		// The pipes were closed because the context is done
+		return fmt.Errorf("%w (%w): %w", ErrCommandCancelled, c.ctx.Err(), outputErr)
+	}
+	if outputErr != nil {
+		return outputErr
+	}
+	if err != nil && c.ctx.Err() != nil {
+		return fmt.Errorf("%w (%w): %w", ErrCommandCancelled, c.ctx.Err(), createError(cmd, stderr.String(), err))
+	}
+	if err != nil {
+		return createError(cmd, stderr.String(), err)
+	}
+	return nil
 }
 
-func splitOutput(out string) [][]string {
-	lines := strings.Split(out, "\n")
+// scanLines reads the output line by line and calls fn with the (at most maxFields, when positive) fields of every line.
+// Only a single string is allocated per line, the fields are substrings of it.
+func scanLines(rdr io.Reader, maxFields int, fn lineFunc) error {
+	scanner := bufio.NewScanner(rdr)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	fields := make([]string, 0, 8)
+	for scanner.Scan() {
+		fields = splitFields(fields[:0], string(scanner.Bytes()), maxFields)
+		err := fn(fields)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
 
-	// last line is always blank
-	lines = lines[0 : len(lines)-1]
-	output := make([][]string, len(lines))
-	for i, l := range lines {
-		output[i] = strings.Split(l, fieldSeparator)
+// splitFields appends the fields of the line to dst, to prevent allocating a new slice for every line.
+// When maxFields is positive, the line is split in at most that many fields.
+func splitFields(dst []string, line string, maxFields int) []string {
+	for n := 1; maxFields <= 0 || n < maxFields; n++ {
+		idx := strings.Index(line, fieldSeparator)
+		if idx < 0 {
+			break
+		}
+		dst = append(dst, line[:idx])
+		line = line[idx+len(fieldSeparator):]
 	}
+	return append(dst, line)
+}
+
+func splitOutput(out string, maxFields int) [][]string {
+	output := make([][]string, 0, strings.Count(out, "\n"))
+	_ = scanLines(strings.NewReader(out), maxFields, func(fields []string) error {
+		output = append(output, slices.Clone(fields))
+		return nil
+	})
 	return output
 }
 
@@ -0,0 +1,150 @@
+package zfs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Feature is a capability of ZFS that depends on its version
+type Feature string
+
+// Features of OpenZFS that are not available in every version
+const (
+	FeatureResumableReceive Feature = "resumable-receive"
+	FeatureRawSend          Feature = "raw-send"
+	FeatureRedaction        Feature = "redaction"
+	FeatureZstd             Feature = "zstd"
+	FeatureJSONOutput       Feature = "json-output"
+	FeaturePoolWait         Feature = "pool-wait"
+)
+
+// featureVersions are the OpenZFS versions that introduced the features
+var featureVersions = map[Feature]ZFSVersion{
+	FeatureResumableReceive: {Major: 0, Minor: 7},
+	FeatureRawSend:          {Major: 0, Minor: 8},
+	FeatureRedaction:        {Major: 2, Minor: 0},
+	FeatureZstd:             {Major: 2, Minor: 0},
+	FeaturePoolWait:         {Major: 2, Minor: 0},
+	FeatureJSONOutput:       {Major: 2, Minor: 3},
+}
+
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ZFSVersion is a version of the zfs userland tools or kernel module
+type ZFSVersion struct {
+	Major int `json:"Major"`
+	Minor int `json:"Minor"`
+	Patch int `json:"Patch"`
+	// Raw is the version as printed by zfs version, like zfs-2.2.2-0ubuntu9
+	Raw string `json:"Raw"`
+}
+
+// ParseZFSVersion parses a version like 2.2.2, or as printed by zfs version, like zfs-kmod-2.2.2-0ubuntu9
+func ParseZFSVersion(version string) (ZFSVersion, error) {
+	match := versionRegex.FindStringSubmatch(version)
+	if match == nil {
+		return ZFSVersion{}, fmt.Errorf("invalid zfs version %q", version)
+	}
+	v := ZFSVersion{Raw: version}
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 when the version is older, equal or newer than the other version
+func (v ZFSVersion) Compare(other ZFSVersion) int {
+	return cmp.Or(cmp.Compare(v.Major, other.Major), cmp.Compare(v.Minor, other.Minor), cmp.Compare(v.Patch, other.Patch))
+}
+
+// IsZero returns whether the version is unknown
+func (v ZFSVersion) IsZero() bool {
+	return v.Major == 0 && v.Minor == 0 && v.Patch == 0
+}
+
+func (v ZFSVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Versions are the versions of the zfs userland tools and the kernel module
+type Versions struct {
+	Userland ZFSVersion `json:"Userland"`
+	// Kernel is zero when the kernel module is not loaded, or its version could not be parsed
+	Kernel ZFSVersion `json:"Kernel"`
+}
+
+// SupportsFeature returns whether both the userland tools and the kernel module support the feature
+func (v *Versions) SupportsFeature(feature Feature) bool {
+	minimum, ok := featureVersions[feature]
+	if !ok {
+		return false
+	}
+	if v.Userland.Compare(minimum) < 0 {
+		return false
+	}
+	return v.Kernel.IsZero() || v.Kernel.Compare(minimum) >= 0
+}
+
+// Version returns the versions of the zfs userland tools and kernel module with zfs version, which is available in
+// OpenZFS 0.8 and newer
+func Version(ctx context.Context) (*Versions, error) {
+	out, err := zfsOutput(ctx, "version")
+	if err != nil {
+		return nil, err
+	}
+	return parseVersions(out)
+}
+
+func parseVersions(out [][]string) (*Versions, error) {
+	versions := &Versions{}
+	for _, line := range out {
+		if len(line) == 0 {
+			continue
+		}
+		raw := strings.TrimSpace(strings.Join(line, " "))
+		if strings.HasPrefix(raw, "zfs-kmod-") {
+			// The kernel module of FreeBSD can have a version like zfs-kmod-v2023072100-zfs_..., leave it unknown
+			versions.Kernel, _ = ParseZFSVersion(raw)
+			continue
+		}
+		if versions.Userland.Raw != "" {
+			continue
+		}
+		var err error
+		versions.Userland, err = ParseZFSVersion(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if versions.Userland.Raw == "" {
+		return nil, fmt.Errorf("no zfs version in output")
+	}
+	return versions, nil
+}
+
+// WithVersions returns the platform with the flags that depend on the version set to whether the versions support them
+func (p Platform) WithVersions(versions *Versions) Platform {
+	p.ResumableReceive = p.ResumableReceive && versions.SupportsFeature(FeatureResumableReceive)
+	p.RawSend = p.RawSend && versions.SupportsFeature(FeatureRawSend)
+	p.PoolWait = p.PoolWait && versions.SupportsFeature(FeaturePoolWait)
+	p.JSONOutput = versions.SupportsFeature(FeatureJSONOutput)
+	return p
+}
+
+// DetectPlatform detects the versions of zfs and sets the current platform to the current platform with the flags
+// that depend on the version adapted to them, see Platform.WithVersions
+func DetectPlatform(ctx context.Context) (*Versions, error) {
+	versions, err := Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := CurrentPlatform().WithVersions(versions)
+	SetPlatform(&p)
+	return versions, nil
+}
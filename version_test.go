@@ -0,0 +1,69 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseVersions(t *testing.T) {
+	versions, err := parseVersions([][]string{{"zfs-2.2.2-0ubuntu9"}, {"zfs-kmod-2.1.5-1ubuntu6"}})
+	require.NoError(t, err)
+	require.Equal(t, ZFSVersion{Major: 2, Minor: 2, Patch: 2, Raw: "zfs-2.2.2-0ubuntu9"}, versions.Userland)
+	require.Equal(t, ZFSVersion{Major: 2, Minor: 1, Patch: 5, Raw: "zfs-kmod-2.1.5-1ubuntu6"}, versions.Kernel)
+
+	versions, err = parseVersions([][]string{{"zfs-2.1.4-FreeBSD_g52bad4f23"}, {"zfs-kmod-v2023072100-zfs_9ef0b67f8"}})
+	require.NoError(t, err)
+	require.Equal(t, "2.1.4", versions.Userland.String())
+	require.True(t, versions.Kernel.IsZero())
+
+	_, err = parseVersions([][]string{{"unrecognized command 'version'"}})
+	require.Error(t, err)
+	_, err = parseVersions(nil)
+	require.Error(t, err)
+}
+
+func Test_SupportsFeature(t *testing.T) {
+	versions := &Versions{
+		Userland: ZFSVersion{Major: 2, Minor: 3, Patch: 1},
+		Kernel:   ZFSVersion{Major: 2, Minor: 2, Patch: 6},
+	}
+	require.True(t, versions.SupportsFeature(FeatureRawSend))
+	require.True(t, versions.SupportsFeature(FeatureZstd))
+	require.False(t, versions.SupportsFeature(FeatureJSONOutput))
+	require.False(t, versions.SupportsFeature(Feature("unknown")))
+
+	versions.Kernel = ZFSVersion{}
+	require.True(t, versions.SupportsFeature(FeatureJSONOutput))
+
+	versions.Userland = ZFSVersion{Major: 0, Minor: 7, Patch: 13}
+	require.True(t, versions.SupportsFeature(FeatureResumableReceive))
+	require.False(t, versions.SupportsFeature(FeatureRawSend))
+	require.False(t, versions.SupportsFeature(FeatureRedaction))
+}
+
+func Test_DetectPlatform(t *testing.T) {
+	SetPlatform(&PlatformLinux)
+	defer SetPlatform(nil)
+
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"version"}, args)
+		_, err := io.WriteString(stdout, "zfs-0.7.13-1\nzfs-kmod-0.7.13-1\n")
+		return "", err
+	}))
+	versions, err := DetectPlatform(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0.7.13", versions.Userland.String())
+
+	p := CurrentPlatform()
+	require.True(t, p.ResumableReceive)
+	require.False(t, p.RawSend)
+	require.False(t, p.PoolWait)
+	require.False(t, p.JSONOutput)
+
+	ds := &Dataset{Name: "pool/fs@snap", Type: DatasetSnapshot}
+	_, err = ds.SendSnapshot(ctx, io.Discard, SendOptions{Raw: true})
+	require.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,159 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// ErrVolumeShrink is returned when shrinking a volume without forcing it, as that truncates the data at its end
+var ErrVolumeShrink = errors.New("volume cannot be shrunk without force")
+
+// ErrZvolNotReady is returned when the block device of a volume did not appear in time
+var ErrZvolNotReady = errors.New("zvol device not ready")
+
+// zvolDevDir is the directory udev on Linux and devfs on FreeBSD create the volume block devices in
+var zvolDevDir = "/dev/zvol"
+
+// zvolPollInterval is how often WaitForZvol checks for the block device
+const zvolPollInterval = 50 * time.Millisecond
+
+// SetVolSizeOptions are options you can specify to customize resizing a volume
+type SetVolSizeOptions struct {
+	// Force allows shrinking the volume, which loses the data beyond the new size
+	Force bool
+}
+
+// SetVolSize resizes the volume to the new size in bytes, which must be a multiple of its volblocksize.
+// The current size is looked up first, shrinking the volume returns ErrVolumeShrink unless forced.
+// The reservation of a thick provisioned volume grows along with it, a sparse volume stays sparse.
+func (d *Dataset) SetVolSize(ctx context.Context, newSize uint64, options SetVolSizeOptions) error {
+	if d.Type != DatasetVolume {
+		return fmt.Errorf("resizing %s: only volumes have a volsize", d.Name)
+	}
+	if newSize == 0 {
+		return fmt.Errorf("resizing %s: size cannot be zero", d.Name)
+	}
+	sizes, err := d.volumeSizes(ctx)
+	if err != nil {
+		return err
+	}
+	if newSize < sizes.volsize && !options.Force {
+		return fmt.Errorf("resizing %s from %d to %d bytes: %w", d.Name, sizes.volsize, newSize, ErrVolumeShrink)
+	}
+
+	err = d.SetProperty(ctx, PropertyVolSize, strconv.FormatUint(newSize, 10))
+	if err != nil {
+		return err
+	}
+	d.Volsize = newSize
+	return nil
+}
+
+// Sparse returns whether the volume is sparse, meaning its refreservation does not cover its size, so writes to it
+// can fail when the pool is full
+func (d *Dataset) Sparse(ctx context.Context) (bool, error) {
+	if d.Type != DatasetVolume {
+		return false, fmt.Errorf("checking %s: only volumes can be sparse", d.Name)
+	}
+	sizes, err := d.volumeSizes(ctx)
+	if err != nil {
+		return false, err
+	}
+	return sizes.refreservation < sizes.volsize, nil
+}
+
+// ReserveVolume makes the volume thick provisioned, by setting its refreservation to auto, which reserves its size
+// plus the space for its metadata
+func (d *Dataset) ReserveVolume(ctx context.Context) error {
+	if d.Type != DatasetVolume {
+		return fmt.Errorf("reserving %s: only volumes can be reserved", d.Name)
+	}
+	return d.SetProperty(ctx, PropertyRefReservation, "auto")
+}
+
+// UnreserveVolume makes the volume sparse, by removing its refreservation
+func (d *Dataset) UnreserveVolume(ctx context.Context) error {
+	if d.Type != DatasetVolume {
+		return fmt.Errorf("unreserving %s: only volumes can be reserved", d.Name)
+	}
+	return d.SetProperty(ctx, PropertyRefReservation, ValueNone)
+}
+
+type volumeSizes struct {
+	volsize        uint64
+	refreservation uint64
+}
+
+// volumeSizes looks up the current size and reservation of the volume
+func (d *Dataset) volumeSizes(ctx context.Context) (volumeSizes, error) {
+	out, err := zfsOutput(ctx, "get", "-Hp", "-o", "property,value",
+		fmt.Sprintf("%s,%s", PropertyVolSize, PropertyRefReservation), d.Name)
+	if err != nil {
+		return volumeSizes{}, err
+	}
+
+	var sizes volumeSizes
+	for _, fields := range out {
+		if len(fields) != 2 {
+			return volumeSizes{}, fmt.Errorf("output contains line with %d fields: %v", len(fields), fields)
+		}
+		var dst *uint64
+		switch fields[0] {
+		case PropertyVolSize:
+			dst = &sizes.volsize
+		case PropertyRefReservation:
+			dst = &sizes.refreservation
+		default:
+			continue
+		}
+		if fields[1] == ValueNone {
+			continue
+		}
+		*dst, err = setUint(fields[1])
+		if err != nil {
+			return volumeSizes{}, fmt.Errorf("error parsing %s of %s: %w", fields[0], d.Name, err)
+		}
+	}
+	return sizes, nil
+}
+
+// ZvolDevicePath returns the path of the block device of the volume, /dev/zvol/<name>
+func (d *Dataset) ZvolDevicePath() string {
+	return path.Join(zvolDevDir, d.Name)
+}
+
+// WaitForZvol waits until the block device of the volume exists and returns its path. The device appears
+// asynchronously after creating, cloning, renaming or receiving a volume, so it cannot be opened right away.
+// Returns ErrZvolNotReady when it does not appear within the timeout.
+func (d *Dataset) WaitForZvol(ctx context.Context, timeout time.Duration) (string, error) {
+	if d.Type != DatasetVolume {
+		return "", fmt.Errorf("waiting for %s: only volumes have a block device", d.Name)
+	}
+
+	devPath := d.ZvolDevicePath()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(zvolPollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := os.Stat(devPath)
+		switch {
+		case err == nil:
+			return devPath, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return "", fmt.Errorf("error checking zvol device %s: %w", devPath, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for %s: %w: %w", devPath, ErrZvolNotReady, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetVolSize(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[0] == "get" {
+			_, _ = io.WriteString(stdout, "volsize\t1073741824\nrefreservation\tnone\n")
+		}
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "pool/vol", Type: DatasetVolume}
+	require.NoError(t, ds.SetVolSize(ctx, 2147483648, SetVolSizeOptions{}))
+	require.EqualValues(t, 2147483648, ds.Volsize)
+	require.Equal(t, []string{"set", "volsize=2147483648", "pool/vol"}, executed[1])
+
+	executed = nil
+	err := ds.SetVolSize(ctx, 536870912, SetVolSizeOptions{})
+	require.ErrorIs(t, err, ErrVolumeShrink)
+	require.Len(t, executed, 1)
+
+	require.NoError(t, ds.SetVolSize(ctx, 536870912, SetVolSizeOptions{Force: true}))
+
+	sparse, err := ds.Sparse(ctx)
+	require.NoError(t, err)
+	require.True(t, sparse)
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.Error(t, fs.SetVolSize(ctx, 2147483648, SetVolSizeOptions{}))
+}
+
+func Test_ReserveVolume(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "pool/vol", Type: DatasetVolume}
+	require.NoError(t, ds.ReserveVolume(ctx))
+	require.NoError(t, ds.UnreserveVolume(ctx))
+	require.Equal(t, [][]string{
+		{"set", "refreservation=auto", "pool/vol"},
+		{"set", "refreservation=none", "pool/vol"},
+	}, executed)
+}
+
+func Test_WaitForZvol(t *testing.T) {
+	dir := t.TempDir()
+	defer func(old string) { zvolDevDir = old }(zvolDevDir)
+	zvolDevDir = dir
+
+	ds := &Dataset{Name: "pool/vol", Type: DatasetVolume}
+	require.Equal(t, filepath.Join(dir, "pool/vol"), ds.ZvolDevicePath())
+
+	_, err := ds.WaitForZvol(context.Background(), 100*time.Millisecond)
+	require.ErrorIs(t, err, ErrZvolNotReady)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Compute the path up front, the goroutine must not read zvolDevDir while the deferred restore writes it
+	path := ds.ZvolDevicePath()
+	created := make(chan struct{})
+	go func() {
+		defer close(created)
+		time.Sleep(100 * time.Millisecond)
+		_ = os.MkdirAll(filepath.Join(dir, "pool"), 0o755)
+		_ = os.WriteFile(path, nil, 0o600)
+	}()
+	devPath, err := ds.WaitForZvol(context.Background(), 5*time.Second)
+	<-created
+	require.NoError(t, err)
+	require.Equal(t, path, devPath)
+}
@@ -0,0 +1,36 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+)
+
+// WaitActivity is a background activity of a dataset that zfs wait can wait for
+type WaitActivity string
+
+// Activities zfs wait can wait for
+const (
+	// WaitActivityDeleteQueue is the queue of files that are unlinked but still open, and are freed in the background
+	WaitActivityDeleteQueue WaitActivity = "deleteq"
+)
+
+// Wait blocks until the background activities of the dataset are finished, all activities when none are given.
+// For instance to wait until deleted files are freed before measuring the free space.
+func (d *Dataset) Wait(ctx context.Context, activities ...WaitActivity) error {
+	p := CurrentPlatform()
+	if !p.PoolWait {
+		return p.notSupported("zfs wait")
+	}
+
+	args := make([]string, 1, 4)
+	args[0] = "wait"
+	if len(activities) > 0 {
+		types := make([]string, len(activities))
+		for i, activity := range activities {
+			types[i] = string(activity)
+		}
+		args = append(args, "-t", strings.Join(types, ","))
+	}
+	args = append(args, d.Name)
+	return zfs(ctx, args...)
+}
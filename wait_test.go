@@ -0,0 +1,25 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Wait(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, ds.Wait(ctx))
+	require.NoError(t, ds.Wait(ctx, WaitActivityDeleteQueue))
+	require.Equal(t, [][]string{
+		{"wait", "pool/fs"},
+		{"wait", "-t", "deleteq", "pool/fs"},
+	}, executed)
+}
@@ -2,18 +2,27 @@
 package zfs
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/klauspost/compress/zstd"
 )
 
+// Binary and PoolBinary are the names of the zfs and zpool commands, which Executors get as command. The paths they
+// are run from can be changed with a CommandConfig.
 const (
-	Binary = "zfs"
+	Binary     = "zfs"
+	PoolBinary = "zpool"
 )
 
 // ListOptions are options you can specify to customize the ListDatasets and other List commands
@@ -32,50 +41,269 @@ type ListOptions struct {
 	Depth int
 	// FilterSelf: When true, it will filter out the parent dataset itself from the results
 	FilterSelf bool
+	// Fields limits the properties retrieved for the fields of the Dataset struct to these, so the other fields
+	// are left empty. This saves work when only a few of them are needed. When empty, all fields are retrieved,
+	// except for the EncryptionProperties.
+	Fields []string
+	// CreatedAfter filters out the datasets created at or before this time, zero for no filter.
+	// With a creation filter the datasets are listed sorted by creation with zfs list, so the listing stops once the
+	// datasets are past the range. Without SortBy they are returned oldest first.
+	CreatedAfter time.Time
+	// CreatedBefore filters out the datasets created at or after this time, zero for no filter, like CreatedAfter.
+	// The creation time of datasets has a resolution of seconds.
+	CreatedBefore time.Time
+	// SortBy has zfs list sort the datasets by this property, like zfs list -s does. Empty keeps the order of zfs get.
+	SortBy string
+	// SortDescending sorts the datasets in descending order instead, like zfs list -S does
+	SortDescending bool
+	// Offset skips this amount of datasets, after filtering and sorting them
+	Offset int
+	// Limit returns at most this amount of datasets, after filtering, sorting and skipping the offset, zero for no limit
+	Limit int
+	// CommandTimeout terminates the command when it has not finished within this time, zero for no timeout.
+	// The error then matches ErrCommandCancelled and context.DeadlineExceeded.
+	CommandTimeout time.Duration
 }
 
-// ListDatasets lists the datasets by type and allows you to fetch extra custom fields
-func ListDatasets(ctx context.Context, options ListOptions) ([]Dataset, error) {
+// paginate returns the page of the datasets selected by the offset and limit
+func (o ListOptions) paginate(ds []Dataset) []Dataset {
+	ds = ds[min(max(o.Offset, 0), len(ds)):]
+	if o.Limit > 0 && len(ds) > o.Limit {
+		ds = ds[:o.Limit]
+	}
+	return ds
+}
+
+// filterCreated returns whether the creation filters are set
+func (o ListOptions) filterCreated() bool {
+	return !o.CreatedAfter.IsZero() || !o.CreatedBefore.IsZero()
+}
+
+// createdInRange returns whether the creation time is within the creation filters
+func (o ListOptions) createdInRange(created time.Time) bool {
+	if !o.CreatedAfter.IsZero() && !created.After(o.CreatedAfter) {
+		return false
+	}
+	return o.CreatedBefore.IsZero() || created.Before(o.CreatedBefore)
+}
+
+// sorted returns whether the datasets are listed sorted with zfs list, instead of with zfs get
+func (o ListOptions) sorted() bool {
+	return o.SortBy != "" || o.filterCreated()
+}
+
+// sortProperty returns the property zfs list sorts the datasets by
+func (o ListOptions) sortProperty() string {
+	if o.SortBy == "" {
+		return PropertyCreation
+	}
+	return o.SortBy
+}
+
+// pastCreated returns whether the datasets sorted by creation have passed the creation filters, so the rest of them
+// is filtered out as well
+func (o ListOptions) pastCreated(ds *Dataset) bool {
+	switch {
+	case o.sortProperty() != PropertyCreation:
+		return false
+	case o.SortDescending:
+		return !o.CreatedAfter.IsZero() && !ds.Creation.After(o.CreatedAfter)
+	}
+	return !o.CreatedBefore.IsZero() && !ds.Creation.Before(o.CreatedBefore)
+}
+
+// listArgs returns the arguments of zfs get for the list options, or those of zfs list when sorting, and the fields
+// retrieved
+func (o ListOptions) listArgs(jsonOutput bool) ([]string, []string, error) {
+	fields := dsPropList
+	if len(o.Fields) > 0 {
+		for _, field := range o.Fields {
+			if !slices.Contains(dsPropList, field) && !slices.Contains(EncryptionProperties, field) {
+				return nil, nil, fmt.Errorf("%w: %s", ErrUnknownField, field)
+			}
+		}
+		fields = o.Fields
+		if o.filterCreated() && !slices.Contains(fields, PropertyCreation) {
+			fields = append(slices.Clip(fields), PropertyCreation)
+		}
+	}
+	props := propertyList(fields, o.ExtraProperties)
+
 	args := make([]string, 0, 16)
-	args = append(args, "get", "-Hp", "-o", "name,property,value")
-	if options.DatasetType != "" {
-		args = append(args, "-t", string(options.DatasetType))
+	switch {
+	case o.sorted():
+		args = append(args, "list", "-Hp", "-o", strings.Join(listColumns(props), ","))
+		if o.SortDescending {
+			args = append(args, "-S", o.sortProperty())
+		} else {
+			args = append(args, "-s", o.sortProperty())
+		}
+	case jsonOutput:
+		args = append(args, "get", "-j", "-p")
+	default:
+		args = append(args, "get", "-Hp", "-o", "name,property,value")
+	}
+	if o.DatasetType != "" {
+		args = append(args, "-t", string(o.DatasetType))
 	}
 
-	if options.Recursive {
+	if o.Recursive {
 		args = append(args, "-r")
 	}
 
-	if options.Depth > 0 {
-		args = append(args, "-d", strconv.Itoa(options.Depth))
+	if o.Depth > 0 {
+		args = append(args, "-d", strconv.Itoa(o.Depth))
 	}
 
-	allFields := append(dsPropList, options.ExtraProperties...) // nolint: gocritic
-	args = append(args, strings.Join(allFields, ","))
+	if !o.sorted() {
+		args = append(args, strings.Join(props, ","))
+	}
+	if o.ParentDataset != "" {
+		args = append(args, o.ParentDataset)
+	}
+	return args, fields, nil
+}
 
-	if options.ParentDataset != "" {
-		args = append(args, options.ParentDataset)
+// filtered returns whether the dataset is filtered out by the FilterSelf or creation filters
+func (o ListOptions) filtered(ds *Dataset) bool {
+	if o.FilterSelf && ds.Name == o.ParentDataset {
+		return true
 	}
+	return o.filterCreated() && !o.createdInRange(ds.Creation)
+}
 
-	out, err := zfsOutput(ctx, args...)
+// ListDatasets lists the datasets by type and allows you to fetch extra custom fields.
+// Sorted listings and listings filtered by creation do not use the lookup cache, as they may stop early.
+func ListDatasets(ctx context.Context, options ListOptions) ([]Dataset, error) {
+	if options.sorted() {
+		var ds []Dataset
+		err := IterateDatasets(ctx, options, func(dataset Dataset) error {
+			ds = append(ds, dataset)
+			return nil
+		})
+		return ds, err
+	}
+
+	ctx, cancel := withCommandTimeout(ctx, options.CommandTimeout)
+	defer cancel()
+
+	jsonOutput := CurrentPlatform().JSONOutput
+	args, fields, err := options.listArgs(jsonOutput)
 	if err != nil {
 		return nil, err
 	}
 
-	ds, err := readDatasets(out, options.ExtraProperties)
+	ds, err := cachedLookup(ctx, args, cloneDatasets, func() ([]Dataset, error) {
+		if jsonOutput {
+			var out bytes.Buffer
+			c := command{
+				cmd:    Binary,
+				ctx:    ctx,
+				stdout: &out,
+			}
+			_, err := c.Run(args...)
+			if err != nil {
+				return nil, err
+			}
+			return parseDatasetsJSON(out.Bytes(), fields, options.ExtraProperties)
+		}
+
+		c := command{
+			cmd:    Binary,
+			ctx:    ctx,
+			fields: 3,
+		}
+		parser := newDatasetParser(fields, options.ExtraProperties)
+		err := c.Stream(parser.parseLine, args...)
+		if err != nil {
+			return nil, err
+		}
+		return parser.datasets()
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Filter out the parent dataset:
-	if options.FilterSelf {
-		ds = slices.DeleteFunc(ds, func(dataset Dataset) bool {
-			return dataset.Name == options.ParentDataset
-		})
+	ds = slices.DeleteFunc(ds, func(dataset Dataset) bool {
+		return options.filtered(&dataset)
+	})
+	return options.paginate(ds), nil
+}
+
+// IterateDatasets lists the datasets like ListDatasets, but calls fn for every dataset as soon as its output has been
+// read, so the complete list never has to be held in memory. When fn returns an error, the listing is stopped and the
+// error is returned. The listing also stops once the Limit is reached, or once the datasets sorted by creation are
+// past the creation filters. The lookup cache is not used, and with JSON output the datasets are only iterated after
+// the complete output has been parsed, unless they are sorted.
+func IterateDatasets(ctx context.Context, options ListOptions, fn func(Dataset) error) error {
+	ctx, cancel := withCommandTimeout(ctx, options.CommandTimeout)
+	defer cancel()
+
+	jsonOutput := CurrentPlatform().JSONOutput
+	if jsonOutput && !options.sorted() {
+		ds, err := ListDatasets(ctx, options)
+		if err != nil {
+			return err
+		}
+		for _, dataset := range ds {
+			err = fn(dataset)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	return ds, nil
+
+	args, fields, err := options.listArgs(jsonOutput)
+	if err != nil {
+		return err
+	}
+	parser := newDatasetParser(fields, options.ExtraProperties)
+	skip := options.Offset
+	emitted := 0
+	emit := func(ds Dataset) error {
+		switch {
+		case options.pastCreated(&ds):
+			return errLimitReached
+		case options.filtered(&ds):
+			return nil
+		case skip > 0:
+			skip--
+			return nil
+		}
+		emitted++
+		err := fn(ds)
+		if err == nil && options.Limit > 0 && emitted >= options.Limit {
+			return errLimitReached
+		}
+		return err
+	}
+
+	c := command{
+		cmd:    Binary,
+		ctx:    ctx,
+		fields: 3,
+	}
+	lineFn := parser.stream(emit)
+	if options.sorted() {
+		props := propertyList(fields, options.ExtraProperties)
+		c.fields = len(listColumns(props))
+		lineFn = parser.rows(props, emit)
+	}
+	err = c.Stream(lineFn, args...)
+	if err == nil {
+		err = parser.flush(emit)
+	}
+	if errors.Is(err, errLimitReached) {
+		return nil
+	}
+	return err
 }
 
+// errLimitReached stops iterating the datasets once the limit has been reached
+var errLimitReached = errors.New("limit reached")
+
 // ListVolumes returns a slice of ZFS volumes.
 // A filter argument may be passed to select a volume with the matching name, or empty string ("") may be used to select all volumes.
 func ListVolumes(ctx context.Context, options ListOptions) ([]Dataset, error) {
@@ -113,8 +341,9 @@ type ListWithPropertyOptions struct {
 // ListWithProperty returns a map of dataset names mapped to the properties value for datasets which have the given ZFS property.
 func ListWithProperty(ctx context.Context, property string, options ListWithPropertyOptions) (map[string]string, error) {
 	c := command{
-		cmd: Binary,
-		ctx: ctx,
+		cmd:    Binary,
+		ctx:    ctx,
+		fields: 2,
 	}
 
 	args := make([]string, 0, 16)
@@ -137,20 +366,68 @@ func ListWithProperty(ctx context.Context, property string, options ListWithProp
 		args = append(args, options.ParentDataset)
 	}
 
-	lines, err := c.Run(args...)
-	if err != nil {
-		return nil, err
-	}
-	result := make(map[string]string, len(lines))
-	for _, line := range lines {
-		switch len(line) {
-		case 2:
-			result[line[0]] = line[1]
-		case 1:
-			result[line[0]] = ""
+	return cachedLookup(ctx, args, maps.Clone, func() (map[string]string, error) {
+		result := make(map[string]string, 16)
+		err := c.Stream(func(line []string) error {
+			addPropertyValue(result, line)
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
 		}
-	}
-	return result, nil
+		return result, nil
+	})
+}
+
+// PropertyValue is the value of a property of a dataset together with its source
+type PropertyValue struct {
+	Value  string         `json:"Value"`
+	Source PropertySource `json:"Source"`
+	// InheritedFrom is the dataset the value is inherited from, when the source is inherited
+	InheritedFrom string `json:"InheritedFrom,omitempty"`
+}
+
+// GetPropertyRecursive returns a map of the dataset and all its descendants mapped to the value and source of the
+// property, so locally set values can be told apart from inherited or received ones
+func GetPropertyRecursive(ctx context.Context, dataset, property string) (map[string]PropertyValue, error) {
+	// The value is the last field, so values containing tabs are kept intact
+	args := []string{"get", "-r", "-Hp", "-o", "name,source,value", property, dataset}
+	return cachedLookup(ctx, args, maps.Clone, func() (map[string]PropertyValue, error) {
+		c := command{
+			cmd:    Binary,
+			ctx:    ctx,
+			fields: 3,
+		}
+		result := make(map[string]PropertyValue, 16)
+		err := c.Stream(func(line []string) error {
+			switch len(line) {
+			case 3:
+				result[line[0]] = parsePropertyValue(line[1], line[2])
+			case 2:
+				result[line[0]] = parsePropertyValue(line[1], "")
+			default:
+				return fmt.Errorf("output contains line with %d fields: %v", len(line), line)
+			}
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+}
+
+// parsePropertyValue parses the source of zfs get, which is like inherited from pool/fs for inherited values
+func parsePropertyValue(source, value string) PropertyValue {
+	prop := PropertyValue{Value: value, Source: PropertySource(source)}
+	switch {
+	case source == ValueUnset:
+		prop.Source = PropertySourceNone
+	case strings.HasPrefix(source, "inherited from "):
+		prop.Source = PropertySourceInherited
+		prop.InheritedFrom = strings.TrimPrefix(source, "inherited from ")
+	}
+	return prop
 }
 
 // GetDataset retrieves a single ZFS dataset by name.
@@ -172,6 +449,101 @@ func GetDataset(ctx context.Context, name string, extraProperties ...string) (*D
 	return &ds[0], nil
 }
 
+// DatasetExists returns whether the dataset exists, and is of the given type when the type is not empty.
+// A dataset that does not exist is not an error.
+func DatasetExists(ctx context.Context, name string, datasetType DatasetType) (bool, error) {
+	ds, err := ListDatasets(ctx, ListOptions{
+		ParentDataset: name,
+		Fields:        []string{PropertyName, PropertyType},
+	})
+	switch {
+	case errors.Is(err, ErrDatasetNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	case len(ds) != 1:
+		return false, fmt.Errorf("expected one dataset, got %d", len(ds))
+	}
+	return datasetType == "" || ds[0].Type == datasetType, nil
+}
+
+// getDatasetsBatchSize is the maximum amount of datasets GetDatasets retrieves with one command, to stay well within
+// the maximum length of the command line
+const getDatasetsBatchSize = 1000
+
+// GetDatasets retrieves multiple ZFS datasets by name, using a single zfs get command for all of them. Large amounts
+// of datasets are retrieved in batches, which run in parallel within the CommandLimits.
+// When one of the datasets does not exist ErrDatasetNotFound is returned.
+func GetDatasets(ctx context.Context, names []string, extraProperties ...string) ([]Dataset, error) {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	names = slices.Compact(names)
+	if len(names) == 0 {
+		return []Dataset{}, nil
+	}
+	if len(names) <= getDatasetsBatchSize {
+		return getDatasets(ctx, names, extraProperties)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var batches [][]string
+	for start := 0; start < len(names); start += getDatasetsBatchSize {
+		batches = append(batches, names[start:min(start+getDatasetsBatchSize, len(names))])
+	}
+	results := make([][]Dataset, len(batches))
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	for i, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			results[i], err = getDatasets(ctx, batch, extraProperties)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel() // No need to retrieve the other batches
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	ds := make([]Dataset, 0, len(names))
+	for _, result := range results {
+		ds = append(ds, result...)
+	}
+	return ds, nil
+}
+
+func getDatasets(ctx context.Context, names, extraProperties []string) ([]Dataset, error) {
+	args := make([]string, 0, 8+len(names))
+	args = append(args, "get", "-Hp", "-o", "name,property,value")
+
+	args = append(args, strings.Join(propertyList(dsPropList, extraProperties), ","))
+	args = append(args, names...)
+
+	return cachedLookup(ctx, args, cloneDatasets, func() ([]Dataset, error) {
+		c := command{
+			cmd:    Binary,
+			ctx:    ctx,
+			fields: 3,
+		}
+		parser := newDatasetParser(dsPropList, extraProperties)
+		err := c.Stream(parser.parseLine, args...)
+		if err != nil {
+			return nil, err
+		}
+		return parser.datasets()
+	})
+}
+
 // CloneOptions are options you can specify to customize the clone command
 type CloneOptions struct {
 	// Properties to be applied to the new dataset
@@ -181,6 +553,10 @@ type CloneOptions struct {
 	// to the mountpoint property inherited from their parent. If the target filesystem or volume already exists,
 	// the operation completes successfully.
 	CreateParents bool
+
+	// SkipRefetch skips retrieving the clone afterwards, saving a zfs command.
+	// The returned dataset then only has its name and origin set.
+	SkipRefetch bool
 }
 
 // Clone clones a ZFS snapshot and returns a clone dataset.
@@ -203,18 +579,24 @@ func (d *Dataset) Clone(ctx context.Context, dest string, options CloneOptions)
 	if err != nil {
 		return nil, err
 	}
+	if options.SkipRefetch {
+		return &Dataset{Name: dest, Origin: d.Name}, nil
+	}
 	return GetDataset(ctx, dest)
 }
 
-// Promote promotes a cloned dataset to no longer depend on origin snapshot.
-// An error will be returned if the input dataset is not of snapshot type.
-// The
-func (d *Dataset) Promote(ctx context.Context) error {
-	args := make([]string, 1, 4)
-	args[0] = "promote"
-	args = append(args, []string{d.Name}...)
-
-	return zfs(ctx, args...)
+// Promote promotes a cloned dataset to no longer depend on its origin snapshot, so the origin can be destroyed.
+// The snapshots of the origin up to the origin snapshot are moved to the promoted dataset. It returns the promoted
+// dataset, retrieved again as its origin changed.
+func (d *Dataset) Promote(ctx context.Context) (*Dataset, error) {
+	if d.Type == DatasetSnapshot {
+		return nil, ErrSnapshotsNotSupported
+	}
+	err := zfs(ctx, "promote", d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return GetDataset(ctx, d.Name)
 }
 
 // UnmountOptions are options you can specify to customize the unmount command
@@ -237,6 +619,9 @@ func (d *Dataset) Unmount(ctx context.Context, options UnmountOptions) error {
 		args = append(args, "-f")
 	}
 	if options.UnloadKeys {
+		if p := CurrentPlatform(); !p.UnmountUnloadKeys {
+			return p.notSupported("unmount -u")
+		}
 		args = append(args, "-u")
 	}
 	args = append(args, d.Name)
@@ -328,6 +713,9 @@ func (d *Dataset) Mount(ctx context.Context, options MountOptions) error {
 		args = append(args, "-O")
 	}
 	if options.LoadKeys {
+		if p := CurrentPlatform(); !p.MountLoadKeys {
+			return p.notSupported("mount -l")
+		}
 		args = append(args, "-l")
 	}
 	if len(options.Options) > 0 {
@@ -344,27 +732,68 @@ type ReceiveOptions struct {
 	// Whether the received snapshot should be resumable on interrupions, or be thrown away
 	Resumable bool
 
-	// Properties to be applied to the dataset
+	// Properties to be applied to the dataset, overriding the values in the stream (-o)
 	Properties map[string]string
 
+	// ExcludeProperties are not received from the stream, the dataset inherits them instead (-x).
+	// This also works for raw streams of encrypted datasets, like excluding the mountpoint.
+	ExcludeProperties []string
+
 	// EnableCompression enables zstd decompression
 	EnableDecompression bool
 
 	// Force a rollback of the file system to the most recent snapshot before performing the receive operation.
 	ForceRollback bool
+
+	// DoNotMount prevents the file system associated with the received stream from being mounted.
+	DoNotMount bool
+
+	// BufferSize sets the amount of bytes to read ahead from the input in memory, zero for no buffering
+	BufferSize int
+	// ProgressFn is called every ProgressEvery with the amount of bytes received by zfs so far, and once more with
+	// the total. The totals are also returned in the StreamStats.
+	ProgressFn StreamProgressCallback
+	// ProgressEvery determines the interval at which ProgressFn is called
+	ProgressEvery time.Duration
+	// ExternalBuffer runs an external program buffering the input in front of zfs, nil for none
+	ExternalBuffer *ExternalBuffer
+
+	// SkipRefetch skips retrieving the received dataset afterwards.
+	// The returned dataset then only has its name set, and its type when receiving into a named snapshot.
+	SkipRefetch bool
+
+	// DryRun consumes and validates the stream against the target without receiving it. The returned dataset then
+	// only has the name and type of the snapshot that would be created.
+	DryRun bool
+
+	// CommandTimeout terminates the receive when it has not finished within this time, zero for no timeout.
+	// The error then matches ErrCommandCancelled and context.DeadlineExceeded.
+	CommandTimeout time.Duration
 }
 
 // ReceiveSnapshot receives a ZFS stream from the input io.Reader.
 // A new snapshot is created with the specified name, and streams the input data into the newly-created snapshot.
-func ReceiveSnapshot(ctx context.Context, input io.Reader, name string, options ReceiveOptions) (*Dataset, error) {
+// The returned stats hold the amount of bytes zfs received and how long that took, also when the receive failed.
+func ReceiveSnapshot(ctx context.Context, input io.Reader, name string, options ReceiveOptions) (*Dataset, StreamStats, error) {
+	ctx, cancel := withCommandTimeout(ctx, options.CommandTimeout)
+	defer cancel()
+
 	if options.EnableDecompression {
 		decoder, err := zstd.NewReader(input)
 		if err != nil {
-			return nil, fmt.Errorf("error creating zstd reader: %w", err)
+			return nil, StreamStats{}, fmt.Errorf("error creating zstd reader: %w", err)
 		}
 		defer decoder.Close()
 		input = decoder
 	}
+	input, stopBuffer := bufferReader(input, options.BufferSize)
+	defer stopBuffer()
+	input, statsDone := countInput(input, options.ProgressEvery, options.ProgressFn)
+	input, waitBuffer, err := externalBufferReader(ctx, input, options.ExternalBuffer)
+	if err != nil {
+		return nil, statsDone(), err
+	}
+
 	c := command{
 		cmd:   Binary,
 		ctx:   ctx,
@@ -377,16 +806,61 @@ func ReceiveSnapshot(ctx context.Context, input io.Reader, name string, options
 		args = append(args, "-F")
 	}
 	if options.Resumable {
+		if p := CurrentPlatform(); !p.ResumableReceive {
+			return nil, statsDone(), p.notSupported("receive -s")
+		}
 		args = append(args, "-s")
 	}
+	if options.DoNotMount {
+		args = append(args, "-u")
+	}
+	if options.DryRun {
+		args = append(args, "-n", "-v")
+		c.fields = 1
+	}
 	args = append(args, propsSlice(options.Properties)...)
+	for _, prop := range options.ExcludeProperties {
+		args = append(args, "-x", prop)
+	}
 	args = append(args, name)
 
-	_, err := c.Run(args...)
+	out, err := c.Run(args...)
+	bufferErr := waitBuffer()
+	stats := statsDone()
 	if err != nil {
-		return nil, err
+		return nil, stats, err
 	}
-	return GetDataset(ctx, name)
+	if bufferErr != nil {
+		return nil, stats, fmt.Errorf("external buffer: %w", bufferErr)
+	}
+	if options.DryRun {
+		return dryRunReceiveDataset(out, name), stats, nil
+	}
+	if options.SkipRefetch && strings.Contains(name, "@") {
+		return &Dataset{Name: name, Type: DatasetSnapshot}, stats, nil
+	}
+	if options.SkipRefetch {
+		return &Dataset{Name: name}, stats, nil
+	}
+	ds, err := GetDataset(ctx, name)
+	return ds, stats, err
+}
+
+// dryRunReceiveDataset returns the snapshot a dry run receive would have created, according to its verbose output
+func dryRunReceiveDataset(output [][]string, name string) *Dataset {
+	for _, line := range output {
+		if len(line) == 0 || !strings.HasPrefix(line[0], "would receive ") {
+			continue
+		}
+		idx := strings.LastIndex(line[0], " into ")
+		if idx >= 0 {
+			return &Dataset{Name: line[0][idx+len(" into "):], Type: DatasetSnapshot}
+		}
+	}
+	if strings.Contains(name, "@") {
+		return &Dataset{Name: name, Type: DatasetSnapshot}
+	}
+	return &Dataset{Name: name}
 }
 
 // SendOptions are options you can specify to customize the send command
@@ -413,43 +887,62 @@ type SendOptions struct {
 	//
 	//           If the destination is a clone, the source may be the origin snapshot, which must be
 	//           fully specified (for example, pool/fs@origin, not just @origin).
+	//
+	// The base may be a bookmark of an earlier snapshot instead of the snapshot itself.
 	IncrementalBase *Dataset
+	// Generate a stream package that sends all intermediary snapshots from the first snapshot to
+	//           the second snapshot. For example, -I @a fs@d is similar to -i @a fs@b; -i @b fs@c; -i
+	//           @c fs@d.  The incremental source may be specified as with the -i option.
+	//
+	// It is ignored without an IncrementalBase, which must be a snapshot.
+	IncludeIntermediarySnapshots bool
 	// When set, uses a rate-limiter to limit the flow to this amount of bytes per second
 	BytesPerSecond int64
 	// CompressionLevel is the level of zstd compression, 0 for off
 	CompressionLevel zstd.EncoderLevel
+	// BufferSize sets the amount of bytes to buffer in memory between zfs and the output, zero for no buffering
+	BufferSize int
+	// ProgressFn is called every ProgressEvery with the amount of bytes sent by zfs so far, and once more with the total.
+	// The totals are also returned in the StreamStats.
+	ProgressFn StreamProgressCallback
+	// ProgressEvery determines the interval at which ProgressFn is called
+	ProgressEvery time.Duration
+	// ExternalBuffer runs an external program buffering the output of zfs, nil for none
+	ExternalBuffer *ExternalBuffer
+	// CommandTimeout terminates the send when it has not finished within this time, zero for no timeout.
+	// The error then matches ErrCommandCancelled and context.DeadlineExceeded.
+	CommandTimeout time.Duration
 }
 
 // SendSnapshot sends a ZFS stream of a snapshot to the input io.Writer.
 // An error will be returned if the input dataset is not of snapshot type.
-func (d *Dataset) SendSnapshot(ctx context.Context, output io.Writer, options SendOptions) error {
+// The returned stats hold the amount of bytes zfs sent and how long that took, also when the send failed.
+func (d *Dataset) SendSnapshot(ctx context.Context, output io.Writer, options SendOptions) (StreamStats, error) {
 	if d.Type != DatasetSnapshot {
-		return ErrOnlySnapshotsSupported
-	}
-
-	args := make([]string, 1, 8)
-	args[0] = "send"
-
-	if options.Raw {
-		args = append(args, "-w")
-	}
-	if options.IncludeProperties {
-		args = append(args, "-p")
+		return StreamStats{}, ErrOnlySnapshotsSupported
 	}
-	if options.IncrementalBase != nil {
-		if options.IncrementalBase.Type != DatasetSnapshot {
-			return fmt.Errorf("send base %s: %w", options.IncrementalBase.Name, ErrOnlySnapshotsSupported)
-		}
-		args = append(args, "-i", options.IncrementalBase.Name)
+	ctx, cancel := withCommandTimeout(ctx, options.CommandTimeout)
+	defer cancel()
+	args, err := sendArgs(options)
+	if err != nil {
+		return StreamStats{}, err
 	}
 
 	output = rateLimitWriter(output, options.BytesPerSecond)
 	output, closer, err := zstdWriter(output, options.CompressionLevel)
 	if err != nil {
-		return err
+		return StreamStats{}, err
 	}
 	defer closer()
 
+	output, flush := bufferWriter(output, options.BufferSize)
+	output, statsDone := countOutput(output, options.ProgressEvery, options.ProgressFn)
+	output, waitBuffer, err := externalBufferWriter(ctx, output, options.ExternalBuffer)
+	if err != nil {
+		_ = flush()
+		return statsDone(), err
+	}
+
 	c := command{
 		cmd:    Binary,
 		ctx:    ctx,
@@ -457,7 +950,100 @@ func (d *Dataset) SendSnapshot(ctx context.Context, output io.Writer, options Se
 	}
 	args = append(args, d.Name)
 	_, err = c.Run(args...)
-	return err
+	// When the buffer program failed, zfs failing is usually caused by it
+	bufferErr := waitBuffer()
+	flushErr := flush()
+	stats := statsDone()
+	if bufferErr != nil {
+		return stats, fmt.Errorf("external buffer: %w", bufferErr)
+	}
+	if err != nil {
+		return stats, err
+	}
+	return stats, flushErr
+}
+
+// SendSize estimates the size of the stream zfs sends for the snapshot with the options, without sending it.
+// The estimate is of the stream before compression by the CompressionLevel of the options.
+func (d *Dataset) SendSize(ctx context.Context, options SendOptions) (int64, error) {
+	if d.Type != DatasetSnapshot {
+		return 0, ErrOnlySnapshotsSupported
+	}
+	ctx, cancel := withCommandTimeout(ctx, options.CommandTimeout)
+	defer cancel()
+	args, err := sendArgs(options)
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, "-n", "-P", "-v", d.Name)
+
+	out, err := zfsOutput(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	for _, fields := range out {
+		if len(fields) == 2 && fields[0] == "size" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no size estimate in output of sending %s", d.Name)
+}
+
+// sendArgs returns the arguments of zfs send for the options, without the snapshot
+func sendArgs(options SendOptions) ([]string, error) {
+	args := make([]string, 1, 8)
+	args[0] = "send"
+
+	if options.Raw {
+		if p := CurrentPlatform(); !p.RawSend {
+			return nil, p.notSupported("send -w")
+		}
+		args = append(args, "-w")
+	}
+	if options.IncludeProperties {
+		args = append(args, "-p")
+	}
+	if options.IncrementalBase != nil {
+		switch {
+		case options.IncludeIntermediarySnapshots && options.IncrementalBase.Type != DatasetSnapshot,
+			options.IncrementalBase.Type != DatasetSnapshot && options.IncrementalBase.Type != DatasetBookmark:
+			return nil, fmt.Errorf("send base %s: %w", options.IncrementalBase.Name, ErrOnlySnapshotsSupported)
+		case options.IncludeIntermediarySnapshots:
+			args = append(args, "-I", options.IncrementalBase.Name)
+		default:
+			args = append(args, "-i", options.IncrementalBase.Name)
+		}
+	}
+	return args, nil
+}
+
+// MultiSendTarget is one of the outputs of a MultiSend
+type MultiSendTarget struct {
+	// Output receives the send stream
+	Output io.Writer
+	// ProgressFn is called every ProgressEvery with the amount of bytes written to this output so far
+	ProgressFn ProgressCallback
+	// ProgressEvery determines the interval at which ProgressFn is called
+	ProgressEvery time.Duration
+}
+
+// MultiSend sends a ZFS stream of a snapshot to multiple outputs at once, so the snapshot is only read from disk once.
+// The outputs are written to concurrently, each buffering up to the BufferSize of the options. An output that fails
+// is dropped while the others continue, the send is only stopped when all of them have failed.
+// The returned slice holds the error of every target in order, the returned error is set when the send itself failed.
+func (d *Dataset) MultiSend(ctx context.Context, targets []MultiSendTarget, options SendOptions) ([]error, error) {
+	outputs := make([]io.Writer, len(targets))
+	for i, target := range targets {
+		counter := NewCountWriter(target.Output)
+		counter.SetProgressCallback(target.ProgressEvery, target.ProgressFn)
+		outputs[i] = counter
+	}
+
+	fanout := newFanoutWriter(outputs, options.BufferSize)
+	options.BufferSize = 0 // Every output is buffered separately already
+	_, err := d.SendSnapshot(ctx, fanout, options)
+	errs := fanout.flush()
+	return errs, err
 }
 
 // ResumeSendOptions are options you can specify to customize the send resume command
@@ -466,26 +1052,68 @@ type ResumeSendOptions struct {
 	BytesPerSecond int64
 	// CompressionLevel is the level of zstd compression, zero for off
 	CompressionLevel zstd.EncoderLevel
+	// BufferSize sets the amount of bytes to buffer in memory between zfs and the output, zero for no buffering
+	BufferSize int
+	// ProgressFn is called every ProgressEvery with the amount of bytes sent by zfs so far, and once more with the total.
+	// The totals are also returned in the StreamStats.
+	ProgressFn StreamProgressCallback
+	// ProgressEvery determines the interval at which ProgressFn is called
+	ProgressEvery time.Duration
+	// ExternalBuffer runs an external program buffering the output of zfs, nil for none
+	ExternalBuffer *ExternalBuffer
 }
 
 // ResumeSend resumes an interrupted ZFS stream of a snapshot to the input io.Writer using the receive_resume_token.
 // An error will be returned if the input dataset is not of snapshot type.
-func ResumeSend(ctx context.Context, output io.Writer, resumeToken string, options ResumeSendOptions) error {
+// The returned stats hold the amount of bytes zfs sent and how long that took, also when the send failed.
+func ResumeSend(ctx context.Context, output io.Writer, resumeToken string, options ResumeSendOptions) (StreamStats, error) {
+	return sendStream(ctx, output, []string{"send", "-t", resumeToken}, options)
+}
+
+// SendSavedState sends the partially received state of a dataset, left by an interrupted resumable receive,
+// to the output io.Writer. Receiving it elsewhere results in the same partial state, which can then be resumed there,
+// so interrupted transfers can be relayed to other receivers.
+func SendSavedState(ctx context.Context, output io.Writer, dataset string, options ResumeSendOptions) (StreamStats, error) {
+	if p := CurrentPlatform(); !p.SendSavedState {
+		return StreamStats{}, p.notSupported("send -S")
+	}
+	return sendStream(ctx, output, []string{"send", "-S", dataset}, options)
+}
+
+// sendStream runs a zfs send command with the options applied to its output
+func sendStream(ctx context.Context, output io.Writer, args []string, options ResumeSendOptions) (StreamStats, error) {
 	output = rateLimitWriter(output, options.BytesPerSecond)
 	output, closer, err := zstdWriter(output, options.CompressionLevel)
 	if err != nil {
-		return err
+		return StreamStats{}, err
 	}
 	defer closer()
 
+	output, flush := bufferWriter(output, options.BufferSize)
+	output, statsDone := countOutput(output, options.ProgressEvery, options.ProgressFn)
+	output, waitBuffer, err := externalBufferWriter(ctx, output, options.ExternalBuffer)
+	if err != nil {
+		_ = flush()
+		return statsDone(), err
+	}
+
 	c := command{
 		cmd:    Binary,
 		ctx:    ctx,
 		stdout: output,
 	}
-	args := append([]string{"send"}, "-t", resumeToken)
 	_, err = c.Run(args...)
-	return err
+	// When the buffer program failed, zfs failing is usually caused by it
+	bufferErr := waitBuffer()
+	flushErr := flush()
+	stats := statsDone()
+	if bufferErr != nil {
+		return stats, fmt.Errorf("external buffer: %w", bufferErr)
+	}
+	if err != nil {
+		return stats, err
+	}
+	return stats, flushErr
 }
 
 // CreateVolumeOptions are options you can specify to customize the create volume command
@@ -508,6 +1136,9 @@ type CreateVolumeOptions struct {
 
 	// Provide input to stdin, for instance for loading keys
 	Stdin io.Reader
+
+	// SkipRefetch skips retrieving the volume after creating it, only its name, type and size are then returned.
+	SkipRefetch bool
 }
 
 // CreateVolume creates a new ZFS volume with the specified name, size, and properties.
@@ -544,6 +1175,9 @@ func CreateVolume(ctx context.Context, name string, size uint64, options CreateV
 	if err != nil {
 		return nil, err
 	}
+	if options.SkipRefetch {
+		return &Dataset{Name: name, Type: DatasetVolume, Volsize: size}, nil
+	}
 
 	return GetDataset(ctx, name)
 }
@@ -570,8 +1204,80 @@ type DestroyOptions struct {
 // If the destroy bit flag is set, any descendents of the dataset will be recursively destroyed, including snapshots.
 // If the deferred bit flag is set, the snapshot is marked for deferred deletion.
 func (d *Dataset) Destroy(ctx context.Context, options DestroyOptions) error {
-	args := make([]string, 1, 6)
+	return zfs(ctx, d.destroyArgs(options)...)
+}
+
+// DestroyEstimate is the outcome of a dry-run destroy
+type DestroyEstimate struct {
+	// Datasets are the datasets that would be destroyed
+	Datasets []string `json:"Datasets"`
+	// Reclaim is the amount of bytes that would be freed
+	Reclaim uint64 `json:"Reclaim"`
+}
+
+// DestroyDryRun estimates which datasets are destroyed and how much space is freed by destroying the dataset with
+// the given options, without destroying anything
+func (d *Dataset) DestroyDryRun(ctx context.Context, options DestroyOptions) (*DestroyEstimate, error) {
+	options.DryRun = false
+	args := d.destroyArgs(options)
+	args = append(args[:1], append([]string{"-n", "-p", "-v"}, args[1:]...)...)
+	out, err := zfsOutput(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseDestroyEstimate(out, d.Name)
+}
+
+// DestroySnapshotRange destroys the snapshots of the filesystem from the first up to and including the last snapshot
+// in one command, which is a lot faster than destroying them one by one. The snapshots may be given with or without
+// the filesystem. Leaving out the first snapshot starts the range at the oldest snapshot, leaving out the last ends it
+// at the newest snapshot, but at least one of them is required.
+func DestroySnapshotRange(ctx context.Context, filesystem, firstSnapshot, lastSnapshot string, options DestroyOptions) error {
+	name, err := snapshotRange(filesystem, firstSnapshot, lastSnapshot)
+	if err != nil {
+		return err
+	}
+	d := &Dataset{Name: name, Type: DatasetSnapshot}
+	return d.Destroy(ctx, options)
+}
+
+// snapshotRange returns the range of snapshots of the filesystem as used by zfs destroy, fs@first%last
+func snapshotRange(filesystem, firstSnapshot, lastSnapshot string) (string, error) {
+	if strings.Contains(filesystem, "@") {
+		return "", fmt.Errorf("%w: %s is not a filesystem", ErrInvalidSnapshotRange, filesystem)
+	}
+	snapshotOnly := func(name string) (string, error) {
+		dataset, snap, found := strings.Cut(name, "@")
+		switch {
+		case !found:
+			return name, nil
+		case dataset != "" && dataset != filesystem:
+			return "", fmt.Errorf("%w: snapshot %s is not of %s", ErrInvalidSnapshotRange, name, filesystem)
+		}
+		return snap, nil
+	}
+	firstSnapshot, err := snapshotOnly(firstSnapshot)
+	if err != nil {
+		return "", err
+	}
+	lastSnapshot, err = snapshotOnly(lastSnapshot)
+	switch {
+	case err != nil:
+		return "", err
+	case firstSnapshot == "" && lastSnapshot == "":
+		return "", fmt.Errorf("%w: no first or last snapshot of %s given", ErrInvalidSnapshotRange, filesystem)
+	case firstSnapshot == lastSnapshot:
+		return fmt.Sprintf("%s@%s", filesystem, firstSnapshot), nil
+	}
+	return fmt.Sprintf("%s@%s%%%s", filesystem, firstSnapshot, lastSnapshot), nil
+}
+
+func (d *Dataset) destroyArgs(options DestroyOptions) []string {
+	args := make([]string, 1, 7)
 	args[0] = "destroy"
+	if options.DryRun {
+		args = append(args, "-n")
+	}
 	if options.Recursive {
 		args = append(args, "-r")
 	}
@@ -584,9 +1290,32 @@ func (d *Dataset) Destroy(ctx context.Context, options DestroyOptions) error {
 	if options.Force {
 		args = append(args, "-f")
 	}
-	args = append(args, d.Name)
+	return append(args, d.Name)
+}
 
-	return zfs(ctx, args...)
+func parseDestroyEstimate(out [][]string, name string) (*DestroyEstimate, error) {
+	estimate := &DestroyEstimate{}
+	found := false
+	for _, fields := range out {
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "destroy":
+			estimate.Datasets = append(estimate.Datasets, fields[1])
+		case "reclaim":
+			reclaim, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing reclaim estimate of destroying %s: %w", name, err)
+			}
+			estimate.Reclaim = reclaim
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no reclaim estimate in output of destroying %s", name)
+	}
+	return estimate, nil
 }
 
 // SetProperty sets a ZFS property on the receiving dataset.
@@ -599,17 +1328,56 @@ func (d *Dataset) SetProperty(ctx context.Context, key, val string) error {
 	return zfs(ctx, "set", prop, d.Name)
 }
 
+// SetPropertyOptions are options you can specify to customize the set command
+type SetPropertyOptions struct {
+	// NoMount updates the properties without remounting or resharing the filesystem, so changing properties like
+	// mountpoint or sharenfs does not disrupt its users. The changes take effect on the next mount or share.
+	// Requires OpenZFS 2.2 or newer.
+	NoMount bool
+}
+
+// SetProperties sets multiple ZFS properties on the receiving dataset at once
+func (d *Dataset) SetProperties(ctx context.Context, properties map[string]string, options SetPropertyOptions) error {
+	if len(properties) == 0 {
+		return nil
+	}
+	args := make([]string, 1, len(properties)+3)
+	args[0] = "set"
+	if options.NoMount {
+		if p := CurrentPlatform(); !p.SetNoMount {
+			return p.notSupported("set -u")
+		}
+		args = append(args, "-u")
+	}
+	props := make([]string, 0, len(properties))
+	for key, val := range properties {
+		props = append(props, key+"="+val)
+	}
+	slices.Sort(props)
+	args = append(args, props...)
+	args = append(args, d.Name)
+
+	return zfs(ctx, args...)
+}
+
 // GetProperty returns the current value of a ZFS property from the receiving dataset.
 //
 // A full list of available ZFS properties may be found in the ZFS manual:
 // https://openzfs.github.io/openzfs-docs/man/7/zfsprops.7.html.
 func (d *Dataset) GetProperty(ctx context.Context, key string) (string, error) {
-	out, err := zfsOutput(ctx, "get", "-Hp", "-o", "value", key, d.Name)
-	if err != nil {
-		return "", err
-	}
-
-	return out[0][0], nil
+	args := []string{"get", "-Hp", "-o", "value", key, d.Name}
+	return cachedLookup(ctx, args, cloneString, func() (string, error) {
+		c := command{
+			cmd:    Binary,
+			ctx:    ctx,
+			fields: 1,
+		}
+		out, err := c.Run(args...)
+		if err != nil {
+			return "", err
+		}
+		return out[0][0], nil
+	})
 }
 
 // InheritProperty clears a property from the receiving dataset, making it use its parent datasets value.
@@ -636,8 +1404,28 @@ type RenameOptions struct {
 	Force bool
 }
 
-// Rename renames a dataset.
+// Rename renames a dataset. A snapshot can only be renamed within its dataset, so its new name can also be given as
+// just the snapshot name, like new or @new. The options for mounting and creating parents do not apply to snapshots,
+// while renaming recursively only applies to them.
 func (d *Dataset) Rename(ctx context.Context, name string, options RenameOptions) error {
+	if d.Type == DatasetSnapshot || strings.Contains(d.Name, "@") {
+		if options.CreateParent || options.NoMount || options.Force {
+			return fmt.Errorf("rename %s: cannot create parents, skip or force unmounting for a snapshot", d.Name)
+		}
+		dataset, _, _ := strings.Cut(d.Name, "@")
+		target, snap, ok := strings.Cut(name, "@")
+		switch {
+		case !ok:
+			name = dataset + "@" + name
+		case target == "":
+			name = dataset + "@" + snap
+		case target != dataset:
+			return fmt.Errorf("rename %s: snapshot cannot be renamed to another dataset: %s", d.Name, name)
+		}
+	} else if options.Recursive {
+		return fmt.Errorf("rename %s recursively: %w", d.Name, ErrOnlySnapshotsSupported)
+	}
+
 	args := make([]string, 1, 6)
 	args[0] = "rename"
 	if options.CreateParent {
@@ -647,6 +1435,9 @@ func (d *Dataset) Rename(ctx context.Context, name string, options RenameOptions
 		args = append(args, "-r")
 	}
 	if options.NoMount {
+		if p := CurrentPlatform(); !p.RenameNoMount {
+			return p.notSupported("rename -u")
+		}
 		args = append(args, "-u")
 	}
 	if options.Force {
@@ -687,6 +1478,54 @@ type CreateFilesystemOptions struct {
 
 	// Provide input to stdin, for instance for loading keys
 	Stdin io.Reader
+
+	// SkipRefetch skips retrieving the filesystem after creating it, only its name and type are then returned.
+	SkipRefetch bool
+
+	// Encryption is the encryption algorithm of the filesystem, or on for the default algorithm.
+	// It defaults to on when a key format is set.
+	Encryption string
+
+	// KeyFormat is the format of the key, raw, hex or passphrase. Setting it creates an encryption root.
+	KeyFormat string
+
+	// KeyLocation is the location of the key, prompt or a file:// URL. It defaults to prompt when there is a
+	// PassphraseReader.
+	KeyLocation string
+
+	// PassphraseReader provides the passphrase or key on stdin, a raw key must be exactly 32 bytes.
+	// It cannot be combined with Stdin.
+	PassphraseReader io.Reader
+}
+
+// properties returns the properties of the filesystem, including its encryption properties
+func (o CreateFilesystemOptions) properties() (map[string]string, error) {
+	if o.PassphraseReader != nil && o.Stdin != nil {
+		return nil, errors.New("cannot use both a passphrase reader and stdin")
+	}
+	if o.PassphraseReader != nil && o.KeyFormat == "" {
+		return nil, errors.New("a passphrase reader requires a key format")
+	}
+	if o.Encryption == "" && o.KeyFormat == "" && o.KeyLocation == "" {
+		return o.Properties, nil
+	}
+
+	props := make(map[string]string, len(o.Properties)+3)
+	maps.Copy(props, o.Properties)
+	props[PropertyEncryption] = cmp.Or(o.Encryption, ValueOn)
+	if o.KeyFormat != "" {
+		props[PropertyKeyFormat] = o.KeyFormat
+	}
+	switch {
+	case o.KeyLocation != "":
+		if o.PassphraseReader != nil && o.KeyLocation != KeyLocationPrompt {
+			return nil, fmt.Errorf("a passphrase reader requires key location %s, got %s", KeyLocationPrompt, o.KeyLocation)
+		}
+		props[PropertyKeyLocation] = o.KeyLocation
+	case o.PassphraseReader != nil:
+		props[PropertyKeyLocation] = KeyLocationPrompt
+	}
+	return props, nil
 }
 
 // CreateFilesystem creates a new ZFS filesystem with the specified name and properties.
@@ -694,11 +1533,16 @@ type CreateFilesystemOptions struct {
 // A full list of available ZFS properties may be found in the ZFS manual:
 // https://openzfs.github.io/openzfs-docs/man/7/zfsprops.7.html.
 func CreateFilesystem(ctx context.Context, name string, options CreateFilesystemOptions) (*Dataset, error) {
+	props, err := options.properties()
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", name, err)
+	}
+
 	args := make([]string, 1, 10)
 	args[0] = "create"
 
-	if options.Properties != nil {
-		args = append(args, propsSlice(options.Properties)...)
+	if props != nil {
+		args = append(args, propsSlice(props)...)
 	}
 
 	if options.CreateParents {
@@ -708,6 +1552,9 @@ func CreateFilesystem(ctx context.Context, name string, options CreateFilesystem
 		args = append(args, "-n")
 	}
 	if options.NoMount {
+		if p := CurrentPlatform(); !p.CreateNoMount {
+			return nil, p.notSupported("create -u")
+		}
 		args = append(args, "-u")
 	}
 
@@ -718,10 +1565,16 @@ func CreateFilesystem(ctx context.Context, name string, options CreateFilesystem
 		ctx:   ctx,
 		stdin: options.Stdin,
 	}
-	_, err := cmd.Run(args...)
+	if options.PassphraseReader != nil {
+		cmd.stdin = options.PassphraseReader
+	}
+	_, err = cmd.Run(args...)
 	if err != nil {
 		return nil, err
 	}
+	if options.SkipRefetch {
+		return &Dataset{Name: name, Type: DatasetFilesystem}, nil
+	}
 
 	return GetDataset(ctx, name)
 }
@@ -733,6 +1586,9 @@ type SnapshotOptions struct {
 
 	// Recursively create snapshots of all descendent datasets.
 	Recursive bool
+
+	// SkipRefetch skips retrieving the snapshot after creating it, only its name and type are then returned.
+	SkipRefetch bool
 }
 
 // Snapshot creates a new ZFS snapshot of the receiving dataset, using the specified name.
@@ -754,9 +1610,50 @@ func (d *Dataset) Snapshot(ctx context.Context, name string, options SnapshotOpt
 	if err != nil {
 		return nil, err
 	}
+	if options.SkipRefetch {
+		return &Dataset{Name: snapName, Type: DatasetSnapshot}, nil
+	}
 	return GetDataset(ctx, snapName)
 }
 
+// CreateSnapshots creates the snapshots, given by their full names like pool/fs@name, in a single atomic operation.
+// The datasets may have differing snapshot names, but must be in the same pool. All snapshots are taken in the same
+// transaction group, so they are consistent with each other, for instance for a database spread over datasets.
+// The named snapshots are returned sorted by name, recursively created snapshots of descendants are not.
+func CreateSnapshots(ctx context.Context, names []string, options SnapshotOptions) ([]Dataset, error) {
+	if len(names) == 0 {
+		return []Dataset{}, nil
+	}
+	for _, name := range names {
+		if !strings.Contains(name, "@") {
+			return nil, fmt.Errorf("snapshot name %s: %w", name, ErrOnlySnapshotsSupported)
+		}
+	}
+
+	args := make([]string, 1, 10+len(names))
+	args[0] = "snapshot"
+	if options.Recursive {
+		args = append(args, "-r")
+	}
+	if options.Properties != nil {
+		args = append(args, propsSlice(options.Properties)...)
+	}
+	args = append(args, names...)
+
+	err := zfs(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if options.SkipRefetch {
+		snaps := make([]Dataset, len(names))
+		for i, name := range names {
+			snaps[i] = Dataset{Name: name, Type: DatasetSnapshot}
+		}
+		return snaps, nil
+	}
+	return GetDatasets(ctx, names)
+}
+
 // RollbackOptions are options you can specify to customize the rollback command
 type RollbackOptions struct {
 	// Destroy any snapshots and bookmarks more recent than the one specified.
@@ -794,6 +1691,19 @@ func (d *Dataset) Rollback(ctx context.Context, options RollbackOptions) error {
 	return zfs(ctx, args...)
 }
 
+// RollbackTo rolls the filesystem or volume back to its snapshot with the given name, which can be given with or
+// without the @ prefix, so the snapshot does not have to be retrieved first
+func (d *Dataset) RollbackTo(ctx context.Context, snapshotName string, options RollbackOptions) error {
+	if d.Type == DatasetSnapshot || strings.Contains(d.Name, "@") {
+		return fmt.Errorf("rollback of %s to %s: cannot roll back a snapshot", d.Name, snapshotName)
+	}
+	snap := &Dataset{
+		Name: d.Name + "@" + strings.TrimPrefix(snapshotName, "@"),
+		Type: DatasetSnapshot,
+	}
+	return snap.Rollback(ctx, options)
+}
+
 // Children returns a slice of children of the receiving ZFS dataset.
 // A recursion depth may be specified, or a depth of 0 allows unlimited recursion.
 func (d *Dataset) Children(ctx context.Context, options ListOptions) ([]Dataset, error) {
@@ -7,12 +7,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/klauspost/compress/zstd"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vansante/go-zfsutils/zfstest"
 )
 
 const testZPool = "go-test-zpool"
@@ -22,7 +27,7 @@ var noMountProps = map[string]string{PropertyCanMount: ValueOff}
 func TestDatasets(t *testing.T) {
 	t.Helper()
 
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		_, err := ListDatasets(context.Background(), ListOptions{})
 		require.NoError(t, err)
 
@@ -35,7 +40,7 @@ func TestDatasets(t *testing.T) {
 }
 
 func TestDatasetsWithProps(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		ds, err := GetDataset(context.Background(), testZPool)
 		require.NoError(t, err)
 
@@ -50,16 +55,63 @@ func TestDatasetsWithProps(t *testing.T) {
 	})
 }
 
+func TestListDatasetsFields(t *testing.T) {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
+		ds, err := ListDatasets(context.Background(), ListOptions{
+			ParentDataset:   testZPool,
+			Fields:          []string{PropertyType},
+			ExtraProperties: []string{PropertyGUID},
+		})
+		require.NoError(t, err)
+		require.Len(t, ds, 1)
+		require.Equal(t, testZPool, ds[0].Name)
+		require.Equal(t, DatasetFilesystem, ds[0].Type)
+		require.NotEmpty(t, ds[0].ExtraProps[PropertyGUID])
+		require.Zero(t, ds[0].Used)
+
+		_, err = ListDatasets(context.Background(), ListOptions{Fields: []string{"atime"}})
+		require.ErrorIs(t, err, ErrUnknownField)
+	})
+}
+
 func TestGetNotExistingDataset(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		_, err := GetDataset(context.Background(), testZPool+"/doesnt-exist")
 		require.Error(t, err)
 		require.ErrorIs(t, err, ErrDatasetNotFound)
 	})
 }
 
+func TestGetDatasets(t *testing.T) {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
+		const prop = "nl.test:hello"
+
+		f1, err := CreateFilesystem(context.Background(), testZPool+"/get-test1", CreateFilesystemOptions{
+			Properties: noMountProps,
+		})
+		require.NoError(t, err)
+		require.NoError(t, f1.SetProperty(context.Background(), prop, "world"))
+
+		f2, err := CreateFilesystem(context.Background(), testZPool+"/get-test2", CreateFilesystemOptions{
+			Properties: noMountProps,
+		})
+		require.NoError(t, err)
+
+		ds, err := GetDatasets(context.Background(), []string{f2.Name, f1.Name, f2.Name}, prop)
+		require.NoError(t, err)
+		require.Len(t, ds, 2)
+		require.Equal(t, f1.Name, ds[0].Name)
+		require.Equal(t, "world", ds[0].ExtraProps[prop])
+		require.Equal(t, f2.Name, ds[1].Name)
+		require.Equal(t, "", ds[1].ExtraProps[prop])
+
+		_, err = GetDatasets(context.Background(), []string{f1.Name, testZPool + "/doesnt-exist"})
+		require.ErrorIs(t, err, ErrDatasetNotFound)
+	})
+}
+
 func TestDatasetGetProperty(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		ds, err := GetDataset(context.Background(), testZPool)
 		require.NoError(t, err)
 
@@ -74,7 +126,7 @@ func TestDatasetGetProperty(t *testing.T) {
 }
 
 func TestDatasetSetInheritProperty(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		ds, err := GetDataset(context.Background(), testZPool)
 		require.NoError(t, err)
 
@@ -93,8 +145,37 @@ func TestDatasetSetInheritProperty(t *testing.T) {
 	})
 }
 
+func TestWhitespaceNamesAndValues(t *testing.T) {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
+		const prop = "nl.test:whitespace"
+		const val = " a\tvalue  with\twhitespace "
+
+		fs, err := CreateFilesystem(context.Background(), testZPool+"/with space", CreateFilesystemOptions{
+			Properties: noMountProps,
+		})
+		require.NoError(t, err)
+		require.Equal(t, testZPool+"/with space", fs.Name)
+		require.NoError(t, fs.SetProperty(context.Background(), prop, val))
+
+		got, err := fs.GetProperty(context.Background(), prop)
+		require.NoError(t, err)
+		require.Equal(t, val, got)
+
+		ds, err := GetDataset(context.Background(), fs.Name, prop)
+		require.NoError(t, err)
+		require.Equal(t, val, ds.ExtraProps[prop])
+
+		props, err := ListWithProperty(context.Background(), prop, ListWithPropertyOptions{
+			ParentDataset: testZPool,
+			DatasetType:   DatasetFilesystem,
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{fs.Name: val}, props)
+	})
+}
+
 func TestSnapshots(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		snapshots, err := ListSnapshots(context.Background(), ListOptions{})
 		require.NoError(t, err)
 
@@ -105,7 +186,7 @@ func TestSnapshots(t *testing.T) {
 }
 
 func TestFilesystems(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/filesystem-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -123,7 +204,7 @@ func TestFilesystems(t *testing.T) {
 }
 
 func TestCreateFilesystemWithProperties(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/filesystem-test", CreateFilesystemOptions{
 			Properties: map[string]string{
 				PropertyCompression: "lz4",
@@ -145,8 +226,8 @@ func TestCreateFilesystemWithProperties(t *testing.T) {
 }
 
 func TestVolumes(t *testing.T) {
-	TestZPool(testZPool, func() {
-		v, err := CreateVolume(context.Background(), testZPool+"/volume-test", uint64(pow2(23)), CreateVolumeOptions{})
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
+		v, err := CreateVolume(context.Background(), testZPool+"/volume-test", 8*1024*1024, CreateVolumeOptions{})
 		require.NoError(t, err)
 
 		// volumes are sometimes "busy" if you try to manipulate them right away
@@ -165,7 +246,7 @@ func TestVolumes(t *testing.T) {
 }
 
 func TestSnapshot(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -208,7 +289,7 @@ func TestSnapshot(t *testing.T) {
 }
 
 func TestListingWithProperty(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		const prop1 = "nl.test:bla"
 		const prop2 = "nl.test:hoi"
 
@@ -245,7 +326,7 @@ func TestListingWithProperty(t *testing.T) {
 }
 
 func TestListWithProperty(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		const prop = "nl.test:bla"
 
 		f1, err := CreateFilesystem(context.Background(), testZPool+"/list-test", CreateFilesystemOptions{
@@ -267,7 +348,7 @@ func TestListWithProperty(t *testing.T) {
 }
 
 func TestCloneAndPromote(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -292,12 +373,10 @@ func TestCloneAndPromote(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, DatasetFilesystem, c.Type)
 
-		err = c.Promote(context.Background())
+		clone, err := c.Promote(context.Background())
 		require.NoError(t, err)
-
-		clone, err := GetDataset(context.Background(), testZPool+"/clone-test")
-		require.NoError(t, err)
-		require.NotEmpty(t, clone)
+		require.Equal(t, testZPool+"/clone-test", clone.Name)
+		require.Empty(t, clone.Origin)
 
 		require.NoError(t, f.Destroy(context.Background(), DestroyOptions{}))
 		require.NoError(t, clone.Destroy(context.Background(), DestroyOptions{
@@ -307,7 +386,7 @@ func TestCloneAndPromote(t *testing.T) {
 }
 
 func TestSendSnapshot(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -323,7 +402,7 @@ func TestSendSnapshot(t *testing.T) {
 		s, err := f.Snapshot(context.Background(), "test", SnapshotOptions{})
 		require.NoError(t, err)
 
-		err = s.SendSnapshot(context.Background(), io.Discard, SendOptions{})
+		_, err = s.SendSnapshot(context.Background(), io.Discard, SendOptions{})
 		require.NoError(t, err)
 		require.NoError(t, s.Destroy(context.Background(), DestroyOptions{}))
 		require.NoError(t, f.Destroy(context.Background(), DestroyOptions{}))
@@ -331,7 +410,7 @@ func TestSendSnapshot(t *testing.T) {
 }
 
 func TestSendSnapshotAlreadyExists(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -344,12 +423,12 @@ func TestSendSnapshotAlreadyExists(t *testing.T) {
 			t.Logf("Sending snapshot %s (%d)", s.Name, i+1)
 			pipeRdr, pipeWrtr := io.Pipe()
 			go func() {
-				err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{})
+				_, err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{})
 				require.NoError(t, err)
 				require.NoError(t, pipeWrtr.Close())
 			}()
 
-			_, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test@snap", ReceiveOptions{
+			_, _, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test@snap", ReceiveOptions{
 				Properties: noMountProps,
 			})
 
@@ -364,7 +443,7 @@ func TestSendSnapshotAlreadyExists(t *testing.T) {
 }
 
 func TestSendSnapshotResume(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -375,12 +454,12 @@ func TestSendSnapshotResume(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{})
+			_, err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = ReceiveSnapshot(context.Background(), io.LimitReader(pipeRdr, 10*1024), testZPool+"/recv-test", ReceiveOptions{
+		_, _, err = ReceiveSnapshot(context.Background(), io.LimitReader(pipeRdr, 10*1024), testZPool+"/recv-test", ReceiveOptions{
 			Resumable:  true,
 			Properties: noMountProps,
 		})
@@ -405,12 +484,12 @@ func TestSendSnapshotResume(t *testing.T) {
 
 		pipeRdr, pipeWrtr = io.Pipe()
 		go func() {
-			err := ResumeSend(context.Background(), pipeWrtr, list[0].ExtraProps[PropertyReceiveResumeToken], ResumeSendOptions{})
+			_, err := ResumeSend(context.Background(), pipeWrtr, list[0].ExtraProps[PropertyReceiveResumeToken], ResumeSendOptions{})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
+		_, _, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
 			Resumable:  true,
 			Properties: noMountProps,
 		})
@@ -426,8 +505,130 @@ func TestSendSnapshotResume(t *testing.T) {
 	})
 }
 
+func Test_SendSavedState(t *testing.T) {
+	var executed []string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		_, err := io.WriteString(stdout, "stream")
+		return "", err
+	}))
+	defer SetExecutor(nil)
+
+	var buf bytes.Buffer
+	var total uint64
+	_, err := SendSavedState(context.Background(), &buf, "pool/recv", ResumeSendOptions{
+		ProgressFn: func(bytes uint64) {
+			total = bytes
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"send", "-S", "pool/recv"}, executed)
+	require.Equal(t, "stream", buf.String())
+	require.EqualValues(t, 6, total)
+}
+
+func Test_SetProperties(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	ds := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, ds.SetProperties(ctx, map[string]string{
+		PropertyMountPoint: "/srv/fs",
+		"sharenfs":         ValueOn,
+	}, SetPropertyOptions{NoMount: true}))
+	require.NoError(t, ds.SetProperties(ctx, map[string]string{PropertyReadOnly: ValueOn}, SetPropertyOptions{}))
+	require.NoError(t, ds.SetProperties(ctx, nil, SetPropertyOptions{}))
+	require.Equal(t, [][]string{
+		{"set", "-u", "mountpoint=/srv/fs", "sharenfs=on", "pool/fs"},
+		{"set", "readonly=on", "pool/fs"},
+	}, executed)
+}
+
+func Test_ListDatasetsCreated(t *testing.T) {
+	creation := map[string]int64{
+		"pool/fs@a": 1_700_000_000,
+		"pool/fs@b": 1_700_000_100,
+		"pool/fs@c": 1_700_000_200,
+		"pool/fs@d": 1_700_000_300,
+	}
+	var executed []string
+	var written []string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		written = nil
+		columns := strings.Split(args[3], ",")
+		names := []string{"pool/fs@a", "pool/fs@b", "pool/fs@c", "pool/fs@d"}
+		if args[4] == "-S" {
+			slices.Reverse(names)
+		}
+		for _, name := range names {
+			values := make([]string, len(columns))
+			for i, prop := range columns {
+				values[i] = "0"
+				switch prop {
+				case PropertyName:
+					values[i] = name
+				case PropertyType:
+					values[i] = string(DatasetSnapshot)
+				case PropertyCreation:
+					values[i] = strconv.FormatInt(creation[name], 10)
+				}
+			}
+			_, err := io.WriteString(stdout, strings.Join(values, "\t")+"\n")
+			if err != nil {
+				return "", err // The listing was stopped
+			}
+			written = append(written, name)
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	names := func(list []Dataset) []string {
+		var names []string
+		for _, ds := range list {
+			names = append(names, ds.Name)
+		}
+		return names
+	}
+
+	ctx := context.Background()
+	list, err := ListSnapshots(ctx, ListOptions{
+		ParentDataset: "pool/fs",
+		CreatedAfter:  time.Unix(1_700_000_000, 0),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/fs@b", "pool/fs@c", "pool/fs@d"}, names(list))
+
+	// The snapshots are listed oldest first, so the listing stops once they are past the range
+	list, err = ListSnapshots(ctx, ListOptions{
+		ParentDataset: "pool/fs",
+		CreatedBefore: time.Unix(1_700_000_200, 0),
+		Fields:        []string{PropertyName},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/fs@a", "pool/fs@b"}, names(list))
+	require.Equal(t, []string{"list", "-Hp", "-o", "name,creation", "-s", "creation", "-t", "snapshot", "-r", "pool/fs"}, executed)
+	require.NotContains(t, written, "pool/fs@d")
+
+	list, err = ListSnapshots(ctx, ListOptions{
+		ParentDataset:  "pool/fs",
+		CreatedAfter:   time.Unix(1_700_000_000, 0),
+		CreatedBefore:  time.Unix(1_700_000_200, 0),
+		SortDescending: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/fs@b"}, names(list))
+	require.Equal(t, "-S", executed[4])
+}
+
 func TestSendSnapshotSpeedLimit(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -438,12 +639,12 @@ func TestSendSnapshotSpeedLimit(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{BytesPerSecond: 10_000})
+			_, err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{BytesPerSecond: 10_000})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
+		_, _, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
 			Properties: noMountProps,
 		})
 		require.NoError(t, err)
@@ -451,7 +652,7 @@ func TestSendSnapshotSpeedLimit(t *testing.T) {
 }
 
 func TestSendSnapshotCompressed(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -462,12 +663,12 @@ func TestSendSnapshotCompressed(t *testing.T) {
 
 		pipeRdr, pipeWrtr := io.Pipe()
 		go func() {
-			err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{CompressionLevel: zstd.SpeedDefault})
+			_, err := s.SendSnapshot(context.Background(), pipeWrtr, SendOptions{CompressionLevel: zstd.SpeedDefault})
 			require.NoError(t, err)
 			require.NoError(t, pipeWrtr.Close())
 		}()
 
-		_, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
+		_, _, err = ReceiveSnapshot(context.Background(), pipeRdr, testZPool+"/recv-test", ReceiveOptions{
 			EnableDecompression: true,
 			Properties:          noMountProps,
 		})
@@ -476,7 +677,7 @@ func TestSendSnapshotCompressed(t *testing.T) {
 }
 
 func TestChildren(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -497,7 +698,7 @@ func TestChildren(t *testing.T) {
 		require.Equal(t, 1, len(children))
 		require.Equal(t, testZPool+"/snapshot-test@test", children[0].Name)
 		require.Len(t, children[0].ExtraProps, 1)
-		require.Equal(t, map[string]string{PropertyFilesystemCount: ""}, children[0].ExtraProps)
+		require.Equal(t, Properties{PropertyFilesystemCount: ""}, children[0].ExtraProps)
 
 		require.NoError(t, s.Destroy(context.Background(), DestroyOptions{}))
 		require.NoError(t, f.Destroy(context.Background(), DestroyOptions{}))
@@ -505,7 +706,7 @@ func TestChildren(t *testing.T) {
 }
 
 func TestRollback(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		f, err := CreateFilesystem(context.Background(), testZPool+"/snapshot-test", CreateFilesystemOptions{
 			Properties: noMountProps,
 		})
@@ -542,7 +743,7 @@ func TestRollback(t *testing.T) {
 }
 
 func TestDataset_LoadKey_UnloadKey(t *testing.T) {
-	TestZPool(testZPool, func() {
+	zfstest.Run(t, testZPool, func(*zfstest.Pool) {
 		encKey := make([]byte, 32)
 		_, _ = rand.Read(encKey)
 
@@ -576,3 +777,319 @@ func TestDataset_LoadKey_UnloadKey(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func Test_GetPropertyRecursive(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"get", "-r", "-Hp", "-o", "name,source,value", "nl.test:retention", "pool/fs"}, args)
+		_, err := io.WriteString(stdout, "pool/fs\tlocal\t7\tdays\n"+
+			"pool/fs/child\tinherited from pool/fs\t7\tdays\n"+
+			"pool/fs/recv\treceived\t30\n"+
+			"pool/fs/other\t-\t-\n")
+		return "", err
+	}))
+
+	props, err := GetPropertyRecursive(ctx, "pool/fs", "nl.test:retention")
+	require.NoError(t, err)
+	require.Equal(t, map[string]PropertyValue{
+		"pool/fs":       {Value: "7\tdays", Source: PropertySourceLocal},
+		"pool/fs/child": {Value: "7\tdays", Source: PropertySourceInherited, InheritedFrom: "pool/fs"},
+		"pool/fs/recv":  {Value: "30", Source: PropertySourceReceived},
+		"pool/fs/other": {Value: "-", Source: PropertySourceNone},
+	}, props)
+}
+
+func Test_IterateDatasets(t *testing.T) {
+	output := "pool/fs\tname\tpool/fs\npool/fs\tused\t100\n" +
+		"pool/fs/a\tname\tpool/fs/a\npool/fs/a\tused\t200\n" +
+		"pool/fs/b\tname\tpool/fs/b\npool/fs/b\tused\t300\n"
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"get", "-Hp", "-o", "name,property,value", "-r", "name,used", "pool/fs"}, args)
+		_, err := io.WriteString(stdout, output)
+		return "", err
+	}))
+	options := ListOptions{
+		ParentDataset: "pool/fs",
+		Recursive:     true,
+		FilterSelf:    true,
+		Fields:        []string{PropertyName, PropertyUsed},
+	}
+
+	var names []string
+	var used uint64
+	err := IterateDatasets(ctx, options, func(ds Dataset) error {
+		names = append(names, ds.Name)
+		used += ds.Used
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/fs/a", "pool/fs/b"}, names)
+	require.EqualValues(t, 500, used)
+
+	errStop := errors.New("stop")
+	names = nil
+	err = IterateDatasets(ctx, options, func(ds Dataset) error {
+		names = append(names, ds.Name)
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, []string{"pool/fs/a"}, names)
+
+	output = "pool/fs\tname\tpool/fs\npool/fs/a\tname\tpool/fs/a\npool/fs/a\tused\t200\n"
+	err = IterateDatasets(ctx, options, func(Dataset) error { return nil })
+	require.Error(t, err)
+}
+
+func Test_ListDatasetsSorted(t *testing.T) {
+	var written []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"list", "-Hp", "-o", "name,creation", "-S", "creation", "-t", "snapshot", "-d", "1", "pool/fs"}, args)
+		written = nil
+		for _, row := range []string{"pool/fs@c\t1700000900\n", "pool/fs@b\t1700000600\n", "pool/fs@a\t1700000000\n"} {
+			_, err := io.WriteString(stdout, row)
+			if err != nil {
+				return "", err // The listing was stopped
+			}
+			written = append(written, row)
+		}
+		return "", nil
+	}))
+
+	options := ListOptions{
+		ParentDataset:  "pool/fs",
+		DatasetType:    DatasetSnapshot,
+		Depth:          1,
+		Fields:         []string{PropertyName, PropertyCreation},
+		SortBy:         PropertyCreation,
+		SortDescending: true,
+	}
+	snaps, err := ListDatasets(ctx, options)
+	require.NoError(t, err)
+	require.Len(t, snaps, 3)
+	require.Equal(t, "pool/fs@c", snaps[0].Name)
+	require.Equal(t, time.Unix(1700000900, 0), snaps[0].Creation)
+	require.Equal(t, "pool/fs@a", snaps[2].Name)
+
+	// The listing stops once the limit is reached
+	options.Limit = 1
+	var names []string
+	require.NoError(t, IterateDatasets(ctx, options, func(ds Dataset) error {
+		names = append(names, ds.Name)
+		return nil
+	}))
+	require.Equal(t, []string{"pool/fs@c"}, names)
+	require.Less(t, len(written), 3)
+}
+
+func Test_ListDatasetsPaginated(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, _ []string, _ io.Reader, stdout io.Writer) (string, error) {
+		for _, name := range []string{"pool/fs@a", "pool/fs@b", "pool/fs@c", "pool/fs@d"} {
+			_, err := fmt.Fprintf(stdout, "%s\tname\t%s\n", name, name)
+			if err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	}))
+
+	for _, test := range []struct {
+		offset, limit int
+		expected      []string
+	}{
+		{0, 0, []string{"pool/fs@a", "pool/fs@b", "pool/fs@c", "pool/fs@d"}},
+		{1, 2, []string{"pool/fs@b", "pool/fs@c"}},
+		{3, 5, []string{"pool/fs@d"}},
+		{5, 1, nil},
+	} {
+		options := ListOptions{
+			ParentDataset: "pool/fs",
+			DatasetType:   DatasetSnapshot,
+			Fields:        []string{PropertyName},
+			Offset:        test.offset,
+			Limit:         test.limit,
+		}
+		snaps, err := ListDatasets(ctx, options)
+		require.NoError(t, err)
+		var names []string
+		for _, snap := range snaps {
+			names = append(names, snap.Name)
+		}
+		require.Equal(t, test.expected, names)
+
+		names = nil
+		require.NoError(t, IterateDatasets(ctx, options, func(ds Dataset) error {
+			names = append(names, ds.Name)
+			return nil
+		}))
+		require.Equal(t, test.expected, names)
+	}
+}
+
+func Test_Rename(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	require.NoError(t, fs.Rename(ctx, "pool/new/fs", RenameOptions{CreateParent: true, Force: true}))
+	require.NoError(t, snap.Rename(ctx, "b", RenameOptions{Recursive: true}))
+	require.NoError(t, snap.Rename(ctx, "@c", RenameOptions{}))
+	require.NoError(t, snap.Rename(ctx, "pool/fs@d", RenameOptions{}))
+	require.Equal(t, [][]string{
+		{"rename", "-p", "-f", "pool/fs", "pool/new/fs"},
+		{"rename", "-r", "pool/fs@a", "pool/fs@b"},
+		{"rename", "pool/fs@a", "pool/fs@c"},
+		{"rename", "pool/fs@a", "pool/fs@d"},
+	}, executed)
+
+	require.ErrorIs(t, fs.Rename(ctx, "pool/fs2", RenameOptions{Recursive: true}), ErrOnlySnapshotsSupported)
+	require.Error(t, snap.Rename(ctx, "pool/other@a", RenameOptions{}))
+	require.Error(t, snap.Rename(ctx, "b", RenameOptions{NoMount: true}))
+	require.Len(t, executed, 4)
+}
+
+func Test_RollbackTo(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	fs := &Dataset{Name: "pool/fs", Type: DatasetFilesystem}
+	require.NoError(t, fs.RollbackTo(ctx, "a", RollbackOptions{DestroyMoreRecent: true}))
+	require.NoError(t, fs.RollbackTo(ctx, "@b", RollbackOptions{DestroyMoreRecentClones: true, Force: true}))
+	require.Equal(t, [][]string{
+		{"rollback", "-r", "pool/fs@a"},
+		{"rollback", "-R", "-f", "pool/fs@b"},
+	}, executed)
+
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	require.Error(t, snap.RollbackTo(ctx, "b", RollbackOptions{}))
+}
+
+func Test_DestroyDryRun(t *testing.T) {
+	var executed []string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = args
+		_, err := io.WriteString(stdout, "destroy\tpool/fs@a\ndestroy\tpool/fs/child@a\nreclaim\t12345\n")
+		return "", err
+	}))
+
+	snap := &Dataset{Name: "pool/fs@a", Type: DatasetSnapshot}
+	estimate, err := snap.DestroyDryRun(ctx, DestroyOptions{Recursive: true, DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"destroy", "-n", "-p", "-v", "-r", "pool/fs@a"}, executed)
+	require.Equal(t, &DestroyEstimate{
+		Datasets: []string{"pool/fs@a", "pool/fs/child@a"},
+		Reclaim:  12345,
+	}, estimate)
+
+	require.NoError(t, snap.Destroy(ctx, DestroyOptions{DryRun: true, Defer: true}))
+	require.Equal(t, []string{"destroy", "-n", "-d", "pool/fs@a"}, executed)
+}
+
+func Test_DestroySnapshotRange(t *testing.T) {
+	var executed [][]string
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, _ io.Writer) (string, error) {
+		executed = append(executed, args)
+		return "", nil
+	}))
+
+	require.NoError(t, DestroySnapshotRange(ctx, "pool/fs", "a", "pool/fs@e", DestroyOptions{Recursive: true}))
+	require.NoError(t, DestroySnapshotRange(ctx, "pool/fs", "@a", "a", DestroyOptions{}))
+	require.NoError(t, DestroySnapshotRange(ctx, "pool/fs", "", "e", DestroyOptions{Defer: true}))
+	require.Equal(t, [][]string{
+		{"destroy", "-r", "pool/fs@a%e"},
+		{"destroy", "pool/fs@a"},
+		{"destroy", "-d", "pool/fs@%e"},
+	}, executed)
+
+	err := DestroySnapshotRange(ctx, "pool/fs", "", "", DestroyOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshotRange)
+	err = DestroySnapshotRange(ctx, "pool/fs", "pool/fs@", "@", DestroyOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshotRange)
+	err = DestroySnapshotRange(ctx, "pool/fs@a", "a", "e", DestroyOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshotRange)
+	err = DestroySnapshotRange(ctx, "pool/fs", "pool/other@a", "e", DestroyOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshotRange)
+	err = DestroySnapshotRange(ctx, "pool/fs", "a", "pool/fs/child@e", DestroyOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshotRange)
+	require.Len(t, executed, 3)
+}
+
+func Test_DatasetExists(t *testing.T) {
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		require.Equal(t, []string{"get", "-Hp", "-o", "name,property,value", "name,type", args[len(args)-1]}, args)
+		switch args[len(args)-1] {
+		case "pool/fs":
+			_, err := io.WriteString(stdout, "pool/fs\tname\tpool/fs\npool/fs\ttype\tfilesystem\n")
+			return "", err
+		case "pool/err":
+			return "cannot open 'pool/err': permission denied", errors.New("exit status 1")
+		}
+		return "cannot open 'pool/missing': dataset does not exist", errors.New("exit status 1")
+	}))
+
+	exists, err := DatasetExists(ctx, "pool/fs", "")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = DatasetExists(ctx, "pool/fs", DatasetFilesystem)
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = DatasetExists(ctx, "pool/fs", DatasetVolume)
+	require.NoError(t, err)
+	require.False(t, exists)
+	exists, err = DatasetExists(ctx, "pool/missing", "")
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = DatasetExists(ctx, "pool/err", "")
+	require.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func Test_GetDatasetsBatches(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	ctx := WithExecutor(context.Background(), executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		names := args[5:]
+		require.LessOrEqual(t, len(names), getDatasetsBatchSize)
+		for _, name := range names {
+			if name == "pool/missing" {
+				return "cannot open 'pool/missing': dataset does not exist", errors.New("exit status 1")
+			}
+			for _, prop := range dsPropList {
+				value := ValueUnset
+				switch prop {
+				case PropertyName:
+					value = name
+				case PropertyType:
+					value = string(DatasetSnapshot)
+				}
+				_, err := fmt.Fprintf(stdout, "%s\t%s\t%s\n", name, prop, value)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+		return "", nil
+	}))
+
+	names := make([]string, 2500)
+	for i := range names {
+		names[i] = fmt.Sprintf("pool/fs@%04d", i)
+	}
+	ds, err := GetDatasets(ctx, names)
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Len(t, ds, len(names))
+	for i := range ds {
+		require.Equal(t, names[i], ds[i].Name)
+	}
+
+	_, err = GetDatasets(ctx, append(names, "pool/missing"))
+	require.ErrorIs(t, err, ErrDatasetNotFound)
+}
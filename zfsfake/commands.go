@@ -0,0 +1,764 @@
+package zfsfake
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// pool returns the name of the pool the dataset is in
+func pool(name string) string {
+	if idx := strings.IndexAny(name, "@#/"); idx >= 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+// ensureParent checks whether the parent of the dataset exists, creating missing parents when create is set.
+// The action describes the command in the error, like: cannot create 'pool/fs'
+func (f *Fake) ensureParent(name string, create bool, action string) error {
+	var missing []string
+	for p := parent(name); p != ""; p = parent(p) {
+		if _, ok := f.datasets[p]; ok {
+			break
+		}
+		missing = append(missing, p)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	top := missing[len(missing)-1]
+	if parent(top) == "" {
+		return fail("%s: no such pool '%s'", action, top)
+	}
+	if !create {
+		return fail("%s: parent does not exist", action)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		f.add(missing[i], zfs.DatasetFilesystem, nil).mounted = true
+	}
+	return nil
+}
+
+// remove destroys the datasets. When the list contains snapshots with clones outside the list, the clones and their
+// descendants are destroyed as well if withClones is set, otherwise an error is returned. A dry run only checks this.
+func (f *Fake) remove(list []*dataset, withClones, dryRun bool, action string) error {
+	names := make(map[string]bool, len(list))
+	for _, ds := range list {
+		names[ds.name] = true
+	}
+	for i := 0; i < len(list); i++ {
+		if list[i].typ != zfs.DatasetSnapshot {
+			continue
+		}
+		for _, clone := range f.clones(list[i].name) {
+			if names[clone] {
+				continue
+			}
+			if !withClones {
+				return fail("%s: snapshot has dependent clones\nuse '-R' to destroy the following datasets:\n%s",
+					action, strings.Join(f.clones(list[i].name), "\n"))
+			}
+			for _, ds := range f.descendants(f.datasets[clone]) {
+				names[ds.name] = true
+				list = append(list, ds)
+			}
+		}
+	}
+
+	for _, ds := range list {
+		if len(ds.holds) > 0 {
+			return fail("cannot destroy snapshot %s: dataset is busy", ds.name)
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	for name := range names {
+		delete(f.datasets, name)
+	}
+	return nil
+}
+
+// move renames a single dataset, updating the origin of its clones
+func (f *Fake) move(oldName, newName string) {
+	ds := f.datasets[oldName]
+	delete(f.datasets, oldName)
+	ds.name = newName
+	f.datasets[newName] = ds
+
+	for _, other := range f.datasets {
+		if other.origin == oldName {
+			other.origin = newName
+		}
+	}
+}
+
+// create implements zfs create [-punsv] [-o property=value]... [-V size] dataset
+func (f *Fake) create(args []string, stdin io.Reader) error {
+	flags, operands, err := parseArgs(args, "punsvP", "oVb")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single dataset name")
+	}
+	name := operands[0]
+	action := fmt.Sprintf("cannot create '%s'", name)
+	if strings.Contains(name, "@") {
+		return fail("%s: snapshot delimiter '@' is not expected here", action)
+	}
+
+	props, err := parseProperties(flags['o'])
+	if err != nil {
+		return err
+	}
+	typ := zfs.DatasetFilesystem
+	var volsize uint64
+	for _, value := range flags['V'] {
+		typ = zfs.DatasetVolume
+		volsize, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fail("bad volume size '%s'", value)
+		}
+	}
+	if stdin != nil {
+		_, err = io.Copy(io.Discard, stdin) // Key material
+		if err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.datasets[name]; ok {
+		if has(flags, 'p') {
+			return nil
+		}
+		return fail("%s: dataset already exists", action)
+	}
+	if parent(name) == "" {
+		return fail("%s: no such pool '%s'", action, name)
+	}
+	err = f.ensureParent(name, has(flags, 'p') && !has(flags, 'n'), action)
+	if err != nil || has(flags, 'n') {
+		return err
+	}
+
+	ds := f.add(name, typ, nil)
+	ds.volsize = volsize
+	for prop, value := range props {
+		switch prop {
+		case zfs.PropertyEncryption:
+			ds.props[prop] = value
+			ds.keyLoaded = value != zfs.ValueOff
+		default:
+			err = f.setProperty(ds, prop, value)
+		}
+		if err != nil {
+			delete(f.datasets, name)
+			return err
+		}
+	}
+
+	canMount, _ := f.property(ds, zfs.PropertyCanMount)
+	ds.mounted = typ == zfs.DatasetFilesystem && !has(flags, 'u') && canMount == zfs.ValueOn
+	return nil
+}
+
+// snapshot implements zfs snapshot [-r] [-o property=value]... snapshot...
+func (f *Fake) snapshot(args []string) error {
+	flags, operands, err := parseArgs(args, "r", "o")
+	if err != nil {
+		return err
+	}
+	if len(operands) == 0 {
+		return fail("missing snapshot argument")
+	}
+	props, err := parseProperties(flags['o'])
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var snapNames []string
+	for _, name := range operands {
+		fsName, snap, ok := strings.Cut(name, "@")
+		if !ok || snap == "" {
+			return fail("cannot create snapshot '%s': missing '@' delimiter in snapshot name", name)
+		}
+		ds, err := f.lookup(fsName)
+		if err != nil {
+			return err
+		}
+
+		list := []*dataset{ds}
+		if has(flags, 'r') {
+			list = f.descendants(ds)
+		}
+		for _, ds := range list {
+			if ds.typ == zfs.DatasetSnapshot {
+				continue
+			}
+			snapName := ds.name + "@" + snap
+			if _, ok := f.datasets[snapName]; ok {
+				return fail("cannot create snapshot '%s': dataset already exists", snapName)
+			}
+			snapNames = append(snapNames, snapName)
+		}
+	}
+	for prop := range props {
+		if !isUserProperty(prop) {
+			return fail("cannot create snapshot '%s': property '%s' can not be set for snapshots", operands[0], prop)
+		}
+	}
+
+	for _, snapName := range snapNames {
+		f.add(snapName, zfs.DatasetSnapshot, props)
+	}
+	return nil
+}
+
+// bookmark implements zfs bookmark snapshot|bookmark bookmark
+func (f *Fake) bookmark(args []string) error {
+	_, operands, err := parseArgs(args, "", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 2 {
+		return fail("expected a snapshot or bookmark and a bookmark name")
+	}
+	source, name := operands[0], operands[1]
+	action := fmt.Sprintf("cannot create bookmark '%s'", name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(source, "#") || strings.HasPrefix(source, "@") {
+		source = parent(name) + source
+	}
+	ds, err := f.lookup(source)
+	if err != nil {
+		return err
+	}
+	if ds.typ != zfs.DatasetSnapshot && ds.typ != zfs.DatasetBookmark {
+		return fail("%s: source must be a snapshot or bookmark", action)
+	}
+	if !strings.Contains(name, "#") {
+		return fail("%s: missing '#' delimiter in bookmark name", action)
+	}
+	if parent(name) != parent(source) {
+		return fail("%s: source is not an ancestor of the new bookmark's dataset", action)
+	}
+	if _, ok := f.datasets[name]; ok {
+		return fail("%s: bookmark exists", action)
+	}
+
+	bookmark := &dataset{
+		name:    name,
+		typ:     zfs.DatasetBookmark,
+		props:   make(map[string]string),
+		guid:    ds.guid,
+		txg:     ds.txg,
+		created: ds.created,
+	}
+	f.datasets[name] = bookmark
+	return nil
+}
+
+// destroy implements zfs destroy [-rRdfnpv] dataset|snapshot|filesystem@first%last[,...]
+func (f *Fake) destroy(args []string, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "rRdfnpv", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single dataset name")
+	}
+	name := operands[0]
+	action := fmt.Sprintf("cannot destroy '%s'", name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if fsName, snapRanges, ok := strings.Cut(name, "@"); ok && strings.ContainsAny(snapRanges, "%,") {
+		var list []*dataset
+		for _, snapRange := range strings.Split(snapRanges, ",") {
+			snaps, err := f.snapshotRange(fsName, snapRange)
+			if err != nil {
+				return err
+			}
+			for _, snap := range snaps {
+				if !slices.Contains(list, snap) {
+					list = append(list, snap)
+				}
+			}
+		}
+		return f.removeVerbose(list, flags, action, stdout)
+	}
+
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if parent(name) == "" {
+		return fail("%s: operation does not apply to pools\nuse 'zfs destroy -r %s' to destroy all datasets in the pool\n"+
+			"use 'zpool destroy %s' to destroy the pool itself", action, name, name)
+	}
+
+	if ds.typ != zfs.DatasetSnapshot {
+		list := f.descendants(ds)
+		if len(list) > 1 && !has(flags, 'r') && !has(flags, 'R') {
+			names := make([]string, 0, len(list)-1)
+			for _, child := range list[1:] {
+				names = append(names, child.name)
+			}
+			return fail("%s: filesystem has children\nuse '-r' to destroy the following datasets:\n%s",
+				action, strings.Join(names, "\n"))
+		}
+		return f.removeVerbose(list, flags, action, stdout)
+	}
+
+	if has(flags, 'd') && len(f.clones(name)) > 0 {
+		return nil // Destroyed once the clones are gone, which the fake does not track
+	}
+	list := []*dataset{ds}
+	if has(flags, 'r') {
+		fsName, snap, _ := strings.Cut(name, "@")
+		for _, child := range f.descendants(f.datasets[fsName]) {
+			if snapDS, ok := f.datasets[child.name+"@"+snap]; ok && child.name != fsName {
+				list = append(list, snapDS)
+			}
+		}
+	}
+	return f.removeVerbose(list, flags, action, stdout)
+}
+
+// snapshotRange returns the snapshots of the filesystem from the first up to and including the last snapshot,
+// given as first%last, or the single snapshot without a %. An empty first or last snapshot means the oldest or the
+// newest snapshot.
+func (f *Fake) snapshotRange(fsName, snapRange string) ([]*dataset, error) {
+	first, last, found := strings.Cut(snapRange, "%")
+	if !found {
+		if first == "" {
+			return nil, fail("could not find any snapshots to destroy; check snapshot names.")
+		}
+		last = first
+	}
+	snaps := f.snapshots(fsName)
+	from, to := 0, len(snaps)-1
+	for i, snap := range snaps {
+		_, snapName, _ := strings.Cut(snap.name, "@")
+		if snapName == first {
+			from = i
+		}
+		if snapName == last {
+			to = i
+		}
+	}
+	for _, name := range []string{first, last} {
+		if _, ok := f.datasets[fsName+"@"+name]; name != "" && !ok {
+			return nil, fail("could not find any snapshots to destroy; check snapshot names.")
+		}
+	}
+	if from > to {
+		return nil, fail("could not find any snapshots to destroy; check snapshot names.")
+	}
+	return snaps[from : to+1], nil
+}
+
+// removeVerbose removes the datasets, with -v it reports them and the space reclaimed like zfs does
+func (f *Fake) removeVerbose(list []*dataset, flags map[byte][]string, action string, stdout io.Writer) error {
+	err := f.remove(list, has(flags, 'R'), has(flags, 'n'), action)
+	if err != nil || !has(flags, 'v') {
+		return err
+	}
+
+	verb := "will"
+	if has(flags, 'n') {
+		verb = "would"
+	}
+	var reclaim uint64
+	for _, ds := range list {
+		used, _ := f.property(ds, zfs.PropertyUsed)
+		size, _ := strconv.ParseUint(used, 10, 64)
+		reclaim += size
+		if has(flags, 'p') {
+			_, err = fmt.Fprintf(stdout, "destroy\t%s\n", ds.name)
+		} else {
+			_, err = fmt.Fprintf(stdout, "%s destroy %s\n", verb, ds.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if has(flags, 'p') {
+		_, err = fmt.Fprintf(stdout, "reclaim\t%d\n", reclaim)
+	} else {
+		_, err = fmt.Fprintf(stdout, "%s reclaim %d\n", verb, reclaim)
+	}
+	return err
+}
+
+// rename implements zfs rename [-fpru] dataset|snapshot newname
+func (f *Fake) rename(args []string) error {
+	flags, operands, err := parseArgs(args, "fpru", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 2 {
+		return fail("expected the dataset and its new name")
+	}
+	oldName, newName := operands[0], operands[1]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.lookup(oldName)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(newName, "@") {
+		newName = parent(oldName) + newName
+	}
+	action := fmt.Sprintf("cannot rename to '%s'", newName)
+	if _, ok := f.datasets[newName]; ok {
+		return fail("%s: dataset already exists", action)
+	}
+	if pool(oldName) != pool(newName) {
+		return fail("%s: datasets must be within same pool", action)
+	}
+
+	if ds.typ == zfs.DatasetSnapshot {
+		if !strings.Contains(newName, "@") || parent(newName) != parent(oldName) {
+			return fail("%s: snapshots must be part of same dataset", action)
+		}
+		_, oldSnap, _ := strings.Cut(oldName, "@")
+		_, newSnap, _ := strings.Cut(newName, "@")
+
+		f.move(oldName, newName)
+		if has(flags, 'r') {
+			for _, child := range f.descendants(f.datasets[parent(oldName)]) {
+				if _, ok := f.datasets[child.name+"@"+oldSnap]; ok {
+					f.move(child.name+"@"+oldSnap, child.name+"@"+newSnap)
+				}
+			}
+		}
+		return nil
+	}
+
+	if strings.Contains(newName, "@") {
+		return fail("%s: snapshot delimiter '@' is not expected here", action)
+	}
+	if strings.HasPrefix(newName, oldName+"/") {
+		return fail("%s: New dataset name cannot be a descendant of current dataset name", action)
+	}
+	if parent(oldName) == "" || parent(newName) == "" {
+		return fail("%s: operation does not apply to pools", action)
+	}
+	err = f.ensureParent(newName, has(flags, 'p'), action)
+	if err != nil {
+		return err
+	}
+	for _, child := range f.descendants(ds) {
+		f.move(child.name, newName+strings.TrimPrefix(child.name, oldName))
+	}
+	return nil
+}
+
+// clone implements zfs clone [-p] [-o property=value]... snapshot filesystem|volume
+func (f *Fake) clone(args []string) error {
+	flags, operands, err := parseArgs(args, "p", "o")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 2 {
+		return fail("expected the snapshot and the clone name")
+	}
+	snapName, name := operands[0], operands[1]
+	action := fmt.Sprintf("cannot create '%s'", name)
+	props, err := parseProperties(flags['o'])
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snap, err := f.lookup(snapName)
+	if err != nil {
+		return err
+	}
+	if snap.typ != zfs.DatasetSnapshot {
+		return fail("cannot open '%s': operation only applies to snapshots", snapName)
+	}
+	if _, ok := f.datasets[name]; ok {
+		return fail("%s: dataset already exists", action)
+	}
+	if pool(snapName) != pool(name) {
+		return fail("%s: source and target pools differ", action)
+	}
+	err = f.ensureParent(name, has(flags, 'p'), action)
+	if err != nil {
+		return err
+	}
+
+	origin := f.datasets[parent(snapName)]
+	ds := f.add(name, origin.typ, nil)
+	ds.origin = snapName
+	ds.volsize = origin.volsize
+	for prop, value := range props {
+		err = f.setProperty(ds, prop, value)
+		if err != nil {
+			delete(f.datasets, name)
+			return err
+		}
+	}
+	canMount, _ := f.property(ds, zfs.PropertyCanMount)
+	ds.mounted = ds.typ == zfs.DatasetFilesystem && canMount == zfs.ValueOn
+	return nil
+}
+
+// promote implements zfs promote clone, moving the snapshots up to the origin from the origin filesystem to the clone
+func (f *Fake) promote(args []string) error {
+	if len(args) != 1 {
+		return fail("expected a single dataset name")
+	}
+	name := args[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if ds.origin == "" {
+		return fail("cannot promote '%s': not a cloned filesystem", name)
+	}
+
+	originSnap := f.datasets[ds.origin]
+	originFS := f.datasets[parent(ds.origin)]
+	var moving []*dataset
+	for _, snap := range f.snapshots(originFS.name) {
+		if snap.txg > originSnap.txg {
+			break
+		}
+		_, snapName, _ := strings.Cut(snap.name, "@")
+		if _, ok := f.datasets[name+"@"+snapName]; ok {
+			return fail("cannot promote '%s': snapshot name '%s' from origin conflicts with '%s' from target",
+				name, snap.name, name+"@"+snapName)
+		}
+		moving = append(moving, snap)
+	}
+
+	previousOrigin := originFS.origin
+	for _, snap := range moving {
+		_, snapName, _ := strings.Cut(snap.name, "@")
+		f.move(snap.name, name+"@"+snapName)
+	}
+	originFS.origin = originSnap.name
+	ds.origin = previousOrigin
+	return nil
+}
+
+// rollback implements zfs rollback [-rRf] snapshot
+func (f *Fake) rollback(args []string) error {
+	flags, operands, err := parseArgs(args, "rRf", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single snapshot name")
+	}
+	name := operands[0]
+	action := fmt.Sprintf("cannot rollback to '%s'", name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snap, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if snap.typ != zfs.DatasetSnapshot {
+		return fail("cannot open '%s': operation only applies to snapshots", name)
+	}
+
+	var newer []*dataset
+	var names []string
+	for _, other := range f.snapshots(parent(name)) {
+		if other.txg > snap.txg {
+			newer = append(newer, other)
+			names = append(names, other.name)
+		}
+	}
+	if len(newer) > 0 && !has(flags, 'r') && !has(flags, 'R') {
+		return fail("%s: more recent snapshots or bookmarks exist\n"+
+			"use '-r' to force deletion of the following snapshots and bookmarks:\n%s", action, strings.Join(names, "\n"))
+	}
+	return f.remove(newer, has(flags, 'R'), false, action)
+}
+
+// mount implements zfs mount [-Ol] [-o options] filesystem
+func (f *Fake) mount(args []string) error {
+	flags, operands, err := parseArgs(args, "Olfv", "o")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single filesystem name")
+	}
+	name := operands[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if ds.typ != zfs.DatasetFilesystem {
+		return fail("cannot mount '%s': operation not applicable to datasets of this type", name)
+	}
+	if ds.mounted {
+		return fail("cannot mount '%s': filesystem already mounted", name)
+	}
+	if keyStatus, _ := f.property(ds, zfs.PropertyKeyStatus); keyStatus == "unavailable" {
+		if !has(flags, 'l') {
+			return fail("cannot mount '%s': encryption key not loaded", name)
+		}
+		ds.keyLoaded = true
+	}
+	ds.mounted = true
+	ds.mountOptions = mountOptions(flags['o'])
+	return nil
+}
+
+// mountOptions returns the temporary property values for the mount options
+func mountOptions(options []string) map[string]string {
+	values := make(map[string]string)
+	for _, list := range options {
+		for _, option := range strings.Split(list, ",") {
+			switch option {
+			case "ro":
+				values[zfs.PropertyReadOnly] = zfs.ValueOn
+			case "rw":
+				values[zfs.PropertyReadOnly] = zfs.ValueOff
+			default:
+				if mountpoint, ok := strings.CutPrefix(option, zfs.PropertyMountPoint+"="); ok {
+					values[zfs.PropertyMountPoint] = mountpoint
+				}
+			}
+		}
+	}
+	return values
+}
+
+// unmount implements zfs umount [-fu] filesystem
+func (f *Fake) unmount(args []string) error {
+	flags, operands, err := parseArgs(args, "fu", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single filesystem name")
+	}
+	name := operands[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if !ds.mounted {
+		return fail("cannot unmount '%s': not currently mounted", name)
+	}
+	ds.mounted = false
+	ds.mountOptions = nil
+	if has(flags, 'u') {
+		ds.keyLoaded = false
+	}
+	return nil
+}
+
+// loadKey implements zfs load-key [-rn] [-L keylocation] filesystem, reading the key from stdin
+func (f *Fake) loadKey(args []string, stdin io.Reader) error {
+	flags, operands, err := parseArgs(args, "rn", "L")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single dataset name")
+	}
+	if stdin != nil {
+		_, err = io.Copy(io.Discard, stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setKeys(operands[0], has(flags, 'r'), !has(flags, 'n'), true)
+}
+
+// unloadKey implements zfs unload-key [-r] filesystem
+func (f *Fake) unloadKey(args []string) error {
+	flags, operands, err := parseArgs(args, "r", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single dataset name")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setKeys(operands[0], has(flags, 'r'), true, false)
+}
+
+// setKeys loads or unloads the keys of the dataset, and its encrypted descendants when recursive is set
+func (f *Fake) setKeys(name string, recursive, apply, loaded bool) error {
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if encryption, _ := f.property(ds, zfs.PropertyEncryption); encryption == zfs.ValueOff {
+		return fail("Key load error: Keys must be loaded for encryption root of '%s'.", name)
+	}
+	switch {
+	case loaded && ds.keyLoaded:
+		return fail("Key load error: Key already loaded for '%s'.", name)
+	case !loaded && !ds.keyLoaded:
+		return fail("Key unload error: Key already unloaded for '%s'.", name)
+	case !loaded && ds.mounted:
+		return fail("Key unload error: '%s' is busy.", name)
+	case !apply:
+		return nil
+	}
+
+	list := []*dataset{ds}
+	if recursive {
+		list = f.descendants(ds)
+	}
+	for _, ds := range list {
+		if encryption, _ := f.property(ds, zfs.PropertyEncryption); encryption != zfs.ValueOff {
+			ds.keyLoaded = loaded
+		}
+	}
+	return nil
+}
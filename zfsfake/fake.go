@@ -0,0 +1,373 @@
+// Package zfsfake provides an in-memory fake of the zfs command, so code using this package can be unit tested
+// without ZFS. The fake keeps a tree of datasets with their snapshots and properties, and sends and receives
+// small canned streams, which it understands on the receiving side.
+//
+// Install the fake for a test using Install, or for the whole process with zfs.SetExecutor(zfsfake.New("pool")).
+package zfsfake
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// errExitStatus is returned for commands that fail, like the zfs binary exiting with a non-zero status
+var errExitStatus = errors.New("exit status 1")
+
+// failure is a command failure, with the message the zfs binary would write to stderr
+type failure struct {
+	stderr string
+}
+
+func (f *failure) Error() string {
+	return f.stderr
+}
+
+func fail(format string, args ...any) error {
+	return &failure{stderr: fmt.Sprintf(format, args...)}
+}
+
+// Fake is an in-memory fake of the zfs command, it implements zfs.Executor
+type Fake struct {
+	mu       sync.Mutex
+	datasets map[string]*dataset
+	txg      uint64
+	failures map[string][]string
+	commands [][]string
+}
+
+type dataset struct {
+	name      string
+	typ       zfs.DatasetType
+	origin    string
+	props     map[string]string
+	volsize   uint64
+	guid      uint64
+	txg       uint64
+	created   time.Time
+	mounted   bool
+	keyLoaded bool
+	// mountOptions are the temporary property values of the mount
+	mountOptions map[string]string
+	// holds are the creation times of the holds on a snapshot, indexed by tag
+	holds map[string]time.Time
+}
+
+// New creates a fake with a pool for each of the given names
+func New(pools ...string) *Fake {
+	f := &Fake{
+		datasets: make(map[string]*dataset, 16),
+		failures: make(map[string][]string),
+	}
+	for _, pool := range pools {
+		f.add(pool, zfs.DatasetFilesystem, nil).mounted = true
+	}
+	return f
+}
+
+// Install creates a fake with the given pools and sets it as the zfs executor until the test ends.
+// Tests using it cannot run in parallel with other tests using the zfs package.
+func Install(t testing.TB, pools ...string) *Fake {
+	t.Helper()
+	f := New(pools...)
+	zfs.SetExecutor(f)
+	t.Cleanup(func() {
+		zfs.SetExecutor(nil)
+	})
+	return f
+}
+
+// FailNext makes the next command with the given subcommand, like send or destroy, fail with the given stderr output.
+// Calling it multiple times for a subcommand fails that many commands, in order.
+func (f *Fake) FailNext(subcommand, stderr string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[subcommand] = append(f.failures[subcommand], stderr)
+}
+
+// Commands returns the arguments of all commands executed so far, in order
+func (f *Fake) Commands() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cmds := make([][]string, len(f.commands))
+	for i := range f.commands {
+		cmds[i] = slices.Clone(f.commands[i])
+	}
+	return cmds
+}
+
+// Execute runs a zfs command against the in-memory datasets
+func (f *Fake) Execute(ctx context.Context, _ string, args []string, stdin io.Reader, stdout io.Writer) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "missing command", errExitStatus
+	}
+
+	f.mu.Lock()
+	f.commands = append(f.commands, slices.Clone(args))
+	if failures := f.failures[args[0]]; len(failures) > 0 {
+		f.failures[args[0]] = failures[1:]
+		f.mu.Unlock()
+		return failures[0], errExitStatus
+	}
+	f.mu.Unlock()
+
+	// Output is written once the datasets are unlocked again, as the caller may run other commands while reading it.
+	// Send unlocks them itself before writing the stream.
+	output := stdout
+	var buf bytes.Buffer
+	if args[0] != "send" {
+		output = &buf
+	}
+	err := f.dispatch(ctx, args[0], args[1:], stdin, output)
+	if buf.Len() > 0 {
+		_, writeErr := buf.WriteTo(stdout)
+		if err == nil && writeErr != nil {
+			err = writeErr
+		}
+	}
+	var failed *failure
+	switch {
+	case errors.As(err, &failed):
+		return failed.stderr, errExitStatus
+	case err != nil:
+		return err.Error(), err
+	}
+	return "", nil
+}
+
+func (f *Fake) dispatch(ctx context.Context, subcommand string, args []string, stdin io.Reader, stdout io.Writer) error {
+	switch subcommand {
+	case "get":
+		return f.get(args, stdout)
+	case "list":
+		return f.list(args, stdout)
+	case "set":
+		return f.set(args)
+	case "inherit":
+		return f.inherit(args)
+	case "create":
+		return f.create(args, stdin)
+	case "snapshot":
+		return f.snapshot(args)
+	case "destroy":
+		return f.destroy(args, stdout)
+	case "rename":
+		return f.rename(args)
+	case "clone":
+		return f.clone(args)
+	case "promote":
+		return f.promote(args)
+	case "hold":
+		return f.hold(args)
+	case "release":
+		return f.release(args)
+	case "bookmark":
+		return f.bookmark(args)
+	case "holds":
+		return f.holds(args, stdout)
+	case "rollback":
+		return f.rollback(args)
+	case "mount":
+		return f.mount(args)
+	case "umount":
+		return f.unmount(args)
+	case "load-key":
+		return f.loadKey(args, stdin)
+	case "unload-key":
+		return f.unloadKey(args)
+	case "send":
+		return f.send(ctx, args, stdout)
+	case "receive":
+		return f.receive(ctx, args, stdin, stdout)
+	}
+	return fail("unrecognized command '%s'", subcommand)
+}
+
+// add adds a dataset, the caller checks whether it can be added
+func (f *Fake) add(name string, typ zfs.DatasetType, props map[string]string) *dataset {
+	f.txg++
+	ds := &dataset{
+		name:    name,
+		typ:     typ,
+		props:   make(map[string]string, len(props)),
+		guid:    rand.Uint64(),
+		txg:     f.txg,
+		created: time.Now(),
+	}
+	for k, v := range props {
+		ds.props[k] = v
+	}
+	f.datasets[name] = ds
+	return ds
+}
+
+// lookup returns the dataset, or the error zfs returns for datasets that do not exist
+func (f *Fake) lookup(name string) (*dataset, error) {
+	ds, ok := f.datasets[name]
+	if !ok {
+		return nil, fail("cannot open '%s': dataset does not exist", name)
+	}
+	return ds, nil
+}
+
+// snapshots returns the snapshots of the dataset, oldest first
+func (f *Fake) snapshots(name string) []*dataset {
+	var snaps []*dataset
+	for dsName, ds := range f.datasets {
+		if strings.HasPrefix(dsName, name+"@") {
+			snaps = append(snaps, ds)
+		}
+	}
+	slices.SortFunc(snaps, func(a, b *dataset) int {
+		return cmp.Compare(a.txg, b.txg)
+	})
+	return snaps
+}
+
+// bookmarks returns the bookmarks of the dataset, oldest first
+func (f *Fake) bookmarks(name string) []*dataset {
+	var bookmarks []*dataset
+	for dsName, ds := range f.datasets {
+		if strings.HasPrefix(dsName, name+"#") {
+			bookmarks = append(bookmarks, ds)
+		}
+	}
+	slices.SortFunc(bookmarks, func(a, b *dataset) int {
+		return cmp.Or(cmp.Compare(a.txg, b.txg), strings.Compare(a.name, b.name))
+	})
+	return bookmarks
+}
+
+// children returns the direct child filesystems and volumes of the dataset, sorted by name
+func (f *Fake) children(name string) []*dataset {
+	var children []*dataset
+	for dsName, ds := range f.datasets {
+		if path.Dir(dsName) == name && !strings.ContainsAny(dsName, "@#") {
+			children = append(children, ds)
+		}
+	}
+	slices.SortFunc(children, func(a, b *dataset) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return children
+}
+
+// clones returns the names of the datasets cloned from the snapshot
+func (f *Fake) clones(snapName string) []string {
+	var clones []string
+	for name, ds := range f.datasets {
+		if ds.origin == snapName {
+			clones = append(clones, name)
+		}
+	}
+	slices.Sort(clones)
+	return clones
+}
+
+// walk calls fn for the dataset and, up to the given depth, its snapshots, bookmarks and descendants.
+// A negative depth walks all descendants.
+func (f *Fake) walk(ds *dataset, depth int, fn func(ds *dataset)) {
+	fn(ds)
+	if depth == 0 || ds.typ == zfs.DatasetSnapshot || ds.typ == zfs.DatasetBookmark {
+		return
+	}
+	for _, snap := range f.snapshots(ds.name) {
+		fn(snap)
+	}
+	for _, bookmark := range f.bookmarks(ds.name) {
+		fn(bookmark)
+	}
+	for _, child := range f.children(ds.name) {
+		f.walk(child, depth-1, fn)
+	}
+}
+
+// descendants returns the dataset with all its snapshots and descendants
+func (f *Fake) descendants(ds *dataset) []*dataset {
+	var list []*dataset
+	f.walk(ds, -1, func(ds *dataset) {
+		list = append(list, ds)
+	})
+	return list
+}
+
+// parent returns the name of the parent dataset, or the filesystem or volume of a snapshot or bookmark
+func parent(name string) string {
+	if idx := strings.IndexAny(name, "@#"); idx >= 0 {
+		return name[:idx]
+	}
+	if idx := strings.LastIndexByte(name, '/'); idx >= 0 {
+		return name[:idx]
+	}
+	return ""
+}
+
+// parseArgs splits the arguments into flags and operands. Flags can be combined like -Hp, the flags in valueFlags
+// take a value. Every occurrence of a flag is recorded, so repeated flags like -o keep all their values.
+func parseArgs(args []string, boolFlags, valueFlags string) (map[byte][]string, []string, error) {
+	flags := make(map[byte][]string, 4)
+	operands := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			operands = append(operands, arg)
+			continue
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			switch {
+			case strings.IndexByte(valueFlags, flag) >= 0:
+				value := arg[j+1:]
+				if value == "" {
+					i++
+					if i >= len(args) {
+						return nil, nil, fail("missing argument for '%c' option", flag)
+					}
+					value = args[i]
+				}
+				flags[flag] = append(flags[flag], value)
+				j = len(arg)
+			case strings.IndexByte(boolFlags, flag) >= 0:
+				flags[flag] = append(flags[flag], "")
+			default:
+				return nil, nil, fail("invalid option '%c'", flag)
+			}
+		}
+	}
+	return flags, operands, nil
+}
+
+// parseProperties parses the values of -o flags
+func parseProperties(values []string) (map[string]string, error) {
+	props := make(map[string]string, len(values))
+	for _, value := range values {
+		k, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fail("missing '=' for property=value argument")
+		}
+		props[k] = v
+	}
+	return props, nil
+}
+
+func has(flags map[byte][]string, flag byte) bool {
+	_, ok := flags[flag]
+	return ok
+}
@@ -0,0 +1,611 @@
+package zfsfake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+const testProp = "nl.test:prop"
+
+func TestFake_Datasets(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/a/b", zfs.CreateFilesystemOptions{
+		CreateParents: true,
+		Properties:    map[string]string{testProp: "value"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, zfs.DatasetFilesystem, fs.Type)
+	require.Equal(t, "/pool/a/b", fs.Mountpoint)
+	require.True(t, fs.Mounted)
+
+	_, err = zfs.CreateFilesystem(ctx, "pool/a/b", zfs.CreateFilesystemOptions{})
+	require.ErrorIs(t, err, zfs.ErrDatasetExists)
+	_, err = zfs.CreateFilesystem(ctx, "pool/x/y", zfs.CreateFilesystemOptions{})
+	require.Error(t, err)
+
+	vol, err := zfs.CreateVolume(ctx, "pool/vol", 1024*1024, zfs.CreateVolumeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, zfs.DatasetVolume, vol.Type)
+	require.EqualValues(t, 1024*1024, vol.Volsize)
+
+	_, err = fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{Properties: map[string]string{testProp: "snap"}})
+	require.NoError(t, err)
+
+	list, err := zfs.ListDatasets(ctx, zfs.ListOptions{ParentDataset: "pool", Recursive: true, ExtraProperties: []string{testProp}})
+	require.NoError(t, err)
+	names := make([]string, len(list))
+	for i := range list {
+		names[i] = list[i].Name
+	}
+	require.Equal(t, []string{"pool", "pool/a", "pool/a/b", "pool/a/b@s1", "pool/a/b@s2", "pool/vol"}, names)
+	require.Equal(t, "", list[1].ExtraProps[testProp])
+	require.Equal(t, "value", list[2].ExtraProps[testProp])
+	require.Equal(t, "value", list[3].ExtraProps[testProp])
+	require.Equal(t, "snap", list[4].ExtraProps[testProp])
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "pool/a", Depth: 1})
+	require.NoError(t, err)
+	require.Len(t, snaps, 0)
+
+	props, err := zfs.ListWithProperty(ctx, testProp, zfs.ListWithPropertyOptions{
+		ParentDataset: "pool",
+		DatasetType:   zfs.DatasetAll,
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"pool/a/b": "value", "pool/a/b@s2": "snap"}, props)
+
+	require.NoError(t, fs.InheritProperty(ctx, testProp))
+	value, err := fs.GetProperty(ctx, testProp)
+	require.NoError(t, err)
+	require.Equal(t, zfs.ValueUnset, value)
+
+	_, err = zfs.GetDataset(ctx, "pool/missing")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+}
+
+func TestFake_DestroyAndClones(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "snap", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	clone, err := snap.Clone(ctx, "pool/clone", zfs.CloneOptions{})
+	require.NoError(t, err)
+	require.Equal(t, snap.Name, clone.Origin)
+
+	err = snap.Destroy(ctx, zfs.DestroyOptions{})
+	require.ErrorIs(t, err, zfs.ErrSnapshotHasDependentClones)
+	err = fs.Destroy(ctx, zfs.DestroyOptions{})
+	require.Error(t, err)
+
+	_, err = snap.Promote(ctx)
+	require.ErrorIs(t, err, zfs.ErrSnapshotsNotSupported)
+	clone, err = clone.Promote(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "", clone.Origin)
+	fs, err = zfs.GetDataset(ctx, "pool/fs")
+	require.NoError(t, err)
+	require.Equal(t, "pool/clone@snap", fs.Origin)
+
+	require.NoError(t, clone.Destroy(ctx, zfs.DestroyOptions{RecursiveClones: true}))
+	_, err = zfs.GetDataset(ctx, "pool/fs")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+}
+
+func TestFake_SendReceive(t *testing.T) {
+	fake := Install(t, "src", "dst")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{
+		Properties: map[string]string{testProp: "value"},
+	})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	snap2, err := fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = snap1.SendSnapshot(ctx, &buf, zfs.SendOptions{IncludeProperties: true})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, bytes.NewReader(buf.Bytes()), "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	_, _, err = zfs.ReceiveSnapshot(ctx, bytes.NewReader(buf.Bytes()), "dst/fs", zfs.ReceiveOptions{})
+	require.ErrorIs(t, err, zfs.ErrDatasetExists)
+
+	buf.Reset()
+	_, err = snap2.SendSnapshot(ctx, &buf, zfs.SendOptions{IncrementalBase: snap1})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{
+		ParentDataset:   "dst/fs",
+		ExtraProperties: []string{zfs.PropertyGUID, testProp},
+	})
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, "dst/fs@s2", snaps[1].Name)
+	require.Equal(t, "value", snaps[1].ExtraProps[testProp])
+
+	src, err := snap2.GetProperty(ctx, zfs.PropertyGUID)
+	require.NoError(t, err)
+	require.Equal(t, src, snaps[1].ExtraProps[zfs.PropertyGUID])
+
+	_, _, err = zfs.ReceiveSnapshot(ctx, bytes.NewBufferString("garbage"), "dst/other", zfs.ReceiveOptions{})
+	require.Error(t, err)
+
+	fake.FailNext("send", "cannot open 'src/fs@s2': dataset does not exist")
+	_, err = snap2.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+	_, err = snap2.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.NoError(t, err)
+
+	cmds := fake.Commands()
+	require.Equal(t, []string{"send", "src/fs@s2"}, cmds[len(cmds)-1])
+}
+
+func TestFake_ReceiveDryRun(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	snap2, err := fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = snap1.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.NoError(t, err)
+	ds, _, err := zfs.ReceiveSnapshot(ctx, bytes.NewReader(buf.Bytes()), "dst/fs", zfs.ReceiveOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, "dst/fs@s1", ds.Name)
+	require.Equal(t, zfs.DatasetSnapshot, ds.Type)
+	_, err = zfs.GetDataset(ctx, "dst/fs")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+
+	// The incremental stream does not match the target, as nothing was received
+	var incremental bytes.Buffer
+	_, err = snap2.SendSnapshot(ctx, &incremental, zfs.SendOptions{IncrementalBase: snap1})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, bytes.NewReader(incremental.Bytes()), "dst/fs", zfs.ReceiveOptions{DryRun: true})
+	require.Error(t, err)
+
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+	ds, _, err = zfs.ReceiveSnapshot(ctx, &incremental, "dst/fs", zfs.ReceiveOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, "dst/fs@s2", ds.Name)
+
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "dst/fs"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+}
+
+func TestFake_Encryption(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	_, err := zfs.CreateFilesystem(ctx, "pool/enc", zfs.CreateFilesystemOptions{
+		Properties: map[string]string{
+			zfs.PropertyEncryption: zfs.EncryptionAES256GCM,
+			zfs.PropertyKeyFormat:  zfs.KeyFormatPassphrase,
+		},
+		Stdin: strings.NewReader("passphrase\n"),
+	})
+	require.NoError(t, err)
+	_, err = zfs.CreateFilesystem(ctx, "pool/enc/child", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+
+	list, err := zfs.ListDatasets(ctx, zfs.ListOptions{
+		ParentDataset: "pool",
+		Recursive:     true,
+		Fields:        append([]string{zfs.PropertyName}, zfs.EncryptionProperties...),
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	require.False(t, list[0].IsEncrypted())
+	require.Empty(t, list[0].EncryptionRoot)
+	require.True(t, list[1].IsEncrypted())
+	require.True(t, list[1].IsEncryptionRoot())
+	require.True(t, list[1].KeyLoaded())
+	require.Equal(t, zfs.KeyFormatPassphrase, list[1].KeyFormat)
+	require.True(t, list[2].IsEncrypted())
+	require.Equal(t, "pool/enc", list[2].EncryptionRoot)
+	require.False(t, list[2].IsEncryptionRoot())
+}
+
+func TestFake_TemporaryMount(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{
+		Properties: map[string]string{zfs.PropertyCanMount: zfs.CanMountNoAuto},
+	})
+	require.NoError(t, err)
+
+	mountpoint := t.TempDir()
+	err = fs.WithTemporaryMount(ctx, zfs.TemporaryMountOptions{Mountpoint: mountpoint, ReadOnly: true}, func(string) error {
+		ds, err := zfs.GetDataset(ctx, "pool/fs", zfs.PropertyReadOnly)
+		require.NoError(t, err)
+		require.True(t, ds.Mounted)
+		require.Equal(t, mountpoint, ds.Mountpoint)
+		require.Equal(t, zfs.ValueOn, ds.ExtraProps[zfs.PropertyReadOnly])
+		return nil
+	})
+	require.NoError(t, err)
+
+	ds, err := zfs.GetDataset(ctx, "pool/fs", zfs.PropertyReadOnly)
+	require.NoError(t, err)
+	require.False(t, ds.Mounted)
+	require.Equal(t, "/pool/fs", ds.Mountpoint)
+	require.Equal(t, zfs.ValueOff, ds.ExtraProps[zfs.PropertyReadOnly])
+}
+
+func TestFake_SetProperties(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	require.NoError(t, fs.SetProperties(ctx, map[string]string{
+		zfs.PropertyMountPoint: "/srv/fs",
+		testProp:               "value",
+	}, zfs.SetPropertyOptions{NoMount: true}))
+
+	ds, err := zfs.GetDataset(ctx, "pool/fs", testProp)
+	require.NoError(t, err)
+	require.Equal(t, "/srv/fs", ds.Mountpoint)
+	require.Equal(t, "value", ds.ExtraProps[testProp])
+}
+
+func TestFake_MostRecentCommonSnapshot(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	dst, err := zfs.CreateFilesystem(ctx, "dst/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	_, err = dst.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	// Snapshots with the same name but another GUID are not in common
+	common, err := zfs.MostRecentCommonSnapshot(ctx, "src/fs", "dst/fs")
+	require.NoError(t, err)
+	require.Nil(t, common)
+	require.NoError(t, dst.Destroy(ctx, zfs.DestroyOptions{Recursive: true}))
+
+	var buf bytes.Buffer
+	_, err = snap1.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	common, err = zfs.MostRecentCommonSnapshot(ctx, "src/fs", "dst/fs")
+	require.NoError(t, err)
+	require.NotNil(t, common)
+	require.Equal(t, "src/fs@s1", common.Name)
+	require.Equal(t, zfs.DatasetSnapshot, common.Type)
+}
+
+func TestFake_SnapshotSpaceMap(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	for _, name := range []string{"s1", "s2", "s3"} {
+		_, err = fs.Snapshot(ctx, name, zfs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+
+	spaces, err := fs.SnapshotSpaceMap(ctx)
+	require.NoError(t, err)
+	require.Len(t, spaces, 3)
+	require.Equal(t, "pool/fs@s1", spaces[0].Snapshot)
+	require.Equal(t, "pool/fs@s3", spaces[2].Snapshot)
+	require.NotZero(t, spaces[2].Referenced)
+
+	// The estimate does not destroy the snapshots
+	_, err = fs.ReclaimableSpace(ctx, "s1", "s3")
+	require.NoError(t, err)
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "pool/fs"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 3)
+
+	_, err = fs.ReclaimableSpace(ctx, "s3", "missing")
+	require.Error(t, err)
+
+	// A list of ranges and single snapshots is estimated at once
+	all := &zfs.Dataset{Name: "pool/fs@s1%s2,s2,s3", Type: zfs.DatasetSnapshot}
+	estimate, err := all.DestroyDryRun(ctx, zfs.DestroyOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pool/fs@s1", "pool/fs@s2", "pool/fs@s3"}, estimate.Datasets)
+	all.Name = "pool/fs@s1,"
+	_, err = all.DestroyDryRun(ctx, zfs.DestroyOptions{})
+	require.Error(t, err)
+}
+
+func Test_parseArgs(t *testing.T) {
+	flags, operands, err := parseArgs([]string{"-Hp", "-o", "name,value", "-t", "snapshot", "-r", "prop", "pool"}, "rHp", "dost")
+	require.NoError(t, err)
+	require.Equal(t, []string{"prop", "pool"}, operands)
+	require.Equal(t, []string{"name,value"}, flags['o'])
+	require.Equal(t, []string{"snapshot"}, flags['t'])
+	require.True(t, has(flags, 'H'))
+	require.True(t, has(flags, 'r'))
+	require.False(t, has(flags, 'd'))
+
+	_, _, err = parseArgs([]string{"-x"}, "r", "")
+	require.Error(t, err)
+	_, _, err = parseArgs([]string{"-o"}, "", "o")
+	require.Error(t, err)
+}
+
+func TestFake_Holds(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "snap", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, snap.Hold(ctx, "keep"))
+	require.ErrorIs(t, snap.Hold(ctx, "keep"), zfs.ErrHoldExists)
+	holds, err := snap.Holds(ctx)
+	require.NoError(t, err)
+	require.Len(t, holds, 1)
+	require.Equal(t, "keep", holds[0].Tag)
+	require.WithinDuration(t, time.Now(), holds[0].Created, time.Minute)
+
+	require.Error(t, snap.Destroy(ctx, zfs.DestroyOptions{}))
+	require.NoError(t, snap.Release(ctx, "keep"))
+	require.ErrorIs(t, snap.Release(ctx, "keep"), zfs.ErrHoldNotFound)
+	require.NoError(t, snap.Destroy(ctx, zfs.DestroyOptions{}))
+}
+
+func TestFake_Bookmarks(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = snap1.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	bookmark, err := snap1.Bookmark(ctx, "b1")
+	require.NoError(t, err)
+	require.Equal(t, "src/fs#b1", bookmark.Name)
+	require.Equal(t, zfs.DatasetBookmark, bookmark.Type)
+	require.Equal(t, snap1.GUID, bookmark.GUID)
+	_, err = fs.Bookmark(ctx, "b2")
+	require.ErrorIs(t, err, zfs.ErrOnlySnapshotsSupported)
+
+	bookmarks, err := zfs.ListBookmarks(ctx, zfs.ListOptions{ParentDataset: "src"})
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 1)
+	require.Equal(t, "src/fs#b1", bookmarks[0].Name)
+	snaps, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "src"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+
+	// The bookmark remains the base of the next incremental send after its snapshot is destroyed
+	require.NoError(t, snap1.Destroy(ctx, zfs.DestroyOptions{}))
+	snap2, err := fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	_, err = snap2.SendSnapshot(ctx, &buf, zfs.SendOptions{IncrementalBase: bookmark})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	snaps, err = zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "dst/fs"})
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, snap2.GUID, snaps[1].GUID)
+
+	require.NoError(t, bookmark.Destroy(ctx, zfs.DestroyOptions{}))
+	bookmarks, err = zfs.ListBookmarks(ctx, zfs.ListOptions{ParentDataset: "src"})
+	require.NoError(t, err)
+	require.Empty(t, bookmarks)
+}
+
+func TestFake_SendIntermediary(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snaps := make([]*zfs.Dataset, 4)
+	for i := range snaps {
+		snaps[i], err = fs.Snapshot(ctx, fmt.Sprintf("s%d", i+1), zfs.SnapshotOptions{})
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = snaps[0].SendSnapshot(ctx, &buf, zfs.SendOptions{})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	_, err = snaps[3].SendSnapshot(ctx, &buf, zfs.SendOptions{
+		IncrementalBase:              snaps[0],
+		IncludeIntermediarySnapshots: true,
+	})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{})
+	require.NoError(t, err)
+
+	received, err := zfs.ListSnapshots(ctx, zfs.ListOptions{ParentDataset: "dst/fs"})
+	require.NoError(t, err)
+	require.Len(t, received, 4)
+	for i, snap := range received {
+		require.Equal(t, fmt.Sprintf("dst/fs@s%d", i+1), snap.Name)
+		require.Equal(t, snaps[i].GUID, snap.GUID)
+	}
+
+	bookmark, err := snaps[3].Bookmark(ctx, "b4")
+	require.NoError(t, err)
+	_, err = snaps[3].SendSnapshot(ctx, &buf, zfs.SendOptions{IncrementalBase: bookmark, IncludeIntermediarySnapshots: true})
+	require.ErrorIs(t, err, zfs.ErrOnlySnapshotsSupported)
+}
+
+func TestFake_SendSize(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "pool/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap1, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	snap2, err := fs.Snapshot(ctx, "s2", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	for _, options := range []zfs.SendOptions{{}, {IncrementalBase: snap1}} {
+		size, err := snap2.SendSize(ctx, options)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		_, err = snap2.SendSnapshot(ctx, &buf, options)
+		require.NoError(t, err)
+		require.EqualValues(t, buf.Len(), size)
+	}
+
+	_, err = fs.SendSize(ctx, zfs.SendOptions{})
+	require.ErrorIs(t, err, zfs.ErrOnlySnapshotsSupported)
+}
+
+func TestFake_ReceiveDoNotMount(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	for _, doNotMount := range []bool{false, true} {
+		var buf bytes.Buffer
+		_, err = snap.SendSnapshot(ctx, &buf, zfs.SendOptions{})
+		require.NoError(t, err)
+		name := fmt.Sprintf("dst/mounted-%t", !doNotMount)
+		_, _, err = zfs.ReceiveSnapshot(ctx, &buf, name, zfs.ReceiveOptions{DoNotMount: doNotMount})
+		require.NoError(t, err)
+
+		ds, err := zfs.GetDataset(ctx, name)
+		require.NoError(t, err)
+		require.Equal(t, !doNotMount, ds.Mounted)
+	}
+}
+
+func TestFake_ReceiveProperties(t *testing.T) {
+	Install(t, "src", "dst")
+	ctx := context.Background()
+	fs, err := zfs.CreateFilesystem(ctx, "src/fs", zfs.CreateFilesystemOptions{
+		Properties: map[string]string{testProp: "value", zfs.PropertyCompression: "lz4"},
+	})
+	require.NoError(t, err)
+	snap, err := fs.Snapshot(ctx, "s1", zfs.SnapshotOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = snap.SendSnapshot(ctx, &buf, zfs.SendOptions{IncludeProperties: true})
+	require.NoError(t, err)
+	_, _, err = zfs.ReceiveSnapshot(ctx, &buf, "dst/fs", zfs.ReceiveOptions{
+		Properties:        map[string]string{zfs.PropertyCompression: "zstd"},
+		ExcludeProperties: []string{testProp},
+	})
+	require.NoError(t, err)
+
+	ds, err := zfs.GetDataset(ctx, "dst/fs", testProp, zfs.PropertyCompression)
+	require.NoError(t, err)
+	require.Empty(t, ds.ExtraProps[testProp])
+	require.Equal(t, "zstd", ds.ExtraProps[zfs.PropertyCompression])
+}
+
+func TestFake_CreateSnapshots(t *testing.T) {
+	Install(t, "src")
+	ctx := context.Background()
+	for _, name := range []string{"src/db", "src/wal"} {
+		_, err := zfs.CreateFilesystem(ctx, name, zfs.CreateFilesystemOptions{})
+		require.NoError(t, err)
+	}
+
+	snaps, err := zfs.CreateSnapshots(ctx, []string{"src/wal@s1", "src/db@s1"}, zfs.SnapshotOptions{})
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, "src/db@s1", snaps[0].Name)
+	require.Equal(t, "src/wal@s1", snaps[1].Name)
+	require.Equal(t, zfs.DatasetSnapshot, snaps[1].Type)
+
+	// Nothing is created when one of the snapshots already exists
+	_, err = zfs.CreateSnapshots(ctx, []string{"src/db@s2", "src/wal@s1"}, zfs.SnapshotOptions{})
+	require.Error(t, err)
+	_, err = zfs.GetDataset(ctx, "src/db@s2")
+	require.ErrorIs(t, err, zfs.ErrDatasetNotFound)
+
+	_, err = zfs.CreateSnapshots(ctx, []string{"src/db"}, zfs.SnapshotOptions{})
+	require.ErrorIs(t, err, zfs.ErrOnlySnapshotsSupported)
+}
+
+func TestFake_ListSorted(t *testing.T) {
+	Install(t, "pool")
+	ctx := context.Background()
+
+	for _, name := range []string{"pool/b", "pool/a", "pool/c"} {
+		_, err := zfs.CreateFilesystem(ctx, name, zfs.CreateFilesystemOptions{})
+		require.NoError(t, err)
+	}
+	ds, err := zfs.GetDataset(ctx, "pool/b")
+	require.NoError(t, err)
+	require.NoError(t, ds.SetProperty(ctx, testProp, "10"))
+	ds, err = zfs.GetDataset(ctx, "pool/a")
+	require.NoError(t, err)
+	require.NoError(t, ds.SetProperty(ctx, testProp, "9"))
+
+	names := func(options zfs.ListOptions) []string {
+		options.ParentDataset = "pool"
+		options.FilterSelf = true
+		options.Recursive = true
+		options.Fields = []string{zfs.PropertyName}
+		list, err := zfs.ListDatasets(ctx, options)
+		require.NoError(t, err)
+		var names []string
+		for _, ds := range list {
+			names = append(names, ds.Name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"pool/b", "pool/a", "pool/c"}, names(zfs.ListOptions{SortBy: zfs.PropertyCreateTXG}))
+	require.Equal(t, []string{"pool/c", "pool/a", "pool/b"}, names(zfs.ListOptions{SortBy: zfs.PropertyCreateTXG, SortDescending: true}))
+	require.Equal(t, []string{"pool/a", "pool/b", "pool/c"}, names(zfs.ListOptions{SortBy: zfs.PropertyName}))
+	// Numeric values are sorted numerically, unset values last
+	require.Equal(t, []string{"pool/a", "pool/b", "pool/c"}, names(zfs.ListOptions{SortBy: testProp}))
+	require.Equal(t, []string{"pool/b", "pool/a", "pool/c"}, names(zfs.ListOptions{SortBy: testProp, SortDescending: true}))
+	require.Equal(t, []string{"pool/a"}, names(zfs.ListOptions{SortBy: zfs.PropertyName, Limit: 1}))
+}
@@ -0,0 +1,98 @@
+package zfsfake
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// holdArgs parses the tag and snapshot of zfs hold and zfs release
+func (f *Fake) holdArgs(args []string, action string) (string, *dataset, error) {
+	_, operands, err := parseArgs(args, "r", "")
+	if err != nil {
+		return "", nil, err
+	}
+	if len(operands) != 2 {
+		return "", nil, fail("expected a tag and a snapshot")
+	}
+	ds, err := f.lookup(operands[1])
+	if err != nil {
+		return "", nil, err
+	}
+	if ds.typ != zfs.DatasetSnapshot {
+		return "", nil, fail("cannot %s '%s': operation only applies to snapshots", action, ds.name)
+	}
+	return operands[0], ds, nil
+}
+
+// hold implements zfs hold tag snapshot
+func (f *Fake) hold(args []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tag, ds, err := f.holdArgs(args, "hold snapshot")
+	if err != nil {
+		return err
+	}
+	if _, ok := ds.holds[tag]; ok {
+		return fail("cannot hold snapshot '%s': tag already exists on this dataset", ds.name)
+	}
+	if ds.holds == nil {
+		ds.holds = make(map[string]time.Time, 1)
+	}
+	ds.holds[tag] = time.Now()
+	return nil
+}
+
+// release implements zfs release tag snapshot
+func (f *Fake) release(args []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tag, ds, err := f.holdArgs(args, "release hold from snapshot")
+	if err != nil {
+		return err
+	}
+	if _, ok := ds.holds[tag]; !ok {
+		return fail("cannot release hold from snapshot '%s': no such tag on this dataset", ds.name)
+	}
+	delete(ds.holds, tag)
+	return nil
+}
+
+// holds implements zfs holds -H [-p] snapshot
+func (f *Fake) holds(args []string, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "rHp", "")
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range operands {
+		ds, err := f.lookup(name)
+		if err != nil {
+			return err
+		}
+		tags := make([]string, 0, len(ds.holds))
+		for tag := range ds.holds {
+			tags = append(tags, tag)
+		}
+		slices.Sort(tags)
+		for _, tag := range tags {
+			created := ds.holds[tag].Format("Mon Jan _2 15:04 2006")
+			if has(flags, 'p') {
+				created = fmt.Sprint(ds.holds[tag].Unix())
+			}
+			_, err = fmt.Fprintf(stdout, "%s\t%s\t%s\n", ds.name, tag, created)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,545 @@
+package zfsfake
+
+import (
+	"cmp"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// Sizes reported for every dataset, the fake does not store any data
+const (
+	referencedSize  = 24576
+	logicalUsedSize = 12288
+	availableSize   = 1 << 30
+)
+
+const sourceNone = "-"
+
+// defaults are the default values of native properties that can be set
+var defaults = map[string]string{
+	zfs.PropertyCompression: zfs.ValueOff,
+	zfs.PropertyReadOnly:    zfs.ValueOff,
+	zfs.PropertyCanMount:    zfs.ValueOn,
+	zfs.PropertyEncryption:  zfs.ValueOff,
+	zfs.PropertyKeyFormat:   zfs.ValueNone,
+	zfs.PropertyKeyLocation: zfs.ValueNone,
+	"atime":                 zfs.ValueOn,
+	"checksum":              zfs.ValueOn,
+	"copies":                "1",
+	"recordsize":            "131072",
+	"sync":                  "standard",
+}
+
+// inheritable are the native properties that are inherited from the parent dataset
+var inheritable = []string{
+	zfs.PropertyCompression,
+	zfs.PropertyReadOnly,
+	zfs.PropertyEncryption,
+	zfs.PropertyKeyFormat,
+	"atime",
+	"checksum",
+	"copies",
+	"recordsize",
+	"sync",
+}
+
+// readonly are the native properties that cannot be set
+var readonly = []string{
+	zfs.PropertyAvailable,
+	zfs.PropertyClones,
+	zfs.PropertyEncryption,
+	zfs.PropertyEncryptionRoot,
+	zfs.PropertyGUID,
+	zfs.PropertyKeyStatus,
+	zfs.PropertyLogicalUsed,
+	zfs.PropertyMounted,
+	zfs.PropertyName,
+	zfs.PropertyOrigin,
+	zfs.PropertyReceiveResumeToken,
+	zfs.PropertyReferenced,
+	zfs.PropertyType,
+	zfs.PropertyUsed,
+	zfs.PropertyUsedByDataset,
+	zfs.PropertyWritten,
+	zfs.PropertyCreateTXG,
+	zfs.PropertyCreation,
+}
+
+// bookmarkProperties are the only properties bookmarks have
+var bookmarkProperties = []string{
+	zfs.PropertyName,
+	zfs.PropertyType,
+	zfs.PropertyGUID,
+	zfs.PropertyCreateTXG,
+	zfs.PropertyCreation,
+}
+
+func isUserProperty(prop string) bool {
+	return strings.Contains(prop, ":")
+}
+
+// property returns the value of the property for the dataset, and its source
+func (f *Fake) property(ds *dataset, prop string) (value, source string) {
+	isSnapshot := ds.typ == zfs.DatasetSnapshot
+	isFilesystem := ds.typ == zfs.DatasetFilesystem
+
+	if value, ok := ds.mountOptions[prop]; ok && ds.mounted {
+		return value, "temporary"
+	}
+	if ds.typ == zfs.DatasetBookmark && !slices.Contains(bookmarkProperties, prop) {
+		return zfs.ValueUnset, sourceNone
+	}
+
+	switch prop {
+	case zfs.PropertyName:
+		return ds.name, sourceNone
+	case zfs.PropertyType:
+		return string(ds.typ), sourceNone
+	case zfs.PropertyGUID:
+		return strconv.FormatUint(ds.guid, 10), sourceNone
+	case zfs.PropertyCreateTXG:
+		return strconv.FormatUint(ds.txg, 10), sourceNone
+	case zfs.PropertyCreation:
+		return strconv.FormatInt(ds.created.Unix(), 10), sourceNone
+	case zfs.PropertyOrigin:
+		if ds.origin == "" {
+			return zfs.ValueUnset, sourceNone
+		}
+		return ds.origin, sourceNone
+	case zfs.PropertyClones:
+		clones := f.clones(ds.name)
+		if !isSnapshot || len(clones) == 0 {
+			return zfs.ValueUnset, sourceNone
+		}
+		return strings.Join(clones, ","), sourceNone
+	case zfs.PropertyUsed, zfs.PropertyUsedByDataset:
+		if isSnapshot {
+			return "0", sourceNone
+		}
+		return strconv.Itoa(referencedSize), sourceNone
+	case zfs.PropertyReferenced:
+		return strconv.Itoa(referencedSize), sourceNone
+	case zfs.PropertyLogicalUsed:
+		return strconv.Itoa(logicalUsedSize), sourceNone
+	case "compressratio", "refcompressratio":
+		return "1.00", sourceNone
+	case zfs.PropertyWritten:
+		if isSnapshot {
+			return "0", sourceNone
+		}
+		return strconv.Itoa(referencedSize), sourceNone
+	case zfs.PropertyAvailable:
+		if isSnapshot {
+			return zfs.ValueUnset, sourceNone
+		}
+		return strconv.Itoa(availableSize), sourceNone
+	case zfs.PropertyVolSize:
+		if ds.typ != zfs.DatasetVolume {
+			return zfs.ValueUnset, sourceNone
+		}
+		return strconv.FormatUint(ds.volsize, 10), "local"
+	case zfs.PropertyMounted:
+		if !isFilesystem {
+			return zfs.ValueUnset, sourceNone
+		}
+		if ds.mounted {
+			return zfs.ValueYes, sourceNone
+		}
+		return zfs.ValueNo, sourceNone
+	case zfs.PropertyMountPoint:
+		if !isFilesystem {
+			return zfs.ValueUnset, sourceNone
+		}
+		return f.mountpoint(ds)
+	case zfs.PropertyQuota, zfs.PropertyRefQuota:
+		if !isFilesystem {
+			return zfs.ValueUnset, sourceNone
+		}
+	case zfs.PropertyKeyStatus:
+		if encryption, _ := f.property(ds, zfs.PropertyEncryption); encryption == zfs.ValueOff {
+			return zfs.ValueUnset, sourceNone
+		}
+		if ds.keyLoaded {
+			return zfs.KeyStatusAvailable, sourceNone
+		}
+		return "unavailable", sourceNone
+	case zfs.PropertyEncryptionRoot:
+		for name := ds.name; name != ""; name = parent(name) {
+			if encryption, ok := f.datasets[name].props[zfs.PropertyEncryption]; ok && encryption != zfs.ValueOff {
+				return name, sourceNone
+			}
+		}
+		return zfs.ValueUnset, sourceNone
+	case zfs.PropertyReceiveResumeToken:
+		return zfs.ValueUnset, sourceNone
+	}
+
+	if value, ok := ds.props[prop]; ok {
+		return value, "local"
+	}
+	if isUserProperty(prop) || slices.Contains(inheritable, prop) {
+		for name := parent(ds.name); name != ""; name = parent(name) {
+			if value, ok := f.datasets[name].props[prop]; ok {
+				return value, "inherited from " + name
+			}
+		}
+	}
+	if value, ok := defaults[prop]; ok {
+		return value, "default"
+	}
+	if prop == zfs.PropertyQuota || prop == zfs.PropertyRefQuota {
+		return "0", "default"
+	}
+	return zfs.ValueUnset, sourceNone
+}
+
+// mountpoint returns the mountpoint of a filesystem, which is inherited relative to the parent's mountpoint
+func (f *Fake) mountpoint(ds *dataset) (value, source string) {
+	for name := ds.name; name != ""; name = parent(name) {
+		mountpoint, ok := f.datasets[name].props[zfs.PropertyMountPoint]
+		switch {
+		case !ok:
+			continue
+		case name == ds.name:
+			return mountpoint, "local"
+		case mountpoint == zfs.ValueNone || mountpoint == "legacy":
+			return mountpoint, "inherited from " + name
+		}
+		return path.Join(mountpoint, strings.TrimPrefix(ds.name, name)), "inherited from " + name
+	}
+	return "/" + ds.name, "default"
+}
+
+// matchesSource returns whether the source of a property is in the list of sources given to get -s
+func matchesSource(source string, sources []string) bool {
+	if len(sources) == 0 {
+		return true
+	}
+	switch {
+	case source == sourceNone:
+		source = "none"
+	case strings.HasPrefix(source, "inherited"):
+		source = string(zfs.PropertySourceInherited)
+	}
+	return slices.Contains(sources, source)
+}
+
+// matchesType returns whether the dataset is of one of the types given to get -t
+func matchesType(ds *dataset, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	return slices.Contains(types, string(zfs.DatasetAll)) || slices.Contains(types, string(ds.typ))
+}
+
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// get implements zfs get [-rHp] [-d depth] [-o field[,field]...] [-s source[,source]...] [-t type[,type]...]
+// property[,property]... [dataset...]
+func (f *Fake) get(args []string, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "rHp", "dost")
+	if err != nil {
+		return err
+	}
+	if len(operands) == 0 {
+		return fail("missing property argument")
+	}
+
+	depth := 0
+	if has(flags, 'r') {
+		depth = -1
+	}
+	for _, value := range flags['d'] {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return fail("invalid depth '%s'", value)
+		}
+	}
+
+	columns := []string{"name", "property", "value", "source"}
+	if has(flags, 'o') {
+		columns = splitList(flags['o'])
+	}
+	props := splitList(operands[:1])
+	types := splitList(flags['t'])
+	sources := splitList(flags['s'])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(operands) == 1 && !has(flags, 'd') {
+		depth = -1 // Without datasets, all datasets are listed
+	}
+	roots, err := f.roots(operands[1:])
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	for _, root := range roots {
+		f.walk(root, depth, func(ds *dataset) {
+			if !matchesType(ds, types) {
+				return
+			}
+			for _, prop := range props {
+				value, source := f.property(ds, prop)
+				if !matchesSource(source, sources) {
+					continue
+				}
+				for i, column := range columns {
+					if i > 0 {
+						out.WriteByte('\t')
+					}
+					switch column {
+					case "name":
+						out.WriteString(ds.name)
+					case "property":
+						out.WriteString(prop)
+					case "value":
+						out.WriteString(value)
+					case "source":
+						out.WriteString(source)
+					}
+				}
+				out.WriteByte('\n')
+			}
+		})
+	}
+
+	_, err = io.WriteString(stdout, out.String())
+	return err
+}
+
+// roots returns the datasets with the given names, or all pools when no names are given
+func (f *Fake) roots(names []string) ([]*dataset, error) {
+	var roots []*dataset
+	if len(names) == 0 {
+		for name, ds := range f.datasets {
+			if parent(name) == "" {
+				roots = append(roots, ds)
+			}
+		}
+		slices.SortFunc(roots, func(a, b *dataset) int {
+			return strings.Compare(a.name, b.name)
+		})
+	}
+	for _, name := range names {
+		ds, err := f.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, ds)
+	}
+	return roots, nil
+}
+
+// list implements zfs list [-rHp] [-d depth] [-o property[,property]...] [-s property]... [-S property]...
+// [-t type[,type]...] [dataset...]. Sorting by both -s and -S sorts by the -s properties first.
+func (f *Fake) list(args []string, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "rHp", "dostS")
+	if err != nil {
+		return err
+	}
+
+	depth := 0
+	if has(flags, 'r') || len(operands) == 0 {
+		depth = -1
+	}
+	for _, value := range flags['d'] {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return fail("invalid depth '%s'", value)
+		}
+	}
+
+	columns := []string{"name", "used", "available", "referenced", "mountpoint"}
+	if has(flags, 'o') {
+		columns = splitList(flags['o'])
+	}
+	types := splitList(flags['t'])
+	if len(types) == 0 {
+		types = []string{string(zfs.DatasetFilesystem), string(zfs.DatasetVolume)}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roots, err := f.roots(operands)
+	if err != nil {
+		return err
+	}
+	var list []*dataset
+	for _, root := range roots {
+		f.walk(root, depth, func(ds *dataset) {
+			if matchesType(ds, types) {
+				list = append(list, ds)
+			}
+		})
+	}
+
+	type sortKey struct {
+		prop       string
+		descending bool
+	}
+	var keys []sortKey
+	for _, prop := range splitList(flags['s']) {
+		keys = append(keys, sortKey{prop: prop})
+	}
+	for _, prop := range splitList(flags['S']) {
+		keys = append(keys, sortKey{prop: prop, descending: true})
+	}
+	slices.SortStableFunc(list, func(a, b *dataset) int {
+		for _, key := range keys {
+			va, _ := f.property(a, key.prop)
+			vb, _ := f.property(b, key.prop)
+			c := compareValues(va, vb)
+			switch {
+			case c == 0:
+				continue
+			case va == zfs.ValueUnset || vb == zfs.ValueUnset:
+				return c // Unset values are sorted last, also when descending
+			case key.descending:
+				return -c
+			}
+			return c
+		}
+		return 0
+	})
+
+	var out strings.Builder
+	if !has(flags, 'H') {
+		out.WriteString(strings.ToUpper(strings.Join(columns, "\t")) + "\n")
+	}
+	for _, ds := range list {
+		for i, column := range columns {
+			if i > 0 {
+				out.WriteByte('\t')
+			}
+			value, _ := f.property(ds, column)
+			out.WriteString(value)
+		}
+		out.WriteByte('\n')
+	}
+	_, err = io.WriteString(stdout, out.String())
+	return err
+}
+
+// compareValues compares property values like zfs list sorts them: numbers numerically, unset values last
+func compareValues(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == zfs.ValueUnset:
+		return 1
+	case b == zfs.ValueUnset:
+		return -1
+	}
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(na, nb)
+	}
+	return strings.Compare(a, b)
+}
+
+// setProperty validates and sets a property on the dataset
+func (f *Fake) setProperty(ds *dataset, prop, value string) error {
+	if slices.Contains(readonly, prop) {
+		return fail("cannot set property for '%s': '%s' is readonly", ds.name, prop)
+	}
+	if ds.typ == zfs.DatasetSnapshot && !isUserProperty(prop) {
+		return fail("cannot set property for '%s': this property can not be modified for snapshots", ds.name)
+	}
+	if prop == zfs.PropertyVolSize {
+		if ds.typ != zfs.DatasetVolume {
+			return fail("cannot set property for '%s': 'volsize' does not apply to datasets of this type", ds.name)
+		}
+		size, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fail("cannot set property for '%s': bad numeric value '%s'", ds.name, value)
+		}
+		ds.volsize = size
+		return nil
+	}
+	ds.props[prop] = value
+	return nil
+}
+
+// set implements zfs set [-u] property=value [property=value]... dataset
+func (f *Fake) set(args []string) error {
+	_, args, err := parseArgs(args, "u", "")
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fail("missing arguments")
+	}
+	name := args[len(args)-1]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[:len(args)-1] {
+		prop, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fail("missing '=' for property=value argument")
+		}
+		err = f.setProperty(ds, prop, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inherit implements zfs inherit [-r] property dataset...
+func (f *Fake) inherit(args []string) error {
+	flags, operands, err := parseArgs(args, "rS", "")
+	if err != nil {
+		return err
+	}
+	if len(operands) < 2 {
+		return fail("missing arguments")
+	}
+	prop := operands[0]
+	if slices.Contains(readonly, prop) {
+		return fail("'%s' property is read-only", prop)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range operands[1:] {
+		ds, err := f.lookup(name)
+		if err != nil {
+			return err
+		}
+		list := []*dataset{ds}
+		if has(flags, 'r') {
+			list = f.descendants(ds)
+		}
+		for _, ds := range list {
+			delete(ds.props, prop)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package zfsfake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	zfs "github.com/vansante/go-zfsutils"
+)
+
+// streamMagic starts every stream, so receive can tell the fake streams apart from other data
+const streamMagic = "zfsfake stream v1\n"
+
+// stream is the canned send stream, it describes the snapshot instead of containing its data
+type stream struct {
+	Snapshot           string            `json:"snapshot"`
+	GUID               uint64            `json:"guid"`
+	Type               zfs.DatasetType   `json:"type"`
+	Volsize            uint64            `json:"volsize,omitempty"`
+	Base               string            `json:"base,omitempty"`
+	BaseGUID           uint64            `json:"baseGuid,omitempty"`
+	Intermediary       []streamSnapshot  `json:"intermediary,omitempty"`
+	Encryption         string            `json:"encryption,omitempty"`
+	Properties         map[string]string `json:"properties,omitempty"`
+	SnapshotProperties map[string]string `json:"snapshotProperties,omitempty"`
+}
+
+// streamSnapshot is an intermediary snapshot in a stream sent with -I
+type streamSnapshot struct {
+	Snapshot   string            `json:"snapshot"`
+	GUID       uint64            `json:"guid"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// send implements zfs send [-wp] [-i snapshot|bookmark | -I snapshot] snapshot
+func (f *Fake) send(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "wpLecvnPRDbS", "iIt")
+	if err != nil {
+		return err
+	}
+	if has(flags, 't') {
+		return fail("cannot resume send: resume tokens are not supported by the fake")
+	}
+	if has(flags, 'S') {
+		return fail("cannot send saved state: partially received state is not supported by the fake")
+	}
+	if len(operands) != 1 {
+		return fail("expected a single snapshot name")
+	}
+	name := operands[0]
+
+	f.mu.Lock()
+	s, err := f.sendStream(name, flags)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	output := streamMagic + string(data) + "\n"
+	if has(flags, 'n') {
+		if !has(flags, 'v') {
+			return nil
+		}
+		// The estimate is the exact size of the canned stream
+		estimate := fmt.Sprintf("full\t%s\t%d\n", name, len(output))
+		if s.Base != "" {
+			estimate = fmt.Sprintf("incremental\t%s\t%s\t%d\n", s.Base, name, len(output))
+		}
+		_, err = fmt.Fprintf(stdout, "%ssize\t%d\n", estimate, len(output))
+		return err
+	}
+	_, err = io.WriteString(stdout, output)
+	return err
+}
+
+func (f *Fake) sendStream(name string, flags map[byte][]string) (*stream, error) {
+	snap, err := f.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if snap.typ != zfs.DatasetSnapshot {
+		return nil, fail("cannot send '%s': operation only applies to snapshots", name)
+	}
+	ds := f.datasets[parent(name)]
+
+	s := &stream{
+		Snapshot: name,
+		GUID:     snap.guid,
+		Type:     ds.typ,
+		Volsize:  ds.volsize,
+	}
+	for _, baseName := range flags['i'] {
+		if _, err = f.streamBase(s, ds, snap, baseName, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, baseName := range flags['I'] {
+		base, err := f.streamBase(s, ds, snap, baseName, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, between := range f.snapshots(ds.name) {
+			if between.txg <= base.txg || between.txg >= snap.txg {
+				continue
+			}
+			intermediary := streamSnapshot{Snapshot: between.name, GUID: between.guid}
+			if has(flags, 'p') {
+				intermediary.Properties = maps.Clone(between.props)
+			}
+			s.Intermediary = append(s.Intermediary, intermediary)
+		}
+	}
+	if encryption, _ := f.property(ds, zfs.PropertyEncryption); encryption != zfs.ValueOff {
+		if !has(flags, 'w') {
+			return nil, fail("cannot send '%s': encrypted dataset may not be sent without the raw flag", name)
+		}
+		s.Encryption = encryption
+	}
+	if has(flags, 'p') {
+		s.Properties = maps.Clone(ds.props)
+		s.SnapshotProperties = maps.Clone(snap.props)
+	}
+	return s, nil
+}
+
+// streamBase checks the incremental source of the stream, which can be a bookmark unless sending intermediary
+// snapshots, and sets it as the base of the stream
+func (f *Fake) streamBase(s *stream, ds, snap *dataset, baseName string, allowBookmark bool) (*dataset, error) {
+	if strings.HasPrefix(baseName, "@") || (allowBookmark && strings.HasPrefix(baseName, "#")) {
+		baseName = ds.name + baseName
+	}
+	base, err := f.lookup(baseName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case base.typ == zfs.DatasetBookmark && !allowBookmark:
+		return nil, fail("cannot send '%s': incremental source must be a snapshot", snap.name)
+	case base.typ != zfs.DatasetSnapshot && base.typ != zfs.DatasetBookmark:
+		return nil, fail("cannot send '%s': incremental source must be a snapshot or bookmark", snap.name)
+	case baseName != ds.origin && (parent(baseName) != ds.name || base.txg >= snap.txg):
+		return nil, fail("cannot send '%s': not an earlier snapshot from the same fs", snap.name)
+	}
+	s.Base = baseName
+	s.BaseGUID = base.guid
+	return base, nil
+}
+
+// receive implements zfs receive [-Fnsuv] [-o property=value]... [-x property]... filesystem|volume|snapshot
+func (f *Fake) receive(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags, operands, err := parseArgs(args, "FsunvdeAM", "ox")
+	if err != nil {
+		return err
+	}
+	if len(operands) != 1 {
+		return fail("expected a single dataset name")
+	}
+	props, err := parseProperties(flags['o'])
+	if err != nil {
+		return err
+	}
+	if stdin == nil {
+		return fail("cannot receive: failed to read from stream")
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, []byte(streamMagic)) {
+		return fail("cannot receive: invalid stream (bad magic number)")
+	}
+	s := &stream{}
+	err = json.Unmarshal(data[len(streamMagic):], s)
+	if err != nil {
+		return fail("cannot receive: invalid stream (checksum mismatch)")
+	}
+
+	fsName, snapName, ok := strings.Cut(operands[0], "@")
+	if !ok {
+		_, snapName, _ = strings.Cut(s.Snapshot, "@")
+	}
+	name := fsName + "@" + snapName
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, err := f.receiveTarget(s, fsName, name, has(flags, 'F'), has(flags, 'n'))
+	if err != nil {
+		return err
+	}
+	if has(flags, 'n') {
+		if !has(flags, 'v') {
+			return nil
+		}
+		kind := "full"
+		if s.Base != "" {
+			kind = "incremental"
+		}
+		_, err = fmt.Fprintf(stdout, "would receive %s stream of %s into %s\n", kind, s.Snapshot, name)
+		return err
+	}
+	if ds == nil {
+		ds = f.add(fsName, s.Type, nil)
+		ds.volsize = s.Volsize
+		ds.mounted = s.Type == zfs.DatasetFilesystem && !has(flags, 'u')
+		if s.Encryption != "" {
+			ds.props[zfs.PropertyEncryption] = s.Encryption
+		}
+	}
+	for prop, value := range s.Properties {
+		ds.props[prop] = value
+	}
+	for prop, value := range props {
+		ds.props[prop] = value
+	}
+	for _, prop := range flags['x'] {
+		delete(ds.props, prop)
+	}
+
+	for _, between := range s.Intermediary {
+		_, snapName, _ := strings.Cut(between.Snapshot, "@")
+		snap := f.add(fsName+"@"+snapName, zfs.DatasetSnapshot, between.Properties)
+		snap.guid = between.GUID
+	}
+	snap := f.add(name, zfs.DatasetSnapshot, s.SnapshotProperties)
+	snap.guid = s.GUID
+	return nil
+}
+
+// receiveTarget checks whether the stream can be received into the filesystem, and returns it when it exists.
+// With force, more recent snapshots are destroyed to receive an incremental stream, unless it is a dry run.
+func (f *Fake) receiveTarget(s *stream, fsName, name string, force, dryRun bool) (*dataset, error) {
+	ds, exists := f.datasets[fsName]
+	var snaps []*dataset
+	if exists {
+		snaps = f.snapshots(fsName)
+	}
+
+	if s.Base == "" {
+		switch {
+		case !exists:
+			if p := parent(fsName); p != "" {
+				if _, err := f.lookup(p); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		case !force:
+			return nil, fail("cannot receive new filesystem stream: destination '%s' exists\n"+
+				"must specify -F to overwrite it", fsName)
+		case len(snaps) > 0:
+			return nil, fail("cannot receive new filesystem stream: destination has snapshots (eg. %s)\n"+
+				"must destroy them to overwrite it", snaps[0].name)
+		}
+		return ds, nil
+	}
+
+	if !exists {
+		return nil, fail("cannot receive incremental stream: destination '%s' does not exist", fsName)
+	}
+	idx := slices.IndexFunc(snaps, func(snap *dataset) bool {
+		return snap.guid == s.BaseGUID
+	})
+	if idx < 0 || (idx < len(snaps)-1 && !force) {
+		return nil, fail("cannot receive incremental stream: most recent snapshot of %s does not\n"+
+			"match incremental source", fsName)
+	}
+	newer := snaps[idx+1:]
+	names := []string{name}
+	for _, between := range s.Intermediary {
+		_, snapName, _ := strings.Cut(between.Snapshot, "@")
+		names = append(names, fsName+"@"+snapName)
+	}
+	for _, snapName := range names {
+		if snap, ok := f.datasets[snapName]; ok && !slices.Contains(newer, snap) {
+			return nil, fail("cannot receive incremental stream: destination '%s' exists", snapName)
+		}
+	}
+	err := f.remove(newer, false, dryRun, fmt.Sprintf("cannot receive incremental stream into '%s'", fsName))
+	if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
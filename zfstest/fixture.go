@@ -0,0 +1,123 @@
+package zfstest
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Filesystem describes a filesystem to create in a test pool
+type Filesystem struct {
+	// Name of the filesystem, relative to the pool
+	Name string
+	// Properties to set on the filesystem
+	Properties map[string]string
+	// Snapshots are the names of the snapshots to create, in order
+	Snapshots []string
+}
+
+// Volume describes a volume to create in a test pool
+type Volume struct {
+	// Name of the volume, relative to the pool
+	Name string
+	// Size of the volume in bytes, the volume is sparse so it only takes up the space that is written to it
+	Size int64
+	// Properties to set on the volume
+	Properties map[string]string
+	// Snapshots are the names of the snapshots to create, in order
+	Snapshots []string
+}
+
+// Fixture describes a test pool and the datasets to create in it
+type Fixture struct {
+	PoolOptions
+	Filesystems []Filesystem
+	Volumes     []Volume
+}
+
+// NewFixture creates a test pool with the given name and populates it as described by the fixture.
+// The pool must be destroyed after use by calling Destroy.
+func NewFixture(name string, fixture Fixture) (*Pool, error) {
+	p, err := NewPool(name, fixture.PoolOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Populate(fixture.Filesystems...)
+	if err == nil {
+		err = p.PopulateVolumes(fixture.Volumes...)
+	}
+	if err != nil {
+		_ = p.Destroy()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Populate creates the given filesystems and their snapshots in the pool.
+// Parent filesystems are created when necessary, and filesystems are not mounted.
+func (p *Pool) Populate(filesystems ...Filesystem) error {
+	for _, fs := range filesystems {
+		name := fmt.Sprintf("%s/%s", p.Name, fs.Name)
+
+		args := []string{"create", "-p", "-u"}
+		args = append(args, propertyArgs(fs.Properties)...)
+		args = append(args, name)
+		err := run("zfs", args...)
+		if err != nil {
+			return err
+		}
+
+		err = p.snapshot(name, fs.Snapshots)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopulateVolumes creates the given sparse volumes and their snapshots in the pool.
+// Parent filesystems are created when necessary.
+func (p *Pool) PopulateVolumes(volumes ...Volume) error {
+	for _, vol := range volumes {
+		name := fmt.Sprintf("%s/%s", p.Name, vol.Name)
+
+		args := []string{"create", "-p", "-s", "-V", strconv.FormatInt(vol.Size, 10)}
+		args = append(args, propertyArgs(vol.Properties)...)
+		args = append(args, name)
+		err := run("zfs", args...)
+		if err != nil {
+			return err
+		}
+
+		err = p.snapshot(name, vol.Snapshots)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pool) snapshot(name string, snapshots []string) error {
+	for _, snap := range snapshots {
+		err := run("zfs", "snapshot", fmt.Sprintf("%s@%s", name, snap))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// propertyArgs returns the -o arguments for the properties, sorted for predictable commands
+func propertyArgs(props map[string]string) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	args := make([]string, 0, len(props)*2)
+	for _, k := range keys {
+		args = append(args, "-o", fmt.Sprintf("%s=%s", k, props[k]))
+	}
+	return args
+}
@@ -0,0 +1,239 @@
+// Package zfstest provides helpers to run integration tests against real ZFS, using file backed zpools.
+// Creating pools requires root, either by running as root or through passwordless sudo.
+package zfstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPoolFiles    = 3
+	defaultPoolFileSize = 512 * 1024 * 1024
+	commandTimeout      = 10 * time.Second
+)
+
+// Permissions are the zfs permissions delegated to everyone on a test pool, so tests can run without root.
+//
+//	sudo zfs allow <user> canmount,clone,compression,create,destroy,encryption,keyformat,keylocation,load-key,mount,
+//	mountpoint,promote,readonly,receive,refquota,refreservation,rename,rollback,send,snapshot,userprop,volblocksize,
+//	volmode,volsize <dataset>
+var Permissions = []string{
+	"canmount",
+	"clone",
+	"compression",
+	"create",
+	"destroy",
+	"encryption",
+	"keyformat",
+	"keylocation",
+	"load-key",
+	"mount",
+	"mountpoint",
+	"promote",
+	"readonly",
+	"receive",
+	"refquota",
+	"refreservation",
+	"rename",
+	"rollback",
+	"send",
+	"snapshot",
+	"userprop",
+	"volblocksize",
+	"volmode",
+	"volsize",
+}
+
+// ErrUnavailable is returned when ZFS or the privileges to create pools are not available
+var ErrUnavailable = errors.New("zfs is not available")
+
+// Available returns nil when the zfs and zpool commands can be found and pools can be created,
+// otherwise an error wrapping ErrUnavailable describing why not.
+func Available() error {
+	for _, bin := range []string{"zfs", "zpool"} {
+		_, err := exec.LookPath(bin)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+	}
+	if os.Geteuid() == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	err := exec.CommandContext(ctx, "sudo", "-n", "true").Run()
+	if err != nil {
+		return fmt.Errorf("%w: not root and no passwordless sudo: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// TB is the part of testing.TB the helpers use. It is declared here instead of importing testing, so importing this
+// package does not link the testing package and its flags into binaries.
+type TB interface {
+	Helper()
+	Skip(args ...any)
+	Fatalf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// SkipUnlessAvailable skips the test when ZFS tests cannot be run on this machine
+func SkipUnlessAvailable(t TB) {
+	t.Helper()
+	err := Available()
+	if err != nil {
+		t.Skip(err.Error())
+	}
+}
+
+// PoolOptions are options you can specify to customize the test pool
+type PoolOptions struct {
+	// Files is the amount of files backing the pool, defaults to 3
+	Files int
+	// FileSize is the size in bytes of every file backing the pool, defaults to 512MiB.
+	// The files are sparse, so they only take up the space that is written to the pool.
+	FileSize int64
+}
+
+// Pool is a file backed zpool for testing
+type Pool struct {
+	Name  string
+	files []string
+}
+
+// NewPool creates a file backed zpool with the given name, and allows everyone to use it.
+// The pool must be destroyed after use by calling Destroy.
+func NewPool(name string, options PoolOptions) (*Pool, error) {
+	if options.Files <= 0 {
+		options.Files = defaultPoolFiles
+	}
+	if options.FileSize <= 0 {
+		options.FileSize = defaultPoolFileSize
+	}
+
+	p := &Pool{Name: name}
+	args := []string{"zpool", "create", name}
+	for i := 0; i < options.Files; i++ {
+		f, err := os.CreateTemp(os.TempDir(), "test-zpool-")
+		if err != nil {
+			p.removeFiles()
+			return nil, fmt.Errorf("error creating zpool file %d: %w", i, err)
+		}
+		p.files = append(p.files, f.Name())
+
+		err = f.Truncate(options.FileSize)
+		if err == nil {
+			err = f.Close()
+		}
+		if err != nil {
+			_ = f.Close()
+			p.removeFiles()
+			return nil, fmt.Errorf("error sizing zpool file %d: %w", i, err)
+		}
+		args = append(args, f.Name())
+	}
+
+	err := privileged(args...)
+	if err != nil {
+		p.removeFiles()
+		return nil, err
+	}
+
+	err = privileged("zfs", "allow", "everyone", strings.Join(Permissions, ","), name)
+	if err != nil {
+		_ = p.Destroy()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Destroy destroys the pool and removes its backing files
+func (p *Pool) Destroy() error {
+	err := privileged("zpool", "destroy", p.Name)
+	p.removeFiles()
+	return err
+}
+
+func (p *Pool) removeFiles() {
+	for _, file := range p.files {
+		_ = os.Remove(file)
+	}
+	p.files = nil
+}
+
+// WithPool creates a test pool with the given name, runs fn and destroys the pool again.
+// It panics when the pool cannot be created or destroyed.
+func WithPool(name string, fn func()) {
+	WithFixture(name, Fixture{}, func(*Pool) {
+		fn()
+	})
+}
+
+// WithFixture creates a test pool with the given name populated as described by the fixture, runs fn and destroys
+// the pool again. It panics when the pool cannot be created or destroyed.
+func WithFixture(name string, fixture Fixture, fn func(pool *Pool)) {
+	p, err := NewFixture(name, fixture)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		err := p.Destroy()
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	fn(p)
+}
+
+// Run creates a test pool with the given name for the duration of the test, skipping the test when ZFS is not available.
+func Run(t TB, name string, fn func(pool *Pool)) {
+	t.Helper()
+	RunFixture(t, name, Fixture{}, fn)
+}
+
+// RunFixture creates a test pool with the given name populated as described by the fixture for the duration of the
+// test, skipping the test when ZFS is not available.
+func RunFixture(t TB, name string, fixture Fixture, fn func(pool *Pool)) {
+	t.Helper()
+	SkipUnlessAvailable(t)
+
+	p, err := NewFixture(name, fixture)
+	if err != nil {
+		t.Fatalf("error creating test pool %s: %v", name, err)
+	}
+	defer func() {
+		err := p.Destroy()
+		if err != nil {
+			t.Errorf("error destroying test pool %s: %v", name, err)
+		}
+	}()
+
+	fn(p)
+}
+
+// privileged runs a command as root, using sudo when not running as root already
+func privileged(args ...string) error {
+	if os.Geteuid() != 0 {
+		args = append([]string{"sudo"}, args...)
+	}
+	return run(args[0], args[1:]...)
+}
+
+func run(name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running %s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package zfstest
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_Populate(t *testing.T) {
+	Run(t, "go-test-zpool-zfstest", func(pool *Pool) {
+		err := pool.Populate(Filesystem{
+			Name:       "parent/child",
+			Properties: map[string]string{"canmount": "off", "nl.test:hello": "world"},
+			Snapshots:  []string{"snap1", "snap2"},
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "zfs", "list", "-H", "-o", "name", "-r", "-t", "all", pool.Name).Output()
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			pool.Name,
+			pool.Name + "/parent",
+			pool.Name + "/parent/child",
+			pool.Name + "/parent/child@snap1",
+			pool.Name + "/parent/child@snap2",
+		}, strings.Fields(string(out)))
+	})
+}
+
+func TestRunFixture(t *testing.T) {
+	fixture := Fixture{
+		PoolOptions: PoolOptions{Files: 1, FileSize: 128 * 1024 * 1024},
+		Filesystems: []Filesystem{{Name: "fs", Snapshots: []string{"snap"}}},
+		Volumes:     []Volume{{Name: "vols/vol", Size: 64 * 1024 * 1024, Snapshots: []string{"snap"}}},
+	}
+	RunFixture(t, "go-test-zpool-fixture", fixture, func(pool *Pool) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "zfs", "list", "-H", "-o", "name,type", "-r", "-t", "all", pool.Name).Output()
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			pool.Name, "filesystem",
+			pool.Name + "/fs", "filesystem",
+			pool.Name + "/fs@snap", "snapshot",
+			pool.Name + "/vols", "filesystem",
+			pool.Name + "/vols/vol", "volume",
+			pool.Name + "/vols/vol@snap", "snapshot",
+		}, strings.Fields(string(out)))
+	})
+}
@@ -0,0 +1,66 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoNamespace is returned when no user namespace was given to delegate a dataset to or revoke it from
+var ErrNoNamespace = errors.New("no user namespace given")
+
+// UserNamespaceFile returns the file of the user namespace of a process, like the init process of a container
+func UserNamespaceFile(pid int) string {
+	return fmt.Sprintf("/proc/%d/ns/user", pid)
+}
+
+// Zone delegates the filesystem and its children to a Linux user namespace, given by its namespace file, so they can
+// be managed from within the namespace, see UserNamespaceFile. The zoned property is set first, as zfs requires it.
+// Zones are available on Linux since OpenZFS 2.2.
+func (d *Dataset) Zone(ctx context.Context, namespaceFile string) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	if namespaceFile == "" {
+		return ErrNoNamespace
+	}
+	err := d.SetZoned(ctx, true)
+	if err != nil {
+		return err
+	}
+	return zfs(ctx, "zone", namespaceFile, d.Name)
+}
+
+// Unzone revokes the delegation of the filesystem and its children to a Linux user namespace.
+// The zoned property stays set, see SetZoned.
+func (d *Dataset) Unzone(ctx context.Context, namespaceFile string) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	if namespaceFile == "" {
+		return ErrNoNamespace
+	}
+	return zfs(ctx, "unzone", namespaceFile, d.Name)
+}
+
+// SetZoned sets or clears the zoned property. A zoned filesystem cannot be mounted on the host, as the namespace may
+// have changed its mountpoint. Check the mountpoint before clearing the property of a filesystem that was zoned.
+func (d *Dataset) SetZoned(ctx context.Context, zoned bool) error {
+	if d.Type == DatasetSnapshot {
+		return ErrSnapshotsNotSupported
+	}
+	value := ValueOff
+	if zoned {
+		value = ValueOn
+	}
+	return d.SetProperty(ctx, PropertyZoned, value)
+}
+
+// Zoned returns whether the zoned property is set
+func (d *Dataset) Zoned(ctx context.Context) (bool, error) {
+	value, err := d.GetProperty(ctx, PropertyZoned)
+	if err != nil {
+		return false, err
+	}
+	return value == ValueOn, nil
+}
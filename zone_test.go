@@ -0,0 +1,42 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Zone(t *testing.T) {
+	var executed [][]string
+	SetExecutor(executorFunc(func(_ context.Context, _ string, args []string, _ io.Reader, stdout io.Writer) (string, error) {
+		executed = append(executed, args)
+		if args[0] == "get" {
+			_, err := io.WriteString(stdout, "off\n")
+			return "", err
+		}
+		return "", nil
+	}))
+	defer SetExecutor(nil)
+
+	ctx := context.Background()
+	ds := &Dataset{Name: "pool/containers/web", Type: DatasetFilesystem}
+	require.NoError(t, ds.Zone(ctx, UserNamespaceFile(1234)))
+	require.NoError(t, ds.Unzone(ctx, "/proc/1234/ns/user"))
+	require.NoError(t, ds.SetZoned(ctx, false))
+	require.Equal(t, [][]string{
+		{"set", "zoned=on", "pool/containers/web"},
+		{"zone", "/proc/1234/ns/user", "pool/containers/web"},
+		{"unzone", "/proc/1234/ns/user", "pool/containers/web"},
+		{"set", "zoned=off", "pool/containers/web"},
+	}, executed)
+
+	zoned, err := ds.Zoned(ctx)
+	require.NoError(t, err)
+	require.False(t, zoned)
+
+	require.ErrorIs(t, ds.Zone(ctx, ""), ErrNoNamespace)
+	snap := &Dataset{Name: "pool/containers/web@snap", Type: DatasetSnapshot}
+	require.ErrorIs(t, snap.Unzone(ctx, "/proc/1234/ns/user"), ErrSnapshotsNotSupported)
+}